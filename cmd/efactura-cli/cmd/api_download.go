@@ -20,13 +20,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
 )
 
 const (
 	flagNameDownloadID      = "id"
 	flagNameDownloadOutFile = "out"
+	flagNameDownloadRetries = "retries"
 )
 
 // apiDownloadCmd represents the `api download` command
@@ -46,7 +50,16 @@ var apiDownloadCmd = &cobra.Command{
 			return err
 		}
 
-		res, err := client.DownloadInvoice(ctx, fvDownloadID)
+		fvRetries, err := cmd.Flags().GetInt(flagNameDownloadRetries)
+		if err != nil {
+			return err
+		}
+
+		var downloadOpts []efactura.DownloadOption
+		if fvRetries > 0 {
+			downloadOpts = append(downloadOpts, efactura.DownloadOptionRetry(fvRetries+1, time.Second))
+		}
+		res, err := client.DownloadInvoice(ctx, fvDownloadID, downloadOpts...)
 		if err != nil {
 			cmd.SilenceUsage = true
 			return err
@@ -87,6 +100,7 @@ var apiDownloadCmd = &cobra.Command{
 func init() {
 	apiDownloadCmd.Flags().Int64(flagNameDownloadID, 0, "Download ID for the Invoice zip")
 	_ = apiDownloadCmd.MarkFlagRequired(flagNameDownloadID)
+	apiDownloadCmd.Flags().Int(flagNameDownloadRetries, 0, "Number of retries for interrupted or corrupted downloads")
 
 	apiDownloadCmd.Flags().String(flagNameDownloadOutFile, "", "Write output to this file instead of stdout")
 
@@ -0,0 +1,106 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/trust"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagNameTrustDir    = "trust-dir"
+	flagNameTrustSource = "source"
+	flagNameTrustList   = "list"
+)
+
+// updateTrustCmd represents the update-trust command
+var updateTrustCmd = &cobra.Command{
+	Use:   "update-trust",
+	Short: "Update the certificates used for offline signature validation",
+	Long: `Download the certificates used for offline signature validation to the
+trust override directory. The certificates from the override directory replace
+the certificates embedded in the library with the same file name. Without any
+--source, the built-in ANAF sources (pinned to the expected certificate
+fingerprints) are used. Only https sources are allowed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fvDir, err := cmd.Flags().GetString(flagNameTrustDir)
+		if err != nil {
+			return err
+		}
+		if fvDir == "" {
+			if fvDir, err = trust.DefaultDir(); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+		}
+
+		fvList, err := cmd.Flags().GetBool(flagNameTrustList)
+		if err != nil {
+			return err
+		}
+		if fvList {
+			store, err := trust.Load(fvDir)
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			for _, c := range store.Certificates() {
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\n", c.Source, c.Name, c.Certificate.Subject,
+					c.Certificate.NotAfter.Format("2006-01-02"), trust.Fingerprint(c.Certificate))
+			}
+			return nil
+		}
+
+		fvSources, err := cmd.Flags().GetStringArray(flagNameTrustSource)
+		if err != nil {
+			return err
+		}
+		sources := trust.DefaultSources
+		if len(fvSources) > 0 {
+			sources = nil
+		} else if len(sources) == 0 {
+			return fmt.Errorf("no built-in sources, at least one --%s is required", flagNameTrustSource)
+		}
+		for _, fvSource := range fvSources {
+			name, url, ok := strings.Cut(fvSource, "=")
+			if !ok || name == "" || url == "" {
+				return fmt.Errorf("invalid source `%s`, expected name=url", fvSource)
+			}
+			sources = append(sources, trust.Source{Name: name, URL: url})
+		}
+
+		paths, err := trust.Update(context.Background(), fvDir, sources)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		for _, path := range paths {
+			fmt.Printf("updated %s\n", path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	updateTrustCmd.Flags().String(flagNameTrustDir, "", "Trust override directory (default is <user config dir>/e-factura/trust)")
+	updateTrustCmd.Flags().StringArray(flagNameTrustSource, nil, "Certificate to download, as name=url (can be repeated, default is the built-in ANAF sources)")
+	updateTrustCmd.Flags().Bool(flagNameTrustList, false, "List the trusted certificates instead of updating")
+
+	rootCmd.AddCommand(updateTrustCmd)
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/spf13/cobra"
+)
+
+// validateOfflineCmd represents the validate-offline command
+var validateOfflineCmd = &cobra.Command{
+	Use:   "validate-offline",
+	Short: "Validate an invoice XML without calling the ANAF API",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fvXmlFile, err := cmd.Flags().GetString(flagNameValidateInFilePath)
+		if err != nil {
+			return err
+		}
+		xmlData, err := os.ReadFile(fvXmlFile)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+
+		var invoice efactura.Invoice
+		if err := efactura.UnmarshalInvoice(xmlData, &invoice); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		errs := efactura.ValidateInvoiceOffline(invoice)
+		if len(errs) > 0 {
+			for _, e := range errs {
+				fmt.Fprintln(os.Stderr, e)
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("validate offline failed: %d errors", len(errs))
+		}
+		fmt.Println("validate offline: OK")
+		return nil
+	},
+}
+
+func init() {
+	validateOfflineCmd.Flags().StringP(flagNameValidateInFilePath, "f", "", "Path of the input XML file")
+	_ = validateOfflineCmd.MarkFlagRequired(flagNameValidateInFilePath)
+
+	rootCmd.AddCommand(validateOfflineCmd)
+}
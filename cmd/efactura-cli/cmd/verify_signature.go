@@ -0,0 +1,99 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/trust"
+	"github.com/printesoi/e-factura-go/pkg/xmlsig"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagNameVerifySignatureArchiveDir = "archive-dir"
+)
+
+// verifySignatureCmd represents the verify-signature command
+var verifySignatureCmd = &cobra.Command{
+	Use:   "verify-signature",
+	Short: "Verify the signature of an e-factura ZIP archive without calling the ANAF API",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fvInvoiceZip, err := cmd.Flags().GetString(flagNameValidateSignatureZip)
+		if err != nil {
+			return err
+		}
+		fvArchiveDir, err := cmd.Flags().GetString(flagNameVerifySignatureArchiveDir)
+		if err != nil {
+			return err
+		}
+		fvDir, err := cmd.Flags().GetString(flagNameTrustDir)
+		if err != nil {
+			return err
+		}
+		var store *trust.Store
+		if fvDir == "" {
+			store, err = trust.LoadDefault()
+		} else {
+			store, err = trust.Load(fvDir)
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		verifier, err := xmlsig.NewVerifier(xmlsig.VerifierTrustStore(store))
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		if fvArchiveDir != "" {
+			cmd.SilenceUsage = true
+			report, err := efactura.ValidateSignaturesDir(cmd.Context(), fvArchiveDir,
+				efactura.SignatureCheckVerifier(verifier))
+			if err != nil {
+				return err
+			}
+			for _, entry := range report.Entries {
+				if entry.Status != efactura.SignatureStatusValid {
+					fmt.Printf("%s: %s: %s\n", entry.Path, entry.Status, entry.Message)
+				}
+			}
+			fmt.Printf("verify signatures: %s\n", report)
+			if !report.OK() {
+				return fmt.Errorf("verify signatures failed")
+			}
+			return nil
+		}
+
+		result, err := verifier.VerifyZipFile(fvInvoiceZip)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("verify signature failed: %w", err)
+		}
+		fmt.Printf("verify signature: OK (signed by %s)\n", result.Certificate.Subject)
+		return nil
+	},
+}
+
+func init() {
+	verifySignatureCmd.Flags().String(flagNameValidateSignatureZip, "", "Path to the ZIP archive")
+	verifySignatureCmd.Flags().String(flagNameVerifySignatureArchiveDir, "", "Verify all the ZIP archives from this directory and print a summary report")
+	verifySignatureCmd.Flags().String(flagNameTrustDir, "", "Trust override directory (default is <user config dir>/e-factura/trust)")
+	verifySignatureCmd.MarkFlagsOneRequired(flagNameValidateSignatureZip, flagNameVerifySignatureArchiveDir)
+	verifySignatureCmd.MarkFlagsMutuallyExclusive(flagNameValidateSignatureZip, flagNameVerifySignatureArchiveDir)
+
+	rootCmd.AddCommand(verifySignatureCmd)
+}
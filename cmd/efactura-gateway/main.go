@@ -0,0 +1,174 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Command efactura-gateway exposes the e-factura client as REST endpoints on
+// localhost (see the gateway package), so that applications not written in Go
+// can integrate with e-factura over HTTP.
+//
+// All the flags can also be set with environment variables, eg.
+// EFACTURA_OAUTH_CLIENT_ID for --oauth-client-id.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/gateway"
+	"github.com/printesoi/e-factura-go/pkg/oauth2"
+)
+
+type config struct {
+	listen            string
+	production        bool
+	oauthClientID     string
+	oauthClientSecret string
+	oauthRedirectURL  string
+	tokenFile         string
+	authToken         string
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.listen, "listen", "127.0.0.1:8080", "Listen address")
+	flag.BoolVar(&cfg.production, "production", false, "Production mode (default sandbox)")
+	flag.StringVar(&cfg.oauthClientID, "oauth-client-id", "", "OAuth2 client ID")
+	flag.StringVar(&cfg.oauthClientSecret, "oauth-client-secret", "", "OAuth2 client secret")
+	flag.StringVar(&cfg.oauthRedirectURL, "oauth-redirect-url", "", "OAuth2 redirect URL. This needs to match one of the URLs for the OAuth2 app in SPV.")
+	flag.StringVar(&cfg.tokenFile, "token-file", "", "Path of the file with the JSON-encoded OAuth2 token. The file is updated when the token is refreshed.")
+	flag.StringVar(&cfg.authToken, "auth-token", "", "Bearer token required by the gateway endpoints (optional)")
+	setFlagsFromEnv()
+	flag.Parse()
+
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// setFlagsFromEnv sets the default value of each flag from the corresponding
+// EFACTURA_* environment variable, if set.
+func setFlagsFromEnv() {
+	flag.VisitAll(func(f *flag.Flag) {
+		name := "EFACTURA_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if err := f.Value.Set(value); err != nil {
+				log.Fatalf("invalid value for %s: %v", name, err)
+			}
+			f.DefValue = value
+		}
+	})
+}
+
+func run(cfg config) error {
+	for name, value := range map[string]string{
+		"oauth-client-id":     cfg.oauthClientID,
+		"oauth-client-secret": cfg.oauthClientSecret,
+		"oauth-redirect-url":  cfg.oauthRedirectURL,
+		"token-file":          cfg.tokenFile,
+	} {
+		if value == "" {
+			return fmt.Errorf("missing required flag --%s", name)
+		}
+	}
+
+	tokenData, err := os.ReadFile(cfg.tokenFile)
+	if err != nil {
+		return fmt.Errorf("error reading token: %w", err)
+	}
+	token, err := oauth2.TokenFromJSON(tokenData)
+	if err != nil {
+		return fmt.Errorf("error loading token from JSON: %w", err)
+	}
+
+	oauth2Cfg, err := oauth2.MakeConfig(
+		oauth2.ConfigCredentials(cfg.oauthClientID, cfg.oauthClientSecret),
+		oauth2.ConfigRedirectURL(cfg.oauthRedirectURL),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating oauth2 config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	onTokenChanged := func(ctx context.Context, token *xoauth2.Token) error {
+		return saveToken(cfg.tokenFile, token)
+	}
+	tokenSource := oauth2Cfg.TokenSourceWithChangedHandler(ctx, token, onTokenChanged)
+	var client *efactura.Client
+	if cfg.production {
+		client, err = efactura.NewProductionClient(ctx, tokenSource)
+	} else {
+		client, err = efactura.NewSandboxClient(ctx, tokenSource)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+
+	var opts []gateway.ConfigOption
+	if cfg.authToken != "" {
+		opts = append(opts, gateway.ConfigAuthToken(cfg.authToken))
+	}
+	server := &http.Server{
+		Addr:              cfg.listen,
+		Handler:           gateway.New(client, opts...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("efactura-gateway listening on %s", cfg.listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// saveToken atomically replaces the token file with the JSON-encoded token.
+func saveToken(path string, token *xoauth2.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	efactura_oauth2 "github.com/printesoi/e-factura-go/pkg/oauth2"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// daemonStatus is the status of the daemon, shown in the UI.
+type daemonStatus struct {
+	LoggedIn   bool
+	LastSync   time.Time
+	LastError  string
+	Downloaded int
+}
+
+// daemon watches the messages list of the configured CIF and downloads the
+// new invoices (sent and received) and messages into the store. It also runs
+// a SignatureRevalidator over the store.
+type daemon struct {
+	cfg       config
+	oauth2Cfg efactura_oauth2.Config
+	tokens    tokenStore
+	store     store.Store
+	login     chan *xoauth2.Token
+
+	mu     sync.Mutex
+	status daemonStatus
+}
+
+func newDaemon(cfg config, oauth2Cfg efactura_oauth2.Config, tokens tokenStore, st store.Store) *daemon {
+	return &daemon{
+		cfg:       cfg,
+		oauth2Cfg: oauth2Cfg,
+		tokens:    tokens,
+		store:     st,
+		login:     make(chan *xoauth2.Token, 1),
+	}
+}
+
+// Status returns the current status of the daemon.
+func (d *daemon) Status() daemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// LoggedIn is called by the gateway after a successful login.
+func (d *daemon) LoggedIn(token *xoauth2.Token) {
+	select {
+	case d.login <- token:
+	default:
+	}
+}
+
+// Run waits for a token (saved or from a login), then syncs the messages
+// every SyncInterval until ctx is done.
+func (d *daemon) Run(ctx context.Context) error {
+	token, err := d.tokens.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		log.Printf("daemon: no saved token, waiting for login")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case token = <-d.login:
+		}
+	}
+	d.mu.Lock()
+	d.status.LoggedIn = true
+	d.mu.Unlock()
+
+	// Every time the token is refreshed it's saved in the database.
+	tokenSource := d.oauth2Cfg.TokenSourceWithChangedHandler(ctx, token, d.tokens.Save)
+	var client *efactura.Client
+	if d.cfg.Sandbox {
+		client, err = efactura.NewSandboxClient(ctx, tokenSource)
+	} else {
+		client, err = efactura.NewProductionClient(ctx, tokenSource)
+	}
+	if err != nil {
+		return err
+	}
+
+	revalidator := efactura.NewSignatureRevalidator(client, d.store,
+		efactura.RevalidationFilter(func(entry store.Entry) bool {
+			return entry.MessageType == efactura.MessageTypeSentInvoice ||
+				entry.MessageType == efactura.MessageTypeReceivedInvoice
+		}),
+		efactura.RevalidationOnResult(func(res efactura.RevalidationResult) {
+			if res.Err == nil && !res.Valid {
+				log.Printf("daemon: document %s failed validation: %s", res.Key, res.Message)
+			}
+		}))
+	go func() {
+		if err := revalidator.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("daemon: revalidator stopped: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(d.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		n, err := d.sync(ctx, client)
+		d.mu.Lock()
+		d.status.LastSync = time.Now()
+		d.status.Downloaded += n
+		d.status.LastError = ""
+		if err != nil {
+			d.status.LastError = err.Error()
+			log.Printf("daemon: sync failed: %v", err)
+		}
+		d.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync downloads the messages from the last SyncDays days that are not in
+// the store yet. It returns the number of downloaded messages.
+func (d *daemon) sync(ctx context.Context, client *efactura.Client) (downloaded int, err error) {
+	res, err := client.GetMessagesList(ctx, d.cfg.CIF, d.cfg.SyncDays, efactura.MessageFilterAll)
+	if err != nil {
+		return 0, err
+	}
+	if !res.IsOk() {
+		return 0, fmt.Errorf("messages list: %s", res.Error)
+	}
+
+	for _, message := range res.Messages {
+		key := message.ID
+		if _, err := d.store.Stat(ctx, key); err == nil {
+			continue
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return downloaded, err
+		}
+
+		dres, err := client.DownloadInvoice(ctx, message.GetID())
+		if err != nil {
+			return downloaded, err
+		}
+		if !dres.IsOk() {
+			return downloaded, fmt.Errorf("download %s: %s", key, dres.Error.Error)
+		}
+		entry := store.Entry{
+			Key:         key,
+			DownloadID:  message.GetID(),
+			UploadIndex: message.GetUploadIndex(),
+			CIF:         message.CIF,
+			MessageType: message.Type,
+			Name:        key + ".zip",
+			Metadata: map[string]string{
+				"details":       message.Details,
+				"creation_date": message.CreationDate,
+			},
+		}
+		if err := d.store.Put(ctx, entry, dres.Zip); err != nil {
+			return downloaded, err
+		}
+		downloaded++
+	}
+	return downloaded, nil
+}
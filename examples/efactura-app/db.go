@@ -0,0 +1,89 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	xoauth2 "golang.org/x/oauth2"
+	_ "modernc.org/sqlite"
+
+	efactura_oauth2 "github.com/printesoi/e-factura-go/pkg/oauth2"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS oauth_token (
+	id         INTEGER PRIMARY KEY CHECK (id = 1),
+	token_json TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS documents (
+	key        TEXT PRIMARY KEY,
+	entry_json TEXT NOT NULL,
+	data       BLOB NOT NULL
+);
+`
+
+// openDB opens (or creates) the SQLite database at path and applies the
+// schema.
+func openDB(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite supports a single writer.
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// tokenStore persists the OAuth2 token, so the daemon survives restarts
+// without a new login.
+type tokenStore struct {
+	db *sql.DB
+}
+
+// Load returns the saved token, or nil if no token was saved yet.
+func (s tokenStore) Load(ctx context.Context) (*xoauth2.Token, error) {
+	var tokenJSON string
+	err := s.db.QueryRowContext(ctx, `SELECT token_json FROM oauth_token WHERE id = 1`).Scan(&tokenJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return efactura_oauth2.TokenFromJSON([]byte(tokenJSON))
+}
+
+// Save saves the token. It's used as the efactura_oauth2.TokenChangedHandler
+// of the token source, so refreshed tokens are persisted.
+func (s tokenStore) Save(ctx context.Context, token *xoauth2.Token) error {
+	tokenJSON, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO oauth_token (id, token_json, updated_at)
+		VALUES (1, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET token_json = excluded.token_json, updated_at = excluded.updated_at`,
+		string(tokenJSON))
+	return err
+}
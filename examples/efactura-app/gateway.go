@@ -0,0 +1,176 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	efactura_oauth2 "github.com/printesoi/e-factura-go/pkg/oauth2"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+const oauthStateCookie = "efactura_oauth_state"
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>e-factura</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>e-factura {{.CIF}}{{if .Sandbox}} (sandbox){{end}}</h1>
+{{if .Status.LoggedIn}}
+<p>Last sync: {{if .Status.LastSync.IsZero}}never{{else}}{{.Status.LastSync.Format "2006-01-02 15:04:05"}}{{end}},
+downloaded since start: {{.Status.Downloaded}}</p>
+{{if .Status.LastError}}<p class="error">{{.Status.LastError}}</p>{{end}}
+{{else}}
+<p><a href="/login">Login with the ANAF certificate</a></p>
+{{end}}
+<table>
+<tr><th>ID</th><th>Type</th><th>CIF</th><th>Created</th><th>Details</th><th>Signature</th><th></th></tr>
+{{range .Entries}}
+<tr>
+<td>{{.Key}}</td>
+<td>{{.MessageType}}</td>
+<td>{{.CIF}}</td>
+<td>{{index .Metadata "creation_date"}}</td>
+<td>{{index .Metadata "details"}}</td>
+<td>{{with index .Metadata "signature_valid"}}{{if eq . "true"}}valid{{else}}<span class="error">invalid</span>{{end}}{{else}}-{{end}}</td>
+<td><a href="/documents/{{.Key}}">zip</a></td>
+</tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// gateway is the HTTP server of the application: the OAuth2 login flow and a
+// minimal UI for the downloaded documents.
+type gateway struct {
+	cfg       config
+	oauth2Cfg efactura_oauth2.Config
+	tokens    tokenStore
+	store     store.Store
+	daemon    *daemon
+}
+
+func (g *gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", g.handleIndex)
+	mux.HandleFunc("GET /login", g.handleLogin)
+	mux.HandleFunc("GET /oauth/callback", g.handleCallback)
+	mux.HandleFunc("GET /documents/{key}", g.handleDocument)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func (g *gateway) handleIndex(w http.ResponseWriter, r *http.Request) {
+	entries, err := g.store.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Newest first.
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	data := struct {
+		CIF     string
+		Sandbox bool
+		Status  daemonStatus
+		Entries []store.Entry
+	}{
+		CIF:     g.cfg.CIF,
+		Sandbox: g.cfg.Sandbox,
+		Status:  g.daemon.Status(),
+		Entries: entries,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, data); err != nil {
+		log.Printf("gateway: render index: %v", err)
+	}
+}
+
+func (g *gateway) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(b[:])
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/oauth",
+		MaxAge:   600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, g.oauth2Cfg.AuthCodeURL(state), http.StatusFound)
+}
+
+func (g *gateway) handleCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oauthStateCookie)
+	state := r.URL.Query().Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		http.Error(w, "invalid OAuth2 state", http.StatusBadRequest)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+	token, err := g.oauth2Cfg.Exchange(r.Context(), code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := g.tokens.Save(r.Context(), token); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.daemon.LoggedIn(token)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (g *gateway) handleDocument(w http.ResponseWriter, r *http.Request) {
+	entry, data, err := g.store.Get(r.Context(), r.PathValue("key"))
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+entry.Name+`"`)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Command efactura-app is an example application showing the recommended
+// wiring of the e-factura-go subsystems: an HTTP gateway handling the OAuth2
+// login and a minimal UI, a daemon that watches the messages list and
+// downloads the new documents into a SQLite store, and a signature
+// re-validation job. The OAuth2 token is persisted in the same database.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+
+	efactura_oauth2 "github.com/printesoi/e-factura-go/pkg/oauth2"
+)
+
+// config is the application config, read from the environment.
+type config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	CIF          string
+	Sandbox      bool
+	DBPath       string
+	ListenAddr   string
+	SyncInterval time.Duration
+	SyncDays     int
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func loadConfig() (cfg config, err error) {
+	cfg = config{
+		ClientID:     os.Getenv("EFACTURA_CLIENT_ID"),
+		ClientSecret: os.Getenv("EFACTURA_CLIENT_SECRET"),
+		RedirectURL:  getenv("EFACTURA_REDIRECT_URL", "http://localhost:8080/oauth/callback"),
+		CIF:          os.Getenv("EFACTURA_CIF"),
+		DBPath:       getenv("EFACTURA_DB", "efactura.db"),
+		ListenAddr:   getenv("EFACTURA_LISTEN", ":8080"),
+	}
+	if cfg.CIF == "" {
+		return cfg, errors.New("EFACTURA_CIF is required")
+	}
+	if cfg.Sandbox, err = strconv.ParseBool(getenv("EFACTURA_SANDBOX", "true")); err != nil {
+		return cfg, fmt.Errorf("EFACTURA_SANDBOX: %w", err)
+	}
+	if cfg.SyncInterval, err = time.ParseDuration(getenv("EFACTURA_SYNC_INTERVAL", "15m")); err != nil {
+		return cfg, fmt.Errorf("EFACTURA_SYNC_INTERVAL: %w", err)
+	}
+	if cfg.SyncDays, err = strconv.Atoi(getenv("EFACTURA_SYNC_DAYS", "60")); err != nil {
+		return cfg, fmt.Errorf("EFACTURA_SYNC_DAYS: %w", err)
+	}
+	if cfg.SyncDays < 1 || cfg.SyncDays > 60 {
+		return cfg, errors.New("EFACTURA_SYNC_DAYS must be between 1 and 60")
+	}
+	return cfg, nil
+}
+
+func run(ctx context.Context) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	oauth2Cfg, err := efactura_oauth2.MakeConfig(
+		efactura_oauth2.ConfigCredentials(cfg.ClientID, cfg.ClientSecret),
+		efactura_oauth2.ConfigRedirectURL(cfg.RedirectURL),
+	)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(ctx, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tokens := tokenStore{db: db}
+	st := newSQLiteStore(db)
+	d := newDaemon(cfg, oauth2Cfg, tokens, st)
+	g := &gateway{
+		cfg:       cfg,
+		oauth2Cfg: oauth2Cfg,
+		tokens:    tokens,
+		store:     st,
+		daemon:    d,
+	}
+
+	server := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           g.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 2)
+	go func() {
+		log.Printf("gateway: listening on %s", cfg.ListenAddr)
+		errc <- server.ListenAndServe()
+	}()
+	go func() {
+		errc <- d.Run(ctx)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	return err
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// sqliteStore is a store.Store that keeps the documents in the SQLite
+// database. The entry is stored as JSON next to the data.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+var (
+	_ store.Store           = (*sqliteStore)(nil)
+	_ store.MetadataUpdater = (*sqliteStore)(nil)
+)
+
+func newSQLiteStore(db *sql.DB) *sqliteStore {
+	return &sqliteStore{db: db}
+}
+
+// Put implements the store.Store interface.
+func (s *sqliteStore) Put(ctx context.Context, entry store.Entry, data []byte) error {
+	if err := store.ValidateKey(entry.Key); err != nil {
+		return err
+	}
+	entry.Size = int64(len(data))
+	entry.SHA256 = store.Checksum(data)
+	if entry.StoredAt.IsZero() {
+		entry.StoredAt = time.Now()
+	}
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO documents (key, entry_json, data) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET entry_json = excluded.entry_json, data = excluded.data`,
+		entry.Key, string(entryJSON), data)
+	return err
+}
+
+// Get implements the store.Store interface.
+func (s *sqliteStore) Get(ctx context.Context, key string) (entry store.Entry, data []byte, err error) {
+	var entryJSON string
+	err = s.db.QueryRowContext(ctx, `SELECT entry_json, data FROM documents WHERE key = ?`, key).
+		Scan(&entryJSON, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(entryJSON), &entry)
+	return
+}
+
+// Stat implements the store.Store interface.
+func (s *sqliteStore) Stat(ctx context.Context, key string) (entry store.Entry, err error) {
+	var entryJSON string
+	err = s.db.QueryRowContext(ctx, `SELECT entry_json FROM documents WHERE key = ?`, key).
+		Scan(&entryJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(entryJSON), &entry)
+	return
+}
+
+// Delete implements the store.Store interface.
+func (s *sqliteStore) Delete(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM documents WHERE key = ?`, key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// List implements the store.Store interface.
+func (s *sqliteStore) List(ctx context.Context) ([]store.Entry, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT entry_json FROM documents ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []store.Entry
+	for rows.Next() {
+		var entryJSON string
+		if err := rows.Scan(&entryJSON); err != nil {
+			return nil, err
+		}
+		var entry store.Entry
+		if err := json.Unmarshal([]byte(entryJSON), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateMetadata implements the store.MetadataUpdater interface.
+func (s *sqliteStore) UpdateMetadata(ctx context.Context, key string, md map[string]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var entryJSON string
+	err = tx.QueryRowContext(ctx, `SELECT entry_json FROM documents WHERE key = ?`, key).Scan(&entryJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var entry store.Entry
+	if err := json.Unmarshal([]byte(entryJSON), &entry); err != nil {
+		return err
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = make(map[string]string)
+	}
+	for k, v := range md {
+		if v == "" {
+			delete(entry.Metadata, k)
+		} else {
+			entry.Metadata[k] = v
+		}
+	}
+	updatedJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE documents SET entry_json = ? WHERE key = ?`,
+		string(updatedJSON), key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
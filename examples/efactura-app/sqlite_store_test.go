@@ -0,0 +1,99 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := openDB(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := newSQLiteStore(db)
+	assert.Error(s.Put(ctx, store.Entry{Key: "../escape"}, nil))
+	assert.NoError(s.Put(ctx, store.Entry{Key: "3002", MessageType: "FACTURA PRIMITA"}, []byte("zip-2")))
+	assert.NoError(s.Put(ctx, store.Entry{Key: "3001"}, []byte("zip-1")))
+
+	entry, data, err := s.Get(ctx, "3002")
+	if assert.NoError(err) {
+		assert.Equal("zip-2", string(data))
+		assert.Equal("FACTURA PRIMITA", entry.MessageType)
+		assert.Equal(int64(5), entry.Size)
+		assert.NoError(store.Verify(entry, data))
+	}
+
+	assert.NoError(store.UpdateMetadata(ctx, s, "3002", map[string]string{"signature_valid": "true"}))
+	entry, err = s.Stat(ctx, "3002")
+	if assert.NoError(err) {
+		assert.Equal("true", entry.Metadata["signature_valid"])
+	}
+
+	entries, err := s.List(ctx)
+	if assert.NoError(err) && assert.Len(entries, 2) {
+		assert.Equal("3001", entries[0].Key)
+		assert.Equal("3002", entries[1].Key)
+	}
+
+	assert.NoError(s.Delete(ctx, "3001"))
+	assert.True(errors.Is(s.Delete(ctx, "3001"), store.ErrNotFound))
+	_, _, err = s.Get(ctx, "3001")
+	assert.True(errors.Is(err, store.ErrNotFound))
+}
+
+func TestTokenStore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := openDB(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tokens := tokenStore{db: db}
+	token, err := tokens.Load(ctx)
+	assert.NoError(err)
+	assert.Nil(token)
+
+	for _, accessToken := range []string{"access-1", "access-2"} {
+		assert.NoError(tokens.Save(ctx, &xoauth2.Token{
+			AccessToken:  accessToken,
+			TokenType:    "Bearer",
+			RefreshToken: "refresh",
+			Expiry:       time.Now().Add(time.Hour),
+		}))
+	}
+	token, err = tokens.Load(ctx)
+	if assert.NoError(err) && assert.NotNil(token) {
+		assert.Equal("access-2", token.AccessToken)
+		assert.Equal("refresh", token.RefreshToken)
+	}
+}
@@ -22,11 +22,13 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 
 	api_helpers "github.com/printesoi/e-factura-go/internal/helpers/api"
 	"github.com/printesoi/e-factura-go/internal/ptr"
 	iregexp "github.com/printesoi/e-factura-go/internal/regexp"
 	errors "github.com/printesoi/e-factura-go/pkg/errors"
+	"github.com/printesoi/e-factura-go/pkg/text"
 )
 
 var (
@@ -129,6 +131,40 @@ func ErrorMessageMatchLimitExceeded(err string) (limit int64, match bool) {
 	return
 }
 
+// noMessagesErrorMessages are the (normalized) prefixes of the error messages
+// returned by the messages list APIs when there are no messages in the
+// selected interval. ANAF returns these as errors, although they are not.
+var noMessagesErrorMessages = []string{
+	"nu exista mesaje",
+	"nu au fost gasite mesaje",
+	"nu s-au gasit mesaje",
+	"no messages",
+	"there are no messages",
+}
+
+// normalizeErrorMessage transliterates, lowercases and collapses the spaces
+// of the given error message.
+func normalizeErrorMessage(msg string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text.Transliterate(msg)), " "))
+}
+
+// ErrorMessageMatchNoMessages returns true if the error message returned by
+// a messages list API means that there are no messages (eg. "Nu exista
+// mesaje in ultimele 1 zile"). The match ignores the case, the diacritics and
+// the whitespace differences.
+func ErrorMessageMatchNoMessages(err string) bool {
+	msg := normalizeErrorMessage(err)
+	if msg == "" {
+		return false
+	}
+	for _, prefix := range noMessagesErrorMessages {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func NewErrorResponseDetectType(resp *http.Response) error {
 	data, err := api_helpers.PeekResponseBody(resp)
 	if err == nil && len(data) > 0 {
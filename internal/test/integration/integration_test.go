@@ -0,0 +1,309 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package integration_test is an opt-in integration test suite that runs the
+// full document flows against the ANAF TEST (sandbox) environment. It can be
+// used to certify the credentials and the configuration before go-live. The
+// e-factura tests are run only if EFACTURA_TEST_INTEGRATION=1 and the
+// e-transport tests only if ETRANSPORT_TEST_INTEGRATION=1, in addition to the
+// credentials env variables (see internal/test/efactura and
+// internal/test/etransport):
+//
+//	EFACTURA_TEST_INTEGRATION=1 go test -v -timeout 30m ./internal/test/integration/
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	itestefactura "github.com/printesoi/e-factura-go/internal/test/efactura"
+	itestetransport "github.com/printesoi/e-factura-go/internal/test/etransport"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/etransport"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+const (
+	// statePollInterval is the interval between two message state requests.
+	statePollInterval = 10 * time.Second
+	// statePollTimeout is the maximum time waiting for a message to be
+	// processed by ANAF.
+	statePollTimeout = 15 * time.Minute
+)
+
+// getEnv returns the value of the env variable key, or def if not set.
+func getEnv(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// pollUntil calls done every statePollInterval until it returns true, an
+// error, or statePollTimeout elapses.
+func pollUntil(t *testing.T, ctx context.Context, done func() (bool, error)) bool {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(ctx, statePollTimeout)
+	defer cancel()
+	for {
+		ok, err := done()
+		if err != nil {
+			t.Errorf("polling failed: %v", err)
+			return false
+		}
+		if ok {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			t.Errorf("polling timed out after %s", statePollTimeout)
+			return false
+		case <-time.After(statePollInterval):
+		}
+	}
+}
+
+func setupEfacturaClient(t *testing.T) (*efactura.Client, string) {
+	t.Helper()
+
+	if os.Getenv("EFACTURA_TEST_INTEGRATION") != "1" {
+		t.Skip("Skipping integration test, EFACTURA_TEST_INTEGRATION not set")
+	}
+	client, err := itestefactura.SetupRealClient(false, nil, nil)
+	if err != nil {
+		t.Fatalf("error creating e-factura client: %v", err)
+	}
+	cif := itestefactura.GetTestCIF()
+	if cif == "" {
+		t.Fatal("EFACTURA_TEST_CIF not set")
+	}
+	return client, cif
+}
+
+// buildTestInvoice builds an invoice issued by the test CIF. The parties can
+// be changed using the same EFACTURA_TEST_INVOICE_* env variables as the
+// efactura package tests.
+func buildTestInvoice(t *testing.T, cif string) efactura.Invoice {
+	t.Helper()
+
+	supplier := efactura.InvoiceSupplierParty{
+		PostalAddress: efactura.MakeInvoiceSupplierPostalAddress(efactura.PostalAddress{
+			Country:          efactura.CountryRO,
+			CountrySubentity: efactura.CountrySubentityType(getEnv("EFACTURA_TEST_INVOICE_SUPPLIER_ADDRESS_COUNTRY_SUBENTITY", string(efactura.CountrySubentityRO_B))),
+			CityName:         getEnv("EFACTURA_TEST_INVOICE_SUPPLIER_ADDRESS_CITY_NAME", efactura.CityNameROBSector1),
+			Line1:            getEnv("EFACTURA_TEST_INVOICE_SUPPLIER_ADDRESS_LINE1", "Piata Victoriei 1"),
+		}),
+		TaxScheme: &efactura.InvoicePartyTaxScheme{
+			TaxScheme: efactura.TaxSchemeVAT,
+			CompanyID: getEnv("EFACTURA_TEST_INVOICE_SUPPLIER_COMPANY_ID", "RO"+strings.TrimPrefix(cif, "RO")),
+		},
+		LegalEntity: efactura.InvoiceSupplierLegalEntity{
+			Name:             getEnv("EFACTURA_TEST_INVOICE_SUPPLIER_LEGAL_NAME", "Seller SRL"),
+			CompanyLegalForm: getEnv("EFACTURA_TEST_INVOICE_SUPPLIER_LEGAL_FORM", "J40/12345/1998"),
+		},
+	}
+	customer := efactura.InvoiceCustomerParty{
+		PostalAddress: efactura.MakeInvoiceCustomerPostalAddress(efactura.PostalAddress{
+			Country:          efactura.CountryRO,
+			CountrySubentity: efactura.CountrySubentityType(getEnv("EFACTURA_TEST_INVOICE_CUSTOMER_ADDRESS_COUNTRY_SUBENTITY", string(efactura.CountrySubentityRO_B))),
+			CityName:         getEnv("EFACTURA_TEST_INVOICE_CUSTOMER_ADDRESS_CITY_NAME", efactura.CityNameROBSector1),
+			Line1:            getEnv("EFACTURA_TEST_INVOICE_CUSTOMER_ADDRESS_LINE1", "Piata Victoriei 2"),
+		}),
+		TaxScheme: &efactura.InvoicePartyTaxScheme{
+			TaxScheme: efactura.TaxSchemeVAT,
+			CompanyID: getEnv("EFACTURA_TEST_INVOICE_CUSTOMER_COMPANY_ID", "RO"+strings.TrimPrefix(cif, "RO")),
+		},
+		LegalEntity: efactura.InvoiceCustomerLegalEntity{
+			Name: getEnv("EFACTURA_TEST_INVOICE_CUSTOMER_LEGAL_NAME", "Buyer SRL"),
+		},
+	}
+
+	now := time.Now()
+	today := types.MakeDateFromTime(now)
+	invoice, err := efactura.NewInvoiceBuilder(fmt.Sprintf("IT-%d", now.Unix())).
+		WithIssueDate(today).
+		WithDueDate(types.MakeDateFromTime(now.AddDate(0, 0, 30))).
+		WithDocumentCurrencyCode(efactura.CurrencyRON).
+		WithSupplier(supplier).
+		WithCustomer(customer).
+		WithPaymentMeans(efactura.InvoicePaymentMeans{
+			PaymentMeansCode: efactura.PaymentMeansCode{Code: efactura.PaymentMeansCreditTransfer},
+		}).
+		AppendLineBuilders(efactura.NewInvoiceLineBuilder("", "").
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(1)).
+			WithGrossPriceAmount(types.D(100)).
+			WithItemName("Integration test").
+			WithItemTaxCategory(efactura.InvoiceLineTaxCategory{
+				TaxScheme: efactura.TaxSchemeVAT,
+				ID:        efactura.TaxCategoryVATStandardRate,
+				Percent:   types.D(19),
+			})).
+		WithValidation(true).
+		Build()
+	if err != nil {
+		t.Fatalf("error building the invoice: %v", err)
+	}
+	return invoice
+}
+
+// TestEfacturaUploadDownload runs the upload -> state -> download ->
+// signature validation -> parse flow for an e-factura invoice.
+func TestEfacturaUploadDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	client, cif := setupEfacturaClient(t)
+	ctx := context.Background()
+	invoice := buildTestInvoice(t, cif)
+
+	validateRes, err := client.ValidateInvoice(ctx, invoice)
+	if !assert.NoError(err) || !assert.True(validateRes.IsOk(), validateRes.GetFirstMessage()) {
+		return
+	}
+
+	uploadRes, err := client.UploadInvoice(ctx, invoice, cif)
+	if !assert.NoError(err) || !assert.True(uploadRes.IsOk(), uploadRes.GetFirstErrorMessage()) {
+		return
+	}
+	uploadIndex := uploadRes.GetUploadIndex()
+	t.Logf("uploaded invoice %s: upload index %d, correlation ID %s", invoice.ID, uploadIndex, uploadRes.CorrelationID)
+
+	var state *efactura.GetMessageStateResponse
+	if !pollUntil(t, ctx, func() (bool, error) {
+		state, err = client.GetMessageState(ctx, uploadIndex)
+		return err == nil && !state.IsProcessing(), err
+	}) {
+		return
+	}
+	if !assert.True(state.IsOk(), "message state %s: %s", state.State, state.GetFirstErrorMessage()) {
+		return
+	}
+
+	downloadRes, err := client.DownloadInvoiceParseZip(ctx, state.GetDownloadID())
+	if !assert.NoError(err) || !assert.True(downloadRes.IsOk()) {
+		return
+	}
+	if assert.NotNil(downloadRes.Invoice) {
+		assert.Equal(invoice.ID, downloadRes.Invoice.ID)
+		assert.True(invoice.LegalMonetaryTotal.PayableAmount.Amount.Equal(downloadRes.Invoice.LegalMonetaryTotal.PayableAmount.Amount))
+	}
+
+	signatureRes, err := client.ValidateSignatureZipData(ctx, downloadRes.DownloadResponse.Zip)
+	if assert.NoError(err) {
+		assert.True(signatureRes.IsValid(), signatureRes.Message)
+	}
+}
+
+func setupEtransportClient(t *testing.T) (*etransport.Client, string) {
+	t.Helper()
+
+	if os.Getenv("ETRANSPORT_TEST_INTEGRATION") != "1" {
+		t.Skip("Skipping integration test, ETRANSPORT_TEST_INTEGRATION not set")
+	}
+	client, err := itestetransport.SetupRealClient(false, nil, nil)
+	if err != nil {
+		t.Fatalf("error creating e-transport client: %v", err)
+	}
+	cif := itestetransport.GetTestCIF()
+	if cif == "" {
+		t.Fatal("ETRANSPORT_TEST_CIF not set")
+	}
+	return client, cif
+}
+
+// TestEtransportUploadDownload runs the upload -> state -> download flow for
+// an e-transport notification declaration, and then deletes the declaration.
+func TestEtransportUploadDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	client, cif := setupEtransportClient(t)
+	ctx := context.Background()
+
+	tomorrow := types.MakeDateFromTime(time.Now().AddDate(0, 0, 1))
+	location := &etransport.PostingDeclationLocation{
+		CountyCode:   etransport.CountyCodeB,
+		LocalityName: "Bucuresti",
+		StreetName:   "Piata Victoriei",
+	}
+	var decl etransport.PostingDeclarationV2
+	decl.DeclarantCode = cif
+	decl.DeclarantRef = fmt.Sprintf("IT-%d", time.Now().Unix())
+	decl.SetNotification(etransport.PostingDeclarationNotification{
+		OpType: etransport.OpTypeTTN,
+		TransportedGoods: []etransport.PostingDeclarationNotificationTransportedGood{{
+			OpPurposeCode:   etransport.OpPurposeCodeTypeCommercialization,
+			TariffCode:      "07019050",
+			GoodName:        "Cartofi",
+			Quantity:        types.D(1000),
+			UnitMeasureCode: etransport.UnitCodeType("KGM"),
+			GrossWeight:     types.D(1000),
+		}},
+		CommercialPartner: etransport.PostingDeclarationNotificationCommercialPartner{
+			CountryCode: etransport.CountryCodeRO,
+			Code:        cif,
+			Name:        "Partner SRL",
+		},
+		TransportData: etransport.PostingDeclarationNotificationTransportData{
+			LicensePlate:            "B100ABC",
+			TransportOrgCountryCode: etransport.CountryCodeRO,
+			TransportOrgCode:        cif,
+			TransportOrgName:        "Transport SRL",
+			TransportDate:           tomorrow,
+		},
+		RouteStartPlace: etransport.PostingDeclationPlace{Location: location},
+		RouteEndPlace:   etransport.PostingDeclationPlace{Location: location},
+		TransportDocuments: []etransport.PostingDeclarationTransportDocument{{
+			DocumentType: etransport.DocumentTypeCMR,
+			DocumentNo:   decl.DeclarantRef,
+			DocumentDate: tomorrow,
+		}},
+	})
+
+	uploadRes, err := client.UploadPostingDeclaration(ctx, decl, cif)
+	if !assert.NoError(err) || !assert.True(uploadRes.IsOk(), uploadRes.GetFirstErrorMessage()) {
+		return
+	}
+	uploadIndex, uit := uploadRes.GetUploadIndex(), uploadRes.GetUIT()
+	assert.NotEmpty(uit)
+	t.Logf("uploaded declaration %s: upload index %d, UIT %s", decl.DeclarantRef, uploadIndex, uit)
+
+	var state *etransport.GetMessageStateResponse
+	if !pollUntil(t, ctx, func() (bool, error) {
+		state, err = client.GetMessageState(ctx, uploadIndex)
+		return err == nil && !state.IsProcessing(), err
+	}) {
+		return
+	}
+	if !assert.True(state.IsOk(), "message state %s: %s", state.State, state.GetFirstErrorMessage()) {
+		return
+	}
+
+	message, err := client.DownloadDeclaration(ctx, cif, uploadIndex, 1)
+	if assert.NoError(err) {
+		assert.True(message.IsOk())
+		assert.Equal(uit, message.GetUIT())
+	}
+
+	deleteRes, err := client.DeleteTransport(ctx, cif, uit)
+	if assert.NoError(err) {
+		assert.True(deleteRes.IsOk(), deleteRes.GetFirstErrorMessage())
+	}
+}
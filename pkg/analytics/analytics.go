@@ -0,0 +1,320 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package analytics computes common aggregates (revenue per month and per
+// partner, VAT by category, average payment terms) from a set of parsed
+// invoices, for dashboards built on top of the SDK.
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// PartnerRole selects the party of the invoice considered the partner.
+type PartnerRole int
+
+const (
+	// PartnerCustomer uses the customer as partner (for sent invoices).
+	PartnerCustomer PartnerRole = iota
+	// PartnerSupplier uses the supplier as partner (for received
+	// invoices).
+	PartnerSupplier
+)
+
+// Config is the config used for computing the report.
+type Config struct {
+	// Partner is the party of the invoice considered the partner. Default
+	// is PartnerCustomer.
+	Partner PartnerRole
+	// MonthLayout is the layout used for formatting the month keys. Default
+	// is "2006-01".
+	MonthLayout string
+}
+
+// ConfigOption allows gradually modifying a Config
+type ConfigOption func(*Config)
+
+// WithPartner sets the party considered the partner.
+func WithPartner(role PartnerRole) ConfigOption {
+	return func(c *Config) {
+		c.Partner = role
+	}
+}
+
+// WithMonthLayout sets the layout used for formatting the month keys.
+func WithMonthLayout(layout string) ConfigOption {
+	return func(c *Config) {
+		c.MonthLayout = layout
+	}
+}
+
+// Totals are the aggregated amounts of a set of invoices, in the document
+// currency. Credit notes are subtracted.
+type Totals struct {
+	Currency efactura.CurrencyCodeType
+	// InvoiceCount is the number of invoices (including credit notes).
+	InvoiceCount int
+	// NetAmount is the sum of the amounts without VAT (BT-109).
+	NetAmount types.Decimal
+	// VATAmount is the sum of the VAT amounts (BT-110).
+	VATAmount types.Decimal
+	// GrossAmount is the sum of the amounts with VAT (BT-112).
+	GrossAmount types.Decimal
+}
+
+func (t *Totals) add(sign types.Decimal, net, vat, gross types.Decimal) {
+	t.InvoiceCount++
+	t.NetAmount = t.NetAmount.Add(net.Mul(sign))
+	t.VATAmount = t.VATAmount.Add(vat.Mul(sign))
+	t.GrossAmount = t.GrossAmount.Add(gross.Mul(sign))
+}
+
+// MonthRevenue is the revenue for a month (by issue date).
+type MonthRevenue struct {
+	Month string
+	Totals
+}
+
+// PartnerRevenue is the revenue for a partner.
+type PartnerRevenue struct {
+	// PartnerID is the VAT identifier of the partner, or the legal
+	// registration ID if the partner has no VAT identifier.
+	PartnerID   string
+	PartnerName string
+	Totals
+}
+
+// VATCategory is the VAT breakdown for a VAT category and rate.
+type VATCategory struct {
+	Category efactura.TaxCategoryCodeType
+	Percent  types.Decimal
+	Currency efactura.CurrencyCodeType
+	// TaxableAmount is the sum of the taxable amounts (BT-116).
+	TaxableAmount types.Decimal
+	// TaxAmount is the sum of the VAT amounts (BT-117).
+	TaxAmount types.Decimal
+}
+
+// PaymentTerms are the statistics for the payment terms (the number of days
+// between the issue date and the due date).
+type PaymentTerms struct {
+	// InvoiceCount is the number of invoices with a due date.
+	InvoiceCount int
+	// AverageDays is the average number of days between the issue date and
+	// the due date.
+	AverageDays float64
+	// MinDays is the minimum number of days.
+	MinDays int
+	// MaxDays is the maximum number of days.
+	MaxDays int
+}
+
+// Report is the result of Compute.
+type Report struct {
+	// RevenueByMonth is sorted by month and currency.
+	RevenueByMonth []MonthRevenue
+	// RevenueByPartner is sorted by net amount (descending).
+	RevenueByPartner []PartnerRevenue
+	// VATByCategory is sorted by currency, category and percent.
+	VATByCategory []VATCategory
+	PaymentTerms  PaymentTerms
+}
+
+// TopPartners returns the first n partners by net amount.
+func (r Report) TopPartners(n int) []PartnerRevenue {
+	if n > len(r.RevenueByPartner) {
+		n = len(r.RevenueByPartner)
+	}
+	return append([]PartnerRevenue(nil), r.RevenueByPartner[:n]...)
+}
+
+type currencyKey struct {
+	key      string
+	currency efactura.CurrencyCodeType
+}
+
+type vatKey struct {
+	category efactura.TaxCategoryCodeType
+	percent  string
+	currency efactura.CurrencyCodeType
+}
+
+// Compute computes the aggregates for the given invoices. The amounts are
+// aggregated separately per document currency. Credit notes (type code 381)
+// are subtracted.
+func Compute(invoices []efactura.Invoice, opts ...ConfigOption) (report Report) {
+	cfg := Config{
+		Partner:     PartnerCustomer,
+		MonthLayout: "2006-01",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	months := make(map[currencyKey]*MonthRevenue)
+	partners := make(map[currencyKey]*PartnerRevenue)
+	vats := make(map[vatKey]*VATCategory)
+	var termDays []int
+
+	for _, iv := range invoices {
+		sign := types.D(1)
+		if iv.InvoiceTypeCode == efactura.InvoiceTypeCreditNote {
+			sign = types.D(-1)
+		}
+		currency := iv.DocumentCurrencyCode
+		net := iv.LegalMonetaryTotal.TaxExclusiveAmount.Amount
+		gross := iv.LegalMonetaryTotal.TaxInclusiveAmount.Amount
+		vat := types.Zero
+		for _, taxTotal := range iv.TaxTotal {
+			if taxTotal.TaxAmount == nil || taxTotal.TaxAmount.CurrencyID != currency {
+				// Tax total in the tax currency (BT-111).
+				continue
+			}
+			vat = vat.Add(taxTotal.TaxAmount.Amount)
+			for _, subtotal := range taxTotal.TaxSubtotals {
+				key := vatKey{
+					category: subtotal.TaxCategory.ID,
+					percent:  subtotal.TaxCategory.Percent.String(),
+					currency: currency,
+				}
+				v, ok := vats[key]
+				if !ok {
+					v = &VATCategory{
+						Category:      key.category,
+						Percent:       subtotal.TaxCategory.Percent,
+						Currency:      currency,
+						TaxableAmount: types.Zero,
+						TaxAmount:     types.Zero,
+					}
+					vats[key] = v
+				}
+				v.TaxableAmount = v.TaxableAmount.Add(subtotal.TaxableAmount.Amount.Mul(sign))
+				v.TaxAmount = v.TaxAmount.Add(subtotal.TaxAmount.Amount.Mul(sign))
+			}
+		}
+
+		monthKey := currencyKey{key: iv.IssueDate.Format(cfg.MonthLayout), currency: currency}
+		m, ok := months[monthKey]
+		if !ok {
+			m = &MonthRevenue{Month: monthKey.key, Totals: newTotals(currency)}
+			months[monthKey] = m
+		}
+		m.add(sign, net, vat, gross)
+
+		partnerID, partnerName := invoicePartner(iv, cfg.Partner)
+		partnerKey := currencyKey{key: partnerID, currency: currency}
+		p, ok := partners[partnerKey]
+		if !ok {
+			p = &PartnerRevenue{PartnerID: partnerID, PartnerName: partnerName, Totals: newTotals(currency)}
+			partners[partnerKey] = p
+		}
+		p.add(sign, net, vat, gross)
+
+		if iv.DueDate != nil && !iv.IssueDate.IsZero() {
+			termDays = append(termDays, int(math.Round(iv.DueDate.Sub(iv.IssueDate.Time).Hours()/24)))
+		}
+	}
+
+	for _, m := range months {
+		report.RevenueByMonth = append(report.RevenueByMonth, *m)
+	}
+	sort.Slice(report.RevenueByMonth, func(i, j int) bool {
+		a, b := report.RevenueByMonth[i], report.RevenueByMonth[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		return a.Currency < b.Currency
+	})
+
+	for _, p := range partners {
+		report.RevenueByPartner = append(report.RevenueByPartner, *p)
+	}
+	sort.Slice(report.RevenueByPartner, func(i, j int) bool {
+		a, b := report.RevenueByPartner[i], report.RevenueByPartner[j]
+		if !a.NetAmount.Equal(b.NetAmount) {
+			return a.NetAmount.GreaterThan(b.NetAmount.Decimal)
+		}
+		if a.PartnerID != b.PartnerID {
+			return a.PartnerID < b.PartnerID
+		}
+		return a.Currency < b.Currency
+	})
+
+	for _, v := range vats {
+		report.VATByCategory = append(report.VATByCategory, *v)
+	}
+	sort.Slice(report.VATByCategory, func(i, j int) bool {
+		a, b := report.VATByCategory[i], report.VATByCategory[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Percent.LessThan(b.Percent.Decimal)
+	})
+
+	if len(termDays) > 0 {
+		terms := PaymentTerms{
+			InvoiceCount: len(termDays),
+			MinDays:      termDays[0],
+			MaxDays:      termDays[0],
+		}
+		sum := 0
+		for _, days := range termDays {
+			sum += days
+			terms.MinDays = min(terms.MinDays, days)
+			terms.MaxDays = max(terms.MaxDays, days)
+		}
+		terms.AverageDays = float64(sum) / float64(len(termDays))
+		report.PaymentTerms = terms
+	}
+	return
+}
+
+func newTotals(currency efactura.CurrencyCodeType) Totals {
+	return Totals{
+		Currency:    currency,
+		NetAmount:   types.Zero,
+		VATAmount:   types.Zero,
+		GrossAmount: types.Zero,
+	}
+}
+
+// invoicePartner returns the ID and name of the partner of the invoice.
+func invoicePartner(iv efactura.Invoice, role PartnerRole) (id, name string) {
+	var taxScheme *efactura.InvoicePartyTaxScheme
+	var companyID *efactura.ValueWithAttrs
+	switch role {
+	case PartnerSupplier:
+		party := iv.Supplier.Party
+		taxScheme, companyID, name = party.TaxScheme, party.LegalEntity.CompanyID, party.LegalEntity.Name
+	default:
+		party := iv.Customer.Party
+		taxScheme, companyID, name = party.TaxScheme, party.LegalEntity.CompanyID, party.LegalEntity.Name
+	}
+	switch {
+	case taxScheme != nil && taxScheme.CompanyID != "":
+		id = taxScheme.CompanyID
+	case companyID != nil && companyID.Value != "":
+		id = companyID.Value
+	default:
+		id = name
+	}
+	return
+}
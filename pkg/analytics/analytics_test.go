@@ -0,0 +1,127 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package analytics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/analytics"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func newTestInvoice(typeCode efactura.InvoiceTypeCodeType, issueDate types.Date, dueDays int,
+	customerCIF, customerName string, net, vatPercent float64,
+) efactura.Invoice {
+	vat := types.D(net).Mul(types.D(vatPercent)).Div(types.D(100)).Round(2)
+	iv := efactura.Invoice{
+		InvoiceTypeCode:      typeCode,
+		IssueDate:            issueDate,
+		DocumentCurrencyCode: efactura.CurrencyRON,
+		TaxTotal: []efactura.InvoiceTaxTotal{{
+			TaxAmount: &efactura.AmountWithCurrency{Amount: vat, CurrencyID: efactura.CurrencyRON},
+			TaxSubtotals: []efactura.InvoiceTaxSubtotal{{
+				TaxableAmount: efactura.AmountWithCurrency{Amount: types.D(net), CurrencyID: efactura.CurrencyRON},
+				TaxAmount:     efactura.AmountWithCurrency{Amount: vat, CurrencyID: efactura.CurrencyRON},
+				TaxCategory: efactura.InvoiceTaxCategory{
+					TaxScheme: efactura.TaxSchemeVAT,
+					ID:        efactura.TaxCategoryVATStandardRate,
+					Percent:   types.D(vatPercent),
+				},
+			}},
+		}},
+		LegalMonetaryTotal: efactura.InvoiceLegalMonetaryTotal{
+			TaxExclusiveAmount: efactura.AmountWithCurrency{Amount: types.D(net), CurrencyID: efactura.CurrencyRON},
+			TaxInclusiveAmount: efactura.AmountWithCurrency{Amount: types.D(net).Add(vat), CurrencyID: efactura.CurrencyRON},
+		},
+	}
+	iv.Customer.Party.LegalEntity.Name = customerName
+	iv.Customer.Party.TaxScheme = &efactura.InvoicePartyTaxScheme{
+		TaxScheme: efactura.TaxSchemeVAT,
+		CompanyID: customerCIF,
+	}
+	if dueDays > 0 {
+		iv.DueDate = types.NewDate(issueDate.Year(), issueDate.Month(), issueDate.Day()+dueDays)
+	}
+	return iv
+}
+
+func TestCompute(t *testing.T) {
+	assert := assert.New(t)
+
+	invoices := []efactura.Invoice{
+		newTestInvoice(efactura.InvoiceTypeCommercialInvoice, types.MakeDate(2024, 3, 5), 30, "RO1", "Alpha", 100, 19),
+		newTestInvoice(efactura.InvoiceTypeCommercialInvoice, types.MakeDate(2024, 3, 20), 15, "RO2", "Beta", 300, 19),
+		newTestInvoice(efactura.InvoiceTypeCommercialInvoice, types.MakeDate(2024, 4, 2), 0, "RO1", "Alpha", 50, 9),
+		newTestInvoice(efactura.InvoiceTypeCreditNote, types.MakeDate(2024, 4, 10), 0, "RO2", "Beta", 100, 19),
+	}
+	report := analytics.Compute(invoices)
+
+	if assert.Len(report.RevenueByMonth, 2) {
+		march := report.RevenueByMonth[0]
+		assert.Equal("2024-03", march.Month)
+		assert.Equal(2, march.InvoiceCount)
+		assert.Equal("400.00", march.NetAmount.StringFixed(2))
+		assert.Equal("76.00", march.VATAmount.StringFixed(2))
+
+		april := report.RevenueByMonth[1]
+		assert.Equal("2024-04", april.Month)
+		assert.Equal("-50.00", april.NetAmount.StringFixed(2))
+	}
+
+	if assert.Len(report.RevenueByPartner, 2) {
+		assert.Equal("RO2", report.RevenueByPartner[0].PartnerID)
+		assert.Equal("Beta", report.RevenueByPartner[0].PartnerName)
+		assert.Equal("200.00", report.RevenueByPartner[0].NetAmount.StringFixed(2))
+		assert.Equal("RO1", report.RevenueByPartner[1].PartnerID)
+		assert.Equal("150.00", report.RevenueByPartner[1].NetAmount.StringFixed(2))
+	}
+	assert.Len(report.TopPartners(1), 1)
+	assert.Len(report.TopPartners(5), 2)
+
+	if assert.Len(report.VATByCategory, 2) {
+		assert.Equal("9", report.VATByCategory[0].Percent.String())
+		assert.Equal("4.50", report.VATByCategory[0].TaxAmount.StringFixed(2))
+		assert.Equal("19", report.VATByCategory[1].Percent.String())
+		assert.Equal("300.00", report.VATByCategory[1].TaxableAmount.StringFixed(2))
+		assert.Equal("57.00", report.VATByCategory[1].TaxAmount.StringFixed(2))
+	}
+
+	assert.Equal(2, report.PaymentTerms.InvoiceCount)
+	assert.Equal(22.5, report.PaymentTerms.AverageDays)
+	assert.Equal(15, report.PaymentTerms.MinDays)
+	assert.Equal(30, report.PaymentTerms.MaxDays)
+
+	var buf bytes.Buffer
+	if assert.NoError(report.WriteRevenueByPartnerCSV(&buf)) {
+		assert.Equal("partner_id,partner_name,currency,invoices,net_amount,vat_amount,gross_amount\n"+
+			"RO2,Beta,RON,2,200.00,38.00,238.00\n"+
+			"RO1,Alpha,RON,2,150.00,23.50,173.50\n", buf.String())
+	}
+	buf.Reset()
+	if assert.NoError(report.WriteRevenueByMonthCSV(&buf)) {
+		assert.Contains(buf.String(), "2024-03,RON,2,400.00,76.00,476.00\n")
+	}
+	buf.Reset()
+	if assert.NoError(report.WriteVATByCategoryCSV(&buf)) {
+		assert.Contains(buf.String(), "S,19,RON,300.00,57.00\n")
+	}
+
+	report = analytics.Compute(invoices, analytics.WithPartner(analytics.PartnerSupplier))
+	assert.Len(report.RevenueByPartner, 1)
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func (t Totals) csvFields() []string {
+	return []string{
+		string(t.Currency),
+		strconv.Itoa(t.InvoiceCount),
+		t.NetAmount.StringFixed(2),
+		t.VATAmount.StringFixed(2),
+		t.GrossAmount.StringFixed(2),
+	}
+}
+
+var totalsCSVHeader = []string{"currency", "invoices", "net_amount", "vat_amount", "gross_amount"}
+
+// WriteRevenueByMonthCSV writes the revenue by month as CSV (with a header
+// row) to w.
+func (r Report) WriteRevenueByMonthCSV(w io.Writer) error {
+	rows := make([][]string, 0, len(r.RevenueByMonth))
+	for _, m := range r.RevenueByMonth {
+		rows = append(rows, append([]string{m.Month}, m.csvFields()...))
+	}
+	return writeCSV(w, append([]string{"month"}, totalsCSVHeader...), rows)
+}
+
+// WriteRevenueByPartnerCSV writes the revenue by partner as CSV (with a
+// header row) to w.
+func (r Report) WriteRevenueByPartnerCSV(w io.Writer) error {
+	rows := make([][]string, 0, len(r.RevenueByPartner))
+	for _, p := range r.RevenueByPartner {
+		rows = append(rows, append([]string{p.PartnerID, p.PartnerName}, p.csvFields()...))
+	}
+	return writeCSV(w, append([]string{"partner_id", "partner_name"}, totalsCSVHeader...), rows)
+}
+
+// WriteVATByCategoryCSV writes the VAT breakdown by category as CSV (with a
+// header row) to w.
+func (r Report) WriteVATByCategoryCSV(w io.Writer) error {
+	rows := make([][]string, 0, len(r.VATByCategory))
+	for _, v := range r.VATByCategory {
+		rows = append(rows, []string{
+			string(v.Category),
+			v.Percent.String(),
+			string(v.Currency),
+			v.TaxableAmount.StringFixed(2),
+			v.TaxAmount.StringFixed(2),
+		})
+	}
+	return writeCSV(w, []string{"category", "percent", "currency", "taxable_amount", "tax_amount"}, rows)
+}
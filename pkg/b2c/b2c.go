@@ -0,0 +1,238 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package b2c generates the consumer-facing verification payload of the
+// invoices issued to consumers (B2C), usually printed as a QR code on the
+// receipt or on the PDF, allowing the consumer to retrieve the invoice.
+//
+// The format of the payload is not yet specified by ANAF, so it's pluggable
+// (see PayloadFormat). LinkFormat is a provisional format (a link with the
+// invoice identification as query parameters). This package does not
+// implement the QR code symbology, a QR library can be plugged in with
+// QREncoder.
+package b2c
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+var (
+	// ErrNotConsumerInvoice is returned by Generator for invoices that are
+	// not issued to consumers (see IsConsumerInvoice).
+	ErrNotConsumerInvoice = errors.New("b2c: not a consumer invoice")
+	// ErrNoQREncoder is returned by Generator.QRCode if no QREncoder is set.
+	ErrNoQREncoder = errors.New("b2c: no QR encoder")
+)
+
+// Reference identifies an invoice uploaded to the ANAF e-factura system.
+type Reference struct {
+	// UploadIndex is the upload index (index_incarcare) returned when the
+	// invoice was uploaded.
+	UploadIndex int64
+	// DownloadID is the download ID (id_descarcare) of the invoice
+	// (optional), known after the invoice was processed.
+	DownloadID int64
+}
+
+// PayloadFormat builds the verification payload of an invoice.
+type PayloadFormat interface {
+	Payload(iv *efactura.Invoice, ref Reference) (string, error)
+}
+
+// PayloadFormatFunc is an adapter allowing a function to be used as a
+// PayloadFormat.
+type PayloadFormatFunc func(iv *efactura.Invoice, ref Reference) (string, error)
+
+// Payload implements the PayloadFormat interface.
+func (f PayloadFormatFunc) Payload(iv *efactura.Invoice, ref Reference) (string, error) {
+	return f(iv, ref)
+}
+
+// The query parameters of the links built by LinkFormat.
+const (
+	LinkParamSupplier    = "cif"
+	LinkParamNumber      = "nr"
+	LinkParamIssueDate   = "data"
+	LinkParamTotal       = "total"
+	LinkParamCurrency    = "moneda"
+	LinkParamUploadIndex = "index"
+	LinkParamDownloadID  = "id"
+)
+
+// LinkFormat is a provisional PayloadFormat building a link to BaseURL with
+// the invoice identification as query parameters: the Seller identifier, the
+// invoice number, the issue date, the amount due for payment, the currency
+// and the upload index (and the download ID, if set). Eg.
+//
+//	https://example.com/b2c?cif=RO1234567&data=2024-04-01&index=5001234567&moneda=RON&nr=F-1&total=119.00
+type LinkFormat struct {
+	// BaseURL is the URL of the page where the consumer can retrieve the
+	// invoice.
+	BaseURL string
+}
+
+// Payload implements the PayloadFormat interface.
+func (f LinkFormat) Payload(iv *efactura.Invoice, ref Reference) (string, error) {
+	u, err := url.Parse(f.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("b2c: invalid base URL: %w", err)
+	}
+	if !u.IsAbs() {
+		return "", fmt.Errorf("b2c: base URL %q must be an absolute URL", f.BaseURL)
+	}
+	if ref.UploadIndex <= 0 {
+		return "", errors.New("b2c: missing upload index")
+	}
+	supplier := iv.SupplierVATID()
+	if supplier == "" {
+		supplier = iv.Supplier.Party.LegalRegistrationID()
+	}
+	if supplier == "" || iv.ID == "" || !iv.IssueDate.IsInitialized() {
+		return "", errors.New("b2c: the invoice must have the Seller identifier, the number and the issue date")
+	}
+
+	query := u.Query()
+	query.Set(LinkParamSupplier, supplier)
+	query.Set(LinkParamNumber, iv.ID)
+	query.Set(LinkParamIssueDate, iv.IssueDate.Format(time.DateOnly))
+	query.Set(LinkParamTotal, iv.LegalMonetaryTotal.PayableAmount.Amount.StringFixed(2))
+	query.Set(LinkParamCurrency, string(iv.DocumentCurrencyCode))
+	query.Set(LinkParamUploadIndex, strconv.FormatInt(ref.UploadIndex, 10))
+	if ref.DownloadID > 0 {
+		query.Set(LinkParamDownloadID, strconv.FormatInt(ref.DownloadID, 10))
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// QREncoder encodes a payload as a QR code image, eg. an adapter for a QR
+// code library.
+type QREncoder interface {
+	EncodeQR(payload string) (image.Image, error)
+}
+
+// QREncoderFunc is an adapter allowing a function to be used as a
+// QREncoder.
+type QREncoderFunc func(payload string) (image.Image, error)
+
+// EncodeQR implements the QREncoder interface.
+func (f QREncoderFunc) EncodeQR(payload string) (image.Image, error) {
+	return f(payload)
+}
+
+// GeneratorConfig is the config used to create a Generator.
+type GeneratorConfig struct {
+	// Format is the format of the payload (required).
+	Format PayloadFormat
+	// QREncoder is the encoder used by Generator.QRCode (optional).
+	QREncoder QREncoder
+	// AllowBusinessInvoices allows generating the payload for the invoices
+	// that are not issued to consumers.
+	AllowBusinessInvoices bool
+}
+
+// GeneratorConfigOption allows gradually modifying a GeneratorConfig.
+type GeneratorConfigOption func(*GeneratorConfig)
+
+// GeneratorFormat sets the format of the payload.
+func GeneratorFormat(format PayloadFormat) GeneratorConfigOption {
+	return func(c *GeneratorConfig) {
+		c.Format = format
+	}
+}
+
+// GeneratorQREncoder sets the encoder used for the QR code images.
+func GeneratorQREncoder(encoder QREncoder) GeneratorConfigOption {
+	return func(c *GeneratorConfig) {
+		c.QREncoder = encoder
+	}
+}
+
+// GeneratorAllowBusinessInvoices allows generating the payload for the
+// invoices that are not issued to consumers.
+func GeneratorAllowBusinessInvoices(allow bool) GeneratorConfigOption {
+	return func(c *GeneratorConfig) {
+		c.AllowBusinessInvoices = allow
+	}
+}
+
+// Generator generates the verification payload and QR code of the consumer
+// invoices.
+type Generator struct {
+	config GeneratorConfig
+}
+
+// NewGenerator creates a new Generator.
+func NewGenerator(opts ...GeneratorConfigOption) (*Generator, error) {
+	var cfg GeneratorConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Format == nil {
+		return nil, errors.New("b2c: missing payload format")
+	}
+	return &Generator{config: cfg}, nil
+}
+
+// Payload returns the verification payload of the invoice. ErrNotConsumerInvoice
+// is returned if the invoice is not issued to a consumer, unless
+// GeneratorAllowBusinessInvoices is set.
+func (g *Generator) Payload(iv *efactura.Invoice, ref Reference) (string, error) {
+	if !g.config.AllowBusinessInvoices && !IsConsumerInvoice(iv) {
+		return "", ErrNotConsumerInvoice
+	}
+	return g.config.Format.Payload(iv, ref)
+}
+
+// QRCode returns the QR code image of the verification payload of the
+// invoice (eg. for printing on the receipt). ErrNoQREncoder is returned if
+// no QREncoder is set.
+func (g *Generator) QRCode(iv *efactura.Invoice, ref Reference) (image.Image, error) {
+	if g.config.QREncoder == nil {
+		return nil, ErrNoQREncoder
+	}
+	payload, err := g.Payload(iv, ref)
+	if err != nil {
+		return nil, err
+	}
+	return g.config.QREncoder.EncodeQR(payload)
+}
+
+// IsConsumerInvoice returns true if the invoice is issued to a consumer (a
+// natural person): the Buyer has no VAT identifier and the Buyer legal
+// registration identifier (BT-47) is a CNP (13 digits, including the
+// "0000000000000" placeholder used when the consumer doesn't provide it).
+func IsConsumerInvoice(iv *efactura.Invoice) bool {
+	if iv.CustomerVATID() != "" {
+		return false
+	}
+	id := strings.TrimSpace(iv.Customer.Party.LegalRegistrationID())
+	if len(id) != 13 {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package b2c_test
+
+import (
+	"errors"
+	"image"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/b2c"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func testConsumerInvoice(buyerID string) *efactura.Invoice {
+	var iv efactura.Invoice
+	iv.ID = "F-1"
+	iv.IssueDate = types.MakeDate(2024, 4, 1)
+	iv.DocumentCurrencyCode = efactura.CurrencyRON
+	iv.Supplier.Party.TaxScheme = &efactura.InvoicePartyTaxScheme{
+		CompanyID: "RO1234567",
+		TaxScheme: efactura.TaxScheme{ID: efactura.TaxSchemeIDVAT},
+	}
+	if buyerID != "" {
+		companyID := efactura.MakeValueWithAttrs(buyerID)
+		iv.Customer.Party.LegalEntity.CompanyID = &companyID
+	}
+	iv.LegalMonetaryTotal.PayableAmount = efactura.AmountWithCurrency{
+		Amount:     types.D(119),
+		CurrencyID: efactura.CurrencyRON,
+	}
+	return &iv
+}
+
+func TestIsConsumerInvoice(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(b2c.IsConsumerInvoice(testConsumerInvoice("1800101221144")))
+	assert.True(b2c.IsConsumerInvoice(testConsumerInvoice("0000000000000")))
+	assert.False(b2c.IsConsumerInvoice(testConsumerInvoice("")))
+	assert.False(b2c.IsConsumerInvoice(testConsumerInvoice("J40/1/2020")))
+
+	iv := testConsumerInvoice("1800101221144")
+	iv.Customer.Party.TaxScheme = &efactura.InvoicePartyTaxScheme{
+		CompanyID: "RO7654321",
+		TaxScheme: efactura.TaxScheme{ID: efactura.TaxSchemeIDVAT},
+	}
+	assert.False(b2c.IsConsumerInvoice(iv))
+}
+
+func TestLinkFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	format := b2c.LinkFormat{BaseURL: "https://example.com/b2c?src=qr"}
+	payload, err := format.Payload(testConsumerInvoice("0000000000000"), b2c.Reference{UploadIndex: 5001234567})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("https://example.com/b2c?cif=RO1234567&data=2024-04-01&index=5001234567&moneda=RON&nr=F-1&src=qr&total=119.00", payload)
+
+	payload, err = format.Payload(testConsumerInvoice("0000000000000"), b2c.Reference{UploadIndex: 1, DownloadID: 2})
+	if assert.NoError(err) {
+		u, err := url.Parse(payload)
+		if assert.NoError(err) {
+			assert.Equal("2", u.Query().Get(b2c.LinkParamDownloadID))
+		}
+	}
+
+	_, err = format.Payload(testConsumerInvoice("0000000000000"), b2c.Reference{})
+	assert.ErrorContains(err, "upload index")
+	_, err = b2c.LinkFormat{BaseURL: "/b2c"}.Payload(testConsumerInvoice("0000000000000"), b2c.Reference{UploadIndex: 1})
+	assert.ErrorContains(err, "absolute")
+	_, err = format.Payload(&efactura.Invoice{}, b2c.Reference{UploadIndex: 1})
+	assert.Error(err)
+}
+
+func TestGenerator(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := b2c.NewGenerator()
+	assert.Error(err)
+
+	format := b2c.PayloadFormatFunc(func(iv *efactura.Invoice, ref b2c.Reference) (string, error) {
+		return iv.ID, nil
+	})
+	g, err := b2c.NewGenerator(b2c.GeneratorFormat(format))
+	if !assert.NoError(err) {
+		return
+	}
+	payload, err := g.Payload(testConsumerInvoice("0000000000000"), b2c.Reference{})
+	assert.NoError(err)
+	assert.Equal("F-1", payload)
+	_, err = g.Payload(testConsumerInvoice("J40/1/2020"), b2c.Reference{})
+	assert.ErrorIs(err, b2c.ErrNotConsumerInvoice)
+	_, err = g.QRCode(testConsumerInvoice("0000000000000"), b2c.Reference{})
+	assert.ErrorIs(err, b2c.ErrNoQREncoder)
+
+	var encoded string
+	g, err = b2c.NewGenerator(
+		b2c.GeneratorFormat(format),
+		b2c.GeneratorAllowBusinessInvoices(true),
+		b2c.GeneratorQREncoder(b2c.QREncoderFunc(func(payload string) (image.Image, error) {
+			encoded = payload
+			return image.NewGray(image.Rect(0, 0, 21, 21)), nil
+		})))
+	if !assert.NoError(err) {
+		return
+	}
+	img, err := g.QRCode(testConsumerInvoice("J40/1/2020"), b2c.Reference{})
+	if assert.NoError(err) {
+		assert.Equal(21, img.Bounds().Dx())
+	}
+	assert.Equal("F-1", encoded)
+
+	errFormat := errors.New("format error")
+	g, err = b2c.NewGenerator(b2c.GeneratorFormat(b2c.PayloadFormatFunc(func(*efactura.Invoice, b2c.Reference) (string, error) {
+		return "", errFormat
+	})), b2c.GeneratorQREncoder(b2c.QREncoderFunc(func(string) (image.Image, error) {
+		t.Fatal("unexpected call")
+		return nil, nil
+	})))
+	if assert.NoError(err) {
+		_, err = g.QRCode(testConsumerInvoice("0000000000000"), b2c.Reference{})
+		assert.ErrorIs(err, errFormat)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package bnr implements a client for the reference exchange rates (cursul
+// valutar) published daily by the National Bank of Romania (BNR). The rates
+// are used to compute the invoice total VAT amount in RON (BT-111) of the
+// invoices issued in another currency (see ConvertInvoiceTaxToRON).
+package bnr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the BNR website.
+	DefaultBaseURL = "https://www.bnr.ro/"
+
+	latestRatesEndpoint     = "nbrfxrates.xml"
+	yearRatesEndpointFormat = "files/xml/years/nbrfxrates%d.xml"
+)
+
+var (
+	// ErrRateNotFound is returned if there is no exchange rate for the
+	// currency at the given date.
+	ErrRateNotFound = errors.New("bnr: exchange rate not found")
+)
+
+// ClientConfig is the config used to create a Client.
+type ClientConfig struct {
+	// PublicApiClient is the client used to make the requests (optional).
+	// If nil, a client with the base URL DefaultBaseURL is created.
+	PublicApiClient *client.PublicApiClient
+}
+
+// ClientConfigOption allows gradually modifying a ClientConfig.
+type ClientConfigOption func(*ClientConfig)
+
+// ClientPublicApiClient sets the PublicApiClient used to make the requests.
+// The base URL of the client must point to the BNR website (or a mirror).
+func ClientPublicApiClient(publicApiClient *client.PublicApiClient) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.PublicApiClient = publicApiClient
+	}
+}
+
+// Client is a client for the BNR exchange rates. The rates of the past
+// years are cached. It's safe for concurrent use.
+type Client struct {
+	publicApiClient *client.PublicApiClient
+
+	mu    sync.Mutex
+	years map[int][]Rates
+}
+
+// NewClient creates a new Client.
+func NewClient(opts ...ClientConfigOption) (*Client, error) {
+	var cfg ClientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.PublicApiClient == nil {
+		publicApiClient, err := client.NewPublicApiClient(client.PublicApiClientBaseURL(DefaultBaseURL))
+		if err != nil {
+			return nil, err
+		}
+		cfg.PublicApiClient = publicApiClient
+	}
+	return &Client{
+		publicApiClient: cfg.PublicApiClient,
+		years:           make(map[int][]Rates),
+	}, nil
+}
+
+// Rates are the exchange rates published at a date.
+type Rates struct {
+	Date types.Date
+	// Rates are the RON values of one unit of each currency (the rates
+	// published with a multiplier are divided by the multiplier).
+	Rates map[efactura.CurrencyCodeType]types.Decimal
+}
+
+// Rate returns the RON value of one unit of the currency. The rate of RON
+// is 1.
+func (r Rates) Rate(currency efactura.CurrencyCodeType) (types.Decimal, bool) {
+	if currency == efactura.CurrencyRON {
+		return types.D(1), true
+	}
+	rate, ok := r.Rates[currency]
+	return rate, ok
+}
+
+// LatestRates returns the rates published by BNR on the last business day.
+func (c *Client) LatestRates(ctx context.Context) (*Rates, error) {
+	rates, err := c.getRates(ctx, latestRatesEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, ErrRateNotFound
+	}
+	return &rates[len(rates)-1], nil
+}
+
+// YearRates returns the rates published by BNR in the given year, sorted by
+// date.
+func (c *Client) YearRates(ctx context.Context, year int) ([]Rates, error) {
+	c.mu.Lock()
+	rates, ok := c.years[year]
+	c.mu.Unlock()
+	if ok {
+		return rates, nil
+	}
+
+	rates, err := c.getRates(ctx, fmt.Sprintf(yearRatesEndpointFormat, year))
+	if err != nil {
+		return nil, err
+	}
+	// The rates of the current year are still published.
+	if year < itime.Now().Year() {
+		c.mu.Lock()
+		c.years[year] = rates
+		c.mu.Unlock()
+	}
+	return rates, nil
+}
+
+// Rate returns the RON value of one unit of the currency published by BNR
+// at the given date or, if no rates were published that day (eg. a weekend
+// or a holiday), the last rate published before. This is the rate used for
+// the VAT base of the operations in a foreign currency (Codul fiscal, art.
+// 290). ErrRateNotFound is returned if the currency has no published rate.
+func (c *Client) Rate(ctx context.Context, currency efactura.CurrencyCodeType, date types.Date) (types.Decimal, error) {
+	if currency == efactura.CurrencyRON {
+		return types.D(1), nil
+	}
+	if !date.IsInitialized() {
+		return types.Decimal{}, errors.New("bnr: date not set")
+	}
+	// Look in the previous year too, for the first days of the year.
+	for year := date.Year(); year >= date.Year()-1; year-- {
+		rates, err := c.YearRates(ctx, year)
+		if err != nil {
+			return types.Decimal{}, err
+		}
+		for i := len(rates) - 1; i >= 0; i-- {
+			if rates[i].Date.After(date.Time) {
+				continue
+			}
+			if rate, ok := rates[i].Rate(currency); ok {
+				return rate, nil
+			}
+		}
+	}
+	return types.Decimal{}, ErrRateNotFound
+}
+
+// ConvertInvoiceTaxToRON sets the invoice total VAT amount in RON (BT-111)
+// using the rate of the invoice currency at the invoice issue date (see
+// Rate and efactura.Invoice.ConvertTaxToRON).
+func (c *Client) ConvertInvoiceTaxToRON(ctx context.Context, iv *efactura.Invoice) error {
+	rate, err := c.Rate(ctx, iv.DocumentCurrencyCode, iv.IssueDate)
+	if err != nil {
+		return err
+	}
+	return iv.ConvertTaxToRON(rate)
+}
+
+type dataSet struct {
+	Cubes []cube `xml:"Body>Cube"`
+}
+
+type cube struct {
+	Date  string `xml:"date,attr"`
+	Rates []rate `xml:"Rate"`
+}
+
+type rate struct {
+	Currency   string `xml:"currency,attr"`
+	Multiplier int64  `xml:"multiplier,attr"`
+	Value      string `xml:",chardata"`
+}
+
+func (c *Client) getRates(ctx context.Context, endpoint string) ([]Rates, error) {
+	req, err := c.publicApiClient.NewRequest(ctx, http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var ds dataSet
+	if err := c.publicApiClient.DoUnmarshalXML(req, &ds); err != nil {
+		return nil, err
+	}
+
+	res := make([]Rates, 0, len(ds.Cubes))
+	for _, cube := range ds.Cubes {
+		date, err := types.MakeDateFromString(cube.Date)
+		if err != nil {
+			return nil, fmt.Errorf("bnr: invalid date %q: %w", cube.Date, err)
+		}
+		rates := Rates{
+			Date:  date,
+			Rates: make(map[efactura.CurrencyCodeType]types.Decimal, len(cube.Rates)),
+		}
+		for _, r := range cube.Rates {
+			value, err := types.NewFromString(strings.TrimSpace(r.Value))
+			if err != nil {
+				// Skip the currencies without a rate.
+				continue
+			}
+			if r.Multiplier > 1 {
+				value = value.Div(types.D(float64(r.Multiplier)))
+			}
+			rates.Rates[efactura.CurrencyCodeType(r.Currency)] = value
+		}
+		res = append(res, rates)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Date.Before(res[j].Date.Time)
+	})
+	return res, nil
+}
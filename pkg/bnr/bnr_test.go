@@ -0,0 +1,160 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package bnr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/bnr"
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+const testRatesXML = `<?xml version="1.0" encoding="utf-8"?>
+<DataSet xmlns="http://www.bnr.ro/xsd" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.bnr.ro/xsd nbrfxrates.xsd">
+  <Header>
+    <Publisher>National Bank of Romania</Publisher>
+    <PublishingDate>%[1]s</PublishingDate>
+    <MessageType>DR</MessageType>
+  </Header>
+  <Body>
+    <Subject>Reference rates</Subject>
+    <OrigCurrency>RON</OrigCurrency>
+    %[2]s
+  </Body>
+</DataSet>`
+
+func testCube(date string, eur string) string {
+	return fmt.Sprintf(`<Cube date="%s">
+      <Rate currency="EUR">%s</Rate>
+      <Rate currency="HUF" multiplier="100">1.2690</Rate>
+      <Rate currency="XXX">-</Rate>
+    </Cube>`, date, eur)
+}
+
+func setupTestClient(t *testing.T) (*bnr.Client, map[string]int) {
+	t.Helper()
+
+	requests := make(map[string]int)
+	mux := http.NewServeMux()
+	serve := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			requests[path]++
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	serve("/nbrfxrates.xml", fmt.Sprintf(testRatesXML, "2024-04-02", testCube("2024-04-02", "4.9701")))
+	serve("/files/xml/years/nbrfxrates2024.xml", fmt.Sprintf(testRatesXML, "2024-04-02",
+		testCube("2024-01-03", "4.9712")+testCube("2024-03-29", "4.9695")+testCube("2024-04-01", "4.9691")))
+	serve("/files/xml/years/nbrfxrates2023.xml", fmt.Sprintf(testRatesXML, "2023-12-29",
+		testCube("2023-12-28", "4.9730")+testCube("2023-12-29", "4.9746")))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	publicApiClient, err := client.NewPublicApiClient(
+		client.PublicApiClientBaseURL(server.URL + "/"),
+	)
+	if err != nil {
+		t.Fatalf("error creating public api client: %v", err)
+	}
+	c, err := bnr.NewClient(bnr.ClientPublicApiClient(publicApiClient))
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return c, requests
+}
+
+func TestRates(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	c, requests := setupTestClient(t)
+
+	latest, err := c.LatestRates(ctx)
+	if assert.NoError(err) {
+		assert.Equal(types.MakeDate(2024, 4, 2), latest.Date)
+		rate, ok := latest.Rate(efactura.CurrencyEUR)
+		assert.True(ok)
+		assert.Equal("4.9701", rate.String())
+		rate, ok = latest.Rate("HUF")
+		assert.True(ok)
+		assert.Equal("0.01269", rate.String())
+		_, ok = latest.Rate("XXX")
+		assert.False(ok)
+	}
+
+	for _, tc := range []struct {
+		date     types.Date
+		expected string
+	}{
+		{types.MakeDate(2024, 4, 1), "4.9691"},
+		// Weekend, the rate of the previous Friday.
+		{types.MakeDate(2024, 3, 31), "4.9695"},
+		// No rates published yet in 2024.
+		{types.MakeDate(2024, 1, 1), "4.9746"},
+	} {
+		rate, err := c.Rate(ctx, efactura.CurrencyEUR, tc.date)
+		if assert.NoError(err) {
+			assert.Equal(tc.expected, rate.String())
+		}
+	}
+	// The rates of the past years are cached.
+	assert.Equal(1, requests["/files/xml/years/nbrfxrates2023.xml"])
+	_, err = c.Rate(ctx, efactura.CurrencyEUR, types.MakeDate(2023, 12, 29))
+	assert.NoError(err)
+	assert.Equal(1, requests["/files/xml/years/nbrfxrates2023.xml"])
+
+	rate, err := c.Rate(ctx, efactura.CurrencyRON, types.MakeDate(2024, 4, 1))
+	if assert.NoError(err) {
+		assert.Equal("1", rate.String())
+	}
+	_, err = c.Rate(ctx, "USD", types.MakeDate(2024, 4, 1))
+	assert.True(errors.Is(err, bnr.ErrRateNotFound))
+}
+
+func TestConvertInvoiceTaxToRON(t *testing.T) {
+	assert := assert.New(t)
+	c, _ := setupTestClient(t)
+
+	iv := efactura.Invoice{
+		IssueDate:            types.MakeDate(2024, 3, 30),
+		DocumentCurrencyCode: efactura.CurrencyEUR,
+		TaxTotal: []efactura.InvoiceTaxTotal{{
+			TaxAmount: &efactura.AmountWithCurrency{Amount: types.D(19), CurrencyID: efactura.CurrencyEUR},
+			TaxSubtotals: []efactura.InvoiceTaxSubtotal{{
+				TaxableAmount: efactura.AmountWithCurrency{Amount: types.D(100), CurrencyID: efactura.CurrencyEUR},
+				TaxAmount:     efactura.AmountWithCurrency{Amount: types.D(19), CurrencyID: efactura.CurrencyEUR},
+				TaxCategory: efactura.InvoiceTaxCategory{
+					TaxScheme: efactura.TaxSchemeVAT,
+					ID:        efactura.TaxCategoryVATStandardRate,
+					Percent:   types.D(19),
+				},
+			}},
+		}},
+	}
+	if assert.NoError(c.ConvertInvoiceTaxToRON(context.Background(), &iv)) && assert.Len(iv.TaxTotal, 2) {
+		assert.Equal(efactura.CurrencyRON, iv.TaxCurrencyCode)
+		assert.Equal(efactura.CurrencyRON, iv.TaxTotal[1].TaxAmount.CurrencyID)
+		assert.Equal("94.42", iv.TaxTotal[1].TaxAmount.Amount.String())
+	}
+}
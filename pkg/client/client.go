@@ -17,9 +17,9 @@ package client
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
@@ -28,6 +28,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	xoauth2 "golang.org/x/oauth2"
 
@@ -35,6 +36,7 @@ import (
 	"github.com/printesoi/e-factura-go/internal/helpers"
 	api_helpers "github.com/printesoi/e-factura-go/internal/helpers/api"
 	"github.com/printesoi/e-factura-go/pkg/constants"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
 	"github.com/printesoi/e-factura-go/pkg/xml"
 )
 
@@ -45,10 +47,12 @@ const (
 // baseClient is a HTTP client for the ANAF APIs. It's embedded in a ApiClient
 // or PublicApiClient.
 type baseClient struct {
-	baseURL    *url.URL
-	userAgent  string
-	httpClient *http.Client
-	wg         sync.WaitGroup
+	baseURL     *url.URL
+	userAgent   string
+	httpClient  *http.Client
+	retryPolicy *RetryPolicy
+	logger      *slog.Logger
+	wg          sync.WaitGroup
 }
 
 // newBaseClient creates a new baseClient using the provided config options.
@@ -68,6 +72,8 @@ func newBaseClient(opts ...baseClientConfigOption) (*baseClient, error) {
 	} else {
 		client.httpClient = &http.Client{}
 	}
+	client.retryPolicy = cfg.RetryPolicy
+	client.logger = cfg.Logger
 
 	baseURL, err := url.Parse(cfg.BaseURL)
 	if err != nil {
@@ -81,15 +87,31 @@ func newBaseClient(opts ...baseClientConfigOption) (*baseClient, error) {
 }
 
 // Do sends the given HTTP request and returns an HTTP response. A non-200
-// response results in an *errors.ErrorResponse error.
+// response results in an *errors.ErrorResponse error. If the client has a
+// RetryPolicy, the request is retried on transient errors. If the client
+// has a Logger, the request and the retries are logged.
 func (c *baseClient) Do(req *http.Request) (resp *http.Response, err error) {
 	c.wg.Add(1)
 	defer c.wg.Done()
 
-	resp, err = c.httpClient.Do(req)
+	start, attempts := time.Now(), 1
+	if c.retryPolicy != nil {
+		var onRetry func(int, time.Duration, *http.Response, error)
+		if c.logger != nil {
+			onRetry = func(attempt int, wait time.Duration, resp *http.Response, err error) {
+				attempts = attempt + 1
+				c.logRetry(req, attempt, wait, resp, err)
+			}
+		}
+		resp, err = c.retryPolicy.doWithRetry(c.httpClient, req, onRetry)
+	} else {
+		resp, err = c.httpClient.Do(req)
+	}
 	if err == nil && !api_helpers.ResponseIsSuccess(resp.StatusCode) {
 		err = ierrors.NewErrorResponse(resp, nil)
-		return
+	}
+	if c.logger != nil {
+		c.logRequest(req, resp, err, time.Since(start), attempts)
 	}
 	return
 }
@@ -149,6 +171,11 @@ func (c *baseClient) DoUnmarshalJSON(req *http.Request, destResponse any, cb fun
 	return nil
 }
 
+// BaseURL returns the base URL of the client.
+func (c *baseClient) BaseURL() string {
+	return c.baseURL.String()
+}
+
 // Wait wait for all requests for finish
 func (c *baseClient) Wait() {
 	c.wg.Wait()
@@ -165,6 +192,8 @@ func RequestOptionHeader(name, value string) RequestOption {
 
 // NewRequest creates an API request. refURL is resolved relative to the client
 // baseURL. The relative URL should always be specified without a preceding slash.
+// If ctx carries a correlation ID (see correlation.NewContext), it's sent in
+// the correlation.HeaderName header.
 func (c *baseClient) NewRequest(ctx context.Context, method string,
 	refURL string, query url.Values, body io.Reader, opts ...RequestOption,
 ) (*http.Request, error) {
@@ -179,6 +208,9 @@ func (c *baseClient) NewRequest(ctx context.Context, method string,
 	}
 
 	req.Header.Set("User-Agent", c.userAgent)
+	if id := correlation.FromContext(ctx); id != "" {
+		req.Header.Set(correlation.HeaderName, id)
+	}
 	for _, opt := range opts {
 		opt(req)
 	}
@@ -198,6 +230,9 @@ func NewPublicApiClient(opts ...PublicApiClientConfigOption) (*PublicApiClient,
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	baseURL := constants.PublicApiBaseURL
 	if cfg.BaseURL != nil {
@@ -212,6 +247,12 @@ func NewPublicApiClient(opts ...PublicApiClientConfigOption) (*PublicApiClient,
 	if cfg.InsecureSkipVerify {
 		baseOpts = append(baseOpts, baseClientInsecureSkipVerify(cfg.InsecureSkipVerify))
 	}
+	if cfg.RetryPolicy != nil {
+		baseOpts = append(baseOpts, baseClientRetryPolicy(*cfg.RetryPolicy))
+	}
+	if cfg.Logger != nil {
+		baseOpts = append(baseOpts, baseClientLogger(cfg.Logger))
+	}
 	baseClient, err := newBaseClient(baseOpts...)
 	if err != nil {
 		return nil, err
@@ -235,8 +276,8 @@ func NewApiClient(opts ...ApiClientConfigOption) (*ApiClient, error) {
 		opt(&cfg)
 	}
 
-	if cfg.TokenSource == nil {
-		return nil, errors.New("invalid token source for client")
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	var baseURL string
@@ -262,6 +303,12 @@ func NewApiClient(opts ...ApiClientConfigOption) (*ApiClient, error) {
 	if cfg.InsecureSkipVerify {
 		baseOpts = append(baseOpts, baseClientInsecureSkipVerify(cfg.InsecureSkipVerify))
 	}
+	if cfg.RetryPolicy != nil {
+		baseOpts = append(baseOpts, baseClientRetryPolicy(*cfg.RetryPolicy))
+	}
+	if cfg.Logger != nil {
+		baseOpts = append(baseOpts, baseClientLogger(cfg.Logger))
+	}
 	baseClient, err := newBaseClient(baseOpts...)
 	if err != nil {
 		return nil, err
@@ -16,11 +16,17 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 
 	xoauth2 "golang.org/x/oauth2"
 
 	"github.com/printesoi/e-factura-go/internal/ptr"
+	"github.com/printesoi/e-factura-go/pkg/constants"
 )
 
 // baseClientConfig is the config used to create a baseClient
@@ -35,6 +41,11 @@ type baseClientConfig struct {
 	// Since this is a security risk, it should only be use with a custom
 	// BaseURL in development/testing environments.
 	InsecureSkipVerify bool
+	// Retry policy for the requests that failed with a transient error. If
+	// nil, the requests are not retried.
+	RetryPolicy *RetryPolicy
+	// Logger for the requests. If nil, nothing is logged.
+	Logger *slog.Logger
 }
 
 // baseClientConfigOption allows gradually modifying a baseClientConfig
@@ -69,6 +80,21 @@ func baseClientInsecureSkipVerify(skipVerify bool) baseClientConfigOption {
 	}
 }
 
+// baseClientRetryPolicy sets the retry policy for the requests that failed
+// with a transient error.
+func baseClientRetryPolicy(policy RetryPolicy) baseClientConfigOption {
+	return func(c *baseClientConfig) {
+		c.RetryPolicy = &policy
+	}
+}
+
+// baseClientLogger sets the logger for the requests.
+func baseClientLogger(logger *slog.Logger) baseClientConfigOption {
+	return func(c *baseClientConfig) {
+		c.Logger = logger
+	}
+}
+
 // PublicApiClientConfig is the config used to create a PublicApiClient
 type PublicApiClientConfig struct {
 	// Base URL of the ANAF public APIs. It is only useful in
@@ -80,6 +106,26 @@ type PublicApiClientConfig struct {
 	// Since this is a security risk, it should only be use with a custom
 	// BaseURL in development/testing environments.
 	InsecureSkipVerify bool
+	// Retry policy for the requests that failed with a transient error
+	// (optional). If nil, the requests are not retried.
+	RetryPolicy *RetryPolicy
+	// Logger for the requests (optional). If nil, nothing is logged.
+	Logger *slog.Logger
+}
+
+// Validate checks that the config is complete and consistent. All the
+// problems found are returned as a single joined error.
+func (c *PublicApiClientConfig) Validate() error {
+	var errs []error
+	if c.BaseURL != nil {
+		if err := validateBaseURL(*c.BaseURL); err != nil {
+			errs = append(errs, err)
+		}
+		if sameHost(*c.BaseURL, constants.ApiBaseURL) {
+			errs = append(errs, fmt.Errorf("BaseURL %q points to the protected APIs, not to the public APIs", *c.BaseURL))
+		}
+	}
+	return joinConfigErrors(errs)
 }
 
 // PublicApiClientConfigOption allows gradually modifying a PublicApiClientConfig
@@ -108,6 +154,22 @@ func PublicApiClientInsecureSkipVerify(skipVerify bool) PublicApiClientConfigOpt
 	}
 }
 
+// PublicApiClientRetryPolicy sets the retry policy for the requests that
+// failed with a transient error (see RetryPolicy and DefaultRetryPolicy).
+func PublicApiClientRetryPolicy(policy RetryPolicy) PublicApiClientConfigOption {
+	return func(c *PublicApiClientConfig) {
+		c.RetryPolicy = &policy
+	}
+}
+
+// PublicApiClientLogger sets the logger for the requests (see
+// ApiClientLogger).
+func PublicApiClientLogger(logger *slog.Logger) PublicApiClientConfigOption {
+	return func(c *PublicApiClientConfig) {
+		c.Logger = logger
+	}
+}
+
 // ApiClientConfig is the config used to create an ApiClient
 type ApiClientConfig struct {
 	// TokenSource is the token source used for generating OAuth2 tokens.
@@ -129,6 +191,63 @@ type ApiClientConfig struct {
 	// Since this is a security risk, it should only be use with a custom
 	// BaseURL in development/testing environments.
 	InsecureSkipVerify bool
+	// Retry policy for the requests that failed with a transient error
+	// (optional). If nil, the requests are not retried.
+	RetryPolicy *RetryPolicy
+	// Logger for the requests (optional). If nil, nothing is logged.
+	Logger *slog.Logger
+
+	// sandboxSet is true if the environment was explicitly set by one of
+	// ApiClientSandboxEnvironment or ApiClientProductionEnvironment.
+	sandboxSet bool
+	// sandboxConflict is true if the environment options set both the
+	// sandbox and the production environment.
+	sandboxConflict bool
+	// environmentErr is the error of an invalid environment passed to
+	// ApiClientEnvironment.
+	environmentErr error
+}
+
+// setSandbox sets the Sandbox field, remembering if the environment was
+// previously explicitly set to a different value.
+func (c *ApiClientConfig) setSandbox(sandbox bool) {
+	if c.sandboxSet && c.Sandbox != sandbox {
+		c.sandboxConflict = true
+	}
+	c.Sandbox = sandbox
+	c.sandboxSet = true
+}
+
+// Validate checks that the config is complete and consistent. All the
+// problems found are returned as a single joined error.
+func (c *ApiClientConfig) Validate() error {
+	var errs []error
+	if c.TokenSource == nil {
+		errs = append(errs, errors.New("missing token source for client"))
+	}
+	if c.environmentErr != nil {
+		errs = append(errs, c.environmentErr)
+	}
+	if c.sandboxConflict {
+		errs = append(errs, errors.New("both sandbox and production environments were set"))
+	}
+	if c.BaseURL != nil {
+		if err := validateBaseURL(*c.BaseURL); err != nil {
+			errs = append(errs, err)
+		}
+		if c.sandboxSet {
+			switch {
+			case c.Sandbox && sameBaseURL(*c.BaseURL, constants.ApiBaseProd):
+				errs = append(errs, fmt.Errorf("BaseURL %q is the production endpoint, but the sandbox environment was set", *c.BaseURL))
+			case !c.Sandbox && sameBaseURL(*c.BaseURL, constants.ApiBaseSandbox):
+				errs = append(errs, fmt.Errorf("BaseURL %q is the sandbox endpoint, but the production environment was set", *c.BaseURL))
+			}
+		}
+		if sameHost(*c.BaseURL, constants.PublicApiBaseURL) {
+			errs = append(errs, fmt.Errorf("BaseURL %q points to the public APIs, not to the protected APIs", *c.BaseURL))
+		}
+	}
+	return joinConfigErrors(errs)
 }
 
 // ApiClientConfigOption allows gradually modifying a ApiClientConfig
@@ -147,7 +266,7 @@ func ApiClientOAuth2TokenSource(tokenSource xoauth2.TokenSource) ApiClientConfig
 // if called with sandbox=false sets the BaseURL to the production URL.
 func ApiClientSandboxEnvironment(sandbox bool) ApiClientConfigOption {
 	return func(c *ApiClientConfig) {
-		c.Sandbox = sandbox
+		c.setSandbox(sandbox)
 	}
 }
 
@@ -156,7 +275,21 @@ func ApiClientSandboxEnvironment(sandbox bool) ApiClientConfigOption {
 // if called with prod=false sets the BaseURL to the sandbox URL.
 func ApiClientProductionEnvironment(prod bool) ApiClientConfigOption {
 	return func(c *ApiClientConfig) {
-		c.Sandbox = !prod
+		c.setSandbox(!prod)
+	}
+}
+
+// ApiClientEnvironment sets the BaseURL to the URL of the protected APIs in
+// the given environment. It is equivalent to
+// ApiClientSandboxEnvironment(env == EnvironmentSandbox), but an invalid
+// environment is reported by Validate.
+func ApiClientEnvironment(env Environment) ApiClientConfigOption {
+	return func(c *ApiClientConfig) {
+		if err := env.Validate(); err != nil {
+			c.environmentErr = err
+			return
+		}
+		c.setSandbox(env.IsSandbox())
 	}
 }
 
@@ -189,3 +322,91 @@ func ApiClientInsecureSkipVerify(skipVerify bool) ApiClientConfigOption {
 		c.InsecureSkipVerify = skipVerify
 	}
 }
+
+// ApiClientRetryPolicy sets the retry policy for the requests that failed
+// with a transient error (see RetryPolicy and DefaultRetryPolicy).
+func ApiClientRetryPolicy(policy RetryPolicy) ApiClientConfigOption {
+	return func(c *ApiClientConfig) {
+		c.RetryPolicy = &policy
+	}
+}
+
+// ApiClientLogger sets the logger for the requests. Each request is logged
+// when completed with the method, the URL, the status, the correlation ID,
+// the ANAF trace_id, the latency and the number of attempts (debug level
+// for successful requests, warning level for failed requests). Each retry
+// is logged with the info level. The records are logged with the request
+// context, so a context-aware slog.Handler can add more attributes. The
+// headers and bodies are never logged, and the values of the secret query
+// parameters (eg. token) are redacted.
+func ApiClientLogger(logger *slog.Logger) ApiClientConfigOption {
+	return func(c *ApiClientConfig) {
+		c.Logger = logger
+	}
+}
+
+// ValidateBaseURL checks that baseURL is a valid absolute URL with a trailing
+// slash, as required for the base URLs of the clients and of the endpoint
+// groups.
+func ValidateBaseURL(baseURL string) error {
+	return validateBaseURL(baseURL)
+}
+
+// SameHost returns true if the two URLs have the same host, eg. for checking
+// that a base URL doesn't point to the wrong ANAF APIs.
+func SameHost(a, b string) bool {
+	return sameHost(a, b)
+}
+
+// validateBaseURL checks that baseURL is a valid absolute URL with a trailing
+// slash.
+func validateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid BaseURL %q: %w", baseURL, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("BaseURL %q must be an absolute URL", baseURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		return fmt.Errorf("BaseURL must have a trailing slash, but %q does not", baseURL)
+	}
+	return nil
+}
+
+// sameBaseURL returns true if the two URLs have the same scheme, host and
+// path.
+func sameBaseURL(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) &&
+		strings.TrimSuffix(ua.Path, "/") == strings.TrimSuffix(ub.Path, "/")
+}
+
+// sameHost returns true if the two URLs have the same host.
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
+
+// joinConfigErrors returns nil if errs is empty, otherwise an error wrapping
+// all the errors from errs.
+func joinConfigErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid client config: %w", errors.Join(errs...))
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/constants"
+)
+
+func TestNewApiClientValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	tokenSource := xoauth2.StaticTokenSource(&xoauth2.Token{AccessToken: "test"})
+
+	_, err := NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientSandboxEnvironment(true),
+	)
+	assert.NoError(err)
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientSandboxEnvironment(true),
+		ApiClientBaseURL("http://localhost:8080/test/"),
+	)
+	assert.NoError(err, "custom base URL with an environment is allowed")
+
+	_, err = NewApiClient(
+		ApiClientSandboxEnvironment(true),
+		ApiClientProductionEnvironment(true),
+		ApiClientBaseURL(constants.ApiBaseProd),
+	)
+	if assert.Error(err) {
+		assert.ErrorContains(err, "missing token source")
+		assert.ErrorContains(err, "both sandbox and production")
+	}
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientSandboxEnvironment(true),
+		ApiClientBaseURL(constants.ApiBaseProd),
+	)
+	assert.ErrorContains(err, "production endpoint")
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientProductionEnvironment(true),
+		ApiClientBaseURL(constants.ApiBaseSandbox),
+	)
+	assert.ErrorContains(err, "sandbox endpoint")
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientEnvironment(EnvironmentSandbox),
+		ApiClientBaseURL(constants.ApiBaseProd),
+	)
+	assert.ErrorContains(err, "production endpoint")
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientEnvironment(EnvironmentSandbox),
+		ApiClientProductionEnvironment(true),
+	)
+	assert.ErrorContains(err, "both sandbox and production")
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientEnvironment("test"),
+	)
+	assert.ErrorContains(err, `invalid environment "test"`)
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientBaseURL(constants.PublicApiBaseProd),
+	)
+	assert.ErrorContains(err, "public APIs")
+
+	_, err = NewApiClient(
+		ApiClientOAuth2TokenSource(tokenSource),
+		ApiClientBaseURL("http://localhost:8080/test"),
+	)
+	assert.ErrorContains(err, "trailing slash")
+}
+
+func TestNewPublicApiClientValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewPublicApiClient()
+	assert.NoError(err)
+
+	_, err = NewPublicApiClient(PublicApiClientBaseURL(constants.PublicApiBaseProd))
+	assert.NoError(err)
+
+	_, err = NewPublicApiClient(PublicApiClientBaseURL(constants.ApiBaseProd))
+	assert.ErrorContains(err, "protected APIs")
+
+	_, err = NewPublicApiClient(PublicApiClientBaseURL("prod/"))
+	assert.ErrorContains(err, "absolute URL")
+}
+
+func TestEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(constants.ApiBaseProd, EnvironmentProduction.ApiBaseURL())
+	assert.Equal(constants.ApiBaseSandbox, EnvironmentSandbox.ApiBaseURL())
+	assert.Equal(constants.PublicApiBaseProd, EnvironmentProduction.PublicApiBaseURL())
+	assert.Equal(constants.PublicApiBaseProd, EnvironmentSandbox.PublicApiBaseURL())
+	assert.NoError(EnvironmentProduction.Validate())
+	assert.NoError(EnvironmentSandbox.Validate())
+	assert.Error(Environment("").Validate())
+	assert.Error(Environment("prod").Validate())
+
+	for env, expected := range map[Environment]string{
+		EnvironmentProduction: constants.ApiBaseProd,
+		EnvironmentSandbox:    constants.ApiBaseSandbox,
+	} {
+		c, err := NewApiClient(
+			ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{AccessToken: "test"})),
+			ApiClientEnvironment(env),
+		)
+		if assert.NoError(err) {
+			assert.Equal(expected, c.baseURL.String())
+		}
+	}
+}
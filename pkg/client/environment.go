@@ -0,0 +1,65 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/constants"
+)
+
+// Environment is an environment of the ANAF APIs.
+type Environment string
+
+const (
+	// EnvironmentProduction is the production environment.
+	EnvironmentProduction Environment = "production"
+	// EnvironmentSandbox is the sandbox (testing) environment.
+	EnvironmentSandbox Environment = "sandbox"
+)
+
+// String returns the name of the environment.
+func (e Environment) String() string {
+	return string(e)
+}
+
+// Validate returns an error if e is not one of EnvironmentProduction or
+// EnvironmentSandbox.
+func (e Environment) Validate() error {
+	switch e {
+	case EnvironmentProduction, EnvironmentSandbox:
+		return nil
+	}
+	return fmt.Errorf("invalid environment %q", string(e))
+}
+
+// IsSandbox returns true if e is the sandbox environment.
+func (e Environment) IsSandbox() bool {
+	return e == EnvironmentSandbox
+}
+
+// ApiBaseURL returns the base URL of the protected APIs in the environment
+// (constants.ApiBaseProd or constants.ApiBaseSandbox).
+func (e Environment) ApiBaseURL() string {
+	return getApiBase(e.IsSandbox())
+}
+
+// PublicApiBaseURL returns the base URL of the versioned public APIs in the
+// environment (eg. the e-factura validation and XML to PDF). ANAF does not
+// provide a sandbox version of the public APIs, so this is
+// constants.PublicApiBaseProd for both environments.
+func (e Environment) PublicApiBaseURL() string {
+	return constants.PublicApiBaseProd
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	api_helpers "github.com/printesoi/e-factura-go/internal/helpers/api"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+	perrors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+// The keys of the attributes of the request log records.
+const (
+	LogKeyMethod        = "method"
+	LogKeyURL           = "url"
+	LogKeyStatus        = "status"
+	LogKeyLatency       = "latency"
+	LogKeyAttempts      = "attempts"
+	LogKeyAttempt       = "attempt"
+	LogKeyWait          = "wait"
+	LogKeyCorrelationID = "correlation_id"
+	LogKeyTraceID       = "trace_id"
+	LogKeyError         = "error"
+)
+
+const (
+	// redactedValue replaces the values of the secrets in the logs.
+	redactedValue = "REDACTED"
+	// maxTraceIDPeek is the maximum size of a JSON response body read for
+	// finding the ANAF trace_id.
+	maxTraceIDPeek = 64 << 10
+)
+
+// redactedQueryParams are the (lower case) names of the query parameters
+// whose values are never logged.
+var redactedQueryParams = []string{
+	"access_token",
+	"client_secret",
+	"code",
+	"password",
+	"refresh_token",
+	"token",
+}
+
+// redactURL returns the URL as a string, without the user info and with the
+// values of the secret query parameters redacted.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	ru := *u
+	ru.User = nil
+	if ru.RawQuery != "" {
+		query := ru.Query()
+		for name, values := range query {
+			for _, secret := range redactedQueryParams {
+				if strings.EqualFold(name, secret) {
+					for i := range values {
+						values[i] = redactedValue
+					}
+				}
+			}
+		}
+		ru.RawQuery = query.Encode()
+	}
+	return ru.String()
+}
+
+// redactError returns the error message, with the request URL redacted.
+func redactError(req *http.Request, err error) string {
+	msg := err.Error()
+	if req.URL != nil {
+		msg = strings.ReplaceAll(msg, req.URL.String(), redactURL(req.URL))
+	}
+	return msg
+}
+
+// peekTraceID returns the ANAF trace_id from a JSON response body. The body
+// is replaced so it can still be read by the caller.
+func peekTraceID(resp *http.Response) string {
+	if resp == nil || resp.Body == nil || resp.Body == http.NoBody ||
+		!api_helpers.ResponseBodyIsJSON(resp.Header) {
+		return ""
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxTraceIDPeek))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+
+	var b struct {
+		TraceID string `json:"trace_id"`
+	}
+	// Don't care if we get an error here (eg. a truncated body).
+	_ = json.Unmarshal(data, &b)
+	return b.TraceID
+}
+
+// requestLogAttrs returns the common attributes of the log records of a
+// request.
+func requestLogAttrs(req *http.Request) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String(LogKeyMethod, req.Method),
+		slog.String(LogKeyURL, redactURL(req.URL)),
+	}
+	if id := req.Header.Get(correlation.HeaderName); id != "" {
+		attrs = append(attrs, slog.String(LogKeyCorrelationID, id))
+	}
+	return attrs
+}
+
+// logRetry logs an attempt that is retried.
+func (c *baseClient) logRetry(req *http.Request, attempt int, wait time.Duration, resp *http.Response, err error) {
+	ctx := req.Context()
+	if !c.logger.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	attrs := append(requestLogAttrs(req),
+		slog.Int(LogKeyAttempt, attempt),
+		slog.Duration(LogKeyWait, wait))
+	if resp != nil {
+		attrs = append(attrs, slog.Int(LogKeyStatus, resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String(LogKeyError, redactError(req, err)))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "ANAF API request retried", attrs...)
+}
+
+// logRequest logs a completed request: successful requests are logged with
+// the debug level, failed requests with the warning level.
+func (c *baseClient) logRequest(req *http.Request, resp *http.Response, err error, latency time.Duration, attempts int) {
+	ctx := req.Context()
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if !c.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := append(requestLogAttrs(req),
+		slog.Duration(LogKeyLatency, latency),
+		slog.Int(LogKeyAttempts, attempts))
+	if resp != nil {
+		attrs = append(attrs, slog.Int(LogKeyStatus, resp.StatusCode))
+	}
+	var traceID string
+	var errResp *perrors.ErrorResponse
+	if errors.As(err, &errResp) && errResp.TraceID != nil {
+		traceID = *errResp.TraceID
+	} else if err == nil {
+		traceID = peekTraceID(resp)
+	}
+	if traceID != "" {
+		attrs = append(attrs, slog.String(LogKeyTraceID, traceID))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String(LogKeyError, redactError(req, err)))
+	}
+	c.logger.LogAttrs(ctx, level, "ANAF API request", attrs...)
+}
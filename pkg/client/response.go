@@ -0,0 +1,27 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package client
+
+// Response is implemented by the parsed responses of the efactura and
+// etransport APIs, so the code handling both systems can share the error
+// handling. The limit exceeded errors are returned by the API methods as
+// *errors.LimitExceededError errors, for both systems.
+type Response interface {
+	// IsOk returns true if the response is successful.
+	IsOk() bool
+	// GetFirstErrorMessage returns the first error message of the response,
+	// or empty string if the response has no error messages.
+	GetFirstErrorMessage() string
+}
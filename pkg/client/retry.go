@@ -0,0 +1,227 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	xoauth2 "golang.org/x/oauth2"
+)
+
+const (
+	// DefaultRetryMaxAttempts is the default maximum number of attempts
+	// (including the first one) of a RetryPolicy.
+	DefaultRetryMaxAttempts = 4
+	// DefaultRetryInitialBackoff is the default wait before the first retry.
+	DefaultRetryInitialBackoff = 500 * time.Millisecond
+	// DefaultRetryMaxBackoff is the default maximum wait between retries.
+	DefaultRetryMaxBackoff = 30 * time.Second
+	// DefaultRetryMultiplier is the default factor by which the wait is
+	// multiplied before each subsequent retry.
+	DefaultRetryMultiplier = 2.0
+	// DefaultRetryJitter is the default jitter of the wait between retries.
+	DefaultRetryJitter = 0.2
+)
+
+// RetryPolicy configures the automatic retries of the requests that failed
+// with a transient error: a network error, 429 Too Many Requests or a server
+// error (5xx). The wait before each retry grows exponentially, with jitter,
+// and a Retry-After header sent by the server is respected (capped at
+// MaxBackoff). Retries stop as soon as the request context is done.
+//
+// Since a request that failed with a network error or a server error might
+// still have been processed by ANAF, non-idempotent requests (eg. POST
+// uploads) are only retried on 429 Too Many Requests, unless
+// RetryNonIdempotent is set. Requests with a body that cannot be replayed
+// (see http.Request.GetBody) are never retried. The error messages returned
+// by ANAF in successful responses (eg. exceeded limits) are not retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first
+	// one. A value <= 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum wait between retries.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the wait is multiplied before each
+	// subsequent retry. Values < 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction (between 0 and 1) of the wait that is
+	// randomized, to avoid retries from multiple clients happening at the
+	// same time. A wait w becomes a random value in [w*(1-Jitter), w].
+	Jitter float64
+	// RetryNonIdempotent allows retrying non-idempotent requests on network
+	// and server errors. Enabling this might result in duplicate uploads.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the RetryPolicy with the default values.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DefaultRetryMaxAttempts,
+		InitialBackoff: DefaultRetryInitialBackoff,
+		MaxBackoff:     DefaultRetryMaxBackoff,
+		Multiplier:     DefaultRetryMultiplier,
+		Jitter:         DefaultRetryJitter,
+	}
+}
+
+// Backoff returns the wait before the retry following the given attempt
+// (1-based), including the jitter.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	multiplier := max(p.Multiplier, 1)
+	for i := 1; i < attempt && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= multiplier
+	}
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, float64(p.MaxBackoff))
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		backoff -= backoff * jitter * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// canRetry returns true if the request can be sent again.
+func (p RetryPolicy) canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// shouldRetry returns true if the attempt that resulted in resp and err
+// should be retried.
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	idempotent := p.RetryNonIdempotent || isIdempotentMethod(req.Method)
+	if err != nil {
+		// Errors from the token source (eg. an expired refresh token) are
+		// not transient.
+		var retrieveErr *xoauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return false
+		}
+		var urlErr *url.Error
+		return idempotent && errors.As(err, &urlErr)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return idempotent && resp.StatusCode != http.StatusNotImplemented
+	}
+	return false
+}
+
+// wait returns the wait before the retry following the given attempt,
+// using the Retry-After header from resp if set.
+func (p RetryPolicy) wait(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if p.MaxBackoff > 0 {
+				d = min(d, p.MaxBackoff)
+			}
+			return d
+		}
+	}
+	return p.Backoff(attempt)
+}
+
+// isIdempotentMethod returns true if the HTTP method is idempotent.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which can be
+// either a number of seconds or a HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discardResponse drains and closes the body of a response that is not
+// returned to the caller, so the connection can be reused.
+func discardResponse(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+	}
+}
+
+// doWithRetry sends the request using httpClient, retrying it according to
+// the policy. If onRetry is not nil, it's called before waiting for each
+// retry, with the failed attempt (1-based), the wait and its response or
+// error.
+func (p RetryPolicy) doWithRetry(httpClient *http.Client, req *http.Request,
+	onRetry func(attempt int, wait time.Duration, resp *http.Response, err error),
+) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := httpClient.Do(req)
+		if attempt >= p.MaxAttempts || !p.canRetry(req) || !p.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		wait := p.wait(attempt, resp)
+		if onRetry != nil {
+			onRetry(attempt, wait, resp, err)
+		}
+		discardResponse(resp)
+		if er := sleepContext(req.Context(), wait); er != nil {
+			return nil, er
+		}
+		if req.GetBody != nil {
+			body, er := req.GetBody()
+			if er != nil {
+				return nil, er
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	perrors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+func setupTestRetryClient(t *testing.T, policy RetryPolicy, handler http.HandlerFunc) *PublicApiClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := NewPublicApiClient(
+		PublicApiClientBaseURL(server.URL+"/"),
+		PublicApiClientRetryPolicy(policy),
+	)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return c
+}
+
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryPolicyRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	var attempts int
+	var bodies []string
+	c := setupTestRetryClient(t, testRetryPolicy(), func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch r.URL.Path {
+		case "/get":
+			if attempts < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/post":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "/post-429":
+			if attempts < 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "/bad-request":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ctx := context.Background()
+	do := func(method, path string, body io.Reader) error {
+		attempts, bodies = 0, nil
+		req, err := c.NewRequest(ctx, method, path, nil, body)
+		if err != nil {
+			return err
+		}
+		resp, err := c.Do(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// Idempotent requests are retried on server errors.
+	assert.NoError(do(http.MethodGet, "get", nil))
+	assert.Equal(3, attempts)
+
+	// Non-idempotent requests are not retried on server errors...
+	assert.Error(do(http.MethodPost, "post", bytes.NewReader([]byte("data"))))
+	assert.Equal(1, attempts)
+
+	// ...but are retried on 429, with the body replayed.
+	assert.NoError(do(http.MethodPost, "post-429", bytes.NewReader([]byte("data"))))
+	assert.Equal(2, attempts)
+	assert.Equal([]string{"data", "data"}, bodies)
+
+	// A body that cannot be replayed is never retried.
+	assert.Error(do(http.MethodPost, "post-429", io.MultiReader(bytes.NewReader([]byte("data")))))
+	assert.Equal(1, attempts)
+
+	// Client errors are not retried.
+	assert.Error(do(http.MethodGet, "bad-request", nil))
+	assert.Equal(1, attempts)
+
+	// The last error response is returned after MaxAttempts attempts.
+	err := do(http.MethodGet, "fail", nil)
+	var errResponse *perrors.ErrorResponse
+	if assert.True(errors.As(err, &errResponse)) {
+		assert.Equal(http.StatusBadGateway, errResponse.StatusCode)
+	}
+	assert.Equal(DefaultRetryMaxAttempts, attempts)
+}
+
+func TestRetryPolicyNonIdempotent(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := testRetryPolicy()
+	policy.RetryNonIdempotent = true
+	var attempts int
+	c := setupTestRetryClient(t, policy, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	req, err := c.NewRequest(context.Background(), http.MethodPost, "post", nil, bytes.NewReader([]byte("data")))
+	if !assert.NoError(err) {
+		return
+	}
+	resp, err := c.Do(req)
+	if assert.NoError(err) {
+		resp.Body.Close()
+	}
+	assert.Equal(2, attempts)
+}
+
+func TestRetryPolicyContext(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := testRetryPolicy()
+	policy.InitialBackoff, policy.MaxBackoff = time.Hour, time.Hour
+	var attempts int
+	c := setupTestRetryClient(t, policy, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := c.NewRequest(ctx, http.MethodGet, "get", nil, nil)
+	if !assert.NoError(err) {
+		return
+	}
+	_, err = c.Do(req)
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(1, attempts)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(time.Second, policy.Backoff(1))
+	assert.Equal(2*time.Second, policy.Backoff(2))
+	assert.Equal(8*time.Second, policy.Backoff(4))
+	assert.Equal(10*time.Second, policy.Backoff(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(2)
+		assert.GreaterOrEqual(backoff, time.Second)
+		assert.LessOrEqual(backoff, 2*time.Second)
+	}
+
+	d, ok := parseRetryAfter("120")
+	assert.True(ok)
+	assert.Equal(2*time.Minute, d)
+	_, ok = parseRetryAfter("soon")
+	assert.False(ok)
+	assert.Equal(10*time.Second, policy.wait(1, &http.Response{Header: http.Header{"Retry-After": {"120"}}}))
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package correlation provides correlation IDs that are attached to the
+// submission and download operations, so the same ID can be used to join the
+// responses, the stored documents and the logs of a single operation.
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HeaderName is the name of the HTTP header used for sending the correlation
+// ID with the requests.
+const HeaderName = "X-Correlation-ID"
+
+// IDGenerator is a function that returns a new unique correlation ID.
+type IDGenerator func() string
+
+var (
+	uuidv7Mu     sync.Mutex
+	uuidv7LastMs int64
+	uuidv7Seq    uint16
+)
+
+// NewUUIDv7 returns a new UUID version 7 (RFC 9562): a 48 bits Unix
+// timestamp in milliseconds followed by random bits, so the IDs are sortable
+// by creation time. The IDs generated in the same millisecond by the same
+// process are monotonic (a 12 bits counter is used for rand_a). This is the
+// default IDGenerator.
+func NewUUIDv7() string {
+	var u uuid.UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		// crypto/rand never fails on the supported platforms.
+		panic(err)
+	}
+
+	uuidv7Mu.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= uuidv7LastMs {
+		uuidv7Seq++
+		if uuidv7Seq > 0x0fff {
+			// Counter overflow, borrow the next millisecond.
+			uuidv7LastMs++
+			uuidv7Seq = 0
+		}
+		ms = uuidv7LastMs
+	} else {
+		uuidv7LastMs = ms
+		uuidv7Seq = binary.BigEndian.Uint16(u[6:8]) & 0x07ff
+	}
+	seq := uuidv7Seq
+	uuidv7Mu.Unlock()
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return u.String()
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given correlation ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID carried by ctx, or empty string if
+// ctx doesn't carry a correlation ID.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Ensure returns ctx and the correlation ID carried by ctx, if any.
+// Otherwise a new ID is generated with gen (or NewUUIDv7 if gen is nil) and a
+// copy of ctx carrying the new ID is returned.
+func Ensure(ctx context.Context, gen IDGenerator) (context.Context, string) {
+	if id := FromContext(ctx); id != "" {
+		return ctx, id
+	}
+	if gen == nil {
+		gen = NewUUIDv7
+	}
+	id := gen()
+	return NewContext(ctx, id), id
+}
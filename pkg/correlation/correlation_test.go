@@ -0,0 +1,61 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package correlation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUUIDv7(t *testing.T) {
+	assert := assert.New(t)
+
+	prev := ""
+	for i := 0; i < 10000; i++ {
+		id := NewUUIDv7()
+		u, err := uuid.Parse(id)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(uuid.Version(7), u.Version())
+		assert.Equal(uuid.RFC4122, u.Variant())
+		if !assert.Greater(id, prev, "IDs must be monotonic") {
+			return
+		}
+		prev = id
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	assert.Empty(FromContext(ctx))
+
+	ctx1, id := Ensure(ctx, func() string { return "id-1" })
+	assert.Equal("id-1", id)
+	assert.Equal("id-1", FromContext(ctx1))
+
+	// An existing ID is kept.
+	ctx2, id := Ensure(ctx1, func() string { return "id-2" })
+	assert.Equal("id-1", id)
+	assert.Equal(ctx1, ctx2)
+
+	_, id = Ensure(ctx, nil)
+	assert.NotEmpty(id)
+}
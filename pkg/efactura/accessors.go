@@ -0,0 +1,484 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// The accessors below read deeply nested optional fields of the parsed
+// documents without nil checks: if a field on the path is missing, the zero
+// value is returned.
+
+// Name returns the Seller name (BT-27).
+func (p InvoiceSupplierParty) Name() string {
+	return p.LegalEntity.Name
+}
+
+// VATID returns the Seller VAT identifier (BT-31).
+func (p InvoiceSupplierParty) VATID() string {
+	if p.TaxScheme == nil || p.TaxScheme.TaxScheme.ID != TaxSchemeIDVAT {
+		return ""
+	}
+	return p.TaxScheme.CompanyID
+}
+
+// TaxRegistrationID returns the Seller tax registration identifier (BT-32).
+func (p InvoiceSupplierParty) TaxRegistrationID() string {
+	if p.TaxScheme == nil || p.TaxScheme.TaxScheme.ID == TaxSchemeIDVAT {
+		return ""
+	}
+	return p.TaxScheme.CompanyID
+}
+
+// LegalRegistrationID returns the Seller legal registration identifier
+// (BT-30).
+func (p InvoiceSupplierParty) LegalRegistrationID() string {
+	if p.LegalEntity.CompanyID == nil {
+		return ""
+	}
+	return p.LegalEntity.CompanyID.Value
+}
+
+// Country returns the Seller country code (BT-40).
+func (p InvoiceSupplierParty) Country() CountryCodeType {
+	return p.PostalAddress.Country.Code
+}
+
+// CountrySubentity returns the Seller country subdivision (BT-39).
+func (p InvoiceSupplierParty) CountrySubentity() CountrySubentityType {
+	return p.PostalAddress.CountrySubentity
+}
+
+// City returns the Seller city (BT-37).
+func (p InvoiceSupplierParty) City() string {
+	return p.PostalAddress.CityName
+}
+
+// ContactName returns the Seller contact point (BT-41).
+func (p InvoiceSupplierParty) ContactName() string {
+	if p.Contact == nil {
+		return ""
+	}
+	return p.Contact.Name
+}
+
+// Phone returns the Seller contact telephone number (BT-42).
+func (p InvoiceSupplierParty) Phone() string {
+	if p.Contact == nil {
+		return ""
+	}
+	return p.Contact.Phone
+}
+
+// Email returns the Seller contact email address (BT-43).
+func (p InvoiceSupplierParty) Email() string {
+	if p.Contact == nil {
+		return ""
+	}
+	return p.Contact.Email
+}
+
+// Name returns the Buyer name (BT-44).
+func (p InvoiceCustomerParty) Name() string {
+	return p.LegalEntity.Name
+}
+
+// VATID returns the Buyer VAT identifier (BT-48).
+func (p InvoiceCustomerParty) VATID() string {
+	if p.TaxScheme == nil || p.TaxScheme.TaxScheme.ID != TaxSchemeIDVAT {
+		return ""
+	}
+	return p.TaxScheme.CompanyID
+}
+
+// LegalRegistrationID returns the Buyer legal registration identifier
+// (BT-47).
+func (p InvoiceCustomerParty) LegalRegistrationID() string {
+	if p.LegalEntity.CompanyID == nil {
+		return ""
+	}
+	return p.LegalEntity.CompanyID.Value
+}
+
+// Country returns the Buyer country code (BT-55).
+func (p InvoiceCustomerParty) Country() CountryCodeType {
+	return p.PostalAddress.Country.Code
+}
+
+// CountrySubentity returns the Buyer country subdivision (BT-54).
+func (p InvoiceCustomerParty) CountrySubentity() CountrySubentityType {
+	return p.PostalAddress.CountrySubentity
+}
+
+// City returns the Buyer city (BT-52).
+func (p InvoiceCustomerParty) City() string {
+	return p.PostalAddress.CityName
+}
+
+// ContactName returns the Buyer contact point (BT-56).
+func (p InvoiceCustomerParty) ContactName() string {
+	if p.Contact == nil {
+		return ""
+	}
+	return p.Contact.Name
+}
+
+// Phone returns the Buyer contact telephone number (BT-57).
+func (p InvoiceCustomerParty) Phone() string {
+	if p.Contact == nil {
+		return ""
+	}
+	return p.Contact.Phone
+}
+
+// Email returns the Buyer contact email address (BT-58).
+func (p InvoiceCustomerParty) Email() string {
+	if p.Contact == nil {
+		return ""
+	}
+	return p.Contact.Email
+}
+
+// SupplierName returns the Seller name (BT-27).
+func (iv Invoice) SupplierName() string {
+	return iv.Supplier.Party.Name()
+}
+
+// SupplierVATID returns the Seller VAT identifier (BT-31).
+func (iv Invoice) SupplierVATID() string {
+	return iv.Supplier.Party.VATID()
+}
+
+// SupplierCountry returns the Seller country code (BT-40).
+func (iv Invoice) SupplierCountry() CountryCodeType {
+	return iv.Supplier.Party.Country()
+}
+
+// CustomerName returns the Buyer name (BT-44).
+func (iv Invoice) CustomerName() string {
+	return iv.Customer.Party.Name()
+}
+
+// CustomerVATID returns the Buyer VAT identifier (BT-48).
+func (iv Invoice) CustomerVATID() string {
+	return iv.Customer.Party.VATID()
+}
+
+// CustomerCountry returns the Buyer country code (BT-55).
+func (iv Invoice) CustomerCountry() CountryCodeType {
+	return iv.Customer.Party.Country()
+}
+
+// GetDueDate returns the payment due date (BT-9).
+func (iv Invoice) GetDueDate() types.Date {
+	if iv.DueDate == nil {
+		return types.Date{}
+	}
+	return *iv.DueDate
+}
+
+// DeliveryDate returns the actual delivery date (BT-72).
+func (iv Invoice) DeliveryDate() types.Date {
+	return deliveryDate(iv.Delivery)
+}
+
+// PeriodStartDate returns the invoicing period start date (BT-73).
+func (iv Invoice) PeriodStartDate() types.Date {
+	if iv.InvoicePeriod == nil {
+		return types.Date{}
+	}
+	return dateOrZero(iv.InvoicePeriod.StartDate)
+}
+
+// PeriodEndDate returns the invoicing period end date (BT-74).
+func (iv Invoice) PeriodEndDate() types.Date {
+	if iv.InvoicePeriod == nil {
+		return types.Date{}
+	}
+	return dateOrZero(iv.InvoicePeriod.EndDate)
+}
+
+// OrderID returns the purchase order reference (BT-13).
+func (iv Invoice) OrderID() string {
+	if iv.OrderReference == nil {
+		return ""
+	}
+	return iv.OrderReference.OrderID
+}
+
+// ContractID returns the contract reference (BT-12).
+func (iv Invoice) ContractID() string {
+	return idNodeValue(iv.ContractDocumentReference)
+}
+
+// ProjectID returns the project reference (BT-11).
+func (iv Invoice) ProjectID() string {
+	return idNodeValue(iv.ProjectReference)
+}
+
+// PaymentTermsNote returns the payment terms (BT-20).
+func (iv Invoice) PaymentTermsNote() string {
+	if iv.PaymentTerms == nil {
+		return ""
+	}
+	return iv.PaymentTerms.Note
+}
+
+// PaymentAccountIDs returns the payment account identifiers (BT-84) from all
+// the payment means.
+func (iv Invoice) PaymentAccountIDs() []string {
+	return paymentAccountIDs(iv.PaymentMeans)
+}
+
+// SupplierName returns the Seller name (BT-27).
+func (cn CreditNote) SupplierName() string {
+	return cn.Supplier.Party.Name()
+}
+
+// SupplierVATID returns the Seller VAT identifier (BT-31).
+func (cn CreditNote) SupplierVATID() string {
+	return cn.Supplier.Party.VATID()
+}
+
+// SupplierCountry returns the Seller country code (BT-40).
+func (cn CreditNote) SupplierCountry() CountryCodeType {
+	return cn.Supplier.Party.Country()
+}
+
+// CustomerName returns the Buyer name (BT-44).
+func (cn CreditNote) CustomerName() string {
+	return cn.Customer.Party.Name()
+}
+
+// CustomerVATID returns the Buyer VAT identifier (BT-48).
+func (cn CreditNote) CustomerVATID() string {
+	return cn.Customer.Party.VATID()
+}
+
+// CustomerCountry returns the Buyer country code (BT-55).
+func (cn CreditNote) CustomerCountry() CountryCodeType {
+	return cn.Customer.Party.Country()
+}
+
+// DeliveryDate returns the actual delivery date (BT-72).
+func (cn CreditNote) DeliveryDate() types.Date {
+	return deliveryDate(cn.Delivery)
+}
+
+// OrderID returns the purchase order reference (BT-13).
+func (cn CreditNote) OrderID() string {
+	if cn.OrderReference == nil {
+		return ""
+	}
+	return cn.OrderReference.OrderID
+}
+
+// ContractID returns the contract reference (BT-12).
+func (cn CreditNote) ContractID() string {
+	return idNodeValue(cn.ContractDocumentReference)
+}
+
+// PaymentAccountIDs returns the payment account identifiers (BT-84) from all
+// the payment means.
+func (cn CreditNote) PaymentAccountIDs() []string {
+	return paymentAccountIDs(cn.PaymentMeans)
+}
+
+// VATCategory returns the VAT category code of the invoiced item (BT-151).
+func (l InvoiceLine) VATCategory() TaxCategoryCodeType {
+	return l.Item.TaxCategory.ID
+}
+
+// VATRate returns the VAT rate of the invoiced item (BT-152). The rate is
+// zero for the items not subject to VAT.
+func (l InvoiceLine) VATRate() types.Decimal {
+	return itemVATRate(l.Item)
+}
+
+// OrderLineID returns the referenced purchase order line (BT-132).
+func (l InvoiceLine) OrderLineID() string {
+	return orderLineID(l.OrderLineReference)
+}
+
+// ObjectID returns the invoice line object identifier (BT-128).
+func (l InvoiceLine) ObjectID() string {
+	return lineObjectID(l.DocumentReference)
+}
+
+// BuyerItemID returns the Buyer's item identifier (BT-156).
+func (l InvoiceLine) BuyerItemID() string {
+	return idNodeValue(l.Item.BuyerItemID)
+}
+
+// SellerItemID returns the Seller item identifier (BT-155).
+func (l InvoiceLine) SellerItemID() string {
+	return idNodeValue(l.Item.SellerItemID)
+}
+
+// OriginCountry returns the country code of the item origin (BT-159), or an
+// empty string if not set.
+func (l InvoiceLine) OriginCountry() CountryCodeType {
+	return itemOriginCountry(l.Item)
+}
+
+// ItemProperty returns the value of the invoiced item attribute (BT-161) with
+// the given name (BT-160), and whether the attribute is present.
+func (l InvoiceLine) ItemProperty(name string) (string, bool) {
+	return itemProperty(l.Item, name)
+}
+
+// StandardItemID returns the standard item identifier (BT-157).
+func (l InvoiceLine) StandardItemID() string {
+	return itemStandardID(l.Item)
+}
+
+// PeriodStartDate returns the invoice line period start date (BT-134).
+func (l InvoiceLine) PeriodStartDate() types.Date {
+	if l.InvoicePeriod == nil {
+		return types.Date{}
+	}
+	return dateOrZero(l.InvoicePeriod.StartDate)
+}
+
+// PeriodEndDate returns the invoice line period end date (BT-135).
+func (l InvoiceLine) PeriodEndDate() types.Date {
+	if l.InvoicePeriod == nil {
+		return types.Date{}
+	}
+	return dateOrZero(l.InvoicePeriod.EndDate)
+}
+
+// VATCategory returns the VAT category code of the credited item (BT-151).
+func (l CreditNoteLine) VATCategory() TaxCategoryCodeType {
+	return l.Item.TaxCategory.ID
+}
+
+// VATRate returns the VAT rate of the credited item (BT-152). The rate is
+// zero for the items not subject to VAT.
+func (l CreditNoteLine) VATRate() types.Decimal {
+	return itemVATRate(l.Item)
+}
+
+// OrderLineID returns the referenced purchase order line (BT-132).
+func (l CreditNoteLine) OrderLineID() string {
+	return orderLineID(l.OrderLineReference)
+}
+
+// ObjectID returns the invoice line object identifier (BT-128).
+func (l CreditNoteLine) ObjectID() string {
+	return lineObjectID(l.DocumentReference)
+}
+
+// BuyerItemID returns the Buyer's item identifier (BT-156).
+func (l CreditNoteLine) BuyerItemID() string {
+	return idNodeValue(l.Item.BuyerItemID)
+}
+
+// SellerItemID returns the Seller item identifier (BT-155).
+func (l CreditNoteLine) SellerItemID() string {
+	return idNodeValue(l.Item.SellerItemID)
+}
+
+// OriginCountry returns the country code of the item origin (BT-159), or an
+// empty string if not set.
+func (l CreditNoteLine) OriginCountry() CountryCodeType {
+	return itemOriginCountry(l.Item)
+}
+
+// ItemProperty returns the value of the credited item attribute (BT-161) with
+// the given name (BT-160), and whether the attribute is present.
+func (l CreditNoteLine) ItemProperty(name string) (string, bool) {
+	return itemProperty(l.Item, name)
+}
+
+// StandardItemID returns the standard item identifier (BT-157).
+func (l CreditNoteLine) StandardItemID() string {
+	return itemStandardID(l.Item)
+}
+
+func dateOrZero(d *types.Date) types.Date {
+	if d == nil {
+		return types.Date{}
+	}
+	return *d
+}
+
+func deliveryDate(delivery *InvoiceDelivery) types.Date {
+	if delivery == nil {
+		return types.Date{}
+	}
+	return dateOrZero(delivery.ActualDeliveryDate)
+}
+
+func idNodeValue(n *IDNode) string {
+	if n == nil {
+		return ""
+	}
+	return n.ID
+}
+
+func paymentAccountIDs(paymentMeans []InvoicePaymentMeans) (ids []string) {
+	for _, pm := range paymentMeans {
+		for _, account := range pm.PayeeFinancialAccounts {
+			ids = append(ids, account.ID)
+		}
+	}
+	return
+}
+
+func itemVATRate(item InvoiceLineItem) types.Decimal {
+	if item.TaxCategory.ID == TaxCategoryNotSubjectToVAT {
+		return types.Zero
+	}
+	return item.TaxCategory.Percent
+}
+
+func orderLineID(ref *InvoiceOrderLineReference) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.LineID
+}
+
+func lineObjectID(ref *InvoiceLineDocumentReference) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.ID.Value
+}
+
+func itemOriginCountry(item InvoiceLineItem) CountryCodeType {
+	if item.OriginCountry == nil {
+		return ""
+	}
+	return item.OriginCountry.Code
+}
+
+func itemProperty(item InvoiceLineItem, name string) (string, bool) {
+	for _, property := range item.AdditionalProperties {
+		if property.Name == name {
+			return property.Value, true
+		}
+	}
+	return "", false
+}
+
+func itemStandardID(item InvoiceLineItem) string {
+	if item.StandardItemIdentification == nil {
+		return ""
+	}
+	return item.StandardItemIdentification.Code
+}
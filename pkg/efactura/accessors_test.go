@@ -0,0 +1,95 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestInvoiceAccessors(t *testing.T) {
+	assert := assert.New(t)
+
+	// Missing paths return zero values.
+	var empty Invoice
+	assert.Equal("", empty.SupplierVATID())
+	assert.Equal("", empty.Supplier.Party.Email())
+	assert.Equal(CountryCodeType(""), empty.CustomerCountry())
+	assert.Equal("", empty.Customer.Party.LegalRegistrationID())
+	assert.Equal(types.Date{}, empty.GetDueDate())
+	assert.Equal(types.Date{}, empty.DeliveryDate())
+	assert.Equal(types.Date{}, empty.PeriodEndDate())
+	assert.Equal("", empty.OrderID())
+	assert.Equal("", empty.ContractID())
+	assert.Equal("", empty.PaymentTermsNote())
+	assert.Empty(empty.PaymentAccountIDs())
+	var emptyLine InvoiceLine
+	assert.True(emptyLine.VATRate().IsZero())
+	assert.Equal("", emptyLine.SellerItemID())
+	assert.Equal(types.Date{}, emptyLine.PeriodStartDate())
+
+	dueDate := types.MakeDate(2024, 3, 31)
+	deliveryDate := types.MakeDate(2024, 3, 2)
+	invoice := Invoice{
+		DueDate:                   &dueDate,
+		OrderReference:            &InvoiceOrderReference{OrderID: "PO-1"},
+		ContractDocumentReference: &IDNode{ID: "C-1"},
+		Supplier:                  MakeInvoiceSupplier(getInvoiceSupplierParty()),
+		Customer:                  MakeInvoiceCustomer(getInvoiceCustomerParty()),
+		Delivery:                  &InvoiceDelivery{ActualDeliveryDate: &deliveryDate},
+		PaymentMeans: []InvoicePaymentMeans{{
+			PayeeFinancialAccounts: []PayeeFinancialAccount{{ID: "RO49AAAA1B31007593840000"}},
+		}},
+		InvoiceLines: []InvoiceLine{{
+			Item: InvoiceLineItem{
+				SellerItemID: &IDNode{ID: "SKU-1"},
+				TaxCategory: InvoiceLineTaxCategory{
+					ID:      TaxCategoryVATStandardRate,
+					Percent: types.D(19),
+				},
+			},
+		}, {
+			Item: InvoiceLineItem{
+				TaxCategory: InvoiceLineTaxCategory{
+					ID:      TaxCategoryNotSubjectToVAT,
+					Percent: types.D(19),
+				},
+			},
+		}},
+	}
+	invoice.Supplier.Party.TaxScheme = &InvoicePartyTaxScheme{CompanyID: "RO12345678", TaxScheme: TaxSchemeVAT}
+	assert.Equal(invoice.Supplier.Party.LegalEntity.Name, invoice.SupplierName())
+	assert.Equal(invoice.Supplier.Party.PostalAddress.Country.Code, invoice.SupplierCountry())
+	assert.Equal("RO12345678", invoice.SupplierVATID())
+	assert.Equal("", invoice.Supplier.Party.TaxRegistrationID())
+	assert.Equal(invoice.Customer.Party.LegalEntity.Name, invoice.CustomerName())
+	assert.Equal(dueDate, invoice.GetDueDate())
+	assert.Equal(deliveryDate, invoice.DeliveryDate())
+	assert.Equal("PO-1", invoice.OrderID())
+	assert.Equal("C-1", invoice.ContractID())
+	assert.Equal([]string{"RO49AAAA1B31007593840000"}, invoice.PaymentAccountIDs())
+	assert.True(invoice.InvoiceLines[0].VATRate().Equal(types.D(19)))
+	assert.Equal("SKU-1", invoice.InvoiceLines[0].SellerItemID())
+	// The rate of the items not subject to VAT is zero.
+	assert.True(invoice.InvoiceLines[1].VATRate().IsZero())
+
+	creditNote := invoice.CreditNote()
+	assert.Equal(invoice.SupplierVATID(), creditNote.SupplierVATID())
+	assert.Equal(invoice.CustomerCountry(), creditNote.CustomerCountry())
+	assert.Equal("SKU-1", creditNote.CreditNoteLines[0].SellerItemID())
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// CurrencyMismatchError is returned by the AmountWithCurrency arithmetic
+// methods if the amounts are in different currencies.
+type CurrencyMismatchError struct {
+	// Op is the operation ("add" or "subtract").
+	Op string
+	// CurrencyID is the currency of the receiver.
+	CurrencyID CurrencyCodeType
+	// OtherCurrencyID is the currency of the argument.
+	OtherCurrencyID CurrencyCodeType
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return fmt.Sprintf("cannot %s amounts in different currencies: %s and %s", e.Op, e.CurrencyID, e.OtherCurrencyID)
+}
+
+// UnitMismatchError is returned by the InvoicedQuantity arithmetic methods
+// if the quantities have different units of measure.
+type UnitMismatchError struct {
+	// Op is the operation ("add" or "subtract").
+	Op string
+	// UnitCode is the unit of the receiver.
+	UnitCode UnitCodeType
+	// OtherUnitCode is the unit of the argument.
+	OtherUnitCode UnitCodeType
+}
+
+func (e *UnitMismatchError) Error() string {
+	return fmt.Sprintf("cannot %s quantities with different units: %s and %s", e.Op, e.UnitCode, e.OtherUnitCode)
+}
+
+// MakeAmountWithCurrency creates an AmountWithCurrency with the given amount
+// and currency.
+func MakeAmountWithCurrency(amount types.Decimal, currencyID CurrencyCodeType) AmountWithCurrency {
+	return AmountWithCurrency{Amount: amount, CurrencyID: currencyID}
+}
+
+// combineCurrency returns the currency of the result of an operation with
+// amounts in the currencies a and b. An amount without a currency (eg. the
+// zero value) can be combined with an amount in any currency.
+func combineCurrency(op string, a, b CurrencyCodeType) (CurrencyCodeType, error) {
+	switch {
+	case a == "":
+		return b, nil
+	case b == "" || a == b:
+		return a, nil
+	}
+	return "", &CurrencyMismatchError{Op: op, CurrencyID: a, OtherCurrencyID: b}
+}
+
+// Add returns a + b. If the amounts are in different currencies, a
+// *CurrencyMismatchError is returned. An amount without a currency, like the
+// zero value, is compatible with any currency, so Add can be used to sum
+// amounts starting from the zero value.
+func (a AmountWithCurrency) Add(b AmountWithCurrency) (AmountWithCurrency, error) {
+	currencyID, err := combineCurrency("add", a.CurrencyID, b.CurrencyID)
+	if err != nil {
+		return AmountWithCurrency{}, err
+	}
+	return MakeAmountWithCurrency(a.Amount.Add(b.Amount), currencyID), nil
+}
+
+// Sub returns a - b. If the amounts are in different currencies, a
+// *CurrencyMismatchError is returned.
+func (a AmountWithCurrency) Sub(b AmountWithCurrency) (AmountWithCurrency, error) {
+	currencyID, err := combineCurrency("subtract", a.CurrencyID, b.CurrencyID)
+	if err != nil {
+		return AmountWithCurrency{}, err
+	}
+	return MakeAmountWithCurrency(a.Amount.Sub(b.Amount), currencyID), nil
+}
+
+// Mul returns the amount multiplied by factor (eg. a VAT rate), in the same
+// currency. The result is not rounded.
+func (a AmountWithCurrency) Mul(factor types.Decimal) AmountWithCurrency {
+	return MakeAmountWithCurrency(a.Amount.Mul(factor), a.CurrencyID)
+}
+
+// Neg returns -a.
+func (a AmountWithCurrency) Neg() AmountWithCurrency {
+	return MakeAmountWithCurrency(a.Amount.Neg(), a.CurrencyID)
+}
+
+// AsAmount returns the amount rounded to two decimal places, in the same
+// currency.
+func (a AmountWithCurrency) AsAmount() AmountWithCurrency {
+	return MakeAmountWithCurrency(a.Amount.AsAmount(), a.CurrencyID)
+}
+
+// SumAmounts returns the sum of the given amounts. If the amounts are not all
+// in the same currency, a *CurrencyMismatchError is returned.
+func SumAmounts(amounts ...AmountWithCurrency) (sum AmountWithCurrency, err error) {
+	sum.Amount = types.Zero
+	for _, amount := range amounts {
+		if sum, err = sum.Add(amount); err != nil {
+			return AmountWithCurrency{}, err
+		}
+	}
+	return
+}
+
+// combineUnit returns the unit of measure of the result of an operation with
+// quantities in the units a and b. A quantity without a unit (eg. the zero
+// value) can be combined with a quantity in any unit.
+func combineUnit(op string, a, b InvoicedQuantity) (InvoicedQuantity, error) {
+	switch {
+	case a.UnitCode == "":
+		return b, nil
+	case b.UnitCode == "" || a.UnitCode == b.UnitCode:
+		return a, nil
+	}
+	return InvoicedQuantity{}, &UnitMismatchError{Op: op, UnitCode: a.UnitCode, OtherUnitCode: b.UnitCode}
+}
+
+// Add returns q + q2. If the quantities have different units of measure, a
+// *UnitMismatchError is returned. A quantity without a unit, like the zero
+// value, is compatible with any unit.
+func (q InvoicedQuantity) Add(q2 InvoicedQuantity) (InvoicedQuantity, error) {
+	result, err := combineUnit("add", q, q2)
+	if err != nil {
+		return result, err
+	}
+	result.Quantity = q.Quantity.Add(q2.Quantity)
+	return result, nil
+}
+
+// Sub returns q - q2. If the quantities have different units of measure, a
+// *UnitMismatchError is returned.
+func (q InvoicedQuantity) Sub(q2 InvoicedQuantity) (InvoicedQuantity, error) {
+	result, err := combineUnit("subtract", q, q2)
+	if err != nil {
+		return result, err
+	}
+	result.Quantity = q.Quantity.Sub(q2.Quantity)
+	return result, nil
+}
+
+// Mul returns the quantity multiplied by factor, in the same unit of
+// measure.
+func (q InvoicedQuantity) Mul(factor types.Decimal) InvoicedQuantity {
+	q.Quantity = q.Quantity.Mul(factor)
+	return q
+}
+
+// MulPrice returns the quantity multiplied by the given unit price (eg. the
+// item net price, BT-146), in the currency of the price. The result is not
+// rounded.
+func (q InvoicedQuantity) MulPrice(price AmountWithCurrency) AmountWithCurrency {
+	return price.Mul(q.Quantity)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestAmountWithCurrencyArithmetic(t *testing.T) {
+	assert := assert.New(t)
+
+	a := MakeAmountWithCurrency(types.D(10.50), CurrencyRON)
+	b := MakeAmountWithCurrency(types.D(2.25), CurrencyRON)
+
+	sum, err := a.Add(b)
+	if assert.NoError(err) {
+		assert.True(sum.Amount.Equal(types.D(12.75)))
+		assert.Equal(CurrencyRON, sum.CurrencyID)
+	}
+	diff, err := a.Sub(b)
+	if assert.NoError(err) {
+		assert.True(diff.Amount.Equal(types.D(8.25)))
+		assert.Equal(CurrencyRON, diff.CurrencyID)
+	}
+	vat := a.Mul(types.D(0.19))
+	assert.Equal("1.995", vat.Amount.String())
+	assert.Equal("2", vat.AsAmount().Amount.String())
+	assert.Equal(CurrencyRON, vat.CurrencyID)
+	assert.True(a.Neg().Amount.Equal(types.D(-10.50)))
+
+	// The zero value is compatible with any currency.
+	var total AmountWithCurrency
+	total, err = total.Add(a)
+	if assert.NoError(err) {
+		assert.Equal(a, total)
+	}
+
+	_, err = a.Add(MakeAmountWithCurrency(types.D(1), CurrencyEUR))
+	var mismatch *CurrencyMismatchError
+	if assert.ErrorAs(err, &mismatch) {
+		assert.Equal("add", mismatch.Op)
+		assert.Equal(CurrencyRON, mismatch.CurrencyID)
+		assert.Equal(CurrencyEUR, mismatch.OtherCurrencyID)
+		assert.EqualError(err, "cannot add amounts in different currencies: RON and EUR")
+	}
+	_, err = a.Sub(MakeAmountWithCurrency(types.D(1), CurrencyEUR))
+	assert.ErrorAs(err, &mismatch)
+
+	sum, err = SumAmounts(a, b, b)
+	if assert.NoError(err) {
+		assert.True(sum.Amount.Equal(types.D(15)))
+		assert.Equal(CurrencyRON, sum.CurrencyID)
+	}
+	sum, err = SumAmounts()
+	if assert.NoError(err) {
+		assert.True(sum.Amount.IsZero())
+	}
+	_, err = SumAmounts(a, MakeAmountWithCurrency(types.D(1), CurrencyEUR))
+	assert.ErrorAs(err, &mismatch)
+}
+
+func TestInvoicedQuantityArithmetic(t *testing.T) {
+	assert := assert.New(t)
+
+	q := InvoicedQuantity{Quantity: types.D(3), UnitCode: "H87"}
+	q2 := InvoicedQuantity{Quantity: types.D(2), UnitCode: "H87"}
+
+	sum, err := q.Add(q2)
+	if assert.NoError(err) {
+		assert.True(sum.Quantity.Equal(types.D(5)))
+		assert.Equal(UnitCodeType("H87"), sum.UnitCode)
+	}
+	diff, err := q.Sub(q2)
+	if assert.NoError(err) {
+		assert.True(diff.Quantity.Equal(types.D(1)))
+	}
+	assert.True(q.Mul(types.D(1.5)).Quantity.Equal(types.D(4.5)))
+	assert.Equal(UnitCodeType("H87"), q.Mul(types.D(2)).UnitCode)
+
+	amount := q.MulPrice(MakeAmountWithCurrency(types.D(33.335), CurrencyRON))
+	assert.Equal("100.005", amount.Amount.String())
+	assert.Equal(CurrencyRON, amount.CurrencyID)
+
+	var total InvoicedQuantity
+	total, err = total.Add(q)
+	if assert.NoError(err) {
+		assert.Equal(q, total)
+	}
+
+	_, err = q.Add(InvoicedQuantity{Quantity: types.D(1), UnitCode: "KGM"})
+	var mismatch *UnitMismatchError
+	if assert.ErrorAs(err, &mismatch) {
+		assert.Equal(UnitCodeType("H87"), mismatch.UnitCode)
+		assert.Equal(UnitCodeType("KGM"), mismatch.OtherUnitCode)
+		assert.EqualError(err, "cannot add quantities with different units: H87 and KGM")
+	}
+	_, err = q.Sub(InvoicedQuantity{Quantity: types.D(1), UnitCode: "KGM"})
+	assert.ErrorAs(err, &mismatch)
+}
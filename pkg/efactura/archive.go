@@ -0,0 +1,162 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+const (
+	// defaultArchiveLookupDays is the default number of days used for
+	// locating the sent invoice message in the messages list.
+	defaultArchiveLookupDays = 60
+)
+
+type archiveOptions struct {
+	cif      string
+	numDays  int
+	metadata Metadata
+}
+
+type ArchiveOption func(*archiveOptions)
+
+// ArchiveOptionLookupCIF is an archive option that locates the sent invoice
+// message in the messages list of the given cif for the last numDays days (60
+// if numDays <= 0). The message is checked to be a sent invoice (FACTURA
+// TRIMISA) and the CIF and message type are recorded in the store entry.
+func ArchiveOptionLookupCIF(cif string, numDays int) ArchiveOption {
+	return func(o *archiveOptions) {
+		o.cif = cif
+		o.numDays = numDays
+	}
+}
+
+// ArchiveOptionMetadata is an archive option that sets the user metadata
+// stored with the archive. The metadata is merged over the metadata from the
+// request context (see ContextWithMetadata).
+func ArchiveOptionMetadata(md Metadata) ArchiveOption {
+	return func(o *archiveOptions) {
+		o.metadata = md
+	}
+}
+
+// ArchiveSentInvoiceResponse is the response of ArchiveSentInvoice.
+type ArchiveSentInvoiceResponse struct {
+	// State is the state of the upload.
+	State *GetMessageStateResponse
+	// Message is the sent invoice message, set only if the message was
+	// located using ArchiveOptionLookupCIF.
+	Message *Message
+	// Entry is the store entry of the archive, set only if the archive was
+	// stored.
+	Entry *store.Entry
+}
+
+// IsArchived returns true if the signed invoice was archived.
+func (r *ArchiveSentInvoiceResponse) IsArchived() bool {
+	return r != nil && r.Entry != nil
+}
+
+// ArchiveSentInvoice closes the loop for an uploaded invoice: once the upload
+// with the given upload index was validated (the message state is ok), it
+// locates the sent invoice message (FACTURA TRIMISA), downloads the zip
+// archive with the ANAF signed invoice and stores it in st, keyed by the
+// download ID. If the upload is not validated yet (or was rejected), the
+// response only has the State set and IsArchived() returns false, so the
+// method can be called periodically until the invoice is archived.
+func (c *Client) ArchiveSentInvoice(
+	ctx context.Context, uploadIndex int64, st store.Store, opts ...ArchiveOption,
+) (response *ArchiveSentInvoiceResponse, err error) {
+	archiveOpts := archiveOptions{}
+	for _, opt := range opts {
+		opt(&archiveOpts)
+	}
+	if st == nil {
+		return nil, errors.New("archive: nil store")
+	}
+	// All the requests of the archive operation share the same correlation
+	// ID, which is recorded in the store entry.
+	ctx, correlationID := c.withCorrelationID(ctx)
+
+	state, er := c.GetMessageState(ctx, uploadIndex)
+	if err = er; err != nil {
+		return
+	}
+	response = &ArchiveSentInvoiceResponse{State: state}
+	if !state.IsOk() {
+		return
+	}
+
+	entry := store.Entry{
+		DownloadID:    state.GetDownloadID(),
+		UploadIndex:   uploadIndex,
+		MessageType:   MessageTypeSentInvoice,
+		CorrelationID: correlationID,
+		Metadata:      MetadataFromContext(ctx).Merge(archiveOpts.metadata),
+	}
+	if archiveOpts.cif != "" {
+		numDays := archiveOpts.numDays
+		if numDays <= 0 {
+			numDays = defaultArchiveLookupDays
+		}
+		message, er := c.FindMessageByUploadIndex(ctx, archiveOpts.cif, uploadIndex, numDays)
+		if err = er; err != nil {
+			return
+		}
+		if !message.IsSentInvoice() {
+			err = fmt.Errorf("archive: upload index %d: expected message type %s, got %s",
+				uploadIndex, MessageTypeSentInvoice, message.Type)
+			return
+		}
+		response.Message = message
+		entry.DownloadID = message.GetID()
+		entry.CIF = message.CIF
+		entry.MessageType = message.Type
+	}
+	if entry.DownloadID == 0 {
+		err = fmt.Errorf("archive: upload index %d: missing download ID", uploadIndex)
+		return
+	}
+
+	dres, er := c.DownloadInvoice(ctx, entry.DownloadID)
+	if err = er; err != nil {
+		return
+	}
+	if !dres.IsOk() {
+		err = fmt.Errorf("archive: download %d: %s: %s", entry.DownloadID, dres.Error.Title, dres.Error.Error)
+		return
+	}
+	// Check that the archive is complete before storing it.
+	if _, _, err = parseInvoiceZip(ctx, dres.Zip); err != nil {
+		err = fmt.Errorf("archive: download %d: %w", entry.DownloadID, err)
+		return
+	}
+
+	entry.Key = strconv.FormatInt(entry.DownloadID, 10)
+	entry.Name = entry.Key + ".zip"
+	if err = st.Put(ctx, entry, dres.Zip); err != nil {
+		return
+	}
+	if entry, err = st.Stat(ctx, entry.Key); err != nil {
+		return
+	}
+	response.Entry = &entry
+	return
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// newTestInvoiceZip returns a zip archive like the ones returned by the
+// download endpoint.
+func newTestInvoiceZip(t *testing.T, downloadID int64) []byte {
+	t.Helper()
+	return newTestZip(t, downloadID, "<Invoice/>")
+}
+
+// newTestZip returns a zip archive like the ones returned by the download
+// endpoint, with the given document XML.
+func newTestZip(t *testing.T, downloadID int64, documentXML string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name, content string
+	}{
+		{fmt.Sprintf("%d.xml", downloadID), documentXML},
+		{fmt.Sprintf("semnatura_%d.xml", downloadID), "<Signature/>"},
+	} {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveSentInvoice(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	state := "in prelucrare"
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("5001", r.URL.Query().Get("id_incarcare"))
+		w.Header().Set("Content-Type", "application/xml")
+		downloadID := ""
+		if state == "ok" {
+			downloadID = ` id_descarcare="3001"`
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="%s"%s/>`, state, downloadID)
+	})
+	mux.HandleFunc("/FCTEL/rest/listaMesajeFactura", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, efactura.MessagesListResponse{
+			Messages: []efactura.Message{{
+				ID:          "3001",
+				Type:        efactura.MessageTypeSentInvoice,
+				UploadIndex: "5001",
+				CIF:         "12345678",
+			}},
+		})
+	})
+	var downloads int
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		assert.Equal("3001", r.URL.Query().Get("id"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(newTestInvoiceZip(t, 3001))
+	})
+
+	ctx := efactura.ContextWithMetadata(context.Background(), efactura.Metadata{"tenant": "acme"})
+	st := store.NewMemoryStore()
+
+	res, err := client.ArchiveSentInvoice(ctx, 5001, st)
+	if assert.NoError(err) {
+		assert.False(res.IsArchived())
+		assert.True(res.State.IsProcessing())
+	}
+	assert.Equal(0, downloads)
+
+	state = "ok"
+	res, err = client.ArchiveSentInvoice(ctx, 5001, st,
+		efactura.ArchiveOptionLookupCIF("12345678", 0),
+		efactura.ArchiveOptionMetadata(efactura.Metadata{"source": "erp"}))
+	if assert.NoError(err) && assert.True(res.IsArchived()) {
+		assert.Equal("3001", res.Entry.Key)
+		assert.Equal(int64(5001), res.Entry.UploadIndex)
+		assert.Equal("12345678", res.Entry.CIF)
+		assert.Equal(efactura.MessageTypeSentInvoice, res.Entry.MessageType)
+		assert.Equal(map[string]string{"tenant": "acme", "source": "erp"}, res.Entry.Metadata)
+		assert.NotEmpty(res.Entry.CorrelationID)
+		assert.NotNil(res.Message)
+	}
+
+	entry, data, err := st.Get(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal("3001.zip", entry.Name)
+		assert.Equal(newTestInvoiceZip(t, 3001), data)
+	}
+
+	_, err = client.ArchiveSentInvoice(ctx, 5001, nil)
+	assert.Error(err)
+}
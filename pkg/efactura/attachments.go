@@ -0,0 +1,154 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"encoding/base64"
+)
+
+// DocumentTypeCodeInvoicedObject is the document type code (UNTDID 1001) of
+// an AdditionalDocumentReference used for the invoiced object identifier
+// (BT-18) instead of a supporting document (BG-24).
+const DocumentTypeCodeInvoicedObject = "130"
+
+// AttachmentMimeCodeType is the mime code of an attached document (BT-125-1).
+type AttachmentMimeCodeType string
+
+// The mime codes allowed by EN 16931 for the attached documents.
+const (
+	AttachmentMimeCodePDF  AttachmentMimeCodeType = "application/pdf"
+	AttachmentMimeCodePNG  AttachmentMimeCodeType = "image/png"
+	AttachmentMimeCodeJPEG AttachmentMimeCodeType = "image/jpeg"
+	AttachmentMimeCodeCSV  AttachmentMimeCodeType = "text/csv"
+	AttachmentMimeCodeXLSX AttachmentMimeCodeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	AttachmentMimeCodeODS  AttachmentMimeCodeType = "application/vnd.oasis.opendocument.spreadsheet"
+)
+
+// IsValid returns true if the mime code is allowed by EN 16931 for an
+// attached document.
+func (c AttachmentMimeCodeType) IsValid() bool {
+	switch c {
+	case AttachmentMimeCodePDF, AttachmentMimeCodePNG, AttachmentMimeCodeJPEG,
+		AttachmentMimeCodeCSV, AttachmentMimeCodeXLSX, AttachmentMimeCodeODS:
+		return true
+	}
+	return false
+}
+
+// InvoiceAdditionalDocumentReference is a cac:AdditionalDocumentReference
+// object, used for the supporting documents (BG-24), eg. timesheets or
+// receipts, and for the invoiced object identifier (BT-18, with the
+// DocumentTypeCode set to DocumentTypeCodeInvoicedObject).
+type InvoiceAdditionalDocumentReference struct {
+	// ID: BT-122
+	// Term: Referinţa documentului justificativ
+	// Cardinality: 1..1
+	// ID: BT-18
+	// Term: Identificatorul obiectului facturat
+	// Cardinality: 0..1
+	// ID: BT-18-1
+	// Term: Identificatorul schemei
+	// Cardinality: 0..1
+	ID ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
+	// Term: Codul tipului documentului
+	// Description: Trebuie să fie 130 pentru identificatorul obiectului
+	//     facturat (BT-18) și lipsește pentru documentele justificative.
+	// Cardinality: 0..1
+	DocumentTypeCode string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DocumentTypeCode,omitempty" json:"documentTypeCode,omitempty"`
+	// ID: BT-123
+	// Term: Descrierea documentului justificativ
+	// Cardinality: 0..1
+	DocumentDescription string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DocumentDescription,omitempty" json:"documentDescription,omitempty"`
+	// Term: Documentul justificativ atașat (BT-124, BT-125)
+	// Cardinality: 0..1
+	Attachment *InvoiceAttachment `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Attachment,omitempty" json:"attachment,omitempty"`
+}
+
+// InvoiceAttachment is the attachment of a supporting document (BG-24),
+// either embedded in the invoice or as an external reference.
+type InvoiceAttachment struct {
+	// ID: BT-125
+	// Term: Document ataşat
+	// Cardinality: 0..1
+	EmbeddedDocument *InvoiceEmbeddedDocument `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EmbeddedDocumentBinaryObject,omitempty" json:"embeddedDocument,omitempty"`
+	// ID: BT-124
+	// Term: Localizarea documentului extern
+	// Description: Adresa URL (Uniform Resource Locator) care identifică
+	//     locaţia unde poate fi găsit documentul extern.
+	// Cardinality: 0..1
+	ExternalReference *InvoiceExternalReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ExternalReference,omitempty" json:"externalReference,omitempty"`
+}
+
+// InvoiceEmbeddedDocument is a document embedded in the invoice as a base64
+// encoded cbc:EmbeddedDocumentBinaryObject.
+type InvoiceEmbeddedDocument struct {
+	// Value is the base64 encoded content of the document.
+	Value string `xml:",chardata" json:"value,omitempty"`
+	// ID: BT-125-1
+	// Term: Codul MIME al documentului ataşat
+	// Cardinality: 1..1
+	MimeCode AttachmentMimeCodeType `xml:"mimeCode,attr" json:"mimeCode,omitempty"`
+	// ID: BT-125-2
+	// Term: Numele fişierului documentului ataşat
+	// Cardinality: 1..1
+	Filename string `xml:"filename,attr" json:"filename,omitempty"`
+}
+
+// NewInvoiceEmbeddedDocument creates a new InvoiceEmbeddedDocument with the
+// given content, mime code and file name.
+func NewInvoiceEmbeddedDocument(content []byte, mimeCode AttachmentMimeCodeType, filename string) *InvoiceEmbeddedDocument {
+	return &InvoiceEmbeddedDocument{
+		Value:    base64.StdEncoding.EncodeToString(content),
+		MimeCode: mimeCode,
+		Filename: filename,
+	}
+}
+
+// Content returns the decoded content of the embedded document.
+func (d InvoiceEmbeddedDocument) Content() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(d.Value)
+}
+
+// InvoiceExternalReference is the location of an external document.
+type InvoiceExternalReference struct {
+	URI string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 URI" json:"uri,omitempty"`
+}
+
+// MakeSupportingDocument creates an AdditionalDocumentReference for a
+// supporting document (BG-24) with the given reference and description,
+// embedding the given content.
+func MakeSupportingDocument(id, description string, content []byte, mimeCode AttachmentMimeCodeType, filename string) InvoiceAdditionalDocumentReference {
+	return InvoiceAdditionalDocumentReference{
+		ID:                  MakeValueWithAttrs(id),
+		DocumentDescription: description,
+		Attachment: &InvoiceAttachment{
+			EmbeddedDocument: NewInvoiceEmbeddedDocument(content, mimeCode, filename),
+		},
+	}
+}
+
+// MakeInvoicedObjectReference creates an AdditionalDocumentReference for the
+// invoiced object identifier (BT-18) with the given identifier and an
+// optional scheme ID (BT-18-1).
+func MakeInvoicedObjectReference(id, schemeID string) InvoiceAdditionalDocumentReference {
+	ref := InvoiceAdditionalDocumentReference{
+		ID:               MakeValueWithAttrs(id),
+		DocumentTypeCode: DocumentTypeCodeInvoicedObject,
+	}
+	if schemeID != "" {
+		ref.ID = MakeValueWithScheme(id, schemeID)
+	}
+	return ref
+}
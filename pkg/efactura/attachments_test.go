@@ -0,0 +1,99 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestInvoiceAdditionalDocumentReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("HUR").
+		WithInvoicedQuantity(types.D(10)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Consultanta").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	timesheet := []byte("data,ore\n2024-03-01,8\n2024-03-02,2\n")
+	invoice, err := NewInvoiceBuilder("FCT-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 3, 31)).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithInvoiceLines([]InvoiceLine{line}).
+		WithAdditionalDocumentReferences([]InvoiceAdditionalDocumentReference{
+			MakeInvoicedObjectReference("CTR-2024-1", "ABT"),
+		}).
+		AppendAdditionalDocumentReferences(
+			MakeSupportingDocument("PONTAJ-03", "Pontaj martie", timesheet, AttachmentMimeCodeCSV, "pontaj.csv"),
+			InvoiceAdditionalDocumentReference{
+				ID: MakeValueWithAttrs("BON-1"),
+				Attachment: &InvoiceAttachment{
+					ExternalReference: &InvoiceExternalReference{URI: "https://example.com/bon-1.pdf"},
+				},
+			},
+		).
+		Build()
+	if !assert.NoError(err) || !assert.Len(invoice.AdditionalDocumentReferences, 3) {
+		return
+	}
+
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	xmlStr := string(xmlData)
+	assert.Contains(xmlStr, "<cac:AdditionalDocumentReference>"+
+		`<cbc:ID schemeID="ABT">CTR-2024-1</cbc:ID>`+
+		"<cbc:DocumentTypeCode>130</cbc:DocumentTypeCode>"+
+		"</cac:AdditionalDocumentReference>")
+	assert.Contains(xmlStr, "<cac:AdditionalDocumentReference>"+
+		"<cbc:ID>PONTAJ-03</cbc:ID>"+
+		"<cbc:DocumentDescription>Pontaj martie</cbc:DocumentDescription>"+
+		"<cac:Attachment>"+
+		`<cbc:EmbeddedDocumentBinaryObject mimeCode="text/csv" filename="pontaj.csv">`+
+		invoice.AdditionalDocumentReferences[1].Attachment.EmbeddedDocument.Value+
+		"</cbc:EmbeddedDocumentBinaryObject>"+
+		"</cac:Attachment>"+
+		"</cac:AdditionalDocumentReference>")
+	assert.Contains(xmlStr, "<cac:ExternalReference><cbc:URI>https://example.com/bon-1.pdf</cbc:URI></cac:ExternalReference>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) {
+		assert.Equal(invoice.AdditionalDocumentReferences, parsed.AdditionalDocumentReferences)
+		content, err := parsed.AdditionalDocumentReferences[1].Attachment.EmbeddedDocument.Content()
+		if assert.NoError(err) {
+			assert.Equal(timesheet, content)
+		}
+	}
+	assert.Empty(ValidateInvoiceOffline(invoice))
+
+	assert.True(AttachmentMimeCodeXLSX.IsValid())
+	assert.False(AttachmentMimeCodeType("application/zip").IsValid())
+}
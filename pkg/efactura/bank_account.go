@@ -0,0 +1,217 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	ierrors "github.com/printesoi/e-factura-go/internal/errors"
+)
+
+var (
+	// ErrInvalidBIC is returned if a BIC/SWIFT code has an invalid format.
+	ErrInvalidBIC = errors.New("invalid BIC")
+	// ErrInvalidIBAN is returned if an IBAN has an invalid format or check
+	// digits.
+	ErrInvalidIBAN = errors.New("invalid IBAN")
+	// ErrBICCountryMismatch is returned if the country of the BIC does not
+	// match the country of the IBAN.
+	ErrBICCountryMismatch = errors.New("BIC country does not match IBAN country")
+)
+
+var (
+	// 4 letters bank code, 2 letters country code, 2 alphanumeric location
+	// code and an optional 3 alphanumeric branch code (ISO 9362).
+	regexBIC = regexp.MustCompile(`^[A-Z]{4}([A-Z]{2})[A-Z0-9]{2}(?:[A-Z0-9]{3})?$`)
+	// 2 letters country code, 2 check digits and up to 30 alphanumeric
+	// characters for the BBAN (ISO 13616).
+	regexIBAN = regexp.MustCompile(`^([A-Z]{2})[0-9]{2}[A-Z0-9]{11,30}$`)
+)
+
+// bicCountryAliases maps the IBAN countries to the countries that can be
+// used in the BIC of an account from that country (eg. the Channel Islands
+// and Isle of Man use GB IBANs).
+var bicCountryAliases = map[CountryCodeType][]CountryCodeType{
+	CountryCodeGB: {CountryCodeGG, CountryCodeJE, CountryCodeIM},
+	CountryCodeFR: {
+		CountryCodeGF, CountryCodeGP, CountryCodeMQ, CountryCodeRE,
+		CountryCodeYT, CountryCodeBL, CountryCodeMF, CountryCodePM,
+		CountryCodeNC, CountryCodePF, CountryCodeWF, CountryCodeMC,
+	},
+}
+
+// normalizeAccountCode removes the spaces from an IBAN or BIC and converts it
+// to upper case.
+func normalizeAccountCode(code string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(code), ""))
+}
+
+// ValidateBIC checks that bic is a valid BIC/SWIFT code (8 or 11
+// characters). Spaces and lower case letters are accepted.
+func ValidateBIC(bic string) error {
+	if !regexBIC.MatchString(normalizeAccountCode(bic)) {
+		return fmt.Errorf("%w: %q", ErrInvalidBIC, bic)
+	}
+	return nil
+}
+
+// BICCountry returns the country code of bic, or an empty string if bic is
+// not a valid BIC.
+func BICCountry(bic string) CountryCodeType {
+	m := regexBIC.FindStringSubmatch(normalizeAccountCode(bic))
+	if m == nil {
+		return ""
+	}
+	return CountryCodeType(m[1])
+}
+
+// IsIBAN returns true if account has the format of an IBAN. It does not
+// verify the check digits (see ValidateIBAN).
+func IsIBAN(account string) bool {
+	return regexIBAN.MatchString(normalizeAccountCode(account))
+}
+
+// ValidateIBAN checks the format and the check digits (mod 97) of iban.
+// Spaces and lower case letters are accepted.
+func ValidateIBAN(iban string) error {
+	norm := normalizeAccountCode(iban)
+	if !regexIBAN.MatchString(norm) {
+		return fmt.Errorf("%w: %q", ErrInvalidIBAN, iban)
+	}
+	// Move the first 4 characters to the end and replace the letters with
+	// two digits (A=10, ..., Z=35). The result mod 97 must be 1.
+	var digits strings.Builder
+	for _, r := range norm[4:] + norm[:4] {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	if n.Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("%w: %q: wrong check digits", ErrInvalidIBAN, iban)
+	}
+	return nil
+}
+
+// IBANCountry returns the country code of iban, or an empty string if iban
+// does not have the format of an IBAN.
+func IBANCountry(iban string) CountryCodeType {
+	m := regexIBAN.FindStringSubmatch(normalizeAccountCode(iban))
+	if m == nil {
+		return ""
+	}
+	return CountryCodeType(m[1])
+}
+
+// CheckIBANBICCountry checks that the country of the iban and the country of
+// the bic are consistent. Some territories use the IBAN country of another
+// country (eg. Jersey uses GB IBANs), those are accepted.
+func CheckIBANBICCountry(iban, bic string) error {
+	ibanCountry, bicCountry := IBANCountry(iban), BICCountry(bic)
+	if ibanCountry == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidIBAN, iban)
+	}
+	if bicCountry == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidBIC, bic)
+	}
+	if ibanCountry == bicCountry {
+		return nil
+	}
+	for _, alias := range bicCountryAliases[ibanCountry] {
+		if alias == bicCountry {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: IBAN country %s, BIC country %s", ErrBICCountryMismatch, ibanCountry, bicCountry)
+}
+
+// PayeeFinancialAccountBuilder builds a PayeeFinancialAccount object (BG-17).
+type PayeeFinancialAccountBuilder struct {
+	id           string
+	name         string
+	currencyCode CurrencyCodeType
+	bic          string
+}
+
+// NewPayeeFinancialAccountBuilder creates a new PayeeFinancialAccountBuilder
+// for the account with the given ID (BT-84, usually an IBAN).
+func NewPayeeFinancialAccountBuilder(id string) *PayeeFinancialAccountBuilder {
+	b := new(PayeeFinancialAccountBuilder)
+	return b.WithID(id)
+}
+
+func (b *PayeeFinancialAccountBuilder) WithID(id string) *PayeeFinancialAccountBuilder {
+	b.id = id
+	return b
+}
+
+func (b *PayeeFinancialAccountBuilder) WithName(name string) *PayeeFinancialAccountBuilder {
+	b.name = name
+	return b
+}
+
+func (b *PayeeFinancialAccountBuilder) WithCurrencyCode(currencyCode CurrencyCodeType) *PayeeFinancialAccountBuilder {
+	b.currencyCode = currencyCode
+	return b
+}
+
+// WithBIC sets the BIC/SWIFT code of the payment service provider (BT-86).
+// The code is validated by Build.
+func (b *PayeeFinancialAccountBuilder) WithBIC(bic string) *PayeeFinancialAccountBuilder {
+	b.bic = bic
+	return b
+}
+
+// Build builds the PayeeFinancialAccount. If the account ID has the format
+// of an IBAN, the check digits are validated, and if a BIC is also set, the
+// IBAN and BIC countries must be consistent. The IBAN and the BIC are
+// normalized (spaces removed, upper case).
+func (b PayeeFinancialAccountBuilder) Build() (account PayeeFinancialAccount, err error) {
+	if strings.TrimSpace(b.id) == "" {
+		err = ierrors.NewBuilderErrorf(b, "BT-84", "account id not set")
+		return
+	}
+	account.ID = b.id
+	isIBAN := IsIBAN(b.id)
+	if isIBAN {
+		if er := ValidateIBAN(b.id); er != nil {
+			err = ierrors.NewBuilderErrorf(b, "BT-84", "%w", er)
+			return
+		}
+		account.ID = normalizeAccountCode(b.id)
+	}
+	if b.bic != "" {
+		if er := ValidateBIC(b.bic); er != nil {
+			err = ierrors.NewBuilderErrorf(b, "BT-86", "%w", er)
+			return
+		}
+		if isIBAN {
+			if er := CheckIBANBICCountry(b.id, b.bic); er != nil {
+				err = ierrors.NewBuilderErrorf(b, "BT-86", "%w", er)
+				return
+			}
+		}
+		account.FinancialInstitutionBranch = NewIDNode(normalizeAccountCode(b.bic))
+	}
+	account.Name = b.name
+	account.CurrencyCode = b.currencyCode
+	return
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestValidateBIC(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, bic := range []string{"BTRLRO22", "RNCBROBU", "RNCBROBUXXX", "bacx ro bu", "DEUTDEFF500"} {
+		assert.NoError(efactura.ValidateBIC(bic), bic)
+	}
+	for _, bic := range []string{"", "BTRLRO2", "BTRLRO22X", "BTR1RO22", "BTRLR022", "BTRLRO22-XX"} {
+		assert.True(errors.Is(efactura.ValidateBIC(bic), efactura.ErrInvalidBIC), bic)
+	}
+	assert.Equal(efactura.CountryCodeRO, efactura.BICCountry("RNCBROBUXXX"))
+	assert.Equal(efactura.CountryCodeType(""), efactura.BICCountry("invalid"))
+}
+
+func TestValidateIBAN(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, iban := range []string{"RO49AAAA1B31007593840000", "RO49 AAAA 1B31 0075 9384 0000", "DE89370400440532013000", "gb82west12345698765432"} {
+		assert.NoError(efactura.ValidateIBAN(iban), iban)
+	}
+	for _, iban := range []string{"", "RO48AAAA1B31007593840000", "RO49AAAA", "1O49AAAA1B31007593840000"} {
+		assert.True(errors.Is(efactura.ValidateIBAN(iban), efactura.ErrInvalidIBAN), iban)
+	}
+	assert.Equal(efactura.CountryCodeDE, efactura.IBANCountry("DE89370400440532013000"))
+}
+
+func TestCheckIBANBICCountry(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(efactura.CheckIBANBICCountry("RO49AAAA1B31007593840000", "BTRLRO22"))
+	assert.NoError(efactura.CheckIBANBICCountry("GB82WEST12345698765432", "ABCDJESH"))
+	assert.True(errors.Is(efactura.CheckIBANBICCountry("RO49AAAA1B31007593840000", "DEUTDEFF"),
+		efactura.ErrBICCountryMismatch))
+	assert.True(errors.Is(efactura.CheckIBANBICCountry("RO49AAAA1B31007593840000", "invalid"),
+		efactura.ErrInvalidBIC))
+}
+
+func TestPayeeFinancialAccountBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	account, err := efactura.NewPayeeFinancialAccountBuilder("ro49 aaaa 1b31 0075 9384 0000").
+		WithName("Cont RON").
+		WithCurrencyCode(efactura.CurrencyRON).
+		WithBIC("btrlro22").
+		Build()
+	if assert.NoError(err) {
+		assert.Equal("RO49AAAA1B31007593840000", account.ID)
+		assert.Equal("Cont RON", account.Name)
+		assert.Equal(efactura.CurrencyRON, account.CurrencyCode)
+		if assert.NotNil(account.FinancialInstitutionBranch) {
+			assert.Equal("BTRLRO22", account.FinancialInstitutionBranch.ID)
+		}
+	}
+
+	// Account IDs that are not IBANs are kept as is, only the BIC is
+	// validated.
+	account, err = efactura.NewPayeeFinancialAccountBuilder("123-456").WithBIC("DEUTDEFF").Build()
+	if assert.NoError(err) {
+		assert.Equal("123-456", account.ID)
+	}
+
+	_, err = efactura.NewPayeeFinancialAccountBuilder("").Build()
+	assert.Error(err)
+	_, err = efactura.NewPayeeFinancialAccountBuilder("RO48AAAA1B31007593840000").Build()
+	assert.True(errors.Is(err, efactura.ErrInvalidIBAN))
+	_, err = efactura.NewPayeeFinancialAccountBuilder("RO49AAAA1B31007593840000").WithBIC("BTRL").Build()
+	assert.True(errors.Is(err, efactura.ErrInvalidBIC))
+	_, err = efactura.NewPayeeFinancialAccountBuilder("RO49AAAA1B31007593840000").WithBIC("DEUTDEFF").Build()
+	assert.True(errors.Is(err, efactura.ErrBICCountryMismatch))
+}
@@ -15,6 +15,10 @@
 package efactura
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	ierrors "github.com/printesoi/e-factura-go/internal/errors"
 	"github.com/printesoi/e-factura-go/internal/ptr"
 	"github.com/printesoi/e-factura-go/pkg/types"
@@ -77,6 +81,16 @@ func (b *InvoiceLineAllowanceChargeBuilder) WithAllowanceChargeReasonCode(allowa
 	return b
 }
 
+// WithAllowanceReasonCode sets the reason code of an allowance (UNTDID 5189).
+func (b *InvoiceLineAllowanceChargeBuilder) WithAllowanceReasonCode(code AllowanceReasonCodeType) *InvoiceLineAllowanceChargeBuilder {
+	return b.WithAllowanceChargeReasonCode(string(code))
+}
+
+// WithChargeReasonCode sets the reason code of a charge (UNTDID 7161).
+func (b *InvoiceLineAllowanceChargeBuilder) WithChargeReasonCode(code ChargeReasonCodeType) *InvoiceLineAllowanceChargeBuilder {
+	return b.WithAllowanceChargeReasonCode(string(code))
+}
+
 func (b *InvoiceLineAllowanceChargeBuilder) WithAllowanceChargeReason(allowanceChargeReason string) *InvoiceLineAllowanceChargeBuilder {
 	b.allowanceChargeReason = ptr.String(allowanceChargeReason)
 	return b
@@ -91,6 +105,12 @@ func (b InvoiceLineAllowanceChargeBuilder) Build() (allowanceCharge InvoiceLineA
 		err = ierrors.NewBuilderErrorf(b, "", "currency not set")
 		return
 	}
+	if b.allowanceChargeReasonCode != nil {
+		if verr := ValidateAllowanceChargeReasonCode(b.chargeIndicator, *b.allowanceChargeReasonCode); verr != nil {
+			err = ierrors.NewBuilderErrorf(b, "", "%v", verr)
+			return
+		}
+	}
 	allowanceCharge.ChargeIndicator = b.chargeIndicator
 	allowanceCharge.Amount = AmountWithCurrency{
 		Amount:     b.amount,
@@ -124,16 +144,22 @@ type InvoiceLineBuilder struct {
 
 	grossPriceAmount types.Decimal
 	priceDeduction   types.Decimal
+	priceDecimals    *int32
 
-	invoicePeriod     *InvoiceLinePeriod
-	allowancesCharges []InvoiceLineAllowanceCharge
+	invoicePeriod      *InvoiceLinePeriod
+	orderLineReference *string
+	documentReference  *InvoiceLineDocumentReference
+	allowancesCharges  []InvoiceLineAllowanceCharge
 
 	itemName                       string
 	itemDescription                string
+	itemBuyerID                    *string
 	itemSellerID                   *string
 	itemStandardItemIdentification *ItemStandardIdentificationCode
+	itemOriginCountry              *CountryCodeType
 	itemCommodityClassification    *ItemCommodityClassification
 	itemTaxCategory                InvoiceLineTaxCategory
+	itemProperties                 []InvoiceItemProperty
 }
 
 // NewInvoiceLineBuilder creates a new InvoiceLineBuilder
@@ -182,11 +208,30 @@ func (b *InvoiceLineBuilder) WithPriceDeduction(deduction types.Decimal) *Invoic
 	return b
 }
 
+// WithPricePrecision sets the maximum number of decimals of the item prices
+// (BT-146, BT-147, BT-148). The prices are rounded to this number of decimals
+// before computing the line net amount. The default is the PriceDecimals of
+// the DefaultPrecisionPolicy.
+func (b *InvoiceLineBuilder) WithPricePrecision(decimals int32) *InvoiceLineBuilder {
+	b.priceDecimals = &decimals
+	return b
+}
+
 func (b *InvoiceLineBuilder) WithInvoicePeriod(invoicePeriod *InvoiceLinePeriod) *InvoiceLineBuilder {
 	b.invoicePeriod = invoicePeriod
 	return b
 }
 
+func (b *InvoiceLineBuilder) WithOrderLineReference(lineID string) *InvoiceLineBuilder {
+	b.orderLineReference = &lineID
+	return b
+}
+
+func (b *InvoiceLineBuilder) WithDocumentReference(reference InvoiceLineDocumentReference) *InvoiceLineBuilder {
+	b.documentReference = &reference
+	return b
+}
+
 func (b *InvoiceLineBuilder) WithAllowancesCharges(allowancesCharges []InvoiceLineAllowanceCharge) *InvoiceLineBuilder {
 	b.allowancesCharges = allowancesCharges
 	return b
@@ -206,6 +251,11 @@ func (b *InvoiceLineBuilder) WithItemDescription(description string) *InvoiceLin
 	return b
 }
 
+func (b *InvoiceLineBuilder) WithItemBuyerID(id string) *InvoiceLineBuilder {
+	b.itemBuyerID = &id
+	return b
+}
+
 func (b *InvoiceLineBuilder) WithItemSellerID(id string) *InvoiceLineBuilder {
 	b.itemSellerID = &id
 	return b
@@ -216,6 +266,11 @@ func (b *InvoiceLineBuilder) WithItemStandardItemIdentification(identification I
 	return b
 }
 
+func (b *InvoiceLineBuilder) WithItemOriginCountry(country CountryCodeType) *InvoiceLineBuilder {
+	b.itemOriginCountry = &country
+	return b
+}
+
 func (b *InvoiceLineBuilder) WithItemCommodityClassification(classification ItemCommodityClassification) *InvoiceLineBuilder {
 	b.itemCommodityClassification = &classification
 	return b
@@ -226,6 +281,15 @@ func (b *InvoiceLineBuilder) WithItemTaxCategory(taxCategory InvoiceLineTaxCateg
 	return b
 }
 
+func (b *InvoiceLineBuilder) WithItemProperties(properties []InvoiceItemProperty) *InvoiceLineBuilder {
+	b.itemProperties = properties
+	return b
+}
+
+func (b *InvoiceLineBuilder) AppendItemProperty(name, value string) *InvoiceLineBuilder {
+	return b.WithItemProperties(append(b.itemProperties, MakeInvoiceItemProperty(name, value)))
+}
+
 func (b InvoiceLineBuilder) Build() (line InvoiceLine, err error) {
 	if b.id == "" {
 		err = ierrors.NewBuilderErrorf(b, "", "id not set")
@@ -255,6 +319,24 @@ func (b InvoiceLineBuilder) Build() (line InvoiceLine, err error) {
 		err = ierrors.NewBuilderErrorf(b, "", "item tax category not set")
 		return
 	}
+	if b.orderLineReference != nil && *b.orderLineReference == "" {
+		err = ierrors.NewBuilderErrorf(b, "", "order line reference not set")
+		return
+	}
+	if b.documentReference != nil && b.documentReference.ID.Value == "" {
+		err = ierrors.NewBuilderErrorf(b, "", "document reference id not set")
+		return
+	}
+	if b.itemOriginCountry != nil && !b.itemOriginCountry.IsValid() {
+		err = ierrors.NewBuilderErrorf(b, "", "invalid item origin country %q", *b.itemOriginCountry)
+		return
+	}
+	for _, property := range b.itemProperties {
+		if property.Name == "" || property.Value == "" {
+			err = ierrors.NewBuilderErrorf(b, "", "item property name or value not set")
+			return
+		}
+	}
 
 	line.ID = b.id
 	line.Note = b.note
@@ -262,23 +344,25 @@ func (b InvoiceLineBuilder) Build() (line InvoiceLine, err error) {
 		Quantity: b.invoicedQuantity,
 		UnitCode: b.unitCode,
 	}
+	pricePrecision := DefaultPrecisionPolicy().PriceDecimals
+	if b.priceDecimals != nil {
+		pricePrecision = *b.priceDecimals
+	}
+	grossPriceAmount := b.grossPriceAmount.Round(pricePrecision)
+	priceDeduction := b.priceDeduction.Round(pricePrecision)
 	var netPriceAmount types.Decimal
-	if b.priceDeduction.IsZero() {
-		netPriceAmount = b.grossPriceAmount
+	if priceDeduction.IsZero() {
+		netPriceAmount = grossPriceAmount
 	} else {
-		netPriceAmount = b.grossPriceAmount.Sub(b.priceDeduction)
-		line.Price.PriceAmount = AmountWithCurrency{
-			Amount:     netPriceAmount,
-			CurrencyID: b.currencyID,
-		}
+		netPriceAmount = grossPriceAmount.Sub(priceDeduction)
 		line.Price.AllowanceCharge = &InvoiceLinePriceAllowanceCharge{
 			ChargeIndicator: false,
 			Amount: AmountWithCurrency{
-				Amount:     b.priceDeduction,
+				Amount:     priceDeduction,
 				CurrencyID: b.currencyID,
 			},
 			BaseAmount: AmountWithCurrency{
-				Amount:     b.grossPriceAmount,
+				Amount:     grossPriceAmount,
 				CurrencyID: b.currencyID,
 			},
 		}
@@ -296,15 +380,26 @@ func (b InvoiceLineBuilder) Build() (line InvoiceLine, err error) {
 
 	line.Item.Name = b.itemName
 	line.Item.Description = b.itemDescription
+	if b.itemBuyerID != nil {
+		line.Item.BuyerItemID = NewIDNode(*b.itemBuyerID)
+	}
 	if b.itemSellerID != nil {
 		line.Item.SellerItemID = NewIDNode(*b.itemSellerID)
 	}
 	line.Item.StandardItemIdentification = b.itemStandardItemIdentification
+	if b.itemOriginCountry != nil {
+		line.Item.OriginCountry = &Country{Code: *b.itemOriginCountry}
+	}
 	line.Item.CommodityClassification = b.itemCommodityClassification
 	line.Item.TaxCategory = b.itemTaxCategory
+	line.Item.AdditionalProperties = b.itemProperties
 
 	line.AllowanceCharges = b.allowancesCharges
 	line.InvoicePeriod = b.invoicePeriod
+	if b.orderLineReference != nil {
+		line.OrderLineReference = &InvoiceOrderLineReference{LineID: *b.orderLineReference}
+	}
+	line.DocumentReference = b.documentReference
 
 	// Invoiced quantity * (Item net price / item price base quantity)
 	//  + Sum of invoice line charge amount
@@ -340,6 +435,7 @@ type InvoiceDocumentAllowanceChargeBuilder struct {
 	amount                    types.Decimal
 	taxCategory               InvoiceTaxCategory
 	baseAmount                *types.Decimal
+	percent                   *types.Decimal
 	allowanceChargeReasonCode *string
 	allowanceChargeReason     *string
 }
@@ -391,17 +487,38 @@ func (b *InvoiceDocumentAllowanceChargeBuilder) WithBaseAmount(amount types.Deci
 	return b
 }
 
+// WithPercent sets the percentage (BT-94 for allowances, BT-101 for charges)
+// applied to the base amount (see WithBaseAmount). If the amount is not set,
+// Build computes it from the base amount and the percentage.
+func (b *InvoiceDocumentAllowanceChargeBuilder) WithPercent(percent types.Decimal) *InvoiceDocumentAllowanceChargeBuilder {
+	b.percent = percent.Ptr()
+	return b
+}
+
 func (b *InvoiceDocumentAllowanceChargeBuilder) WithAllowanceChargeReasonCode(allowanceChargeReasonCode string) *InvoiceDocumentAllowanceChargeBuilder {
 	b.allowanceChargeReasonCode = ptr.String(allowanceChargeReasonCode)
 	return b
 }
 
+// WithAllowanceReasonCode sets the reason code of an allowance (UNTDID 5189).
+func (b *InvoiceDocumentAllowanceChargeBuilder) WithAllowanceReasonCode(code AllowanceReasonCodeType) *InvoiceDocumentAllowanceChargeBuilder {
+	return b.WithAllowanceChargeReasonCode(string(code))
+}
+
+// WithChargeReasonCode sets the reason code of a charge (UNTDID 7161).
+func (b *InvoiceDocumentAllowanceChargeBuilder) WithChargeReasonCode(code ChargeReasonCodeType) *InvoiceDocumentAllowanceChargeBuilder {
+	return b.WithAllowanceChargeReasonCode(string(code))
+}
+
 func (b *InvoiceDocumentAllowanceChargeBuilder) WithAllowanceChargeReason(allowanceChargeReason string) *InvoiceDocumentAllowanceChargeBuilder {
 	b.allowanceChargeReason = ptr.String(allowanceChargeReason)
 	return b
 }
 
 func (b InvoiceDocumentAllowanceChargeBuilder) Build() (allowanceCharge InvoiceDocumentAllowanceCharge, err error) {
+	if !b.amount.IsInitialized() && b.percent != nil && b.baseAmount != nil {
+		b.amount = b.baseAmount.Mul(*b.percent).Div(types.D(100)).AsAmount()
+	}
 	if !b.amount.IsInitialized() {
 		err = ierrors.NewBuilderErrorf(b, "", "amount not set")
 		return
@@ -414,6 +531,12 @@ func (b InvoiceDocumentAllowanceChargeBuilder) Build() (allowanceCharge InvoiceD
 		err = ierrors.NewBuilderErrorf(b, "", "item tax category not set")
 		return
 	}
+	if b.allowanceChargeReasonCode != nil {
+		if verr := ValidateAllowanceChargeReasonCode(b.chargeIndicator, *b.allowanceChargeReasonCode); verr != nil {
+			err = ierrors.NewBuilderErrorf(b, "", "%v", verr)
+			return
+		}
+	}
 	allowanceCharge.ChargeIndicator = b.chargeIndicator
 	allowanceCharge.Amount = AmountWithCurrency{
 		Amount:     b.amount,
@@ -426,6 +549,9 @@ func (b InvoiceDocumentAllowanceChargeBuilder) Build() (allowanceCharge InvoiceD
 			CurrencyID: b.currencyID,
 		}
 	}
+	if b.percent != nil {
+		allowanceCharge.Percent = b.percent.Ptr()
+	}
 	if b.allowanceChargeReasonCode != nil {
 		allowanceCharge.AllowanceChargeReasonCode = *b.allowanceChargeReasonCode
 	}
@@ -458,17 +584,29 @@ type InvoiceBuilder struct {
 	orderReference            *InvoiceOrderReference
 	notes                     []InvoiceNote
 	invoicePeriod             *InvoicePeriod
+	vatPointDateCode          VATPointDateCodeType
+	delivery                  *InvoiceDelivery
 	billingReferences         []InvoiceDocumentReference
 	contractDocumentReference *string
+	additionalDocuments       []InvoiceAdditionalDocumentReference
 	supplier                  InvoiceSupplierParty
 	customer                  InvoiceCustomerParty
-	paymentMeans              *InvoicePaymentMeans
+	paymentMeans              []InvoicePaymentMeans
 	paymentTerms              *InvoicePaymentTerms
 
-	allowancesCharges []InvoiceDocumentAllowanceCharge
-	invoiceLines      []InvoiceLine
+	allowancesCharges       []InvoiceDocumentAllowanceCharge
+	allowanceChargeBuilders []*InvoiceDocumentAllowanceChargeBuilder
+	invoiceLines            []InvoiceLine
+	lineBuilders            []*InvoiceLineBuilder
+
+	validate   bool
+	truncation *TruncationPolicy
 
 	expectedTaxInclusiveAmount *types.Decimal
+	payableRoundingIncrement   *types.Decimal
+	payableRoundingMode        types.RoundingMode
+	prepaidAmount              *types.Decimal
+	advanceInvoices            []InvoiceDocumentReference
 }
 
 func NewInvoiceBuilder(id string) (b *InvoiceBuilder) {
@@ -539,6 +677,22 @@ func (b *InvoiceBuilder) AppendAllowanceCharge(allowanceCharge InvoiceDocumentAl
 	return b.WithAllowancesCharges(append(b.allowancesCharges, allowanceCharge))
 }
 
+// AppendAllowanceChargeBuilders appends document level allowances and charges
+// that are built by Build (after the ones set with
+// WithAllowancesCharges/AppendAllowanceCharge). If not set, the currency
+// defaults to the document currency. The amount of each allowance (charge)
+// is included in the AllowanceTotalAmount (ChargeTotalAmount) and subtracted
+// from (added to) the taxable amount of its VAT category. The allowances and
+// charges built from these builders are checked by Build: the currency must
+// be the document currency, and an allowance must have the VAT category of
+// at least one invoice line. The allowances and charges set with
+// WithAllowancesCharges/AppendAllowanceCharge are used as they are, without
+// these checks.
+func (b *InvoiceBuilder) AppendAllowanceChargeBuilders(allowanceChargeBuilders ...*InvoiceDocumentAllowanceChargeBuilder) *InvoiceBuilder {
+	b.allowanceChargeBuilders = append(b.allowanceChargeBuilders, allowanceChargeBuilders...)
+	return b
+}
+
 func (b *InvoiceBuilder) WithInvoiceLines(invoiceLines []InvoiceLine) *InvoiceBuilder {
 	b.invoiceLines = invoiceLines
 	return b
@@ -549,6 +703,33 @@ func (b *InvoiceBuilder) AppendInvoiceLines(lines ...InvoiceLine) *InvoiceBuilde
 	return b
 }
 
+// AppendLineBuilders appends invoice lines that are built by Build (after
+// the lines set with WithInvoiceLines/AppendInvoiceLines), so the errors of
+// all the lines are handled once. If not set, the line ID defaults to the
+// position of the line (starting from 1) and the currency defaults to the
+// document currency.
+func (b *InvoiceBuilder) AppendLineBuilders(lineBuilders ...*InvoiceLineBuilder) *InvoiceBuilder {
+	b.lineBuilders = append(b.lineBuilders, lineBuilders...)
+	return b
+}
+
+// WithValidation enables the offline validation (see ValidateInvoiceOffline)
+// of the built invoice. If the invoice is not valid, Build returns a
+// ValidationErrors error.
+func (b *InvoiceBuilder) WithValidation(validate bool) *InvoiceBuilder {
+	b.validate = validate
+	return b
+}
+
+// WithTruncation sets the policy for truncating the text fields longer than
+// the maximum length (see Invoice.TruncateTextFields). The fields are
+// truncated before the offline validation. If policy is nil (the default),
+// the text fields are not truncated.
+func (b *InvoiceBuilder) WithTruncation(policy *TruncationPolicy) *InvoiceBuilder {
+	b.truncation = policy
+	return b
+}
+
 func (b *InvoiceBuilder) WithAccountingCost(accountingCost string) *InvoiceBuilder {
 	b.accountingCost = accountingCost
 	return b
@@ -579,13 +760,53 @@ func (b *InvoiceBuilder) WithInvoicePeriod(invoicePeriod InvoicePeriod) *Invoice
 	return b
 }
 
+// WithVATPointDateCode sets the VAT point date code (BT-8) of the invoice
+// period. The code is validated by Build: it must be one of the allowed codes
+// and VATPointDateCodeDeliveryDate requires an actual delivery date (see
+// WithDelivery).
+func (b *InvoiceBuilder) WithVATPointDateCode(code VATPointDateCodeType) *InvoiceBuilder {
+	b.vatPointDateCode = code
+	return b
+}
+
+// WithDelivery sets the delivery information (BG-13) of the invoice.
+func (b *InvoiceBuilder) WithDelivery(delivery InvoiceDelivery) *InvoiceBuilder {
+	b.delivery = &delivery
+	return b
+}
+
 func (b *InvoiceBuilder) WithContractDocumentReference(contractDocumentReference string) *InvoiceBuilder {
 	b.contractDocumentReference = &contractDocumentReference
 	return b
 }
 
+// WithAdditionalDocumentReferences sets the additional document references
+// of the invoice: the supporting documents (BG-24), eg. timesheets or
+// receipts (see MakeSupportingDocument), and the invoiced object identifier
+// (BT-18, see MakeInvoicedObjectReference).
+func (b *InvoiceBuilder) WithAdditionalDocumentReferences(refs []InvoiceAdditionalDocumentReference) *InvoiceBuilder {
+	b.additionalDocuments = refs
+	return b
+}
+
+// AppendAdditionalDocumentReferences appends the given additional document
+// references (see WithAdditionalDocumentReferences).
+func (b *InvoiceBuilder) AppendAdditionalDocumentReferences(refs ...InvoiceAdditionalDocumentReference) *InvoiceBuilder {
+	return b.WithAdditionalDocumentReferences(append(b.additionalDocuments, refs...))
+}
+
+// WithPaymentMeans sets the payment means (BG-16) of the invoice, replacing
+// any previously set payment means. Use AppendPaymentMeans for setting more
+// payment means.
 func (b *InvoiceBuilder) WithPaymentMeans(paymentMeans InvoicePaymentMeans) *InvoiceBuilder {
-	b.paymentMeans = &paymentMeans
+	b.paymentMeans = []InvoicePaymentMeans{paymentMeans}
+	return b
+}
+
+// AppendPaymentMeans adds payment means to the invoice, eg. to offer both a
+// RON and an EUR account.
+func (b *InvoiceBuilder) AppendPaymentMeans(paymentMeans ...InvoicePaymentMeans) *InvoiceBuilder {
+	b.paymentMeans = append(b.paymentMeans, paymentMeans...)
 	return b
 }
 
@@ -594,6 +815,10 @@ func (b *InvoiceBuilder) WithPaymentTerms(paymentTerms InvoicePaymentTerms) *Inv
 	return b
 }
 
+// AddTaxExemptionReason sets the exemption reason text (BT-120) and code
+// (BT-121) for the given VAT category. If the reason text is empty, the
+// standard text for the code is used. Build returns an error if the code is
+// not allowed for the category.
 func (b *InvoiceBuilder) AddTaxExemptionReason(taxCategoryCode TaxCategoryCodeType, reason string, exemptionCode TaxExemptionReasonCodeType) *InvoiceBuilder {
 	if b.taxExeptionReasons == nil {
 		b.taxExeptionReasons = make(map[TaxCategoryCodeType]taxExemptionReason)
@@ -616,6 +841,71 @@ func (b *InvoiceBuilder) WithExpectedTaxInclusiveAmount(amount types.Decimal) *I
 	return b
 }
 
+// WithPrepaidAmount sets the sum of the amounts paid in advance (BT-113),
+// which is subtracted from the amount due for payment (BT-115). Build adds a
+// payment information note (BT-22) with the prepaid amount and the given
+// advance invoices, which are also referenced as preceding invoices (BG-3)
+// if not already referenced.
+func (b *InvoiceBuilder) WithPrepaidAmount(amount types.Decimal, advanceInvoices ...InvoiceDocumentReference) *InvoiceBuilder {
+	b.prepaidAmount = amount.Ptr()
+	b.advanceInvoices = advanceInvoices
+	return b
+}
+
+// prepaidAmountNotePrefix is the prefix of the notes returned by
+// prepaidAmountNote.
+const prepaidAmountNotePrefix = "Avans încasat: "
+
+// prepaidAmountNote returns the payment information note for the given
+// prepaid amount and advance invoices.
+func prepaidAmountNote(amount types.Decimal, currencyID CurrencyCodeType, advanceInvoices []InvoiceDocumentReference) InvoiceNote {
+	note := fmt.Sprintf("%s%s %s", prepaidAmountNotePrefix, amount.StringFixed(2), currencyID)
+	if len(advanceInvoices) > 0 {
+		refs := make([]string, len(advanceInvoices))
+		for i, ref := range advanceInvoices {
+			refs[i] = "factura " + ref.ID
+			if ref.IssueDate != nil {
+				refs[i] += " din " + ref.IssueDate.Format("02.01.2006")
+			}
+		}
+		note += " (" + strings.Join(refs, ", ") + ")"
+	}
+	return InvoiceNote{
+		SubjectCode: InvoiceNoteSubjectPaymentInformation,
+		Note:        note,
+	}
+}
+
+// withoutPrepaidAmountNotes returns the notes without the ones added by
+// Build for the prepaid amount.
+func withoutPrepaidAmountNotes(notes []InvoiceNote) (res []InvoiceNote) {
+	for _, note := range notes {
+		if note.SubjectCode == InvoiceNoteSubjectPaymentInformation &&
+			strings.HasPrefix(note.Note, prepaidAmountNotePrefix) {
+			continue
+		}
+		res = append(res, note)
+	}
+	return
+}
+
+// roundToIncrement rounds amount to a multiple of increment using the given
+// rounding mode.
+func roundToIncrement(amount, increment types.Decimal, mode types.RoundingMode) types.Decimal {
+	return amount.Div(increment).RoundWithMode(0, mode).Mul(increment)
+}
+
+// WithPayableRounding rounds the amount due for payment (BT-115) to a
+// multiple of increment (eg. types.D(1) for whole RON for cash payments)
+// using the given mode. The difference is set as the payable rounding amount
+// (BT-114). If WithExpectedTaxInclusiveAmount is also used, the payable
+// amount is rounded after the adjustment for the expected amount.
+func (b *InvoiceBuilder) WithPayableRounding(increment types.Decimal, mode types.RoundingMode) *InvoiceBuilder {
+	b.payableRoundingIncrement = increment.Ptr()
+	b.payableRoundingMode = mode
+	return b
+}
+
 func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 	if b.id == "" {
 		err = ierrors.NewBuilderErrorf(b, "", "id not set")
@@ -633,6 +923,34 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 		err = ierrors.NewBuilderErrorf(b, "", "document to tax currency exchange rate not set")
 		return
 	}
+	if er := validatePartyFiscalCode(b.supplier.TaxScheme, b.supplier.PostalAddress.Country.Code); er != nil {
+		term := "BT-31"
+		if b.supplier.TaxScheme.TaxScheme.ID != TaxSchemeIDVAT {
+			term = "BT-32"
+		}
+		err = ierrors.NewBuilderErrorf(b, term, "supplier: %w", er)
+		return
+	}
+	if er := validatePartyFiscalCode(b.customer.TaxScheme, b.customer.PostalAddress.Country.Code); er != nil {
+		err = ierrors.NewBuilderErrorf(b, "BT-48", "customer: %w", er)
+		return
+	}
+
+	vatPointDateCode := b.vatPointDateCode
+	if vatPointDateCode == "" && b.invoicePeriod != nil {
+		vatPointDateCode = b.invoicePeriod.DescriptionCode
+	}
+	if vatPointDateCode != "" {
+		if !vatPointDateCode.IsValid() {
+			err = ierrors.NewBuilderErrorf(b, "", "invalid VAT point date code %q", vatPointDateCode)
+			return
+		}
+		if vatPointDateCode == VATPointDateCodeDeliveryDate &&
+			(b.delivery == nil || b.delivery.ActualDeliveryDate == nil) {
+			err = ierrors.NewBuilderErrorf(b, "", "VAT point date code %s requires the actual delivery date", vatPointDateCode)
+			return
+		}
+	}
 
 	taxCurrencyID := b.taxCurrencyID
 	if taxCurrencyID == "" {
@@ -646,23 +964,61 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 	invoice.IssueDate = b.issueDate
 	invoice.DueDate = b.dueDate
 	invoice.InvoiceTypeCode = b.invoiceType
+	if invoice.InvoiceTypeCode == "" {
+		invoice.InvoiceTypeCode = InvoiceTypeCommercialInvoice
+	}
 	invoice.DocumentCurrencyCode = b.documentCurrencyID
 	invoice.TaxCurrencyCode = b.taxCurrencyID
 	invoice.AccountingCost = b.accountingCost
 	invoice.BuyerReference = b.buyerReference
 	invoice.OrderReference = b.orderReference
 	invoice.Note = b.notes
-	invoice.InvoicePeriod = b.invoicePeriod
+	if b.prepaidAmount != nil {
+		if prepaid := *b.prepaidAmount; prepaid.IsNegative() || !prepaid.Equal(prepaid.AsAmount()) {
+			err = ierrors.NewBuilderErrorf(b, "BT-113", "invalid prepaid amount %s", prepaid.String())
+			return
+		}
+		if !b.prepaidAmount.IsZero() {
+			invoice.Note = append(append([]InvoiceNote(nil), b.notes...),
+				prepaidAmountNote(*b.prepaidAmount, b.documentCurrencyID, b.advanceInvoices))
+		}
+	}
+	if b.invoicePeriod != nil {
+		invoicePeriod := *b.invoicePeriod
+		invoice.InvoicePeriod = &invoicePeriod
+	}
+	if vatPointDateCode != "" {
+		if invoice.InvoicePeriod == nil {
+			invoice.InvoicePeriod = new(InvoicePeriod)
+		}
+		invoice.InvoicePeriod.DescriptionCode = vatPointDateCode
+	}
+	invoice.Delivery = b.delivery
 
 	for _, ref := range b.billingReferences {
 		invoice.BillingReferences = append(invoice.BillingReferences, InvoiceBillingReference{
 			InvoiceDocumentReference: ref,
 		})
 	}
+	for _, ref := range b.advanceInvoices {
+		referenced := false
+		for _, billingReference := range invoice.BillingReferences {
+			if billingReference.InvoiceDocumentReference.ID == ref.ID {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			invoice.BillingReferences = append(invoice.BillingReferences, InvoiceBillingReference{
+				InvoiceDocumentReference: ref,
+			})
+		}
+	}
 
 	if b.contractDocumentReference != nil {
 		invoice.ContractDocumentReference = NewIDNode(*b.contractDocumentReference)
 	}
+	invoice.AdditionalDocumentReferences = b.additionalDocuments
 
 	invoice.Supplier.Party = b.supplier
 	invoice.Customer.Party = b.customer
@@ -680,7 +1036,38 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 	}
 
 	invoice.AllowanceCharges = b.allowancesCharges
+	for i, acb := range b.allowanceChargeBuilders {
+		allowanceChargeBuilder := *acb
+		if allowanceChargeBuilder.currencyID == "" {
+			allowanceChargeBuilder.currencyID = b.documentCurrencyID
+		}
+		allowanceCharge, er := allowanceChargeBuilder.Build()
+		if er != nil {
+			term := "BG-20"
+			if allowanceChargeBuilder.chargeIndicator {
+				term = "BG-21"
+			}
+			err = ierrors.NewBuilderErrorf(b, term, "allowance/charge %d: %w", i, er)
+			return
+		}
+		invoice.AllowanceCharges = append(invoice.AllowanceCharges, allowanceCharge)
+	}
 	invoice.InvoiceLines = b.invoiceLines
+	for _, lb := range b.lineBuilders {
+		lineBuilder := *lb
+		if lineBuilder.id == "" {
+			lineBuilder.id = strconv.Itoa(len(invoice.InvoiceLines) + 1)
+		}
+		if lineBuilder.currencyID == "" {
+			lineBuilder.currencyID = b.documentCurrencyID
+		}
+		line, er := lineBuilder.Build()
+		if er != nil {
+			err = ierrors.NewBuilderErrorf(b, "BG-25", "line %s: %w", lineBuilder.id, er)
+			return
+		}
+		invoice.InvoiceLines = append(invoice.InvoiceLines, line)
+	}
 
 	var (
 		lineExtensionAmount   = types.Zero
@@ -692,6 +1079,9 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 		payableRoundingAmount = types.Zero
 		payableAmount         = types.Zero
 	)
+	if b.prepaidAmount != nil {
+		prepaidAmount = *b.prepaidAmount
+	}
 
 	taxCategoryMap := make(taxCategoryMap)
 	for i, line := range invoice.InvoiceLines {
@@ -708,6 +1098,23 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 		}
 	}
 	for i, allowanceCharge := range invoice.AllowanceCharges {
+		// Only the allowances and charges from the builders are checked,
+		// the ones set directly are used as they are.
+		checked := i >= len(b.allowancesCharges)
+		if checked && allowanceCharge.Amount.CurrencyID != invoice.DocumentCurrencyCode {
+			err = ierrors.NewBuilderErrorf(b, "", "invoice allowance/charge %d: invalid currency id", i)
+			return
+		}
+		// A document level allowance reduces the taxable amount of the
+		// invoice lines with the same VAT category.
+		if checked && !allowanceCharge.ChargeIndicator {
+			if _, ok := taxCategoryMap[makeTaxCategoryKey(allowanceCharge.TaxCategory)]; !ok {
+				err = ierrors.NewBuilderErrorf(b, "BG-20", "invoice allowance %d: no invoice line with tax category %s/%s",
+					i, allowanceCharge.TaxCategory.ID, allowanceCharge.TaxCategory.Percent.String())
+				return
+			}
+		}
+
 		var amount types.Decimal
 		if allowanceCharge.ChargeIndicator {
 			amount = allowanceCharge.Amount.Amount
@@ -750,6 +1157,15 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 					subtotal.TaxCategory.ID, subtotal.TaxCategory.Percent.String())
 				return
 			} else {
+				if reason.code != "" {
+					if er := ValidateTaxExemptionReasonCode(subtotal.TaxCategory.ID, reason.code); er != nil {
+						err = ierrors.NewBuilderErrorf(b, "BT-121", "%w", er)
+						return
+					}
+					if reason.reason == "" {
+						reason.reason, _ = reason.code.Reason()
+					}
+				}
 				subtotal.TaxCategory.TaxExemptionReason = reason.reason
 				subtotal.TaxCategory.TaxExemptionReasonCode = reason.code
 			}
@@ -763,6 +1179,15 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 		payableRoundingAmount = b.expectedTaxInclusiveAmount.Sub(taxInclusiveAmount)
 	}
 	payableAmount = taxInclusiveAmount.Sub(prepaidAmount).Add(payableRoundingAmount)
+	if increment := b.payableRoundingIncrement; increment != nil {
+		if increment.Sign() <= 0 || !increment.Equal(increment.AsAmount()) {
+			err = ierrors.NewBuilderErrorf(b, "BT-114", "invalid payable rounding increment %s", increment.String())
+			return
+		}
+		roundedPayableAmount := roundToIncrement(payableAmount, *increment, b.payableRoundingMode)
+		payableRoundingAmount = payableRoundingAmount.Add(roundedPayableAmount.Sub(payableAmount))
+		payableAmount = roundedPayableAmount
+	}
 
 	if len(taxSubtotals) > 0 {
 		taxTotalNode := InvoiceTaxTotal{
@@ -825,6 +1250,16 @@ func (b InvoiceBuilder) Build() (retInvoice Invoice, err error) {
 		CurrencyID: b.documentCurrencyID,
 	}
 
+	if b.truncation != nil {
+		invoice.TruncateTextFields(*b.truncation)
+	}
+	if b.validate {
+		if verrs := ValidateInvoiceOffline(invoice); len(verrs) > 0 {
+			err = ValidationErrors(verrs)
+			return
+		}
+	}
+
 	retInvoice = invoice
 	return
 }
@@ -25,14 +25,14 @@ import (
 )
 
 const (
-	defaultTestSupplierCompanyID               = "RO1234567890"
+	defaultTestSupplierCompanyID               = "RO1234567897"
 	defaultTestSupplierLegalName               = "Seller SRL"
 	defaultTestSupplierLegalForm               = "J40/12345/1998"
 	defaultTestSupplierAddressLine1            = "Piata Victoriei 1"
 	defaultTestSupplierAddressCityName         = CityNameROBSector1
 	defaultTestSupplierAddressCountrySubentity = CountrySubentityRO_B
 
-	defaultTestCustomerCompanyID               = "RO987456123"
+	defaultTestCustomerCompanyID               = "RO987456126"
 	defaultTestCustomerLegalName               = "Buyer SRL"
 	defaultTestCustomerAddressLine1            = "Piata Victoriei 1"
 	defaultTestCustomerAddressCityName         = CityNameROBSector1
@@ -683,3 +683,496 @@ func findTaxTotalByCurrency(totals []InvoiceTaxTotal, currencyID CurrencyCodeTyp
 	}
 	return -1
 }
+
+func TestInvoiceBuilderVATPointDateCode(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	newBuilder := func() *InvoiceBuilder {
+		return NewInvoiceBuilder("test.bt8").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines([]InvoiceLine{line})
+	}
+
+	invoice, err := newBuilder().WithVATPointDateCode(VATPointDateCodeIssueDate).Build()
+	if assert.NoError(err) && assert.NotNil(invoice.InvoicePeriod) {
+		assert.Equal(VATPointDateCodeIssueDate, invoice.InvoicePeriod.DescriptionCode)
+		date, ok := invoice.VATPointDate()
+		assert.True(ok)
+		assert.Equal(types.MakeDate(2024, 3, 1), date)
+
+		xmlData, err := invoice.XML()
+		if assert.NoError(err) {
+			assert.Contains(string(xmlData), "<cac:InvoicePeriod><cbc:DescriptionCode>3</cbc:DescriptionCode></cac:InvoicePeriod>")
+		}
+	}
+
+	_, err = newBuilder().WithVATPointDateCode("1").Build()
+	assert.Error(err, "invalid VAT point date code")
+	_, err = newBuilder().WithVATPointDateCode(VATPointDateCodeDeliveryDate).Build()
+	assert.Error(err, "VAT point date code 35 requires the actual delivery date")
+	_, err = newBuilder().WithInvoicePeriod(InvoicePeriod{DescriptionCode: VATPointDateCodeDeliveryDate}).Build()
+	assert.Error(err, "VAT point date code 35 from the invoice period requires the actual delivery date")
+
+	deliveryDate := types.MakeDate(2024, 2, 28)
+	invoice, err = newBuilder().
+		WithInvoicePeriod(InvoicePeriod{
+			StartDate: types.MakeDate(2024, 2, 1).Ptr(),
+			EndDate:   types.MakeDate(2024, 2, 29).Ptr(),
+		}).
+		WithVATPointDateCode(VATPointDateCodeDeliveryDate).
+		WithDelivery(InvoiceDelivery{ActualDeliveryDate: &deliveryDate}).
+		Build()
+	if assert.NoError(err) && assert.NotNil(invoice.InvoicePeriod) {
+		assert.Equal(types.MakeDate(2024, 2, 1), *invoice.InvoicePeriod.StartDate)
+		assert.Equal(VATPointDateCodeDeliveryDate, invoice.InvoicePeriod.DescriptionCode)
+		date, ok := invoice.VATPointDate()
+		assert.True(ok)
+		assert.Equal(deliveryDate, date)
+
+		xmlData, err := invoice.XML()
+		if assert.NoError(err) {
+			var parsed Invoice
+			if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) && assert.NotNil(parsed.InvoicePeriod) {
+				assert.Equal(VATPointDateCodeDeliveryDate, parsed.InvoicePeriod.DescriptionCode)
+			}
+		}
+	}
+
+	invoice, err = newBuilder().WithVATPointDateCode(VATPointDateCodePaidToDate).Build()
+	if assert.NoError(err) {
+		_, ok := invoice.VATPointDate()
+		assert.False(ok, "the payment date is not available in the invoice")
+	}
+}
+
+func TestInvoiceBuilderTaxExemptionReason(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATReverseCharge,
+			Percent:   types.D(0),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	newBuilder := func() *InvoiceBuilder {
+		return NewInvoiceBuilder("test.vatex").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines([]InvoiceLine{line})
+	}
+
+	invoice, err := newBuilder().
+		AddTaxExemptionReason(TaxCategoryVATReverseCharge, "", TaxExemptionCodeVATEX_EU_AE).
+		Build()
+	if assert.NoError(err) && assert.Len(invoice.TaxTotal, 1) && assert.Len(invoice.TaxTotal[0].TaxSubtotals, 1) {
+		category := invoice.TaxTotal[0].TaxSubtotals[0].TaxCategory
+		assert.Equal(TaxExemptionReasonCodeType(TaxExemptionCodeVATEX_EU_AE), category.TaxExemptionReasonCode)
+		assert.Equal("Taxare inversa", category.TaxExemptionReason)
+	}
+
+	invoice, err = newBuilder().
+		AddTaxExemptionReason(TaxCategoryVATReverseCharge, "Taxare inversă art. 331", TaxExemptionCodeVATEX_EU_AE).
+		Build()
+	if assert.NoError(err) {
+		assert.Equal("Taxare inversă art. 331", invoice.TaxTotal[0].TaxSubtotals[0].TaxCategory.TaxExemptionReason)
+	}
+
+	_, err = newBuilder().
+		AddTaxExemptionReason(TaxCategoryVATReverseCharge, "", TaxExemptionCodeVATEX_EU_IC).
+		Build()
+	assert.ErrorContains(err, "only allowed with tax category K")
+	_, err = newBuilder().
+		AddTaxExemptionReason(TaxCategoryVATReverseCharge, "", "VATEX-EU-XX").
+		Build()
+	assert.ErrorContains(err, "unknown tax exemption reason code")
+
+	reason, ok := TaxExemptionReasonCodeType(TaxExemptionCodeVATEX_EU_D).Reason()
+	assert.True(ok)
+	assert.Equal("Regim special pentru agentiile de turism", reason)
+
+	category, ok := TaxExemptionReasonCodeType(TaxExemptionCodeVATEX_EU_132_1A).TaxCategory()
+	assert.True(ok)
+	assert.Equal(TaxCategoryVATExempt, category)
+	assert.NoError(ValidateTaxExemptionReasonCode(TaxCategoryNotSubjectToVAT, TaxExemptionCodeVATEX_EU_O))
+	assert.Error(ValidateTaxExemptionReasonCode(TaxCategoryVATExempt, TaxExemptionCodeVATEX_EU_G))
+}
+
+func TestInvoiceBuilderPayableRounding(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(10.10)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	newBuilder := func() *InvoiceBuilder {
+		return NewInvoiceBuilder("test.rounding").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines([]InvoiceLine{line})
+	}
+
+	// 10.10 + 1.92 VAT = 12.02
+	tests := []struct {
+		increment        types.Decimal
+		mode             types.RoundingMode
+		expectedRounding types.Decimal
+		expectedPayable  types.Decimal
+	}{
+		{types.D(1), types.RoundingModeHalfUp, types.D(-0.02), types.D(12)},
+		{types.D(1), types.RoundingModeCeil, types.D(0.98), types.D(13)},
+		{types.D(1), types.RoundingModeFloor, types.D(-0.02), types.D(12)},
+		{types.D(0.05), types.RoundingModeHalfUp, types.D(-0.02), types.D(12)},
+		{types.D(0.05), types.RoundingModeCeil, types.D(0.03), types.D(12.05)},
+		{types.D(0.01), types.RoundingModeHalfUp, types.Zero, types.D(12.02)},
+	}
+	for _, test := range tests {
+		invoice, err := newBuilder().WithPayableRounding(test.increment, test.mode).Build()
+		if !assert.NoError(err) {
+			continue
+		}
+		total := invoice.LegalMonetaryTotal
+		assert.True(total.TaxInclusiveAmount.Amount.Equal(types.D(12.02)))
+		assert.True(total.PayableAmount.Amount.Equal(test.expectedPayable),
+			"payable amount %s, expected %s", total.PayableAmount.Amount.String(), test.expectedPayable.String())
+		if test.expectedRounding.IsZero() {
+			assert.Nil(total.PayableRoundingAmount)
+		} else if assert.NotNil(total.PayableRoundingAmount) {
+			assert.True(total.PayableRoundingAmount.Amount.Equal(test.expectedRounding),
+				"rounding amount %s, expected %s", total.PayableRoundingAmount.Amount.String(), test.expectedRounding.String())
+		}
+	}
+
+	// Rounding is applied after the expected tax inclusive amount.
+	invoice, err := newBuilder().
+		WithExpectedTaxInclusiveAmount(types.D(12.03)).
+		WithPayableRounding(types.D(0.1), types.RoundingModeFloor).
+		Build()
+	if assert.NoError(err) && assert.NotNil(invoice.LegalMonetaryTotal.PayableRoundingAmount) {
+		assert.True(invoice.LegalMonetaryTotal.PayableRoundingAmount.Amount.Equal(types.D(-0.02)))
+		assert.True(invoice.LegalMonetaryTotal.PayableAmount.Amount.Equal(types.D(12)))
+	}
+
+	_, err = newBuilder().WithPayableRounding(types.Zero, types.RoundingModeHalfUp).Build()
+	assert.ErrorContains(err, "invalid payable rounding increment")
+	_, err = newBuilder().WithPayableRounding(types.D(0.001), types.RoundingModeHalfUp).Build()
+	assert.ErrorContains(err, "invalid payable rounding increment")
+}
+
+func TestInvoiceBuilderPrepaidAmount(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	newBuilder := func() *InvoiceBuilder {
+		return NewInvoiceBuilder("FCT-2").
+			WithIssueDate(types.MakeDate(2024, 3, 15)).
+			WithDueDate(types.MakeDate(2024, 4, 15)).
+			WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines([]InvoiceLine{line}).
+			WithValidation(true)
+	}
+
+	advance := InvoiceDocumentReference{ID: "AV-1", IssueDate: types.NewDate(2024, 3, 1)}
+	invoice, err := newBuilder().
+		AppendNotes(InvoiceNote{Note: "Livrare finală"}).
+		AppendBillingReferences(InvoiceDocumentReference{ID: "FCT-0"}).
+		WithPrepaidAmount(types.D(50), advance).
+		Build()
+	if assert.NoError(err) {
+		total := invoice.LegalMonetaryTotal
+		assert.True(total.TaxInclusiveAmount.Amount.Equal(types.D(119)))
+		if assert.NotNil(total.PrepaidAmount) {
+			assert.True(total.PrepaidAmount.Amount.Equal(types.D(50)))
+		}
+		assert.True(total.PayableAmount.Amount.Equal(types.D(69)))
+		assert.Equal([]InvoiceNote{{Note: "Livrare finală"}, {
+			SubjectCode: InvoiceNoteSubjectPaymentInformation,
+			Note:        "Avans încasat: 50.00 RON (factura AV-1 din 01.03.2024)",
+		}}, invoice.Note)
+		if assert.Len(invoice.BillingReferences, 2) {
+			assert.Equal(advance, invoice.BillingReferences[1].InvoiceDocumentReference)
+		}
+	}
+
+	// An advance invoice already referenced is not referenced again.
+	invoice, err = newBuilder().
+		AppendBillingReferences(advance).
+		WithPrepaidAmount(types.D(50), advance).
+		Build()
+	if assert.NoError(err) {
+		assert.Len(invoice.BillingReferences, 1)
+	}
+
+	// A zero prepaid amount doesn't add the note.
+	invoice, err = newBuilder().WithPrepaidAmount(types.Zero).Build()
+	if assert.NoError(err) {
+		assert.Nil(invoice.LegalMonetaryTotal.PrepaidAmount)
+		assert.Empty(invoice.Note)
+	}
+
+	_, err = newBuilder().WithPrepaidAmount(types.D(-1)).Build()
+	assert.ErrorContains(err, "invalid prepaid amount")
+	_, err = newBuilder().WithPrepaidAmount(types.D(0.001)).Build()
+	assert.ErrorContains(err, "invalid prepaid amount")
+
+	// The terms of the amount due for payment are reported by the validator.
+	invoice, err = newBuilder().WithPrepaidAmount(types.D(50)).Build()
+	if !assert.NoError(err) {
+		return
+	}
+	invoice.LegalMonetaryTotal.PayableAmount.Amount = types.D(119)
+	errs := ValidateInvoiceOffline(invoice)
+	if assert.Len(errs, 1) {
+		assert.Equal("BR-CO-16", errs[0].Rule)
+		assert.Equal("the amount due for payment (BT-115) is 119.00, expected 69.00 = 119.00 (BT-112) - 50.00 (BT-113) + 0.00 (BT-114)", errs[0].Message)
+		assert.Equal("suma de plată (BT-115) este 119.00, în loc de 69.00 = 119.00 (BT-112) - 50.00 (BT-113) + 0.00 (BT-114)", errs[0].MessageRO)
+	}
+}
+
+func TestInvoiceBuilderLineBuildersAndValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	vat19 := InvoiceLineTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	builder := NewInvoiceBuilder("FCT-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 3, 31)).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithPaymentMeans(InvoicePaymentMeans{
+			PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansCreditTransfer},
+		}).
+		AppendLineBuilders(
+			NewInvoiceLineBuilder("", "").WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(2)).WithGrossPriceAmount(types.D(50)).
+				WithItemName("Produs 1").WithItemTaxCategory(vat19),
+			NewInvoiceLineBuilder("", "").WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(1)).WithGrossPriceAmount(types.D(100)).
+				WithItemName("Produs 2").WithItemTaxCategory(vat19),
+		).
+		WithValidation(true)
+
+	invoice, err := builder.Build()
+	if assert.NoError(err) {
+		assert.Equal(InvoiceTypeCommercialInvoice, invoice.InvoiceTypeCode)
+		if assert.Len(invoice.InvoiceLines, 2) {
+			assert.Equal("1", invoice.InvoiceLines[0].ID)
+			assert.Equal("2", invoice.InvoiceLines[1].ID)
+			assert.Equal(CurrencyRON, invoice.InvoiceLines[1].LineExtensionAmount.CurrencyID)
+		}
+		assert.True(invoice.LegalMonetaryTotal.TaxInclusiveAmount.Amount.Equal(types.D(238)))
+	}
+
+	// Validation errors are returned as ValidationErrors.
+	_, err = builder.WithID("FCT").Build()
+	var verrs ValidationErrors
+	if assert.ErrorAs(err, &verrs) && assert.Len(verrs, 1) {
+		assert.Equal("BR-RO-010", verrs[0].Rule)
+	}
+
+	// Line errors are reported with the line ID.
+	_, err = NewInvoiceBuilder("FCT-2").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDocumentCurrencyCode(CurrencyRON).
+		AppendLineBuilders(NewInvoiceLineBuilder("", "")).
+		Build()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "line 1")
+	}
+}
+
+func TestInvoiceBuilderDocumentAllowanceChargeBuilders(t *testing.T) {
+	assert := assert.New(t)
+
+	vat19 := InvoiceTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	vat9 := InvoiceTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(9),
+	}
+	newBuilder := func() *InvoiceBuilder {
+		return NewInvoiceBuilder("FCT-1").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			AppendLineBuilders(
+				NewInvoiceLineBuilder("", "").WithUnitCode("H87").
+					WithInvoicedQuantity(types.D(2)).WithGrossPriceAmount(types.D(100)).
+					WithItemName("Produs 1").WithItemTaxCategory(InvoiceLineTaxCategory{
+					TaxScheme: vat19.TaxScheme,
+					ID:        vat19.ID,
+					Percent:   vat19.Percent,
+				}),
+				NewInvoiceLineBuilder("", "").WithUnitCode("H87").
+					WithInvoicedQuantity(types.D(1)).WithGrossPriceAmount(types.D(50)).
+					WithItemName("Produs 2").WithItemTaxCategory(InvoiceLineTaxCategory{
+					TaxScheme: vat9.TaxScheme,
+					ID:        vat9.ID,
+					Percent:   vat9.Percent,
+				}),
+			)
+	}
+
+	invoice, err := newBuilder().
+		AppendAllowanceChargeBuilders(
+			// 10% discount for the products with 19% VAT.
+			NewInvoiceDocumentAllowanceBuilder("", types.Decimal{}, vat19).
+				WithBaseAmount(types.D(200)).WithPercent(types.D(10)).
+				WithAllowanceReasonCode(AllowanceReasonCodeDiscount).
+				WithAllowanceChargeReason("Discount"),
+			NewInvoiceDocumentChargeBuilder("", types.D(5), vat9).
+				WithChargeReasonCode(ChargeReasonCodeFreightService).
+				WithAllowanceChargeReason("Transport"),
+		).
+		Build()
+	if assert.NoError(err) {
+		if assert.Len(invoice.AllowanceCharges, 2) {
+			allowance := invoice.AllowanceCharges[0]
+			assert.Equal(CurrencyRON, allowance.Amount.CurrencyID)
+			assert.True(allowance.Amount.Amount.Equal(types.D(20)))
+			if assert.NotNil(allowance.Percent) {
+				assert.True(allowance.Percent.Equal(types.D(10)))
+			}
+			assert.Equal("95", allowance.AllowanceChargeReasonCode)
+			assert.Equal("FC", invoice.AllowanceCharges[1].AllowanceChargeReasonCode)
+		}
+
+		if assert.NotNil(invoice.LegalMonetaryTotal.AllowanceTotalAmount) {
+			assert.True(invoice.LegalMonetaryTotal.AllowanceTotalAmount.Amount.Equal(types.D(20)))
+		}
+		if assert.NotNil(invoice.LegalMonetaryTotal.ChargeTotalAmount) {
+			assert.True(invoice.LegalMonetaryTotal.ChargeTotalAmount.Amount.Equal(types.D(5)))
+		}
+		assert.True(invoice.LegalMonetaryTotal.TaxExclusiveAmount.Amount.Equal(types.D(235)))
+
+		if assert.Len(invoice.TaxTotal, 1) {
+			subtotals := make(map[string]InvoiceTaxSubtotal)
+			for _, subtotal := range invoice.TaxTotal[0].TaxSubtotals {
+				subtotals[subtotal.TaxCategory.Percent.String()] = subtotal
+			}
+			if subtotal, ok := subtotals["19"]; assert.True(ok) {
+				assert.True(subtotal.TaxableAmount.Amount.Equal(types.D(180)))
+				assert.True(subtotal.TaxAmount.Amount.Equal(types.D(34.2)))
+			}
+			if subtotal, ok := subtotals["9"]; assert.True(ok) {
+				assert.True(subtotal.TaxableAmount.Amount.Equal(types.D(55)))
+				assert.True(subtotal.TaxAmount.Amount.Equal(types.D(4.95)))
+			}
+		}
+		assert.True(invoice.LegalMonetaryTotal.PayableAmount.Amount.Equal(types.D(274.15)))
+	}
+
+	// An allowance must reduce the taxable amount of some invoice lines.
+	_, err = newBuilder().
+		AppendAllowanceChargeBuilders(NewInvoiceDocumentAllowanceBuilder("", types.D(10), InvoiceTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(5),
+		})).
+		Build()
+	assert.ErrorContains(err, "no invoice line with tax category")
+
+	// The allowances must be in the document currency.
+	_, err = newBuilder().
+		AppendAllowanceChargeBuilders(NewInvoiceDocumentAllowanceBuilder(CurrencyEUR, types.D(10), vat19)).
+		Build()
+	assert.ErrorContains(err, "invalid currency id")
+
+	// The allowances set directly are used as they are, without the checks
+	// done for the ones from the builders.
+	_, err = newBuilder().
+		AppendAllowanceCharge(InvoiceDocumentAllowanceCharge{
+			ChargeIndicator: false,
+			Amount:          AmountWithCurrency{Amount: types.D(10)},
+			TaxCategory: InvoiceTaxCategory{
+				TaxScheme: TaxSchemeVAT,
+				ID:        TaxCategoryVATStandardRate,
+				Percent:   types.D(5),
+			},
+		}).
+		Build()
+	assert.NoError(err)
+
+	// Allowance/charge build errors.
+	_, err = newBuilder().
+		AppendAllowanceChargeBuilders(NewInvoiceDocumentChargeBuilder("", types.Decimal{}, vat19)).
+		Build()
+	assert.ErrorContains(err, "amount not set")
+
+	// The reason code must match the charge indicator.
+	_, err = newBuilder().
+		AppendAllowanceChargeBuilders(NewInvoiceDocumentChargeBuilder("", types.D(5), vat19).
+			WithAllowanceChargeReasonCode(string(AllowanceReasonCodeDiscount))).
+		Build()
+	assert.ErrorContains(err, `invalid charge reason code "95"`)
+	_, err = NewInvoiceLineAllowanceBuilder(CurrencyRON, types.D(5)).
+		WithChargeReasonCode(ChargeReasonCodePacking).
+		Build()
+	assert.ErrorContains(err, `invalid allowance reason code "PC"`)
+}
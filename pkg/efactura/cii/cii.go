@@ -0,0 +1,577 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package cii implements the UN/CEFACT Cross Industry Invoice (CII D16B)
+// syntax accepted by e-factura with the CII upload standard. Only the
+// elements used by EN 16931 (and CIUS-RO) are modeled. FromInvoice and
+// CrossIndustryInvoice.Invoice convert between CII and efactura.Invoice.
+package cii
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/printesoi/xml-go"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+// Constants for namespaces
+const (
+	xmlnsRSM = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	xmlnsRAM = "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+	xmlnsUDT = "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100"
+	xmlnsQDT = "urn:un:unece:uncefact:data:standard:QualifiedDataType:100"
+
+	// DateFormat is the format code for dates (YYYYMMDD) from UNTDID 2379.
+	DateFormat = "102"
+	// TaxTypeVAT is the tax type code for VAT.
+	TaxTypeVAT = "VAT"
+	// TaxRegistrationSchemeVAT is the schemeID of a VAT identifier (BT-31,
+	// BT-48).
+	TaxRegistrationSchemeVAT = "VA"
+	// TaxRegistrationSchemeFiscal is the schemeID of a tax registration
+	// identifier (BT-32).
+	TaxRegistrationSchemeFiscal = "FC"
+)
+
+// CrossIndustryInvoice is the rsm:CrossIndustryInvoice document.
+type CrossIndustryInvoice struct {
+	ExchangedDocumentContext    DocumentContext             `xml:"urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100 ExchangedDocumentContext"`
+	ExchangedDocument           ExchangedDocument           `xml:"urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100 ExchangedDocument"`
+	SupplyChainTradeTransaction SupplyChainTradeTransaction `xml:"urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100 SupplyChainTradeTransaction"`
+
+	// Name of node.
+	XMLName xml.Name `xml:"urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100 CrossIndustryInvoice"`
+	// xmlns:rsm attr. Will be automatically set in MarshalXML
+	NamespaceRSM string `xml:"xmlns:rsm,attr"`
+	// xmlns:ram attr. Will be automatically set in MarshalXML
+	NamespaceRAM string `xml:"xmlns:ram,attr"`
+	// xmlns:udt attr. Will be automatically set in MarshalXML
+	NamespaceUDT string `xml:"xmlns:udt,attr"`
+	// xmlns:qdt attr. Will be automatically set in MarshalXML
+	NamespaceQDT string `xml:"xmlns:qdt,attr"`
+}
+
+// Prefill sets the namespaces and the guideline (CustomizationID) for
+// ensuring that the required attributes and properties are set for a valid
+// CII XML.
+func (ci *CrossIndustryInvoice) Prefill() {
+	ci.NamespaceRSM = xmlnsRSM
+	ci.NamespaceRAM = xmlnsRAM
+	ci.NamespaceUDT = xmlnsUDT
+	ci.NamespaceQDT = xmlnsQDT
+	ci.ExchangedDocumentContext.Guideline.ID = efactura.CIUSRO_v101
+}
+
+func (ci CrossIndustryInvoice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// This allows us to strip the MarshalXML method.
+	type crossIndustryInvoice CrossIndustryInvoice
+	for _, ns := range []struct{ uri, prefix string }{
+		{xmlnsRSM, "rsm"}, {xmlnsRAM, "ram"}, {xmlnsUDT, "udt"}, {xmlnsQDT, "qdt"},
+	} {
+		e.AddNamespaceBinding(ns.uri, ns.prefix)
+		e.AddSkipNamespaceAttrForPrefix(ns.uri, ns.prefix)
+	}
+	ci.Prefill()
+	start.Name = xml.Name{Space: xmlnsRSM, Local: "CrossIndustryInvoice"}
+	return e.EncodeElement(crossIndustryInvoice(ci), start)
+}
+
+// XML returns the XML encoding of the CrossIndustryInvoice
+func (ci CrossIndustryInvoice) XML() ([]byte, error) {
+	return pxml.MarshalXMLWithHeader(ci)
+}
+
+// XMLIndent works like XML, but each XML element begins on a new
+// indented line that starts with prefix and is followed by one or more
+// copies of indent according to the nesting depth.
+func (ci CrossIndustryInvoice) XMLIndent(prefix, indent string) ([]byte, error) {
+	return pxml.MarshalIndentXMLWithHeader(ci, prefix, indent)
+}
+
+// UploadStandard implements the efactura.Document interface.
+func (ci CrossIndustryInvoice) UploadStandard() efactura.UploadStandard {
+	return efactura.UploadStandardCII
+}
+
+// Unmarshal unmarshals a CrossIndustryInvoice from XML data. This method
+// does not check if the unmarshaled document is valid.
+func Unmarshal(xmlData []byte, ci *CrossIndustryInvoice) error {
+	return pxml.UnmarshalXML(xmlData, ci)
+}
+
+// DocumentContext is the rsm:ExchangedDocumentContext.
+type DocumentContext struct {
+	// ID: BT-24
+	// Term: Identificatorul specificaţiei
+	// NOTE: this field will be automatically set to efactura.CIUSRO_v101
+	//       when marshaled.
+	Guideline DocumentContextParameter `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 GuidelineSpecifiedDocumentContextParameter"`
+}
+
+type DocumentContextParameter struct {
+	ID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ID"`
+}
+
+// ExchangedDocument is the rsm:ExchangedDocument.
+type ExchangedDocument struct {
+	// ID: BT-1
+	// Term: Numărul facturii
+	ID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ID"`
+	// ID: BT-3
+	// Term: Codul tipului facturii
+	TypeCode efactura.InvoiceTypeCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TypeCode"`
+	// ID: BT-2
+	// Term: Data emiterii facturii
+	IssueDateTime DateTime `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 IssueDateTime"`
+	// ID: BG-1
+	// Term: COMENTARIU ÎN FACTURĂ
+	IncludedNotes []Note `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 IncludedNote,omitempty"`
+}
+
+// Note is a ram:IncludedNote.
+type Note struct {
+	// ID: BT-22
+	Content string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Content"`
+	// ID: BT-21
+	SubjectCode efactura.InvoiceNoteSubjectCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SubjectCode,omitempty"`
+}
+
+// DateTime is a date encoded as udt:DateTimeString.
+type DateTime struct {
+	DateTimeString DateTimeString `xml:"urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100 DateTimeString"`
+}
+
+// FormattedDateTime is a date encoded as qdt:DateTimeString.
+type FormattedDateTime struct {
+	DateTimeString DateTimeString `xml:"urn:un:unece:uncefact:data:standard:QualifiedDataType:100 DateTimeString"`
+}
+
+type DateTimeString struct {
+	Value  string `xml:",chardata"`
+	Format string `xml:"format,attr"`
+}
+
+// MakeDateTime creates a DateTime for the given date, using DateFormat.
+func MakeDateTime(date types.Date) DateTime {
+	return DateTime{DateTimeString: makeDateTimeString(date)}
+}
+
+// NewDateTime same as MakeDateTime, but a pointer is returned.
+func NewDateTime(date types.Date) *DateTime {
+	dt := MakeDateTime(date)
+	return &dt
+}
+
+// Date returns the date. Only the DateFormat format is supported.
+func (dt DateTime) Date() (types.Date, error) {
+	return dt.DateTimeString.date()
+}
+
+// MakeFormattedDateTime creates a FormattedDateTime for the given date,
+// using DateFormat.
+func MakeFormattedDateTime(date types.Date) FormattedDateTime {
+	return FormattedDateTime{DateTimeString: makeDateTimeString(date)}
+}
+
+// Date returns the date. Only the DateFormat format is supported.
+func (dt FormattedDateTime) Date() (types.Date, error) {
+	return dt.DateTimeString.date()
+}
+
+func makeDateTimeString(date types.Date) DateTimeString {
+	return DateTimeString{Value: date.Format("20060102"), Format: DateFormat}
+}
+
+func (s DateTimeString) date() (date types.Date, err error) {
+	if s.Format != DateFormat {
+		err = fmt.Errorf("cii: unsupported date format %q", s.Format)
+		return
+	}
+	t, err := time.Parse("20060102", s.Value)
+	if err != nil {
+		return date, fmt.Errorf("cii: invalid date %q: %w", s.Value, err)
+	}
+	return types.MakeDateFromTime(t), nil
+}
+
+// ID is an identifier with an optional scheme.
+type ID struct {
+	Value    string `xml:",chardata"`
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+}
+
+// Amount is a monetary amount, with an optional currency. Amounts are
+// formatted using efactura.FormatAmount.
+type Amount struct {
+	Amount     types.Decimal             `xml:",chardata"`
+	CurrencyID efactura.CurrencyCodeType `xml:"currencyID,attr,omitempty"`
+}
+
+// MakeAmount creates an Amount without a currency.
+func MakeAmount(amount types.Decimal) Amount {
+	return Amount{Amount: amount}
+}
+
+// NewAmount same as MakeAmount, but a pointer is returned.
+func NewAmount(amount types.Decimal) *Amount {
+	return &Amount{Amount: amount}
+}
+
+func (a Amount) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type amount struct {
+		Amount     string                    `xml:",chardata"`
+		CurrencyID efactura.CurrencyCodeType `xml:"currencyID,attr,omitempty"`
+	}
+	return e.EncodeElement(amount{
+		Amount:     efactura.FormatAmount(a.Amount),
+		CurrencyID: a.CurrencyID,
+	}, start)
+}
+
+// Price is a unit price amount, formatted using efactura.FormatPrice.
+type Price struct {
+	Amount types.Decimal `xml:",chardata"`
+}
+
+func (p Price) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(efactura.FormatPrice(p.Amount), start)
+}
+
+// Quantity is a quantity with a unit of measure.
+type Quantity struct {
+	Quantity types.Decimal         `xml:",chardata"`
+	UnitCode efactura.UnitCodeType `xml:"unitCode,attr,omitempty"`
+}
+
+// Indicator is a boolean encoded as udt:Indicator.
+type Indicator struct {
+	Indicator bool `xml:"urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100 Indicator"`
+}
+
+// SupplyChainTradeTransaction is the rsm:SupplyChainTradeTransaction.
+type SupplyChainTradeTransaction struct {
+	// ID: BG-25
+	// Term: LINIE A FACTURII
+	LineItems  []LineItem            `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 IncludedSupplyChainTradeLineItem"`
+	Agreement  HeaderTradeAgreement  `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ApplicableHeaderTradeAgreement"`
+	Delivery   HeaderTradeDelivery   `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ApplicableHeaderTradeDelivery"`
+	Settlement HeaderTradeSettlement `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ApplicableHeaderTradeSettlement"`
+}
+
+type HeaderTradeAgreement struct {
+	// ID: BT-10
+	// Term: Referinţa Cumpărătorului
+	BuyerReference string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BuyerReference,omitempty"`
+	// ID: BG-4
+	// Term: VÂNZĂTOR
+	Seller TradeParty `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SellerTradeParty"`
+	// ID: BG-7
+	// Term: CUMPĂRĂTOR
+	Buyer TradeParty `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BuyerTradeParty"`
+	// ID: BT-14
+	// Term: Referinţa comenzii de vânzare
+	SellerOrderReferencedDocument *ReferencedDocument `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SellerOrderReferencedDocument,omitempty"`
+	// ID: BT-13
+	// Term: Referinţa comenzii
+	BuyerOrderReferencedDocument *ReferencedDocument `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BuyerOrderReferencedDocument,omitempty"`
+	// ID: BT-12
+	// Term: Referinţa contractului
+	ContractReferencedDocument *ReferencedDocument `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ContractReferencedDocument,omitempty"`
+}
+
+type ReferencedDocument struct {
+	IssuerAssignedID       string             `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 IssuerAssignedID"`
+	FormattedIssueDateTime *FormattedDateTime `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 FormattedIssueDateTime,omitempty"`
+}
+
+type HeaderTradeDelivery struct {
+	// ID: BT-72
+	// Term: Data reală a livrării
+	ActualDeliverySupplyChainEvent *SupplyChainEvent `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ActualDeliverySupplyChainEvent,omitempty"`
+}
+
+type SupplyChainEvent struct {
+	OccurrenceDateTime DateTime `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 OccurrenceDateTime"`
+}
+
+type HeaderTradeSettlement struct {
+	// ID: BT-83
+	// Term: Aviz de plată
+	PaymentReference string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 PaymentReference,omitempty"`
+	// ID: BT-6
+	// Term: Codul monedei de contabilizare a TVA
+	TaxCurrencyCode efactura.CurrencyCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TaxCurrencyCode,omitempty"`
+	// ID: BT-5
+	// Term: Codul monedei facturii
+	InvoiceCurrencyCode efactura.CurrencyCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 InvoiceCurrencyCode"`
+	// ID: BG-16
+	// Term: INSTRUCŢIUNI DE PLATĂ
+	PaymentMeans []PaymentMeans `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradeSettlementPaymentMeans,omitempty"`
+	// ID: BG-23
+	// Term: DETALIEREA TVA
+	ApplicableTradeTaxes []TradeTax `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ApplicableTradeTax"`
+	// ID: BG-14
+	// Term: Perioada de facturare
+	BillingSpecifiedPeriod *Period `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BillingSpecifiedPeriod,omitempty"`
+	// ID: BG-20 / BG-21
+	// Term: DEDUCERI / TAXE SUPLIMENTARE LA NIVELUL DOCUMENTULUI
+	AllowanceCharges []TradeAllowanceCharge `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradeAllowanceCharge,omitempty"`
+	// ID: BT-20, BT-9
+	// Term: Termeni de plată, Data scadenţei
+	PaymentTerms *PaymentTerms `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradePaymentTerms,omitempty"`
+	// ID: BG-22
+	// Term: TOTALURILE DOCUMENTULUI
+	MonetarySummation HeaderMonetarySummation `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradeSettlementHeaderMonetarySummation"`
+	// ID: BG-3
+	// Term: REFERINŢĂ LA O FACTURĂ ANTERIOARĂ
+	InvoiceReferencedDocuments []ReferencedDocument `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 InvoiceReferencedDocument,omitempty"`
+}
+
+type PaymentMeans struct {
+	// ID: BT-81
+	// Term: Codul tipului de instrument de plată
+	TypeCode efactura.PaymentMeansCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TypeCode"`
+	// ID: BT-82
+	// Term: Explicaţii privind instrumentul de plată
+	Information string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Information,omitempty"`
+	// ID: BG-17
+	// Term: VIRAMENT
+	PayeeAccount *CreditorFinancialAccount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 PayeePartyCreditorFinancialAccount,omitempty"`
+	// ID: BT-86
+	// Term: Identificatorul furnizorului de servicii de plată
+	PayeeInstitution *CreditorFinancialInstitution `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 PayeeSpecifiedCreditorFinancialInstitution,omitempty"`
+}
+
+type CreditorFinancialAccount struct {
+	// ID: BT-84
+	// Term: Identificatorul contului de plată (IBAN)
+	IBANID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 IBANID,omitempty"`
+	// ID: BT-85
+	// Term: Numele contului de plată
+	AccountName string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 AccountName,omitempty"`
+	// ID: BT-84
+	// Term: Identificatorul contului de plată (alt cont decât IBAN)
+	ProprietaryID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ProprietaryID,omitempty"`
+}
+
+type CreditorFinancialInstitution struct {
+	BICID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BICID"`
+}
+
+// TradeTax is a ram:ApplicableTradeTax or a ram:CategoryTradeTax.
+type TradeTax struct {
+	// ID: BT-117
+	// Term: Valoarea TVA pentru fiecare categorie
+	CalculatedAmount *Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CalculatedAmount,omitempty"`
+	TypeCode         string  `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TypeCode"`
+	// ID: BT-120
+	// Term: Motivul scutirii de TVA
+	ExemptionReason string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ExemptionReason,omitempty"`
+	// ID: BT-116
+	// Term: Baza de calcul pentru categoria de TVA
+	BasisAmount *Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BasisAmount,omitempty"`
+	// ID: BT-118
+	// Term: Codul categoriei de TVA
+	CategoryCode efactura.TaxCategoryCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CategoryCode"`
+	// ID: BT-121
+	// Term: Codul motivului scutirii de TVA
+	ExemptionReasonCode efactura.TaxExemptionReasonCodeType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ExemptionReasonCode,omitempty"`
+	// ID: BT-119
+	// Term: Cota categoriei de TVA
+	RateApplicablePercent *types.Decimal `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 RateApplicablePercent,omitempty"`
+}
+
+type Period struct {
+	StartDateTime *DateTime `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 StartDateTime,omitempty"`
+	EndDateTime   *DateTime `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 EndDateTime,omitempty"`
+}
+
+type TradeAllowanceCharge struct {
+	ChargeIndicator    Indicator      `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ChargeIndicator"`
+	CalculationPercent *types.Decimal `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CalculationPercent,omitempty"`
+	BasisAmount        *Amount        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BasisAmount,omitempty"`
+	ActualAmount       Amount         `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ActualAmount"`
+	ReasonCode         string         `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ReasonCode,omitempty"`
+	Reason             string         `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Reason,omitempty"`
+	// Only used for document level allowances and charges.
+	CategoryTradeTax *TradeTax `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CategoryTradeTax,omitempty"`
+}
+
+type PaymentTerms struct {
+	// ID: BT-20
+	// Term: Termeni de plată
+	Description string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Description,omitempty"`
+	// ID: BT-9
+	// Term: Data scadenţei
+	DueDateDateTime *DateTime `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 DueDateDateTime,omitempty"`
+}
+
+type HeaderMonetarySummation struct {
+	// ID: BT-106
+	LineTotalAmount Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 LineTotalAmount"`
+	// ID: BT-108
+	ChargeTotalAmount *Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ChargeTotalAmount,omitempty"`
+	// ID: BT-107
+	AllowanceTotalAmount *Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 AllowanceTotalAmount,omitempty"`
+	// ID: BT-109
+	TaxBasisTotalAmount Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TaxBasisTotalAmount"`
+	// ID: BT-110, BT-111
+	// Term: Valoarea totală a TVA (in moneda facturii si in moneda de
+	//     contabilizare)
+	TaxTotalAmounts []Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TaxTotalAmount,omitempty"`
+	// ID: BT-114
+	RoundingAmount *Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 RoundingAmount,omitempty"`
+	// ID: BT-112
+	GrandTotalAmount Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 GrandTotalAmount"`
+	// ID: BT-113
+	TotalPrepaidAmount *Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TotalPrepaidAmount,omitempty"`
+	// ID: BT-115
+	DuePayableAmount Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 DuePayableAmount"`
+}
+
+// TradeParty is a seller or buyer party.
+type TradeParty struct {
+	// ID: BT-29 / BT-46
+	IDs []ID `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ID,omitempty"`
+	// ID: BT-29 / BT-46 (with a scheme)
+	GlobalIDs []ID `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 GlobalID,omitempty"`
+	// ID: BT-27 / BT-44
+	Name string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Name"`
+	// ID: BT-33
+	Description       string             `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Description,omitempty"`
+	LegalOrganization *LegalOrganization `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedLegalOrganization,omitempty"`
+	// ID: BG-6 / BG-9
+	Contact *TradeContact `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 DefinedTradeContact,omitempty"`
+	// ID: BG-5 / BG-8
+	PostalAddress *TradeAddress `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 PostalTradeAddress,omitempty"`
+	// ID: BT-31, BT-32 / BT-48
+	TaxRegistrations []TaxRegistration `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTaxRegistration,omitempty"`
+}
+
+type LegalOrganization struct {
+	// ID: BT-30 / BT-47
+	ID *ID `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ID,omitempty"`
+	// ID: BT-28 / BT-45
+	TradingBusinessName string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TradingBusinessName,omitempty"`
+}
+
+type TradeContact struct {
+	PersonName string                  `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 PersonName,omitempty"`
+	Telephone  *UniversalCommunication `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 TelephoneUniversalCommunication,omitempty"`
+	Email      *EmailCommunication     `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 EmailURIUniversalCommunication,omitempty"`
+}
+
+type UniversalCommunication struct {
+	CompleteNumber string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CompleteNumber"`
+}
+
+type EmailCommunication struct {
+	URIID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 URIID"`
+}
+
+type TradeAddress struct {
+	PostcodeCode           string                        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 PostcodeCode,omitempty"`
+	LineOne                string                        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 LineOne,omitempty"`
+	LineTwo                string                        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 LineTwo,omitempty"`
+	LineThree              string                        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 LineThree,omitempty"`
+	CityName               string                        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CityName,omitempty"`
+	CountryID              efactura.CountryCodeType      `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CountryID"`
+	CountrySubDivisionName efactura.CountrySubentityType `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 CountrySubDivisionName,omitempty"`
+}
+
+type TaxRegistration struct {
+	ID ID `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ID"`
+}
+
+// LineItem is a ram:IncludedSupplyChainTradeLineItem.
+type LineItem struct {
+	AssociatedDocument LineDocument        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 AssociatedDocumentLineDocument"`
+	Product            TradeProduct        `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradeProduct"`
+	Agreement          LineTradeAgreement  `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedLineTradeAgreement"`
+	Delivery           LineTradeDelivery   `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedLineTradeDelivery"`
+	Settlement         LineTradeSettlement `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedLineTradeSettlement"`
+}
+
+type LineDocument struct {
+	// ID: BT-126
+	LineID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 LineID"`
+	// ID: BT-127
+	IncludedNote *Note `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 IncludedNote,omitempty"`
+}
+
+type TradeProduct struct {
+	// ID: BT-157
+	GlobalID *ID `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 GlobalID,omitempty"`
+	// ID: BT-155
+	SellerAssignedID string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SellerAssignedID,omitempty"`
+	// ID: BT-153
+	Name string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Name"`
+	// ID: BT-154
+	Description string `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 Description,omitempty"`
+	// ID: BT-158
+	Classifications []ProductClassification `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 DesignatedProductClassification,omitempty"`
+}
+
+type ProductClassification struct {
+	ClassCode ClassCode `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ClassCode"`
+}
+
+type ClassCode struct {
+	Value  string `xml:",chardata"`
+	ListID string `xml:"listID,attr,omitempty"`
+}
+
+type LineTradeAgreement struct {
+	// ID: BT-148
+	// Term: Preţul brut al articolului
+	GrossPrice *TradePrice `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 GrossPriceProductTradePrice,omitempty"`
+	// ID: BG-29
+	// Term: DETALII ALE PREŢULUI
+	NetPrice TradePrice `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 NetPriceProductTradePrice"`
+}
+
+type TradePrice struct {
+	// ID: BT-146
+	ChargeAmount Price `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ChargeAmount"`
+	// ID: BT-149, BT-150
+	BasisQuantity *Quantity `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BasisQuantity,omitempty"`
+	// ID: BT-147
+	// Term: Reducere la prețul articolului
+	// NOTE: only used for the gross price.
+	AppliedAllowanceCharge *TradeAllowanceCharge `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 AppliedTradeAllowanceCharge,omitempty"`
+}
+
+type LineTradeDelivery struct {
+	// ID: BT-129, BT-130
+	BilledQuantity Quantity `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BilledQuantity"`
+}
+
+type LineTradeSettlement struct {
+	// ID: BG-30
+	Tax TradeTax `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 ApplicableTradeTax"`
+	// ID: BG-26
+	BillingSpecifiedPeriod *Period `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 BillingSpecifiedPeriod,omitempty"`
+	// ID: BG-27 / BG-28
+	AllowanceCharges []TradeAllowanceCharge `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradeAllowanceCharge,omitempty"`
+	// ID: BT-131
+	MonetarySummation LineMonetarySummation `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 SpecifiedTradeSettlementLineMonetarySummation"`
+}
+
+type LineMonetarySummation struct {
+	LineTotalAmount Amount `xml:"urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100 LineTotalAmount"`
+}
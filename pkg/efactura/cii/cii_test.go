@@ -0,0 +1,201 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package cii
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func buildTestInvoice(t *testing.T) efactura.Invoice {
+	t.Helper()
+
+	taxCategory := efactura.InvoiceLineTaxCategory{
+		TaxScheme: efactura.TaxSchemeVAT,
+		ID:        efactura.TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	line1, err := efactura.NewInvoiceLineBuilder("1", efactura.CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(3)).
+		WithGrossPriceAmount(types.D(100)).
+		WithPriceDeduction(types.D(10)).
+		WithItemName("Produs").
+		WithItemSellerID("P-001").
+		WithItemTaxCategory(taxCategory).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	line2, err := efactura.NewInvoiceLineBuilder("2", efactura.CurrencyRON).
+		WithNote("Transport").
+		WithUnitCode("C62").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(50)).
+		WithItemName("Serviciu").
+		WithItemTaxCategory(taxCategory).
+		AppendAllowanceCharge(efactura.InvoiceLineAllowanceCharge{
+			ChargeIndicator:       false,
+			AllowanceChargeReason: "Discount",
+			Amount:                efactura.AmountWithCurrency{Amount: types.D(5), CurrencyID: efactura.CurrencyRON},
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, err := efactura.NewInvoiceBuilder("CII-1").
+		WithInvoiceTypeCode(efactura.InvoiceTypeCommercialInvoice).
+		WithIssueDate(types.MakeDate(2024, 4, 1)).
+		WithDueDate(types.MakeDate(2024, 5, 1)).
+		WithDocumentCurrencyCode(efactura.CurrencyRON).
+		WithBuyerReference("REF-1").
+		WithOrderReference(efactura.InvoiceOrderReference{OrderID: "PO-1"}).
+		AppendNotes(efactura.InvoiceNote{Note: "Comentariu"}).
+		WithSupplier(efactura.InvoiceSupplierParty{
+			PostalAddress: efactura.MakeInvoiceSupplierPostalAddress(efactura.PostalAddress{
+				Country:          efactura.CountryRO,
+				CountrySubentity: efactura.CountrySubentityRO_B,
+				CityName:         efactura.CityNameROBSector1,
+				Line1:            "Strada Exemplu 1",
+			}),
+			TaxScheme: &efactura.InvoicePartyTaxScheme{
+				TaxScheme: efactura.TaxSchemeVAT,
+				CompanyID: "RO10000008",
+			},
+			LegalEntity: efactura.InvoiceSupplierLegalEntity{
+				Name:             "Furnizor SRL",
+				CompanyID:        efactura.NewValueWithAttrs("J40/1/2020"),
+				CompanyLegalForm: "Capital social 200 RON",
+			},
+			Contact: &efactura.InvoiceSupplierContact{
+				Name:  "Ion Popescu",
+				Email: "ion@example.com",
+			},
+		}).
+		WithCustomer(efactura.InvoiceCustomerParty{
+			Identifications: []efactura.InvoicePartyIdentification{
+				{ID: efactura.MakeValueWithScheme("5790000435968", "0088")},
+			},
+			PostalAddress: efactura.MakeInvoiceCustomerPostalAddress(efactura.PostalAddress{
+				Country:          efactura.CountryRO,
+				CountrySubentity: efactura.CountrySubentityRO_CJ,
+				CityName:         "Cluj-Napoca",
+				Line1:            "Strada Client 2",
+			}),
+			LegalEntity: efactura.InvoiceCustomerLegalEntity{
+				Name:      "Client SRL",
+				CompanyID: efactura.NewValueWithAttrs("12345678"),
+			},
+		}).
+		WithPaymentMeans(efactura.InvoicePaymentMeans{
+			PaymentMeansCode: efactura.PaymentMeansCode{Code: efactura.PaymentMeansCreditTransfer},
+			PaymentID:        "CII-1",
+			PayeeFinancialAccounts: []efactura.PayeeFinancialAccount{
+				{ID: "RO49AAAA1B31007593840000", Name: "Furnizor SRL"},
+			},
+		}).
+		WithPaymentTerms(efactura.InvoicePaymentTerms{Note: "30 de zile"}).
+		WithInvoiceLines([]efactura.InvoiceLine{line1, line2}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return invoice
+}
+
+func TestFromInvoice(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := buildTestInvoice(t)
+	doc := FromInvoice(invoice)
+	assert.Equal(efactura.UploadStandardCII, doc.UploadStandard())
+
+	xmlData, err := doc.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	xmlStr := string(xmlData)
+	assert.Contains(xmlStr, `<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"`)
+	assert.Contains(xmlStr, `<ram:GuidelineSpecifiedDocumentContextParameter><ram:ID>`+efactura.CIUSRO_v101+`</ram:ID>`)
+	assert.Contains(xmlStr, `<ram:IssueDateTime><udt:DateTimeString format="102">20240401</udt:DateTimeString></ram:IssueDateTime>`)
+	assert.Contains(xmlStr, `<ram:BilledQuantity unitCode="H87">3</ram:BilledQuantity>`)
+	assert.Contains(xmlStr, `<ram:NetPriceProductTradePrice><ram:ChargeAmount>90.00</ram:ChargeAmount>`)
+	assert.Contains(xmlStr, `<ram:GlobalID schemeID="0088">5790000435968</ram:GlobalID>`)
+	assert.Contains(xmlStr, `<ram:SpecifiedTaxRegistration><ram:ID schemeID="VA">RO10000008</ram:ID>`)
+	assert.Contains(xmlStr, `<ram:IBANID>RO49AAAA1B31007593840000</ram:IBANID>`)
+	assert.Contains(xmlStr, `<ram:TaxTotalAmount currencyID="RON">`)
+	assert.Contains(xmlStr, `<ram:DuePayableAmount>`+
+		efactura.FormatAmount(invoice.LegalMonetaryTotal.PayableAmount.Amount)+
+		`</ram:DuePayableAmount>`)
+	assert.NotContains(xmlStr, "urn:oasis:names:specification:ubl")
+
+	st, err := efactura.DetectUploadStandard(xmlData)
+	if assert.NoError(err) {
+		assert.Equal(efactura.UploadStandardCII, st)
+	}
+}
+
+func TestInvoiceRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := buildTestInvoice(t)
+	xmlData, err := FromInvoice(invoice).XML()
+	if !assert.NoError(err) {
+		return
+	}
+
+	var doc CrossIndustryInvoice
+	if !assert.NoError(Unmarshal(xmlData, &doc)) {
+		return
+	}
+	assert.Equal("CII-1", doc.ExchangedDocument.ID)
+	assert.Len(doc.SupplyChainTradeTransaction.LineItems, 2)
+
+	converted, err := doc.Invoice()
+	if !assert.NoError(err) {
+		return
+	}
+	// Decimals are not comparable after a round trip, so compare the
+	// marshaled UBL XML.
+	expectedXML, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	convertedXML, err := converted.XML()
+	if assert.NoError(err) {
+		assert.Equal(string(expectedXML), string(convertedXML))
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	assert := assert.New(t)
+
+	dt := MakeDateTime(types.MakeDate(2024, 2, 29))
+	assert.Equal("20240229", dt.DateTimeString.Value)
+	date, err := dt.Date()
+	if assert.NoError(err) {
+		assert.True(date.Equal(types.MakeDate(2024, 2, 29).Time))
+	}
+
+	_, err = DateTime{DateTimeString: DateTimeString{Value: "2024-02-29", Format: "203"}}.Date()
+	assert.Error(err, "unsupported format")
+	_, err = DateTime{DateTimeString: DateTimeString{Value: "20240230", Format: DateFormat}}.Date()
+	assert.Error(err, "invalid date")
+}
@@ -0,0 +1,765 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package cii
+
+import (
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// FromInvoice converts a UBL efactura.Invoice to a CrossIndustryInvoice.
+//
+// The conversion covers the business terms modeled by this package. The
+// following invoice fields have no counterpart here and are dropped:
+// AccountingCost (BT-19), ProjectReference (BT-11), the despatch, receipt,
+// originator and additional document references (BT-15 - BT-18), Payee
+// (BG-10), TaxRepresentative (BG-11), the delivery location and party, the
+// VAT point date code (BT-8), the business process type (BT-23), the
+// electronic addresses (BT-34, BT-49) and the unit code list attributes of
+// quantities.
+// Only the PaymentID of the first payment means is kept, as the payment
+// reference (BT-83).
+func FromInvoice(iv efactura.Invoice) CrossIndustryInvoice {
+	ci := CrossIndustryInvoice{
+		ExchangedDocument: ExchangedDocument{
+			ID:            iv.ID,
+			TypeCode:      iv.InvoiceTypeCode,
+			IssueDateTime: MakeDateTime(iv.IssueDate),
+		},
+	}
+	for _, note := range iv.Note {
+		ci.ExchangedDocument.IncludedNotes = append(ci.ExchangedDocument.IncludedNotes, Note{
+			Content:     note.Note,
+			SubjectCode: note.SubjectCode,
+		})
+	}
+
+	tx := &ci.SupplyChainTradeTransaction
+	for _, line := range iv.InvoiceLines {
+		tx.LineItems = append(tx.LineItems, fromInvoiceLine(line))
+	}
+
+	tx.Agreement = HeaderTradeAgreement{
+		BuyerReference: iv.BuyerReference,
+		Seller:         fromSupplierParty(iv.Supplier.Party),
+		Buyer:          fromCustomerParty(iv.Customer.Party),
+	}
+	if ref := iv.OrderReference; ref != nil {
+		if ref.SalesOrderID != "" {
+			tx.Agreement.SellerOrderReferencedDocument = &ReferencedDocument{IssuerAssignedID: ref.SalesOrderID}
+		}
+		if ref.OrderID != "" {
+			tx.Agreement.BuyerOrderReferencedDocument = &ReferencedDocument{IssuerAssignedID: ref.OrderID}
+		}
+	}
+	if ref := iv.ContractDocumentReference; ref != nil {
+		tx.Agreement.ContractReferencedDocument = &ReferencedDocument{IssuerAssignedID: ref.ID}
+	}
+
+	if iv.Delivery != nil && iv.Delivery.ActualDeliveryDate != nil {
+		tx.Delivery.ActualDeliverySupplyChainEvent = &SupplyChainEvent{
+			OccurrenceDateTime: MakeDateTime(*iv.Delivery.ActualDeliveryDate),
+		}
+	}
+
+	settlement := &tx.Settlement
+	settlement.InvoiceCurrencyCode = iv.DocumentCurrencyCode
+	settlement.TaxCurrencyCode = iv.TaxCurrencyCode
+	for _, pm := range iv.PaymentMeans {
+		if settlement.PaymentReference == "" {
+			settlement.PaymentReference = pm.PaymentID
+		}
+		settlement.PaymentMeans = append(settlement.PaymentMeans, fromPaymentMeans(pm)...)
+	}
+	for _, taxTotal := range iv.TaxTotal {
+		for _, subtotal := range taxTotal.TaxSubtotals {
+			settlement.ApplicableTradeTaxes = append(settlement.ApplicableTradeTaxes, TradeTax{
+				CalculatedAmount:      NewAmount(subtotal.TaxAmount.Amount),
+				TypeCode:              TaxTypeVAT,
+				ExemptionReason:       subtotal.TaxCategory.TaxExemptionReason,
+				BasisAmount:           NewAmount(subtotal.TaxableAmount.Amount),
+				CategoryCode:          subtotal.TaxCategory.ID,
+				ExemptionReasonCode:   subtotal.TaxCategory.TaxExemptionReasonCode,
+				RateApplicablePercent: subtotal.TaxCategory.Percent.Ptr(),
+			})
+		}
+		if taxTotal.TaxAmount != nil {
+			currencyID := taxTotal.TaxAmount.CurrencyID
+			if currencyID == "" {
+				currencyID = iv.DocumentCurrencyCode
+			}
+			settlement.MonetarySummation.TaxTotalAmounts = append(settlement.MonetarySummation.TaxTotalAmounts,
+				Amount{Amount: taxTotal.TaxAmount.Amount, CurrencyID: currencyID})
+		}
+	}
+	if p := iv.InvoicePeriod; p != nil && (p.StartDate != nil || p.EndDate != nil) {
+		settlement.BillingSpecifiedPeriod = fromPeriod(p.StartDate, p.EndDate)
+	}
+	for _, ac := range iv.AllowanceCharges {
+		settlement.AllowanceCharges = append(settlement.AllowanceCharges, TradeAllowanceCharge{
+			ChargeIndicator:    Indicator{Indicator: ac.ChargeIndicator},
+			CalculationPercent: ac.Percent,
+			BasisAmount:        fromAmountPtr(ac.BaseAmount),
+			ActualAmount:       MakeAmount(ac.Amount.Amount),
+			ReasonCode:         ac.AllowanceChargeReasonCode,
+			Reason:             ac.AllowanceChargeReason,
+			CategoryTradeTax: &TradeTax{
+				TypeCode:              TaxTypeVAT,
+				CategoryCode:          ac.TaxCategory.ID,
+				RateApplicablePercent: ac.TaxCategory.Percent.Ptr(),
+			},
+		})
+	}
+	if iv.PaymentTerms != nil || iv.DueDate != nil {
+		settlement.PaymentTerms = &PaymentTerms{}
+		if iv.PaymentTerms != nil {
+			settlement.PaymentTerms.Description = iv.PaymentTerms.Note
+		}
+		if iv.DueDate != nil {
+			settlement.PaymentTerms.DueDateDateTime = NewDateTime(*iv.DueDate)
+		}
+	}
+	total := iv.LegalMonetaryTotal
+	settlement.MonetarySummation.LineTotalAmount = MakeAmount(total.LineExtensionAmount.Amount)
+	settlement.MonetarySummation.ChargeTotalAmount = fromAmountPtr(total.ChargeTotalAmount)
+	settlement.MonetarySummation.AllowanceTotalAmount = fromAmountPtr(total.AllowanceTotalAmount)
+	settlement.MonetarySummation.TaxBasisTotalAmount = MakeAmount(total.TaxExclusiveAmount.Amount)
+	settlement.MonetarySummation.RoundingAmount = fromAmountPtr(total.PayableRoundingAmount)
+	settlement.MonetarySummation.GrandTotalAmount = MakeAmount(total.TaxInclusiveAmount.Amount)
+	settlement.MonetarySummation.TotalPrepaidAmount = fromAmountPtr(total.PrepaidAmount)
+	settlement.MonetarySummation.DuePayableAmount = MakeAmount(total.PayableAmount.Amount)
+	for _, ref := range iv.BillingReferences {
+		doc := ReferencedDocument{IssuerAssignedID: ref.InvoiceDocumentReference.ID}
+		if ref.InvoiceDocumentReference.IssueDate != nil {
+			dt := MakeFormattedDateTime(*ref.InvoiceDocumentReference.IssueDate)
+			doc.FormattedIssueDateTime = &dt
+		}
+		settlement.InvoiceReferencedDocuments = append(settlement.InvoiceReferencedDocuments, doc)
+	}
+
+	ci.Prefill()
+	return ci
+}
+
+// Invoice converts the CrossIndustryInvoice to a UBL efactura.Invoice. All
+// amounts use the invoice currency (BT-5), except the tax total amounts that
+// have a different currencyID. An error is returned if a date cannot be
+// parsed. See FromInvoice for the fields that are not converted.
+func (ci CrossIndustryInvoice) Invoice() (iv efactura.Invoice, err error) {
+	doc := ci.ExchangedDocument
+	tx := ci.SupplyChainTradeTransaction
+	currencyID := tx.Settlement.InvoiceCurrencyCode
+	amount := func(a Amount) efactura.AmountWithCurrency {
+		return efactura.AmountWithCurrency{Amount: a.Amount, CurrencyID: currencyID}
+	}
+	amountPtr := func(a *Amount) *efactura.AmountWithCurrency {
+		if a == nil {
+			return nil
+		}
+		v := amount(*a)
+		return &v
+	}
+
+	iv.ID = doc.ID
+	iv.InvoiceTypeCode = doc.TypeCode
+	if iv.IssueDate, err = doc.IssueDateTime.Date(); err != nil {
+		return
+	}
+	for _, note := range doc.IncludedNotes {
+		iv.Note = append(iv.Note, efactura.InvoiceNote{
+			SubjectCode: note.SubjectCode,
+			Note:        note.Content,
+		})
+	}
+	iv.DocumentCurrencyCode = currencyID
+	iv.TaxCurrencyCode = tx.Settlement.TaxCurrencyCode
+
+	agreement := tx.Agreement
+	iv.BuyerReference = agreement.BuyerReference
+	if agreement.SellerOrderReferencedDocument != nil || agreement.BuyerOrderReferencedDocument != nil {
+		iv.OrderReference = &efactura.InvoiceOrderReference{}
+		if ref := agreement.SellerOrderReferencedDocument; ref != nil {
+			iv.OrderReference.SalesOrderID = ref.IssuerAssignedID
+		}
+		if ref := agreement.BuyerOrderReferencedDocument; ref != nil {
+			iv.OrderReference.OrderID = ref.IssuerAssignedID
+		}
+	}
+	if ref := agreement.ContractReferencedDocument; ref != nil {
+		iv.ContractDocumentReference = efactura.NewIDNode(ref.IssuerAssignedID)
+	}
+	iv.Supplier.Party = toSupplierParty(agreement.Seller)
+	iv.Customer.Party = toCustomerParty(agreement.Buyer)
+
+	if event := tx.Delivery.ActualDeliverySupplyChainEvent; event != nil {
+		date, er := event.OccurrenceDateTime.Date()
+		if err = er; err != nil {
+			return
+		}
+		iv.Delivery = &efactura.InvoiceDelivery{ActualDeliveryDate: &date}
+	}
+
+	settlement := tx.Settlement
+	for _, pm := range settlement.PaymentMeans {
+		iv.PaymentMeans = append(iv.PaymentMeans, toPaymentMeans(pm, settlement.PaymentReference))
+	}
+	if p := settlement.BillingSpecifiedPeriod; p != nil {
+		iv.InvoicePeriod = &efactura.InvoicePeriod{}
+		if iv.InvoicePeriod.StartDate, iv.InvoicePeriod.EndDate, err = toPeriod(p); err != nil {
+			return
+		}
+	}
+	if terms := settlement.PaymentTerms; terms != nil {
+		if terms.Description != "" {
+			iv.PaymentTerms = &efactura.InvoicePaymentTerms{Note: terms.Description}
+		}
+		if terms.DueDateDateTime != nil {
+			date, er := terms.DueDateDateTime.Date()
+			if err = er; err != nil {
+				return
+			}
+			iv.DueDate = &date
+		}
+	}
+	for _, ac := range settlement.AllowanceCharges {
+		docAC := efactura.InvoiceDocumentAllowanceCharge{
+			ChargeIndicator:           ac.ChargeIndicator.Indicator,
+			AllowanceChargeReasonCode: ac.ReasonCode,
+			AllowanceChargeReason:     ac.Reason,
+			Amount:                    amount(ac.ActualAmount),
+			BaseAmount:                amountPtr(ac.BasisAmount),
+			Percent:                   ac.CalculationPercent,
+		}
+		if tax := ac.CategoryTradeTax; tax != nil {
+			docAC.TaxCategory = efactura.InvoiceTaxCategory{
+				ID:        tax.CategoryCode,
+				TaxScheme: efactura.TaxSchemeVAT,
+			}
+			if tax.RateApplicablePercent != nil {
+				docAC.TaxCategory.Percent = *tax.RateApplicablePercent
+			}
+		}
+		iv.AllowanceCharges = append(iv.AllowanceCharges, docAC)
+	}
+
+	summation := settlement.MonetarySummation
+	taxTotal := efactura.InvoiceTaxTotal{}
+	for _, tax := range settlement.ApplicableTradeTaxes {
+		subtotal := efactura.InvoiceTaxSubtotal{
+			TaxCategory: efactura.InvoiceTaxCategory{
+				ID:                     tax.CategoryCode,
+				TaxExemptionReason:     tax.ExemptionReason,
+				TaxExemptionReasonCode: tax.ExemptionReasonCode,
+				TaxScheme:              efactura.TaxSchemeVAT,
+			},
+		}
+		if tax.BasisAmount != nil {
+			subtotal.TaxableAmount = amount(*tax.BasisAmount)
+		}
+		if tax.CalculatedAmount != nil {
+			subtotal.TaxAmount = amount(*tax.CalculatedAmount)
+		}
+		if tax.RateApplicablePercent != nil {
+			subtotal.TaxCategory.Percent = *tax.RateApplicablePercent
+		}
+		taxTotal.TaxSubtotals = append(taxTotal.TaxSubtotals, subtotal)
+	}
+	var otherTaxTotals []efactura.InvoiceTaxTotal
+	for _, taxAmount := range summation.TaxTotalAmounts {
+		if taxAmount.CurrencyID == "" || taxAmount.CurrencyID == currencyID {
+			taxTotal.TaxAmount = amountPtr(&taxAmount)
+			continue
+		}
+		otherTaxTotals = append(otherTaxTotals, efactura.InvoiceTaxTotal{
+			TaxAmount: &efactura.AmountWithCurrency{
+				Amount:     taxAmount.Amount,
+				CurrencyID: taxAmount.CurrencyID,
+			},
+		})
+	}
+	iv.TaxTotal = append([]efactura.InvoiceTaxTotal{taxTotal}, otherTaxTotals...)
+	iv.LegalMonetaryTotal = efactura.InvoiceLegalMonetaryTotal{
+		LineExtensionAmount:   amount(summation.LineTotalAmount),
+		TaxExclusiveAmount:    amount(summation.TaxBasisTotalAmount),
+		TaxInclusiveAmount:    amount(summation.GrandTotalAmount),
+		AllowanceTotalAmount:  amountPtr(summation.AllowanceTotalAmount),
+		ChargeTotalAmount:     amountPtr(summation.ChargeTotalAmount),
+		PrepaidAmount:         amountPtr(summation.TotalPrepaidAmount),
+		PayableRoundingAmount: amountPtr(summation.RoundingAmount),
+		PayableAmount:         amount(summation.DuePayableAmount),
+	}
+	for _, ref := range settlement.InvoiceReferencedDocuments {
+		billingRef := efactura.InvoiceBillingReference{
+			InvoiceDocumentReference: efactura.InvoiceDocumentReference{ID: ref.IssuerAssignedID},
+		}
+		if ref.FormattedIssueDateTime != nil {
+			date, er := ref.FormattedIssueDateTime.Date()
+			if err = er; err != nil {
+				return
+			}
+			billingRef.InvoiceDocumentReference.IssueDate = &date
+		}
+		iv.BillingReferences = append(iv.BillingReferences, billingRef)
+	}
+
+	for i, item := range tx.LineItems {
+		line, er := toInvoiceLine(item, currencyID)
+		if er != nil {
+			err = fmt.Errorf("line %d: %w", i, er)
+			return
+		}
+		iv.InvoiceLines = append(iv.InvoiceLines, line)
+	}
+
+	iv.Prefill()
+	return
+}
+
+func fromAmountPtr(a *efactura.AmountWithCurrency) *Amount {
+	if a == nil {
+		return nil
+	}
+	return NewAmount(a.Amount)
+}
+
+func fromPeriod(start, end *types.Date) *Period {
+	p := &Period{}
+	if start != nil {
+		p.StartDateTime = NewDateTime(*start)
+	}
+	if end != nil {
+		p.EndDateTime = NewDateTime(*end)
+	}
+	return p
+}
+
+func toPeriod(p *Period) (start, end *types.Date, err error) {
+	if p.StartDateTime != nil {
+		date, er := p.StartDateTime.Date()
+		if err = er; err != nil {
+			return
+		}
+		start = &date
+	}
+	if p.EndDateTime != nil {
+		date, er := p.EndDateTime.Date()
+		if err = er; err != nil {
+			return
+		}
+		end = &date
+	}
+	return
+}
+
+// fromPaymentMeans converts a UBL payment means to CII payment means. CII
+// has a single creditor account per payment means, so a payment means with
+// several accounts is converted to one payment means per account.
+func fromPaymentMeans(pm efactura.InvoicePaymentMeans) (means []PaymentMeans) {
+	base := PaymentMeans{
+		TypeCode:    pm.PaymentMeansCode.Code,
+		Information: pm.PaymentMeansCode.Name,
+	}
+	if len(pm.PayeeFinancialAccounts) == 0 {
+		return []PaymentMeans{base}
+	}
+	for _, account := range pm.PayeeFinancialAccounts {
+		m := base
+		m.PayeeAccount = &CreditorFinancialAccount{AccountName: account.Name}
+		if efactura.IsIBAN(account.ID) {
+			m.PayeeAccount.IBANID = account.ID
+		} else {
+			m.PayeeAccount.ProprietaryID = account.ID
+		}
+		if account.FinancialInstitutionBranch != nil {
+			m.PayeeInstitution = &CreditorFinancialInstitution{BICID: account.FinancialInstitutionBranch.ID}
+		}
+		means = append(means, m)
+	}
+	return
+}
+
+func toPaymentMeans(pm PaymentMeans, paymentID string) efactura.InvoicePaymentMeans {
+	means := efactura.InvoicePaymentMeans{
+		PaymentMeansCode: efactura.PaymentMeansCode{
+			Code: pm.TypeCode,
+			Name: pm.Information,
+		},
+		PaymentID: paymentID,
+	}
+	if pm.PayeeAccount != nil {
+		account := efactura.PayeeFinancialAccount{
+			ID:   pm.PayeeAccount.IBANID,
+			Name: pm.PayeeAccount.AccountName,
+		}
+		if account.ID == "" {
+			account.ID = pm.PayeeAccount.ProprietaryID
+		}
+		if pm.PayeeInstitution != nil {
+			account.FinancialInstitutionBranch = efactura.NewIDNode(pm.PayeeInstitution.BICID)
+		}
+		means.PayeeFinancialAccounts = append(means.PayeeFinancialAccounts, account)
+	}
+	return means
+}
+
+func fromIdentifications(party *TradeParty, identifications []efactura.InvoicePartyIdentification) {
+	for _, identification := range identifications {
+		id := ID{
+			Value:    identification.ID.Value,
+			SchemeID: identification.ID.GetAttrByName("schemeID").Value,
+		}
+		if id.SchemeID != "" {
+			party.GlobalIDs = append(party.GlobalIDs, id)
+		} else {
+			party.IDs = append(party.IDs, id)
+		}
+	}
+}
+
+func toIdentifications(party TradeParty) (identifications []efactura.InvoicePartyIdentification) {
+	for _, id := range party.IDs {
+		identifications = append(identifications, efactura.InvoicePartyIdentification{
+			ID: efactura.MakeValueWithAttrs(id.Value),
+		})
+	}
+	for _, id := range party.GlobalIDs {
+		identifications = append(identifications, efactura.InvoicePartyIdentification{
+			ID: efactura.MakeValueWithScheme(id.Value, id.SchemeID),
+		})
+	}
+	return
+}
+
+func fromLegalOrganization(companyID *efactura.ValueWithAttrs, commercialName *efactura.InvoicePartyName) *LegalOrganization {
+	if companyID == nil && commercialName == nil {
+		return nil
+	}
+	org := &LegalOrganization{}
+	if companyID != nil {
+		org.ID = &ID{
+			Value:    companyID.Value,
+			SchemeID: companyID.GetAttrByName("schemeID").Value,
+		}
+	}
+	if commercialName != nil {
+		org.TradingBusinessName = commercialName.Name
+	}
+	return org
+}
+
+func toCompanyID(org *LegalOrganization) *efactura.ValueWithAttrs {
+	if org == nil || org.ID == nil {
+		return nil
+	}
+	if org.ID.SchemeID != "" {
+		return efactura.MakeValueWithScheme(org.ID.Value, org.ID.SchemeID).Ptr()
+	}
+	return efactura.NewValueWithAttrs(org.ID.Value)
+}
+
+func toCommercialName(org *LegalOrganization) *efactura.InvoicePartyName {
+	if org == nil || org.TradingBusinessName == "" {
+		return nil
+	}
+	return &efactura.InvoicePartyName{Name: org.TradingBusinessName}
+}
+
+func fromContact(name, phone, email string) *TradeContact {
+	if name == "" && phone == "" && email == "" {
+		return nil
+	}
+	contact := &TradeContact{PersonName: name}
+	if phone != "" {
+		contact.Telephone = &UniversalCommunication{CompleteNumber: phone}
+	}
+	if email != "" {
+		contact.Email = &EmailCommunication{URIID: email}
+	}
+	return contact
+}
+
+func toContact(contact *TradeContact) (name, phone, email string) {
+	if contact == nil {
+		return
+	}
+	name = contact.PersonName
+	if contact.Telephone != nil {
+		phone = contact.Telephone.CompleteNumber
+	}
+	if contact.Email != nil {
+		email = contact.Email.URIID
+	}
+	return
+}
+
+func fromPostalAddress(address efactura.PostalAddress) *TradeAddress {
+	return &TradeAddress{
+		PostcodeCode:           address.PostalZone,
+		LineOne:                address.Line1,
+		LineTwo:                address.Line2,
+		LineThree:              address.Line3,
+		CityName:               address.CityName,
+		CountryID:              address.Country.Code,
+		CountrySubDivisionName: address.CountrySubentity,
+	}
+}
+
+func toPostalAddress(address *TradeAddress) (postalAddress efactura.PostalAddress) {
+	if address == nil {
+		return
+	}
+	return efactura.PostalAddress{
+		Line1:            address.LineOne,
+		Line2:            address.LineTwo,
+		Line3:            address.LineThree,
+		CityName:         address.CityName,
+		PostalZone:       address.PostcodeCode,
+		CountrySubentity: address.CountrySubDivisionName,
+		Country:          efactura.Country{Code: address.CountryID},
+	}
+}
+
+func fromTaxScheme(taxScheme *efactura.InvoicePartyTaxScheme) []TaxRegistration {
+	if taxScheme == nil || taxScheme.CompanyID == "" {
+		return nil
+	}
+	return []TaxRegistration{{ID: ID{Value: taxScheme.CompanyID, SchemeID: TaxRegistrationSchemeVAT}}}
+}
+
+func toTaxScheme(registrations []TaxRegistration) *efactura.InvoicePartyTaxScheme {
+	for _, registration := range registrations {
+		if registration.ID.SchemeID == TaxRegistrationSchemeVAT {
+			return &efactura.InvoicePartyTaxScheme{
+				CompanyID: registration.ID.Value,
+				TaxScheme: efactura.TaxSchemeVAT,
+			}
+		}
+	}
+	return nil
+}
+
+func fromSupplierParty(p efactura.InvoiceSupplierParty) TradeParty {
+	party := TradeParty{
+		Name:              p.LegalEntity.Name,
+		Description:       p.LegalEntity.CompanyLegalForm,
+		LegalOrganization: fromLegalOrganization(p.LegalEntity.CompanyID, p.CommercialName),
+		PostalAddress:     fromPostalAddress(p.PostalAddress.PostalAddress),
+		TaxRegistrations:  fromTaxScheme(p.TaxScheme),
+	}
+	fromIdentifications(&party, p.Identifications)
+	if p.Contact != nil {
+		party.Contact = fromContact(p.Contact.Name, p.Contact.Phone, p.Contact.Email)
+	}
+	return party
+}
+
+func toSupplierParty(party TradeParty) (p efactura.InvoiceSupplierParty) {
+	p.Identifications = toIdentifications(party)
+	p.CommercialName = toCommercialName(party.LegalOrganization)
+	p.PostalAddress = efactura.MakeInvoiceSupplierPostalAddress(toPostalAddress(party.PostalAddress))
+	p.TaxScheme = toTaxScheme(party.TaxRegistrations)
+	p.LegalEntity = efactura.InvoiceSupplierLegalEntity{
+		Name:             party.Name,
+		CompanyID:        toCompanyID(party.LegalOrganization),
+		CompanyLegalForm: party.Description,
+	}
+	if party.Contact != nil {
+		p.Contact = &efactura.InvoiceSupplierContact{}
+		p.Contact.Name, p.Contact.Phone, p.Contact.Email = toContact(party.Contact)
+	}
+	return
+}
+
+func fromCustomerParty(p efactura.InvoiceCustomerParty) TradeParty {
+	party := TradeParty{
+		Name:              p.LegalEntity.Name,
+		LegalOrganization: fromLegalOrganization(p.LegalEntity.CompanyID, p.CommercialName),
+		PostalAddress:     fromPostalAddress(p.PostalAddress.PostalAddress),
+		TaxRegistrations:  fromTaxScheme(p.TaxScheme),
+	}
+	fromIdentifications(&party, p.Identifications)
+	if p.Contact != nil {
+		party.Contact = fromContact(p.Contact.Name, p.Contact.Phone, p.Contact.Email)
+	}
+	return party
+}
+
+func toCustomerParty(party TradeParty) (p efactura.InvoiceCustomerParty) {
+	p.Identifications = toIdentifications(party)
+	p.CommercialName = toCommercialName(party.LegalOrganization)
+	p.PostalAddress = efactura.MakeInvoiceCustomerPostalAddress(toPostalAddress(party.PostalAddress))
+	p.TaxScheme = toTaxScheme(party.TaxRegistrations)
+	p.LegalEntity = efactura.InvoiceCustomerLegalEntity{
+		Name:      party.Name,
+		CompanyID: toCompanyID(party.LegalOrganization),
+	}
+	if party.Contact != nil {
+		p.Contact = &efactura.InvoiceCustomerContact{}
+		p.Contact.Name, p.Contact.Phone, p.Contact.Email = toContact(party.Contact)
+	}
+	return
+}
+
+func fromInvoiceLine(line efactura.InvoiceLine) LineItem {
+	item := LineItem{
+		AssociatedDocument: LineDocument{LineID: line.ID},
+		Product: TradeProduct{
+			Name:        line.Item.Name,
+			Description: line.Item.Description,
+		},
+		Agreement: LineTradeAgreement{
+			NetPrice: TradePrice{ChargeAmount: Price{Amount: line.Price.PriceAmount.Amount}},
+		},
+		Delivery: LineTradeDelivery{
+			BilledQuantity: Quantity{
+				Quantity: line.InvoicedQuantity.Quantity,
+				UnitCode: line.InvoicedQuantity.UnitCode,
+			},
+		},
+		Settlement: LineTradeSettlement{
+			Tax: TradeTax{
+				TypeCode:              TaxTypeVAT,
+				CategoryCode:          line.Item.TaxCategory.ID,
+				RateApplicablePercent: line.Item.TaxCategory.Percent.Ptr(),
+			},
+			MonetarySummation: LineMonetarySummation{
+				LineTotalAmount: MakeAmount(line.LineExtensionAmount.Amount),
+			},
+		},
+	}
+	if line.Note != "" {
+		item.AssociatedDocument.IncludedNote = &Note{Content: line.Note}
+	}
+	if id := line.Item.StandardItemIdentification; id != nil {
+		item.Product.GlobalID = &ID{Value: id.Code, SchemeID: id.SchemeID}
+	}
+	if line.Item.SellerItemID != nil {
+		item.Product.SellerAssignedID = line.Item.SellerItemID.ID
+	}
+	if c := line.Item.CommodityClassification; c != nil {
+		item.Product.Classifications = append(item.Product.Classifications, ProductClassification{
+			ClassCode: ClassCode{
+				Value:  c.ItemClassificationCode.Code,
+				ListID: c.ItemClassificationCode.ListID,
+			},
+		})
+	}
+	if q := line.Price.BaseQuantity; q != nil {
+		item.Agreement.NetPrice.BasisQuantity = &Quantity{Quantity: q.Quantity, UnitCode: q.UnitCode}
+	}
+	if ac := line.Price.AllowanceCharge; ac != nil {
+		item.Agreement.GrossPrice = &TradePrice{
+			ChargeAmount: Price{Amount: ac.BaseAmount.Amount},
+			AppliedAllowanceCharge: &TradeAllowanceCharge{
+				ChargeIndicator: Indicator{Indicator: ac.ChargeIndicator},
+				ActualAmount:    MakeAmount(ac.Amount.Amount),
+			},
+		}
+	}
+	if p := line.InvoicePeriod; p != nil && (p.StartDate != nil || p.EndDate != nil) {
+		item.Settlement.BillingSpecifiedPeriod = fromPeriod(p.StartDate, p.EndDate)
+	}
+	for _, ac := range line.AllowanceCharges {
+		item.Settlement.AllowanceCharges = append(item.Settlement.AllowanceCharges, TradeAllowanceCharge{
+			ChargeIndicator: Indicator{Indicator: ac.ChargeIndicator},
+			BasisAmount:     fromAmountPtr(ac.BaseAmount),
+			ActualAmount:    MakeAmount(ac.Amount.Amount),
+			ReasonCode:      ac.AllowanceChargeReasonCode,
+			Reason:          ac.AllowanceChargeReason,
+		})
+	}
+	return item
+}
+
+func toInvoiceLine(item LineItem, currencyID efactura.CurrencyCodeType) (line efactura.InvoiceLine, err error) {
+	amount := func(a types.Decimal) efactura.AmountWithCurrency {
+		return efactura.AmountWithCurrency{Amount: a, CurrencyID: currencyID}
+	}
+
+	line.ID = item.AssociatedDocument.LineID
+	if note := item.AssociatedDocument.IncludedNote; note != nil {
+		line.Note = note.Content
+	}
+	line.InvoicedQuantity = efactura.InvoicedQuantity{
+		Quantity: item.Delivery.BilledQuantity.Quantity,
+		UnitCode: item.Delivery.BilledQuantity.UnitCode,
+	}
+	line.LineExtensionAmount = amount(item.Settlement.MonetarySummation.LineTotalAmount.Amount)
+	if p := item.Settlement.BillingSpecifiedPeriod; p != nil {
+		line.InvoicePeriod = &efactura.InvoiceLinePeriod{}
+		if line.InvoicePeriod.StartDate, line.InvoicePeriod.EndDate, err = toPeriod(p); err != nil {
+			return
+		}
+	}
+	for _, ac := range item.Settlement.AllowanceCharges {
+		lineAC := efactura.InvoiceLineAllowanceCharge{
+			ChargeIndicator:           ac.ChargeIndicator.Indicator,
+			AllowanceChargeReasonCode: ac.ReasonCode,
+			AllowanceChargeReason:     ac.Reason,
+			Amount:                    amount(ac.ActualAmount.Amount),
+		}
+		if ac.BasisAmount != nil {
+			baseAmount := amount(ac.BasisAmount.Amount)
+			lineAC.BaseAmount = &baseAmount
+		}
+		line.AllowanceCharges = append(line.AllowanceCharges, lineAC)
+	}
+
+	product := item.Product
+	line.Item = efactura.InvoiceLineItem{
+		Name:        product.Name,
+		Description: product.Description,
+		TaxCategory: efactura.InvoiceLineTaxCategory{
+			ID:        item.Settlement.Tax.CategoryCode,
+			TaxScheme: efactura.TaxSchemeVAT,
+		},
+	}
+	if rate := item.Settlement.Tax.RateApplicablePercent; rate != nil {
+		line.Item.TaxCategory.Percent = *rate
+	}
+	if product.SellerAssignedID != "" {
+		line.Item.SellerItemID = efactura.NewIDNode(product.SellerAssignedID)
+	}
+	if product.GlobalID != nil {
+		line.Item.StandardItemIdentification = &efactura.ItemStandardIdentificationCode{
+			Code:     product.GlobalID.Value,
+			SchemeID: product.GlobalID.SchemeID,
+		}
+	}
+	if len(product.Classifications) > 0 {
+		// UBL CIUS-RO allows a single commodity classification.
+		line.Item.CommodityClassification = &efactura.ItemCommodityClassification{
+			ItemClassificationCode: efactura.ItemClassificationCode{
+				Code:   product.Classifications[0].ClassCode.Value,
+				ListID: product.Classifications[0].ClassCode.ListID,
+			},
+		}
+	}
+
+	line.Price.PriceAmount = amount(item.Agreement.NetPrice.ChargeAmount.Amount)
+	if q := item.Agreement.NetPrice.BasisQuantity; q != nil {
+		line.Price.BaseQuantity = &efactura.InvoicedQuantity{Quantity: q.Quantity, UnitCode: q.UnitCode}
+	}
+	if gross := item.Agreement.GrossPrice; gross != nil && gross.AppliedAllowanceCharge != nil {
+		line.Price.AllowanceCharge = &efactura.InvoiceLinePriceAllowanceCharge{
+			ChargeIndicator: gross.AppliedAllowanceCharge.ChargeIndicator.Indicator,
+			Amount:          amount(gross.AppliedAllowanceCharge.ActualAmount.Amount),
+			BaseAmount:      amount(gross.ChargeAmount.Amount),
+		}
+	}
+	return
+}
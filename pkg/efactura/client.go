@@ -16,11 +16,15 @@ package efactura
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	xoauth2 "golang.org/x/oauth2"
 
 	"github.com/printesoi/e-factura-go/pkg/client"
 	"github.com/printesoi/e-factura-go/pkg/constants"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
 )
 
 // ClientConfig is the config used to create a Client
@@ -29,6 +33,83 @@ type ClientConfig struct {
 	ApiClient *client.ApiClient
 	// the client to use for making requests to the ANAF public APIs.
 	PublicApiClient *client.PublicApiClient
+	// the cache for the terminal states of the uploaded messages (optional).
+	MessageStateCache MessageStateCache
+	// the User-Agent sent with all the requests (optional). If empty, the
+	// User-Agent of the ApiClient and PublicApiClient is used.
+	UserAgent string
+	// the application name used in the "generated with" XML comment of the
+	// uploaded invoices (optional).
+	GeneratedWith string
+	// the CharsetReader used for parsing the downloaded documents that are
+	// not encoded as UTF-8 (optional).
+	CharsetReader pxml.CharsetReader
+	// the generator of the correlation IDs attached to the upload and
+	// download operations (optional). If not set, correlation.NewUUIDv7 is
+	// used.
+	CorrelationIDGenerator correlation.IDGenerator
+	// the client-side rate limits per call group (optional). If not set,
+	// the calls are not rate limited.
+	RateLimits map[RateLimitGroup]RateLimit
+	// the base URL of the e-factura endpoints of the protected APIs
+	// (optional), eg. for a proxy that rewrites the paths. If not set, the
+	// endpoints are under FCTEL/rest/ relative to the base URL of the
+	// ApiClient.
+	ApiBaseURL string
+	// the base URL of the e-factura endpoints of the public APIs
+	// (optional). If not set, the endpoints are under FCTEL/rest/ relative
+	// to the base URL of the PublicApiClient. The signature validation
+	// endpoint is not part of this group.
+	PublicApiBaseURL string
+	// the instrumentation receiving the telemetry of the calls (optional).
+	Instrumentation Instrumentation
+	// the ANAF environment of the e-factura endpoints (optional). If set,
+	// the endpoint groups that don't have an explicit base URL (ApiBaseURL,
+	// PublicApiBaseURL) use the base URLs of the environment, regardless of
+	// the base URLs of the ApiClient and PublicApiClient.
+	Environment client.Environment
+}
+
+// Validate checks that the config is complete and consistent. The ApiClient
+// and PublicApiClient are already validated when created (see
+// client.ApiClientConfig.Validate and client.PublicApiClientConfig.Validate).
+// All the problems found are returned as a single joined error.
+func (c *ClientConfig) Validate() error {
+	var errs []error
+	if c.ApiClient == nil && c.PublicApiClient == nil {
+		errs = append(errs, errors.New("at least one of ApiClient or PublicApiClient must be set"))
+	}
+	if c.Environment != "" {
+		if err := c.Environment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.ApiBaseURL != "" {
+		if err := client.ValidateBaseURL(c.ApiBaseURL); err != nil {
+			errs = append(errs, fmt.Errorf("ApiBaseURL: %w", err))
+		}
+		if c.ApiClient == nil {
+			errs = append(errs, errors.New("ApiBaseURL is set, but ApiClient is not"))
+		}
+		if client.SameHost(c.ApiBaseURL, constants.PublicApiBaseURL) {
+			errs = append(errs, fmt.Errorf("ApiBaseURL %q points to the public APIs, not to the protected APIs", c.ApiBaseURL))
+		}
+	}
+	if c.PublicApiBaseURL != "" {
+		if err := client.ValidateBaseURL(c.PublicApiBaseURL); err != nil {
+			errs = append(errs, fmt.Errorf("PublicApiBaseURL: %w", err))
+		}
+		if c.PublicApiClient == nil {
+			errs = append(errs, errors.New("PublicApiBaseURL is set, but PublicApiClient is not"))
+		}
+		if client.SameHost(c.PublicApiBaseURL, constants.ApiBaseURL) {
+			errs = append(errs, fmt.Errorf("PublicApiBaseURL %q points to the protected APIs, not to the public APIs", c.PublicApiBaseURL))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid client config: %w", errors.Join(errs...))
+	}
+	return nil
 }
 
 // ClientConfigOption allows gradually modifying a ClientConfig
@@ -48,14 +129,136 @@ func ClientPublicApiClient(publicApiClient *client.PublicApiClient) ClientConfig
 	}
 }
 
+// ClientMessageStateCache sets the cache used by GetMessageState for the
+// terminal states of the uploaded messages.
+func ClientMessageStateCache(cache MessageStateCache) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.MessageStateCache = cache
+	}
+}
+
+// ClientUserAgent sets the User-Agent sent with all the requests, so that
+// the traffic can be tagged with the application identity (eg.
+// "MyERP/2.1 e-factura-go/" + efactura.Version()).
+func ClientUserAgent(userAgent string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.UserAgent = userAgent
+	}
+}
+
+// ClientGeneratedWith sets the application name used in the "generated
+// with" XML comment (see GeneratedWithComment) of the invoices and credit
+// notes uploaded by the Client. Documents with a non-empty Comment are not
+// changed.
+func ClientGeneratedWith(application string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.GeneratedWith = application
+	}
+}
+
+// ClientCharsetReader sets the CharsetReader used by DownloadInvoiceParseZip
+// for the documents that are not encoded as UTF-8. If not set, the
+// CharsetReader set with pxml.SetCharsetReader is used (by default
+// pxml.DefaultCharsetReader).
+func ClientCharsetReader(cr pxml.CharsetReader) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.CharsetReader = cr
+	}
+}
+
+// ClientCorrelationIDGenerator sets the generator of the correlation IDs
+// attached to the upload and download operations that are not called with a
+// context already carrying a correlation ID (see correlation.NewContext).
+func ClientCorrelationIDGenerator(gen correlation.IDGenerator) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.CorrelationIDGenerator = gen
+	}
+}
+
+// ClientRateLimit sets the client-side rate limit for the given call group,
+// so bulk integrations don't hit the ANAF limits (see
+// errors.LimitExceededError). The calls wait until the limits of their group
+// and of RateLimitGroupAll allow them, or until the context is done. A limit
+// with Limit <= 0 removes the limit of the group.
+func ClientRateLimit(group RateLimitGroup, limit RateLimit) ClientConfigOption {
+	return func(c *ClientConfig) {
+		if c.RateLimits == nil {
+			c.RateLimits = make(map[RateLimitGroup]RateLimit)
+		}
+		c.RateLimits[group] = limit
+	}
+}
+
+// ClientDefaultRateLimits sets the rate limits returned by DefaultRateLimits.
+func ClientDefaultRateLimits() ClientConfigOption {
+	return func(c *ClientConfig) {
+		for group, limit := range DefaultRateLimits() {
+			ClientRateLimit(group, limit)(c)
+		}
+	}
+}
+
+// ClientApiBaseURL sets the base URL of the e-factura endpoints of the
+// protected APIs, eg. "https://proxy.example.com/anaf/efactura/" for a proxy
+// that forwards to https://api.anaf.ro/prod/FCTEL/rest/. The requests are
+// still made with the ApiClient (and its OAuth2 token).
+func ClientApiBaseURL(baseURL string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.ApiBaseURL = baseURL
+	}
+}
+
+// ClientPublicApiBaseURL sets the base URL of the e-factura endpoints of the
+// public APIs (validation and XML-To-PDF), eg. for a proxy that forwards to
+// https://webservicesp.anaf.ro/prod/FCTEL/rest/.
+func ClientPublicApiBaseURL(baseURL string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.PublicApiBaseURL = baseURL
+	}
+}
+
+// ClientInstrumentation sets the Instrumentation receiving the telemetry of
+// the calls (eg. for OpenTelemetry spans and metrics): the operation, the
+// status code, the duration, the request and response sizes, the wait for
+// the client-side rate limiters and the ANAF limit exceeded errors.
+func ClientInstrumentation(instrumentation Instrumentation) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.Instrumentation = instrumentation
+	}
+}
+
+// ClientEnvironment sets the ANAF environment (client.EnvironmentProduction
+// or client.EnvironmentSandbox) of the e-factura endpoints, so the same code
+// can switch between the sandbox and the production APIs with a single
+// option. The base URLs set with ClientApiBaseURL and ClientPublicApiBaseURL
+// take precedence. ANAF does not provide a sandbox version of the public
+// APIs, so the validation and XML to PDF endpoints are always the production
+// ones (see client.Environment.PublicApiBaseURL).
+func ClientEnvironment(env client.Environment) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.Environment = env
+	}
+}
+
 // Client is a client that talks to ANAF e-factura APIs.
 type Client struct {
 	apiClient       *client.ApiClient
 	publicApiClient *client.PublicApiClient
+	stateCache      MessageStateCache
+	userAgent       string
+	generatedWith   string
+	charsetReader   pxml.CharsetReader
+	correlationIDs  correlation.IDGenerator
+	rateLimiters    map[RateLimitGroup]*tokenBucket
+	apiBase         string
+	publicApiBase   string
+	instrumentation Instrumentation
 }
 
-// NewProductionClient creates a new basic Client for the ANAF e-factura production APIs.
-func NewProductionClient(ctx context.Context, tokenSource xoauth2.TokenSource) (*Client, error) {
+// NewProductionClient creates a new basic Client for the ANAF e-factura
+// production APIs. The options are applied after the default API clients are
+// set (see NewClient).
+func NewProductionClient(ctx context.Context, tokenSource xoauth2.TokenSource, opts ...ClientConfigOption) (*Client, error) {
 	apiClient, err := client.NewApiClient(
 		client.ApiClientContext(ctx),
 		client.ApiClientProductionEnvironment(true),
@@ -68,15 +271,20 @@ func NewProductionClient(ctx context.Context, tokenSource xoauth2.TokenSource) (
 	publicApiClient, err := client.NewPublicApiClient(
 		client.PublicApiClientBaseURL(constants.PublicApiBaseProd),
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Client{
-		apiClient:       apiClient,
-		publicApiClient: publicApiClient,
-	}, nil
+	return NewClient(append([]ClientConfigOption{
+		ClientApiClient(apiClient),
+		ClientPublicApiClient(publicApiClient),
+	}, opts...)...)
 }
 
-// NewSandboxClient creates a new basic Client for the ANAF e-factura sandbox(test) APIs.
-func NewSandboxClient(ctx context.Context, tokenSource xoauth2.TokenSource) (*Client, error) {
+// NewSandboxClient creates a new basic Client for the ANAF e-factura
+// sandbox(test) APIs. The options are applied after the default API clients
+// are set (see NewClient).
+func NewSandboxClient(ctx context.Context, tokenSource xoauth2.TokenSource, opts ...ClientConfigOption) (*Client, error) {
 	apiClient, err := client.NewApiClient(
 		client.ApiClientContext(ctx),
 		client.ApiClientSandboxEnvironment(true),
@@ -93,22 +301,78 @@ func NewSandboxClient(ctx context.Context, tokenSource xoauth2.TokenSource) (*Cl
 		return nil, err
 	}
 
-	return &Client{
-		apiClient:       apiClient,
-		publicApiClient: publicApiClient,
-	}, nil
+	return NewClient(append([]ClientConfigOption{
+		ClientApiClient(apiClient),
+		ClientPublicApiClient(publicApiClient),
+	}, opts...)...)
 }
 
 // NewClient allow for more control than NewProductionClient and NewSandboxClient
-// by passing custom ApiClient and PublicApiClient to this Client.
+// by passing custom ApiClient and PublicApiClient to this Client. The config
+// is validated before creating the Client (see ClientConfig.Validate).
 func NewClient(opts ...ClientConfigOption) (*Client, error) {
 	cfg := &ClientConfig{}
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	groupBase, publicGroupBase := apiBase, publicApiBase
+	if cfg.Environment != "" {
+		groupBase = cfg.Environment.ApiBaseURL() + apiBase
+		publicGroupBase = cfg.Environment.PublicApiBaseURL() + publicApiBase
+	}
+	if cfg.ApiBaseURL != "" {
+		groupBase = cfg.ApiBaseURL
+	}
+	if cfg.PublicApiBaseURL != "" {
+		publicGroupBase = cfg.PublicApiBaseURL
+	}
+
+	rateLimiters := make(map[RateLimitGroup]*tokenBucket)
+	for group, limit := range cfg.RateLimits {
+		if limit.Limit > 0 && limit.Per > 0 {
+			rateLimiters[group] = newTokenBucket(limit)
+		}
+	}
 
 	return &Client{
 		apiClient:       cfg.ApiClient,
 		publicApiClient: cfg.PublicApiClient,
+		stateCache:      cfg.MessageStateCache,
+		userAgent:       cfg.UserAgent,
+		generatedWith:   cfg.GeneratedWith,
+		charsetReader:   cfg.CharsetReader,
+		correlationIDs:  cfg.CorrelationIDGenerator,
+		rateLimiters:    rateLimiters,
+		apiBase:         groupBase,
+		publicApiBase:   publicGroupBase,
+		instrumentation: cfg.Instrumentation,
 	}, nil
 }
+
+// requestOptions appends to opts the request options set for all the
+// requests made by the Client (eg. the User-Agent).
+func (c *Client) requestOptions(opts ...client.RequestOption) []client.RequestOption {
+	if c.userAgent != "" {
+		opts = append(opts, client.RequestOptionHeader("User-Agent", c.userAgent))
+	}
+	return opts
+}
+
+// withCorrelationID returns ctx and the correlation ID carried by ctx, or a
+// copy of ctx carrying a new correlation ID.
+func (c *Client) withCorrelationID(ctx context.Context) (context.Context, string) {
+	return correlation.Ensure(ctx, c.correlationIDs)
+}
+
+// generatedWithComment returns the "generated with" comment for a document
+// with the given comment.
+func (c *Client) generatedWithComment(comment string) string {
+	if comment == "" && c.generatedWith != "" {
+		return GeneratedWithComment(c.generatedWith)
+	}
+	return comment
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// setupTestClient sets up a test HTTP server along with an efactura.Client
+// that is configured to talk to that test server (for both the protected and
+// the public APIs). Tests should register handlers on mux which provide mock
+// responses for the API method being tested.
+func setupTestClient(t *testing.T, opts ...efactura.ClientConfigOption) (c *efactura.Client, mux *http.ServeMux) {
+	t.Helper()
+
+	mux = http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	apiClient, err := client.NewApiClient(
+		client.ApiClientContext(ctx),
+		client.ApiClientBaseURL(server.URL+"/"),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if err != nil {
+		t.Fatalf("error creating api client: %v", err)
+	}
+	publicApiClient, err := client.NewPublicApiClient(
+		client.PublicApiClientBaseURL(server.URL + "/"),
+	)
+	if err != nil {
+		t.Fatalf("error creating public api client: %v", err)
+	}
+	c, err = efactura.NewClient(append([]efactura.ClientConfigOption{
+		efactura.ClientApiClient(apiClient),
+		efactura.ClientPublicApiClient(publicApiClient),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestNewClientValidation(t *testing.T) {
+	if _, err := efactura.NewClient(); err == nil {
+		t.Errorf("NewClient without any API client must fail")
+	}
+}
+
+func TestClientUserAgentAndGeneratedWith(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t,
+		efactura.ClientUserAgent("MyERP/2.1"),
+		efactura.ClientGeneratedWith("MyERP 2.1"))
+	var userAgents, bodies []string
+	mux.HandleFunc("/FCTEL/rest/upload", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		userAgents = append(userAgents, r.UserAgent())
+		bodies = append(bodies, string(body))
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+			`<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="42"/>`)
+	})
+
+	ctx := context.Background()
+	_, err := client.UploadInvoice(ctx, efactura.Invoice{ID: "1"}, "123456789")
+	assert.NoError(err)
+	_, err = client.UploadInvoice(ctx, efactura.Invoice{ID: "2", Comment: "Custom"}, "123456789")
+	assert.NoError(err)
+	_, err = client.Upload(ctx, efactura.RaspMessage{UploadIndex: 42, Message: "test"}, "123456789")
+	assert.NoError(err)
+
+	if assert.Len(bodies, 3) {
+		assert.Equal([]string{"MyERP/2.1", "MyERP/2.1", "MyERP/2.1"}, userAgents)
+		assert.Contains(bodies[0], "<!--Generated with MyERP 2.1 (e-factura-go "+efactura.Version()+")-->")
+		assert.Contains(bodies[1], "<!--Custom-->")
+		assert.False(strings.Contains(bodies[2], "Generated with"))
+	}
+	assert.Equal("Generated with e-factura-go "+efactura.Version(), efactura.GeneratedWithComment(""))
+}
+
+func TestClientCorrelationID(t *testing.T) {
+	assert := assert.New(t)
+
+	var n int
+	client, mux := setupTestClient(t, efactura.ClientCorrelationIDGenerator(func() string {
+		n++
+		return fmt.Sprintf("corr-%d", n)
+	}))
+	var headers []string
+	mux.HandleFunc("/FCTEL/rest/upload", func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get(correlation.HeaderName))
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+			`<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="42"/>`)
+	})
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get(correlation.HeaderName))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(newTestInvoiceZip(t, 3001))
+	})
+
+	ctx := context.Background()
+	ures, err := client.UploadInvoice(ctx, efactura.Invoice{ID: "1"}, "123456789")
+	if assert.NoError(err) {
+		assert.Equal("corr-1", ures.CorrelationID)
+	}
+	dres, err := client.DownloadInvoice(ctx, 3001)
+	if assert.NoError(err) {
+		assert.Equal("corr-2", dres.CorrelationID)
+	}
+
+	// The correlation ID carried by the context is used instead of
+	// generating a new one.
+	ctx = correlation.NewContext(ctx, "order-77")
+	ures, err = client.UploadInvoice(ctx, efactura.Invoice{ID: "2"}, "123456789")
+	if assert.NoError(err) {
+		assert.Equal("order-77", ures.CorrelationID)
+	}
+	assert.Equal([]string{"corr-1", "corr-2", "order-77"}, headers)
+}
+
+func TestClientEndpointGroupBaseURLs(t *testing.T) {
+	assert := assert.New(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	apiClient, err := client.NewApiClient(
+		client.ApiClientBaseURL(server.URL+"/"),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	publicApiClient, err := client.NewPublicApiClient(client.PublicApiClientBaseURL(server.URL + "/"))
+	if !assert.NoError(err) {
+		return
+	}
+	c, err := efactura.NewClient(
+		efactura.ClientApiClient(apiClient),
+		efactura.ClientPublicApiClient(publicApiClient),
+		efactura.ClientApiBaseURL(server.URL+"/proxy/efactura/"),
+		efactura.ClientPublicApiBaseURL(server.URL+"/proxy/public/"),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+
+	mux.HandleFunc("/proxy/efactura/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		// The requests of the group are still authorized.
+		assert.Equal("Bearer test-access-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="in prelucrare"/>`)
+	})
+	mux.HandleFunc("/proxy/public/validare/FACT1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"stare":"ok","trace_id":"t-1"}`)
+	})
+
+	state, err := c.GetMessageState(context.Background(), 5001)
+	if assert.NoError(err) {
+		assert.True(state.IsProcessing())
+	}
+	validation, err := c.ValidateXML(context.Background(), strings.NewReader("<Invoice/>"), efactura.ValidateStandardFACT1)
+	if assert.NoError(err) {
+		assert.True(validation.IsOk())
+	}
+}
+
+func TestClientEndpointGroupBaseURLsValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	publicApiClient, err := client.NewPublicApiClient()
+	if !assert.NoError(err) {
+		return
+	}
+	_, err = efactura.NewClient(
+		efactura.ClientPublicApiClient(publicApiClient),
+		efactura.ClientApiBaseURL("https://proxy.example.com/efactura"),
+		efactura.ClientPublicApiBaseURL("https://api.anaf.ro/prod/FCTEL/rest/"),
+	)
+	if assert.Error(err) {
+		assert.ErrorContains(err, "ApiBaseURL: BaseURL must have a trailing slash")
+		assert.ErrorContains(err, "ApiBaseURL is set, but ApiClient is not")
+		assert.ErrorContains(err, "points to the protected APIs")
+	}
+
+	_, err = efactura.NewClient(
+		efactura.ClientPublicApiClient(publicApiClient),
+		efactura.ClientPublicApiBaseURL("proxy/"),
+	)
+	assert.ErrorContains(err, "must be an absolute URL")
+}
+
+// redirectTransport records the URLs of the requests and sends them to the
+// test server instead.
+type redirectTransport struct {
+	target *url.URL
+	urls   []string
+}
+
+func (t *redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, r.URL.String())
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host, r.Host = t.target.Scheme, t.target.Host, ""
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestClientEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	})
+	for _, path := range []string{"/test/FCTEL/rest/stareMesaj", "/prod/FCTEL/rest/stareMesaj"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="in prelucrare"/>`)
+		})
+	}
+
+	target, _ := url.Parse(server.URL)
+	transport := &redirectTransport{target: target}
+	ctx := context.WithValue(context.Background(), xoauth2.HTTPClient, &http.Client{Transport: transport})
+	apiClient, err := client.NewApiClient(
+		client.ApiClientContext(ctx),
+		client.ApiClientBaseURL(server.URL+"/"),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+
+	for _, env := range []client.Environment{client.EnvironmentSandbox, client.EnvironmentProduction} {
+		c, err := efactura.NewClient(
+			efactura.ClientApiClient(apiClient),
+			efactura.ClientEnvironment(env),
+		)
+		if !assert.NoError(err) {
+			return
+		}
+		_, err = c.GetMessageState(context.Background(), 5001)
+		assert.NoError(err)
+	}
+	assert.Equal([]string{
+		"https://api.anaf.ro/test/FCTEL/rest/stareMesaj?id_incarcare=5001",
+		"https://api.anaf.ro/prod/FCTEL/rest/stareMesaj?id_incarcare=5001",
+	}, transport.urls)
+
+	// An explicit base URL takes precedence over the environment.
+	transport.urls = nil
+	c, err := efactura.NewClient(
+		efactura.ClientApiClient(apiClient),
+		efactura.ClientEnvironment(client.EnvironmentSandbox),
+		efactura.ClientApiBaseURL("https://proxy.example.com/prod/FCTEL/rest/"),
+	)
+	if assert.NoError(err) {
+		_, err = c.GetMessageState(context.Background(), 5002)
+		assert.NoError(err)
+		assert.Equal([]string{"https://proxy.example.com/prod/FCTEL/rest/stareMesaj?id_incarcare=5002"}, transport.urls)
+	}
+
+	_, err = efactura.NewClient(
+		efactura.ClientApiClient(apiClient),
+		efactura.ClientEnvironment("staging"),
+	)
+	assert.ErrorContains(err, `invalid environment "staging"`)
+}
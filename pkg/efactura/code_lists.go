@@ -0,0 +1,854 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+// The code lists below contain all the codes defined in codes.go and must be
+// kept in sync with it.
+
+var invoiceTypeCodes = map[InvoiceTypeCodeType]struct{}{
+	InvoiceTypeCommercialInvoice:                    {},
+	InvoiceTypeCreditNote:                           {},
+	InvoiceTypeCorrectedInvoice:                     {},
+	InvoiceTypeSelfBilledInvoice:                    {},
+	InvoiceTypeInvoiceInformationAccountingPurposes: {},
+}
+
+// IsValid returns true if the code is a valid invoice type code allowed by CIUS-RO (UNTDID 1001 subset).
+func (c InvoiceTypeCodeType) IsValid() bool {
+	_, ok := invoiceTypeCodes[c]
+	return ok
+}
+
+var currencyCodes = map[CurrencyCodeType]struct{}{
+	CurrencyAED: {},
+	CurrencyAFN: {},
+	CurrencyALL: {},
+	CurrencyAMD: {},
+	CurrencyANG: {},
+	CurrencyAOA: {},
+	CurrencyARS: {},
+	CurrencyAUD: {},
+	CurrencyAWG: {},
+	CurrencyAZN: {},
+	CurrencyBAM: {},
+	CurrencyBBD: {},
+	CurrencyBDT: {},
+	CurrencyBGN: {},
+	CurrencyBHD: {},
+	CurrencyBIF: {},
+	CurrencyBMD: {},
+	CurrencyBND: {},
+	CurrencyBOB: {},
+	CurrencyBOV: {},
+	CurrencyBRL: {},
+	CurrencyBSD: {},
+	CurrencyBTN: {},
+	CurrencyBWP: {},
+	CurrencyBYN: {},
+	CurrencyBZD: {},
+	CurrencyCAD: {},
+	CurrencyCDF: {},
+	CurrencyCHE: {},
+	CurrencyCHF: {},
+	CurrencyCHW: {},
+	CurrencyCLF: {},
+	CurrencyCLP: {},
+	CurrencyCNY: {},
+	CurrencyCOP: {},
+	CurrencyCOU: {},
+	CurrencyCRC: {},
+	CurrencyCUC: {},
+	CurrencyCUP: {},
+	CurrencyCVE: {},
+	CurrencyCZK: {},
+	CurrencyDJF: {},
+	CurrencyDKK: {},
+	CurrencyDOP: {},
+	CurrencyDZD: {},
+	CurrencyEGP: {},
+	CurrencyERN: {},
+	CurrencyETB: {},
+	CurrencyEUR: {},
+	CurrencyFJD: {},
+	CurrencyFKP: {},
+	CurrencyGBP: {},
+	CurrencyGEL: {},
+	CurrencyGHS: {},
+	CurrencyGIP: {},
+	CurrencyGMD: {},
+	CurrencyGNF: {},
+	CurrencyGTQ: {},
+	CurrencyGYD: {},
+	CurrencyHKD: {},
+	CurrencyHNL: {},
+	CurrencyHRK: {},
+	CurrencyHTG: {},
+	CurrencyHUF: {},
+	CurrencyIDR: {},
+	CurrencyILS: {},
+	CurrencyINR: {},
+	CurrencyIQD: {},
+	CurrencyIRR: {},
+	CurrencyISK: {},
+	CurrencyJMD: {},
+	CurrencyJOD: {},
+	CurrencyJPY: {},
+	CurrencyKES: {},
+	CurrencyKGS: {},
+	CurrencyKHR: {},
+	CurrencyKMF: {},
+	CurrencyKPW: {},
+	CurrencyKRW: {},
+	CurrencyKWD: {},
+	CurrencyKYD: {},
+	CurrencyKZT: {},
+	CurrencyLAK: {},
+	CurrencyLBP: {},
+	CurrencyLKR: {},
+	CurrencyLRD: {},
+	CurrencyLSL: {},
+	CurrencyLYD: {},
+	CurrencyMAD: {},
+	CurrencyMDL: {},
+	CurrencyMGA: {},
+	CurrencyMKD: {},
+	CurrencyMMK: {},
+	CurrencyMNT: {},
+	CurrencyMOP: {},
+	CurrencyMRO: {},
+	CurrencyMUR: {},
+	CurrencyMVR: {},
+	CurrencyMWK: {},
+	CurrencyMXN: {},
+	CurrencyMXV: {},
+	CurrencyMYR: {},
+	CurrencyMZN: {},
+	CurrencyNAD: {},
+	CurrencyNGN: {},
+	CurrencyNIO: {},
+	CurrencyNOK: {},
+	CurrencyNPR: {},
+	CurrencyNZD: {},
+	CurrencyOMR: {},
+	CurrencyPAB: {},
+	CurrencyPEN: {},
+	CurrencyPGK: {},
+	CurrencyPHP: {},
+	CurrencyPKR: {},
+	CurrencyPLN: {},
+	CurrencyPYG: {},
+	CurrencyQAR: {},
+	CurrencyRON: {},
+	CurrencyRSD: {},
+	CurrencyRUB: {},
+	CurrencyRWF: {},
+	CurrencySAR: {},
+	CurrencySBD: {},
+	CurrencySCR: {},
+	CurrencySDG: {},
+	CurrencySEK: {},
+	CurrencySGD: {},
+	CurrencySHP: {},
+	CurrencySLL: {},
+	CurrencySOS: {},
+	CurrencySRD: {},
+	CurrencySSP: {},
+	CurrencySTD: {},
+	CurrencySVC: {},
+	CurrencySYP: {},
+	CurrencySZL: {},
+	CurrencyTHB: {},
+	CurrencyTJS: {},
+	CurrencyTMT: {},
+	CurrencyTND: {},
+	CurrencyTOP: {},
+	CurrencyTRY: {},
+	CurrencyTTD: {},
+	CurrencyTWD: {},
+	CurrencyTZS: {},
+	CurrencyUAH: {},
+	CurrencyUGX: {},
+	CurrencyUSD: {},
+	CurrencyUSN: {},
+	CurrencyUYI: {},
+	CurrencyUYU: {},
+	CurrencyUZS: {},
+	CurrencyVEF: {},
+	CurrencyVND: {},
+	CurrencyVUV: {},
+	CurrencyWST: {},
+	CurrencyXAF: {},
+	CurrencyXAG: {},
+	CurrencyXAU: {},
+	CurrencyXBA: {},
+	CurrencyXBB: {},
+	CurrencyXBC: {},
+	CurrencyXBD: {},
+	CurrencyXCD: {},
+	CurrencyXDR: {},
+	CurrencyXOF: {},
+	CurrencyXPD: {},
+	CurrencyXPF: {},
+	CurrencyXPT: {},
+	CurrencyXSU: {},
+	CurrencyXTS: {},
+	CurrencyXUA: {},
+	CurrencyXXX: {},
+	CurrencyYER: {},
+	CurrencyZAR: {},
+	CurrencyZMW: {},
+	CurrencyZWL: {},
+}
+
+// IsValid returns true if the code is a valid ISO 4217 currency code.
+func (c CurrencyCodeType) IsValid() bool {
+	_, ok := currencyCodes[c]
+	return ok
+}
+
+var taxCategoryCodes = map[TaxCategoryCodeType]struct{}{
+	TaxCategoryVATStandardRate:               {},
+	TaxCategoryVATZeroRate:                   {},
+	TaxCategoryVATExempt:                     {},
+	TaxCategoryVATReverseCharge:              {},
+	TaxCategoryVATExemptIntraCommunitySupply: {},
+	TaxCategoryVATNotChargedFreeExportItem:   {},
+	TaxCategoryNotSubjectToVAT:               {},
+	TaxCategoryCanaryIslandsIGIC:             {},
+	TaxCategoryCeutaMelillaIPSI:              {},
+}
+
+// IsValid returns true if the code is a valid UNTDID 5305 tax category code.
+func (c TaxCategoryCodeType) IsValid() bool {
+	_, ok := taxCategoryCodes[c]
+	return ok
+}
+
+var countryCodes = map[CountryCodeType]struct{}{
+	CountryCodeRO: {},
+	CountryCodeAD: {},
+	CountryCodeAE: {},
+	CountryCodeAF: {},
+	CountryCodeAG: {},
+	CountryCodeAI: {},
+	CountryCodeAL: {},
+	CountryCodeAM: {},
+	CountryCodeAO: {},
+	CountryCodeAQ: {},
+	CountryCodeAR: {},
+	CountryCodeAS: {},
+	CountryCodeAT: {},
+	CountryCodeAU: {},
+	CountryCodeAW: {},
+	CountryCodeAX: {},
+	CountryCodeAZ: {},
+	CountryCodeBA: {},
+	CountryCodeBB: {},
+	CountryCodeBD: {},
+	CountryCodeBE: {},
+	CountryCodeBF: {},
+	CountryCodeBG: {},
+	CountryCodeBH: {},
+	CountryCodeBI: {},
+	CountryCodeBJ: {},
+	CountryCodeBL: {},
+	CountryCodeBM: {},
+	CountryCodeBN: {},
+	CountryCodeBO: {},
+	CountryCodeBQ: {},
+	CountryCodeBR: {},
+	CountryCodeBS: {},
+	CountryCodeBT: {},
+	CountryCodeBV: {},
+	CountryCodeBW: {},
+	CountryCodeBY: {},
+	CountryCodeBZ: {},
+	CountryCodeCA: {},
+	CountryCodeCC: {},
+	CountryCodeCD: {},
+	CountryCodeCF: {},
+	CountryCodeCG: {},
+	CountryCodeCH: {},
+	CountryCodeCI: {},
+	CountryCodeCK: {},
+	CountryCodeCL: {},
+	CountryCodeCM: {},
+	CountryCodeCN: {},
+	CountryCodeCO: {},
+	CountryCodeCR: {},
+	CountryCodeCU: {},
+	CountryCodeCV: {},
+	CountryCodeCW: {},
+	CountryCodeCX: {},
+	CountryCodeCY: {},
+	CountryCodeCZ: {},
+	CountryCodeDE: {},
+	CountryCodeDJ: {},
+	CountryCodeDK: {},
+	CountryCodeDM: {},
+	CountryCodeDO: {},
+	CountryCodeDZ: {},
+	CountryCodeEC: {},
+	CountryCodeEE: {},
+	CountryCodeEG: {},
+	CountryCodeEH: {},
+	CountryCodeER: {},
+	CountryCodeES: {},
+	CountryCodeET: {},
+	CountryCodeFI: {},
+	CountryCodeFJ: {},
+	CountryCodeFK: {},
+	CountryCodeFM: {},
+	CountryCodeFO: {},
+	CountryCodeFR: {},
+	CountryCodeGA: {},
+	CountryCodeGB: {},
+	CountryCodeGD: {},
+	CountryCodeGE: {},
+	CountryCodeGF: {},
+	CountryCodeGG: {},
+	CountryCodeGH: {},
+	CountryCodeGI: {},
+	CountryCodeGL: {},
+	CountryCodeGM: {},
+	CountryCodeGN: {},
+	CountryCodeGP: {},
+	CountryCodeGQ: {},
+	CountryCodeGR: {},
+	CountryCodeGS: {},
+	CountryCodeGT: {},
+	CountryCodeGU: {},
+	CountryCodeGW: {},
+	CountryCodeGY: {},
+	CountryCodeHK: {},
+	CountryCodeHM: {},
+	CountryCodeHN: {},
+	CountryCodeHR: {},
+	CountryCodeHT: {},
+	CountryCodeHU: {},
+	CountryCodeID: {},
+	CountryCodeIE: {},
+	CountryCodeIL: {},
+	CountryCodeIM: {},
+	CountryCodeIN: {},
+	CountryCodeIO: {},
+	CountryCodeIQ: {},
+	CountryCodeIR: {},
+	CountryCodeIS: {},
+	CountryCodeIT: {},
+	CountryCodeJE: {},
+	CountryCodeJM: {},
+	CountryCodeJO: {},
+	CountryCodeJP: {},
+	CountryCodeKE: {},
+	CountryCodeKG: {},
+	CountryCodeKH: {},
+	CountryCodeKI: {},
+	CountryCodeKM: {},
+	CountryCodeKN: {},
+	CountryCodeKP: {},
+	CountryCodeKR: {},
+	CountryCodeKW: {},
+	CountryCodeKY: {},
+	CountryCodeKZ: {},
+	CountryCodeLA: {},
+	CountryCodeLB: {},
+	CountryCodeLC: {},
+	CountryCodeLI: {},
+	CountryCodeLK: {},
+	CountryCodeLR: {},
+	CountryCodeLS: {},
+	CountryCodeLT: {},
+	CountryCodeLU: {},
+	CountryCodeLV: {},
+	CountryCodeLY: {},
+	CountryCodeMA: {},
+	CountryCodeMC: {},
+	CountryCodeMD: {},
+	CountryCodeME: {},
+	CountryCodeMF: {},
+	CountryCodeMG: {},
+	CountryCodeMH: {},
+	CountryCodeMK: {},
+	CountryCodeML: {},
+	CountryCodeMM: {},
+	CountryCodeMN: {},
+	CountryCodeMO: {},
+	CountryCodeMP: {},
+	CountryCodeMQ: {},
+	CountryCodeMR: {},
+	CountryCodeMS: {},
+	CountryCodeMT: {},
+	CountryCodeMU: {},
+	CountryCodeMV: {},
+	CountryCodeMW: {},
+	CountryCodeMX: {},
+	CountryCodeMY: {},
+	CountryCodeMZ: {},
+	CountryCodeNA: {},
+	CountryCodeNC: {},
+	CountryCodeNE: {},
+	CountryCodeNF: {},
+	CountryCodeNG: {},
+	CountryCodeNI: {},
+	CountryCodeNL: {},
+	CountryCodeNO: {},
+	CountryCodeNP: {},
+	CountryCodeNR: {},
+	CountryCodeNU: {},
+	CountryCodeNZ: {},
+	CountryCodeOM: {},
+	CountryCodePA: {},
+	CountryCodePE: {},
+	CountryCodePF: {},
+	CountryCodePG: {},
+	CountryCodePH: {},
+	CountryCodePK: {},
+	CountryCodePL: {},
+	CountryCodePM: {},
+	CountryCodePN: {},
+	CountryCodePR: {},
+	CountryCodePS: {},
+	CountryCodePT: {},
+	CountryCodePW: {},
+	CountryCodePY: {},
+	CountryCodeQA: {},
+	CountryCodeRE: {},
+	CountryCodeRS: {},
+	CountryCodeRU: {},
+	CountryCodeRW: {},
+	CountryCodeSA: {},
+	CountryCodeSB: {},
+	CountryCodeSC: {},
+	CountryCodeSD: {},
+	CountryCodeSE: {},
+	CountryCodeSG: {},
+	CountryCodeSH: {},
+	CountryCodeSI: {},
+	CountryCodeSJ: {},
+	CountryCodeSK: {},
+	CountryCodeSL: {},
+	CountryCodeSM: {},
+	CountryCodeSN: {},
+	CountryCodeSO: {},
+	CountryCodeSR: {},
+	CountryCodeSS: {},
+	CountryCodeST: {},
+	CountryCodeSV: {},
+	CountryCodeSX: {},
+	CountryCodeSY: {},
+	CountryCodeSZ: {},
+	CountryCodeTC: {},
+	CountryCodeTD: {},
+	CountryCodeTF: {},
+	CountryCodeTG: {},
+	CountryCodeTH: {},
+	CountryCodeTJ: {},
+	CountryCodeTK: {},
+	CountryCodeTL: {},
+	CountryCodeTM: {},
+	CountryCodeTN: {},
+	CountryCodeTO: {},
+	CountryCodeTR: {},
+	CountryCodeTT: {},
+	CountryCodeTV: {},
+	CountryCodeTW: {},
+	CountryCodeTZ: {},
+	CountryCodeUA: {},
+	CountryCodeUG: {},
+	CountryCodeUM: {},
+	CountryCodeUS: {},
+	CountryCodeUY: {},
+	CountryCodeUZ: {},
+	CountryCodeVA: {},
+	CountryCodeVC: {},
+	CountryCodeVE: {},
+	CountryCodeVG: {},
+	CountryCodeVI: {},
+	CountryCodeVN: {},
+	CountryCodeVU: {},
+	CountryCodeWF: {},
+	CountryCodeWS: {},
+	CountryCodeYE: {},
+	CountryCodeYT: {},
+	CountryCodeZA: {},
+	CountryCodeZM: {},
+	CountryCodeZW: {},
+	CountryCode1A: {},
+}
+
+// IsValid returns true if the code is a valid ISO 3166-1 alpha-2 country code.
+func (c CountryCodeType) IsValid() bool {
+	_, ok := countryCodes[c]
+	return ok
+}
+
+var countrySubentityCodes = map[CountrySubentityType]struct{}{
+	CountrySubentityRO_B:  {},
+	CountrySubentityRO_AB: {},
+	CountrySubentityRO_AR: {},
+	CountrySubentityRO_AG: {},
+	CountrySubentityRO_BC: {},
+	CountrySubentityRO_BH: {},
+	CountrySubentityRO_BN: {},
+	CountrySubentityRO_BT: {},
+	CountrySubentityRO_BR: {},
+	CountrySubentityRO_BV: {},
+	CountrySubentityRO_BZ: {},
+	CountrySubentityRO_CL: {},
+	CountrySubentityRO_CS: {},
+	CountrySubentityRO_CJ: {},
+	CountrySubentityRO_CT: {},
+	CountrySubentityRO_CV: {},
+	CountrySubentityRO_DB: {},
+	CountrySubentityRO_DJ: {},
+	CountrySubentityRO_GL: {},
+	CountrySubentityRO_GR: {},
+	CountrySubentityRO_GJ: {},
+	CountrySubentityRO_HR: {},
+	CountrySubentityRO_HD: {},
+	CountrySubentityRO_IL: {},
+	CountrySubentityRO_IS: {},
+	CountrySubentityRO_IF: {},
+	CountrySubentityRO_MM: {},
+	CountrySubentityRO_MH: {},
+	CountrySubentityRO_MS: {},
+	CountrySubentityRO_NT: {},
+	CountrySubentityRO_OT: {},
+	CountrySubentityRO_PH: {},
+	CountrySubentityRO_SJ: {},
+	CountrySubentityRO_SM: {},
+	CountrySubentityRO_SB: {},
+	CountrySubentityRO_SV: {},
+	CountrySubentityRO_TR: {},
+	CountrySubentityRO_TM: {},
+	CountrySubentityRO_TL: {},
+	CountrySubentityRO_VS: {},
+	CountrySubentityRO_VL: {},
+	CountrySubentityRO_VN: {},
+}
+
+// IsValid returns true if the code is a valid ISO 3166-2:RO county code.
+func (c CountrySubentityType) IsValid() bool {
+	_, ok := countrySubentityCodes[c]
+	return ok
+}
+
+var paymentMeansCodes = map[PaymentMeansCodeType]struct{}{
+	PaymentMeansInstrumentNotDefined:                             {},
+	PaymentMeansAutomatedClearingHouseCredit:                     {},
+	PaymentMeansAutomatedClearingHouseDebit:                      {},
+	PaymentMeansACHDemandDebitReversal:                           {},
+	PaymentMeansACHDemandCreditReversal:                          {},
+	PaymentMeansACHDemandCredit:                                  {},
+	PaymentMeansACHDemandDebit:                                   {},
+	PaymentMeansHold:                                             {},
+	PaymentMeansNationalRegionalClearing:                         {},
+	PaymentMeansInCash:                                           {},
+	PaymentMeansACHSavingsCreditReversal:                         {},
+	PaymentMeansACHSavingsDebitReversal:                          {},
+	PaymentMeansACHSavingsCredit:                                 {},
+	PaymentMeansACHSavingsDebit:                                  {},
+	PaymentMeansBookentryCredit:                                  {},
+	PaymentMeansBookentryDebit:                                   {},
+	PaymentMeansACHDemandCashCCDCredit:                           {},
+	PaymentMeansACHDemandCashCCDDebit:                            {},
+	PaymentMeansACHDemandCTPCredit:                               {},
+	PaymentMeansCheque:                                           {},
+	PaymentMeansBankersDraft:                                     {},
+	PaymentMeansCertifiedBankersDraft:                            {},
+	PaymentMeansBankCheque:                                       {},
+	PaymentMeansExchangeAwaitingAcceptanceBill:                   {},
+	PaymentMeansCertifiedCheque:                                  {},
+	PaymentMeansLocalCheque:                                      {},
+	PaymentMeansACHDemandCTPDebit:                                {},
+	PaymentMeansACHDemandCTXCredit:                               {},
+	PaymentMeansACHDemandCTXDebit:                                {},
+	PaymentMeansCreditTransfer:                                   {},
+	PaymentMeansDebitTransfer:                                    {},
+	PaymentMeansACHDemandCCDPlusCredit:                           {},
+	PaymentMeansACHDemandCCDPlusDebit:                            {},
+	PaymentMeansACHPPD:                                           {},
+	PaymentMeansACHSavingsCCDCredit:                              {},
+	PaymentMeansACHSavingsCCDDebit:                               {},
+	PaymentMeansACHSavingsCTPCredit:                              {},
+	PaymentMeansACHSavingsCTPDebit:                               {},
+	PaymentMeansACHSavingsCTXCredit:                              {},
+	PaymentMeansACHSavingsCTXDebit:                               {},
+	PaymentMeansACHSavingsCCDPlus:                                {},
+	PaymentMeansPaymentToBankAccount:                             {},
+	PaymentMeansACHSavingsCashCCDPlus:                            {},
+	PaymentMeansAcceptedExchangeBill:                             {},
+	PaymentMeansReferencedHomeBankingCreditTransfer:              {},
+	PaymentMeansInterbankDebitTransfer:                           {},
+	PaymentMeansHomeBankingDebitTransfer:                         {},
+	PaymentMeansBankCard:                                         {},
+	PaymentMeansDirectDebit:                                      {},
+	PaymentMeansPostgiro:                                         {},
+	PaymentMeansCFONBOptionA:                                     {},
+	PaymentMeansUrgentCommercialPayment:                          {},
+	PaymentMeansUrgentTreasuryPayment:                            {},
+	PaymentMeansCreditCard:                                       {},
+	PaymentMeansDebitCard:                                        {},
+	PaymentMeansBankgiro:                                         {},
+	PaymentMeansStandingAgreement:                                {},
+	PaymentMeansSEPACreditTransfer:                               {},
+	PaymentMeansSEPADirectDebit:                                  {},
+	PaymentMeansPromissoryNote:                                   {},
+	PaymentMeansPromissoryNoteSignedByDebtor:                     {},
+	PaymentMeansPromissoryNoteSignedByDebtorEndorsedByBank:       {},
+	PaymentMeansPromissoryNoteSignedByDebtorEndorsedByThirdParty: {},
+	PaymentMeansPromissoryNoteSignedByBank:                       {},
+	PaymentMeansPromissoryNoteSignedByBankEndorsedByAnotherBank:  {},
+	PaymentMeansPromissoryNoteSignedByThirdParty:                 {},
+	PaymentMeansPromissoryNoteSignedByThirdPartyEndorsedByBank:   {},
+	PaymentMeansOnlinePaymentService:                             {},
+	PaymentMeansBillDrawnByCreditorOnDebtor:                      {},
+	PaymentMeansBillDrawnByCreditorOnBank:                        {},
+	PaymentMeansBillDrawnByCreditorEndorsedByAnotherBank:         {},
+	PaymentMeansBillDrawnByCreditorOnBankEndorsedByThirdParty:    {},
+	PaymentMeansBillDrawnByCreditorOnThirdParty:                  {},
+	PaymentMeansBillDrawnByCreditorOnThirdPartyEndorsedByBank:    {},
+	PaymentMeansNotTransferableBankersDraft:                      {},
+	PaymentMeansNotTransferableLocalCheque:                       {},
+	PaymentMeansReferenceGiro:                                    {},
+	PaymentMeansUrgentGiro:                                       {},
+	PaymentMeansFreeFormatGiro:                                   {},
+	PaymentMeansRequestedPaymentMethodNotUsed:                    {},
+	PaymentMeansClearingBetweenPartners:                          {},
+	PaymentMeansMutuallyDefined:                                  {},
+}
+
+// IsValid returns true if the code is a valid UNTDID 4461 payment means code.
+func (c PaymentMeansCodeType) IsValid() bool {
+	_, ok := paymentMeansCodes[c]
+	return ok
+}
+
+var allowanceReasonCodes = map[AllowanceReasonCodeType]struct{}{
+	AllowanceReasonCodeBonusWorksAheadOfSchedule:      {},
+	AllowanceReasonCodeOtherBonus:                     {},
+	AllowanceReasonCodeManufacturersConsumerDiscount:  {},
+	AllowanceReasonCodeDueToMilitaryStatus:            {},
+	AllowanceReasonCodeDueToWorkAccident:              {},
+	AllowanceReasonCodeSpecialAgreement:               {},
+	AllowanceReasonCodeProductionErrorDiscount:        {},
+	AllowanceReasonCodeNewOutletDiscount:              {},
+	AllowanceReasonCodeSampleDiscount:                 {},
+	AllowanceReasonCodeEndOfRangeDiscount:             {},
+	AllowanceReasonCodeIncotermDiscount:               {},
+	AllowanceReasonCodePointOfSalesThresholdAllowance: {},
+	AllowanceReasonCodeMaterialSurchargeDeduction:     {},
+	AllowanceReasonCodeDiscount:                       {},
+	AllowanceReasonCodeSpecialRebate:                  {},
+	AllowanceReasonCodeFixedLongTerm:                  {},
+	AllowanceReasonCodeTemporary:                      {},
+	AllowanceReasonCodeStandard:                       {},
+	AllowanceReasonCodeYearlyTurnover:                 {},
+}
+
+// IsValid returns true if the code is a valid UNTDID 5189 allowance reason
+// code.
+func (c AllowanceReasonCodeType) IsValid() bool {
+	_, ok := allowanceReasonCodes[c]
+	return ok
+}
+
+var chargeReasonCodes = map[ChargeReasonCodeType]struct{}{
+	ChargeReasonCodeAdvertising:                                       {},
+	ChargeReasonCodeTelecommunication:                                 {},
+	ChargeReasonCodeTechnicalModification:                             {},
+	ChargeReasonCodeJobOrderProduction:                                {},
+	ChargeReasonCodeOutlays:                                           {},
+	ChargeReasonCodeOffPremises:                                       {},
+	ChargeReasonCodeAdditionalProcessing:                              {},
+	ChargeReasonCodeAttesting:                                         {},
+	ChargeReasonCodeAcceptance:                                        {},
+	ChargeReasonCodeRushDelivery:                                      {},
+	ChargeReasonCodeSpecialConstruction:                               {},
+	ChargeReasonCodeAirportFacilities:                                 {},
+	ChargeReasonCodeConcession:                                        {},
+	ChargeReasonCodeCompulsoryStorage:                                 {},
+	ChargeReasonCodeFuelRemoval:                                       {},
+	ChargeReasonCodeIntoPlane:                                         {},
+	ChargeReasonCodeOvertime:                                          {},
+	ChargeReasonCodeTooling:                                           {},
+	ChargeReasonCodeMiscellaneous:                                     {},
+	ChargeReasonCodeAdditionalPackaging:                               {},
+	ChargeReasonCodeDunnage:                                           {},
+	ChargeReasonCodeContainerisation:                                  {},
+	ChargeReasonCodeCartonPacking:                                     {},
+	ChargeReasonCodeHessianWrapped:                                    {},
+	ChargeReasonCodePolyethyleneWrapPacking:                           {},
+	ChargeReasonCodeMiscellaneousTreatment:                            {},
+	ChargeReasonCodeEnamellingTreatment:                               {},
+	ChargeReasonCodeHeatTreatment:                                     {},
+	ChargeReasonCodePlatingTreatment:                                  {},
+	ChargeReasonCodePainting:                                          {},
+	ChargeReasonCodePolishing:                                         {},
+	ChargeReasonCodePriming:                                           {},
+	ChargeReasonCodePreservationTreatment:                             {},
+	ChargeReasonCodeFitting:                                           {},
+	ChargeReasonCodeConsolidation:                                     {},
+	ChargeReasonCodeBillOfLading:                                      {},
+	ChargeReasonCodeAirbag:                                            {},
+	ChargeReasonCodeTransfer:                                          {},
+	ChargeReasonCodeSlipsheet:                                         {},
+	ChargeReasonCodeBinding:                                           {},
+	ChargeReasonCodeRepairOrReplacementOfBrokenReturnablePackage:      {},
+	ChargeReasonCodeEfficientLogistics:                                {},
+	ChargeReasonCodeMerchandising:                                     {},
+	ChargeReasonCodeProductMix:                                        {},
+	ChargeReasonCodeOtherServices:                                     {},
+	ChargeReasonCodePickUp:                                            {},
+	ChargeReasonCodeChronicIllness:                                    {},
+	ChargeReasonCodeNewProductIntroduction:                            {},
+	ChargeReasonCodeDirectDelivery:                                    {},
+	ChargeReasonCodeDiversion:                                         {},
+	ChargeReasonCodeDisconnect:                                        {},
+	ChargeReasonCodeDistribution:                                      {},
+	ChargeReasonCodeHandlingOfHazardousCargo:                          {},
+	ChargeReasonCodeRentsAndLeases:                                    {},
+	ChargeReasonCodeLocationDifferential:                              {},
+	ChargeReasonCodeAircraftRefueling:                                 {},
+	ChargeReasonCodeFuelShippedIntoStorage:                            {},
+	ChargeReasonCodeCashOnDelivery:                                    {},
+	ChargeReasonCodeSmallOrderProcessingService:                       {},
+	ChargeReasonCodeClericalOrAdministrativeServices:                  {},
+	ChargeReasonCodeGuarantee:                                         {},
+	ChargeReasonCodeCollectionAndRecycling:                            {},
+	ChargeReasonCodeCopyrightFeeCollection:                            {},
+	ChargeReasonCodeVeterinaryInspectionService:                       {},
+	ChargeReasonCodePensionerService:                                  {},
+	ChargeReasonCodeMedicineFreePassHolder:                            {},
+	ChargeReasonCodeEnvironmentalProtectionService:                    {},
+	ChargeReasonCodeEnvironmentalCleanUpService:                       {},
+	ChargeReasonCodeNationalChequeProcessingServiceOutsideAccountArea: {},
+	ChargeReasonCodeNationalPaymentServiceOutsideAccountArea:          {},
+	ChargeReasonCodeNationalPaymentServiceWithinAccountArea:           {},
+	ChargeReasonCodeAdjustments:                                       {},
+	ChargeReasonCodeAuthentication:                                    {},
+	ChargeReasonCodeCatalogue:                                         {},
+	ChargeReasonCodeCartage:                                           {},
+	ChargeReasonCodeCertification:                                     {},
+	ChargeReasonCodeCertificateOfConformance:                          {},
+	ChargeReasonCodeCertificateOfOrigin:                               {},
+	ChargeReasonCodeCutting:                                           {},
+	ChargeReasonCodeConsularService:                                   {},
+	ChargeReasonCodeCustomerCollection:                                {},
+	ChargeReasonCodePayrollPaymentService:                             {},
+	ChargeReasonCodeCashTransportation:                                {},
+	ChargeReasonCodeHomeBankingService:                                {},
+	ChargeReasonCodeBilateralAgreementService:                         {},
+	ChargeReasonCodeInsuranceBrokerageService:                         {},
+	ChargeReasonCodeChequeGeneration:                                  {},
+	ChargeReasonCodePreferentialMerchandisingLocation:                 {},
+	ChargeReasonCodeCrane:                                             {},
+	ChargeReasonCodeSpecialColourService:                              {},
+	ChargeReasonCodeSorting:                                           {},
+	ChargeReasonCodeBatteryCollectionAndRecycling:                     {},
+	ChargeReasonCodeProductTakeBackFee:                                {},
+	ChargeReasonCodeQualityControlReleased:                            {},
+	ChargeReasonCodeQualityControlHeld:                                {},
+	ChargeReasonCodeQualityControlEmbargo:                             {},
+	ChargeReasonCodeCarLoading:                                        {},
+	ChargeReasonCodeCleaning:                                          {},
+	ChargeReasonCodeCigaretteStamping:                                 {},
+	ChargeReasonCodeCountAndRecount:                                   {},
+	ChargeReasonCodeLayoutDesign:                                      {},
+	ChargeReasonCodeAssortmentAllowance:                               {},
+	ChargeReasonCodeDriverAssignedUnloading:                           {},
+	ChargeReasonCodeDebtorBound:                                       {},
+	ChargeReasonCodeDealerAllowance:                                   {},
+	ChargeReasonCodeAllowanceTransferableToTheConsumer:                {},
+	ChargeReasonCodeGrowthOfBusiness:                                  {},
+	ChargeReasonCodeIntroductionAllowance:                             {},
+	ChargeReasonCodeMultiBuyPromotion:                                 {},
+	ChargeReasonCodePartnership:                                       {},
+	ChargeReasonCodeReturnHandling:                                    {},
+	ChargeReasonCodeMinimumOrderNotFulfilledCharge:                    {},
+	ChargeReasonCodePointOfSalesThresholdAllowance:                    {},
+	ChargeReasonCodeWholesalingDiscount:                               {},
+	ChargeReasonCodeDocumentaryCreditsTransferCommission:              {},
+	ChargeReasonCodeDelivery:                                          {},
+	ChargeReasonCodeEngraving:                                         {},
+	ChargeReasonCodeExpediting:                                        {},
+	ChargeReasonCodeExchangeRateGuarantee:                             {},
+	ChargeReasonCodeFabrication:                                       {},
+	ChargeReasonCodeFreightEqualization:                               {},
+	ChargeReasonCodeFreightExtraordinaryHandling:                      {},
+	ChargeReasonCodeFreightService:                                    {},
+	ChargeReasonCodeFillingHandling:                                   {},
+	ChargeReasonCodeFinancing:                                         {},
+	ChargeReasonCodeGrinding:                                          {},
+	ChargeReasonCodeHose:                                              {},
+	ChargeReasonCodeHandling:                                          {},
+	ChargeReasonCodeHoistingAndHauling:                                {},
+	ChargeReasonCodeInstallation:                                      {},
+	ChargeReasonCodeInstallationAndWarranty:                           {},
+	ChargeReasonCodeInsideDelivery:                                    {},
+	ChargeReasonCodeInspection:                                        {},
+	ChargeReasonCodeInstallationAndTraining:                           {},
+	ChargeReasonCodeInvoicing:                                         {},
+	ChargeReasonCodeKoshering:                                         {},
+	ChargeReasonCodeCarrierCount:                                      {},
+	ChargeReasonCodeLabelling:                                         {},
+	ChargeReasonCodeLabour:                                            {},
+	ChargeReasonCodeRepairAndReturn:                                   {},
+	ChargeReasonCodeLegalisation:                                      {},
+	ChargeReasonCodeMounting:                                          {},
+	ChargeReasonCodeMailInvoice:                                       {},
+	ChargeReasonCodeMailInvoiceToEachLocation:                         {},
+	ChargeReasonCodeNonReturnableContainers:                           {},
+	ChargeReasonCodeOutsideCableConnectors:                            {},
+	ChargeReasonCodeInvoiceWithShipment:                               {},
+	ChargeReasonCodePhosphatizingSteelTreatment:                       {},
+	ChargeReasonCodePacking:                                           {},
+	ChargeReasonCodePalletizing:                                       {},
+	ChargeReasonCodeRepacking:                                         {},
+	ChargeReasonCodeRepair:                                            {},
+	ChargeReasonCodeReturnableContainer:                               {},
+	ChargeReasonCodeRestocking:                                        {},
+	ChargeReasonCodeReDelivery:                                        {},
+	ChargeReasonCodeRefurbishing:                                      {},
+	ChargeReasonCodeRailWagonHire:                                     {},
+	ChargeReasonCodeLoading:                                           {},
+	ChargeReasonCodeSalvaging:                                         {},
+	ChargeReasonCodeShippingAndHandling:                               {},
+	ChargeReasonCodeSpecialPackaging:                                  {},
+	ChargeReasonCodeStamping:                                          {},
+	ChargeReasonCodeConsigneeUnload:                                   {},
+	ChargeReasonCodeShrinkWrap:                                        {},
+	ChargeReasonCodeSpecialHandling:                                   {},
+	ChargeReasonCodeSpecialFinish:                                     {},
+	ChargeReasonCodeSetUp:                                             {},
+	ChargeReasonCodeTankRenting:                                       {},
+	ChargeReasonCodeTesting:                                           {},
+	ChargeReasonCodeTransportationThirdPartyBilling:                   {},
+	ChargeReasonCodeTransportationByVendor:                            {},
+	ChargeReasonCodeDropYard:                                          {},
+	ChargeReasonCodeDropDock:                                          {},
+	ChargeReasonCodeWarehousing:                                       {},
+	ChargeReasonCodeCombineAllSameDayShipment:                         {},
+	ChargeReasonCodeSplitPickUp:                                       {},
+	ChargeReasonCodeMutuallyDefined:                                   {},
+}
+
+// IsValid returns true if the code is a valid UNTDID 7161 charge reason
+// code.
+func (c ChargeReasonCodeType) IsValid() bool {
+	_, ok := chargeReasonCodes[c]
+	return ok
+}
@@ -15,6 +15,8 @@
 package efactura
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/printesoi/e-factura-go/pkg/text"
@@ -305,6 +307,31 @@ func (c TaxCategoryCodeType) ExemptionReasonRequired() bool {
 	return false
 }
 
+// VATPointDateCodeType is the code of the date when the VAT becomes
+// accountable (BT-8), a subset of UNTDID 2005.
+type VATPointDateCodeType string
+
+const (
+	// Invoice document issue date time (RO: Data emiterii facturii)
+	VATPointDateCodeIssueDate VATPointDateCodeType = "3"
+	// Actual delivery date/time (RO: Data reală a livrării)
+	VATPointDateCodeDeliveryDate VATPointDateCodeType = "35"
+	// Paid to date (RO: Data plății)
+	VATPointDateCodePaidToDate VATPointDateCodeType = "432"
+)
+
+// IsValid returns true if the receiver is one of the VAT point date codes
+// allowed by EN 16931 (3, 35 or 432).
+func (c VATPointDateCodeType) IsValid() bool {
+	switch c {
+	case VATPointDateCodeIssueDate,
+		VATPointDateCodeDeliveryDate,
+		VATPointDateCodePaidToDate:
+		return true
+	}
+	return false
+}
+
 type TaxExemptionReasonCodeType string
 
 const (
@@ -408,7 +435,7 @@ const (
 	TaxExemptionCodeVATEX_EU_309 = "VATEX-EU-309"
 	// VATEX-EU-AE - Taxare inversa
 	TaxExemptionCodeVATEX_EU_AE = "VATEX-EU-AE"
-	// VATEX-EU-D - Intra-Regim special pentru agentiile de turism
+	// VATEX-EU-D - Regim special pentru agentiile de turism
 	TaxExemptionCodeVATEX_EU_D = "VATEX-EU-D"
 	// VATEX-EU-F - Regim special pentru bunuri second hand
 	TaxExemptionCodeVATEX_EU_F = "VATEX-EU-F"
@@ -1211,5 +1238,497 @@ const (
 	PaymentMeansMutuallyDefined                                  PaymentMeansCodeType = "ZZZ"
 )
 
-// TODO: add values
+// InvoiceNoteSubjectCodeType is the subject code of an invoice note (BT-21),
+// from the UNTDID 4451 code list. In UBL the code is serialized as a prefix
+// of the note text, eg. "#AAI#General information".
+// https://unece.org/fileadmin/DAM/trade/untdid/d16b/tred/tred4451.htm
 type InvoiceNoteSubjectCodeType string
+
+// The UNTDID 4451 subject codes used in invoices. Other codes with a valid
+// format (see InvoiceNoteSubjectCodeType.IsValid) can be used as well.
+const (
+	// Goods item description
+	InvoiceNoteSubjectGoodsItemDescription InvoiceNoteSubjectCodeType = "AAA"
+	// Payment term
+	InvoiceNoteSubjectPaymentTerm InvoiceNoteSubjectCodeType = "AAB"
+	// Dangerous goods additional information
+	InvoiceNoteSubjectDangerousGoodsAdditionalInformation InvoiceNoteSubjectCodeType = "AAC"
+	// Dangerous goods technical name
+	InvoiceNoteSubjectDangerousGoodsTechnicalName InvoiceNoteSubjectCodeType = "AAD"
+	// Rate additional information
+	InvoiceNoteSubjectRateAdditionalInformation InvoiceNoteSubjectCodeType = "AAF"
+	// General information
+	InvoiceNoteSubjectGeneralInformation InvoiceNoteSubjectCodeType = "AAI"
+	// Additional conditions of sale/purchase
+	InvoiceNoteSubjectAdditionalConditionsOfSale InvoiceNoteSubjectCodeType = "AAJ"
+	// Price conditions
+	InvoiceNoteSubjectPriceConditions InvoiceNoteSubjectCodeType = "AAK"
+	// Terms of delivery
+	InvoiceNoteSubjectTermsOfDelivery InvoiceNoteSubjectCodeType = "AAR"
+	// Government information
+	InvoiceNoteSubjectGovernmentInformation InvoiceNoteSubjectCodeType = "ABL"
+	// Accounting information
+	InvoiceNoteSubjectAccountingInformation InvoiceNoteSubjectCodeType = "ABN"
+	// Additional information
+	InvoiceNoteSubjectAdditionalInformation InvoiceNoteSubjectCodeType = "ACB"
+	// Reason
+	InvoiceNoteSubjectReason InvoiceNoteSubjectCodeType = "ACD"
+	// Dispute
+	InvoiceNoteSubjectDispute InvoiceNoteSubjectCodeType = "ACE"
+	// Note
+	InvoiceNoteSubjectNote InvoiceNoteSubjectCodeType = "ADU"
+	// Customs declaration information
+	InvoiceNoteSubjectCustomsDeclarationInformation InvoiceNoteSubjectCodeType = "CUS"
+	// Delivery information
+	InvoiceNoteSubjectDeliveryInformation InvoiceNoteSubjectCodeType = "DEL"
+	// Invoice instruction
+	InvoiceNoteSubjectInvoiceInstruction InvoiceNoteSubjectCodeType = "INV"
+	// Order instruction
+	InvoiceNoteSubjectOrderInstruction InvoiceNoteSubjectCodeType = "ORI"
+	// Packing/marking information
+	InvoiceNoteSubjectPackingInformation InvoiceNoteSubjectCodeType = "PAC"
+	// Payment detail/remittance information
+	InvoiceNoteSubjectPaymentDetail InvoiceNoteSubjectCodeType = "PMD"
+	// Payment information
+	InvoiceNoteSubjectPaymentInformation InvoiceNoteSubjectCodeType = "PMT"
+	// Regulatory information
+	InvoiceNoteSubjectRegulatoryInformation InvoiceNoteSubjectCodeType = "REG"
+	// Supplier remarks
+	InvoiceNoteSubjectSupplierRemarks InvoiceNoteSubjectCodeType = "SUR"
+	// Tax declaration
+	InvoiceNoteSubjectTaxDeclaration InvoiceNoteSubjectCodeType = "TXD"
+)
+
+// regexNoteSubjectCode matches the format of the UNTDID 4451 codes.
+var regexNoteSubjectCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// IsValid returns true if the code has the format of an UNTDID 4451 subject
+// code (three uppercase letters).
+func (c InvoiceNoteSubjectCodeType) IsValid() bool {
+	return regexNoteSubjectCode.MatchString(string(c))
+}
+
+// AllowanceReasonCodeType is the reason code of an allowance (BT-98 at
+// document level, BT-140 at line level), from the UNTDID 5189 code list.
+// https://unece.org/fileadmin/DAM/trade/untdid/d16b/tred/tred5189.htm
+type AllowanceReasonCodeType string
+
+const (
+	// Bonus for works ahead of schedule
+	AllowanceReasonCodeBonusWorksAheadOfSchedule AllowanceReasonCodeType = "41"
+	// Other bonus
+	AllowanceReasonCodeOtherBonus AllowanceReasonCodeType = "42"
+	// Manufacturer's consumer discount
+	AllowanceReasonCodeManufacturersConsumerDiscount AllowanceReasonCodeType = "60"
+	// Due to military status
+	AllowanceReasonCodeDueToMilitaryStatus AllowanceReasonCodeType = "62"
+	// Due to work accident
+	AllowanceReasonCodeDueToWorkAccident AllowanceReasonCodeType = "63"
+	// Special agreement
+	AllowanceReasonCodeSpecialAgreement AllowanceReasonCodeType = "64"
+	// Production error discount
+	AllowanceReasonCodeProductionErrorDiscount AllowanceReasonCodeType = "65"
+	// New outlet discount
+	AllowanceReasonCodeNewOutletDiscount AllowanceReasonCodeType = "66"
+	// Sample discount
+	AllowanceReasonCodeSampleDiscount AllowanceReasonCodeType = "67"
+	// End-of-range discount
+	AllowanceReasonCodeEndOfRangeDiscount AllowanceReasonCodeType = "68"
+	// Incoterm discount
+	AllowanceReasonCodeIncotermDiscount AllowanceReasonCodeType = "70"
+	// Point of sales threshold allowance
+	AllowanceReasonCodePointOfSalesThresholdAllowance AllowanceReasonCodeType = "71"
+	// Material surcharge/deduction
+	AllowanceReasonCodeMaterialSurchargeDeduction AllowanceReasonCodeType = "88"
+	// Discount
+	AllowanceReasonCodeDiscount AllowanceReasonCodeType = "95"
+	// Special rebate
+	AllowanceReasonCodeSpecialRebate AllowanceReasonCodeType = "100"
+	// Fixed long term
+	AllowanceReasonCodeFixedLongTerm AllowanceReasonCodeType = "102"
+	// Temporary
+	AllowanceReasonCodeTemporary AllowanceReasonCodeType = "103"
+	// Standard
+	AllowanceReasonCodeStandard AllowanceReasonCodeType = "104"
+	// Yearly turnover
+	AllowanceReasonCodeYearlyTurnover AllowanceReasonCodeType = "105"
+)
+
+// ChargeReasonCodeType is the reason code of a charge (BT-105 at document
+// level, BT-145 at line level), from the UNTDID 7161 code list.
+// https://unece.org/fileadmin/DAM/trade/untdid/d16b/tred/tred7161.htm
+type ChargeReasonCodeType string
+
+const (
+	// Advertising
+	ChargeReasonCodeAdvertising ChargeReasonCodeType = "AA"
+	// Telecommunication
+	ChargeReasonCodeTelecommunication ChargeReasonCodeType = "AAA"
+	// Technical modification
+	ChargeReasonCodeTechnicalModification ChargeReasonCodeType = "AAC"
+	// Job-order production
+	ChargeReasonCodeJobOrderProduction ChargeReasonCodeType = "AAD"
+	// Outlays
+	ChargeReasonCodeOutlays ChargeReasonCodeType = "AAE"
+	// Off-premises
+	ChargeReasonCodeOffPremises ChargeReasonCodeType = "AAF"
+	// Additional processing
+	ChargeReasonCodeAdditionalProcessing ChargeReasonCodeType = "AAH"
+	// Attesting
+	ChargeReasonCodeAttesting ChargeReasonCodeType = "AAI"
+	// Acceptance
+	ChargeReasonCodeAcceptance ChargeReasonCodeType = "AAS"
+	// Rush delivery
+	ChargeReasonCodeRushDelivery ChargeReasonCodeType = "AAT"
+	// Special construction
+	ChargeReasonCodeSpecialConstruction ChargeReasonCodeType = "AAV"
+	// Airport facilities
+	ChargeReasonCodeAirportFacilities ChargeReasonCodeType = "AAY"
+	// Concession
+	ChargeReasonCodeConcession ChargeReasonCodeType = "AAZ"
+	// Compulsory storage
+	ChargeReasonCodeCompulsoryStorage ChargeReasonCodeType = "ABA"
+	// Fuel removal
+	ChargeReasonCodeFuelRemoval ChargeReasonCodeType = "ABB"
+	// Into plane
+	ChargeReasonCodeIntoPlane ChargeReasonCodeType = "ABC"
+	// Overtime
+	ChargeReasonCodeOvertime ChargeReasonCodeType = "ABD"
+	// Tooling
+	ChargeReasonCodeTooling ChargeReasonCodeType = "ABF"
+	// Miscellaneous
+	ChargeReasonCodeMiscellaneous ChargeReasonCodeType = "ABK"
+	// Additional packaging
+	ChargeReasonCodeAdditionalPackaging ChargeReasonCodeType = "ABL"
+	// Dunnage
+	ChargeReasonCodeDunnage ChargeReasonCodeType = "ABN"
+	// Containerisation
+	ChargeReasonCodeContainerisation ChargeReasonCodeType = "ABR"
+	// Carton packing
+	ChargeReasonCodeCartonPacking ChargeReasonCodeType = "ABS"
+	// Hessian wrapped
+	ChargeReasonCodeHessianWrapped ChargeReasonCodeType = "ABT"
+	// Polyethylene wrap packing
+	ChargeReasonCodePolyethyleneWrapPacking ChargeReasonCodeType = "ABU"
+	// Miscellaneous treatment
+	ChargeReasonCodeMiscellaneousTreatment ChargeReasonCodeType = "ACF"
+	// Enamelling treatment
+	ChargeReasonCodeEnamellingTreatment ChargeReasonCodeType = "ACG"
+	// Heat treatment
+	ChargeReasonCodeHeatTreatment ChargeReasonCodeType = "ACH"
+	// Plating treatment
+	ChargeReasonCodePlatingTreatment ChargeReasonCodeType = "ACI"
+	// Painting
+	ChargeReasonCodePainting ChargeReasonCodeType = "ACJ"
+	// Polishing
+	ChargeReasonCodePolishing ChargeReasonCodeType = "ACK"
+	// Priming
+	ChargeReasonCodePriming ChargeReasonCodeType = "ACL"
+	// Preservation treatment
+	ChargeReasonCodePreservationTreatment ChargeReasonCodeType = "ACM"
+	// Fitting
+	ChargeReasonCodeFitting ChargeReasonCodeType = "ACS"
+	// Consolidation
+	ChargeReasonCodeConsolidation ChargeReasonCodeType = "ADC"
+	// Bill of lading
+	ChargeReasonCodeBillOfLading ChargeReasonCodeType = "ADE"
+	// Airbag
+	ChargeReasonCodeAirbag ChargeReasonCodeType = "ADJ"
+	// Transfer
+	ChargeReasonCodeTransfer ChargeReasonCodeType = "ADK"
+	// Slipsheet
+	ChargeReasonCodeSlipsheet ChargeReasonCodeType = "ADL"
+	// Binding
+	ChargeReasonCodeBinding ChargeReasonCodeType = "ADM"
+	// Repair or replacement of broken returnable package
+	ChargeReasonCodeRepairOrReplacementOfBrokenReturnablePackage ChargeReasonCodeType = "ADN"
+	// Efficient logistics
+	ChargeReasonCodeEfficientLogistics ChargeReasonCodeType = "ADO"
+	// Merchandising
+	ChargeReasonCodeMerchandising ChargeReasonCodeType = "ADP"
+	// Product mix
+	ChargeReasonCodeProductMix ChargeReasonCodeType = "ADQ"
+	// Other services
+	ChargeReasonCodeOtherServices ChargeReasonCodeType = "ADR"
+	// Pick-up
+	ChargeReasonCodePickUp ChargeReasonCodeType = "ADT"
+	// Chronic illness
+	ChargeReasonCodeChronicIllness ChargeReasonCodeType = "ADW"
+	// New product introduction
+	ChargeReasonCodeNewProductIntroduction ChargeReasonCodeType = "ADY"
+	// Direct delivery
+	ChargeReasonCodeDirectDelivery ChargeReasonCodeType = "ADZ"
+	// Diversion
+	ChargeReasonCodeDiversion ChargeReasonCodeType = "AEA"
+	// Disconnect
+	ChargeReasonCodeDisconnect ChargeReasonCodeType = "AEB"
+	// Distribution
+	ChargeReasonCodeDistribution ChargeReasonCodeType = "AEC"
+	// Handling of hazardous cargo
+	ChargeReasonCodeHandlingOfHazardousCargo ChargeReasonCodeType = "AED"
+	// Rents and leases
+	ChargeReasonCodeRentsAndLeases ChargeReasonCodeType = "AEF"
+	// Location differential
+	ChargeReasonCodeLocationDifferential ChargeReasonCodeType = "AEH"
+	// Aircraft refueling
+	ChargeReasonCodeAircraftRefueling ChargeReasonCodeType = "AEI"
+	// Fuel shipped into storage
+	ChargeReasonCodeFuelShippedIntoStorage ChargeReasonCodeType = "AEJ"
+	// Cash on delivery
+	ChargeReasonCodeCashOnDelivery ChargeReasonCodeType = "AEK"
+	// Small order processing service
+	ChargeReasonCodeSmallOrderProcessingService ChargeReasonCodeType = "AEL"
+	// Clerical or administrative services
+	ChargeReasonCodeClericalOrAdministrativeServices ChargeReasonCodeType = "AEM"
+	// Guarantee
+	ChargeReasonCodeGuarantee ChargeReasonCodeType = "AEN"
+	// Collection and recycling
+	ChargeReasonCodeCollectionAndRecycling ChargeReasonCodeType = "AEO"
+	// Copyright fee collection
+	ChargeReasonCodeCopyrightFeeCollection ChargeReasonCodeType = "AEP"
+	// Veterinary inspection service
+	ChargeReasonCodeVeterinaryInspectionService ChargeReasonCodeType = "AES"
+	// Pensioner service
+	ChargeReasonCodePensionerService ChargeReasonCodeType = "AET"
+	// Medicine free pass holder
+	ChargeReasonCodeMedicineFreePassHolder ChargeReasonCodeType = "AEU"
+	// Environmental protection service
+	ChargeReasonCodeEnvironmentalProtectionService ChargeReasonCodeType = "AEV"
+	// Environmental clean-up service
+	ChargeReasonCodeEnvironmentalCleanUpService ChargeReasonCodeType = "AEW"
+	// National cheque processing service outside account area
+	ChargeReasonCodeNationalChequeProcessingServiceOutsideAccountArea ChargeReasonCodeType = "AEX"
+	// National payment service outside account area
+	ChargeReasonCodeNationalPaymentServiceOutsideAccountArea ChargeReasonCodeType = "AEY"
+	// National payment service within account area
+	ChargeReasonCodeNationalPaymentServiceWithinAccountArea ChargeReasonCodeType = "AEZ"
+	// Adjustments
+	ChargeReasonCodeAdjustments ChargeReasonCodeType = "AJ"
+	// Authentication
+	ChargeReasonCodeAuthentication ChargeReasonCodeType = "AU"
+	// Catalogue
+	ChargeReasonCodeCatalogue ChargeReasonCodeType = "CA"
+	// Cartage
+	ChargeReasonCodeCartage ChargeReasonCodeType = "CAB"
+	// Certification
+	ChargeReasonCodeCertification ChargeReasonCodeType = "CAD"
+	// Certificate of conformance
+	ChargeReasonCodeCertificateOfConformance ChargeReasonCodeType = "CAE"
+	// Certificate of origin
+	ChargeReasonCodeCertificateOfOrigin ChargeReasonCodeType = "CAF"
+	// Cutting
+	ChargeReasonCodeCutting ChargeReasonCodeType = "CAI"
+	// Consular service
+	ChargeReasonCodeConsularService ChargeReasonCodeType = "CAJ"
+	// Customer collection
+	ChargeReasonCodeCustomerCollection ChargeReasonCodeType = "CAK"
+	// Payroll payment service
+	ChargeReasonCodePayrollPaymentService ChargeReasonCodeType = "CAL"
+	// Cash transportation
+	ChargeReasonCodeCashTransportation ChargeReasonCodeType = "CAM"
+	// Home banking service
+	ChargeReasonCodeHomeBankingService ChargeReasonCodeType = "CAN"
+	// Bilateral agreement service
+	ChargeReasonCodeBilateralAgreementService ChargeReasonCodeType = "CAO"
+	// Insurance brokerage service
+	ChargeReasonCodeInsuranceBrokerageService ChargeReasonCodeType = "CAP"
+	// Cheque generation
+	ChargeReasonCodeChequeGeneration ChargeReasonCodeType = "CAQ"
+	// Preferential merchandising location
+	ChargeReasonCodePreferentialMerchandisingLocation ChargeReasonCodeType = "CAR"
+	// Crane
+	ChargeReasonCodeCrane ChargeReasonCodeType = "CAS"
+	// Special colour service
+	ChargeReasonCodeSpecialColourService ChargeReasonCodeType = "CAT"
+	// Sorting
+	ChargeReasonCodeSorting ChargeReasonCodeType = "CAU"
+	// Battery collection and recycling
+	ChargeReasonCodeBatteryCollectionAndRecycling ChargeReasonCodeType = "CAV"
+	// Product take back fee
+	ChargeReasonCodeProductTakeBackFee ChargeReasonCodeType = "CAW"
+	// Quality control released
+	ChargeReasonCodeQualityControlReleased ChargeReasonCodeType = "CAX"
+	// Quality control held
+	ChargeReasonCodeQualityControlHeld ChargeReasonCodeType = "CAY"
+	// Quality control embargo
+	ChargeReasonCodeQualityControlEmbargo ChargeReasonCodeType = "CAZ"
+	// Car loading
+	ChargeReasonCodeCarLoading ChargeReasonCodeType = "CD"
+	// Cleaning
+	ChargeReasonCodeCleaning ChargeReasonCodeType = "CG"
+	// Cigarette stamping
+	ChargeReasonCodeCigaretteStamping ChargeReasonCodeType = "CS"
+	// Count and recount
+	ChargeReasonCodeCountAndRecount ChargeReasonCodeType = "CT"
+	// Layout/design
+	ChargeReasonCodeLayoutDesign ChargeReasonCodeType = "DAB"
+	// Assortment allowance
+	ChargeReasonCodeAssortmentAllowance ChargeReasonCodeType = "DAC"
+	// Driver assigned unloading
+	ChargeReasonCodeDriverAssignedUnloading ChargeReasonCodeType = "DAD"
+	// Debtor bound
+	ChargeReasonCodeDebtorBound ChargeReasonCodeType = "DAF"
+	// Dealer allowance
+	ChargeReasonCodeDealerAllowance ChargeReasonCodeType = "DAG"
+	// Allowance transferable to the consumer
+	ChargeReasonCodeAllowanceTransferableToTheConsumer ChargeReasonCodeType = "DAH"
+	// Growth of business
+	ChargeReasonCodeGrowthOfBusiness ChargeReasonCodeType = "DAI"
+	// Introduction allowance
+	ChargeReasonCodeIntroductionAllowance ChargeReasonCodeType = "DAJ"
+	// Multi-buy promotion
+	ChargeReasonCodeMultiBuyPromotion ChargeReasonCodeType = "DAK"
+	// Partnership
+	ChargeReasonCodePartnership ChargeReasonCodeType = "DAL"
+	// Return handling
+	ChargeReasonCodeReturnHandling ChargeReasonCodeType = "DAM"
+	// Minimum order not fulfilled charge
+	ChargeReasonCodeMinimumOrderNotFulfilledCharge ChargeReasonCodeType = "DAN"
+	// Point of sales threshold allowance
+	ChargeReasonCodePointOfSalesThresholdAllowance ChargeReasonCodeType = "DAO"
+	// Wholesaling discount
+	ChargeReasonCodeWholesalingDiscount ChargeReasonCodeType = "DAP"
+	// Documentary credits transfer commission
+	ChargeReasonCodeDocumentaryCreditsTransferCommission ChargeReasonCodeType = "DAQ"
+	// Delivery
+	ChargeReasonCodeDelivery ChargeReasonCodeType = "DL"
+	// Engraving
+	ChargeReasonCodeEngraving ChargeReasonCodeType = "EG"
+	// Expediting
+	ChargeReasonCodeExpediting ChargeReasonCodeType = "EP"
+	// Exchange rate guarantee
+	ChargeReasonCodeExchangeRateGuarantee ChargeReasonCodeType = "ER"
+	// Fabrication
+	ChargeReasonCodeFabrication ChargeReasonCodeType = "FAA"
+	// Freight equalization
+	ChargeReasonCodeFreightEqualization ChargeReasonCodeType = "FAB"
+	// Freight extraordinary handling
+	ChargeReasonCodeFreightExtraordinaryHandling ChargeReasonCodeType = "FAC"
+	// Freight service
+	ChargeReasonCodeFreightService ChargeReasonCodeType = "FC"
+	// Filling/handling
+	ChargeReasonCodeFillingHandling ChargeReasonCodeType = "FH"
+	// Financing
+	ChargeReasonCodeFinancing ChargeReasonCodeType = "FI"
+	// Grinding
+	ChargeReasonCodeGrinding ChargeReasonCodeType = "GAA"
+	// Hose
+	ChargeReasonCodeHose ChargeReasonCodeType = "HAA"
+	// Handling
+	ChargeReasonCodeHandling ChargeReasonCodeType = "HD"
+	// Hoisting and hauling
+	ChargeReasonCodeHoistingAndHauling ChargeReasonCodeType = "HH"
+	// Installation
+	ChargeReasonCodeInstallation ChargeReasonCodeType = "IAA"
+	// Installation and warranty
+	ChargeReasonCodeInstallationAndWarranty ChargeReasonCodeType = "IAB"
+	// Inside delivery
+	ChargeReasonCodeInsideDelivery ChargeReasonCodeType = "ID"
+	// Inspection
+	ChargeReasonCodeInspection ChargeReasonCodeType = "IF"
+	// Installation and training
+	ChargeReasonCodeInstallationAndTraining ChargeReasonCodeType = "IR"
+	// Invoicing
+	ChargeReasonCodeInvoicing ChargeReasonCodeType = "IS"
+	// Koshering
+	ChargeReasonCodeKoshering ChargeReasonCodeType = "KO"
+	// Carrier count
+	ChargeReasonCodeCarrierCount ChargeReasonCodeType = "L1"
+	// Labelling
+	ChargeReasonCodeLabelling ChargeReasonCodeType = "LA"
+	// Labour
+	ChargeReasonCodeLabour ChargeReasonCodeType = "LAA"
+	// Repair and return
+	ChargeReasonCodeRepairAndReturn ChargeReasonCodeType = "LAB"
+	// Legalisation
+	ChargeReasonCodeLegalisation ChargeReasonCodeType = "LF"
+	// Mounting
+	ChargeReasonCodeMounting ChargeReasonCodeType = "MAE"
+	// Mail invoice
+	ChargeReasonCodeMailInvoice ChargeReasonCodeType = "MI"
+	// Mail invoice to each location
+	ChargeReasonCodeMailInvoiceToEachLocation ChargeReasonCodeType = "ML"
+	// Non-returnable containers
+	ChargeReasonCodeNonReturnableContainers ChargeReasonCodeType = "NAA"
+	// Outside cable connectors
+	ChargeReasonCodeOutsideCableConnectors ChargeReasonCodeType = "OA"
+	// Invoice with shipment
+	ChargeReasonCodeInvoiceWithShipment ChargeReasonCodeType = "PA"
+	// Phosphatizing (steel treatment)
+	ChargeReasonCodePhosphatizingSteelTreatment ChargeReasonCodeType = "PAA"
+	// Packing
+	ChargeReasonCodePacking ChargeReasonCodeType = "PC"
+	// Palletizing
+	ChargeReasonCodePalletizing ChargeReasonCodeType = "PL"
+	// Repacking
+	ChargeReasonCodeRepacking ChargeReasonCodeType = "RAB"
+	// Repair
+	ChargeReasonCodeRepair ChargeReasonCodeType = "RAC"
+	// Returnable container
+	ChargeReasonCodeReturnableContainer ChargeReasonCodeType = "RAD"
+	// Restocking
+	ChargeReasonCodeRestocking ChargeReasonCodeType = "RAF"
+	// Re-delivery
+	ChargeReasonCodeReDelivery ChargeReasonCodeType = "RE"
+	// Refurbishing
+	ChargeReasonCodeRefurbishing ChargeReasonCodeType = "RF"
+	// Rail wagon hire
+	ChargeReasonCodeRailWagonHire ChargeReasonCodeType = "RH"
+	// Loading
+	ChargeReasonCodeLoading ChargeReasonCodeType = "RV"
+	// Salvaging
+	ChargeReasonCodeSalvaging ChargeReasonCodeType = "SA"
+	// Shipping and handling
+	ChargeReasonCodeShippingAndHandling ChargeReasonCodeType = "SAA"
+	// Special packaging
+	ChargeReasonCodeSpecialPackaging ChargeReasonCodeType = "SAD"
+	// Stamping
+	ChargeReasonCodeStamping ChargeReasonCodeType = "SAE"
+	// Consignee unload
+	ChargeReasonCodeConsigneeUnload ChargeReasonCodeType = "SAI"
+	// Shrink-wrap
+	ChargeReasonCodeShrinkWrap ChargeReasonCodeType = "SG"
+	// Special handling
+	ChargeReasonCodeSpecialHandling ChargeReasonCodeType = "SH"
+	// Special finish
+	ChargeReasonCodeSpecialFinish ChargeReasonCodeType = "SM"
+	// Set-up
+	ChargeReasonCodeSetUp ChargeReasonCodeType = "SU"
+	// Tank renting
+	ChargeReasonCodeTankRenting ChargeReasonCodeType = "TAB"
+	// Testing
+	ChargeReasonCodeTesting ChargeReasonCodeType = "TAC"
+	// Transportation - third party billing
+	ChargeReasonCodeTransportationThirdPartyBilling ChargeReasonCodeType = "TT"
+	// Transportation by vendor
+	ChargeReasonCodeTransportationByVendor ChargeReasonCodeType = "TV"
+	// Drop yard
+	ChargeReasonCodeDropYard ChargeReasonCodeType = "V1"
+	// Drop dock
+	ChargeReasonCodeDropDock ChargeReasonCodeType = "V2"
+	// Warehousing
+	ChargeReasonCodeWarehousing ChargeReasonCodeType = "WH"
+	// Combine all same day shipment
+	ChargeReasonCodeCombineAllSameDayShipment ChargeReasonCodeType = "XAA"
+	// Split pick-up
+	ChargeReasonCodeSplitPickUp ChargeReasonCodeType = "YY"
+	// Mutually defined
+	ChargeReasonCodeMutuallyDefined ChargeReasonCodeType = "ZZZ"
+)
+
+// ValidateAllowanceChargeReasonCode returns an error if the code is not a
+// valid UNTDID 5189 allowance reason code for an allowance
+// (chargeIndicator = false), or a valid UNTDID 7161 charge reason code for
+// a charge (chargeIndicator = true).
+func ValidateAllowanceChargeReasonCode(chargeIndicator bool, code string) error {
+	if chargeIndicator {
+		if !ChargeReasonCodeType(code).IsValid() {
+			return fmt.Errorf("invalid charge reason code %q", code)
+		}
+		return nil
+	}
+	if !AllowanceReasonCodeType(code).IsValid() {
+		return fmt.Errorf("invalid allowance reason code %q", code)
+	}
+	return nil
+}
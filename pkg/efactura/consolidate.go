@@ -0,0 +1,315 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// ConsolidateConfig is the config used for consolidating drafts (eg.
+// delivery notes) into invoices.
+type ConsolidateConfig struct {
+	// CustomerKeyFunc returns the key used for grouping the drafts per
+	// customer. The default uses the customer VAT identifier, legal entity
+	// company ID or registration name (the first one that is set).
+	CustomerKeyFunc func(draft Invoice) string
+	// IDFunc generates the ID of the consolidated invoice for a customer from
+	// the customer key and the consolidated drafts. The default uses the ID
+	// of the first draft.
+	IDFunc func(customerKey string, drafts []Invoice) string
+	// LineNoteFunc generates the note of a consolidated line from the IDs of
+	// the source drafts. The default generates "Ref: <id1>, <id2>".
+	LineNoteFunc func(sourceIDs []string) string
+	// IssueDate, if set, is the issue date of the consolidated invoices. By
+	// default the issue date of the last draft (by issue date) is used.
+	IssueDate *types.Date
+	// TaxCurrencyExchangeRate is the exchange rate from the document currency
+	// to the tax currency. Required if the drafts TaxCurrencyCode is
+	// different from the DocumentCurrencyCode.
+	TaxCurrencyExchangeRate types.Decimal
+}
+
+// ConsolidateConfigOption allows gradually modifying a ConsolidateConfig
+type ConsolidateConfigOption func(*ConsolidateConfig)
+
+// ConsolidateCustomerKeyFunc sets the function used for grouping drafts per
+// customer.
+func ConsolidateCustomerKeyFunc(f func(draft Invoice) string) ConsolidateConfigOption {
+	return func(c *ConsolidateConfig) {
+		c.CustomerKeyFunc = f
+	}
+}
+
+// ConsolidateIDFunc sets the function used for generating the IDs of the
+// consolidated invoices.
+func ConsolidateIDFunc(f func(customerKey string, drafts []Invoice) string) ConsolidateConfigOption {
+	return func(c *ConsolidateConfig) {
+		c.IDFunc = f
+	}
+}
+
+// ConsolidateLineNoteFunc sets the function used for generating the notes
+// of the consolidated lines.
+func ConsolidateLineNoteFunc(f func(sourceIDs []string) string) ConsolidateConfigOption {
+	return func(c *ConsolidateConfig) {
+		c.LineNoteFunc = f
+	}
+}
+
+// ConsolidateIssueDate sets the issue date of the consolidated invoices.
+func ConsolidateIssueDate(date types.Date) ConsolidateConfigOption {
+	return func(c *ConsolidateConfig) {
+		c.IssueDate = &date
+	}
+}
+
+// ConsolidateTaxCurrencyExchangeRate sets the exchange rate from the
+// document currency to the tax currency.
+func ConsolidateTaxCurrencyExchangeRate(rate types.Decimal) ConsolidateConfigOption {
+	return func(c *ConsolidateConfig) {
+		c.TaxCurrencyExchangeRate = rate
+	}
+}
+
+// ConsolidateInvoices consolidates multiple drafts (eg. delivery notes) into
+// a single invoice per customer (eg. a monthly invoice). Identical items
+// (same name, note, seller item ID, unit, price and tax category) from lines
+// without allowances or charges are summed into a single line, the other
+// lines are copied as they are. Each consolidated line has a note referencing
+// the IDs of the source drafts, appended to the note of the draft line (if
+// any). The header (supplier, payment means, etc) is taken from the first
+// draft of the customer and the invoice period is set to the issue dates
+// interval of the drafts if not already set. The prepaid amount is the sum of
+// the prepaid amounts of the drafts, and the invoices referenced by the
+// drafts with a prepaid amount are referenced as advance invoices. The
+// consolidated invoices are returned in the order of the first appearance of
+// each customer.
+func ConsolidateInvoices(drafts []Invoice, opts ...ConsolidateConfigOption) (invoices []Invoice, err error) {
+	cfg := ConsolidateConfig{
+		CustomerKeyFunc: defaultConsolidateCustomerKey,
+		IDFunc: func(customerKey string, drafts []Invoice) string {
+			return drafts[0].ID
+		},
+		LineNoteFunc: func(sourceIDs []string) string {
+			return "Ref: " + strings.Join(sourceIDs, ", ")
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(drafts) == 0 {
+		return nil, errors.New("consolidate: no drafts")
+	}
+
+	var keys []string
+	groups := make(map[string][]Invoice)
+	for _, draft := range drafts {
+		key := cfg.CustomerKeyFunc(draft)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], draft)
+	}
+
+	for _, key := range keys {
+		invoice, er := consolidateCustomerDrafts(key, groups[key], cfg)
+		if err = er; err != nil {
+			return nil, fmt.Errorf("consolidate: customer %s: %w", key, err)
+		}
+		invoices = append(invoices, invoice)
+	}
+	return
+}
+
+func defaultConsolidateCustomerKey(draft Invoice) string {
+	party := draft.Customer.Party
+	if party.TaxScheme != nil && party.TaxScheme.CompanyID != "" {
+		return party.TaxScheme.CompanyID
+	}
+	if party.LegalEntity.CompanyID != nil && party.LegalEntity.CompanyID.Value != "" {
+		return party.LegalEntity.CompanyID.Value
+	}
+	return party.LegalEntity.Name
+}
+
+// consolidatedLine is a consolidated line along with the IDs of the source
+// drafts.
+type consolidatedLine struct {
+	line      InvoiceLine
+	sourceIDs []string
+}
+
+func (l *consolidatedLine) addSource(id string) {
+	for _, sourceID := range l.sourceIDs {
+		if sourceID == id {
+			return
+		}
+	}
+	l.sourceIDs = append(l.sourceIDs, id)
+}
+
+// consolidateLineKey returns the key for summing identical lines. Lines with
+// allowances or charges are never summed, and lines with different notes are
+// kept separate, so no note is lost.
+func consolidateLineKey(line InvoiceLine) (string, bool) {
+	if len(line.AllowanceCharges) > 0 || line.Price.AllowanceCharge != nil {
+		return "", false
+	}
+	var sellerItemID, baseQuantity string
+	if line.Item.SellerItemID != nil {
+		sellerItemID = line.Item.SellerItemID.ID
+	}
+	if line.Price.BaseQuantity != nil {
+		baseQuantity = line.Price.BaseQuantity.Quantity.String()
+	}
+	properties := make([]string, 0, 2*len(line.Item.AdditionalProperties))
+	for _, property := range line.Item.AdditionalProperties {
+		properties = append(properties, property.Name, property.Value)
+	}
+	return strings.Join([]string{
+		line.Item.Name,
+		line.Note,
+		sellerItemID,
+		idNodeValue(line.Item.BuyerItemID),
+		orderLineID(line.OrderLineReference),
+		lineObjectID(line.DocumentReference),
+		string(itemOriginCountry(line.Item)),
+		strings.Join(properties, "\x01"),
+		string(line.InvoicedQuantity.UnitCode),
+		line.Price.PriceAmount.Amount.String(),
+		baseQuantity,
+		string(line.Item.TaxCategory.ID),
+		line.Item.TaxCategory.Percent.String(),
+		string(line.LineExtensionAmount.CurrencyID),
+	}, "\x00"), true
+}
+
+func consolidateCustomerDrafts(customerKey string, drafts []Invoice, cfg ConsolidateConfig) (invoice Invoice, err error) {
+	first := drafts[0]
+
+	var lines []*consolidatedLine
+	linesByKey := make(map[string]*consolidatedLine)
+	var allowancesCharges []InvoiceDocumentAllowanceCharge
+	var prepaidAmount *types.Decimal
+	var advanceInvoices []InvoiceDocumentReference
+	startDate, endDate := first.IssueDate, first.IssueDate
+	for _, draft := range drafts {
+		if draft.DocumentCurrencyCode != first.DocumentCurrencyCode {
+			return invoice, fmt.Errorf("draft %s: currency %s differs from %s",
+				draft.ID, draft.DocumentCurrencyCode, first.DocumentCurrencyCode)
+		}
+		if draft.TaxCurrencyCode != first.TaxCurrencyCode {
+			return invoice, fmt.Errorf("draft %s: tax currency %s differs from %s",
+				draft.ID, draft.TaxCurrencyCode, first.TaxCurrencyCode)
+		}
+		if draft.IssueDate.Before(startDate.Time) {
+			startDate = draft.IssueDate
+		}
+		if draft.IssueDate.After(endDate.Time) {
+			endDate = draft.IssueDate
+		}
+		allowancesCharges = append(allowancesCharges, draft.AllowanceCharges...)
+		if prepaid := draft.LegalMonetaryTotal.PrepaidAmount; prepaid != nil && !prepaid.Amount.IsZero() {
+			if prepaidAmount == nil {
+				prepaidAmount = types.Zero.Ptr()
+			}
+			*prepaidAmount = prepaidAmount.Add(prepaid.Amount)
+			for _, ref := range draft.BillingReferences {
+				advanceInvoices = appendAdvanceInvoice(advanceInvoices, ref.InvoiceDocumentReference)
+			}
+		}
+
+		for _, line := range draft.InvoiceLines {
+			key, ok := consolidateLineKey(line)
+			if ok {
+				if cl, found := linesByKey[key]; found {
+					cl.line.InvoicedQuantity.Quantity = cl.line.InvoicedQuantity.Quantity.Add(line.InvoicedQuantity.Quantity)
+					cl.line.LineExtensionAmount.Amount = cl.line.LineExtensionAmount.Amount.Add(line.LineExtensionAmount.Amount)
+					cl.addSource(draft.ID)
+					continue
+				}
+			}
+			cl := &consolidatedLine{line: line}
+			cl.addSource(draft.ID)
+			if ok {
+				linesByKey[key] = cl
+			}
+			lines = append(lines, cl)
+		}
+	}
+
+	invoiceLines := make([]InvoiceLine, 0, len(lines))
+	for i, cl := range lines {
+		line := cl.line
+		line.ID = strconv.Itoa(i + 1)
+		if note := cfg.LineNoteFunc(cl.sourceIDs); line.Note == "" {
+			line.Note = note
+		} else if note != "" {
+			line.Note = line.Note + "; " + note
+		}
+		invoiceLines = append(invoiceLines, line)
+	}
+
+	b := newInvoiceBuilderFromInvoice(first, cfg.TaxCurrencyExchangeRate).
+		WithID(cfg.IDFunc(customerKey, drafts)).
+		WithInvoiceLines(invoiceLines).
+		WithAllowancesCharges(allowancesCharges)
+	if prepaidAmount != nil {
+		b.WithPrepaidAmount(*prepaidAmount, advanceInvoices...)
+	}
+	if cfg.IssueDate != nil {
+		b.WithIssueDate(*cfg.IssueDate)
+	} else {
+		b.WithIssueDate(endDate)
+	}
+	if first.InvoicePeriod == nil {
+		b.WithInvoicePeriod(InvoicePeriod{
+			StartDate: startDate.Ptr(),
+			EndDate:   endDate.Ptr(),
+		})
+	}
+	for _, draft := range drafts[1:] {
+		for _, taxTotal := range draft.TaxTotal {
+			for _, subtotal := range taxTotal.TaxSubtotals {
+				category := subtotal.TaxCategory
+				if category.TaxExemptionReason != "" || category.TaxExemptionReasonCode != "" {
+					b.AddTaxExemptionReason(category.ID, category.TaxExemptionReason, category.TaxExemptionReasonCode)
+				}
+			}
+		}
+	}
+
+	if invoice, err = b.Build(); err != nil {
+		return
+	}
+	copyInvoiceNonBuilderFields(&invoice, first)
+	return
+}
+
+// appendAdvanceInvoice appends ref to the advance invoices if not already
+// there.
+func appendAdvanceInvoice(advanceInvoices []InvoiceDocumentReference, ref InvoiceDocumentReference) []InvoiceDocumentReference {
+	for _, advanceInvoice := range advanceInvoices {
+		if advanceInvoice.ID == ref.ID {
+			return advanceInvoices
+		}
+	}
+	return append(advanceInvoices, ref)
+}
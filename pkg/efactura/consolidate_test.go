@@ -0,0 +1,238 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestConsolidateInvoices(t *testing.T) {
+	assert := assert.New(t)
+
+	taxCategory := InvoiceLineTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	type draftLine struct {
+		name     string
+		quantity float64
+		price    float64
+	}
+	buildDraft := func(id string, day int, customer InvoiceCustomerParty, draftLines ...draftLine) Invoice {
+		var lines []InvoiceLine
+		for i, l := range draftLines {
+			line, err := NewInvoiceLineBuilder(fmt.Sprint(i+1), CurrencyRON).
+				WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(l.quantity)).
+				WithGrossPriceAmount(types.D(l.price)).
+				WithItemName(l.name).
+				WithItemTaxCategory(taxCategory).
+				Build()
+			if err != nil {
+				t.Fatalf("error building line: %v", err)
+			}
+			lines = append(lines, line)
+		}
+		draft, err := NewInvoiceBuilder(id).
+			WithIssueDate(types.MakeDate(2024, 3, day)).
+			WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(customer).
+			WithInvoiceLines(lines).
+			Build()
+		if err != nil {
+			t.Fatalf("error building draft: %v", err)
+		}
+		return draft
+	}
+
+	customerA := getInvoiceCustomerParty()
+	customerB := getInvoiceCustomerParty()
+	customerB.TaxScheme = &InvoicePartyTaxScheme{TaxScheme: TaxSchemeVAT, CompanyID: "RO990"}
+
+	drafts := []Invoice{
+		buildDraft("DN-1", 3, customerA, draftLine{"Paine", 10, 2.5}, draftLine{"Lapte", 2, 6}),
+		buildDraft("DN-2", 5, customerB, draftLine{"Paine", 1, 2.5}),
+		buildDraft("DN-3", 20, customerA, draftLine{"Paine", 4, 2.5}, draftLine{"Paine", 1, 3}),
+	}
+
+	invoices, err := ConsolidateInvoices(drafts,
+		ConsolidateIDFunc(func(customerKey string, drafts []Invoice) string {
+			return "F-" + customerKey
+		}),
+		ConsolidateIssueDate(types.MakeDate(2024, 3, 31)),
+	)
+	if !assert.NoError(err) || !assert.Len(invoices, 2) {
+		return
+	}
+
+	invoice := invoices[0]
+	assert.Equal("F-"+customerA.TaxScheme.CompanyID, invoice.ID)
+	assert.True(invoice.IssueDate.Equal(types.MakeDate(2024, 3, 31).Time))
+	if assert.NotNil(invoice.InvoicePeriod) {
+		assert.True(invoice.InvoicePeriod.StartDate.Equal(types.MakeDate(2024, 3, 3).Time))
+		assert.True(invoice.InvoicePeriod.EndDate.Equal(types.MakeDate(2024, 3, 20).Time))
+	}
+	if assert.Len(invoice.InvoiceLines, 3) {
+		line := invoice.InvoiceLines[0]
+		assert.Equal("1", line.ID)
+		assert.Equal("Paine", line.Item.Name)
+		assert.Equal("14", line.InvoicedQuantity.Quantity.String())
+		assert.Equal("35.00", line.LineExtensionAmount.Amount.StringFixed(2))
+		assert.Equal("Ref: DN-1, DN-3", line.Note)
+
+		assert.Equal("Lapte", invoice.InvoiceLines[1].Item.Name)
+		assert.Equal("Ref: DN-1", invoice.InvoiceLines[1].Note)
+
+		// Different price, not summed.
+		assert.Equal("3", invoice.InvoiceLines[2].ID)
+		assert.Equal("Ref: DN-3", invoice.InvoiceLines[2].Note)
+	}
+	assert.Equal("50.00", invoice.LegalMonetaryTotal.LineExtensionAmount.Amount.StringFixed(2))
+	assert.Equal("59.50", invoice.LegalMonetaryTotal.TaxInclusiveAmount.Amount.StringFixed(2))
+
+	assert.Equal("F-RO990", invoices[1].ID)
+	assert.Len(invoices[1].InvoiceLines, 1)
+
+	drafts[2].DocumentCurrencyCode = CurrencyEUR
+	_, err = ConsolidateInvoices(drafts)
+	assert.Error(err, "drafts with different currencies cannot be consolidated")
+
+	_, err = ConsolidateInvoices(nil)
+	assert.Error(err)
+}
+
+func TestConsolidateInvoicesLineNotes(t *testing.T) {
+	assert := assert.New(t)
+
+	buildDraft := func(id string, notes ...string) Invoice {
+		var lines []InvoiceLine
+		for i, note := range notes {
+			line, err := NewInvoiceLineBuilder(fmt.Sprint(i+1), CurrencyRON).
+				WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(1)).
+				WithGrossPriceAmount(types.D(10)).
+				WithItemName("Paine").
+				WithItemTaxCategory(InvoiceLineTaxCategory{
+					TaxScheme: TaxSchemeVAT,
+					ID:        TaxCategoryVATStandardRate,
+					Percent:   types.D(19),
+				}).
+				WithNote(note).
+				Build()
+			if err != nil {
+				t.Fatalf("error building line: %v", err)
+			}
+			lines = append(lines, line)
+		}
+		draft, err := NewInvoiceBuilder(id).
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines(lines).
+			Build()
+		if err != nil {
+			t.Fatalf("error building draft: %v", err)
+		}
+		return draft
+	}
+
+	invoices, err := ConsolidateInvoices([]Invoice{
+		buildDraft("DN-1", "Lot 1", ""),
+		buildDraft("DN-2", "Lot 2", "Lot 1"),
+	})
+	if !assert.NoError(err) || !assert.Len(invoices, 1) {
+		return
+	}
+	var notes []string
+	for _, line := range invoices[0].InvoiceLines {
+		notes = append(notes, line.Note)
+	}
+	// The lines with different notes are not summed, and the notes of the
+	// drafts are kept.
+	assert.Equal([]string{
+		"Lot 1; Ref: DN-1, DN-2",
+		"Ref: DN-1",
+		"Lot 2; Ref: DN-2",
+	}, notes)
+	assert.Equal("2", invoices[0].InvoiceLines[0].InvoicedQuantity.Quantity.String())
+}
+
+func TestConsolidateInvoicesPrepaidAmount(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Paine").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	buildDraft := func(id string, prepaidAmount float64, advanceInvoices ...InvoiceDocumentReference) Invoice {
+		draft, err := NewInvoiceBuilder(id).
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines([]InvoiceLine{line}).
+			WithPrepaidAmount(types.D(prepaidAmount), advanceInvoices...).
+			Build()
+		if err != nil {
+			t.Fatalf("error building draft: %v", err)
+		}
+		return draft
+	}
+
+	advance1 := InvoiceDocumentReference{ID: "AV-1"}
+	advance2 := InvoiceDocumentReference{ID: "AV-2"}
+	invoices, err := ConsolidateInvoices([]Invoice{
+		buildDraft("DN-1", 10, advance1),
+		buildDraft("DN-2", 0),
+		buildDraft("DN-3", 20, advance1, advance2),
+	})
+	if !assert.NoError(err) || !assert.Len(invoices, 1) {
+		return
+	}
+
+	total := invoices[0].LegalMonetaryTotal
+	assert.Equal("357.00", total.TaxInclusiveAmount.Amount.StringFixed(2))
+	if assert.NotNil(total.PrepaidAmount) {
+		assert.Equal("30.00", total.PrepaidAmount.Amount.StringFixed(2))
+	}
+	assert.Equal("327.00", total.PayableAmount.Amount.StringFixed(2))
+	assert.Equal([]InvoiceNote{{
+		SubjectCode: InvoiceNoteSubjectPaymentInformation,
+		Note:        "Avans încasat: 30.00 RON (factura AV-1, factura AV-2)",
+	}}, invoices[0].Note)
+	if assert.Len(invoices[0].BillingReferences, 2) {
+		assert.Equal(advance1, invoices[0].BillingReferences[0].InvoiceDocumentReference)
+		assert.Equal(advance2, invoices[0].BillingReferences[1].InvoiceDocumentReference)
+	}
+}
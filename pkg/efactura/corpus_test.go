@@ -0,0 +1,112 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// corpusFS are the sample documents covering the CIUS-RO scenarios. Each
+// document must be parsed and re-produced with the same content (see
+// RoundTripCheck). The samples are anonymized: the names, the identifiers and
+// the bank accounts are fictitious. More samples (eg. the ones published by
+// ANAF) can be dropped in the directory.
+//
+//go:embed testdata/corpus/*.xml
+var corpusFS embed.FS
+
+// corpusKnownGaps are the paths of the elements of each sample that are not
+// re-produced because of known gaps of the model. When a gap is fixed, the
+// test fails until the path is removed from here.
+var corpusKnownGaps = map[string][]string{
+	"invoice_standard.xml": {
+		// The item standard identifier is modeled as the value of
+		// cac:StandardItemIdentification, not of its cbc:ID.
+		"Invoice/InvoiceLine/Item/StandardItemIdentification",
+	},
+	"invoice_references.xml": {
+		// The address line is modeled as cbc:AddressLine, not
+		// cac:AddressLine/cbc:Line.
+		"Invoice/AccountingCustomerParty/Party/PostalAddress/AddressLine",
+	},
+}
+
+func TestCorpus(t *testing.T) {
+	files, err := corpusFS.ReadDir("testdata/corpus")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for name := range corpusKnownGaps {
+		if _, err := fs.Stat(corpusFS, path.Join("testdata/corpus", name)); err != nil {
+			t.Errorf("corpusKnownGaps: %v", err)
+		}
+	}
+	for _, file := range files {
+		name := file.Name()
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			original, err := corpusFS.ReadFile(path.Join("testdata/corpus", name))
+			if !assert.NoError(err) {
+				return
+			}
+			report, err := RoundTripCheck(original)
+			if !assert.NoError(err) {
+				return
+			}
+			missing, extra := report.Missing, report.Extra
+			gaps := corpusKnownGaps[name]
+			for _, gap := range gaps {
+				if !hasEntryWithPrefix(missing, gap) && !hasEntryWithPrefix(extra, gap) {
+					t.Errorf("known gap %s is fixed, remove it from corpusKnownGaps", gap)
+				}
+			}
+			missing, extra = filterEntries(missing, gaps), filterEntries(extra, gaps)
+			assert.Empty(missing, "elements not re-produced")
+			assert.Empty(extra, "elements not in the original")
+		})
+	}
+}
+
+func hasEntryWithPrefix(entries []string, prefix string) bool {
+	for _, e := range entries {
+		if strings.HasPrefix(e, prefix+"/") || strings.HasPrefix(e, prefix+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+func filterEntries(entries []string, prefixes []string) (filtered []string) {
+	for _, e := range entries {
+		known := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(e, prefix+"/") || strings.HasPrefix(e, prefix+"=") {
+				known = true
+				break
+			}
+		}
+		if !known {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,392 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+
+	ierrors "github.com/printesoi/e-factura-go/internal/errors"
+	"github.com/printesoi/e-factura-go/pkg/types"
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+	"github.com/printesoi/xml-go"
+)
+
+// CreditNote is the object that represents an e-factura credit note using
+// the UBL 2.1 CreditNote syntax (uploaded with the CN standard). The fields
+// and the business terms are the same as for Invoice, with the differences
+// required by the CreditNote schema: the type code is
+// cbc:CreditNoteTypeCode, the lines are cac:CreditNoteLine with
+// cbc:CreditedQuantity, and there is no cbc:DueDate (BT-9) and no
+// cac:ProjectReference (BT-11).
+type CreditNote struct {
+	// NOTE: this field will be automatically set to efactura.UBLVersionID
+	//       when marshaled.
+	UBLVersionID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 UBLVersionID,omitempty" json:"ublVersionID,omitempty"`
+	// ID: BT-24
+	// Term: Identificatorul specificaţiei
+	// NOTE: this field will be automatically set to efactura.CIUSRO_v101
+	//       when marshaled.
+	// Cardinality: 1..1
+	CustomizationID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CustomizationID" json:"customizationID,omitempty"`
+	// ID: BT-23
+	// Term: Tipul procesului de afaceri
+	// Cardinality: 0..1
+	ProfileID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ProfileID,omitempty" json:"profileID,omitempty"`
+
+	// ID: BT-1
+	// Term: Numărul facturii
+	// Cardinality: 1..1
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
+	// ID: BT-2
+	// Term: Data emiterii facturii
+	// Cardinality: 1..1
+	IssueDate types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueDate" json:"issueDate,omitempty"`
+	// ID: BT-3
+	// Term: Codul tipului facturii
+	// Cardinality: 1..1
+	CreditNoteTypeCode InvoiceTypeCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CreditNoteTypeCode" json:"creditNoteTypeCode,omitempty"`
+	// ID: BG-1
+	// Term: COMENTARIU ÎN FACTURĂ
+	// Cardinality: 0..n
+	Note []InvoiceNote `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note,omitempty" json:"note,omitempty"`
+	// ID: BT-5
+	// Term: Codul monedei facturii
+	// Cardinality: 1..1
+	DocumentCurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DocumentCurrencyCode" json:"documentCurrencyCode,omitempty"`
+	// ID: BT-6
+	// Term: Codul monedei de contabilizare a TVA
+	// Cardinality: 0..1
+	TaxCurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxCurrencyCode,omitempty" json:"taxCurrencyCode,omitempty"`
+	// ID: BT-19
+	// Term: Referinţa contabilă a cumpărătorului
+	// Cardinality: 0..1
+	AccountingCost string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AccountingCost,omitempty" json:"accountingCost,omitempty"`
+	// ID: BT-10
+	// Term: Referinţa Cumpărătorului
+	// Cardinality: 0..1
+	BuyerReference string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BuyerReference,omitempty" json:"buyerReference,omitempty"`
+	// ID: BG-14
+	// Term: Perioada de facturare
+	// Cardinality: 0..1
+	InvoicePeriod  *InvoicePeriod         `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoicePeriod,omitempty" json:"invoicePeriod,omitempty"`
+	OrderReference *InvoiceOrderReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OrderReference,omitempty" json:"orderReference,omitempty"`
+	// ID: BG-3
+	// Term: REFERINŢĂ LA O FACTURĂ ANTERIOARĂ
+	// Cardinality: 0..n
+	BillingReferences []InvoiceBillingReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 BillingReference,omitempty" json:"billingReferences,omitempty"`
+	// ID: BT-16
+	// Term: Referinţa avizului de expediție
+	// Cardinality: 0..1
+	DespatchDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DespatchDocumentReference,omitempty" json:"despatchDocumentReference,omitempty"`
+	// ID: BT-15
+	// Term: Referinţa avizului de recepție
+	// Cardinality: 0..1
+	ReceiptDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ReceiptDocumentReference,omitempty" json:"receiptDocumentReference,omitempty"`
+	// ID: BT-12
+	// Term: Referinţa contractului
+	// Cardinality: 0..1
+	ContractDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ContractDocumentReference,omitempty" json:"contractDocumentReference,omitempty"`
+	// ID: BG-24
+	// Term: DOCUMENTE JUSTIFICATIVE SUPLIMENTARE
+	// Cardinality: 0..n
+	// ID: BT-18
+	// Term: Identificatorul obiectului facturat
+	// Cardinality: 0..1
+	AdditionalDocumentReferences []InvoiceAdditionalDocumentReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AdditionalDocumentReference,omitempty" json:"additionalDocumentReferences,omitempty"`
+	// ID: BT-17
+	// Term: Referinţa avizului de ofertă sau a lotului
+	// Cardinality: 0..1
+	OriginatorDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OriginatorDocumentReference,omitempty" json:"originatorDocumentReference,omitempty"`
+	// ID: BG-4
+	// Term: VÂNZĂTOR
+	// Cardinality: 1..1
+	Supplier InvoiceSupplier `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingSupplierParty" json:"supplier,omitempty"`
+	// ID: BG-7
+	// Term: CUMPĂRĂTOR
+	// Cardinality: 1..1
+	Customer InvoiceCustomer `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingCustomerParty" json:"customer,omitempty"`
+	// ID: BG-10
+	// Term: BENEFICIAR
+	// Cardinality: 0..1
+	Payee *InvoicePayee `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PayeeParty,omitempty" json:"payee,omitempty"`
+	// ID: BG-11
+	// Term: REPREZENTANTUL FISCAL AL VÂNZĂTORULUI
+	// Cardinality: 0..1
+	TaxRepresentative *InvoiceTaxRepresentative `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxRepresentativeParty,omitempty" json:"taxRepresentative,omitempty"`
+	// ID: BG-13
+	// Term: INFORMAȚII REFERITOARE LA LIVRARE
+	// Cardinality: 0..1
+	Delivery *InvoiceDelivery `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Delivery,omitempty" json:"delivery,omitempty"`
+	// ID: BG-16
+	// Term: INSTRUCŢIUNI DE PLATĂ
+	// Cardinality: 0..n
+	PaymentMeans []InvoicePaymentMeans `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentMeans,omitempty" json:"paymentMeans,omitempty"`
+	// ID: BT-20
+	// Term: Termeni de plată
+	// Cardinality: 0..1
+	PaymentTerms *InvoicePaymentTerms `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentTerms,omitempty" json:"paymentTerms,omitempty"`
+	// ID: BG-20 / BG-21
+	// Term: DEDUCERI / TAXE SUPLIMENTARE LA NIVELUL DOCUMENTULUI
+	// Cardinality: 0..n
+	AllowanceCharges []InvoiceDocumentAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty" json:"allowanceCharges,omitempty"`
+	TaxTotal         []InvoiceTaxTotal                `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxTotal" json:"taxTotal,omitempty"`
+	// ID: BG-22
+	// Term: TOTALURILE DOCUMENTULUI
+	// Cardinality: 1..1
+	LegalMonetaryTotal InvoiceLegalMonetaryTotal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 LegalMonetaryTotal" json:"legalMonetaryTotal,omitempty"`
+	// ID: BG-25
+	// Term: LINIE A FACTURII
+	// Cardinality: 1..n
+	CreditNoteLines []CreditNoteLine `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 CreditNoteLine" json:"creditNoteLines,omitempty"`
+
+	// Name of node.
+	XMLName xml.Name `xml:"CreditNote" json:"-"`
+	// xmlns attr. Will be automatically set in MarshalXML
+	Namespace string `xml:"xmlns,attr" json:"-"`
+	// xmlns:cac attr. Will be automatically set in MarshalXML
+	NamespaceCAC string `xml:"xmlns:cac,attr" json:"-"`
+	// xmlns:cbc attr. Will be automatically set in MarshalXML
+	NamespaceCBC string `xml:"xmlns:cbc,attr" json:"-"`
+	// generated with... Will be automatically set in MarshalXML if empty.
+	Comment string `xml:",comment" json:"comment,omitempty"`
+}
+
+// CreditNoteLine is a credit note line (cac:CreditNoteLine). It's the same
+// as InvoiceLine, except the quantity is encoded as cbc:CreditedQuantity.
+type CreditNoteLine struct {
+	// ID: BT-126
+	// Term: Identificatorul liniei facturii
+	// Cardinality: 1..1
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
+	// ID: BT-127
+	// Term: Nota liniei facturii
+	// Cardinality: 0..1
+	Note string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note,omitempty" json:"note,omitempty"`
+	// ID: BT-129
+	// Term: Cantitatea facturată
+	// Cardinality: 1..1
+	// ID: BT-130
+	// Term: Codul unităţii de măsură a cantităţii facturate
+	// Cardinality: 1..1
+	CreditedQuantity InvoicedQuantity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CreditedQuantity" json:"creditedQuantity,omitempty"`
+	// ID: BT-131
+	// Term: Valoarea netă a liniei facturii
+	// Cardinality: 1..1
+	LineExtensionAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 LineExtensionAmount" json:"lineExtensionAmount,omitempty"`
+	// ID: BG-26
+	// Term: Perioada de facturare a liniei
+	// Cardinality: 0..1
+	InvoicePeriod *InvoiceLinePeriod `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoicePeriod,omitempty" json:"invoicePeriod,omitempty"`
+	// ID: BT-132
+	// Term: Referinţa liniei comenzii
+	// Cardinality: 0..1
+	OrderLineReference *InvoiceOrderLineReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OrderLineReference,omitempty" json:"orderLineReference,omitempty"`
+	// ID: BT-128
+	// Term: Identificatorul obiectului liniei facturii
+	// Cardinality: 0..1
+	DocumentReference *InvoiceLineDocumentReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DocumentReference,omitempty" json:"documentReference,omitempty"`
+	// ID: BG-27 / BG-28
+	// Term: DEDUCERI / TAXE SUPLIMENTARE LA LINIA FACTURII
+	// Cardinality: 0..n
+	AllowanceCharges []InvoiceLineAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty" json:"allowanceCharges,omitempty"`
+	// ID: BG-31
+	// Term: INFORMAȚII PRIVIND ARTICOLUL
+	Item InvoiceLineItem `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Item" json:"item,omitempty"`
+	// ID: BG-29
+	// Term: DETALII ALE PREŢULUI
+	// Cardinality: 1..1
+	Price InvoiceLinePrice `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Price" json:"price,omitempty"`
+}
+
+// Prefill sets the  NS, NScac, NScbc and Comment properties for ensuring that
+// the required attributes and properties are set for a valid UBL XML.
+func (cn *CreditNote) Prefill() {
+	cn.Namespace = xmlnsUBLCreditNote2
+	cn.NamespaceCAC = xmlnsUBLcac
+	cn.NamespaceCBC = xmlnsUBLcbc
+	cn.UBLVersionID = UBLVersionID
+	cn.CustomizationID = CIUSRO_v101
+}
+
+func (cn CreditNote) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// This allows us to strip the MarshalXML method.
+	type creditNote CreditNote
+	setupUBLXMLEncoder(e)
+	cn.Prefill()
+	return e.EncodeElement(creditNote(cn), start)
+}
+
+// XML returns the XML encoding of the CreditNote
+func (cn CreditNote) XML() ([]byte, error) {
+	return pxml.MarshalXMLWithHeader(cn)
+}
+
+// XMLIndent works like XML, but each XML element begins on a new
+// indented line that starts with prefix and is followed by one or more
+// copies of indent according to the nesting depth.
+func (cn CreditNote) XMLIndent(prefix, indent string) ([]byte, error) {
+	return pxml.MarshalIndentXMLWithHeader(cn, prefix, indent)
+}
+
+// XMLWithOptions works like XML, but the encoding can be changed with
+// options (eg. MarshalOptionStripMarkup or MarshalOptionIndent).
+func (cn CreditNote) XMLWithOptions(opts ...MarshalOption) ([]byte, error) {
+	return marshalDocumentXML(cn, opts...)
+}
+
+// UnmarshalCreditNote unmarshals a CreditNote from XML data. This method
+// does not check if the unmarshaled CreditNote is valid.
+func UnmarshalCreditNote(xmlData []byte, creditNote *CreditNote) error {
+	return pxml.UnmarshalXML(xmlData, creditNote)
+}
+
+// UploadStandard implements the Document interface.
+func (cn CreditNote) UploadStandard() UploadStandard {
+	return UploadStandardCN
+}
+
+// CreditNote converts the invoice to a CreditNote. The type code is kept,
+// so it should be a credit note type code (eg. InvoiceTypeCreditNote). The
+// due date (BT-9) and the project reference (BT-11) are not supported by the
+// CreditNote syntax and are dropped.
+func (iv Invoice) CreditNote() CreditNote {
+	cn := CreditNote{
+		ProfileID:                    iv.ProfileID,
+		ID:                           iv.ID,
+		IssueDate:                    iv.IssueDate,
+		CreditNoteTypeCode:           iv.InvoiceTypeCode,
+		Note:                         iv.Note,
+		DocumentCurrencyCode:         iv.DocumentCurrencyCode,
+		TaxCurrencyCode:              iv.TaxCurrencyCode,
+		AccountingCost:               iv.AccountingCost,
+		BuyerReference:               iv.BuyerReference,
+		InvoicePeriod:                iv.InvoicePeriod,
+		OrderReference:               iv.OrderReference,
+		BillingReferences:            iv.BillingReferences,
+		DespatchDocumentReference:    iv.DespatchDocumentReference,
+		ReceiptDocumentReference:     iv.ReceiptDocumentReference,
+		ContractDocumentReference:    iv.ContractDocumentReference,
+		AdditionalDocumentReferences: iv.AdditionalDocumentReferences,
+		OriginatorDocumentReference:  iv.OriginatorDocumentReference,
+		Supplier:                     iv.Supplier,
+		Customer:                     iv.Customer,
+		Payee:                        iv.Payee,
+		TaxRepresentative:            iv.TaxRepresentative,
+		Delivery:                     iv.Delivery,
+		PaymentMeans:                 iv.PaymentMeans,
+		PaymentTerms:                 iv.PaymentTerms,
+		AllowanceCharges:             iv.AllowanceCharges,
+		TaxTotal:                     iv.TaxTotal,
+		LegalMonetaryTotal:           iv.LegalMonetaryTotal,
+	}
+	for _, line := range iv.InvoiceLines {
+		cn.CreditNoteLines = append(cn.CreditNoteLines, CreditNoteLine{
+			ID:                  line.ID,
+			Note:                line.Note,
+			CreditedQuantity:    line.InvoicedQuantity,
+			LineExtensionAmount: line.LineExtensionAmount,
+			InvoicePeriod:       line.InvoicePeriod,
+			OrderLineReference:  line.OrderLineReference,
+			DocumentReference:   line.DocumentReference,
+			AllowanceCharges:    line.AllowanceCharges,
+			Item:                line.Item,
+			Price:               line.Price,
+		})
+	}
+	cn.Prefill()
+	return cn
+}
+
+// Invoice converts the credit note to an Invoice with the same type code,
+// eg. to use the Invoice helpers (Lint, analytics, exports) on a credit
+// note.
+func (cn CreditNote) Invoice() Invoice {
+	iv := Invoice{
+		ProfileID:                    cn.ProfileID,
+		ID:                           cn.ID,
+		IssueDate:                    cn.IssueDate,
+		InvoiceTypeCode:              cn.CreditNoteTypeCode,
+		Note:                         cn.Note,
+		DocumentCurrencyCode:         cn.DocumentCurrencyCode,
+		TaxCurrencyCode:              cn.TaxCurrencyCode,
+		AccountingCost:               cn.AccountingCost,
+		BuyerReference:               cn.BuyerReference,
+		InvoicePeriod:                cn.InvoicePeriod,
+		OrderReference:               cn.OrderReference,
+		BillingReferences:            cn.BillingReferences,
+		DespatchDocumentReference:    cn.DespatchDocumentReference,
+		ReceiptDocumentReference:     cn.ReceiptDocumentReference,
+		ContractDocumentReference:    cn.ContractDocumentReference,
+		AdditionalDocumentReferences: cn.AdditionalDocumentReferences,
+		OriginatorDocumentReference:  cn.OriginatorDocumentReference,
+		Supplier:                     cn.Supplier,
+		Customer:                     cn.Customer,
+		Payee:                        cn.Payee,
+		TaxRepresentative:            cn.TaxRepresentative,
+		Delivery:                     cn.Delivery,
+		PaymentMeans:                 cn.PaymentMeans,
+		PaymentTerms:                 cn.PaymentTerms,
+		AllowanceCharges:             cn.AllowanceCharges,
+		TaxTotal:                     cn.TaxTotal,
+		LegalMonetaryTotal:           cn.LegalMonetaryTotal,
+	}
+	for _, line := range cn.CreditNoteLines {
+		iv.InvoiceLines = append(iv.InvoiceLines, InvoiceLine{
+			ID:                  line.ID,
+			Note:                line.Note,
+			InvoicedQuantity:    line.CreditedQuantity,
+			LineExtensionAmount: line.LineExtensionAmount,
+			InvoicePeriod:       line.InvoicePeriod,
+			OrderLineReference:  line.OrderLineReference,
+			DocumentReference:   line.DocumentReference,
+			AllowanceCharges:    line.AllowanceCharges,
+			Item:                line.Item,
+			Price:               line.Price,
+		})
+	}
+	iv.Prefill()
+	return iv
+}
+
+// NewCreditNoteBuilder creates a new InvoiceBuilder for a credit note, with
+// the type code set to InvoiceTypeCreditNote. Use BuildCreditNote to build
+// the CreditNote. Invoice lines are built with the InvoiceLineBuilder and
+// are converted to credit note lines.
+func NewCreditNoteBuilder(id string) *InvoiceBuilder {
+	return NewInvoiceBuilder(id).WithInvoiceTypeCode(InvoiceTypeCreditNote)
+}
+
+// BuildCreditNote builds the CreditNote, computing the totals the same way
+// as Build. The due date (BT-9) is not supported by the CreditNote syntax
+// and it's an error to set it.
+func (b InvoiceBuilder) BuildCreditNote() (creditNote CreditNote, err error) {
+	if b.dueDate != nil {
+		err = ierrors.NewBuilderErrorf(b, "BT-9", "due date is not supported by credit notes")
+		return
+	}
+	invoice, er := b.Build()
+	if err = er; err != nil {
+		return
+	}
+	return invoice.CreditNote(), nil
+}
+
+// UploadCreditNote uploads the given CreditNote (with the CN standard) with
+// the provided optional options.
+func (c *Client) UploadCreditNote(
+	ctx context.Context, creditNote CreditNote, cif string, opts ...UploadOption,
+) (response *UploadResponse, err error) {
+	return c.Upload(ctx, creditNote, cif, opts...)
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestCreditNoteBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(2)).
+		WithGrossPriceAmount(types.D(50)).
+		WithItemName("Produs returnat").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	newBuilder := func() *InvoiceBuilder {
+		return NewCreditNoteBuilder("CN-1").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			AppendBillingReferences(InvoiceDocumentReference{ID: "INV-1"}).
+			WithInvoiceLines([]InvoiceLine{line})
+	}
+
+	creditNote, err := newBuilder().BuildCreditNote()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(InvoiceTypeCreditNote, creditNote.CreditNoteTypeCode)
+	assert.Equal(UploadStandardCN, creditNote.UploadStandard())
+	assert.True(creditNote.LegalMonetaryTotal.PayableAmount.Amount.Equal(types.D(119)))
+	if assert.Len(creditNote.CreditNoteLines, 1) {
+		assert.True(creditNote.CreditNoteLines[0].CreditedQuantity.Quantity.Equal(types.D(2)))
+	}
+
+	xmlData, err := creditNote.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	xmlStr := string(xmlData)
+	assert.Contains(xmlStr, `<CreditNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"`)
+	assert.Contains(xmlStr, "<cbc:IssueDate>2024-03-01</cbc:IssueDate><cbc:CreditNoteTypeCode>381</cbc:CreditNoteTypeCode>")
+	assert.Contains(xmlStr, `<cac:CreditNoteLine><cbc:ID>1</cbc:ID><cbc:CreditedQuantity unitCode="H87">2</cbc:CreditedQuantity>`)
+	assert.NotContains(xmlStr, "InvoiceLine")
+	assert.NotContains(xmlStr, "InvoiceTypeCode")
+	st, err := DetectUploadStandard(xmlData)
+	if assert.NoError(err) {
+		assert.Equal(UploadStandardCN, st)
+	}
+
+	var parsed CreditNote
+	if assert.NoError(UnmarshalCreditNote(xmlData, &parsed)) {
+		parsedXML, err := parsed.XML()
+		if assert.NoError(err) {
+			assert.Equal(xmlStr, string(parsedXML))
+		}
+	}
+	doc, err := UnmarshalDownloadedInvoiceXML(xmlData)
+	if assert.NoError(err) && assert.NotNil(doc.CreditNote) {
+		assert.Nil(doc.Invoice)
+		assert.Equal("CN-1", doc.CreditNote.ID)
+	}
+
+	invoice := parsed.Invoice()
+	assert.Equal(InvoiceTypeCreditNote, invoice.InvoiceTypeCode)
+	if assert.Len(invoice.InvoiceLines, 1) {
+		assert.True(invoice.InvoiceLines[0].InvoicedQuantity.Quantity.Equal(types.D(2)))
+	}
+	roundTripXML, err := invoice.CreditNote().XML()
+	if assert.NoError(err) {
+		assert.Equal(xmlStr, string(roundTripXML))
+	}
+
+	_, err = newBuilder().WithDueDate(types.MakeDate(2024, 3, 31)).BuildCreditNote()
+	if assert.Error(err) {
+		assert.True(strings.Contains(err.Error(), "BT-9"))
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// maxAmountDecimals is the maximum number of decimals of an amount allowed
+// by EN 16931 (BR-DEC-*).
+const maxAmountDecimals = 2
+
+// currencyMinorUnits are the ISO 4217 minor units (the number of decimal
+// places) of the currencies with a minor unit other than 2. Every other
+// valid currency code not in currenciesWithoutMinorUnit has 2 decimals.
+var currencyMinorUnits = map[CurrencyCodeType]int32{
+	CurrencyBHD: 3,
+	CurrencyBIF: 0,
+	CurrencyCLF: 4,
+	CurrencyCLP: 0,
+	CurrencyDJF: 0,
+	CurrencyGNF: 0,
+	CurrencyIQD: 3,
+	CurrencyISK: 0,
+	CurrencyJOD: 3,
+	CurrencyJPY: 0,
+	CurrencyKMF: 0,
+	CurrencyKRW: 0,
+	CurrencyKWD: 3,
+	CurrencyLYD: 3,
+	CurrencyOMR: 3,
+	CurrencyPYG: 0,
+	CurrencyRWF: 0,
+	CurrencyTND: 3,
+	CurrencyUGX: 0,
+	CurrencyUYI: 0,
+	CurrencyVND: 0,
+	CurrencyVUV: 0,
+	CurrencyXAF: 0,
+	CurrencyXOF: 0,
+	CurrencyXPF: 0,
+}
+
+// currenciesWithoutMinorUnit are the ISO 4217 codes without a minor unit
+// (the precious metals, the bond market units, the SDR and the testing
+// codes).
+var currenciesWithoutMinorUnit = map[CurrencyCodeType]struct{}{
+	CurrencyXAG: {},
+	CurrencyXAU: {},
+	CurrencyXBA: {},
+	CurrencyXBB: {},
+	CurrencyXBC: {},
+	CurrencyXBD: {},
+	CurrencyXDR: {},
+	CurrencyXPD: {},
+	CurrencyXPT: {},
+	CurrencyXSU: {},
+	CurrencyXTS: {},
+	CurrencyXUA: {},
+	CurrencyXXX: {},
+}
+
+// MinorUnits returns the number of decimal places of the minor unit of the
+// currency, as defined by ISO 4217 (eg. 2 for RON, 0 for JPY, 3 for BHD).
+// ok is false if the code is not a valid currency code or if the currency
+// has no minor unit (eg. XAU).
+func (c CurrencyCodeType) MinorUnits() (units int32, ok bool) {
+	if !c.IsValid() {
+		return 0, false
+	}
+	if _, ok := currenciesWithoutMinorUnit[c]; ok {
+		return 0, false
+	}
+	if units, ok := currencyMinorUnits[c]; ok {
+		return units, true
+	}
+	return 2, true
+}
+
+// AmountDecimals returns the number of decimals of an amount in the
+// currency: the minor units of the currency, but at most 2, the maximum
+// allowed by EN 16931. The currencies without a minor unit and the invalid
+// codes use 2 decimals.
+func (c CurrencyCodeType) AmountDecimals() int32 {
+	units, ok := c.MinorUnits()
+	if !ok || units > maxAmountDecimals {
+		return maxAmountDecimals
+	}
+	return units
+}
+
+// Round rounds the amount to the number of decimals of the currency (see
+// AmountDecimals), with halves rounded away from zero. Eg. a JPY amount is
+// rounded to an integer, while a BHD amount is rounded to 2 decimals, since
+// EN 16931 doesn't allow 3 decimals.
+func (c CurrencyCodeType) Round(amount types.Decimal) types.Decimal {
+	return amount.Round(c.AmountDecimals())
+}
+
+// RoundToCurrency returns the amount rounded to the number of decimals of
+// its currency (see CurrencyCodeType.Round).
+func (a AmountWithCurrency) RoundToCurrency() AmountWithCurrency {
+	return MakeAmountWithCurrency(a.CurrencyID.Round(a.Amount), a.CurrencyID)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		currency CurrencyCodeType
+		units    int32
+		ok       bool
+		decimals int32
+	}{
+		{CurrencyRON, 2, true, 2},
+		{CurrencyEUR, 2, true, 2},
+		{CurrencyJPY, 0, true, 0},
+		{CurrencyKRW, 0, true, 0},
+		{CurrencyBHD, 3, true, 2},
+		{CurrencyKWD, 3, true, 2},
+		{CurrencyCLF, 4, true, 2},
+		{CurrencyXAU, 0, false, 2},
+		{"ABC", 0, false, 2},
+	}
+	for _, test := range tests {
+		units, ok := test.currency.MinorUnits()
+		assert.Equal(test.units, units, test.currency)
+		assert.Equal(test.ok, ok, test.currency)
+		assert.Equal(test.decimals, test.currency.AmountDecimals(), test.currency)
+	}
+
+	// The exceptions must be valid currency codes.
+	for currency := range currencyMinorUnits {
+		assert.True(currency.IsValid(), currency)
+	}
+	for currency := range currenciesWithoutMinorUnit {
+		assert.True(currency.IsValid(), currency)
+	}
+
+	assert.Equal("1235", CurrencyJPY.Round(types.D(1234.5)).String())
+	assert.Equal("12.35", CurrencyRON.Round(types.D(12.345)).String())
+	assert.Equal("1.23", CurrencyBHD.Round(types.D(1.2345)).String())
+
+	amount := MakeAmountWithCurrency(types.D(99.5), CurrencyJPY).RoundToCurrency()
+	assert.Equal("100", amount.Amount.String())
+	assert.Equal(CurrencyJPY, amount.CurrencyID)
+}
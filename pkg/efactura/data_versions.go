@@ -0,0 +1,203 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// DataComponent is the name of a data set (code list, rules, specification)
+// embedded in this library.
+type DataComponent string
+
+const (
+	// DataComponentCIUSRO is the CIUS-RO specification implemented.
+	DataComponentCIUSRO DataComponent = "CIUS-RO"
+	// DataComponentUBL is the UBL version implemented.
+	DataComponentUBL DataComponent = "UBL"
+	// DataComponentInvoiceTypeCodes is the UNTDID 1001 code list (invoice
+	// type codes).
+	DataComponentInvoiceTypeCodes DataComponent = "UNTDID-1001"
+	// DataComponentTaxCategoryCodes is the UNTDID 5305 code list (tax
+	// category codes).
+	DataComponentTaxCategoryCodes DataComponent = "UNTDID-5305"
+	// DataComponentPaymentMeansCodes is the UNTDID 4461 code list (payment
+	// means codes).
+	DataComponentPaymentMeansCodes DataComponent = "UNTDID-4461"
+	// DataComponentNoteSubjectCodes is the UNTDID 4451 code list (text
+	// subject codes of the invoice notes).
+	DataComponentNoteSubjectCodes DataComponent = "UNTDID-4451"
+	// DataComponentAllowanceReasonCodes is the UNTDID 5189 code list
+	// (allowance reason codes).
+	DataComponentAllowanceReasonCodes DataComponent = "UNTDID-5189"
+	// DataComponentChargeReasonCodes is the UNTDID 7161 code list (charge
+	// reason codes).
+	DataComponentChargeReasonCodes DataComponent = "UNTDID-7161"
+	// DataComponentTaxExemptionReasonCodes is the VATEX code list (tax
+	// exemption reason codes).
+	DataComponentTaxExemptionReasonCodes DataComponent = "VATEX"
+	// DataComponentCurrencyCodes is the ISO 4217 code list (currency codes).
+	DataComponentCurrencyCodes DataComponent = "ISO-4217"
+	// DataComponentCurrencyMinorUnits is the ISO 4217 minor units (the
+	// number of decimals) of the currencies, used by CurrencyCodeType.Round.
+	DataComponentCurrencyMinorUnits DataComponent = "ISO-4217-MINOR-UNITS"
+	// DataComponentCountryCodes is the ISO 3166-1 code list (country codes).
+	DataComponentCountryCodes DataComponent = "ISO-3166-1"
+	// DataComponentCountrySubentityCodes is the ISO 3166-2:RO code list
+	// (Romanian county codes).
+	DataComponentCountrySubentityCodes DataComponent = "ISO-3166-2:RO"
+	// DataComponentValidationRules is the Go implementation of a subset of
+	// the EN 16931 and CIUS-RO business rules, checked by
+	// ValidateInvoiceOffline. No Schematron is embedded.
+	DataComponentValidationRules DataComponent = "CIUS-RO-RULES"
+	// DataComponentSIRUTA is the SIRUTA registry of the Romanian
+	// localities. It's not embedded, but loaded at runtime by the siruta
+	// package, which registers its version (see RegisterDataVersion).
+	DataComponentSIRUTA DataComponent = "SIRUTA"
+)
+
+// DataVersion describes the version of a data set embedded in this library.
+type DataVersion struct {
+	// Component is the name of the data set.
+	Component DataComponent `json:"component"`
+	// Version is the version of the data set, as published by its
+	// maintainer.
+	Version string `json:"version"`
+	// UpdatedAt is the publication date of the version of the data set
+	// the embedded copy was synchronized with, or for the data sets
+	// maintained in this library (eg. the validation rules), the date of
+	// their last change.
+	UpdatedAt types.Date `json:"updatedAt"`
+}
+
+// String implements the fmt.Stringer interface.
+func (v DataVersion) String() string {
+	return fmt.Sprintf("%s %s (%s)", v.Component, v.Version, v.UpdatedAt.Format(time.DateOnly))
+}
+
+// ciusROVersion is the version of the CIUS-RO specification from the
+// CustomizationID (eg. "1.0.1").
+var ciusROVersion = CIUSRO_v101[strings.LastIndex(CIUSRO_v101, ":")+1:]
+
+// dataVersionsMu guards dataVersions, since the data sets loaded at runtime
+// are registered with RegisterDataVersion.
+var dataVersionsMu sync.RWMutex
+
+// dataVersions is the changelog of the embedded data. This must be updated
+// every time an embedded data set is updated. The Version is the edition of
+// the published data set the embedded copy was synchronized with: the
+// UNTDID directory for the UN/EDIFACT code lists (as referenced by EN
+// 16931-1:2017), or the CIUS-RO version for the code lists restricted by
+// CIUS-RO. The UpdatedAt is the publication date of that edition:
+//   - UBL 2.1: the OASIS Standard of 4 November 2013;
+//   - UNTDID D16B: EN 16931-1:2017, as referenced in the Official Journal
+//     of the EU by the Commission Implementing Decision (EU) 2017/1870 (17
+//     October 2017);
+//   - CIUS-RO 1.0.1: the specifications approved by the Order of the
+//     Minister of Finance no. 1366/2021 (8 November 2021).
+//
+// The validation rules are implemented in Go by this library, so their
+// UpdatedAt is the date of their last change.
+var dataVersions = []DataVersion{
+	{Component: DataComponentCIUSRO, Version: ciusROVersion, UpdatedAt: ciusROPublicationDate},
+	{Component: DataComponentUBL, Version: UBLVersionID, UpdatedAt: types.MakeDate(2013, time.November, 4)},
+	{Component: DataComponentInvoiceTypeCodes, Version: "D16B", UpdatedAt: en16931PublicationDate},
+	{Component: DataComponentTaxCategoryCodes, Version: "D16B", UpdatedAt: en16931PublicationDate},
+	{Component: DataComponentPaymentMeansCodes, Version: "D16B", UpdatedAt: en16931PublicationDate},
+	{Component: DataComponentNoteSubjectCodes, Version: "D16B", UpdatedAt: en16931PublicationDate},
+	{Component: DataComponentAllowanceReasonCodes, Version: "D16B", UpdatedAt: en16931PublicationDate},
+	{Component: DataComponentChargeReasonCodes, Version: "D16B", UpdatedAt: en16931PublicationDate},
+	{Component: DataComponentTaxExemptionReasonCodes, Version: "CIUS-RO " + ciusROVersion, UpdatedAt: ciusROPublicationDate},
+	{Component: DataComponentCurrencyCodes, Version: "CIUS-RO " + ciusROVersion, UpdatedAt: ciusROPublicationDate},
+	{Component: DataComponentCurrencyMinorUnits, Version: "ISO 4217 List One (CIUS-RO " + ciusROVersion + " currencies)", UpdatedAt: ciusROPublicationDate},
+	{Component: DataComponentCountryCodes, Version: "CIUS-RO " + ciusROVersion, UpdatedAt: ciusROPublicationDate},
+	{Component: DataComponentCountrySubentityCodes, Version: "CIUS-RO " + ciusROVersion, UpdatedAt: ciusROPublicationDate},
+	{Component: DataComponentValidationRules, Version: "Go subset of EN 16931 and CIUS-RO " + ciusROVersion, UpdatedAt: types.MakeDate(2026, time.October, 16)},
+}
+
+var (
+	// en16931PublicationDate is the publication date of EN 16931-1:2017 in
+	// the Official Journal of the EU.
+	en16931PublicationDate = types.MakeDate(2017, time.October, 17)
+	// ciusROPublicationDate is the publication date of the CIUS-RO
+	// specifications.
+	ciusROPublicationDate = types.MakeDate(2021, time.November, 8)
+)
+
+// DataVersions returns the versions and update dates of the data sets (code
+// lists, specifications) embedded in this library, so that deployments can
+// assert they are running data fresh enough for the current ANAF
+// requirements. Only the data sets actually embedded are listed (eg. the
+// schematron rules are listed only when embedded). The returned slice is a
+// copy and can be modified by the caller.
+func DataVersions() []DataVersion {
+	dataVersionsMu.RLock()
+	defer dataVersionsMu.RUnlock()
+	versions := make([]DataVersion, len(dataVersions))
+	copy(versions, dataVersions)
+	return versions
+}
+
+// GetDataVersion returns the version of the given embedded data set. If the
+// data set is not embedded in this library, ok is false.
+func GetDataVersion(component DataComponent) (version DataVersion, ok bool) {
+	dataVersionsMu.RLock()
+	defer dataVersionsMu.RUnlock()
+	for _, v := range dataVersions {
+		if v.Component == component {
+			return v, true
+		}
+	}
+	return
+}
+
+// RegisterDataVersion records the version of a data set loaded at runtime
+// instead of being embedded (eg. the SIRUTA registry loaded by the siruta
+// package), so that it's listed by DataVersions and checked by
+// CheckDataFreshness. The version of the same component registered before
+// is replaced.
+func RegisterDataVersion(version DataVersion) {
+	dataVersionsMu.Lock()
+	defer dataVersionsMu.Unlock()
+	for i, v := range dataVersions {
+		if v.Component == version.Component {
+			dataVersions[i] = version
+			return
+		}
+	}
+	dataVersions = append(dataVersions, version)
+}
+
+// CheckDataFreshness returns an error listing all the embedded data sets that
+// were last updated before minDate. If all the data sets are fresh enough,
+// nil is returned.
+func CheckDataFreshness(minDate types.Date) error {
+	var stale []string
+	for _, v := range DataVersions() {
+		if v.UpdatedAt.Before(minDate.Time) {
+			stale = append(stale, v.String())
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("embedded data older than %s: %s",
+			minDate.Format(time.DateOnly), strings.Join(stale, ", "))
+	}
+	return nil
+}
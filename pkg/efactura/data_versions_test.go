@@ -0,0 +1,65 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestDataVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	versions := efactura.DataVersions()
+	if !assert.NotEmpty(versions) {
+		return
+	}
+	components := make(map[efactura.DataComponent]bool)
+	for _, v := range versions {
+		assert.NotEmpty(v.Version, "component %s", v.Component)
+		assert.False(v.UpdatedAt.IsZero(), "component %s", v.Component)
+		assert.False(components[v.Component], "duplicate component %s", v.Component)
+		components[v.Component] = true
+	}
+
+	// The returned slice must be a copy.
+	versions[0].Version = "modified"
+	assert.NotEqual("modified", efactura.DataVersions()[0].Version)
+
+	cius, ok := efactura.GetDataVersion(efactura.DataComponentCIUSRO)
+	if assert.True(ok) {
+		assert.Equal("1.0.1", cius.Version)
+		assert.Contains(efactura.CIUSRO_v101, cius.Version)
+	}
+	_, ok = efactura.GetDataVersion(efactura.DataComponentValidationRules)
+	assert.True(ok)
+	_, ok = efactura.GetDataVersion("unknown")
+	assert.False(ok)
+
+	assert.NoError(efactura.CheckDataFreshness(types.MakeDate(2013, time.January, 1)))
+	// The embedded code lists are older than the CIUS-RO specifications.
+	err := efactura.CheckDataFreshness(types.MakeDate(2021, time.January, 1))
+	if assert.Error(err) {
+		assert.Contains(err.Error(), string(efactura.DataComponentInvoiceTypeCodes))
+		assert.NotContains(err.Error(), string(efactura.DataComponentTaxExemptionReasonCodes))
+	}
+	assert.ErrorContains(efactura.CheckDataFreshness(types.MakeDate(2100, time.January, 1)),
+		string(efactura.DataComponentCIUSRO))
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/printesoi/xml-go"
+
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+const (
+	xmlnsUBLCreditNote2 = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+	xmlnsCII            = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	xmlnsRaspMessage    = "mfp:anaf:dgti:spv:reqMesaj:v1"
+)
+
+// ErrDocumentTypeConflict is returned by RegisterDocumentType if the document
+// type name or root element is already registered (or is a built-in
+// document type).
+var ErrDocumentTypeConflict = errors.New("document type already registered")
+
+// DocumentType describes a custom XML document type, identified by the
+// namespace and the local name of the XML root element. Registered document
+// types are recognized by UnmarshalDownloadedInvoiceXML (and thus by
+// DownloadInvoiceParseZip) and by DetectUploadStandard.
+type DocumentType struct {
+	// Name is the unique name of the document type.
+	Name string
+	// Namespace is the namespace of the root element.
+	Namespace string
+	// LocalName is the local name of the root element. If empty, any root
+	// element from Namespace matches.
+	LocalName string
+	// UploadStandard is the standard used for uploading the documents of
+	// this type. If empty, the documents cannot be uploaded.
+	UploadStandard UploadStandard
+	// New returns a new value (a pointer) the XML document is unmarshaled
+	// into.
+	New func() any
+}
+
+// builtinDocumentTypes are the root elements handled by the library. They
+// cannot be overridden by custom document types.
+var builtinDocumentTypes = []DocumentType{
+	{Name: "Invoice", Namespace: xmlnsUBLInvoice2, LocalName: "Invoice", UploadStandard: UploadStandardUBL},
+	{Name: "CreditNote", Namespace: xmlnsUBLCreditNote2, LocalName: "CreditNote", UploadStandard: UploadStandardCN},
+	{Name: "CrossIndustryInvoice", Namespace: xmlnsCII, LocalName: "CrossIndustryInvoice", UploadStandard: UploadStandardCII},
+	{Name: "RaspMessage", Namespace: xmlnsRaspMessage, LocalName: "header", UploadStandard: UploadStandardRASP},
+	{Name: "InvoiceErrorMessage", Namespace: xmlnsMsgErrorV1, LocalName: "header"},
+}
+
+var documentTypes struct {
+	sync.RWMutex
+	types []DocumentType
+}
+
+func (dt DocumentType) matches(namespace, localName string) bool {
+	return dt.Namespace == namespace && (dt.LocalName == "" || dt.LocalName == localName)
+}
+
+func (dt DocumentType) overlaps(other DocumentType) bool {
+	return dt.Namespace == other.Namespace &&
+		(dt.LocalName == "" || other.LocalName == "" || dt.LocalName == other.LocalName)
+}
+
+// RegisterDocumentType registers a custom document type. It's safe to call
+// RegisterDocumentType concurrently, but it's usually called from an init
+// function. An error wrapping ErrDocumentTypeConflict is returned if a
+// document type with the same name or an overlapping root element is
+// already registered.
+func RegisterDocumentType(dt DocumentType) error {
+	if dt.Name == "" || dt.Namespace == "" {
+		return fmt.Errorf("invalid document type: name and namespace are required")
+	}
+	if dt.New == nil {
+		return fmt.Errorf("invalid document type %s: New is required", dt.Name)
+	}
+
+	documentTypes.Lock()
+	defer documentTypes.Unlock()
+	for _, types := range [][]DocumentType{builtinDocumentTypes, documentTypes.types} {
+		for _, other := range types {
+			if other.Name == dt.Name || other.overlaps(dt) {
+				return fmt.Errorf("%w: %s conflicts with %s", ErrDocumentTypeConflict, dt.Name, other.Name)
+			}
+		}
+	}
+	documentTypes.types = append(documentTypes.types, dt)
+	return nil
+}
+
+// MustRegisterDocumentType is like RegisterDocumentType but panics on error.
+func MustRegisterDocumentType(dt DocumentType) {
+	if err := RegisterDocumentType(dt); err != nil {
+		panic(err)
+	}
+}
+
+// UnregisterDocumentType removes the custom document type with the given
+// name. It returns false if no such document type was registered.
+func UnregisterDocumentType(name string) bool {
+	documentTypes.Lock()
+	defer documentTypes.Unlock()
+	for i, dt := range documentTypes.types {
+		if dt.Name == name {
+			documentTypes.types = append(documentTypes.types[:i], documentTypes.types[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// LookupDocumentType returns the custom document type registered for the
+// given root element.
+func LookupDocumentType(namespace, localName string) (dt DocumentType, ok bool) {
+	documentTypes.RLock()
+	defer documentTypes.RUnlock()
+	for _, t := range documentTypes.types {
+		if t.matches(namespace, localName) {
+			return t, true
+		}
+	}
+	return
+}
+
+// DocumentTypes returns the registered custom document types, sorted by
+// name.
+func DocumentTypes() []DocumentType {
+	documentTypes.RLock()
+	types := append([]DocumentType(nil), documentTypes.types...)
+	documentTypes.RUnlock()
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+	return types
+}
+
+// xmlRootName returns the name of the root element of the XML document.
+func xmlRootName(xmlData []byte) (name xml.Name, err error) {
+	var doc struct {
+		XMLName xml.Name
+	}
+	if err = pxml.UnmarshalXML(xmlData, &doc); err != nil {
+		return
+	}
+	return doc.XMLName, nil
+}
+
+// DetectUploadStandard returns the upload standard for the given XML
+// document (to be used with UploadXML), based on the root element: the
+// built-in standards (UBL, CN, CII, RASP) and the registered custom document
+// types with an upload standard are recognized.
+func DetectUploadStandard(xmlData []byte) (UploadStandard, error) {
+	name, err := xmlRootName(xmlData)
+	if err != nil {
+		return "", err
+	}
+	for _, dt := range builtinDocumentTypes {
+		if dt.matches(name.Space, name.Local) && dt.UploadStandard != "" {
+			return dt.UploadStandard, nil
+		}
+	}
+	if dt, ok := LookupDocumentType(name.Space, name.Local); ok && dt.UploadStandard != "" {
+		return dt.UploadStandard, nil
+	}
+	return "", fmt.Errorf("no upload standard for document %q (namespace %q)", name.Local, name.Space)
+}
+
+// DownloadedDocument is the parsed XML document from a downloaded zip
+// archive. Exactly one of Invoice, CreditNote, InvoiceError and Document is
+// set.
+type DownloadedDocument struct {
+	// Invoice is set if the XML is an invoice.
+	Invoice *Invoice
+	// CreditNote is set if the XML is a UBL credit note.
+	CreditNote *CreditNote
+	// InvoiceError is set if the XML is an invoice error message.
+	InvoiceError *InvoiceErrorMessage
+	// Document is set if the XML is a registered custom document type. It's
+	// the value returned by DocumentType.New.
+	Document any
+	// DocumentType is the name of the custom document type, if Document is
+	// set.
+	DocumentType string
+	// ParseIssues are the issues fixed while parsing the invoice in
+	// ParseModeLenient.
+	ParseIssues []ParseIssue
+}
+
+// UnmarshalDownloadedInvoiceXML unmarshals the XML file from a downloaded
+// zip archive (see DownloadInvoice), which can be an invoice, a credit note,
+// an invoice error message or a registered custom document type. The invoice
+// (or credit note) is parsed using ParseModeDefault, unless a different mode
+// is selected using ParseOptionMode.
+func UnmarshalDownloadedInvoiceXML(xmlData []byte, opts ...ParseOption) (*DownloadedDocument, error) {
+	var parseOpts parseOptions
+	for _, opt := range opts {
+		opt(&parseOpts)
+	}
+	return unmarshalDownloadedXML(xmlData, parseOpts)
+}
+
+func unmarshalDownloadedXML(xmlData []byte, parseOpts parseOptions) (*DownloadedDocument, error) {
+	// The document is converted to UTF-8 first, so that all the decoders
+	// below work for documents declared with another encoding.
+	xmlData, err := pxml.ConvertToUTF8(xmlData, parseOpts.charsetReader)
+	if err != nil {
+		return nil, err
+	}
+	if xmlData, err = StripXMLMarkup(xmlData, parseOpts.stripMarkup); err != nil {
+		return nil, err
+	}
+	mode := parseOpts.mode
+
+	name, err := xmlRootName(xmlData)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := new(DownloadedDocument)
+	switch name.Space {
+	case xmlnsUBLInvoice2:
+		iv := new(Invoice)
+		if doc.ParseIssues, err = unmarshalXMLMode(xmlData, iv, mode); err != nil {
+			return nil, err
+		}
+		doc.Invoice = iv
+
+	case xmlnsUBLCreditNote2:
+		cn := new(CreditNote)
+		if doc.ParseIssues, err = unmarshalXMLMode(xmlData, cn, mode); err != nil {
+			return nil, err
+		}
+		doc.CreditNote = cn
+
+	case xmlnsMsgErrorV1:
+		ie := new(InvoiceErrorMessage)
+		if err = pxml.UnmarshalXML(xmlData, ie); err != nil {
+			return nil, err
+		}
+		doc.InvoiceError = ie
+
+	default:
+		dt, ok := LookupDocumentType(name.Space, name.Local)
+		if !ok {
+			return nil, fmt.Errorf("invalid namespace for invoice/message: %q", name.Space)
+		}
+		v := dt.New()
+		if err = pxml.UnmarshalXML(xmlData, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", dt.Name, err)
+		}
+		doc.Document, doc.DocumentType = v, dt.Name
+	}
+	return doc, nil
+}
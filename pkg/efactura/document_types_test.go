@@ -0,0 +1,199 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/printesoi/xml-go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+const (
+	testDocumentNamespace = "urn:example:delivery-note:v1"
+	testDocumentXML       = `<?xml version="1.0" encoding="UTF-8"?>
+<DeliveryNote xmlns="urn:example:delivery-note:v1"><ID>DN-1</ID></DeliveryNote>`
+)
+
+type testDeliveryNote struct {
+	XMLName xml.Name `xml:"urn:example:delivery-note:v1 DeliveryNote"`
+	ID      string   `xml:"urn:example:delivery-note:v1 ID"`
+}
+
+func registerTestDocumentType(t *testing.T) {
+	t.Helper()
+	err := efactura.RegisterDocumentType(efactura.DocumentType{
+		Name:           "DeliveryNote",
+		Namespace:      testDocumentNamespace,
+		LocalName:      "DeliveryNote",
+		UploadStandard: "DN",
+		New:            func() any { return new(testDeliveryNote) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		efactura.UnregisterDocumentType("DeliveryNote")
+	})
+}
+
+func TestRegisterDocumentType(t *testing.T) {
+	assert := assert.New(t)
+
+	registerTestDocumentType(t)
+	dt, ok := efactura.LookupDocumentType(testDocumentNamespace, "DeliveryNote")
+	if assert.True(ok) {
+		assert.Equal("DeliveryNote", dt.Name)
+	}
+	_, ok = efactura.LookupDocumentType(testDocumentNamespace, "Other")
+	assert.False(ok)
+	if types := efactura.DocumentTypes(); assert.Len(types, 1) {
+		assert.Equal("DeliveryNote", types[0].Name)
+	}
+
+	newDoc := func() any { return new(testDeliveryNote) }
+	for _, dt := range []efactura.DocumentType{
+		{Name: "DeliveryNote", Namespace: "urn:example:other", New: newDoc},
+		{Name: "AnyDeliveryNote", Namespace: testDocumentNamespace, New: newDoc},
+		{Name: "MyInvoice", Namespace: "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2", LocalName: "Invoice", New: newDoc},
+	} {
+		err := efactura.RegisterDocumentType(dt)
+		assert.True(errors.Is(err, efactura.ErrDocumentTypeConflict), "document type %s", dt.Name)
+	}
+	assert.Error(efactura.RegisterDocumentType(efactura.DocumentType{Name: "NoNew", Namespace: "urn:example:x"}))
+	assert.Panics(func() {
+		efactura.MustRegisterDocumentType(efactura.DocumentType{Name: "DeliveryNote"})
+	})
+
+	assert.True(efactura.UnregisterDocumentType("DeliveryNote"))
+	assert.False(efactura.UnregisterDocumentType("DeliveryNote"))
+	_, err := efactura.UnmarshalDownloadedInvoiceXML([]byte(testDocumentXML))
+	assert.Error(err, "unregistered document type")
+}
+
+func TestUnmarshalDownloadedInvoiceXML(t *testing.T) {
+	assert := assert.New(t)
+
+	registerTestDocumentType(t)
+	doc, err := efactura.UnmarshalDownloadedInvoiceXML([]byte(testDocumentXML))
+	if assert.NoError(err) {
+		assert.Nil(doc.Invoice)
+		assert.Nil(doc.InvoiceError)
+		assert.Equal("DeliveryNote", doc.DocumentType)
+		if note, ok := doc.Document.(*testDeliveryNote); assert.True(ok) {
+			assert.Equal("DN-1", note.ID)
+		}
+	}
+
+	doc, err = efactura.UnmarshalDownloadedInvoiceXML([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:mesajEroriFactuta:v1" Index_incarcare="5001" Cif_emitent="123">
+<Error errorMessage="E: invalid"/></header>`))
+	if assert.NoError(err) {
+		assert.NotNil(doc.InvoiceError)
+		assert.Nil(doc.Document)
+	}
+}
+
+func TestDetectUploadStandard(t *testing.T) {
+	assert := assert.New(t)
+
+	registerTestDocumentType(t)
+	tests := []struct {
+		xml      string
+		standard efactura.UploadStandard
+	}{
+		{`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"/>`, efactura.UploadStandardUBL},
+		{`<CreditNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"/>`, efactura.UploadStandardCN},
+		{`<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"/>`, efactura.UploadStandardCII},
+		{`<header xmlns="mfp:anaf:dgti:spv:reqMesaj:v1"/>`, efactura.UploadStandardRASP},
+		{testDocumentXML, "DN"},
+	}
+	for _, tt := range tests {
+		standard, err := efactura.DetectUploadStandard([]byte(tt.xml))
+		if assert.NoError(err, tt.xml) {
+			assert.Equal(tt.standard, standard)
+		}
+	}
+	_, err := efactura.DetectUploadStandard([]byte(`<Unknown xmlns="urn:example:unknown"/>`))
+	assert.Error(err)
+	_, err = efactura.DetectUploadStandard([]byte(`<header xmlns="mfp:anaf:dgti:efactura:mesajEroriFactuta:v1"/>`))
+	assert.Error(err, "error messages cannot be uploaded")
+}
+
+func TestDownloadInvoiceParseZipCustomDocument(t *testing.T) {
+	assert := assert.New(t)
+
+	registerTestDocumentType(t)
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(newTestZip(t, 3001, testDocumentXML))
+	})
+
+	res, err := client.DownloadInvoiceParseZip(context.Background(), 3001)
+	if assert.NoError(err) {
+		assert.Nil(res.Invoice)
+		assert.Equal("DeliveryNote", res.DocumentType)
+		if note, ok := res.Document.(*testDeliveryNote); assert.True(ok) {
+			assert.Equal("DN-1", note.ID)
+		}
+	}
+}
+
+func TestDownloadInvoiceParseZipCharset(t *testing.T) {
+	assert := assert.New(t)
+
+	id, err := charmap.ISO8859_2.NewEncoder().String("BRAŞOV-Ţ1")
+	if !assert.NoError(err) {
+		return
+	}
+	invoiceXML := `<?xml version="1.0" encoding="ISO-8859-2"?>` +
+		`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" ` +
+		`xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">` +
+		`<cbc:ID>` + id + `</cbc:ID></Invoice>`
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(newTestZip(t, 3002, invoiceXML))
+	})
+
+	for _, mode := range []efactura.ParseMode{efactura.ParseModeDefault, efactura.ParseModeLenient} {
+		res, err := client.DownloadInvoiceParseZip(context.Background(), 3002, efactura.ParseOptionMode(mode))
+		if assert.NoError(err) && assert.NotNil(res.Invoice) {
+			assert.Equal("BRAŞOV-Ţ1", res.Invoice.ID)
+			// The raw XML is not changed.
+			assert.Equal(invoiceXML, string(res.InvoiceXML))
+		}
+	}
+
+	errCharset := errors.New("unsupported charset")
+	client, mux = setupTestClient(t, efactura.ClientCharsetReader(func(charset string, input io.Reader) (io.Reader, error) {
+		return nil, errCharset
+	}))
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(newTestZip(t, 3002, invoiceXML))
+	})
+	_, err = client.DownloadInvoiceParseZip(context.Background(), 3002)
+	assert.ErrorIs(err, errCharset)
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	ierrors "github.com/printesoi/e-factura-go/internal/errors"
+	api_helpers "github.com/printesoi/e-factura-go/internal/helpers/api"
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+	perrors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+const (
+	defaultDownloadBackoff    = time.Second
+	defaultDownloadMaxBackoff = 30 * time.Second
+)
+
+// ErrIncompleteDownload is returned by DownloadInvoice (when retries are
+// enabled with DownloadOptionRetry) if the zip archive was truncated or
+// failed the checksum verification on the last attempt.
+var ErrIncompleteDownload = errors.New("incomplete download")
+
+type downloadOptions struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// DownloadOption is an option for DownloadInvoice.
+type DownloadOption func(*downloadOptions)
+
+// DownloadOptionRetry enables retrying interrupted or corrupted downloads, up
+// to maxAttempts attempts in total, waiting backoff before the first retry and
+// doubling the wait before each subsequent retry (capped at 30s by default,
+// see DownloadOptionMaxBackoff). When retries are enabled, the zip archive is
+// verified (size and CRC-32 checksum of each file) and, if the server allows
+// it, an interrupted download is resumed using an HTTP Range request instead
+// of downloading the archive again. Only network errors, server errors (5xx)
+// and incomplete downloads are retried; error messages from ANAF (including
+// exceeded limits) are never retried.
+func DownloadOptionRetry(maxAttempts int, backoff time.Duration) DownloadOption {
+	return func(o *downloadOptions) {
+		o.maxAttempts = maxAttempts
+		o.backoff = backoff
+	}
+}
+
+// DownloadOptionMaxBackoff sets the maximum wait between retries.
+func DownloadOptionMaxBackoff(maxBackoff time.Duration) DownloadOption {
+	return func(o *downloadOptions) {
+		o.maxBackoff = maxBackoff
+	}
+}
+
+func (o downloadOptions) retryEnabled() bool {
+	return o.maxAttempts > 1
+}
+
+// backoffFor returns the wait before the retry following the given attempt
+// (1-based).
+func (o downloadOptions) backoffFor(attempt int) time.Duration {
+	backoff, maxBackoff := o.backoff, o.maxBackoff
+	if backoff <= 0 {
+		backoff = defaultDownloadBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultDownloadMaxBackoff
+	}
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// partialDownload is the state of an interrupted download that can be
+// resumed with a Range request.
+type partialDownload struct {
+	data         []byte
+	etag         string
+	lastModified string
+}
+
+// downloadInvoiceAttempt does a single download attempt, resuming the given
+// partial download if not nil. If the download is interrupted and the server
+// accepts Range requests, the returned partial download can be used to resume
+// the download.
+func (c *Client) downloadInvoiceAttempt(
+	ctx context.Context, downloadID int64, partial *partialDownload, verify bool,
+) (response *DownloadInvoiceResponse, resume *partialDownload, err error) {
+	query := url.Values{
+		"id": {strconv.FormatInt(downloadID, 10)},
+	}
+	var reqOpts []client.RequestOption
+	if partial != nil {
+		reqOpts = append(reqOpts, client.RequestOptionHeader("Range", fmt.Sprintf("bytes=%d-", len(partial.data))))
+		if partial.etag != "" {
+			reqOpts = append(reqOpts, client.RequestOptionHeader("If-Range", partial.etag))
+		} else if partial.lastModified != "" {
+			reqOpts = append(reqOpts, client.RequestOptionHeader("If-Range", partial.lastModified))
+		}
+	}
+	ctx, call := c.startCall(ctx, OperationDownload, http.MethodGet)
+	defer func() { call.finish(err) }()
+
+	if err = c.waitRateLimit(ctx, RateLimitGroupDownload); err != nil {
+		return
+	}
+	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, c.apiBase+apiPathDownload, query, nil, c.requestOptions(reqOpts...)...)
+	if err = er; err != nil {
+		return
+	}
+	call.request(req)
+
+	resp, er := c.apiClient.Do(req)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	call.response(resp)
+	if err = er; err != nil {
+		return
+	}
+
+	// If the response content type is application/json, then the download
+	// failed, otherwise we got the zip in response body
+	switch mediaType := api_helpers.ResponseMediaType(resp.Header); mediaType {
+	case api_helpers.MediaTypeApplicationJSON:
+		resError := new(DownloadInvoiceResponseError)
+		if err = api_helpers.UnmarshalReaderJSON(resp.Body, resError); err != nil {
+			err = ierrors.NewErrorResponseParse(resp, err, false)
+			return
+		}
+		if limit, ok := ierrors.ErrorMessageMatchLimitExceeded(resError.Error); ok {
+			err = ierrors.NewLimitExceededError(resp, limit, fmt.Errorf("%s: %s", resError.Title, resError.Error))
+			return
+		}
+		response = &DownloadInvoiceResponse{
+			Error:         resError,
+			Metadata:      MetadataFromContext(ctx).Clone(),
+			CorrelationID: correlation.FromContext(ctx),
+		}
+	case api_helpers.MediaTypeApplicationZIP:
+		var buf bytes.Buffer
+		if partial != nil && resp.StatusCode == http.StatusPartialContent {
+			if !contentRangeStartsAt(resp.Header.Get("Content-Range"), len(partial.data)) {
+				err = fmt.Errorf("%w: unexpected Content-Range %q", ErrIncompleteDownload, resp.Header.Get("Content-Range"))
+				return
+			}
+			buf.Write(partial.data)
+		}
+		n, er := buf.ReadFrom(resp.Body)
+		call.responseSize(int(n))
+		if er != nil {
+			if !verify {
+				err = ierrors.NewErrorResponseParse(resp, er, false)
+				return
+			}
+			err = fmt.Errorf("%w: %v", ErrIncompleteDownload, er)
+			if resp.Header.Get("Accept-Ranges") == "bytes" || resp.StatusCode == http.StatusPartialContent {
+				resume = &partialDownload{
+					data:         buf.Bytes(),
+					etag:         resp.Header.Get("ETag"),
+					lastModified: resp.Header.Get("Last-Modified"),
+				}
+			}
+			return
+		}
+		if verify {
+			if err = verifyZip(buf.Bytes()); err != nil {
+				return
+			}
+		}
+		response = &DownloadInvoiceResponse{
+			Zip:           buf.Bytes(),
+			Metadata:      MetadataFromContext(ctx).Clone(),
+			CorrelationID: correlation.FromContext(ctx),
+		}
+	case api_helpers.MediaTypeTextPlain:
+		err = ierrors.NewErrorResponseDetectType(resp)
+	default:
+		err = ierrors.NewErrorResponse(resp,
+			fmt.Errorf("expected %s or %s, got %s", api_helpers.MediaTypeApplicationJSON,
+				api_helpers.MediaTypeApplicationZIP, mediaType))
+	}
+	return
+}
+
+// contentRangeStartsAt checks if the Content-Range header value (eg.
+// "bytes 100-199/200") starts at the given offset.
+func contentRangeStartsAt(contentRange string, offset int) bool {
+	rangeSpec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return false
+	}
+	start, _, ok := strings.Cut(rangeSpec, "-")
+	return ok && start == strconv.Itoa(offset)
+}
+
+// verifyZip checks that data is a complete zip archive by reading all the
+// files, which verifies the CRC-32 checksum of each file.
+func verifyZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIncompleteDownload, err)
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrIncompleteDownload, f.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrIncompleteDownload, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// isRetryableDownloadError returns true if the download error is transient:
+// an incomplete download, a network error or a server error.
+func isRetryableDownloadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrIncompleteDownload) {
+		return true
+	}
+	var limitErr *perrors.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return false
+	}
+	var responseErr *perrors.ErrorResponse
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestDownloadInvoiceResume(t *testing.T) {
+	assert := assert.New(t)
+
+	zipData := newTestInvoiceZip(t, 3001)
+	half := len(zipData) / 2
+
+	client, mux := setupTestClient(t)
+	var ranges []string
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("ETag", `"v1"`)
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			assert.Equal(`"v1"`, r.Header.Get("If-Range"))
+			assert.Equal(fmt.Sprintf("bytes=%d-", half), rangeHeader)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", half, len(zipData)-1, len(zipData)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(zipData[half:])
+			return
+		}
+		// The connection is closed after writing only half of the body.
+		w.Header().Set("Content-Length", strconv.Itoa(len(zipData)))
+		w.Write(zipData[:half])
+	})
+
+	res, err := client.DownloadInvoice(context.Background(), 3001,
+		efactura.DownloadOptionRetry(3, time.Millisecond))
+	if assert.NoError(err) && assert.True(res.IsOk()) {
+		assert.Equal(zipData, res.Zip)
+	}
+	assert.Equal([]string{"", fmt.Sprintf("bytes=%d-", half)}, ranges)
+}
+
+func TestDownloadInvoiceRetryCorrupted(t *testing.T) {
+	assert := assert.New(t)
+
+	zipData := newTestInvoiceZip(t, 3001)
+	corrupted := append([]byte(nil), zipData...)
+	corrupted[40] ^= 0xff
+
+	client, mux := setupTestClient(t)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Empty(r.Header.Get("Range"))
+		w.Header().Set("Content-Type", "application/zip")
+		if calls == 1 {
+			w.Write(corrupted)
+			return
+		}
+		w.Write(zipData)
+	})
+
+	res, err := client.DownloadInvoice(context.Background(), 3001,
+		efactura.DownloadOptionRetry(3, time.Millisecond))
+	if assert.NoError(err) {
+		assert.Equal(zipData, res.Zip)
+	}
+	assert.Equal(2, calls)
+
+	// Without retries, the archive is returned as is.
+	calls = 0
+	res, err = client.DownloadInvoice(context.Background(), 3001)
+	if assert.NoError(err) {
+		assert.Equal(corrupted, res.Zip)
+	}
+	assert.Equal(1, calls)
+}
+
+func TestDownloadInvoiceRetryExhausted(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("not a zip"))
+	})
+
+	_, err := client.DownloadInvoice(context.Background(), 3001,
+		efactura.DownloadOptionRetry(3, time.Millisecond))
+	assert.True(errors.Is(err, efactura.ErrIncompleteDownload))
+	assert.Equal(3, calls)
+}
+
+func TestDownloadInvoiceNoRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("id") == "3002" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{
+			"eroare": "Nu aveti dreptul sa descarcati acest mesaj",
+			"titlu":  "Descarcare mesaj",
+		})
+	})
+
+	ctx := context.Background()
+	res, err := client.DownloadInvoice(ctx, 3001, efactura.DownloadOptionRetry(3, time.Millisecond))
+	if assert.NoError(err) {
+		assert.False(res.IsOk())
+	}
+	assert.Equal(1, calls)
+
+	calls = 0
+	_, err = client.DownloadInvoice(ctx, 3002, efactura.DownloadOptionRetry(3, time.Millisecond))
+	assert.Error(err)
+	assert.Equal(1, calls)
+}
+
+func TestDownloadInvoiceUnexpectedMediaType(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+
+	_, err := client.DownloadInvoice(context.Background(), 3001)
+	assert.ErrorContains(err, "expected application/json or application/zip, got text/html")
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"encoding/json"
+	"errors"
+
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+// This file contains the marshal/unmarshal helpers for the envelopes of the
+// e-factura REST APIs. The helpers produce and accept the same encoding as the
+// ANAF endpoints, so they can be used to store the responses or to simulate
+// the ANAF servers in tests.
+
+// MarshalUploadResponse returns the XML encoding (with the XML header
+// declaration) of the given UploadResponse, as returned by the upload
+// endpoint.
+func MarshalUploadResponse(r *UploadResponse) ([]byte, error) {
+	return pxml.MarshalXMLWithHeader(r)
+}
+
+// UnmarshalUploadResponse parses an UploadResponse from the XML returned by
+// the upload endpoint.
+func UnmarshalUploadResponse(data []byte) (*UploadResponse, error) {
+	r := new(UploadResponse)
+	if err := pxml.UnmarshalXML(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalGetMessageStateResponse returns the XML encoding (with the XML
+// header declaration) of the given GetMessageStateResponse, as returned by
+// the get message state endpoint.
+func MarshalGetMessageStateResponse(r *GetMessageStateResponse) ([]byte, error) {
+	return pxml.MarshalXMLWithHeader(r)
+}
+
+// UnmarshalGetMessageStateResponse parses a GetMessageStateResponse from the
+// XML returned by the get message state endpoint.
+func UnmarshalGetMessageStateResponse(data []byte) (*GetMessageStateResponse, error) {
+	r := new(GetMessageStateResponse)
+	if err := pxml.UnmarshalXML(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalInvoiceErrorMessage returns the XML encoding (with the XML header
+// declaration) of the given InvoiceErrorMessage, as stored in the zip
+// archive of an invoice with errors.
+func MarshalInvoiceErrorMessage(m *InvoiceErrorMessage) ([]byte, error) {
+	return pxml.MarshalXMLWithHeader(m)
+}
+
+// UnmarshalInvoiceErrorMessage parses an InvoiceErrorMessage from the XML
+// stored in the zip archive of an invoice with errors.
+func UnmarshalInvoiceErrorMessage(data []byte) (*InvoiceErrorMessage, error) {
+	m := new(InvoiceErrorMessage)
+	if err := pxml.UnmarshalXML(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MarshalMessagesListResponse returns the JSON encoding of the given
+// MessagesListResponse, as returned by the list messages endpoint.
+func MarshalMessagesListResponse(r *MessagesListResponse) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalMessagesListResponse parses a MessagesListResponse from the JSON
+// returned by the list messages endpoint.
+func UnmarshalMessagesListResponse(data []byte) (*MessagesListResponse, error) {
+	r := new(MessagesListResponse)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalMessagesListPaginationResponse returns the JSON encoding of the
+// given MessagesListPaginationResponse, as returned by the list messages with
+// pagination endpoint.
+func MarshalMessagesListPaginationResponse(r *MessagesListPaginationResponse) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalMessagesListPaginationResponse parses a
+// MessagesListPaginationResponse from the JSON returned by the list messages
+// with pagination endpoint.
+func UnmarshalMessagesListPaginationResponse(data []byte) (*MessagesListPaginationResponse, error) {
+	r := new(MessagesListPaginationResponse)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalValidateResponse returns the JSON encoding of the given
+// ValidateResponse, as returned by the validate XML endpoint.
+func MarshalValidateResponse(r *ValidateResponse) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalValidateResponse parses a ValidateResponse from the JSON returned
+// by the validate XML endpoint.
+func UnmarshalValidateResponse(data []byte) (*ValidateResponse, error) {
+	r := new(ValidateResponse)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalGeneratePDFResponseError returns the JSON encoding of the given
+// GeneratePDFResponseError, as returned by the XML-To-PDF endpoint.
+func MarshalGeneratePDFResponseError(r *GeneratePDFResponseError) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalGeneratePDFResponseError parses a GeneratePDFResponseError from
+// the JSON returned by the XML-To-PDF endpoint.
+func UnmarshalGeneratePDFResponseError(data []byte) (*GeneratePDFResponseError, error) {
+	r := new(GeneratePDFResponseError)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalValidateSignatureResponse returns the JSON encoding of the given
+// ValidateSignatureResponse, as returned by the validate signature endpoint.
+func MarshalValidateSignatureResponse(r *ValidateSignatureResponse) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalValidateSignatureResponse parses a ValidateSignatureResponse from
+// the JSON returned by the validate signature endpoint.
+func UnmarshalValidateSignatureResponse(data []byte) (*ValidateSignatureResponse, error) {
+	r := new(ValidateSignatureResponse)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// MarshalDownloadInvoiceResponseError returns the JSON encoding of the given
+// DownloadInvoiceResponseError. ANAF uses the same error envelope for the
+// errors of the download endpoint and for the limit exceeded errors of all
+// the endpoints, sometimes with a text/plain Content-Type.
+func MarshalDownloadInvoiceResponseError(r *DownloadInvoiceResponseError) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalDownloadInvoiceResponseError parses a DownloadInvoiceResponseError
+// from the given JSON. An error is returned if data is not a JSON object with
+// a non-empty "eroare" field (eg. a plain-text error message).
+func UnmarshalDownloadInvoiceResponseError(data []byte) (*DownloadInvoiceResponseError, error) {
+	r := new(DownloadInvoiceResponseError)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Error == "" {
+		return nil, errors.New("missing error message")
+	}
+	return r, nil
+}
@@ -0,0 +1,433 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	efactura_errors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+func TestUploadResponseEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		xml      string
+		ok       bool
+		index    int64
+		errorMsg string
+	}{{
+		name: "ok",
+		xml: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="3828"/>`,
+		ok:    true,
+		index: 3828,
+	}, {
+		name: "error",
+		xml: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="1">
+    <Errors errorMessage="Fisierul transmis nu este valid. org.xml.sax.SAXParseException; lineNumber: 1; columnNumber: 1"/>
+</header>`,
+		errorMsg: "Fisierul transmis nu este valid. org.xml.sax.SAXParseException; lineNumber: 1; columnNumber: 1",
+	}, {
+		name: "missing status",
+		xml:  `<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504"/>`,
+	}}
+	for _, test := range tests {
+		res, err := efactura.UnmarshalUploadResponse([]byte(test.xml))
+		if !assert.NoError(err, test.name) {
+			continue
+		}
+		assert.Equal(test.ok, res.IsOk(), test.name)
+		assert.Equal(test.index, res.GetUploadIndex(), test.name)
+		assert.Equal(test.errorMsg, res.GetFirstErrorMessage(), test.name)
+
+		data, err := efactura.MarshalUploadResponse(res)
+		if assert.NoError(err, test.name) {
+			res2, err := efactura.UnmarshalUploadResponse(data)
+			if assert.NoError(err, test.name) {
+				assert.Equal(res, res2, test.name)
+			}
+		}
+	}
+
+	_, err := efactura.UnmarshalUploadResponse([]byte(`Service Unavailable`))
+	assert.Error(err)
+}
+
+func TestGetMessageStateResponseEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name       string
+		xml        string
+		state      efactura.GetMessageStateCode
+		downloadID int64
+		errorMsg   string
+	}{{
+		name: "ok",
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="ok" id_descarcare="1234"/>`,
+		state:      efactura.GetMessageStateCodeOk,
+		downloadID: 1234,
+	}, {
+		name: "nok",
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="nok" id_descarcare="1235"/>`,
+		state:      efactura.GetMessageStateCodeNok,
+		downloadID: 1235,
+	}, {
+		name: "processing",
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="in prelucrare"/>`,
+		state: efactura.GetMessageStateCodeProcessing,
+	}, {
+		name: "invalid XML",
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="XML cu erori nepreluat de sistem">
+    <Errors errorMessage="E: validare esuata"/>
+</header>`,
+		state:    efactura.GetMessageStateCodeInvalidXML,
+		errorMsg: "E: validare esuata",
+	}, {
+		name: "no rights",
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1">
+    <Errors errorMessage="Nu aveti dreptul de inteorgare pentru id_incarcare= 18"/>
+</header>`,
+		errorMsg: "Nu aveti dreptul de inteorgare pentru id_incarcare= 18",
+	}}
+	for _, test := range tests {
+		res, err := efactura.UnmarshalGetMessageStateResponse([]byte(test.xml))
+		if !assert.NoError(err, test.name) {
+			continue
+		}
+		assert.Equal(test.state, res.State, test.name)
+		assert.Equal(test.downloadID, res.GetDownloadID(), test.name)
+		assert.Equal(test.errorMsg, res.GetFirstErrorMessage(), test.name)
+
+		data, err := efactura.MarshalGetMessageStateResponse(res)
+		if assert.NoError(err, test.name) {
+			res2, err := efactura.UnmarshalGetMessageStateResponse(data)
+			if assert.NoError(err, test.name) {
+				assert.Equal(res, res2, test.name)
+			}
+		}
+	}
+}
+
+func TestInvoiceErrorMessageEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	res, err := efactura.UnmarshalInvoiceErrorMessage([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:mesajEroriFactuta:v1" Index_incarcare="3828" Cif_emitent="123456789">
+    <Error errorMessage="E: validari globale eroare: [BR-RO-010] Numarul facturii trebuie sa contina cel putin un caracter numeric"/>
+</header>`))
+	if assert.NoError(err) {
+		assert.Equal(int64(3828), res.UploadIndex)
+		assert.Equal("123456789", res.CIFSeller)
+		if assert.Len(res.Errors, 1) {
+			assert.Contains(res.Errors[0].ErrorMessage, "BR-RO-010")
+		}
+
+		data, err := efactura.MarshalInvoiceErrorMessage(res)
+		if assert.NoError(err) {
+			res2, err := efactura.UnmarshalInvoiceErrorMessage(data)
+			if assert.NoError(err) {
+				assert.Equal(res, res2)
+			}
+		}
+	}
+}
+
+func TestMessagesListResponseEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		json     string
+		ok       bool
+		messages int
+		error    string
+	}{{
+		name: "messages",
+		json: `{
+			"mesaje": [{
+				"data_creare": "202401021504",
+				"cif": "123456789",
+				"id_solicitare": "3828",
+				"detalii": "Factura cu id_incarcare=3828 emisa de cif_emitent=123456789 pentru cif_beneficiar=987654321",
+				"tip": "FACTURA TRIMISA",
+				"id": "3001"
+			}],
+			"serial": "1234AA456",
+			"cui": "123456789",
+			"titlu": "Lista Mesaje disponibile din ultimele 1 zile"
+		}`,
+		ok:       true,
+		messages: 1,
+	}, {
+		// ANAF returns an error when there are no messages, but this is
+		// not an error for the client.
+		name:  "no messages",
+		json:  `{"eroare": "Nu exista mesaje in ultimele 1 zile", "titlu": "Lista Mesaje"}`,
+		ok:    true,
+		error: "Nu exista mesaje in ultimele 1 zile",
+	}, {
+		name:  "invalid CIF",
+		json:  `{"eroare": "CIF introdus= 123a nu este un numar", "titlu": "Lista Mesaje"}`,
+		error: "CIF introdus= 123a nu este un numar",
+	}}
+	for _, test := range tests {
+		res, err := efactura.UnmarshalMessagesListResponse([]byte(test.json))
+		if !assert.NoError(err, test.name) {
+			continue
+		}
+		assert.Equal(test.ok, res.IsOk(), test.name)
+		assert.Len(res.Messages, test.messages, test.name)
+		assert.Equal(test.error, res.Error, test.name)
+
+		data, err := efactura.MarshalMessagesListResponse(res)
+		if assert.NoError(err, test.name) {
+			res2, err := efactura.UnmarshalMessagesListResponse(data)
+			if assert.NoError(err, test.name) {
+				assert.Equal(res, res2, test.name)
+			}
+		}
+	}
+}
+
+func TestMessagesListPaginationResponseEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	res, err := efactura.UnmarshalMessagesListPaginationResponse([]byte(`{
+		"mesaje": [{
+			"data_creare": "202401021504",
+			"cif": "123456789",
+			"id_solicitare": "3828",
+			"detalii": "Factura cu id_incarcare=3828 emisa de cif_emitent=123456789 pentru cif_beneficiar=987654321",
+			"tip": "FACTURA TRIMISA",
+			"id": "3001"
+		}],
+		"numar_inregistrari_in_pagina": 1,
+		"numar_total_inregistrari_per_pagina": 500,
+		"numar_total_inregistrari": 501,
+		"numar_total_pagini": 2,
+		"index_pagina_curenta": 2,
+		"serial": "1234AA456",
+		"cui": "123456789",
+		"titlu": "Lista Mesaje disponibile din intervalul 02-01-2024 - 03-01-2024"
+	}`))
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+		assert.Len(res.Messages, 1)
+		assert.Equal(int64(1), res.RecordsInPage)
+		assert.Equal(int64(500), res.TotalRecordsPerPage)
+		assert.Equal(int64(501), res.TotalRecords)
+		assert.Equal(int64(2), res.TotalPages)
+		assert.Equal(int64(2), res.CurrentPageIndex)
+
+		data, err := efactura.MarshalMessagesListPaginationResponse(res)
+		if assert.NoError(err) {
+			res2, err := efactura.UnmarshalMessagesListPaginationResponse(data)
+			if assert.NoError(err) {
+				assert.Equal(res, res2)
+			}
+		}
+	}
+}
+
+func TestValidateResponseEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		json     string
+		ok       bool
+		firstMsg string
+	}{{
+		name: "ok",
+		json: `{"stare": "ok", "trace_id": "a3c0bd8a-5c70-4ef7-b6a4-8d0c6ffb7d17"}`,
+		ok:   true,
+	}, {
+		name: "nok",
+		json: `{
+			"stare": "nok",
+			"Messages": [{"message": "E: validari globale eroare: [BR-RO-010] Numarul facturii trebuie sa contina cel putin un caracter numeric"}],
+			"trace_id": "a3c0bd8a-5c70-4ef7-b6a4-8d0c6ffb7d17"
+		}`,
+		firstMsg: "E: validari globale eroare: [BR-RO-010] Numarul facturii trebuie sa contina cel putin un caracter numeric",
+	}}
+	for _, test := range tests {
+		res, err := efactura.UnmarshalValidateResponse([]byte(test.json))
+		if !assert.NoError(err, test.name) {
+			continue
+		}
+		assert.Equal(test.ok, res.IsOk(), test.name)
+		assert.Equal(test.firstMsg, res.GetFirstMessage(), test.name)
+
+		data, err := efactura.MarshalValidateResponse(res)
+		if assert.NoError(err, test.name) {
+			res2, err := efactura.UnmarshalValidateResponse(data)
+			if assert.NoError(err, test.name) {
+				assert.Equal(res, res2, test.name)
+			}
+		}
+	}
+}
+
+func TestGeneratePDFResponseErrorEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	res, err := efactura.UnmarshalGeneratePDFResponseError([]byte(`{
+		"stare": "nok",
+		"Messages": [{"message": "Fisierul transmis nu este valid"}],
+		"trace_id": "a3c0bd8a-5c70-4ef7-b6a4-8d0c6ffb7d17"
+	}`))
+	if assert.NoError(err) {
+		assert.Equal(efactura.CodeNok, res.State)
+		assert.Equal("Fisierul transmis nu este valid", res.GetFirstMessage())
+
+		data, err := efactura.MarshalGeneratePDFResponseError(res)
+		if assert.NoError(err) {
+			res2, err := efactura.UnmarshalGeneratePDFResponseError(data)
+			if assert.NoError(err) {
+				assert.Equal(res, res2)
+			}
+		}
+	}
+}
+
+func TestValidateSignatureResponseEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		json  string
+		valid bool
+	}{
+		{json: `{"msg": "Fișierele încărcate au fost validate cu succes, din perspectiva autenticității semnăturii aplicate și a sigiliului MF."}`, valid: true},
+		{json: `{"msg": "Fișierele încărcate NU au putut fi validate cu succes, din perspectiva autenticității semnăturii aplicate și a sigiliului MF."}`},
+	} {
+		res, err := efactura.UnmarshalValidateSignatureResponse([]byte(test.json))
+		if !assert.NoError(err) {
+			continue
+		}
+		assert.Equal(test.valid, res.IsValid())
+
+		data, err := efactura.MarshalValidateSignatureResponse(res)
+		if assert.NoError(err) {
+			res2, err := efactura.UnmarshalValidateSignatureResponse(data)
+			if assert.NoError(err) {
+				assert.Equal(res, res2)
+			}
+		}
+	}
+}
+
+func TestDownloadInvoiceResponseErrorEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	res, err := efactura.UnmarshalDownloadInvoiceResponseError([]byte(
+		`{"eroare": "Pentru id=3001 nu exista inregistrata nici o factura", "titlu": "Descarcare mesaj"}`))
+	if assert.NoError(err) {
+		assert.Equal("Pentru id=3001 nu exista inregistrata nici o factura", res.Error)
+		assert.Equal("Descarcare mesaj", res.Title)
+
+		data, err := efactura.MarshalDownloadInvoiceResponseError(res)
+		if assert.NoError(err) {
+			res2, err := efactura.UnmarshalDownloadInvoiceResponseError(data)
+			if assert.NoError(err) {
+				assert.Equal(res, res2)
+			}
+		}
+	}
+
+	// Plain-text errors and JSON objects without an error message are not
+	// error envelopes.
+	_, err = efactura.UnmarshalDownloadInvoiceResponseError([]byte(`The request was rejected`))
+	assert.Error(err)
+	_, err = efactura.UnmarshalDownloadInvoiceResponseError([]byte(`{"titlu": "Descarcare mesaj"}`))
+	assert.Error(err)
+}
+
+func TestClientOddResponses(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("id_incarcare") {
+		case "1":
+			// A valid envelope, built with the public marshal helper.
+			data, _ := efactura.MarshalGetMessageStateResponse(&efactura.GetMessageStateResponse{
+				State:      efactura.GetMessageStateCodeOk,
+				DownloadID: 3001,
+			})
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write(data)
+		case "2":
+			// The limit exceeded errors are JSON envelopes sent as
+			// text/plain.
+			data, _ := efactura.MarshalDownloadInvoiceResponseError(&efactura.DownloadInvoiceResponseError{
+				Error: "S-au facut deja 100 interogari de stare pentru mesajul cu id_incarcare=2 in cursul zilei",
+				Title: "Stare mesaj",
+			})
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(data)
+		case "3":
+			// Plain-text errors from the API gateway.
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, "The requested URL was rejected. Please consult with your administrator.")
+		case "4":
+			// Malformed XML.
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="ok"`)
+		}
+	})
+
+	ctx := context.Background()
+	res, err := client.GetMessageState(ctx, 1)
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+		assert.Equal(int64(3001), res.GetDownloadID())
+	}
+
+	_, err = client.GetMessageState(ctx, 2)
+	var limitErr *efactura_errors.LimitExceededError
+	if assert.True(errors.As(err, &limitErr)) {
+		assert.Equal(int64(100), limitErr.Limit)
+	}
+
+	_, err = client.GetMessageState(ctx, 3)
+	var errResp *efactura_errors.ErrorResponse
+	if assert.True(errors.As(err, &errResp)) {
+		assert.Equal(http.StatusServiceUnavailable, errResp.StatusCode)
+		assert.Contains(string(errResp.ResponseBody), "The requested URL was rejected")
+	}
+
+	_, err = client.GetMessageState(ctx, 4)
+	if assert.True(errors.As(err, &errResp)) {
+		assert.Equal(http.StatusOK, errResp.StatusCode)
+		assert.Error(errResp.Err)
+	}
+}
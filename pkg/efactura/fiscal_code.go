@@ -0,0 +1,154 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCIF is returned if a Romanian fiscal code (CUI/CIF or CNP) has an
+// invalid format or check digit.
+var ErrInvalidCIF = errors.New("invalid CIF")
+
+// CNPPlaceholder is the CNP accepted by ANAF for a buyer individual whose
+// CNP is not known (13 zeros).
+const CNPPlaceholder = "0000000000000"
+
+// FiscalCodeKind is the kind of a Romanian fiscal code.
+type FiscalCodeKind int
+
+const (
+	// FiscalCodeUnknown is the kind of an invalid fiscal code.
+	FiscalCodeUnknown FiscalCodeKind = iota
+	// FiscalCodeCUI is the unique registration code (CUI, also called CIF)
+	// of a legal entity: 2 - 10 digits, the last one being a check digit.
+	// The VAT identifier of a VAT registered entity is the CUI with the RO
+	// prefix.
+	FiscalCodeCUI
+	// FiscalCodeCNP is the personal numeric code (CNP) of an individual: 13
+	// digits, the last one being a check digit.
+	FiscalCodeCNP
+)
+
+// String returns the name of the fiscal code kind.
+func (k FiscalCodeKind) String() string {
+	switch k {
+	case FiscalCodeCUI:
+		return "CUI"
+	case FiscalCodeCNP:
+		return "CNP"
+	}
+	return "unknown"
+}
+
+var (
+	cuiControlKey = [9]int{7, 5, 3, 2, 1, 7, 5, 3, 2}
+	cnpControlKey = [12]int{2, 7, 9, 1, 4, 6, 3, 5, 8, 2, 7, 9}
+)
+
+// ParseCIF parses a Romanian fiscal code: a CUI (CIF), with or without the
+// RO prefix, or a CNP. The spaces and the case of the prefix are ignored. It
+// returns the fiscal code without the RO prefix and its kind. The check
+// digit is verified; CNPPlaceholder is also accepted as a CNP. A CNP with the
+// RO prefix is not valid, since the VAT identifier is always a CUI.
+func ParseCIF(cif string) (code string, kind FiscalCodeKind, err error) {
+	code = strings.ToUpper(strings.Join(strings.Fields(cif), ""))
+	code, vatID := strings.CutPrefix(code, "RO")
+	if code == "" || strings.TrimLeft(code, "0123456789") != "" {
+		return "", FiscalCodeUnknown, fmt.Errorf("%w: %q", ErrInvalidCIF, cif)
+	}
+
+	switch {
+	case len(code) == 13 && !vatID:
+		if code != CNPPlaceholder && !validCNP(code) {
+			return "", FiscalCodeUnknown, fmt.Errorf("%w: %q: wrong CNP check digit", ErrInvalidCIF, cif)
+		}
+		return code, FiscalCodeCNP, nil
+	case len(code) >= 2 && len(code) <= 10 && code[0] != '0':
+		if !validCUI(code) {
+			return "", FiscalCodeUnknown, fmt.Errorf("%w: %q: wrong check digit", ErrInvalidCIF, cif)
+		}
+		return code, FiscalCodeCUI, nil
+	}
+	return "", FiscalCodeUnknown, fmt.Errorf("%w: %q", ErrInvalidCIF, cif)
+}
+
+// NormalizeCIF returns the Romanian fiscal code (CUI/CIF or CNP) without the
+// RO prefix and spaces, or an error wrapping ErrInvalidCIF if the code is not
+// valid (see ParseCIF).
+func NormalizeCIF(cif string) (string, error) {
+	code, _, err := ParseCIF(cif)
+	return code, err
+}
+
+// validCUI verifies the check digit of a CUI of 2 - 10 digits.
+func validCUI(cui string) bool {
+	body := fmt.Sprintf("%09s", cui[:len(cui)-1])
+	sum := 0
+	for i, key := range cuiControlKey {
+		sum += int(body[i]-'0') * key
+	}
+	control := sum * 10 % 11
+	if control == 10 {
+		control = 0
+	}
+	return int(cui[len(cui)-1]-'0') == control
+}
+
+// validCNP verifies the format and the check digit of a CNP of 13 digits.
+func validCNP(cnp string) bool {
+	if cnp[0] == '0' {
+		return false
+	}
+	month := int(cnp[3]-'0')*10 + int(cnp[4]-'0')
+	day := int(cnp[5]-'0')*10 + int(cnp[6]-'0')
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return false
+	}
+	sum := 0
+	for i, key := range cnpControlKey {
+		sum += int(cnp[i]-'0') * key
+	}
+	control := sum % 11
+	if control == 10 {
+		control = 1
+	}
+	return int(cnp[12]-'0') == control
+}
+
+// validatePartyFiscalCode checks the VAT identifier or the tax registration
+// identifier of a party, if it is a Romanian fiscal code: it has the RO
+// prefix, or it has no prefix and the party has an address in Romania. The
+// identifiers with a foreign prefix (eg. HU12345678) are not checked.
+func validatePartyFiscalCode(taxScheme *InvoicePartyTaxScheme, country CountryCodeType) error {
+	if taxScheme == nil || taxScheme.CompanyID == "" {
+		return nil
+	}
+	id := strings.ToUpper(strings.TrimSpace(taxScheme.CompanyID))
+	if id == "" {
+		return nil
+	}
+	if !strings.HasPrefix(id, "RO") && (country != CountryCodeRO || !isDigit(id[0])) {
+		return nil
+	}
+	_, err := NormalizeCIF(id)
+	return err
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestParseCIF(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		cif  string
+		code string
+		kind FiscalCodeKind
+	}{
+		{"RO1234567897", "1234567897", FiscalCodeCUI},
+		{"1234567897", "1234567897", FiscalCodeCUI},
+		{" ro 18547290 3", "185472903", FiscalCodeCUI},
+		{"RO990", "990", FiscalCodeCUI},
+		{"1800101420010", "1800101420010", FiscalCodeCNP},
+		{"2951231401237", "2951231401237", FiscalCodeCNP},
+		{CNPPlaceholder, CNPPlaceholder, FiscalCodeCNP},
+	}
+	for _, test := range tests {
+		code, kind, err := ParseCIF(test.cif)
+		if assert.NoError(err, test.cif) {
+			assert.Equal(test.code, code, test.cif)
+			assert.Equal(test.kind, kind, test.cif)
+		}
+	}
+
+	for _, cif := range []string{
+		"",
+		"RO",
+		"RO1234567890",    // wrong check digit
+		"RO01234567",      // leading zero
+		"RO12345678901",   // too long for a CUI
+		"RO1800101420010", // CNP with the RO prefix
+		"1800101420011",   // wrong CNP check digit
+		"1801301420010",   // invalid month
+		"DE123456789",
+		"J40/12345/1998",
+	} {
+		_, kind, err := ParseCIF(cif)
+		assert.ErrorIs(err, ErrInvalidCIF, cif)
+		assert.Equal(FiscalCodeUnknown, kind, cif)
+	}
+
+	code, err := NormalizeCIF("ro12345674")
+	if assert.NoError(err) {
+		assert.Equal("12345674", code)
+	}
+	assert.Equal("CNP", FiscalCodeCNP.String())
+}
+
+func TestInvoiceBuilderFiscalCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	build := func(supplier InvoiceSupplierParty, customer InvoiceCustomerParty) error {
+		_, err := NewInvoiceBuilder("FCT-1").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(supplier).
+			WithCustomer(customer).
+			WithInvoiceLines([]InvoiceLine{line}).
+			Build()
+		return err
+	}
+
+	supplier, customer := getInvoiceSupplierParty(), getInvoiceCustomerParty()
+	supplier.TaxScheme.CompanyID = "RO1234567897"
+	customer.TaxScheme.CompanyID = "RO987456126"
+	assert.NoError(build(supplier, customer))
+
+	// A buyer individual identified by the CNP.
+	customer.TaxScheme = &InvoicePartyTaxScheme{CompanyID: "1800101420010"}
+	assert.NoError(build(supplier, customer))
+	customer.TaxScheme.CompanyID = CNPPlaceholder
+	assert.NoError(build(supplier, customer))
+
+	// A foreign buyer is not checked.
+	customer.PostalAddress.Country = Country{Code: CountryCodeDE}
+	customer.TaxScheme = &InvoicePartyTaxScheme{TaxScheme: TaxSchemeVAT, CompanyID: "DE123456789"}
+	assert.NoError(build(supplier, customer))
+
+	customer = getInvoiceCustomerParty()
+	customer.TaxScheme.CompanyID = "RO987456123"
+	err = build(supplier, customer)
+	assert.ErrorIs(err, ErrInvalidCIF)
+	assert.ErrorContains(err, "BT-48")
+
+	supplier.TaxScheme = &InvoicePartyTaxScheme{CompanyID: "1234567890"}
+	err = build(supplier, getInvoiceCustomerParty())
+	assert.ErrorIs(err, ErrInvalidCIF)
+	assert.ErrorContains(err, "BT-32")
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+)
+
+// Uploader uploads documents to e-factura and gets the state of the uploaded
+// messages. It is implemented by *Client.
+type Uploader interface {
+	UploadXML(ctx context.Context, xml io.Reader, st UploadStandard, cif string, opts ...UploadOption) (*UploadResponse, error)
+	Upload(ctx context.Context, doc Document, cif string, opts ...UploadOption) (*UploadResponse, error)
+	UploadInvoice(ctx context.Context, invoice Invoice, cif string, opts ...UploadOption) (*UploadResponse, error)
+	UploadCreditNote(ctx context.Context, creditNote CreditNote, cif string, opts ...UploadOption) (*UploadResponse, error)
+	UploadRaspMessage(ctx context.Context, msg RaspMessage, cif string) (*UploadResponse, error)
+	GetMessageState(ctx context.Context, uploadIndex int64) (*GetMessageStateResponse, error)
+}
+
+// Downloader downloads the invoices (and the invoice error messages) from
+// e-factura. It is implemented by *Client.
+type Downloader interface {
+	DownloadInvoice(ctx context.Context, downloadID int64, opts ...DownloadOption) (*DownloadInvoiceResponse, error)
+	DownloadInvoiceParseZip(ctx context.Context, downloadID int64, opts ...ParseOption) (*DownloadInvoiceParseZipResponse, error)
+}
+
+// MessageLister lists the e-factura messages of a CIF. It is implemented by
+// *Client.
+type MessageLister interface {
+	GetMessagesList(ctx context.Context, cif string, numDays int, msgType MessageFilterType) (*MessagesListResponse, error)
+	GetMessagesListPagination(ctx context.Context, cif string, startTs, endTs time.Time, page int64, msgType MessageFilterType) (*MessagesListPaginationResponse, error)
+	GetAllMessagesPagination(ctx context.Context, cif string, startTs, endTs time.Time, msgType MessageFilterType) (*MessagesListResponse, error)
+}
+
+// Validator validates the documents before the upload and the signatures of
+// the downloaded invoices. It is implemented by *Client.
+type Validator interface {
+	SignatureValidator
+
+	ValidateXML(ctx context.Context, xml io.Reader, st ValidateStandard) (*ValidateResponse, error)
+	ValidateInvoice(ctx context.Context, invoice Invoice) (*ValidateResponse, error)
+	ValidateSignature(ctx context.Context, invoiceXmlData, signatureXmlData []byte) (*ValidateSignatureResponse, error)
+}
+
+// API groups all the narrow interfaces implemented by *Client, for the code
+// that needs most of the e-factura APIs.
+type API interface {
+	Uploader
+	Downloader
+	MessageLister
+	Validator
+}
+
+var (
+	_ API = (*Client)(nil)
+
+	_ client.Response = (*UploadResponse)(nil)
+	_ client.Response = (*GetMessageStateResponse)(nil)
+	_ client.Response = (*MessagesListResponse)(nil)
+	_ client.Response = (*ValidateResponse)(nil)
+)
@@ -16,6 +16,7 @@ package efactura
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/printesoi/e-factura-go/pkg/types"
 	pxml "github.com/printesoi/e-factura-go/pkg/xml"
@@ -34,7 +35,7 @@ type Invoice struct {
 	// NOTE: this field will be automatically set to efactura.CIUSRO_v101 when
 	//       marshaled.
 	// Path: /Invoice/cbc:UBLVersionID
-	UBLVersionID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 UBLVersionID"`
+	UBLVersionID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 UBLVersionID,omitempty" json:"ublVersionID,omitempty"`
 	// ID: BT-24
 	// Term: Identificatorul specificaţiei
 	// Description: O identificare a specificaţiei care conţine totalitatea
@@ -44,126 +45,136 @@ type Invoice struct {
 	// NOTE: this field will be automatically set to efactura.UBLVersionID when
 	//       marshaled.
 	// Cardinality: 1..1
-	CustomizationID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CustomizationID"`
+	CustomizationID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CustomizationID" json:"customizationID,omitempty"`
+	// ID: BT-23
+	// Term: Tipul procesului de afaceri
+	// Description: Identifică contextul procesului de afaceri în care are
+	//     loc tranzacţia, pentru a permite Cumpărătorului să proceseze
+	//     factura într-un mod adecvat.
+	// Cardinality: 0..1
+	ProfileID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ProfileID,omitempty" json:"profileID,omitempty"`
 
 	// ID: BT-1
 	// Term: Numărul facturii
 	// Description: O identificare unică a facturii.
 	// Cardinality: 1..1
-	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 	// ID: BT-2
 	// Term: Data emiterii facturii
 	// Description: Data la care a fost emisă factura.
 	// Cardinality: 1..1
-	IssueDate types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueDate"`
+	IssueDate types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueDate" json:"issueDate,omitempty"`
 	// ID: BT-9
 	// Term: Data scadenţei
 	// Description: Data până la care trebuie făcută plata.
 	// Cardinality: 0..1
-	DueDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DueDate,omitempty"`
+	DueDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DueDate,omitempty" json:"dueDate,omitempty"`
 	// ID: BT-3
 	// Term: Codul tipului facturii
 	// Description: Un cod care specifică tipul funcţional al facturii.
 	// Cardinality: 1..1
-	InvoiceTypeCode InvoiceTypeCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 InvoiceTypeCode"`
+	InvoiceTypeCode InvoiceTypeCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 InvoiceTypeCode" json:"invoiceTypeCode,omitempty"`
 	// ID: BT-5
 	// Term: Codul monedei facturii
 	// Description: Moneda în care sunt exprimate toate sumele din factură,
 	//    cu excepţia sumei totale a TVA care este în moneda de contabilizare.
 	// Cardinality: 1..1
-	DocumentCurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DocumentCurrencyCode"`
+	DocumentCurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DocumentCurrencyCode" json:"documentCurrencyCode,omitempty"`
 	// ID: BT-6
 	// Term: Codul monedei de contabilizare a TVA
 	// Description: Moneda utilizată pentru contabilizarea şi declararea TVA
 	//     aşa cum se acceptă sau se cere în ţara Vânzătorului.
 	// Cardinality: 0..1
-	TaxCurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxCurrencyCode,omitempty"`
+	TaxCurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxCurrencyCode,omitempty" json:"taxCurrencyCode,omitempty"`
 	// ID: BT-19
 	// Term: Referinţa contabilă a cumpărătorului
 	// Cardinality: 0..1
-	AccountingCost string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AccountingCost,omitempty"`
+	AccountingCost string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AccountingCost,omitempty" json:"accountingCost,omitempty"`
 	// ID: BT-10
 	// Term: Referinţa Cumpărătorului
 	// Description: Un identificator atribuit de către Cumpărător utilizat
 	//     pentru circuitul intern al facturii.
 	// Cardinality: 0..1
-	BuyerReference string                 `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BuyerReference,omitempty"`
-	OrderReference *InvoiceOrderReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OrderReference,omitempty"`
+	BuyerReference string                 `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BuyerReference,omitempty" json:"buyerReference,omitempty"`
+	OrderReference *InvoiceOrderReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OrderReference,omitempty" json:"orderReference,omitempty"`
 	// ID: BG-1
 	// Term: COMENTARIU ÎN FACTURĂ
 	// Cardinality: 0..n
-	Note []InvoiceNote `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note,omitempty"`
+	Note []InvoiceNote `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note,omitempty" json:"note,omitempty"`
 	// ID: BG-14
 	// Term: Perioada de facturare
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     despre perioada de facturare.
 	// Cardinality: 0..1
-	InvoicePeriod *InvoicePeriod `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoicePeriod,omitempty"`
+	InvoicePeriod *InvoicePeriod `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoicePeriod,omitempty" json:"invoicePeriod,omitempty"`
 	// ID: BG-3
 	// Term: REFERINŢĂ LA O FACTURĂ ANTERIOARĂ
 	// Cardinality: 0..n
-	BillingReferences []InvoiceBillingReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 BillingReference,omitempty"`
+	BillingReferences []InvoiceBillingReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 BillingReference,omitempty" json:"billingReferences,omitempty"`
 	// ID: BT-16
 	// Term: Referinţa avizului de expediție
 	// Cardinality: 0..1
-	DespatchDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DespatchDocumentReference,omitempty"`
+	DespatchDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DespatchDocumentReference,omitempty" json:"despatchDocumentReference,omitempty"`
 	// ID: BT-15
 	// Term: Referinţa avizului de recepție
 	// Cardinality: 0..1
-	ReceiptDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ReceiptDocumentReference,omitempty"`
+	ReceiptDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ReceiptDocumentReference,omitempty" json:"receiptDocumentReference,omitempty"`
 	// ID: BT-17
 	// Term: Referinţa avizului de ofertă sau a lotului
 	// Cardinality: 0..1
-	OriginatorDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OriginatorDocumentReference,omitempty"`
+	OriginatorDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OriginatorDocumentReference,omitempty" json:"originatorDocumentReference,omitempty"`
 	// ID: BT-12
 	// Term: Referinţa contractului
 	// Cardinality: 0..1
-	ContractDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ContractDocumentReference,omitempty"`
+	ContractDocumentReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ContractDocumentReference,omitempty" json:"contractDocumentReference,omitempty"`
+	// ID: BG-24
+	// Term: DOCUMENTE JUSTIFICATIVE SUPLIMENTARE
+	// Cardinality: 0..n
 	// ID: BT-18
 	// Term: Identificatorul obiectului facturat
 	// Cardinality: 0..1
-	// ID: BT-18-1
-	// Term: Identificatorul obiectului schemei
-	// Cardinality: 0..1
-	AdditionalDocumentReference *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AdditionalDocumentReference,omitempty"`
+	AdditionalDocumentReferences []InvoiceAdditionalDocumentReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AdditionalDocumentReference,omitempty" json:"additionalDocumentReferences,omitempty"`
 	// ID: BT-11
 	// Term: Referinţa proiectului
 	// Cardinality: 0..1
-	ProjectReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ProjectReference,omitempty"`
+	ProjectReference *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ProjectReference,omitempty" json:"projectReference,omitempty"`
 	// ID: BG-4
 	// Term: VÂNZĂTOR
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     despre Vânzător.
 	// Cardinality: 1..1
-	Supplier InvoiceSupplier `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingSupplierParty"`
+	Supplier InvoiceSupplier `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingSupplierParty" json:"supplier,omitempty"`
 	// ID: BG-7
 	// Term: CUMPĂRĂTOR
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     despre Cumpărător.
 	// Cardinality: 1..1
-	Customer InvoiceCustomer `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingCustomerParty"`
+	Customer InvoiceCustomer `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AccountingCustomerParty" json:"customer,omitempty"`
 	// ID: BG-10
 	// Term: BENEFICIAR
 	// Cardinality: 0..1
-	Payee *InvoicePayee `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PayeeParty,omitempty"`
+	Payee *InvoicePayee `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PayeeParty,omitempty" json:"payee,omitempty"`
 	// ID: BG-11
 	// Term: REPREZENTANTUL FISCAL AL VÂNZĂTORULUI
 	// Cardinality: 0..1
-	TaxRepresentative *InvoiceTaxRepresentative `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxRepresentativeParty,omitempty"`
+	TaxRepresentative *InvoiceTaxRepresentative `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxRepresentativeParty,omitempty" json:"taxRepresentative,omitempty"`
 	// ID: BG-13
 	// Term: INFORMAȚII REFERITOARE LA LIVRARE
 	// Cardinality: 0..1
-	Delivery *InvoiceDelivery `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Delivery,omitempty"`
+	Delivery *InvoiceDelivery `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Delivery,omitempty" json:"delivery,omitempty"`
 	// ID: BG-16
 	// Term: INSTRUCŢIUNI DE PLATĂ
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     despre plată.
-	// Cardinality: 0..1
-	PaymentMeans *InvoicePaymentMeans `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentMeans,omitempty"`
+	// Cardinality: 0..n
+	// NOTE: this field was a *InvoicePaymentMeans before multiple payment
+	// means were supported. Code using the old field must use the first
+	// element, if any.
+	PaymentMeans []InvoicePaymentMeans `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentMeans,omitempty" json:"paymentMeans,omitempty"`
 	// ID: BT-20
 	// Term: Termeni de plată
 	// Cardinality: 0..1
-	PaymentTerms *InvoicePaymentTerms `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentTerms,omitempty"`
+	PaymentTerms *InvoicePaymentTerms `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentTerms,omitempty" json:"paymentTerms,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BG-20
 	// Term: DEDUCERI LA NIVELUL DOCUMENTULUI
@@ -172,27 +183,27 @@ type Invoice struct {
 	// ID: BG-21
 	// Term: TAXE SUPLIMENTARE LA NIVELUL DOCUMENTULUI
 	// Cardinality: 0..n
-	AllowanceCharges []InvoiceDocumentAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty"`
-	TaxTotal         []InvoiceTaxTotal                `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxTotal"`
+	AllowanceCharges []InvoiceDocumentAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty" json:"allowanceCharges,omitempty"`
+	TaxTotal         []InvoiceTaxTotal                `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxTotal" json:"taxTotal,omitempty"`
 	// ID: BG-22
 	// Term: TOTALURILE DOCUMENTULUI
 	// Cardinality: 1..1
-	LegalMonetaryTotal InvoiceLegalMonetaryTotal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 LegalMonetaryTotal"`
+	LegalMonetaryTotal InvoiceLegalMonetaryTotal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 LegalMonetaryTotal" json:"legalMonetaryTotal,omitempty"`
 	// ID: BG-25
 	// Term: LINIE A FACTURII
 	// Cardinality: 1..n
-	InvoiceLines []InvoiceLine `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoiceLine"`
+	InvoiceLines []InvoiceLine `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoiceLine" json:"invoiceLines,omitempty"`
 
 	// Name of node.
-	XMLName xml.Name `xml:"Invoice"`
+	XMLName xml.Name `xml:"Invoice" json:"-"`
 	// xmlns attr. Will be automatically set in MarshalXML
-	Namespace string `xml:"xmlns,attr"`
+	Namespace string `xml:"xmlns,attr" json:"-"`
 	// xmlns:cac attr. Will be automatically set in MarshalXML
-	NamespaceCAC string `xml:"xmlns:cac,attr"`
+	NamespaceCAC string `xml:"xmlns:cac,attr" json:"-"`
 	// xmlns:cbc attr. Will be automatically set in MarshalXML
-	NamespaceCBC string `xml:"xmlns:cbc,attr"`
+	NamespaceCBC string `xml:"xmlns:cbc,attr" json:"-"`
 	// generated with... Will be automatically set in MarshalXML if empty.
-	Comment string `xml:",comment"`
+	Comment string `xml:",comment" json:"comment,omitempty"`
 }
 
 // Prefill sets the  NS, NScac, NScbc and Comment properties for ensuring that
@@ -228,6 +239,12 @@ func (iv Invoice) XMLIndent(prefix, indent string) ([]byte, error) {
 	return pxml.MarshalIndentXMLWithHeader(iv, prefix, indent)
 }
 
+// XMLWithOptions works like XML, but the encoding can be changed with
+// options (eg. MarshalOptionStripMarkup or MarshalOptionIndent).
+func (iv Invoice) XMLWithOptions(opts ...MarshalOption) ([]byte, error) {
+	return marshalDocumentXML(iv, opts...)
+}
+
 // UnmarshalInvoice unmarshals an Invoice from XML data. Only use this method
 // for unmarshaling an Invoice, since the standard encoding/xml cannot
 // properly unmarshal a struct like Invoice due to namespace prefixes. This
@@ -237,24 +254,24 @@ func UnmarshalInvoice(xmlData []byte, invoice *Invoice) error {
 }
 
 type InvoiceBillingReference struct {
-	InvoiceDocumentReference InvoiceDocumentReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoiceDocumentReference"`
+	InvoiceDocumentReference InvoiceDocumentReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoiceDocumentReference" json:"invoiceDocumentReference,omitempty"`
 }
 
 type InvoiceDocumentReference struct {
 	// ID: BT-25
 	// Term: Identificatorul Vânzătorului
 	// Cardinality: 1..1
-	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 	// ID: BT-26
 	// Term: Data de emitere a facturii anterioare
 	// Description: Data emiterii facturii anterioare trebuie furnizată în
 	//     cazul în care identificatorul facturii anterioare nu este unic.
 	// Cardinality: 0..1
-	IssueDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueDate,omitempty"`
+	IssueDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IssueDate,omitempty" json:"issueDate,omitempty"`
 }
 
 type InvoiceSupplier struct {
-	Party InvoiceSupplierParty `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Party"`
+	Party InvoiceSupplierParty `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Party" json:"party,omitempty"`
 }
 
 func MakeInvoiceSupplier(party InvoiceSupplierParty) InvoiceSupplier {
@@ -262,22 +279,29 @@ func MakeInvoiceSupplier(party InvoiceSupplierParty) InvoiceSupplier {
 }
 
 type InvoiceSupplierParty struct {
+	// ID: BT-34
+	// Term: Adresa electronică a Vânzătorului
+	// Description: Identifică adresa electronică a Vânzătorului la care
+	//     poate fi livrată o factură.
+	// NOTE: the schemeID attribute (the EAS code) is required.
+	// Cardinality: 0..1
+	EndpointID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EndpointID,omitempty" json:"endpointID,omitempty"`
 	// ID: BT-29
 	// Term: Identificatorul Vânzătorului
 	// Cardinality: 0..n
-	Identifications []InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty"`
+	Identifications []InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty" json:"identifications,omitempty"`
 	// ID: BT-28
 	// Term: Denumirea comercială a Vânzătorului
 	// Description: Un nume sub care este cunoscut Vânzătorul, altul decât
 	//     numele Vânzătorului (cunoscut, de asemenea, ca denumirea comercială).
 	// Cardinality: 0..1
-	CommercialName *InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName,omitempty"`
+	CommercialName *InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName,omitempty" json:"commercialName,omitempty"`
 	// ID: BG-5
 	// Term: Adresa poștală a vânzătorului
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     despre adresa Vânzătorului.
 	// Cardinality: 1..1
-	PostalAddress InvoiceSupplierPostalAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PostalAddress"`
+	PostalAddress InvoiceSupplierPostalAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PostalAddress" json:"postalAddress,omitempty"`
 	// test[cac:PartyTaxScheme/cac:TaxScheme/cbc:ID == 'VAT'] ==>
 	// Field: TaxScheme.CompanyID
 	// ID: BT-31
@@ -294,15 +318,15 @@ type InvoiceSupplierParty struct {
 	//     Vânzătorului să demonstreze că este înregistrat la administraţia
 	//     fiscală.
 	// Cardinality: 0..1
-	TaxScheme   *InvoicePartyTaxScheme     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyTaxScheme,omitempty"`
-	LegalEntity InvoiceSupplierLegalEntity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyLegalEntity"`
+	TaxScheme   *InvoicePartyTaxScheme     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyTaxScheme,omitempty" json:"taxScheme,omitempty"`
+	LegalEntity InvoiceSupplierLegalEntity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyLegalEntity" json:"legalEntity,omitempty"`
 	// TODO:
 	// ID: BG-6
 	// Term: CONTACTUL VÂNZĂTORULUI
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     de contact despre Vânzător.
 	// Cardinality: 0..1
-	Contact *InvoiceSupplierContact `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Contact,omitempty"`
+	Contact *InvoiceSupplierContact `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Contact,omitempty" json:"contact,omitempty"`
 }
 
 type InvoiceSupplierLegalEntity struct {
@@ -313,22 +337,22 @@ type InvoiceSupplierLegalEntity struct {
 	//     de Contribuabil sau îşi exercită activităţile în calitate de persoană
 	//     sau grup de persoane.
 	// Cardinality: 1..1
-	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 RegistrationName"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 RegistrationName" json:"name,omitempty"`
 	// ID: BT-30
 	// Term: Identificatorul de înregistrare legală a Vânzătorului
 	// Description: Un identificator emis de un organism oficial de
 	//     înregistrare care identifică Vânzătorul ca o entitate sau persoană
 	//     juridică.
 	// Cardinality: 1..1
-	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty"`
+	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty" json:"companyID,omitempty"`
 	// ID: BT-33
 	// Term: Informaţii juridice suplimentare despre Vânzător
 	// Cardinality: 0..1
-	CompanyLegalForm string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyLegalForm,omitempty"`
+	CompanyLegalForm string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyLegalForm,omitempty" json:"companyLegalForm,omitempty"`
 }
 
 type InvoicePartyIdentification struct {
-	ID ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 }
 
 type InvoiceSupplierPostalAddress struct {
@@ -370,25 +394,25 @@ func MakeInvoiceSupplierPostalAddress(postalAddress PostalAddress) InvoiceSuppli
 // PostalAddress represents a generic postal address
 type PostalAddress struct {
 	// Adresă - Linia 1
-	Line1 string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 StreetName,omitempty"`
+	Line1 string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 StreetName,omitempty" json:"line1,omitempty"`
 	// Adresă - Linia 2
-	Line2 string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AdditionalStreetName,omitempty"`
+	Line2 string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AdditionalStreetName,omitempty" json:"line2,omitempty"`
 	// Adresă - Linia 3
 	// Description: O linie suplimentară într-o adresă care poate fi utilizată
 	//     pentru informaţii suplimentare şi completări la linia principală.
-	Line3 string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AddressLine,omitempty"`
+	Line3 string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AddressLine,omitempty" json:"line3,omitempty"`
 	// Numele uzual al municipiului, oraşului sau satului, în care se află adresa.
-	CityName string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CityName,omitempty"`
+	CityName string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CityName,omitempty" json:"cityName,omitempty"`
 	// Codul poştal
-	PostalZone string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PostalZone,omitempty"`
+	PostalZone string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PostalZone,omitempty" json:"postalZone,omitempty"`
 	// Subdiviziunea ţării
-	CountrySubentity CountrySubentityType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CountrySubentity,omitempty"`
+	CountrySubentity CountrySubentityType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CountrySubentity,omitempty" json:"countrySubentity,omitempty"`
 	// Codul țării
-	Country Country `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Country"`
+	Country Country `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Country" json:"country,omitempty"`
 }
 
 type Country struct {
-	Code CountryCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IdentificationCode"`
+	Code CountryCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 IdentificationCode" json:"code,omitempty"`
 }
 
 var (
@@ -405,21 +429,21 @@ type InvoiceSupplierContact struct {
 	//     juridică, cum ar fi numele persoanei, identificarea unui contact,
 	//     departament sau serviciu.
 	// Cardinality: 0..1
-	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name,omitempty"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name,omitempty" json:"name,omitempty"`
 	// ID: BT-42
 	// Term: Numărul de telefon al contactului Vânzătorului
 	// Description: Un număr de telefon pentru punctul de contact.
 	// Cardinality: 0..1
-	Phone string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Telephone,omitempty"`
+	Phone string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Telephone,omitempty" json:"phone,omitempty"`
 	// ID: BT-43
 	// Term: Adresa de email a contactului Vânzătorului
 	// Description: O adresă de e-mail pentru punctul de contact.
 	// Cardinality: 0..1
-	Email string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ElectronicMail,omitempty"`
+	Email string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ElectronicMail,omitempty" json:"email,omitempty"`
 }
 
 type InvoiceCustomer struct {
-	Party InvoiceCustomerParty `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Party"`
+	Party InvoiceCustomerParty `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Party" json:"party,omitempty"`
 }
 
 func MakeInvoiceCustomer(party InvoiceCustomerParty) InvoiceCustomer {
@@ -427,36 +451,43 @@ func MakeInvoiceCustomer(party InvoiceCustomerParty) InvoiceCustomer {
 }
 
 type InvoiceCustomerParty struct {
+	// ID: BT-49
+	// Term: Adresa electronică a Cumpărătorului
+	// Description: Identifică adresa electronică a Cumpărătorului la care
+	//     poate fi livrată o factură.
+	// NOTE: the schemeID attribute (the EAS code) is required.
+	// Cardinality: 0..1
+	EndpointID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EndpointID,omitempty" json:"endpointID,omitempty"`
 	// ID: BT-46
 	// Term: Identificatorul Cumpărătorului
 	// Cardinality: 0..n
-	Identifications []InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty"`
+	Identifications []InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty" json:"identifications,omitempty"`
 	// ID: BT-45
 	// Term: Denumirea comercială a Cumpărătorului
 	// Description: Un nume sub care este cunoscut Cumpărătorul, altul decât
 	//     numele Cumpărătorului (cunoscut, de asemenea, ca denumirea comercială).
 	// Cardinality: 0..1
-	CommercialName *InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName,omitempty"`
+	CommercialName *InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName,omitempty" json:"commercialName,omitempty"`
 	// ID: BG-8
 	// Term: Adresa poștală a Cumpărătorului
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     despre adresa Cumpărătorului.
 	// Cardinality: 1..1
-	PostalAddress InvoiceCustomerPostalAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PostalAddress"`
+	PostalAddress InvoiceCustomerPostalAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PostalAddress" json:"postalAddress,omitempty"`
 	// Field: TaxScheme.CompanyID
 	// ID: BT-48
 	// Term: Identificatorul de TVA al Cumpărătorului
 	// Description: Identificatorul de TVA al Cumpărătorului (cunoscut, de
 	//     asemenea, ca numărul de identificare de TVA al Cumpărătorului).
 	// Cardinality: 0..1
-	TaxScheme   *InvoicePartyTaxScheme     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyTaxScheme"`
-	LegalEntity InvoiceCustomerLegalEntity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyLegalEntity"`
+	TaxScheme   *InvoicePartyTaxScheme     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyTaxScheme" json:"taxScheme,omitempty"`
+	LegalEntity InvoiceCustomerLegalEntity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyLegalEntity" json:"legalEntity,omitempty"`
 	// ID: BG-9
 	// Term: Contactul Cumpărătorului
 	// Description: Un grup de termeni operaţionali care furnizează informaţii
 	//     de contact despre Cumpărător.
 	// Cardinality: 0..1
-	Contact *InvoiceCustomerContact `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Contact,omitempty"`
+	Contact *InvoiceCustomerContact `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Contact,omitempty" json:"contact,omitempty"`
 }
 
 type InvoiceCustomerLegalEntity struct {
@@ -464,14 +495,14 @@ type InvoiceCustomerLegalEntity struct {
 	// Term: Numele cumpărătorului
 	// Description: Numele complet al Cumpărătorului.
 	// Cardinality: 1..1
-	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 RegistrationName"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 RegistrationName" json:"name,omitempty"`
 	// ID: BT-47
 	// Term: Identificatorul de înregistrare legală a Cumpărătorului
 	// Description: Un identificator emis de un organism oficial de
 	//     înregistrare care identifică Cumpărătorul ca o entitate sau persoană
 	//     juridică.
 	// Cardinality: 1..1
-	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty"`
+	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty" json:"companyID,omitempty"`
 }
 
 type InvoiceCustomerPostalAddress struct {
@@ -516,50 +547,50 @@ type InvoiceCustomerContact struct {
 	//     juridică, cum ar fi numele persoanei, identificarea unui contact,
 	//     departament sau serviciu.
 	// Cardinality: 0..1
-	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name,omitempty"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name,omitempty" json:"name,omitempty"`
 	// ID: BT-57
 	// Term: Numărul de telefon al contactului Cumpărătorului
 	// Description: Un număr de telefon pentru punctul de contact.
 	// Cardinality: 0..1
-	Phone string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Telephone,omitempty"`
+	Phone string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Telephone,omitempty" json:"phone,omitempty"`
 	// ID: BT-58
 	// Term: Adresa de email a contactului Vânzătorului
 	// Description: O adresă de e-mail pentru punctul de contact.
 	// Cardinality: 0..1
-	Email string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ElectronicMail,omitempty"`
+	Email string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ElectronicMail,omitempty" json:"email,omitempty"`
 }
 
 type InvoicePayee struct {
 	// ID: BT-59
 	// Term: Numele Beneficiarului
 	// Cardinality: 1..1
-	Name InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName"`
+	Name InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName" json:"name,omitempty"`
 	// ID: BT-60 / BT-60-1
 	// Term: Identificatorul Beneficiarului / Identificatorul schemei
 	// Cardinality: 0..1 / 0..1
-	Identification *InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty"`
+	Identification *InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty" json:"identification,omitempty"`
 	// ID: BT-61
 	// Term: Identificatorul înregistrării legale a Beneficiarului
 	// Cardinality: 0..1
 	// ID: BT-61-1
 	// Term: Identificatorul schemei
 	// Cardinality: 0..1
-	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty"`
+	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty" json:"companyID,omitempty"`
 }
 
 type InvoiceTaxRepresentative struct {
 	// ID: BT-62
 	// Term: Numele reprezentantului fiscal al Vânzătorului
 	// Cardinality: 1..1
-	Name InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName"`
+	Name InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName" json:"name,omitempty"`
 	// ID: BT-63
 	// Term: Identificatorul de TVA al reprezentantului fiscal al Vânzătorului
 	// Cardinality: 1..1
-	TaxScheme InvoicePartyTaxScheme `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyTaxScheme"`
+	TaxScheme InvoicePartyTaxScheme `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyTaxScheme" json:"taxScheme,omitempty"`
 	// ID: BG-12
 	// Term: ADRESA POŞTALĂ A REPREZENTANTULUI FISCAL AL VÂNZĂTORULUI
 	// Cardinality: 1..1
-	PostalAddress InvoiceTaxRepresentativePostalAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PostalAddress"`
+	PostalAddress InvoiceTaxRepresentativePostalAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PostalAddress" json:"postalAddress,omitempty"`
 }
 
 type InvoiceTaxRepresentativePostalAddress struct {
@@ -599,14 +630,50 @@ func MakeInvoiceTaxRepresentativePostalAddress(postalAddress PostalAddress) Invo
 }
 
 type InvoiceDelivery struct {
+	// ID: BT-72
+	// Term: Data reală a livrării
+	// Cardinality: 0..1
+	ActualDeliveryDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ActualDeliveryDate,omitempty" json:"actualDeliveryDate,omitempty"`
+	// ID: BT-71 / BG-15
+	// Term: Identificatorul locului / ADRESA DE LIVRARE
+	// Cardinality: 0..1
+	DeliveryLocation *InvoiceDeliveryLocation `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DeliveryLocation,omitempty" json:"deliveryLocation,omitempty"`
 	// ID: BT-70
 	// Term: Numele părţii către care se face livrarea
 	// Cardinality: 0..1
-	Name *InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DeliveryParty,omitempty"`
-	// ID: BT-72
-	// Term: Data reală a livrării
+	DeliveryParty *InvoiceDeliveryParty `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DeliveryParty,omitempty" json:"deliveryParty,omitempty"`
+}
+
+// InvoiceDeliveryParty is the party to which the goods and services are
+// delivered, when it's not the buyer (eg. a third party that must be
+// identified by CUI or GLN).
+type InvoiceDeliveryParty struct {
+	// Term: Identificatorul părţii către care se face livrarea (eg. GLN, with
+	//     the scheme 0088)
+	// Cardinality: 0..n
+	Identifications []InvoicePartyIdentification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyIdentification,omitempty" json:"identifications,omitempty"`
+	// ID: BT-70
+	// Term: Numele părţii către care se face livrarea
 	// Cardinality: 0..1
-	ActualDeliveryDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ActualDeliveryDate,omitempty"`
+	Name *InvoicePartyName `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyName,omitempty" json:"name,omitempty"`
+	// Term: Entitatea juridică a părţii către care se face livrarea
+	// Cardinality: 0..1
+	LegalEntity *InvoiceDeliveryPartyLegalEntity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PartyLegalEntity,omitempty" json:"legalEntity,omitempty"`
+}
+
+// MakeInvoiceDeliveryParty returns an InvoiceDeliveryParty with the given
+// name (BT-70).
+func MakeInvoiceDeliveryParty(name string) InvoiceDeliveryParty {
+	return InvoiceDeliveryParty{Name: &InvoicePartyName{Name: name}}
+}
+
+type InvoiceDeliveryPartyLegalEntity struct {
+	// Term: Denumirea oficială a părţii către care se face livrarea
+	// Cardinality: 1..1
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 RegistrationName" json:"name,omitempty"`
+	// Term: Identificatorul de înregistrare legală (eg. CUI)
+	// Cardinality: 0..1
+	CompanyID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID,omitempty" json:"companyID,omitempty"`
 }
 
 type InvoiceDeliveryLocation struct {
@@ -616,11 +683,11 @@ type InvoiceDeliveryLocation struct {
 	// ID: BT-71-1
 	// Term: Identificatorul schemei
 	// Cardinality: 0..1
-	ID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty"`
+	ID *ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty" json:"id,omitempty"`
 	// ID: BG-15
 	// Term: ADRESA DE LIVRARE
 	// Cardinality: 0..1
-	DeliveryAddress *InvoiceDeliveryAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Address,omitempty"`
+	DeliveryAddress *InvoiceDeliveryAddress `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Address,omitempty" json:"deliveryAddress,omitempty"`
 }
 
 type InvoiceDeliveryAddress struct {
@@ -664,12 +731,39 @@ type InvoicePeriod struct {
 	// Term: Data de început a perioadei de facturare
 	// Description: Data la care începe perioada de facturare.
 	// Cardinality: 0..1
-	StartDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 StartDate,omitempty"`
+	StartDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 StartDate,omitempty" json:"startDate,omitempty"`
 	// ID: BT-74
 	// Term: Data de sfârșit a perioadei de facturare
 	// Description: Data la care sfârșește perioada de facturare.
 	// Cardinality: 0..1
-	EndDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EndDate,omitempty"`
+	EndDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EndDate,omitempty" json:"endDate,omitempty"`
+	// ID: BT-8
+	// Term: Codul datei de exigibilitate a taxei pe valoarea adăugată
+	// Description: Codul utilizat pentru a indica data la care TVA devine
+	//     exigibilă pentru Vânzător şi pentru Cumpărător (3, 35 sau 432).
+	// Cardinality: 0..1
+	DescriptionCode VATPointDateCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DescriptionCode,omitempty" json:"descriptionCode,omitempty"`
+}
+
+// VATPointDate returns the date when the VAT becomes accountable, as
+// indicated by the VAT point date code (BT-8): the issue date for
+// VATPointDateCodeIssueDate and the actual delivery date (BT-72) for
+// VATPointDateCodeDeliveryDate. ok is false if the code is not set, if the
+// date is not available in the invoice (eg. VATPointDateCodePaidToDate) or if
+// the actual delivery date is missing.
+func (iv Invoice) VATPointDate() (date types.Date, ok bool) {
+	if iv.InvoicePeriod == nil {
+		return
+	}
+	switch iv.InvoicePeriod.DescriptionCode {
+	case VATPointDateCodeIssueDate:
+		return iv.IssueDate, true
+	case VATPointDateCodeDeliveryDate:
+		if iv.Delivery != nil && iv.Delivery.ActualDeliveryDate != nil {
+			return *iv.Delivery.ActualDeliveryDate, true
+		}
+	}
+	return
 }
 
 type InvoicePaymentMeans struct {
@@ -678,27 +772,87 @@ type InvoicePaymentMeans struct {
 	// Description: Cod care indică modul în care o platătrebuie să fie sau a
 	//     fost efectuată.
 	// Cardinality: 1..1
-	PaymentMeansCode PaymentMeansCode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PaymentMeansCode"`
+	PaymentMeansCode PaymentMeansCode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PaymentMeansCode" json:"paymentMeansCode,omitempty"`
 	// ID: BT-83
 	// Term: Aviz de plată
 	// Description: Valoare textuală utilizată pentru a stabili o legătură
 	//     între plată şi Factură, emisă de Vânzător.
 	// Cardinality: 0..1
-	PaymentID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PaymentID,omitempty"`
+	PaymentID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PaymentID,omitempty" json:"paymentID,omitempty"`
+	// ID: BG-18
+	// Term: INFORMAŢII DESPRE CARDUL DE PLATĂ
+	// Cardinality: 0..1
+	CardAccount *InvoiceCardAccount `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 CardAccount,omitempty" json:"cardAccount,omitempty"`
 	// ID: BG-17
 	// Term: VIRAMENT
 	// Cardinality: 0..n
-	PayeeFinancialAccounts []PayeeFinancialAccount `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PayeeFinancialAccount,omitempty"`
+	PayeeFinancialAccounts []PayeeFinancialAccount `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PayeeFinancialAccount,omitempty" json:"payeeFinancialAccounts,omitempty"`
+	// ID: BG-19
+	// Term: DEBITARE DIRECTĂ
+	// Cardinality: 0..1
+	PaymentMandate *InvoicePaymentMandate `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PaymentMandate,omitempty" json:"paymentMandate,omitempty"`
+}
+
+// InvoiceCardAccount is the information about the payment card used for the
+// payment of the invoice (BG-18).
+type InvoiceCardAccount struct {
+	// ID: BT-87
+	// Term: Numărul contului cardului de plată
+	// Description: Numărul de cont primar (PAN) al cardului utilizat pentru
+	//     plată. În conformitate cu standardele de securitate ale cardurilor
+	//     de plată, o factură nu ar trebui să includă niciodată un PAN
+	//     complet, ci doar ultimele 4 - 6 cifre.
+	// Cardinality: 1..1
+	PrimaryAccountNumberID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PrimaryAccountNumberID" json:"primaryAccountNumberID,omitempty"`
+	// Term: Identificatorul rețelei cardului
+	// Description: Rețeaua cardului (eg. VISA, MasterCard). Elementul este
+	//     obligatoriu în schema UBL, dar nu face parte din EN 16931.
+	// Cardinality: 1..1
+	NetworkID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 NetworkID" json:"networkID,omitempty"`
+	// ID: BT-88
+	// Term: Numele deţinătorului cardului de plată
+	// Cardinality: 0..1
+	HolderName string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 HolderName,omitempty" json:"holderName,omitempty"`
+}
+
+// InvoicePaymentMandate is the information about the direct debit (BG-19).
+// The bank assigned creditor identifier (BT-90) is a party identification of
+// the Seller or of the Payee (see MakeSEPACreditorIdentification).
+type InvoicePaymentMandate struct {
+	// ID: BT-89
+	// Term: Identificatorul referinţei mandatului
+	// Description: Identificator unic atribuit de către Beneficiarul plăţii
+	//     pentru referinţa mandatului de debitare directă.
+	// Cardinality: 0..1
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty" json:"id,omitempty"`
+	// ID: BT-91
+	// Term: Identificatorul contului debitat
+	// Description: Contul care urmează să fie debitat prin debitare directă.
+	// Cardinality: 0..1
+	PayerFinancialAccount *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 PayerFinancialAccount,omitempty" json:"payerFinancialAccount,omitempty"`
+}
+
+// SEPACreditorIdentificationScheme is the scheme ID of the bank assigned
+// creditor identifier (BT-90).
+const SEPACreditorIdentificationScheme = "SEPA"
+
+// MakeSEPACreditorIdentification creates the party identification of the
+// Seller (or of the Payee) for the bank assigned creditor identifier (BT-90),
+// used for the direct debit payments (see InvoicePaymentMandate).
+func MakeSEPACreditorIdentification(creditorID string) InvoicePartyIdentification {
+	return InvoicePartyIdentification{
+		ID: MakeValueWithScheme(creditorID, SEPACreditorIdentificationScheme),
+	}
 }
 
 type PaymentMeansCode struct {
-	Code PaymentMeansCodeType `xml:",chardata"`
+	Code PaymentMeansCodeType `xml:",chardata" json:"code,omitempty"`
 	// ID: BT-82
 	// Term: Explicaţii privind instrumentul de plată
 	// Description: Text care indică modul în care o plată trebuie să fie sau
 	//     a fost efectuată.
 	// Cardinality: 0..1
-	Name string `xml:"name,attr,omitempty"`
+	Name string `xml:"name,attr,omitempty" json:"name,omitempty"`
 }
 
 type PayeeFinancialAccount struct {
@@ -707,19 +861,25 @@ type PayeeFinancialAccount struct {
 	// Description: Un identificator unic al contului bancar de plată, la un
 	//     furnizor de servicii de plată la care se recomandă să se facă plata
 	// Cardinality: 1..1
-	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 	// ID: BT-85
 	// Term: Numele contului de plată
 	// Cardinality: 0..1
-	Name string `xml:"bc:Name,omitempty"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name,omitempty" json:"name,omitempty"`
+	// Term: Moneda contului de plată
+	// Description: Moneda în care este ţinut contul. Elementul este permis
+	//     de schema UBL, dar nu face parte din EN 16931, deci poate genera
+	//     un avertisment la validare.
+	// Cardinality: 0..1
+	CurrencyCode CurrencyCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CurrencyCode,omitempty" json:"currencyCode,omitempty"`
 	// ID: BT-86
 	// Term: Identificatorul furnizorului de servicii de plată.
 	// Cardinality: 0..1
-	FinancialInstitutionBranch *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 FinancialInstitutionBranch,omitempty"`
+	FinancialInstitutionBranch *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 FinancialInstitutionBranch,omitempty" json:"financialInstitutionBranch,omitempty"`
 }
 
 type InvoicePaymentTerms struct {
-	Note string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note"`
+	Note string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note" json:"note,omitempty"`
 }
 
 // InvoiceDocumentAllowanceCharge is a struct that encodes the
@@ -727,7 +887,7 @@ type InvoicePaymentTerms struct {
 type InvoiceDocumentAllowanceCharge struct {
 	// test[cbc:ChargeIndicator == false] => BG-20 deducere
 	// test[cbc:ChargeIndicator == true ] => BG-21 taxă suplimentară
-	ChargeIndicator bool `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeIndicator"`
+	ChargeIndicator bool `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeIndicator" json:"chargeIndicator,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-98
 	// Term: Codul motivului deducerii la nivelul documentului
@@ -736,7 +896,7 @@ type InvoiceDocumentAllowanceCharge struct {
 	// ID: BT-105
 	// Term: Codul motivului taxei suplimentare la nivelul documentului
 	// Cardinality: 0..1
-	AllowanceChargeReasonCode string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReasonCode,omitempty"`
+	AllowanceChargeReasonCode string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReasonCode,omitempty" json:"allowanceChargeReasonCode,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-97
 	// Term: Motivul deducerii la nivelul documentului
@@ -745,7 +905,7 @@ type InvoiceDocumentAllowanceCharge struct {
 	// ID: BT-104
 	// Term: Motivul taxei suplimentare la nivelul documentului
 	// Cardinality: 0..1
-	AllowanceChargeReason string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReason,omitempty"`
+	AllowanceChargeReason string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReason,omitempty" json:"allowanceChargeReason,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-92
 	// Term: Valoarea deducerii la nivelul documentului
@@ -756,7 +916,7 @@ type InvoiceDocumentAllowanceCharge struct {
 	// Term: Valoarea taxei suplimentare la nivelul documentului
 	// Description: fără TVA
 	// Cardinality: 1..1
-	Amount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount"`
+	Amount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount" json:"amount,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-93
 	// Term: Valoarea de bază a deducerii la nivelul documentului
@@ -771,7 +931,7 @@ type InvoiceDocumentAllowanceCharge struct {
 	//     procentajul taxei suplimentare la nivelul documentului, pentru a
 	//     calcula valoarea taxei suplimentare la nivelul documentului.
 	// Cardinality: 0..1
-	BaseAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount,omitempty"`
+	BaseAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount,omitempty" json:"baseAmount,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-94
 	// Term: Procentajul deducerii la nivelul documentului
@@ -786,7 +946,7 @@ type InvoiceDocumentAllowanceCharge struct {
 	//     taxei suplimentare la nivelul documentului, pentru a calcula
 	//     valoarea taxei suplimentare la nivelul documentului.
 	// Cardinality: 0..1
-	Percent *types.Decimal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 MultiplierFactorNumeric,omitempty"`
+	Percent *types.Decimal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 MultiplierFactorNumeric,omitempty" json:"percent,omitempty"`
 	// Field: TaxCategory.ID
 	// ID: BT-102
 	// Term: Codul categoriei de TVA pentru taxe suplimentare la nivelul
@@ -804,7 +964,7 @@ type InvoiceDocumentAllowanceCharge struct {
 	// ID: BT-105
 	// Term: Codul motivului taxei suplimentare la nivelul documentului
 	// Cardinality: 0..1
-	TaxCategory InvoiceTaxCategory `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxCategory"`
+	TaxCategory InvoiceTaxCategory `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxCategory" json:"taxCategory,omitempty"`
 }
 
 type InvoiceTaxTotal struct {
@@ -819,22 +979,22 @@ type InvoiceTaxTotal struct {
 	//     Valoarea TVA în moneda de contabilizare nu este utilizată în
 	//     calcularea totalurilor facturii.
 	// Cardinality: 0..1
-	TaxAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxAmount,omitempty"`
+	TaxAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxAmount,omitempty" json:"taxAmount,omitempty"`
 	// ID: BG-23
 	// Term: DETALIEREA TVA
 	// Cardinality: 1..n
-	TaxSubtotals []InvoiceTaxSubtotal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxSubtotal"`
+	TaxSubtotals []InvoiceTaxSubtotal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxSubtotal" json:"taxSubtotals,omitempty"`
 }
 
 type InvoiceTaxSubtotal struct {
 	// ID: BT-116
 	// Term: Baza de calcul pentru categoria de TVA
 	// Cardinality: 1..1
-	TaxableAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxableAmount"`
+	TaxableAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxableAmount" json:"taxableAmount,omitempty"`
 	// ID: BT-117
 	// Term: Valoarea TVA pentru fiecare categorie de TVA
 	// Cardinality: 1..1
-	TaxAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxAmount"`
+	TaxAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxAmount" json:"taxAmount,omitempty"`
 	// Field: TaxCategory.ID
 	// ID: BT-118
 	// Term: Codul categoriei de TVA
@@ -851,16 +1011,16 @@ type InvoiceTaxSubtotal struct {
 	// ID: BT-121
 	// Term: Codul motivului scutirii de TVA
 	// Cardinality: 0..1
-	TaxCategory InvoiceTaxCategory `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxCategory"`
+	TaxCategory InvoiceTaxCategory `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxCategory" json:"taxCategory,omitempty"`
 }
 
 // InvoiceTaxCategory is a struct that encodes a cac:TaxCategory node.
 type InvoiceTaxCategory struct {
-	ID                     TaxCategoryCodeType        `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
-	Percent                types.Decimal              `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Percent"`
-	TaxExemptionReason     string                     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxExemptionReason,omitempty"`
-	TaxExemptionReasonCode TaxExemptionReasonCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxExemptionReasonCode,omitempty"`
-	TaxScheme              TaxScheme                  `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxScheme"`
+	ID                     TaxCategoryCodeType        `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
+	Percent                types.Decimal              `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Percent" json:"percent,omitempty"`
+	TaxExemptionReason     string                     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxExemptionReason,omitempty" json:"taxExemptionReason,omitempty"`
+	TaxExemptionReasonCode TaxExemptionReasonCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxExemptionReasonCode,omitempty" json:"taxExemptionReasonCode,omitempty"`
+	TaxScheme              TaxScheme                  `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxScheme" json:"taxScheme,omitempty"`
 }
 
 // MarshalXML implements the xml.Marshaler interface. We use a custom
@@ -891,50 +1051,50 @@ type InvoiceLegalMonetaryTotal struct {
 	// ID: BT-106
 	// Term: Suma valorilor nete ale liniilor facturii
 	// Cardinality: 1..1
-	LineExtensionAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 LineExtensionAmount"`
+	LineExtensionAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 LineExtensionAmount" json:"lineExtensionAmount,omitempty"`
 	// ID: BT-109
 	// Term: Valoarea totală a facturii fără TVA
 	// Cardinality: 1..1
-	TaxExclusiveAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxExclusiveAmount"`
+	TaxExclusiveAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxExclusiveAmount" json:"taxExclusiveAmount,omitempty"`
 	// ID: BT-112
 	// Term: Valoarea totală a facturii cu TVA
 	// Cardinality: 1..1
-	TaxInclusiveAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxInclusiveAmount"`
+	TaxInclusiveAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 TaxInclusiveAmount" json:"taxInclusiveAmount,omitempty"`
 	// ID: BT-107
 	// Term: Suma deducerilor la nivelul documentului
 	// Cardinality: 0..1
-	AllowanceTotalAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceTotalAmount"`
+	AllowanceTotalAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceTotalAmount" json:"allowanceTotalAmount,omitempty"`
 	// ID: BT-108
 	// Term: Suma taxelor suplimentare la nivelul documentului
 	// Cardinality: 0..1
-	ChargeTotalAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeTotalAmount"`
+	ChargeTotalAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeTotalAmount" json:"chargeTotalAmount,omitempty"`
 	// ID: BT-113
 	// Term: Sumă plătită
 	// Cardinality: 0..1
-	PrepaidAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PrepaidAmount,omitempty"`
+	PrepaidAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PrepaidAmount,omitempty" json:"prepaidAmount,omitempty"`
 	// ID: BT-114
 	// Term: Valoare de rotunjire
 	// Description: Valoarea care trebuie adunată la totalul facturii pentru a
 	//     rotunji suma de plată.
 	// Cardinality: 0..1
-	PayableRoundingAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PayableRoundingAmount,omitempty"`
+	PayableRoundingAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PayableRoundingAmount,omitempty" json:"payableRoundingAmount,omitempty"`
 	// ID: BT-115
 	// Term: Suma de plată
 	// Cardinality: 1..1
-	PayableAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PayableAmount"`
+	PayableAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PayableAmount" json:"payableAmount,omitempty"`
 }
 
 type InvoiceLine struct {
 	// ID: BT-126
 	// Term: Identificatorul liniei facturii
 	// Cardinality: 1..1
-	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 	// ID: BT-127
 	// Term: Nota liniei facturii
 	// Description: O notă textuală care furnizează o informaţie nestructurată
 	//     care este relevantă pentru linia facturii.
 	// Cardinality: 0..1
-	Note string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note,omitempty"`
+	Note string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Note,omitempty" json:"note,omitempty"`
 	// ID: BT-129
 	// Term: Cantitatea facturată
 	// Description: Cantitatea articolelor (bunuri sau servicii) luate în
@@ -943,15 +1103,23 @@ type InvoiceLine struct {
 	// ID: BT-130
 	// Term: Codul unităţii de măsură a cantităţii facturate
 	// Cardinality: 1..1
-	InvoicedQuantity InvoicedQuantity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 InvoicedQuantity"`
+	InvoicedQuantity InvoicedQuantity `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 InvoicedQuantity" json:"invoicedQuantity,omitempty"`
 	// ID: BT-131
 	// Term: Valoarea netă a liniei facturii
 	// Cardinality: 1..1
-	LineExtensionAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 LineExtensionAmount"`
+	LineExtensionAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 LineExtensionAmount" json:"lineExtensionAmount,omitempty"`
 	// ID: BG-26
 	// Term: Perioada de facturare a liniei
 	// Cardinality: 0..1
-	InvoicePeriod *InvoiceLinePeriod `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoicePeriod,omitempty"`
+	InvoicePeriod *InvoiceLinePeriod `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 InvoicePeriod,omitempty" json:"invoicePeriod,omitempty"`
+	// ID: BT-132
+	// Term: Referinţa liniei comenzii
+	// Cardinality: 0..1
+	OrderLineReference *InvoiceOrderLineReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OrderLineReference,omitempty" json:"orderLineReference,omitempty"`
+	// ID: BT-128
+	// Term: Identificatorul obiectului liniei facturii
+	// Cardinality: 0..1
+	DocumentReference *InvoiceLineDocumentReference `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 DocumentReference,omitempty" json:"documentReference,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BG-27
 	// Term: DEDUCERI LA LINIA FACTURII
@@ -960,39 +1128,81 @@ type InvoiceLine struct {
 	// ID: BG-28
 	// Term: TAXE SUPLIMENTARE LA LINIA FACTURII
 	// Cardinality: 0..n
-	AllowanceCharges []InvoiceLineAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty"`
+	AllowanceCharges []InvoiceLineAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty" json:"allowanceCharges,omitempty"`
 	// ID: BG-31
 	// Term: INFORMAȚII PRIVIND ARTICOLUL
-	Item InvoiceLineItem `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Item"`
+	Item InvoiceLineItem `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Item" json:"item,omitempty"`
 	// ID: BG-29
 	// Term: DETALII ALE PREŢULUI
 	// Cardinality: 1..1
-	Price InvoiceLinePrice `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Price"`
+	Price InvoiceLinePrice `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 Price" json:"price,omitempty"`
+}
+
+// InvoiceOrderLineReference is a struct that encodes a cac:OrderLineReference
+// node, the reference to the line of the purchase order (BT-13) issued by
+// the buyer.
+type InvoiceOrderLineReference struct {
+	// ID: BT-132
+	// Term: Referinţa liniei comenzii
+	// Cardinality: 1..1
+	LineID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 LineID" json:"lineID,omitempty"`
+}
+
+// InvoiceLineDocumentReference is a struct that encodes a cac:DocumentReference
+// node at invoice line level, used for the invoice line object identifier
+// (BT-128), eg. a subscription or a meter number.
+type InvoiceLineDocumentReference struct {
+	// ID: BT-128
+	// Term: Identificatorul obiectului liniei facturii
+	// Cardinality: 1..1
+	// ID: BT-128-1
+	// Term: Identificatorul schemei
+	// Cardinality: 0..1
+	ID ValueWithAttrs `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
+	// Term: Codul tipului documentului
+	// Description: Trebuie să fie 130 pentru identificatorul obiectului
+	//     liniei facturii.
+	// Cardinality: 1..1
+	DocumentTypeCode string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 DocumentTypeCode" json:"documentTypeCode,omitempty"`
+}
+
+// MakeInvoiceLineObjectReference creates an InvoiceLineDocumentReference for
+// the invoice line object identifier (BT-128) with the given identifier and
+// an optional scheme ID (BT-128-1).
+func MakeInvoiceLineObjectReference(id, schemeID string) InvoiceLineDocumentReference {
+	ref := InvoiceLineDocumentReference{
+		ID:               MakeValueWithAttrs(id),
+		DocumentTypeCode: DocumentTypeCodeInvoicedObject,
+	}
+	if schemeID != "" {
+		ref.ID = MakeValueWithScheme(id, schemeID)
+	}
+	return ref
 }
 
 // InvoicedQuantity represents the quantity (of items) on an invoice line.
 type InvoicedQuantity struct {
-	Quantity types.Decimal `xml:",chardata"`
+	Quantity types.Decimal `xml:",chardata" json:"quantity,omitempty"`
 	// The unit of the quantity.
-	UnitCode UnitCodeType `xml:"unitCode,attr"`
+	UnitCode UnitCodeType `xml:"unitCode,attr" json:"unitCode,omitempty"`
 	// The quantity unit code list.
-	UnitCodeListID string `xml:"unitCodeListID,attr,omitempty"`
+	UnitCodeListID string `xml:"unitCodeListID,attr,omitempty" json:"unitCodeListID,omitempty"`
 	// The identification of the agency that maintains the quantity unit code
 	// list.
-	UnitCodeListAgencyID string `xml:"unitCodeListAgencyID,attr,omitempty"`
+	UnitCodeListAgencyID string `xml:"unitCodeListAgencyID,attr,omitempty" json:"unitCodeListAgencyID,omitempty"`
 	// The name of the agency which maintains the quantity unit code list.
-	UnitCodeListAgencyName string `xml:"unitCodeListAgencyName,attr,omitempty"`
+	UnitCodeListAgencyName string `xml:"unitCodeListAgencyName,attr,omitempty" json:"unitCodeListAgencyName,omitempty"`
 }
 
 type InvoiceLinePeriod struct {
 	// ID: BT-134
 	// Term: Data de început a perioadei de facturare a liniei facturii
 	// Cardinality: 0..1
-	StartDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 StartDate,omitempty"`
+	StartDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 StartDate,omitempty" json:"startDate,omitempty"`
 	// ID: BT-135
 	// Term: Data de sfârșit a perioadei de facturare
 	// Cardinality: 0..1
-	EndDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EndDate,omitempty"`
+	EndDate *types.Date `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 EndDate,omitempty" json:"endDate,omitempty"`
 }
 
 // InvoiceLineAllowanceCharge is a struct that encodes the cbc:AllowanceCharge
@@ -1000,7 +1210,7 @@ type InvoiceLinePeriod struct {
 type InvoiceLineAllowanceCharge struct {
 	// test[cbc:ChargeIndicator == false] => BG-27 deducere
 	// test[cbc:ChargeIndicator == true ] => BG-28 taxă suplimentară
-	ChargeIndicator bool `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeIndicator"`
+	ChargeIndicator bool `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeIndicator" json:"chargeIndicator,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-140
 	// Term: Codul motivului deducerii la linia facturii
@@ -1009,7 +1219,7 @@ type InvoiceLineAllowanceCharge struct {
 	// ID: BT-145
 	// Term: Codul motivului taxei suplimentare la linia facturii
 	// Cardinality: 0..1
-	AllowanceChargeReasonCode string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReasonCode,omitempty"`
+	AllowanceChargeReasonCode string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReasonCode,omitempty" json:"allowanceChargeReasonCode,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-139
 	// Term: Motivul deducerii la linia facturii
@@ -1018,7 +1228,7 @@ type InvoiceLineAllowanceCharge struct {
 	// ID: BT-144
 	// Term: Motivul taxei suplimentare la linia facturii
 	// Cardinality: 0..1
-	AllowanceChargeReason string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReason,omitempty"`
+	AllowanceChargeReason string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 AllowanceChargeReason,omitempty" json:"allowanceChargeReason,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-136
 	// Term: Valoarea deducerii la linia facturii
@@ -1029,7 +1239,7 @@ type InvoiceLineAllowanceCharge struct {
 	// Term: Valoarea taxei suplimentare la linia facturii
 	// Description: fără TVA
 	// Cardinality: 1..1
-	Amount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount"`
+	Amount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount" json:"amount,omitempty"`
 	// test[cbc:ChargeIndicator == false] =>
 	// ID: BT-137
 	// Term: Valoarea de bază a deducerii la linia facturii
@@ -1044,7 +1254,7 @@ type InvoiceLineAllowanceCharge struct {
 	//     procentajul taxei suplimentare la linia facturii, pentru a calcula
 	//     valoarea taxei suplimentare la linia facturii.
 	// Cardinality: 0..1
-	BaseAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount"`
+	BaseAmount *AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount" json:"baseAmount,omitempty"`
 }
 
 type InvoiceLinePrice struct {
@@ -1053,64 +1263,96 @@ type InvoiceLinePrice struct {
 	// Description: Preţul unui articol, exclusiv TVA, după aplicarea reducerii
 	//     la preţul articolului.
 	// Cardinality: 1..1
-	PriceAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PriceAmount"`
+	PriceAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PriceAmount" json:"priceAmount,omitempty"`
 	// ID: BT-149
 	// Term: Cantitatea de bază a preţului articolului
 	// Cardinality: 0..1
 	// ID: BT-150
 	// Term: Codul unităţii de măsură a cantităţii de bază a preţului articolului
 	// Cardinality: 0..1
-	BaseQuantity    *InvoicedQuantity                `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseQuantity,omitempty"`
-	AllowanceCharge *InvoiceLinePriceAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty"`
+	BaseQuantity    *InvoicedQuantity                `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseQuantity,omitempty" json:"baseQuantity,omitempty"`
+	AllowanceCharge *InvoiceLinePriceAllowanceCharge `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AllowanceCharge,omitempty" json:"allowanceCharge,omitempty"`
 }
 
 type InvoiceLineItem struct {
 	// ID: BT-154
 	// Term: Descrierea articolului
 	// Cardinality: 0..1
-	Description string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Description,omitempty"`
+	Description string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Description,omitempty" json:"description,omitempty"`
 	// ID: BT-153
 	// Term: Numele articolului
 	// Cardinality: 1..1
-	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name" json:"name,omitempty"`
+	// ID: BT-156
+	// Term: Identificatorul Cumpărătorului articolului
+	// Cardinality: 0..1
+	BuyerItemID *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 BuyersItemIdentification,omitempty" json:"buyerItemID,omitempty"`
 	// ID: BT-155
 	// Term: Identificatorul Vânzătorului articolului
 	// Cardinality: 0..1
-	SellerItemID *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 SellersItemIdentification,omitempty"`
+	SellerItemID *IDNode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 SellersItemIdentification,omitempty" json:"sellerItemID,omitempty"`
 	// ID: BT-157/BT-157-1
 	// Term: Identificatorul standard al articolului / Identificatorul schemei
-	StandardItemIdentification *ItemStandardIdentificationCode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 StandardItemIdentification,omitempty"`
+	StandardItemIdentification *ItemStandardIdentificationCode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 StandardItemIdentification,omitempty" json:"standardItemIdentification,omitempty"`
+	// ID: BT-159
+	// Term: Ţara de origine a articolului
+	// Cardinality: 0..1
+	OriginCountry *Country `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 OriginCountry,omitempty" json:"originCountry,omitempty"`
 	// ID: BT-158/BT-158-1
 	// Term: Identificatorul clasificării articolului / Identificatorul schemei
-	CommodityClassification *ItemCommodityClassification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 CommodityClassification,omitempty"`
+	CommodityClassification *ItemCommodityClassification `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 CommodityClassification,omitempty" json:"commodityClassification,omitempty"`
 	// ID: BG-30
 	// Term: INFORMAŢII PRIVIND TVA A LINIEI
-	TaxCategory InvoiceLineTaxCategory `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ClassifiedTaxCategory"`
+	TaxCategory InvoiceLineTaxCategory `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 ClassifiedTaxCategory" json:"taxCategory,omitempty"`
+	// ID: BG-32
+	// Term: ATRIBUTELE ARTICOLULUI
+	// Cardinality: 0..n
+	AdditionalProperties []InvoiceItemProperty `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 AdditionalItemProperty,omitempty" json:"additionalProperties,omitempty"`
+}
+
+// InvoiceItemProperty is a struct that encodes a cac:AdditionalItemProperty
+// node, an attribute of the invoiced item (BG-32), eg. the colour or the
+// size.
+type InvoiceItemProperty struct {
+	// ID: BT-160
+	// Term: Numele atributului articolului
+	// Cardinality: 1..1
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name" json:"name,omitempty"`
+	// ID: BT-161
+	// Term: Valoarea atributului articolului
+	// Cardinality: 1..1
+	Value string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Value" json:"value,omitempty"`
+}
+
+// MakeInvoiceItemProperty creates an InvoiceItemProperty with the given name
+// and value.
+func MakeInvoiceItemProperty(name, value string) InvoiceItemProperty {
+	return InvoiceItemProperty{Name: name, Value: value}
 }
 
 type ItemStandardIdentificationCode struct {
-	Code     string `xml:",chardata"`
-	SchemeID string `xml:"schemeID,attr"`
+	Code     string `xml:",chardata" json:"code,omitempty"`
+	SchemeID string `xml:"schemeID,attr" json:"schemeID,omitempty"`
 }
 
 // ItemCommodityClassification is a struct that encodes the
 // cac:CommodityClassification node at an invoice line level.
 type ItemCommodityClassification struct {
-	ItemClassificationCode ItemClassificationCode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ItemClassificationCode"`
+	ItemClassificationCode ItemClassificationCode `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ItemClassificationCode" json:"itemClassificationCode,omitempty"`
 }
 
 type ItemClassificationCode struct {
-	Code   string `xml:",chardata"`
-	ListID string `xml:"listID,attr,omitempty"`
+	Code   string `xml:",chardata" json:"code,omitempty"`
+	ListID string `xml:"listID,attr,omitempty" json:"listID,omitempty"`
 }
 
 type InvoicePartyName struct {
-	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name"`
+	Name string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Name" json:"name,omitempty"`
 }
 
 type InvoicePartyTaxScheme struct {
-	CompanyID string    `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID"`
-	TaxScheme TaxScheme `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxScheme"`
+	CompanyID string    `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 CompanyID" json:"companyID,omitempty"`
+	TaxScheme TaxScheme `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxScheme" json:"taxScheme,omitempty"`
 }
 
 // InvoiceTaxCategory is a struct that encodes a cac:ClassifiedTaxCategory node
@@ -1119,12 +1361,12 @@ type InvoiceLineTaxCategory struct {
 	// ID: BT-151
 	// Term: Codul categoriei de TVA a articolului facturat
 	// Cardinality: 1..1
-	ID TaxCategoryCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID TaxCategoryCodeType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 	// ID: BT-152
 	// Term: Cota TVA pentru articolul facturat
 	// Cardinality: 0..1
-	Percent   types.Decimal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Percent"`
-	TaxScheme TaxScheme     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxScheme"`
+	Percent   types.Decimal `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Percent" json:"percent,omitempty"`
+	TaxScheme TaxScheme     `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2 TaxScheme" json:"taxScheme,omitempty"`
 }
 
 // MarshalXML implements the xml.Marshaler interface. We use a custom
@@ -1150,50 +1392,75 @@ func (c InvoiceLineTaxCategory) MarshalXML(e *xml.Encoder, start xml.StartElemen
 type InvoiceLinePriceAllowanceCharge struct {
 	// test[cbc:ChargeIndicator == false] => deducere
 	// test[cbc:ChargeIndicator == true]  => taxă suplimentară
-	ChargeIndicator bool `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeIndicator"`
+	ChargeIndicator bool `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ChargeIndicator" json:"chargeIndicator,omitempty"`
 	// ID: BT-147
 	// Term: Reducere la prețul articolului
 	// Cardinality: 0..1
-	Amount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount"`
+	Amount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount" json:"amount,omitempty"`
 	// ID: BT-148
 	// Term: Preţul brut al articolului
 	// Cardinality: 0..1
-	BaseAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount"`
+	BaseAmount AmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount" json:"baseAmount,omitempty"`
 }
 
 type InvoiceOrderReference struct {
 	// ID: BT-13
 	// Term: Referinţa comenzii
 	// Cardinality: 0..1
-	OrderID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty"`
+	OrderID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty" json:"orderID,omitempty"`
 	// ID: BT-13
 	// Term: Referinţa comenzii
 	// Cardinality: 0..1
-	SalesOrderID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 SalesOrderID,omitempty"`
+	SalesOrderID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 SalesOrderID,omitempty" json:"salesOrderID,omitempty"`
 }
 
 type InvoiceNote struct {
 	// ID: BT-21
 	// Term: Codul subiectului comentariului din factură
 	// Cardinality: 0..1
-	SubjectCode InvoiceNoteSubjectCodeType
+	SubjectCode InvoiceNoteSubjectCodeType `json:"subjectCode,omitempty"`
 	// ID: BT-22
 	// Term: Comentariu în factură
 	// Cardinality: 1..1
-	Note string `xml:",chardata"`
+	Note string `xml:",chardata" json:"note,omitempty"`
+}
+
+// ParseInvoiceNote parses the text of an invoice note, extracting the
+// subject code (BT-21) from the "#CODE#text" prefix used by UBL. If the text
+// has no valid subject code prefix, the whole text is the note.
+func ParseInvoiceNote(s string) InvoiceNote {
+	if len(s) > 2 && s[0] == '#' {
+		if end := strings.IndexByte(s[1:], '#'); end >= 0 {
+			code := InvoiceNoteSubjectCodeType(s[1 : end+1])
+			if code.IsValid() {
+				return InvoiceNote{SubjectCode: code, Note: s[end+2:]}
+			}
+		}
+	}
+	return InvoiceNote{Note: s}
 }
 
+// String returns the text of the note as serialized in UBL, ie. prefixed
+// with "#CODE#" if the note has a subject code.
+func (n InvoiceNote) String() string {
+	if n.SubjectCode == "" {
+		return n.Note
+	}
+	return fmt.Sprintf("#%s#%s", n.SubjectCode, n.Note)
+}
+
+// MarshalXML implements the xml.Marshaler interface. The subject code is
+// serialized as a "#CODE#" prefix of the note text.
 func (n InvoiceNote) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	var xmlNote struct {
 		Note string `xml:",chardata"`
 	}
-	if n.SubjectCode != "" {
-		xmlNote.Note = fmt.Sprintf("#%s#", n.SubjectCode)
-	}
-	xmlNote.Note += n.Note
+	xmlNote.Note = n.String()
 	return e.EncodeElement(xmlNote, start)
 }
 
+// UnmarshalXML implements the xml.Unmarshaler interface, parsing the subject
+// code from the note text (see ParseInvoiceNote).
 func (n *InvoiceNote) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var xmlNote struct {
 		Note string `xml:",chardata"`
@@ -1201,12 +1468,12 @@ func (n *InvoiceNote) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 	if err := d.DecodeElement(&xmlNote, &start); err != nil {
 		return err
 	}
-	// TODO: implement parsing the code
+	*n = ParseInvoiceNote(xmlNote.Note)
 	return nil
 }
 
 type TaxScheme struct {
-	ID TaxSchemeIDType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty"`
+	ID TaxSchemeIDType `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID,omitempty" json:"id,omitempty"`
 }
 
 var (
@@ -0,0 +1,120 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func buildTestJSONInvoice(t *testing.T) Invoice {
+	t.Helper()
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(2)).
+		WithGrossPriceAmount(types.D(50)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("error building line: %v", err)
+	}
+	invoice, err := NewInvoiceBuilder("INV-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 3, 31)).
+		WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithInvoiceLines([]InvoiceLine{line}).
+		Build()
+	if err != nil {
+		t.Fatalf("error building invoice: %v", err)
+	}
+	invoice.Note = []InvoiceNote{{SubjectCode: "AAI", Note: "Nota"}}
+	invoice.Supplier.Party.Identifications = []InvoicePartyIdentification{{
+		ID: MakeValueWithScheme("5940000000003", "0088"),
+	}}
+	return invoice
+}
+
+func TestInvoiceJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := buildTestJSONInvoice(t)
+	jsonData, err := json.Marshal(invoice)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if assert.NoError(json.Unmarshal(jsonData, &fields)) {
+		// No XML namespaces.
+		assert.NotContains(fields, "namespace")
+		assert.NotContains(fields, "XMLName")
+		assert.NotContains(string(jsonData), "urn:oasis")
+		assert.JSONEq(`"INV-1"`, string(fields["id"]))
+		assert.JSONEq(`"2024-03-01"`, string(fields["issueDate"]))
+		assert.JSONEq(`"2024-03-31"`, string(fields["dueDate"]))
+		assert.JSONEq(`[{"subjectCode":"AAI","note":"Nota"}]`, string(fields["note"]))
+		assert.JSONEq(`{
+			"lineExtensionAmount": {"amount": "100.00", "currencyID": "RON"},
+			"taxExclusiveAmount": {"amount": "100.00", "currencyID": "RON"},
+			"taxInclusiveAmount": {"amount": "119.00", "currencyID": "RON"},
+			"payableAmount": {"amount": "119.00", "currencyID": "RON"}
+		}`, string(fields["legalMonetaryTotal"]))
+	}
+	assert.Contains(string(jsonData), `"identifications":[{"id":{"value":"5940000000003","attributes":{"schemeID":"0088"}}}]`)
+	assert.Contains(string(jsonData), `"invoicedQuantity":{"quantity":"2","unitCode":"H87"}`)
+
+	// The JSON round trip gives the same XML.
+	var decoded Invoice
+	if assert.NoError(json.Unmarshal(jsonData, &decoded)) {
+		xmlData, err := invoice.XML()
+		assert.NoError(err)
+		decodedXMLData, err := decoded.XML()
+		assert.NoError(err)
+		assert.Equal(string(xmlData), string(decodedXMLData))
+	}
+}
+
+func TestCreditNoteJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	creditNote := buildTestJSONInvoice(t).CreditNote()
+	jsonData, err := json.Marshal(creditNote)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(jsonData), `"creditNoteLines":[{"id":"1",`)
+
+	var decoded CreditNote
+	if assert.NoError(json.Unmarshal(jsonData, &decoded)) {
+		xmlData, err := creditNote.XML()
+		assert.NoError(err)
+		decodedXMLData, err := decoded.XML()
+		assert.NoError(err)
+		assert.Equal(string(xmlData), string(decodedXMLData))
+	}
+}
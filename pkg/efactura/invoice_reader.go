@@ -0,0 +1,217 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/printesoi/xml-go"
+
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+// InvoiceReader reads an Invoice sequentially from an io.Reader, decoding
+// the invoice lines one at a time instead of materializing the whole
+// InvoiceLines slice. This allows processing big invoices (eg. received
+// invoices with tens of thousands of lines) in constant memory:
+//
+//	r, err := efactura.NewInvoiceReader(f)
+//	if err != nil {
+//		// Handle error
+//	}
+//	for r.Next() {
+//		line := r.Line()
+//		// Process line
+//	}
+//	if err := r.Err(); err != nil {
+//		// Handle error
+//	}
+//	header := r.Invoice()
+//
+// An InvoiceReader is not safe for concurrent use.
+type InvoiceReader struct {
+	d     *xml.Decoder
+	root  xml.StartElement
+	iv    Invoice
+	line  InvoiceLine
+	lines int
+	err   error
+	done  bool
+}
+
+// NewInvoiceReader creates a new InvoiceReader reading from r. The XML
+// header and the root element are read, and an error is returned if the
+// document is not an Invoice. Documents not encoded as UTF-8 are decoded
+// using the CharsetReader set with pxml.SetCharsetReader.
+func NewInvoiceReader(r io.Reader) (*InvoiceReader, error) {
+	d := pxml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("efactura: no root element found")
+			}
+			return nil, err
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			if start.Name.Local != "Invoice" {
+				return nil, fmt.Errorf("efactura: expected Invoice root element, got %s", start.Name.Local)
+			}
+			return &InvoiceReader{d: d, root: start.Copy()}, nil
+		}
+	}
+}
+
+// Next decodes the next invoice line, which is then available through the
+// Line method. The other elements of the invoice found until the next line
+// are decoded into the invoice returned by Invoice. Next returns false when
+// there are no more lines or an error occurred (see Err).
+func (r *InvoiceReader) Next() bool {
+	if r.done {
+		return false
+	}
+	for {
+		t, err := r.d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			r.fail(err)
+			return false
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Space == xmlnsUBLcac && t.Name.Local == "InvoiceLine" {
+				r.line = InvoiceLine{}
+				if err := r.d.DecodeElement(&r.line, &t); err != nil {
+					r.fail(fmt.Errorf("efactura: invoice line %d: %w", r.lines+1, err))
+					return false
+				}
+				r.lines++
+				return true
+			}
+			if err := r.decodeHeaderElement(t); err != nil {
+				r.fail(err)
+				return false
+			}
+		case xml.EndElement:
+			// The end of the root element.
+			r.done = true
+			return false
+		case xml.Comment:
+			r.iv.Comment += string(t)
+		}
+	}
+}
+
+// Line returns the invoice line decoded by the last call to Next.
+func (r *InvoiceReader) Line() InvoiceLine {
+	return r.line
+}
+
+// Lines returns the number of invoice lines decoded so far.
+func (r *InvoiceReader) Lines() int {
+	return r.lines
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (r *InvoiceReader) Err() error {
+	return r.err
+}
+
+// Invoice returns the invoice without the lines (InvoiceLines is always
+// empty). In a valid UBL invoice all the other elements precede the lines,
+// so the invoice is complete after the first call to Next.
+func (r *InvoiceReader) Invoice() *Invoice {
+	return &r.iv
+}
+
+func (r *InvoiceReader) fail(err error) {
+	r.err, r.done = err, true
+}
+
+// decodeHeaderElement decodes an element other than InvoiceLine into the
+// invoice. The element is decoded as the only child of the root element,
+// so that the field mapping of the Invoice struct is used.
+func (r *InvoiceReader) decodeHeaderElement(start xml.StartElement) error {
+	tr := &elementTokenReader{d: r.d, root: r.root, start: start}
+	return xml.NewTokenDecoder(tr).Decode(&r.iv)
+}
+
+// elementTokenReader returns the tokens of the root start element, of an
+// element read from a Decoder (whose start element was already read) and
+// the root end element.
+type elementTokenReader struct {
+	d     *xml.Decoder
+	root  xml.StartElement
+	start xml.StartElement
+	state int
+	depth int
+}
+
+func (tr *elementTokenReader) Token() (xml.Token, error) {
+	switch tr.state {
+	case 0:
+		tr.state++
+		return tr.root, nil
+	case 1:
+		tr.state++
+		return tr.start.Copy(), nil
+	case 2:
+		t, err := tr.d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			tr.depth++
+		case xml.EndElement:
+			if tr.depth == 0 {
+				tr.state++
+			}
+			tr.depth--
+		}
+		return xml.CopyToken(t), nil
+	case 3:
+		tr.state++
+		return tr.root.End(), nil
+	}
+	return nil, io.EOF
+}
+
+// ReadInvoiceLines reads an Invoice from r, calling fn for each invoice
+// line instead of storing the lines in InvoiceLines. If fn returns an error,
+// reading stops and the error is returned. The returned invoice has all
+// the fields except InvoiceLines.
+func ReadInvoiceLines(r io.Reader, fn func(line InvoiceLine) error) (*Invoice, error) {
+	ir, err := NewInvoiceReader(r)
+	if err != nil {
+		return nil, err
+	}
+	for ir.Next() {
+		if err := fn(ir.Line()); err != nil {
+			return nil, err
+		}
+	}
+	if err := ir.Err(); err != nil {
+		return nil, err
+	}
+	return ir.Invoice(), nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceReader(t *testing.T) {
+	paths, err := fs.Glob(corpusFS, "testdata/corpus/invoice_*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			assert := assert.New(t)
+
+			data, err := corpusFS.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var expected Invoice
+			if !assert.NoError(UnmarshalInvoice(data, &expected)) {
+				return
+			}
+
+			var lines []InvoiceLine
+			invoice, err := ReadInvoiceLines(bytes.NewReader(data), func(line InvoiceLine) error {
+				lines = append(lines, line)
+				return nil
+			})
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(expected.InvoiceLines, lines)
+			expected.InvoiceLines = nil
+			assert.Equal(expected, *invoice)
+		})
+	}
+}
+
+func TestInvoiceReaderErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := corpusFS.ReadFile("testdata/corpus/invoice_standard.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errStop := errors.New("stop")
+	var calls int
+	_, err = ReadInvoiceLines(bytes.NewReader(data), func(line InvoiceLine) error {
+		calls++
+		return errStop
+	})
+	assert.ErrorIs(err, errStop)
+	assert.Equal(1, calls)
+
+	r, err := NewInvoiceReader(bytes.NewReader(data[:len(data)*3/4]))
+	if assert.NoError(err) {
+		for r.Next() {
+		}
+		assert.Error(r.Err())
+		assert.False(r.Next())
+	}
+
+	creditNote, err := corpusFS.ReadFile("testdata/corpus/credit_note.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewInvoiceReader(bytes.NewReader(creditNote))
+	assert.ErrorContains(err, "expected Invoice")
+
+	_, err = NewInvoiceReader(strings.NewReader(""))
+	assert.Error(err)
+}
@@ -0,0 +1,376 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestInvoiceDeliveryParty(t *testing.T) {
+	assert := assert.New(t)
+
+	party := MakeInvoiceDeliveryParty("Depozit Terț SRL")
+	party.Identifications = []InvoicePartyIdentification{{
+		ID: MakeValueWithScheme("5940000000003", "0088"),
+	}}
+	party.LegalEntity = &InvoiceDeliveryPartyLegalEntity{
+		Name:      "Depozit Terț SRL",
+		CompanyID: NewValueWithAttrs("RO12345678"),
+	}
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.Delivery = &InvoiceDelivery{
+		ActualDeliveryDate: types.NewDate(2024, 3, 1),
+		DeliveryLocation: &InvoiceDeliveryLocation{
+			DeliveryAddress: &InvoiceDeliveryAddress{PostalAddress: PostalAddress{
+				Line1:    "Str. Depozitului 1",
+				CityName: "Ploiești",
+				Country:  CountryRO,
+			}},
+		},
+		DeliveryParty: &party,
+	}
+
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(xmlData), "<cac:Delivery>"+
+		"<cbc:ActualDeliveryDate>2024-03-01</cbc:ActualDeliveryDate>"+
+		"<cac:DeliveryLocation><cac:Address>")
+	assert.Contains(string(xmlData), "</cac:DeliveryLocation>"+
+		"<cac:DeliveryParty>"+
+		`<cac:PartyIdentification><cbc:ID schemeID="0088">5940000000003</cbc:ID></cac:PartyIdentification>`+
+		"<cac:PartyName><cbc:Name>Depozit Terț SRL</cbc:Name></cac:PartyName>"+
+		"<cac:PartyLegalEntity><cbc:RegistrationName>Depozit Terț SRL</cbc:RegistrationName><cbc:CompanyID>RO12345678</cbc:CompanyID></cac:PartyLegalEntity>"+
+		"</cac:DeliveryParty></cac:Delivery>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) && assert.NotNil(parsed.Delivery) {
+		assert.Equal(invoice.Delivery.ActualDeliveryDate, parsed.Delivery.ActualDeliveryDate)
+		if assert.NotNil(parsed.Delivery.DeliveryParty) {
+			parsedParty := parsed.Delivery.DeliveryParty
+			if assert.Len(parsedParty.Identifications, 1) {
+				assert.Equal("5940000000003", parsedParty.Identifications[0].ID.Value)
+			}
+			assert.Equal("Depozit Terț SRL", parsedParty.Name.Name)
+			if assert.NotNil(parsedParty.LegalEntity) && assert.NotNil(parsedParty.LegalEntity.CompanyID) {
+				assert.Equal("RO12345678", parsedParty.LegalEntity.CompanyID.Value)
+			}
+		}
+		if assert.NotNil(parsed.Delivery.DeliveryLocation) && assert.NotNil(parsed.Delivery.DeliveryLocation.DeliveryAddress) {
+			assert.Equal("Ploiești", parsed.Delivery.DeliveryLocation.DeliveryAddress.CityName)
+		}
+	}
+}
+
+func TestInvoicePaymentMeans(t *testing.T) {
+	assert := assert.New(t)
+
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.PaymentMeans = []InvoicePaymentMeans{{
+		PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansCreditTransfer},
+		PaymentID:        "INV 1",
+		PayeeFinancialAccounts: []PayeeFinancialAccount{{
+			ID:           "RO49AAAA1B31007593840000",
+			CurrencyCode: CurrencyRON,
+		}},
+	}, {
+		PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansCreditTransfer},
+		PaymentID:        "INV 1",
+		PayeeFinancialAccounts: []PayeeFinancialAccount{{
+			ID:           "RO09BCYP0000001234567890",
+			CurrencyCode: CurrencyEUR,
+		}},
+	}}
+
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(2, strings.Count(string(xmlData), "<cac:PaymentMeans>"))
+	assert.Contains(string(xmlData), "<cac:PayeeFinancialAccount>"+
+		"<cbc:ID>RO49AAAA1B31007593840000</cbc:ID>"+
+		"<cbc:CurrencyCode>RON</cbc:CurrencyCode>"+
+		"</cac:PayeeFinancialAccount>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) && assert.Len(parsed.PaymentMeans, 2) {
+		assert.Equal(invoice.PaymentMeans, parsed.PaymentMeans)
+	}
+}
+
+func TestInvoicePaymentMeansCardAndDirectDebit(t *testing.T) {
+	assert := assert.New(t)
+
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.Supplier.Party.Identifications = []InvoicePartyIdentification{
+		MakeSEPACreditorIdentification("RO98ZZZ000000000001"),
+	}
+	invoice.PaymentMeans = []InvoicePaymentMeans{{
+		PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansBankCard},
+		CardAccount: &InvoiceCardAccount{
+			PrimaryAccountNumberID: "1234",
+			NetworkID:              "VISA",
+			HolderName:             "Ion Popescu",
+		},
+	}, {
+		PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansSEPADirectDebit},
+		PaymentID:        "INV 1",
+		PaymentMandate: &InvoicePaymentMandate{
+			ID:                    "MANDAT-7",
+			PayerFinancialAccount: NewIDNode("RO49AAAA1B31007593840000"),
+		},
+	}}
+
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(xmlData), `<cbc:ID schemeID="SEPA">RO98ZZZ000000000001</cbc:ID>`)
+	assert.Contains(string(xmlData), "<cac:CardAccount>"+
+		"<cbc:PrimaryAccountNumberID>1234</cbc:PrimaryAccountNumberID>"+
+		"<cbc:NetworkID>VISA</cbc:NetworkID>"+
+		"<cbc:HolderName>Ion Popescu</cbc:HolderName>"+
+		"</cac:CardAccount>")
+	assert.Contains(string(xmlData), "<cbc:PaymentID>INV 1</cbc:PaymentID>"+
+		"<cac:PaymentMandate>"+
+		"<cbc:ID>MANDAT-7</cbc:ID>"+
+		"<cac:PayerFinancialAccount><cbc:ID>RO49AAAA1B31007593840000</cbc:ID></cac:PayerFinancialAccount>"+
+		"</cac:PaymentMandate>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) {
+		assert.Equal(invoice.PaymentMeans, parsed.PaymentMeans)
+		assert.Equal(invoice.Supplier.Party.Identifications, parsed.Supplier.Party.Identifications)
+	}
+}
+
+func TestInvoiceLineItemAttributes(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(2)).
+		WithGrossPriceAmount(types.D(50)).
+		WithItemName("Tricou").
+		WithItemBuyerID("CLIENT-42").
+		WithItemSellerID("SKU-1").
+		WithItemOriginCountry(CountryCodeIT).
+		AppendItemProperty("Culoare", "Roșu").
+		AppendItemProperty("Mărime", "M").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("CLIENT-42", line.BuyerItemID())
+	assert.Equal(CountryCodeIT, line.OriginCountry())
+	value, ok := line.ItemProperty("Mărime")
+	assert.True(ok)
+	assert.Equal("M", value)
+	_, ok = line.ItemProperty("Material")
+	assert.False(ok)
+
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.InvoiceLines = []InvoiceLine{line}
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	// The nodes must follow the order of the UBL schema.
+	assert.Contains(string(xmlData), "<cbc:Name>Tricou</cbc:Name>"+
+		"<cac:BuyersItemIdentification><cbc:ID>CLIENT-42</cbc:ID></cac:BuyersItemIdentification>"+
+		"<cac:SellersItemIdentification><cbc:ID>SKU-1</cbc:ID></cac:SellersItemIdentification>"+
+		"<cac:OriginCountry><cbc:IdentificationCode>IT</cbc:IdentificationCode></cac:OriginCountry>"+
+		"<cac:ClassifiedTaxCategory>")
+	assert.Contains(string(xmlData), "</cac:ClassifiedTaxCategory>"+
+		"<cac:AdditionalItemProperty><cbc:Name>Culoare</cbc:Name><cbc:Value>Roșu</cbc:Value></cac:AdditionalItemProperty>"+
+		"<cac:AdditionalItemProperty><cbc:Name>Mărime</cbc:Name><cbc:Value>M</cbc:Value></cac:AdditionalItemProperty>"+
+		"</cac:Item>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) && assert.Len(parsed.InvoiceLines, 1) {
+		assert.Equal(line.Item, parsed.InvoiceLines[0].Item)
+	}
+
+	_, err = NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1)).
+		WithGrossPriceAmount(types.D(1)).
+		WithItemName("Tricou").
+		WithItemOriginCountry("XX").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	assert.Error(err)
+}
+
+func TestInvoiceLineReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	newLine := func() *InvoiceLineBuilder {
+		return NewInvoiceLineBuilder("1", CurrencyRON).
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(2)).
+			WithGrossPriceAmount(types.D(50)).
+			WithItemName("Produs").
+			WithItemTaxCategory(InvoiceLineTaxCategory{
+				TaxScheme: TaxSchemeVAT,
+				ID:        TaxCategoryVATStandardRate,
+				Percent:   types.D(19),
+			})
+	}
+	line, err := newLine().
+		WithOrderLineReference("3").
+		WithDocumentReference(MakeInvoiceLineObjectReference("AB12345", "ABZ")).
+		WithInvoicePeriod(&InvoiceLinePeriod{
+			StartDate: types.NewDate(2024, 3, 1),
+			EndDate:   types.NewDate(2024, 3, 31),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("3", line.OrderLineID())
+	assert.Equal("AB12345", line.ObjectID())
+
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.OrderReference = &InvoiceOrderReference{OrderID: "PO-1"}
+	invoice.InvoiceLines = []InvoiceLine{line}
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	// The nodes must follow the order of the UBL schema.
+	assert.Contains(string(xmlData), "</cac:InvoicePeriod>"+
+		"<cac:OrderLineReference><cbc:LineID>3</cbc:LineID></cac:OrderLineReference>"+
+		"<cac:DocumentReference>"+
+		`<cbc:ID schemeID="ABZ">AB12345</cbc:ID>`+
+		"<cbc:DocumentTypeCode>130</cbc:DocumentTypeCode>"+
+		"</cac:DocumentReference>"+
+		"<cac:Item>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) && assert.Len(parsed.InvoiceLines, 1) {
+		assert.Equal(line.OrderLineReference, parsed.InvoiceLines[0].OrderLineReference)
+		assert.Equal("AB12345", parsed.InvoiceLines[0].ObjectID())
+	}
+
+	creditNote := invoice.CreditNote()
+	if assert.Len(creditNote.CreditNoteLines, 1) {
+		assert.Equal("3", creditNote.CreditNoteLines[0].OrderLineID())
+		assert.Equal("AB12345", creditNote.CreditNoteLines[0].ObjectID())
+	}
+
+	_, err = newLine().WithOrderLineReference("").Build()
+	assert.Error(err)
+	_, err = newLine().WithDocumentReference(InvoiceLineDocumentReference{}).Build()
+	assert.Error(err)
+}
+
+func TestPayeeFinancialAccountName(t *testing.T) {
+	assert := assert.New(t)
+
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.PaymentMeans = []InvoicePaymentMeans{{
+		PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansCreditTransfer},
+		PayeeFinancialAccounts: []PayeeFinancialAccount{{
+			ID:   "RO49AAAA1B31007593840000",
+			Name: "Furnizor SRL",
+		}},
+	}}
+
+	// The account name (BT-85) is a cbc:Name.
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(xmlData), "<cac:PayeeFinancialAccount>"+
+		"<cbc:ID>RO49AAAA1B31007593840000</cbc:ID>"+
+		"<cbc:Name>Furnizor SRL</cbc:Name>"+
+		"</cac:PayeeFinancialAccount>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) && assert.Len(parsed.PaymentMeans, 1) {
+		assert.Equal(invoice.PaymentMeans, parsed.PaymentMeans)
+	}
+}
+
+func TestInvoiceNote(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		text string
+		note InvoiceNote
+	}{
+		{"#AAI#Livrare partiala", InvoiceNote{SubjectCode: InvoiceNoteSubjectGeneralInformation, Note: "Livrare partiala"}},
+		{"#PMT#", InvoiceNote{SubjectCode: InvoiceNoteSubjectPaymentInformation}},
+		{"Taxare inversa", InvoiceNote{Note: "Taxare inversa"}},
+		{"#1# nu este un cod", InvoiceNote{Note: "#1# nu este un cod"}},
+		{"#aai#Livrare", InvoiceNote{Note: "#aai#Livrare"}},
+		{"#AAI fara terminator", InvoiceNote{Note: "#AAI fara terminator"}},
+		{"", InvoiceNote{}},
+	}
+	for _, test := range tests {
+		note := ParseInvoiceNote(test.text)
+		assert.Equal(test.note, note, test.text)
+		assert.Equal(test.text, note.String(), test.text)
+	}
+
+	var invoice Invoice
+	invoice.Prefill()
+	invoice.ID = "1"
+	invoice.Note = []InvoiceNote{
+		{SubjectCode: InvoiceNoteSubjectSupplierRemarks, Note: "Capital social 200 RON"},
+		{Note: "Plata in 30 de zile"},
+	}
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(xmlData), "<cbc:Note>#SUR#Capital social 200 RON</cbc:Note>")
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) {
+		assert.Equal(invoice.Note, parsed.Note)
+	}
+
+	assert.True(InvoiceNoteSubjectTaxDeclaration.IsValid())
+	assert.False(InvoiceNoteSubjectCodeType("AA").IsValid())
+}
@@ -0,0 +1,250 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournalEventType is the type of a JournalEvent.
+type JournalEventType string
+
+const (
+	// JournalEventCreated records that the document was created locally.
+	JournalEventCreated JournalEventType = "created"
+	// JournalEventUploaded records the upload, with the UploadIndex.
+	JournalEventUploaded JournalEventType = "uploaded"
+	// JournalEventStateChanged records a message state returned by ANAF,
+	// with the State (and the DownloadID for the terminal states).
+	JournalEventStateChanged JournalEventType = "state-changed"
+	// JournalEventDownloaded records the download of the response, with the
+	// DownloadID.
+	JournalEventDownloaded JournalEventType = "downloaded"
+	// JournalEventArchived records the archiving of the response, with the
+	// ArchiveKey.
+	JournalEventArchived JournalEventType = "archived"
+)
+
+// JournalEvent is an event of a Journal. Only the fields relevant for the
+// event type are set.
+type JournalEvent struct {
+	// Seq is the sequence number of the event in the journal, starting at 1.
+	Seq uint64 `json:"seq"`
+	// Time is the time of the event.
+	Time time.Time `json:"time"`
+	// SubmissionID identifies the submission (eg. the invoice number).
+	SubmissionID string `json:"submissionID"`
+	// Type is the type of the event.
+	Type JournalEventType `json:"type"`
+	// UploadIndex is the upload index, for the uploaded events.
+	UploadIndex int64 `json:"uploadIndex,omitempty"`
+	// State is the message state, for the state-changed events.
+	State GetMessageStateCode `json:"state,omitempty"`
+	// DownloadID is the download ID, for the state-changed and downloaded
+	// events.
+	DownloadID int64 `json:"downloadID,omitempty"`
+	// ArchiveKey is the key of the archived response, for the archived
+	// events.
+	ArchiveKey string `json:"archiveKey,omitempty"`
+	// Message is a free form message (eg. the ANAF errors of a rejected
+	// document, or the user that triggered the event).
+	Message string `json:"message,omitempty"`
+}
+
+// journalOptions are the options of a Journal.
+type journalOptions struct {
+	writer io.Writer
+	now    func() time.Time
+}
+
+// JournalOption allows configuring a Journal.
+type JournalOption func(*journalOptions)
+
+// JournalWriter sets the writer where each appended event is written as a
+// JSON line (eg. an append-only file), before being added to the journal.
+func JournalWriter(w io.Writer) JournalOption {
+	return func(o *journalOptions) {
+		o.writer = w
+	}
+}
+
+// JournalClock sets the function returning the time of the events appended
+// without a time. Default is time.Now.
+func JournalClock(now func() time.Time) JournalOption {
+	return func(o *journalOptions) {
+		o.now = now
+	}
+}
+
+// Journal is an append-only journal of the events of the submissions. The
+// state of each Submission is the result of applying its events in order,
+// so what happened to each document can be reconstructed from the journal
+// alone (see ReadJournal). It's safe for concurrent use.
+type Journal struct {
+	mu          sync.Mutex
+	opts        journalOptions
+	events      []JournalEvent
+	submissions map[string]*Submission
+}
+
+// NewJournal creates a new empty Journal.
+func NewJournal(opts ...JournalOption) *Journal {
+	o := journalOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Journal{
+		opts:        o,
+		submissions: make(map[string]*Submission),
+	}
+}
+
+// ReadJournal reads the events written as JSON lines (see JournalWriter)
+// from r and replays them into a new Journal. The options are applied to the
+// returned Journal (eg. for continuing to write to the same file), but the
+// replayed events are not written again.
+func ReadJournal(r io.Reader, opts ...JournalOption) (*Journal, error) {
+	j := NewJournal(opts...)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event JournalEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("journal line %d: %w", line, err)
+		}
+		if event.Seq != uint64(len(j.events))+1 {
+			return nil, fmt.Errorf("journal line %d: expected seq %d, got %d",
+				line, len(j.events)+1, event.Seq)
+		}
+		if err := j.apply(event); err != nil {
+			return nil, fmt.Errorf("journal line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append appends the event to the journal and applies it to its
+// submission. The Seq is set by the journal and the Time is set to the
+// current time if zero. If the event is not valid for the submission (see
+// Submission.Apply), or it cannot be written to the writer, the journal is
+// not changed. The appended event is returned.
+func (j *Journal) Append(event JournalEvent) (JournalEvent, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	event.Seq = uint64(len(j.events)) + 1
+	if event.Time.IsZero() {
+		event.Time = j.opts.now()
+	}
+	// Validate the event before writing it.
+	submission := j.submission(event.SubmissionID)
+	if err := submission.Apply(event); err != nil {
+		return event, err
+	}
+	if j.opts.writer != nil {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return event, err
+		}
+		if _, err := j.opts.writer.Write(append(data, '\n')); err != nil {
+			return event, err
+		}
+	}
+	j.events = append(j.events, event)
+	j.submissions[event.SubmissionID] = &submission
+	return event, nil
+}
+
+// submission returns a copy of the submission with the given ID.
+func (j *Journal) submission(id string) Submission {
+	if s, ok := j.submissions[id]; ok {
+		return *s
+	}
+	return Submission{}
+}
+
+func (j *Journal) apply(event JournalEvent) error {
+	submission := j.submission(event.SubmissionID)
+	if err := submission.Apply(event); err != nil {
+		return err
+	}
+	j.events = append(j.events, event)
+	j.submissions[event.SubmissionID] = &submission
+	return nil
+}
+
+// Events returns all the events of the journal, in order.
+func (j *Journal) Events() []JournalEvent {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]JournalEvent(nil), j.events...)
+}
+
+// Submission returns the current state of the submission with the given ID.
+// ok is false if the journal has no events for it.
+func (j *Journal) Submission(id string) (submission Submission, ok bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s, ok := j.submissions[id]
+	if !ok {
+		return
+	}
+	return *s, true
+}
+
+// Submissions returns the current state of all the submissions, sorted by
+// ID.
+func (j *Journal) Submissions() []Submission {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	submissions := make([]Submission, 0, len(j.submissions))
+	for _, s := range j.submissions {
+		submissions = append(submissions, *s)
+	}
+	sort.Slice(submissions, func(a, b int) bool {
+		return submissions[a].ID < submissions[b].ID
+	})
+	return submissions
+}
+
+// ReplaySubmissions replays the events (eg. read from a journal or an
+// external event store) and returns the resulting submissions by ID. Unlike
+// ReadJournal, the sequence numbers are not checked.
+func ReplaySubmissions(events []JournalEvent) (map[string]*Submission, error) {
+	submissions := make(map[string]*Submission)
+	for _, event := range events {
+		s, ok := submissions[event.SubmissionID]
+		if !ok {
+			s = &Submission{}
+		}
+		if err := s.Apply(event); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.Seq, err)
+		}
+		submissions[event.SubmissionID] = s
+	}
+	return submissions, nil
+}
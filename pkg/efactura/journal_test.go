@@ -0,0 +1,111 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestJournal(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	var buf bytes.Buffer
+	journal := efactura.NewJournal(efactura.JournalWriter(&buf), efactura.JournalClock(clock))
+	for _, event := range []efactura.JournalEvent{
+		{SubmissionID: "FCT-1", Type: efactura.JournalEventCreated},
+		{SubmissionID: "FCT-2", Type: efactura.JournalEventCreated},
+		{SubmissionID: "FCT-1", Type: efactura.JournalEventUploaded, UploadIndex: 5001},
+		{SubmissionID: "FCT-1", Type: efactura.JournalEventStateChanged, State: efactura.GetMessageStateCodeProcessing},
+		{SubmissionID: "FCT-1", Type: efactura.JournalEventStateChanged, State: efactura.GetMessageStateCodeOk, DownloadID: 3001},
+		{SubmissionID: "FCT-1", Type: efactura.JournalEventDownloaded},
+		{SubmissionID: "FCT-1", Type: efactura.JournalEventArchived, ArchiveKey: "3001"},
+		{SubmissionID: "FCT-2", Type: efactura.JournalEventUploaded, UploadIndex: 5002},
+		{SubmissionID: "FCT-2", Type: efactura.JournalEventStateChanged, State: efactura.GetMessageStateCodeNok,
+			DownloadID: 3002, Message: "E: cif emitent invalid"},
+	} {
+		_, err := journal.Append(event)
+		if !assert.NoError(err) {
+			return
+		}
+	}
+
+	fct1, ok := journal.Submission("FCT-1")
+	if assert.True(ok) {
+		assert.Equal(efactura.SubmissionStatusArchived, fct1.Status)
+		assert.Equal(int64(5001), fct1.UploadIndex)
+		assert.Equal(int64(3001), fct1.DownloadID)
+		assert.Equal("3001", fct1.ArchiveKey)
+		assert.Equal(efactura.GetMessageStateCodeOk, fct1.State)
+		assert.Len(fct1.Events, 6)
+		assert.Equal(time.Date(2024, 3, 1, 10, 1, 0, 0, time.UTC), fct1.CreatedAt)
+		assert.True(fct1.IsTerminal())
+	}
+	fct2, _ := journal.Submission("FCT-2")
+	assert.Equal(efactura.SubmissionStatusRejected, fct2.Status)
+	_, ok = journal.Submission("FCT-3")
+	assert.False(ok)
+
+	// Invalid transitions are rejected and not journaled.
+	_, err := journal.Append(efactura.JournalEvent{SubmissionID: "FCT-2", Type: efactura.JournalEventArchived})
+	assert.ErrorIs(err, efactura.ErrInvalidTransition)
+	_, err = journal.Append(efactura.JournalEvent{SubmissionID: "FCT-3", Type: efactura.JournalEventUploaded})
+	assert.ErrorIs(err, efactura.ErrInvalidTransition)
+	_, err = journal.Append(efactura.JournalEvent{SubmissionID: "FCT-1", Type: efactura.JournalEventCreated})
+	assert.ErrorIs(err, efactura.ErrInvalidTransition)
+	assert.Len(journal.Events(), 9)
+	assert.Equal(9, strings.Count(buf.String(), "\n"))
+
+	// The journal is reconstructed from the written events.
+	replayed, err := efactura.ReadJournal(bytes.NewReader(buf.Bytes()))
+	if assert.NoError(err) {
+		assert.Equal(journal.Events(), replayed.Events())
+		assert.Equal(journal.Submissions(), replayed.Submissions())
+	}
+	submissions, err := efactura.ReplaySubmissions(journal.Events())
+	if assert.NoError(err) && assert.Len(submissions, 2) {
+		assert.Equal(fct2, *submissions["FCT-2"])
+	}
+
+	// Gaps in the sequence numbers are detected.
+	lines := strings.SplitAfter(buf.String(), "\n")
+	_, err = efactura.ReadJournal(strings.NewReader(lines[0] + lines[2]))
+	assert.ErrorContains(err, "expected seq 2, got 3")
+
+	// A failed write doesn't change the journal.
+	failing := efactura.NewJournal(efactura.JournalWriter(failingWriter{}))
+	_, err = failing.Append(efactura.JournalEvent{SubmissionID: "FCT-1", Type: efactura.JournalEventCreated})
+	assert.Error(err)
+	assert.Empty(failing.Events())
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// LintCheck is the name of an invoice linter check.
+type LintCheck string
+
+const (
+	// LintCheckBuyerReference warns if the BuyerReference (BT-10) is missing
+	// for an invoice sent to a public institution (B2G).
+	LintCheckBuyerReference LintCheck = "buyer-reference"
+	// LintCheckPaymentMeans warns if the invoice has no payment means.
+	LintCheckPaymentMeans LintCheck = "payment-means"
+	// LintCheckDueDate warns if the due date is before the issue date.
+	LintCheckDueDate LintCheck = "due-date"
+	// LintCheckZeroVATExemption warns if a 0% VAT rate is used without an
+	// exemption reason or exemption reason code.
+	LintCheckZeroVATExemption LintCheck = "zero-vat-exemption"
+	// LintCheckQuantity warns about zero quantities or quantities larger than
+	// the configured maximum.
+	LintCheckQuantity LintCheck = "quantity"
+	// LintCheckExemptionReasonCode warns if a tax exemption reason code
+	// (BT-121) is unknown or not allowed for the VAT category.
+	LintCheckExemptionReasonCode LintCheck = "exemption-reason-code"
+	// LintCheckCardNumber warns if the card primary account number (BT-87)
+	// has more than 6 digits, since an invoice should only include the last
+	// 4 - 6 digits of the card number (BR-51).
+	LintCheckCardNumber LintCheck = "card-number"
+	// LintCheckOrderLineReference warns if an invoice line references a
+	// purchase order line (BT-132) but the invoice has no purchase order
+	// reference (BT-13).
+	LintCheckOrderLineReference LintCheck = "order-line-reference"
+	// LintCheckCurrencyMinorUnits warns if the invoice total amounts have
+	// more decimals than the minor unit of the invoice currency (eg. a JPY
+	// amount with decimals).
+	LintCheckCurrencyMinorUnits LintCheck = "currency-minor-units"
+)
+
+// LintWarning is a warning produced by the invoice linter. Warnings are not
+// legal errors (the invoice may still be accepted by ANAF), but point to
+// invoice contents that are probably wrong or incomplete.
+type LintWarning struct {
+	// Check is the name of the check that produced the warning.
+	Check LintCheck
+	// Path is the path of the offending field (eg. "InvoiceLines[2].InvoicedQuantity").
+	Path string
+	// Message is a human readable description of the warning.
+	Message string
+}
+
+// String implements the fmt.Stringer interface.
+func (w LintWarning) String() string {
+	if w.Path == "" {
+		return fmt.Sprintf("%s: %s", w.Check, w.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", w.Check, w.Path, w.Message)
+}
+
+// defaultLintMaxQuantity is the default quantity considered absurd for an
+// invoice line.
+const defaultLintMaxQuantity = 1_000_000
+
+// LintConfig is the config used by the invoice linter.
+type LintConfig struct {
+	// B2G should be set to true if the invoice is sent to a public
+	// institution, in which case the BuyerReference is expected.
+	B2G bool
+	// MaxQuantity is the maximum absolute quantity for an invoice line
+	// before a warning is produced. Default 1000000.
+	MaxQuantity types.Decimal
+	// Disabled is the set of disabled checks.
+	Disabled map[LintCheck]bool
+}
+
+// LintConfigOption allows gradually modifying a LintConfig
+type LintConfigOption func(*LintConfig)
+
+// LintB2G marks the invoice as an invoice sent to a public institution.
+func LintB2G(b2g bool) LintConfigOption {
+	return func(c *LintConfig) {
+		c.B2G = b2g
+	}
+}
+
+// LintMaxQuantity sets the maximum absolute line quantity that is not
+// considered suspicious.
+func LintMaxQuantity(maxQuantity types.Decimal) LintConfigOption {
+	return func(c *LintConfig) {
+		c.MaxQuantity = maxQuantity
+	}
+}
+
+// LintDisable disables the given checks.
+func LintDisable(checks ...LintCheck) LintConfigOption {
+	return func(c *LintConfig) {
+		if c.Disabled == nil {
+			c.Disabled = make(map[LintCheck]bool)
+		}
+		for _, check := range checks {
+			c.Disabled[check] = true
+		}
+	}
+}
+
+// Lint checks the invoice against a set of opinionated best practices that
+// go beyond the legal rules (missing BuyerReference for B2G, missing payment
+// means, due date before issue date, 0% VAT without exemption reason, absurd
+// quantities) and returns the list of warnings. An empty list means no
+// problems were found.
+func (iv Invoice) Lint(opts ...LintConfigOption) (warnings []LintWarning) {
+	cfg := LintConfig{
+		MaxQuantity: types.D(defaultLintMaxQuantity),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	warn := func(check LintCheck, path, format string, args ...any) {
+		if cfg.Disabled[check] {
+			return
+		}
+		warnings = append(warnings, LintWarning{
+			Check:   check,
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if cfg.B2G && iv.BuyerReference == "" {
+		warn(LintCheckBuyerReference, "BuyerReference",
+			"missing buyer reference for an invoice sent to a public institution")
+	}
+	if len(iv.PaymentMeans) == 0 {
+		warn(LintCheckPaymentMeans, "PaymentMeans", "missing payment means")
+	}
+	for i, pm := range iv.PaymentMeans {
+		if pm.CardAccount != nil && lintCountDigits(pm.CardAccount.PrimaryAccountNumberID) > 6 {
+			warn(LintCheckCardNumber, fmt.Sprintf("PaymentMeans[%d].CardAccount.PrimaryAccountNumberID", i),
+				"the card number should only contain the last 4 - 6 digits")
+		}
+	}
+	if decimals := iv.DocumentCurrencyCode.AmountDecimals(); decimals < maxAmountDecimals {
+		for _, amount := range []struct {
+			path   string
+			amount types.Decimal
+		}{
+			{"LegalMonetaryTotal.TaxInclusiveAmount", iv.LegalMonetaryTotal.TaxInclusiveAmount.Amount},
+			{"LegalMonetaryTotal.PayableAmount", iv.LegalMonetaryTotal.PayableAmount.Amount},
+		} {
+			if !amount.amount.HasScale(decimals) {
+				warn(LintCheckCurrencyMinorUnits, amount.path, "amount %s has more than %d decimals for currency %s",
+					amount.amount.String(), decimals, iv.DocumentCurrencyCode)
+			}
+		}
+	}
+	if iv.DueDate != nil && !iv.DueDate.IsZero() && iv.DueDate.Before(iv.IssueDate.Time) {
+		warn(LintCheckDueDate, "DueDate", "due date %s is before the issue date %s",
+			iv.DueDate.Format(time.DateOnly), iv.IssueDate.Format(time.DateOnly))
+	}
+
+	for i, taxTotal := range iv.TaxTotal {
+		for j, subtotal := range taxTotal.TaxSubtotals {
+			category := subtotal.TaxCategory
+			if lintZeroVATWithoutExemption(category.ID, category.Percent) &&
+				category.TaxExemptionReason == "" && category.TaxExemptionReasonCode == "" {
+				warn(LintCheckZeroVATExemption,
+					fmt.Sprintf("TaxTotal[%d].TaxSubtotals[%d].TaxCategory", i, j),
+					"0%% VAT for tax category %s without an exemption reason or exemption reason code", category.ID)
+			}
+			if category.TaxExemptionReasonCode != "" {
+				if err := ValidateTaxExemptionReasonCode(category.ID, category.TaxExemptionReasonCode); err != nil {
+					warn(LintCheckExemptionReasonCode,
+						fmt.Sprintf("TaxTotal[%d].TaxSubtotals[%d].TaxCategory.TaxExemptionReasonCode", i, j),
+						"%v", err)
+				}
+			}
+		}
+	}
+	for i, line := range iv.InvoiceLines {
+		category := line.Item.TaxCategory
+		if category.ID == TaxCategoryVATStandardRate && category.Percent.IsZero() {
+			warn(LintCheckZeroVATExemption, fmt.Sprintf("InvoiceLines[%d].Item.TaxCategory", i),
+				"0%% VAT for the standard rate tax category")
+		}
+
+		if line.OrderLineReference != nil && (iv.OrderReference == nil || iv.OrderReference.OrderID == "") {
+			warn(LintCheckOrderLineReference, fmt.Sprintf("InvoiceLines[%d].OrderLineReference", i),
+				"order line reference %q without a purchase order reference (BT-13)", line.OrderLineReference.LineID)
+		}
+
+		quantity := line.InvoicedQuantity.Quantity
+		switch {
+		case quantity.IsZero():
+			warn(LintCheckQuantity, fmt.Sprintf("InvoiceLines[%d].InvoicedQuantity", i),
+				"zero quantity")
+		case quantity.Abs().GreaterThan(cfg.MaxQuantity.Decimal):
+			warn(LintCheckQuantity, fmt.Sprintf("InvoiceLines[%d].InvoicedQuantity", i),
+				"quantity %s is larger than %s", quantity.String(), cfg.MaxQuantity.String())
+		}
+	}
+	return
+}
+
+// lintZeroVATWithoutExemption returns true if the given tax category with the
+// given percent requires an exemption reason. Zero rated (Z) categories don't
+// need an exemption reason.
+func lintZeroVATWithoutExemption(id TaxCategoryCodeType, percent types.Decimal) bool {
+	switch id {
+	case TaxCategoryVATZeroRate:
+		return false
+	case TaxCategoryVATExempt, TaxCategoryVATReverseCharge, TaxCategoryVATExemptIntraCommunitySupply,
+		TaxCategoryVATNotChargedFreeExportItem, TaxCategoryNotSubjectToVAT:
+		return true
+	}
+	return percent.IsZero()
+}
+
+// lintCountDigits returns the number of decimal digits of s.
+func lintCountDigits(s string) (n int) {
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return
+}
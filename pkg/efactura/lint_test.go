@@ -0,0 +1,130 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func lintChecks(warnings []efactura.LintWarning) (checks []efactura.LintCheck) {
+	for _, w := range warnings {
+		checks = append(checks, w.Check)
+	}
+	return
+}
+
+func TestInvoiceLint(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := efactura.Invoice{
+		IssueDate: types.MakeDate(2024, time.March, 10),
+		DueDate:   types.NewDate(2024, time.March, 1),
+		TaxTotal: []efactura.InvoiceTaxTotal{{
+			TaxSubtotals: []efactura.InvoiceTaxSubtotal{{
+				TaxCategory: efactura.InvoiceTaxCategory{
+					ID:      efactura.TaxCategoryVATExempt,
+					Percent: types.D(0),
+				},
+			}, {
+				TaxCategory: efactura.InvoiceTaxCategory{
+					ID:      efactura.TaxCategoryVATZeroRate,
+					Percent: types.D(0),
+				},
+			}},
+		}},
+		InvoiceLines: []efactura.InvoiceLine{{
+			InvoicedQuantity: efactura.InvoicedQuantity{Quantity: types.D(0)},
+			Item: efactura.InvoiceLineItem{
+				TaxCategory: efactura.InvoiceLineTaxCategory{
+					ID:      efactura.TaxCategoryVATStandardRate,
+					Percent: types.D(0),
+				},
+			},
+		}, {
+			InvoicedQuantity: efactura.InvoicedQuantity{Quantity: types.D(-5000000)},
+			Item: efactura.InvoiceLineItem{
+				TaxCategory: efactura.InvoiceLineTaxCategory{
+					ID:      efactura.TaxCategoryVATStandardRate,
+					Percent: types.D(19),
+				},
+			},
+		}},
+	}
+
+	warnings := invoice.Lint(efactura.LintB2G(true))
+	assert.Equal([]efactura.LintCheck{
+		efactura.LintCheckBuyerReference,
+		efactura.LintCheckPaymentMeans,
+		efactura.LintCheckDueDate,
+		efactura.LintCheckZeroVATExemption,
+		efactura.LintCheckZeroVATExemption,
+		efactura.LintCheckQuantity,
+		efactura.LintCheckQuantity,
+	}, lintChecks(warnings))
+	assert.Equal("TaxTotal[0].TaxSubtotals[0].TaxCategory", warnings[3].Path)
+	assert.Equal("InvoiceLines[1].InvoicedQuantity", warnings[6].Path)
+
+	warnings = invoice.Lint(
+		efactura.LintDisable(efactura.LintCheckPaymentMeans, efactura.LintCheckZeroVATExemption),
+		efactura.LintMaxQuantity(types.D(10000000)),
+	)
+	assert.Equal([]efactura.LintCheck{
+		efactura.LintCheckDueDate,
+		efactura.LintCheckQuantity,
+	}, lintChecks(warnings))
+
+	invoice.DueDate = types.NewDate(2024, time.March, 20)
+	invoice.BuyerReference = "REF"
+	invoice.PaymentMeans = []efactura.InvoicePaymentMeans{{}}
+	invoice.TaxTotal[0].TaxSubtotals[0].TaxCategory.TaxExemptionReasonCode = "VATEX-EU-O"
+	invoice.InvoiceLines = invoice.InvoiceLines[1:]
+	invoice.InvoiceLines[0].InvoicedQuantity.Quantity = types.D(10)
+	warnings = invoice.Lint(efactura.LintB2G(true))
+	assert.Equal([]efactura.LintCheck{efactura.LintCheckExemptionReasonCode}, lintChecks(warnings))
+
+	invoice.TaxTotal[0].TaxSubtotals[0].TaxCategory.TaxExemptionReasonCode = "VATEX-EU-132"
+	assert.Empty(invoice.Lint(efactura.LintB2G(true)))
+
+	invoice.PaymentMeans[0].CardAccount = &efactura.InvoiceCardAccount{
+		PrimaryAccountNumberID: "4111 1111 1111 1111",
+		NetworkID:              "VISA",
+	}
+	warnings = invoice.Lint()
+	assert.Equal([]efactura.LintCheck{efactura.LintCheckCardNumber}, lintChecks(warnings))
+	assert.Equal("PaymentMeans[0].CardAccount.PrimaryAccountNumberID", warnings[0].Path)
+	invoice.PaymentMeans[0].CardAccount.PrimaryAccountNumberID = "XXXX XXXX XXXX 1111"
+	assert.Empty(invoice.Lint())
+
+	invoice.InvoiceLines[0].OrderLineReference = &efactura.InvoiceOrderLineReference{LineID: "3"}
+	warnings = invoice.Lint()
+	assert.Equal([]efactura.LintCheck{efactura.LintCheckOrderLineReference}, lintChecks(warnings))
+	assert.Equal("InvoiceLines[0].OrderLineReference", warnings[0].Path)
+	invoice.OrderReference = &efactura.InvoiceOrderReference{OrderID: "PO-1"}
+	assert.Empty(invoice.Lint())
+
+	jpyInvoice := invoice
+	jpyInvoice.DocumentCurrencyCode = efactura.CurrencyJPY
+	jpyInvoice.LegalMonetaryTotal.TaxInclusiveAmount.Amount = types.D(1190)
+	jpyInvoice.LegalMonetaryTotal.PayableAmount.Amount = types.D(1190.5)
+	warnings = jpyInvoice.Lint()
+	assert.Equal([]efactura.LintCheck{efactura.LintCheckCurrencyMinorUnits}, lintChecks(warnings))
+	assert.Equal("LegalMonetaryTotal.PayableAmount", warnings[0].Path)
+}
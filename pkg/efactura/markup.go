@@ -0,0 +1,177 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+
+	"github.com/printesoi/xml-go"
+
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+// XMLMarkup is a set of XML markup kinds that are not part of the document
+// data (eg. the "generated with" comment, see GeneratedWithComment).
+type XMLMarkup int
+
+const (
+	// XMLMarkupComments selects the XML comments.
+	XMLMarkupComments XMLMarkup = 1 << iota
+	// XMLMarkupProcInsts selects the XML processing instructions (eg.
+	// <?xml-stylesheet ...?>), except the XML declaration.
+	XMLMarkupProcInsts
+
+	// XMLMarkupAll selects all the XML markup kinds.
+	XMLMarkupAll = XMLMarkupComments | XMLMarkupProcInsts
+)
+
+// StripXMLMarkup returns a copy of the XML data without the selected markup
+// and the whitespace following it. The XML declaration is always kept. If
+// there is no markup to strip, xmlData is returned unchanged, otherwise the
+// rest of the document is kept as it is, except the attribute quotes and the
+// character escapes that are normalized. Stripping a document twice gives the
+// same result.
+// Note that stripping a signed document (eg. the InvoiceXML of a downloaded
+// invoice) invalidates its signature.
+func StripXMLMarkup(xmlData []byte, markup XMLMarkup) ([]byte, error) {
+	if markup&XMLMarkupAll == 0 {
+		return xmlData, nil
+	}
+	doc, err := parseXMLDocument(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	var stripped int
+	doc.prolog = stripXMLTokens(doc.prolog, markup, &stripped)
+	doc.root.stripMarkup(markup, &stripped)
+	doc.epilog = stripXMLTokens(doc.epilog, markup, &stripped)
+	if stripped == 0 {
+		return xmlData, nil
+	}
+	return doc.bytes(), nil
+}
+
+// stripXMLToken returns true if the token is markup selected by markup.
+func stripXMLToken(t any, markup XMLMarkup) bool {
+	switch tok := t.(type) {
+	case xml.Comment:
+		return markup&XMLMarkupComments != 0
+	case xml.ProcInst:
+		return markup&XMLMarkupProcInsts != 0 && tok.Target != "xml"
+	}
+	return false
+}
+
+// isXMLWhitespace returns true if the token is whitespace-only char data.
+func isXMLWhitespace(t any) bool {
+	c, ok := t.(xml.CharData)
+	return ok && len(bytes.TrimSpace(c)) == 0
+}
+
+// stripXMLTokens removes the selected markup (and the whitespace following
+// it) from tokens, incrementing count for every removed markup token.
+func stripXMLTokens(tokens []xml.Token, markup XMLMarkup, count *int) []xml.Token {
+	var res []xml.Token
+	stripped := false
+	for _, t := range tokens {
+		if stripXMLToken(t, markup) {
+			stripped = true
+			*count++
+			continue
+		}
+		if stripped && isXMLWhitespace(t) {
+			continue
+		}
+		stripped = false
+		res = append(res, t)
+	}
+	return res
+}
+
+// stripMarkup is like stripXMLTokens, but for the children of the node
+// (recursively).
+func (n *xmlNode) stripMarkup(markup XMLMarkup, count *int) {
+	children := n.children[:0]
+	stripped := false
+	for _, child := range n.children {
+		if stripXMLToken(child, markup) {
+			stripped = true
+			*count++
+			continue
+		}
+		if stripped && isXMLWhitespace(child) {
+			continue
+		}
+		stripped = false
+		if c, ok := child.(*xmlNode); ok {
+			c.stripMarkup(markup, count)
+		}
+		children = append(children, child)
+	}
+	n.children = children
+}
+
+// ParseOptionStripMarkup strips the selected markup (see StripXMLMarkup)
+// from the document before unmarshaling it, so for example the Comment of the
+// parsed Invoice is empty if XMLMarkupComments is selected. The raw XML (eg.
+// DownloadInvoiceParseZipResponse.InvoiceXML) is not changed.
+func ParseOptionStripMarkup(markup XMLMarkup) ParseOption {
+	return func(o *parseOptions) {
+		o.stripMarkup = markup
+	}
+}
+
+// MarshalOption allows changing the XML encoding of a document.
+type MarshalOption func(*marshalOptions)
+
+type marshalOptions struct {
+	stripMarkup       XMLMarkup
+	indent            bool
+	prefix, indentStr string
+}
+
+// MarshalOptionStripMarkup strips the selected markup (see StripXMLMarkup)
+// from the XML encoding of the document, eg. the Comment of an Invoice.
+func MarshalOptionStripMarkup(markup XMLMarkup) MarshalOption {
+	return func(o *marshalOptions) {
+		o.stripMarkup = markup
+	}
+}
+
+// MarshalOptionIndent indents the XML encoding of the document (see
+// Invoice.XMLIndent).
+func MarshalOptionIndent(prefix, indent string) MarshalOption {
+	return func(o *marshalOptions) {
+		o.indent, o.prefix, o.indentStr = true, prefix, indent
+	}
+}
+
+// marshalDocumentXML returns the XML encoding (with the XML header
+// declaration) of v using the given options.
+func marshalDocumentXML(v any, opts ...MarshalOption) (data []byte, err error) {
+	var o marshalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.indent {
+		data, err = pxml.MarshalIndentXMLWithHeader(v, o.prefix, o.indentStr)
+	} else {
+		data, err = pxml.MarshalXMLWithHeader(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return StripXMLMarkup(data, o.stripMarkup)
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestStripXMLMarkup(t *testing.T) {
+	assert := assert.New(t)
+
+	const xmlData = `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="factura.xsl"?>
+<!--Generated with Test-->
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <!--Invoice ID-->
+  <cbc:ID>FCT-1</cbc:ID>
+  <?app-marker id="1"?>
+  <cbc:Note>A &amp; B</cbc:Note>
+</Invoice>
+<!--end-->
+`
+
+	tests := []struct {
+		markup   efactura.XMLMarkup
+		expected string
+	}{{
+		markup: efactura.XMLMarkupComments,
+		expected: `<?xml version="1.0" encoding="UTF-8"?>
+<?xml-stylesheet type="text/xsl" href="factura.xsl"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>FCT-1</cbc:ID>
+  <?app-marker id="1"?>
+  <cbc:Note>A &amp; B</cbc:Note>
+</Invoice>
+`,
+	}, {
+		markup: efactura.XMLMarkupProcInsts,
+		expected: `<?xml version="1.0" encoding="UTF-8"?>
+<!--Generated with Test-->
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <!--Invoice ID-->
+  <cbc:ID>FCT-1</cbc:ID>
+  <cbc:Note>A &amp; B</cbc:Note>
+</Invoice>
+<!--end-->
+`,
+	}, {
+		markup: efactura.XMLMarkupAll,
+		expected: `<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>FCT-1</cbc:ID>
+  <cbc:Note>A &amp; B</cbc:Note>
+</Invoice>
+`,
+	}, {
+		// Nothing to strip, the document is preserved.
+		expected: xmlData,
+	}}
+	for _, test := range tests {
+		stripped, err := efactura.StripXMLMarkup([]byte(xmlData), test.markup)
+		if !assert.NoError(err) {
+			continue
+		}
+		assert.Equal(test.expected, string(stripped))
+
+		// Stripping is deterministic.
+		again, err := efactura.StripXMLMarkup(stripped, test.markup)
+		if assert.NoError(err) {
+			assert.Equal(string(stripped), string(again))
+		}
+	}
+
+	_, err := efactura.StripXMLMarkup([]byte(`<Invoice>`), efactura.XMLMarkupAll)
+	assert.Error(err)
+}
+
+func TestInvoiceMarkupRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := efactura.Invoice{
+		ID:      "FCT-1",
+		Comment: efactura.GeneratedWithComment("Test"),
+	}
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(xmlData), "<!--"+invoice.Comment+"-->")
+
+	// The comment is preserved by default.
+	doc, err := efactura.UnmarshalDownloadedInvoiceXML(xmlData)
+	if assert.NoError(err) && assert.NotNil(doc.Invoice) {
+		assert.Equal(invoice.Comment, doc.Invoice.Comment)
+		roundTrip, err := doc.Invoice.XML()
+		if assert.NoError(err) {
+			assert.Equal(string(xmlData), string(roundTrip))
+		}
+	}
+
+	// The comment is stripped on parse.
+	doc, err = efactura.UnmarshalDownloadedInvoiceXML(xmlData,
+		efactura.ParseOptionStripMarkup(efactura.XMLMarkupComments))
+	if assert.NoError(err) && assert.NotNil(doc.Invoice) {
+		assert.Equal("FCT-1", doc.Invoice.ID)
+		assert.Empty(doc.Invoice.Comment)
+	}
+
+	// The comment is stripped on marshal.
+	stripped, err := invoice.XMLWithOptions(efactura.MarshalOptionStripMarkup(efactura.XMLMarkupAll))
+	if assert.NoError(err) {
+		assert.NotContains(string(stripped), "<!--")
+		commentless := invoice
+		commentless.Comment = ""
+		expected, err := commentless.XML()
+		if assert.NoError(err) {
+			assert.Equal(string(expected), string(stripped))
+		}
+	}
+
+	// The options can be combined.
+	indented, err := invoice.XMLWithOptions(
+		efactura.MarshalOptionIndent("", "  "),
+		efactura.MarshalOptionStripMarkup(efactura.XMLMarkupComments))
+	if assert.NoError(err) {
+		assert.NotContains(string(indented), "<!--")
+		assert.Contains(string(indented), "\n  <cbc:ID>FCT-1</cbc:ID>")
+	}
+
+	creditNote := efactura.CreditNote{ID: "CN-1", Comment: "generated"}
+	stripped, err = creditNote.XMLWithOptions(efactura.MarshalOptionStripMarkup(efactura.XMLMarkupComments))
+	if assert.NoError(err) {
+		assert.NotContains(string(stripped), "<!--")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrMessageNotFound is returned by the Client.FindMessage* methods if no
+// message matches the given identifier.
+var ErrMessageNotFound = errors.New("efactura: message not found")
+
+// MessageIndex correlates the two identifiers used by the e-factura APIs for
+// the same document: the upload index (index_incarcare, returned by the
+// upload endpoint, id_solicitare in the messages list), and the download ID
+// (id_descarcare, returned by the get message state endpoint, id in the
+// messages list, used for downloading the zip archive).
+type MessageIndex struct {
+	byUploadIndex map[int64]Message
+	byDownloadID  map[int64]Message
+}
+
+// NewMessageIndex creates a MessageIndex from a list of messages (eg. the
+// messages returned by GetMessagesList or GetAllMessagesPagination).
+func NewMessageIndex(messages []Message) *MessageIndex {
+	idx := &MessageIndex{
+		byUploadIndex: make(map[int64]Message, len(messages)),
+		byDownloadID:  make(map[int64]Message, len(messages)),
+	}
+	for _, m := range messages {
+		idx.Add(m)
+	}
+	return idx
+}
+
+// Add adds a message to the index. Messages without a valid upload index or
+// download ID are only indexed by the valid identifier.
+func (idx *MessageIndex) Add(m Message) {
+	if uploadIndex := m.GetUploadIndex(); uploadIndex != 0 {
+		idx.byUploadIndex[uploadIndex] = m
+	}
+	if downloadID := m.GetID(); downloadID != 0 {
+		idx.byDownloadID[downloadID] = m
+	}
+}
+
+// Len returns the number of messages from the index.
+func (idx *MessageIndex) Len() int {
+	return len(idx.byDownloadID)
+}
+
+// ByUploadIndex returns the message for the given upload index.
+func (idx *MessageIndex) ByUploadIndex(uploadIndex int64) (m Message, ok bool) {
+	m, ok = idx.byUploadIndex[uploadIndex]
+	return
+}
+
+// ByDownloadID returns the message for the given download ID.
+func (idx *MessageIndex) ByDownloadID(downloadID int64) (m Message, ok bool) {
+	m, ok = idx.byDownloadID[downloadID]
+	return
+}
+
+// DownloadIDForUploadIndex returns the download ID of the message for the
+// given upload index.
+func (idx *MessageIndex) DownloadIDForUploadIndex(uploadIndex int64) (downloadID int64, ok bool) {
+	m, ok := idx.ByUploadIndex(uploadIndex)
+	if !ok {
+		return 0, false
+	}
+	return m.GetID(), true
+}
+
+// UploadIndexForDownloadID returns the upload index of the message for the
+// given download ID.
+func (idx *MessageIndex) UploadIndexForDownloadID(downloadID int64) (uploadIndex int64, ok bool) {
+	m, ok := idx.ByDownloadID(downloadID)
+	if !ok {
+		return 0, false
+	}
+	return m.GetUploadIndex(), true
+}
+
+// getMessageIndex fetches the messages list for the last numDays days and
+// returns a MessageIndex for the messages.
+func (c *Client) getMessageIndex(ctx context.Context, cif string, numDays int) (*MessageIndex, error) {
+	res, err := c.GetMessagesList(ctx, cif, numDays, MessageFilterAll)
+	if err != nil {
+		return nil, err
+	}
+	if !res.IsOk() {
+		return nil, fmt.Errorf("%s: %s", res.Title, res.Error)
+	}
+	return NewMessageIndex(res.Messages), nil
+}
+
+// FindMessageByUploadIndex finds the downloadable message for the given
+// upload index by querying the messages list for the last numDays days for
+// the given cif and matching id_solicitare. The download ID of the message is
+// message.GetID(). If no message matches, ErrMessageNotFound is returned
+// (the message might not be available yet, or it's older than numDays).
+func (c *Client) FindMessageByUploadIndex(
+	ctx context.Context, cif string, uploadIndex int64, numDays int,
+) (message *Message, err error) {
+	idx, er := c.getMessageIndex(ctx, cif, numDays)
+	if err = er; err != nil {
+		return
+	}
+	m, ok := idx.ByUploadIndex(uploadIndex)
+	if !ok {
+		err = fmt.Errorf("upload index %d: %w", uploadIndex, ErrMessageNotFound)
+		return
+	}
+	message = &m
+	return
+}
+
+// FindMessageByDownloadID finds the message for the given download ID by
+// querying the messages list for the last numDays days for the given cif. The
+// upload index of the message is message.GetUploadIndex(). If no message
+// matches, ErrMessageNotFound is returned.
+func (c *Client) FindMessageByDownloadID(
+	ctx context.Context, cif string, downloadID int64, numDays int,
+) (message *Message, err error) {
+	idx, er := c.getMessageIndex(ctx, cif, numDays)
+	if err = er; err != nil {
+		return
+	}
+	m, ok := idx.ByDownloadID(downloadID)
+	if !ok {
+		err = fmt.Errorf("download ID %d: %w", downloadID, ErrMessageNotFound)
+		return
+	}
+	message = &m
+	return
+}
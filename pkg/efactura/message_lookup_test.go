@@ -0,0 +1,93 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestFindMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/listaMesajeFactura", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal("12345678", r.URL.Query().Get("cif"))
+		assert.Equal("30", r.URL.Query().Get("zile"))
+		writeJSON(w, efactura.MessagesListResponse{
+			Serial: "1234AA456",
+			CUI:    "12345678",
+			Title:  "Lista Mesaje disponibile din ultimele 30 zile",
+			Messages: []efactura.Message{{
+				ID:          "3001",
+				Type:        efactura.MessageTypeSentInvoice,
+				UploadIndex: "5001",
+				CIF:         "12345678",
+			}, {
+				ID:          "3002",
+				Type:        efactura.MessageTypeError,
+				UploadIndex: "5002",
+				CIF:         "12345678",
+			}},
+		})
+	})
+
+	ctx := context.Background()
+	message, err := client.FindMessageByUploadIndex(ctx, "12345678", 5002, 30)
+	if assert.NoError(err) && assert.NotNil(message) {
+		assert.Equal(int64(3002), message.GetID())
+		assert.True(message.IsError())
+	}
+
+	message, err = client.FindMessageByDownloadID(ctx, "12345678", 3001, 30)
+	if assert.NoError(err) && assert.NotNil(message) {
+		assert.Equal(int64(5001), message.GetUploadIndex())
+	}
+
+	_, err = client.FindMessageByUploadIndex(ctx, "12345678", 9999, 30)
+	assert.True(errors.Is(err, efactura.ErrMessageNotFound))
+	assert.Equal(3, calls)
+}
+
+func TestMessageIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	idx := efactura.NewMessageIndex([]efactura.Message{
+		{ID: "3001", UploadIndex: "5001"},
+		{ID: "3002", UploadIndex: ""},
+	})
+	assert.Equal(2, idx.Len())
+
+	downloadID, ok := idx.DownloadIDForUploadIndex(5001)
+	assert.True(ok)
+	assert.Equal(int64(3001), downloadID)
+
+	uploadIndex, ok := idx.UploadIndexForDownloadID(3001)
+	assert.True(ok)
+	assert.Equal(int64(5001), uploadIndex)
+
+	_, ok = idx.DownloadIDForUploadIndex(0)
+	assert.False(ok)
+	_, ok = idx.UploadIndexForDownloadID(3003)
+	assert.False(ok)
+}
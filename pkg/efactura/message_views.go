@@ -0,0 +1,169 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// CompanyNameResolver resolves a CIF to the registered company name (eg. by
+// querying the ANAF VAT registry, see vatinfo.Client).
+type CompanyNameResolver interface {
+	ResolveCompanyName(ctx context.Context, cif string) (name string, err error)
+}
+
+// CompanyNameResolverFunc is an adapter to allow the use of an ordinary
+// function as a CompanyNameResolver.
+type CompanyNameResolverFunc func(ctx context.Context, cif string) (name string, err error)
+
+// ResolveCompanyName calls f(ctx, cif).
+func (f CompanyNameResolverFunc) ResolveCompanyName(ctx context.Context, cif string) (string, error) {
+	return f(ctx, cif)
+}
+
+// cachedCompanyNameResolver is a CompanyNameResolver that caches the
+// successful lookups of another resolver.
+type cachedCompanyNameResolver struct {
+	resolver CompanyNameResolver
+
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// NewCachedCompanyNameResolver returns a CompanyNameResolver that caches the
+// names returned by resolver, so each CIF is resolved only once. Failed
+// lookups are not cached. The returned resolver is safe for concurrent use.
+func NewCachedCompanyNameResolver(resolver CompanyNameResolver) CompanyNameResolver {
+	return &cachedCompanyNameResolver{
+		resolver: resolver,
+		names:    make(map[string]string),
+	}
+}
+
+func (r *cachedCompanyNameResolver) ResolveCompanyName(ctx context.Context, cif string) (string, error) {
+	r.mu.Lock()
+	name, ok := r.names[cif]
+	r.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	name, err := r.resolver.ResolveCompanyName(ctx, cif)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.names[cif] = name
+	r.mu.Unlock()
+	return name, nil
+}
+
+// MessageView is a Message with the seller, buyer and counterparty CIFs
+// parsed from the message details and resolved to company names, ready to be
+// displayed.
+type MessageView struct {
+	Message
+
+	SellerCIF  string
+	SellerName string
+	BuyerCIF   string
+	BuyerName  string
+	// CounterpartyCIF is the CIF of the other party of the message (the buyer
+	// for sent invoices, the seller for received invoices).
+	CounterpartyCIF  string
+	CounterpartyName string
+}
+
+// GetCounterpartyCIF returns the CIF of the other party of the message,
+// relative to the CIF for which the message list was fetched (the buyer for
+// sent invoices, the seller for received invoices). For error messages,
+// empty string is returned.
+func (m Message) GetCounterpartyCIF() string {
+	sellerCIF, buyerCIF := m.GetSellerCIF(), m.GetBuyerCIF()
+	switch m.CIF {
+	case sellerCIF:
+		return buyerCIF
+	case buyerCIF:
+		return sellerCIF
+	}
+	if m.IsReceivedInvoice() {
+		return sellerCIF
+	}
+	return buyerCIF
+}
+
+// EnrichMessages creates a MessageView for each of the given messages, with
+// the CIFs resolved to names using the provided resolver. Each distinct CIF
+// is resolved only once. If a CIF cannot be resolved, the corresponding names
+// are left empty and the lookup error is returned (joined with the other
+// lookup errors) along with the views. If the context is canceled, the
+// context error is returned and the views are nil.
+func EnrichMessages(ctx context.Context, messages []Message, resolver CompanyNameResolver) (views []MessageView, err error) {
+	names := make(map[string]string)
+	var lookupErrs []error
+	resolve := func(cif string) (string, error) {
+		if cif == "" {
+			return "", nil
+		}
+		if name, ok := names[cif]; ok {
+			return name, nil
+		}
+		name, err := resolver.ResolveCompanyName(ctx, cif)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return "", ctxErr
+			}
+			lookupErrs = append(lookupErrs, fmt.Errorf("cif %s: %w", cif, err))
+		}
+		// We also store failed lookups, so we don't try again for the same
+		// CIF.
+		names[cif] = name
+		return name, nil
+	}
+
+	views = make([]MessageView, 0, len(messages))
+	for _, m := range messages {
+		view := MessageView{
+			Message:         m,
+			SellerCIF:       m.GetSellerCIF(),
+			BuyerCIF:        m.GetBuyerCIF(),
+			CounterpartyCIF: m.GetCounterpartyCIF(),
+		}
+		if view.SellerName, err = resolve(view.SellerCIF); err != nil {
+			return nil, err
+		}
+		if view.BuyerName, err = resolve(view.BuyerCIF); err != nil {
+			return nil, err
+		}
+		if view.CounterpartyName, err = resolve(view.CounterpartyCIF); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, errors.Join(lookupErrs...)
+}
+
+// Views returns the messages from the response as MessageViews with the CIFs
+// resolved to names. See EnrichMessages.
+func (r *MessagesListResponse) Views(ctx context.Context, resolver CompanyNameResolver) ([]MessageView, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return EnrichMessages(ctx, r.Messages, resolver)
+}
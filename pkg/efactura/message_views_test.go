@@ -0,0 +1,122 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestEnrichMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	companies := map[string]string{
+		"123456789": "Our Company SRL",
+		"987654321": "Partner SA",
+	}
+	lookups := make(map[string]int)
+	resolver := efactura.CompanyNameResolverFunc(func(ctx context.Context, cif string) (string, error) {
+		lookups[cif]++
+		if name, ok := companies[cif]; ok {
+			return name, nil
+		}
+		return "", errors.New("not found")
+	})
+
+	messages := []efactura.Message{
+		{
+			ID:      "1",
+			Type:    efactura.MessageTypeSentInvoice,
+			CIF:     "123456789",
+			Details: "Factura cu id_incarcare=42 emisa de cif_emitent=123456789 pentru cif_beneficiar=987654321",
+		},
+		{
+			ID:      "2",
+			Type:    efactura.MessageTypeReceivedInvoice,
+			CIF:     "123456789",
+			Details: "Factura cu id_incarcare=43 emisa de cif_emitent=987654321 pentru cif_beneficiar=123456789",
+		},
+		{
+			ID:      "3",
+			Type:    efactura.MessageTypeReceivedInvoice,
+			CIF:     "123456789",
+			Details: "Factura cu id_incarcare=44 emisa de cif_emitent=111111111 pentru cif_beneficiar=123456789",
+		},
+		{
+			ID:      "4",
+			Type:    efactura.MessageTypeError,
+			CIF:     "123456789",
+			Details: "Erori de validare identificate la factura transmisa cu id_incarcare=45",
+		},
+	}
+
+	views, err := efactura.EnrichMessages(context.Background(), messages, resolver)
+	assert.Error(err, "the lookup error for unknown CIF must be reported")
+	if !assert.Len(views, len(messages)) {
+		return
+	}
+
+	assert.Equal("Our Company SRL", views[0].SellerName)
+	assert.Equal("Partner SA", views[0].BuyerName)
+	assert.Equal("987654321", views[0].CounterpartyCIF)
+	assert.Equal("Partner SA", views[0].CounterpartyName)
+
+	assert.Equal("987654321", views[1].CounterpartyCIF)
+	assert.Equal("Partner SA", views[1].CounterpartyName)
+	assert.Equal("Our Company SRL", views[1].BuyerName)
+
+	assert.Equal("111111111", views[2].CounterpartyCIF)
+	assert.Equal("", views[2].CounterpartyName)
+
+	assert.Equal("", views[3].CounterpartyCIF)
+	assert.Equal("", views[3].SellerName)
+
+	for cif, n := range lookups {
+		assert.Equal(1, n, "cif %s must be resolved only once", cif)
+	}
+}
+
+func TestCachedCompanyNameResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int
+	resolver := efactura.NewCachedCompanyNameResolver(efactura.CompanyNameResolverFunc(
+		func(ctx context.Context, cif string) (string, error) {
+			calls++
+			if cif == "" {
+				return "", errors.New("invalid cif")
+			}
+			return "Company " + cif, nil
+		}))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		name, err := resolver.ResolveCompanyName(ctx, "123")
+		assert.NoError(err)
+		assert.Equal("Company 123", name)
+	}
+	assert.Equal(1, calls)
+
+	for i := 0; i < 2; i++ {
+		_, err := resolver.ResolveCompanyName(ctx, "")
+		assert.Error(err)
+	}
+	assert.Equal(3, calls, "failed lookups must not be cached")
+}
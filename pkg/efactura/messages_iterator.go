@@ -0,0 +1,224 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultMessagesIteratorMaxRecords is the default maximum number of
+	// records of a time interval walked by MessagesIterator. ANAF doesn't
+	// return more than 500000 records for an interval.
+	defaultMessagesIteratorMaxRecords = 500000
+)
+
+// ErrMessagesIteratorBudgetExhausted is the error of a MessagesIterator
+// that stopped because it made the maximum number of requests allowed (see
+// MessagesIteratorMaxRequests).
+var ErrMessagesIteratorBudgetExhausted = errors.New("messages iterator: requests budget exhausted")
+
+type messagesIteratorOptions struct {
+	maxRequests int
+	maxRecords  int64
+}
+
+// MessagesIteratorOption allows changing the behaviour of a MessagesIterator.
+type MessagesIteratorOption func(*messagesIteratorOptions)
+
+// MessagesIteratorMaxRequests sets the maximum number of requests made by
+// the MessagesIterator, so a walk doesn't burn the daily quota of the
+// messages list calls (see errors.LimitExceededError). When the budget is
+// exhausted, the iterator stops with ErrMessagesIteratorBudgetExhausted. By
+// default the number of requests is not limited.
+func MessagesIteratorMaxRequests(maxRequests int) MessagesIteratorOption {
+	return func(o *messagesIteratorOptions) {
+		o.maxRequests = maxRequests
+	}
+}
+
+// MessagesIteratorMaxRecords sets the maximum number of records of a time
+// interval. The intervals with more records are split in two halves before
+// walking their pages. The default is 500000, the maximum number of records
+// returned by ANAF for an interval.
+func MessagesIteratorMaxRecords(maxRecords int64) MessagesIteratorOption {
+	return func(o *messagesIteratorOptions) {
+		o.maxRecords = maxRecords
+	}
+}
+
+type messagesWindow struct {
+	start, end time.Time
+}
+
+// MessagesIterator walks all the messages returned by the list messages with
+// pagination endpoint for a CIF and a time interval, in the order returned
+// by ANAF. The intervals rejected by ANAF (see
+// MessagesListPaginationResponse.IsPaginationWindowError) or with too many
+// records are split in smaller intervals, and the messages are deduplicated
+// by ID. A MessagesIterator is not safe for concurrent use.
+//
+//	it := client.MessagesIterator(ctx, cif, startTs, endTs, efactura.MessageFilterAll)
+//	for it.Next() {
+//		message := it.Message()
+//	}
+//	if err := it.Err(); err != nil {
+//		// Handle error
+//	}
+type MessagesIterator struct {
+	client *Client
+	ctx    context.Context
+	cif    string
+	filter MessageFilterType
+	opts   messagesIteratorOptions
+
+	windows []messagesWindow
+	window  messagesWindow
+	page    int64
+
+	buffer   []Message
+	message  Message
+	seen     map[string]struct{}
+	requests int
+	err      error
+}
+
+// MessagesIterator returns a MessagesIterator for the messages of the given
+// CIF between startTs and endTs, filtered by msgType. ANAF keeps the
+// messages for 60 days, so startTs is moved to 60 days ago if older. No
+// request is made until the first call of Next. The requests are subject to
+// the client-side rate limits of RateLimitGroupMessagesList (see
+// ClientRateLimit).
+func (c *Client) MessagesIterator(
+	ctx context.Context, cif string, startTs, endTs time.Time, msgType MessageFilterType, opts ...MessagesIteratorOption,
+) *MessagesIterator {
+	it := &MessagesIterator{
+		client: c,
+		ctx:    ctx,
+		cif:    cif,
+		filter: msgType,
+		opts: messagesIteratorOptions{
+			maxRecords: defaultMessagesIteratorMaxRecords,
+		},
+		seen: make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(&it.opts)
+	}
+
+	if oldest := time.Now().Add(-maxSyncLookback); startTs.Before(oldest) {
+		startTs = oldest
+	}
+	if startTs.Before(endTs) {
+		it.windows = []messagesWindow{{start: startTs, end: endTs}}
+	}
+	return it
+}
+
+// Next advances the iterator to the next message, which will then be
+// available through the Message method. It returns false when there are no
+// more messages or an error occurred (see Err).
+func (it *MessagesIterator) Next() bool {
+	for it.err == nil {
+		if len(it.buffer) > 0 {
+			it.message, it.buffer = it.buffer[0], it.buffer[1:]
+			return true
+		}
+		if !it.fetch() {
+			return false
+		}
+	}
+	return false
+}
+
+// Message returns the current message.
+func (it *MessagesIterator) Message() Message {
+	return it.message
+}
+
+// Err returns the first error encountered by the iterator, or nil if all the
+// messages were walked.
+func (it *MessagesIterator) Err() error {
+	return it.err
+}
+
+// Requests returns the number of requests made so far by the iterator.
+func (it *MessagesIterator) Requests() int {
+	return it.requests
+}
+
+// RemainingRequests returns the number of requests that the iterator can
+// still make, or -1 if the number of requests is not limited (see
+// MessagesIteratorMaxRequests).
+func (it *MessagesIterator) RemainingRequests() int {
+	if it.opts.maxRequests <= 0 {
+		return -1
+	}
+	return max(0, it.opts.maxRequests-it.requests)
+}
+
+// fetch fetches the next page, splitting the current interval if needed.
+// It returns false if there are no more pages or an error occurred.
+func (it *MessagesIterator) fetch() bool {
+	if it.page == 0 {
+		if len(it.windows) == 0 {
+			return false
+		}
+		it.window, it.windows = it.windows[0], it.windows[1:]
+		it.page = 1
+	}
+	if it.opts.maxRequests > 0 && it.requests >= it.opts.maxRequests {
+		it.err = ErrMessagesIteratorBudgetExhausted
+		return false
+	}
+
+	w := it.window
+	res, err := it.client.GetMessagesListPagination(it.ctx, it.cif, w.start, w.end, it.page, it.filter)
+	it.requests++
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	tooManyRecords := it.page == 1 && it.opts.maxRecords > 0 && res.TotalRecords > it.opts.maxRecords
+	if (res.IsPaginationWindowError() || tooManyRecords) && w.end.Sub(w.start) > minPaginationWindow {
+		// The interval is split in two halves that are walked in order.
+		mid := w.start.Add(w.end.Sub(w.start) / 2)
+		it.windows = append([]messagesWindow{{start: w.start, end: mid}, {start: mid, end: w.end}}, it.windows...)
+		it.page = 0
+		return true
+	}
+	if !res.IsOk() {
+		it.err = fmt.Errorf("%s: %s", res.Title, res.Error)
+		return false
+	}
+
+	for _, m := range res.Messages {
+		if _, ok := it.seen[m.ID]; ok {
+			continue
+		}
+		it.seen[m.ID] = struct{}{}
+		it.buffer = append(it.buffer, m)
+	}
+	if it.page >= res.TotalPages {
+		it.page = 0
+	} else {
+		it.page++
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// setupMessagesListServer serves the given messages (created at the given
+// times) from the list messages with pagination endpoint, with pages of
+// pageSize messages, rejecting the intervals longer than maxWindow.
+func setupMessagesListServer(t *testing.T, times []time.Time, pageSize int64, maxWindow time.Duration, opts ...efactura.ClientConfigOption) (*efactura.Client, *int) {
+	t.Helper()
+
+	client, mux := setupTestClient(t, opts...)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/listaMesajePaginatieFactura", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		q := r.URL.Query()
+		startMs, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+		endMs, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+		page, _ := strconv.ParseInt(q.Get("pagina"), 10, 64)
+		start, end := time.UnixMilli(startMs), time.UnixMilli(endMs)
+		if end.Sub(start) > maxWindow {
+			writeJSON(w, map[string]any{
+				"eroare": "Intervalul dintre startTime si endTime nu poate fi mai mare de 10 zile",
+				"titlu":  "Lista Mesaje",
+			})
+			return
+		}
+
+		var inWindow []efactura.Message
+		for i, ts := range times {
+			if !ts.Before(start) && ts.Before(end) {
+				inWindow = append(inWindow, efactura.Message{
+					ID:   strconv.Itoa(1000 + i),
+					Type: efactura.MessageTypeReceivedInvoice,
+				})
+			}
+		}
+		if len(inWindow) == 0 {
+			writeJSON(w, map[string]any{
+				"eroare": "Nu exista mesaje in intervalul selectat",
+				"titlu":  "Lista Mesaje",
+			})
+			return
+		}
+		totalPages := (int64(len(inWindow)) + pageSize - 1) / pageSize
+		from := (page - 1) * pageSize
+		to := min(from+pageSize, int64(len(inWindow)))
+		writeJSON(w, map[string]any{
+			"mesaje":                              inWindow[from:to],
+			"numar_inregistrari_in_pagina":        to - from,
+			"numar_total_inregistrari_per_pagina": pageSize,
+			"numar_total_inregistrari":            len(inWindow),
+			"numar_total_pagini":                  totalPages,
+			"index_pagina_curenta":                page,
+			"titlu":                               "Lista Mesaje",
+		})
+	})
+	return client, &calls
+}
+
+func TestMessagesIterator(t *testing.T) {
+	assert := assert.New(t)
+
+	endTs := time.Now().Truncate(time.Millisecond)
+	startTs := endTs.Add(-30 * 24 * time.Hour)
+	// One message every 36 hours for the whole interval.
+	var times []time.Time
+	for ts := startTs; ts.Before(endTs); ts = ts.Add(36 * time.Hour) {
+		times = append(times, ts)
+	}
+
+	client, calls := setupMessagesListServer(t, times, 2, 10*24*time.Hour)
+	it := client.MessagesIterator(context.Background(), "123456789", startTs, endTs, efactura.MessageFilterAll)
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Message().ID)
+	}
+	if assert.NoError(it.Err()) && assert.Len(ids, len(times)) {
+		for i, id := range ids {
+			assert.Equal(strconv.Itoa(1000+i), id)
+		}
+	}
+	assert.Equal(*calls, it.Requests())
+	assert.Equal(-1, it.RemainingRequests())
+
+	// The intervals with too many records are split before walking the
+	// pages.
+	client, _ = setupMessagesListServer(t, times, 2, 30*24*time.Hour)
+	it = client.MessagesIterator(context.Background(), "123456789", startTs, endTs, efactura.MessageFilterAll,
+		efactura.MessagesIteratorMaxRecords(4))
+	ids = nil
+	for it.Next() {
+		ids = append(ids, it.Message().ID)
+	}
+	assert.NoError(it.Err())
+	assert.Len(ids, len(times))
+}
+
+func TestMessagesIteratorMaxRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	endTs := time.Now().Truncate(time.Millisecond)
+	startTs := endTs.Add(-24 * time.Hour)
+	var times []time.Time
+	for ts := startTs; ts.Before(endTs); ts = ts.Add(time.Hour) {
+		times = append(times, ts)
+	}
+
+	client, calls := setupMessagesListServer(t, times, 5, 10*24*time.Hour)
+	it := client.MessagesIterator(context.Background(), "123456789", startTs, endTs, efactura.MessageFilterAll,
+		efactura.MessagesIteratorMaxRequests(2))
+	var count int
+	for it.Next() {
+		count++
+	}
+	assert.ErrorIs(it.Err(), efactura.ErrMessagesIteratorBudgetExhausted)
+	assert.Equal(10, count)
+	assert.Equal(2, *calls)
+	assert.Equal(0, it.RemainingRequests())
+}
+
+func TestMessagesIteratorError(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/listaMesajePaginatieFactura", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"eroare": "CIF introdus= 123456789 nu este un numar",
+			"titlu":  "Lista Mesaje",
+		})
+	})
+
+	endTs := time.Now()
+	it := client.MessagesIterator(context.Background(), "123456789", endTs.Add(-24*time.Hour), endTs, efactura.MessageFilterAll)
+	assert.False(it.Next())
+	assert.ErrorContains(it.Err(), "CIF introdus= 123456789 nu este un numar")
+
+	// No messages is not an error.
+	client, _ = setupMessagesListServer(t, nil, 5, 10*24*time.Hour)
+	it = client.MessagesIterator(context.Background(), "123456789", endTs.Add(-24*time.Hour), endTs, efactura.MessageFilterAll)
+	assert.False(it.Next())
+	assert.NoError(it.Err())
+}
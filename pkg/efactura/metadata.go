@@ -0,0 +1,78 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+)
+
+// Metadata is arbitrary user metadata (eg. order IDs, internal references)
+// that can be attached to an upload or download. The metadata is never
+// serialized in the XML sent to ANAF, it's only carried along with the
+// responses so that integrators don't need parallel lookup tables.
+type Metadata map[string]string
+
+// Get returns the value for key, or empty string if the key is not set.
+func (md Metadata) Get(key string) string {
+	return md[key]
+}
+
+// Clone returns a copy of the metadata. Clone of a nil Metadata is nil.
+func (md Metadata) Clone() Metadata {
+	if md == nil {
+		return nil
+	}
+	c := make(Metadata, len(md))
+	for k, v := range md {
+		c[k] = v
+	}
+	return c
+}
+
+// Merge returns a new Metadata with the keys from md and other. Keys from
+// other override keys from md. If both are empty, nil is returned.
+func (md Metadata) Merge(other Metadata) Metadata {
+	if len(md) == 0 && len(other) == 0 {
+		return nil
+	}
+	m := make(Metadata, len(md)+len(other))
+	for k, v := range md {
+		m[k] = v
+	}
+	for k, v := range other {
+		m[k] = v
+	}
+	return m
+}
+
+type metadataContextKey struct{}
+
+// ContextWithMetadata returns a copy of ctx carrying the given metadata
+// (merged with the metadata already carried by ctx). All the responses
+// returned by Client methods called with this context will have the
+// metadata attached.
+func ContextWithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataContextKey{}, MetadataFromContext(ctx).Merge(md))
+}
+
+// MetadataFromContext returns the metadata carried by ctx, or nil if ctx
+// doesn't carry any metadata.
+func MetadataFromContext(ctx context.Context) Metadata {
+	if ctx == nil {
+		return nil
+	}
+	md, _ := ctx.Value(metadataContextKey{}).(Metadata)
+	return md
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestMetadataPassthrough(t *testing.T) {
+	assert := assert.New(t)
+
+	client, requests := setupTestUploadClient(t)
+	ctx := efactura.ContextWithMetadata(context.Background(), efactura.Metadata{
+		"order_id": "ORD-1",
+		"source":   "shop",
+	})
+
+	res, err := client.UploadInvoice(ctx, efactura.Invoice{ID: "1"}, "123456789",
+		efactura.UploadOptionMetadata(efactura.Metadata{"source": "erp"}))
+	if assert.NoError(err) {
+		assert.Equal(efactura.Metadata{"order_id": "ORD-1", "source": "erp"}, res.Metadata)
+	}
+	if assert.Len(*requests, 1) {
+		assert.Empty((*requests)[0].Query.Get("order_id"), "metadata must not be sent to ANAF")
+	}
+
+	// Metadata is allowed for messages.
+	res, err = client.UploadRaspMessage(efactura.ContextWithMetadata(context.Background(), efactura.Metadata{"k": "v"}),
+		efactura.RaspMessage{UploadIndex: 42, Message: "test"}, "123456789")
+	if assert.NoError(err) {
+		assert.Equal("v", res.Metadata.Get("k"))
+	}
+	res, err = client.Upload(context.Background(), efactura.RaspMessage{UploadIndex: 42, Message: "test"}, "123456789",
+		efactura.UploadOptionMetadata(efactura.Metadata{"k": "v2"}))
+	if assert.NoError(err) {
+		assert.Equal("v2", res.Metadata.Get("k"))
+	}
+}
+
+func TestMetadataDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		_, _ = w.Write([]byte("PK"))
+	})
+
+	md := efactura.Metadata{"order_id": "ORD-2"}
+	res, err := client.DownloadInvoice(efactura.ContextWithMetadata(context.Background(), md), 1)
+	if assert.NoError(err) && assert.True(res.IsOk()) {
+		assert.Equal(md, res.Metadata)
+		// The response metadata must be a copy.
+		res.Metadata["order_id"] = "changed"
+		assert.Equal("ORD-2", md.Get("order_id"))
+	}
+
+	res, err = client.DownloadInvoice(context.Background(), 1)
+	if assert.NoError(err) {
+		assert.Nil(res.Metadata)
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/printesoi/xml-go"
+
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+// ParseMode controls how non-conforming XML documents (eg. downloaded
+// invoices emitted by suppliers) are handled when parsing.
+type ParseMode int
+
+const (
+	// ParseModeDefault unmarshals the XML as it is, without any checks. This
+	// is the behaviour of UnmarshalInvoice.
+	ParseModeDefault ParseMode = iota
+	// ParseModeStrict fails on the first non-conforming construct found in
+	// the XML (eg. empty optional elements or wrong attribute casing).
+	ParseModeStrict
+	// ParseModeLenient fixes what can be fixed in the XML (eg. removes empty
+	// optional elements and fixes the attribute casing) before unmarshaling,
+	// and reports all the fixes as ParseIssues.
+	ParseModeLenient
+)
+
+// String implements the fmt.Stringer interface.
+func (m ParseMode) String() string {
+	switch m {
+	case ParseModeDefault:
+		return "default"
+	case ParseModeStrict:
+		return "strict"
+	case ParseModeLenient:
+		return "lenient"
+	}
+	return fmt.Sprintf("ParseMode(%d)", int(m))
+}
+
+// ParseIssue is a non-conforming construct found while parsing a document.
+type ParseIssue struct {
+	// Path is the path of the element, using local names (eg.
+	// "Invoice/InvoiceLine/Price/PriceAmount").
+	Path string
+	// Line is the line of the element in the XML document.
+	Line int
+	// Message is a human readable description of the issue.
+	Message string
+}
+
+// String implements the fmt.Stringer interface.
+func (i ParseIssue) String() string {
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Path, i.Message)
+}
+
+// ParseError is the error returned by ParseModeStrict for the first
+// non-conforming construct found in the XML.
+type ParseError struct {
+	Issue ParseIssue
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return "strict parse: " + e.Issue.String()
+}
+
+// ParseOption allows selecting the parse behaviour per call.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	mode          ParseMode
+	charsetReader pxml.CharsetReader
+	stripMarkup   XMLMarkup
+}
+
+// ParseOptionMode sets the ParseMode used for parsing the document.
+func ParseOptionMode(mode ParseMode) ParseOption {
+	return func(o *parseOptions) {
+		o.mode = mode
+	}
+}
+
+// ParseOptionCharsetReader sets the CharsetReader used for converting a
+// document that is not encoded as UTF-8 (eg. Windows-1252 or ISO-8859-2).
+// If not set, the CharsetReader set with pxml.SetCharsetReader is used.
+func ParseOptionCharsetReader(cr pxml.CharsetReader) ParseOption {
+	return func(o *parseOptions) {
+		o.charsetReader = cr
+	}
+}
+
+// UnmarshalInvoiceMode unmarshals an Invoice from XML data using the given
+// ParseMode. For ParseModeLenient, the fixes applied to the XML are returned
+// as issues (even if unmarshaling fails). For ParseModeStrict, a *ParseError
+// is returned for the first non-conforming construct.
+func UnmarshalInvoiceMode(xmlData []byte, invoice *Invoice, mode ParseMode) (issues []ParseIssue, err error) {
+	return unmarshalXMLMode(xmlData, invoice, mode)
+}
+
+// prepareXMLForParse checks/fixes the XML data according to the parse mode.
+func prepareXMLForParse(xmlData []byte, mode ParseMode) ([]byte, []ParseIssue, error) {
+	switch mode {
+	case ParseModeDefault:
+		return xmlData, nil, nil
+	case ParseModeStrict, ParseModeLenient:
+	default:
+		return nil, nil, fmt.Errorf("invalid parse mode %v", mode)
+	}
+
+	doc, err := parseXMLDocument(xmlData)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := xmlSanitizer{failFast: mode == ParseModeStrict}
+	s.sanitizeNode(doc.root, "")
+	if mode == ParseModeStrict {
+		if len(s.issues) > 0 {
+			return nil, nil, &ParseError{Issue: s.issues[0]}
+		}
+		return xmlData, nil, nil
+	}
+	if len(s.issues) == 0 {
+		return xmlData, nil, nil
+	}
+	return doc.bytes(), s.issues, nil
+}
+
+// knownXMLAttributes are the attributes used in the documents, used for
+// fixing the attribute casing in ParseModeLenient.
+var knownXMLAttributes = []string{
+	"currencyID",
+	"filename",
+	"listID",
+	"mimeCode",
+	"name",
+	"schemeID",
+	"unitCode",
+	"unitCodeListAgencyID",
+	"unitCodeListAgencyName",
+	"unitCodeListID",
+}
+
+type xmlSanitizer struct {
+	failFast bool
+	issues   []ParseIssue
+}
+
+func (s *xmlSanitizer) addIssue(n *xmlNode, path, format string, args ...any) {
+	if s.failFast && len(s.issues) > 0 {
+		return
+	}
+	s.issues = append(s.issues, ParseIssue{
+		Path:    path,
+		Line:    n.line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// sanitizeNode fixes the attributes casing of the node and removes its empty
+// children. It returns true if the node is empty (has no child elements, no
+// text and no attributes other than the namespace declarations), so
+// attribute-only elements (eg. <cbc:X schemeID="..."/>) are kept.
+func (s *xmlSanitizer) sanitizeNode(n *xmlNode, parentPath string) (empty bool) {
+	path := n.start.Name.Local
+	if parentPath != "" {
+		path = parentPath + "/" + path
+	}
+
+	attrs := n.start.Attr[:0]
+	for _, attr := range n.start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local != "xmlns" {
+			for _, known := range knownXMLAttributes {
+				if attr.Name.Local == known || !strings.EqualFold(attr.Name.Local, known) {
+					continue
+				}
+				if hasXMLAttr(n.start.Attr, known) {
+					s.addIssue(n, path, "duplicate attribute %s removed", attr.Name.Local)
+					attr.Name.Local = ""
+				} else {
+					s.addIssue(n, path, "attribute %s renamed to %s", attr.Name.Local, known)
+					attr.Name.Local = known
+				}
+				break
+			}
+			if attr.Name.Local == "" {
+				continue
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	n.start.Attr = attrs
+
+	hasAttrs := false
+	for _, attr := range n.start.Attr {
+		if attr.Name.Space != "xmlns" && !(attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			hasAttrs = true
+			break
+		}
+	}
+
+	hasText, hasElements := false, false
+	children := n.children[:0]
+	for _, child := range n.children {
+		switch c := child.(type) {
+		case *xmlNode:
+			if s.sanitizeNode(c, path) {
+				s.addIssue(c, path+"/"+c.start.Name.Local, "empty element removed")
+				continue
+			}
+			hasElements = true
+		case xml.CharData:
+			if len(bytes.TrimSpace(c)) > 0 {
+				hasText = true
+			}
+		}
+		children = append(children, child)
+	}
+	n.children = children
+	return parentPath != "" && !hasText && !hasElements && !hasAttrs
+}
+
+func hasXMLAttr(attrs []xml.Attr, local string) bool {
+	for _, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlNode is an element from a document parsed with raw tokens (the names
+// keep the namespace prefixes instead of the namespace URLs), so it can be
+// written back without changing the namespace declarations.
+type xmlNode struct {
+	start    xml.StartElement
+	line     int
+	children []any // *xmlNode, xml.CharData, xml.Comment or xml.ProcInst
+	// selfClosing is true if the element was written as <a/> in the
+	// document, so it can be written back the same way.
+	selfClosing bool
+}
+
+type xmlDocument struct {
+	prolog []xml.Token
+	root   *xmlNode
+	epilog []xml.Token
+}
+
+func parseXMLDocument(xmlData []byte) (*xmlDocument, error) {
+	doc := new(xmlDocument)
+	d := xml.NewDecoder(bytes.NewReader(xmlData))
+	var stack []*xmlNode
+	for {
+		line, _ := d.InputPos()
+		offset := d.InputOffset()
+		t, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t = xml.CopyToken(t)
+
+		switch tok := t.(type) {
+		case xml.StartElement:
+			n := &xmlNode{start: tok, line: line}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if doc.root == nil {
+				doc.root = n
+			} else {
+				return nil, errors.New("xml: multiple root elements")
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("xml: unexpected end element %s", tok.Name.Local)
+			}
+			// The end element of <a/> is synthesized by the decoder
+			// without consuming any input.
+			stack[len(stack)-1].selfClosing = d.InputOffset() == offset
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) > 0 {
+				switch t.(type) {
+				case xml.CharData, xml.Comment, xml.ProcInst:
+					parent := stack[len(stack)-1]
+					parent.children = append(parent.children, t)
+				}
+				continue
+			}
+			if doc.root == nil {
+				doc.prolog = append(doc.prolog, t)
+			} else {
+				doc.epilog = append(doc.epilog, t)
+			}
+		}
+	}
+	if doc.root == nil || len(stack) > 0 {
+		return nil, errors.New("xml: incomplete document")
+	}
+	return doc, nil
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+		"\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+func rawXMLName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// bytes returns the XML encoding of the document.
+func (doc *xmlDocument) bytes() []byte {
+	var b bytes.Buffer
+	writeMisc := func(tokens []xml.Token) {
+		for _, t := range tokens {
+			switch tok := t.(type) {
+			case xml.ProcInst:
+				fmt.Fprintf(&b, "<?%s %s?>", tok.Target, tok.Inst)
+			case xml.Directive:
+				fmt.Fprintf(&b, "<!%s>", tok)
+			case xml.Comment:
+				fmt.Fprintf(&b, "<!--%s-->", tok)
+			case xml.CharData:
+				xmlTextEscaper.WriteString(&b, string(tok))
+			}
+		}
+	}
+	writeMisc(doc.prolog)
+	doc.root.write(&b)
+	writeMisc(doc.epilog)
+	return b.Bytes()
+}
+
+func (n *xmlNode) write(b *bytes.Buffer) {
+	name := rawXMLName(n.start.Name)
+	b.WriteString("<" + name)
+	for _, attr := range n.start.Attr {
+		b.WriteString(" " + rawXMLName(attr.Name) + `="`)
+		xmlAttrEscaper.WriteString(b, attr.Value)
+		b.WriteString(`"`)
+	}
+	if len(n.children) == 0 && n.selfClosing {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">")
+	for _, child := range n.children {
+		switch c := child.(type) {
+		case *xmlNode:
+			c.write(b)
+		case xml.CharData:
+			xmlTextEscaper.WriteString(b, string(c))
+		case xml.Comment:
+			fmt.Fprintf(b, "<!--%s-->", c)
+		case xml.ProcInst:
+			fmt.Fprintf(b, "<?%s %s?>", c.Target, c.Inst)
+		}
+	}
+	b.WriteString("</" + name + ">")
+}
+
+// unmarshalXMLMode is like pxml.UnmarshalXML, but checks/fixes the XML data
+// according to the parse mode first.
+func unmarshalXMLMode(xmlData []byte, v any, mode ParseMode) (issues []ParseIssue, err error) {
+	if xmlData, err = pxml.ConvertToUTF8(xmlData, nil); err != nil {
+		return
+	}
+	if xmlData, issues, err = prepareXMLForParse(xmlData, mode); err != nil {
+		return
+	}
+	err = pxml.UnmarshalXML(xmlData, v)
+	return
+}
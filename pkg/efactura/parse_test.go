@@ -0,0 +1,130 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestUnmarshalInvoiceMode(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(2)).
+		WithGrossPriceAmount(types.D(10)).
+		WithItemName("Item").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	invoice, err := NewInvoiceBuilder("INV-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithInvoiceLines([]InvoiceLine{line}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	xmlData, err := invoice.XMLIndent("", " ")
+	if !assert.NoError(err) {
+		return
+	}
+
+	// A conforming document is parsed the same in all modes.
+	for _, mode := range []ParseMode{ParseModeDefault, ParseModeStrict, ParseModeLenient} {
+		var parsed Invoice
+		issues, err := UnmarshalInvoiceMode(xmlData, &parsed, mode)
+		assert.NoError(err, "mode %s", mode)
+		assert.Empty(issues, "mode %s", mode)
+		assert.Equal("INV-1", parsed.ID)
+	}
+
+	// Empty optional nodes and wrong attribute casing.
+	badXML := strings.Replace(string(xmlData), "</cbc:IssueDate>",
+		"</cbc:IssueDate>\n <cbc:DueDate></cbc:DueDate>\n <cac:InvoicePeriod><cbc:StartDate/></cac:InvoicePeriod>", 1)
+	badXML = strings.Replace(badXML, `<cbc:PayableAmount currencyID="RON">`, `<cbc:PayableAmount CurrencyId="RON">`, 1)
+
+	var parsed Invoice
+	_, err = UnmarshalInvoiceMode([]byte(badXML), &parsed, ParseModeDefault)
+	assert.Error(err)
+
+	_, err = UnmarshalInvoiceMode([]byte(badXML), &parsed, ParseModeStrict)
+	var parseErr *ParseError
+	if assert.True(errors.As(err, &parseErr)) {
+		assert.Equal("Invoice/DueDate", parseErr.Issue.Path)
+		assert.Equal("empty element removed", parseErr.Issue.Message)
+	}
+
+	parsed = Invoice{}
+	issues, err := UnmarshalInvoiceMode([]byte(badXML), &parsed, ParseModeLenient)
+	if assert.NoError(err) {
+		assert.Equal("INV-1", parsed.ID)
+		assert.Nil(parsed.DueDate)
+		assert.Nil(parsed.InvoicePeriod)
+		assert.Equal(CurrencyRON, parsed.LegalMonetaryTotal.PayableAmount.CurrencyID)
+		assert.Equal(invoice.LegalMonetaryTotal.PayableAmount.Amount.String(),
+			parsed.LegalMonetaryTotal.PayableAmount.Amount.String())
+	}
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.Path+": "+issue.Message)
+	}
+	assert.ElementsMatch([]string{
+		"Invoice/DueDate: empty element removed",
+		"Invoice/InvoicePeriod/StartDate: empty element removed",
+		"Invoice/InvoicePeriod: empty element removed",
+		"Invoice/LegalMonetaryTotal/PayableAmount: attribute CurrencyId renamed to currencyID",
+	}, messages)
+
+	_, err = UnmarshalInvoiceMode(xmlData, &parsed, ParseMode(10))
+	assert.Error(err)
+}
+
+func TestPrepareXMLForParseKeepsAttributeOnlyElements(t *testing.T) {
+	assert := assert.New(t)
+
+	xmlData := []byte(`<Invoice xmlns:cbc="urn:cbc" xmlns:cac="urn:cac">` +
+		`<cbc:EndpointID schemeID="EM"/>` +
+		`<cac:Party xmlns:x="urn:x"><cbc:Empty xmlns:y="urn:y"/></cac:Party>` +
+		`</Invoice>`)
+	sanitized, issues, err := prepareXMLForParse(xmlData, ParseModeLenient)
+	if assert.NoError(err) {
+		assert.Contains(string(sanitized), `<cbc:EndpointID schemeID="EM"/>`)
+		assert.NotContains(string(sanitized), "cac:Party")
+	}
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.Path+": "+issue.Message)
+	}
+	assert.ElementsMatch([]string{
+		"Invoice/Party/Empty: empty element removed",
+		"Invoice/Party: empty element removed",
+	}, messages)
+}
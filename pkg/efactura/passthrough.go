@@ -0,0 +1,92 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPassThroughChecksumMismatch is returned by UploadPassThrough if the
+// uploaded bytes do not match the provided XML.
+var ErrPassThroughChecksumMismatch = errors.New("pass-through upload: checksum mismatch")
+
+// xmlSHA256 returns the hex encoded SHA-256 checksum of the data.
+func xmlSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyPassThroughXML parses the XML document without keeping the result,
+// to check that it's a well-formed document of the detected upload standard.
+func verifyPassThroughXML(xmlData []byte) (st UploadStandard, err error) {
+	if st, err = DetectUploadStandard(xmlData); err != nil {
+		return
+	}
+	switch st {
+	case UploadStandardUBL:
+		var invoice Invoice
+		err = UnmarshalInvoice(xmlData, &invoice)
+	case UploadStandardCN:
+		var creditNote CreditNote
+		err = UnmarshalCreditNote(xmlData, &creditNote)
+	}
+	return
+}
+
+// UploadPassThrough uploads the given XML exactly as provided, for documents
+// produced (and maybe signed) by another system. Unlike Upload, the document
+// is never re-serialized or mutated: it's only parsed for verification and
+// for detecting the upload standard (see DetectUploadStandard). The SHA-256
+// checksum of the provided XML is computed before the upload and checked
+// against the checksum of the bytes sent to ANAF and the checksum of the
+// XML after the upload; on mismatch ErrPassThroughChecksumMismatch is
+// returned. The checksum is returned in the SHA256 field of the response.
+// Upload options are allowed as for Upload.
+func (c *Client) UploadPassThrough(
+	ctx context.Context, xmlData []byte, cif string, opts ...UploadOption,
+) (response *UploadResponse, err error) {
+	checksum := xmlSHA256(xmlData)
+
+	st, er := verifyPassThroughXML(xmlData)
+	if err = er; err != nil {
+		return nil, fmt.Errorf("pass-through upload: invalid document: %w", err)
+	}
+	if st == UploadStandardRASP {
+		o := uploadOptions{}
+		for _, opt := range opts {
+			opt(&o)
+		}
+		if o.hasFlags() {
+			return nil, fmt.Errorf("upload options are not supported for the %s standard", st)
+		}
+	}
+
+	sent := sha256.New()
+	response, err = c.UploadXML(ctx, io.TeeReader(bytes.NewReader(xmlData), sent), st, cif, opts...)
+	if err != nil {
+		return
+	}
+	if hex.EncodeToString(sent.Sum(nil)) != checksum || xmlSHA256(xmlData) != checksum {
+		return response, ErrPassThroughChecksumMismatch
+	}
+	response.SHA256 = checksum
+	return
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package peppol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// ConvertConfig is the config used by FromInvoice.
+type ConvertConfig struct {
+	// ProfileID is the business process type (BT-23). Default is the
+	// ProfileID of the invoice, or ProfileIDBilling if empty.
+	ProfileID string
+	// SupplierEndpointID is the electronic address of the Seller (BT-34).
+	// Default is the EndpointID of the invoice.
+	SupplierEndpointID *efactura.ValueWithAttrs
+	// CustomerEndpointID is the electronic address of the Buyer (BT-49).
+	// Default is the EndpointID of the invoice.
+	CustomerEndpointID *efactura.ValueWithAttrs
+}
+
+// ConvertOption allows gradually modifying a ConvertConfig.
+type ConvertOption func(*ConvertConfig)
+
+// ConvertProfileID sets the business process type (BT-23).
+func ConvertProfileID(profileID string) ConvertOption {
+	return func(c *ConvertConfig) {
+		c.ProfileID = profileID
+	}
+}
+
+// ConvertSupplierEndpoint sets the electronic address of the Seller
+// (BT-34), eg. the Peppol participant identifier.
+func ConvertSupplierEndpoint(scheme EndpointScheme, id string) ConvertOption {
+	return func(c *ConvertConfig) {
+		c.SupplierEndpointID = efactura.MakeValueWithScheme(id, string(scheme)).Ptr()
+	}
+}
+
+// ConvertCustomerEndpoint sets the electronic address of the Buyer (BT-49),
+// eg. the Peppol participant identifier.
+func ConvertCustomerEndpoint(scheme EndpointScheme, id string) ConvertOption {
+	return func(c *ConvertConfig) {
+		c.CustomerEndpointID = efactura.MakeValueWithScheme(id, string(scheme)).Ptr()
+	}
+}
+
+// FromInvoice converts an efactura.Invoice to a Peppol BIS Invoice.
+//
+// Peppol BIS requires the electronic addresses of the Seller and the Buyer
+// (BT-34, BT-49). If not set with the options or in the invoice, the
+// address of a party with a Romanian VAT identifier defaults to the VAT
+// identifier (EndpointSchemeROVAT); otherwise an error is returned. Since
+// Peppol BIS allows only one invoice note, multiple notes (BG-1) are joined
+// in a single note, separated by new lines. An error is returned for the
+// type codes that cannot be used in a Peppol BIS invoice: credit notes must
+// be sent as a Peppol BIS CreditNote, and the RO accounting invoice (751) is
+// specific to CIUS-RO.
+func FromInvoice(iv efactura.Invoice, opts ...ConvertOption) (Invoice, error) {
+	cfg := ConvertConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	switch iv.InvoiceTypeCode {
+	case efactura.InvoiceTypeCreditNote, efactura.InvoiceTypeInvoiceInformationAccountingPurposes:
+		return Invoice{}, fmt.Errorf("peppol: invoice type code %s is not supported", iv.InvoiceTypeCode)
+	}
+
+	pi := Invoice(iv)
+	if cfg.ProfileID != "" {
+		pi.ProfileID = cfg.ProfileID
+	}
+
+	var errs []error
+	supplierEndpointID := cfg.SupplierEndpointID
+	if supplierEndpointID == nil {
+		supplierEndpointID = defaultEndpointID(iv.Supplier.Party.EndpointID, iv.Supplier.Party.VATID())
+	}
+	if supplierEndpointID == nil {
+		errs = append(errs, errors.New("peppol: missing Seller electronic address (BT-34)"))
+	}
+	pi.Supplier.Party.EndpointID = supplierEndpointID
+
+	customerEndpointID := cfg.CustomerEndpointID
+	if customerEndpointID == nil {
+		customerEndpointID = defaultEndpointID(iv.Customer.Party.EndpointID, iv.Customer.Party.VATID())
+	}
+	if customerEndpointID == nil {
+		errs = append(errs, errors.New("peppol: missing Buyer electronic address (BT-49)"))
+	}
+	pi.Customer.Party.EndpointID = customerEndpointID
+	if len(errs) > 0 {
+		return Invoice{}, errors.Join(errs...)
+	}
+
+	if len(iv.Note) > 1 {
+		pi.Note = []efactura.InvoiceNote{joinNotes(iv.Note)}
+	}
+
+	pi.Prefill()
+	return pi, nil
+}
+
+// Invoice converts the Peppol BIS Invoice to an efactura.Invoice with the
+// CIUS-RO customization. The business process type (BT-23) and the
+// electronic addresses (BT-34, BT-49) are kept. The converted invoice is
+// not checked for the CIUS-RO rules (eg. the Romanian address codes), use
+// efactura.Invoice.Lint for that.
+func (pi Invoice) Invoice() efactura.Invoice {
+	iv := efactura.Invoice(pi)
+	iv.Prefill()
+	return iv
+}
+
+// defaultEndpointID returns the endpointID if set, or an electronic address
+// created from a Romanian VAT identifier.
+func defaultEndpointID(endpointID *efactura.ValueWithAttrs, vatID string) *efactura.ValueWithAttrs {
+	if endpointID != nil {
+		return endpointID
+	}
+	if strings.HasPrefix(vatID, "RO") {
+		return efactura.MakeValueWithScheme(vatID, string(EndpointSchemeROVAT)).Ptr()
+	}
+	return nil
+}
+
+// joinNotes joins the notes in a single note. The subject code (BT-21) is
+// kept only if all the notes have the same subject code.
+func joinNotes(notes []efactura.InvoiceNote) efactura.InvoiceNote {
+	texts := make([]string, 0, len(notes))
+	note := efactura.InvoiceNote{SubjectCode: notes[0].SubjectCode}
+	for _, n := range notes {
+		if n.SubjectCode != note.SubjectCode {
+			note.SubjectCode = ""
+		}
+		texts = append(texts, n.Note)
+	}
+	note.Note = strings.Join(texts, "\n")
+	return note
+}
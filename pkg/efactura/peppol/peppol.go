@@ -0,0 +1,105 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package peppol implements the Peppol BIS Billing 3.0 invoice. Peppol BIS
+// and CIUS-RO are both customizations of EN 16931 over UBL 2.1, so a Peppol
+// Invoice has the same structure as an efactura.Invoice, but it's marshaled
+// with the Peppol customization and profile identifiers. FromInvoice and
+// Invoice.Invoice convert between efactura.Invoice and Invoice, so the same
+// model can be used to generate both CIUS-RO and Peppol BIS documents.
+package peppol
+
+import (
+	"github.com/printesoi/xml-go"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+// Constants for namespaces and identifiers
+const (
+	// CustomizationID is the specification identifier (BT-24) of Peppol BIS
+	// Billing 3.0.
+	CustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	// ProfileIDBilling is the business process type (BT-23) of the Peppol
+	// billing process.
+	ProfileIDBilling = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+
+	xmlnsUBLInvoice2 = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	xmlnsUBLcac      = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	xmlnsUBLcbc      = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+// EndpointScheme is a code from the Electronic Address Scheme (EAS) code
+// list, used as the schemeID of the electronic addresses (BT-34, BT-49).
+type EndpointScheme string
+
+const (
+	// EndpointSchemeDUNS is the D-U-N-S number.
+	EndpointSchemeDUNS EndpointScheme = "0060"
+	// EndpointSchemeGLN is the Global Location Number.
+	EndpointSchemeGLN EndpointScheme = "0088"
+	// EndpointSchemeROVAT is the Romanian VAT number.
+	EndpointSchemeROVAT EndpointScheme = "9947"
+	// EndpointSchemeEmail is an email address.
+	EndpointSchemeEmail EndpointScheme = "EM"
+)
+
+// Invoice is a Peppol BIS Billing 3.0 invoice. The fields are the same as
+// for efactura.Invoice.
+type Invoice efactura.Invoice
+
+// Prefill sets the namespaces, the CustomizationID and the ProfileID (if
+// empty) for ensuring that the required attributes and properties are set
+// for a valid Peppol BIS XML. The UBLVersionID is cleared, since it should
+// not be used in Peppol BIS documents.
+func (pi *Invoice) Prefill() {
+	pi.Namespace = xmlnsUBLInvoice2
+	pi.NamespaceCAC = xmlnsUBLcac
+	pi.NamespaceCBC = xmlnsUBLcbc
+	pi.UBLVersionID = ""
+	pi.CustomizationID = CustomizationID
+	if pi.ProfileID == "" {
+		pi.ProfileID = ProfileIDBilling
+	}
+}
+
+func (pi Invoice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// This allows us to strip the MarshalXML method.
+	type invoice Invoice
+	e.AddNamespaceBinding(xmlnsUBLcac, "cac")
+	e.AddSkipNamespaceAttrForPrefix(xmlnsUBLcac, "cac")
+	e.AddNamespaceBinding(xmlnsUBLcbc, "cbc")
+	e.AddSkipNamespaceAttrForPrefix(xmlnsUBLcbc, "cbc")
+	pi.Prefill()
+	return e.EncodeElement(invoice(pi), start)
+}
+
+// XML returns the XML encoding of the Invoice
+func (pi Invoice) XML() ([]byte, error) {
+	return pxml.MarshalXMLWithHeader(pi)
+}
+
+// XMLIndent works like XML, but each XML element begins on a new
+// indented line that starts with prefix and is followed by one or more
+// copies of indent according to the nesting depth.
+func (pi Invoice) XMLIndent(prefix, indent string) ([]byte, error) {
+	return pxml.MarshalIndentXMLWithHeader(pi, prefix, indent)
+}
+
+// Unmarshal unmarshals an Invoice from XML data. This method does not check
+// if the unmarshaled document is valid.
+func Unmarshal(xmlData []byte, pi *Invoice) error {
+	return pxml.UnmarshalXML(xmlData, pi)
+}
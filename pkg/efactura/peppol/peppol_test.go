@@ -0,0 +1,184 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package peppol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func buildTestInvoice(t *testing.T) efactura.Invoice {
+	t.Helper()
+
+	line, err := efactura.NewInvoiceLineBuilder("1", efactura.CurrencyEUR).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(2)).
+		WithGrossPriceAmount(types.D(100)).
+		WithItemName("Produs").
+		WithItemTaxCategory(efactura.InvoiceLineTaxCategory{
+			TaxScheme: efactura.TaxSchemeVAT,
+			ID:        efactura.TaxCategoryVATExemptIntraCommunitySupply,
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invoice, err := efactura.NewInvoiceBuilder("EXP-1").
+		WithInvoiceTypeCode(efactura.InvoiceTypeCommercialInvoice).
+		WithIssueDate(types.MakeDate(2024, 4, 1)).
+		WithDueDate(types.MakeDate(2024, 5, 1)).
+		WithDocumentCurrencyCode(efactura.CurrencyEUR).
+		WithTaxCurrencyCode(efactura.CurrencyRON).
+		WithDocumentToTaxCurrencyExchangeRate(types.D(4.97)).
+		WithBuyerReference("REF-1").
+		WithSupplier(efactura.InvoiceSupplierParty{
+			PostalAddress: efactura.MakeInvoiceSupplierPostalAddress(efactura.PostalAddress{
+				Country:          efactura.CountryRO,
+				CountrySubentity: efactura.CountrySubentityRO_B,
+				CityName:         efactura.CityNameROBSector1,
+				Line1:            "Strada Exemplu 1",
+			}),
+			TaxScheme: &efactura.InvoicePartyTaxScheme{
+				TaxScheme: efactura.TaxSchemeVAT,
+				CompanyID: "RO10000008",
+			},
+			LegalEntity: efactura.InvoiceSupplierLegalEntity{
+				Name:      "Furnizor SRL",
+				CompanyID: efactura.NewValueWithAttrs("J40/1/2020"),
+			},
+		}).
+		WithCustomer(efactura.InvoiceCustomerParty{
+			PostalAddress: efactura.MakeInvoiceCustomerPostalAddress(efactura.PostalAddress{
+				Country:  efactura.Country{Code: efactura.CountryCodeDE},
+				CityName: "Berlin",
+				Line1:    "Hauptstraße 1",
+			}),
+			TaxScheme: &efactura.InvoicePartyTaxScheme{
+				TaxScheme: efactura.TaxSchemeVAT,
+				CompanyID: "DE123456789",
+			},
+			LegalEntity: efactura.InvoiceCustomerLegalEntity{
+				Name: "Kunde GmbH",
+			},
+		}).
+		AddTaxExemptionReason(efactura.TaxCategoryVATExemptIntraCommunitySupply,
+			"Livrare intracomunitară scutită", efactura.TaxExemptionCodeVATEX_EU_IC).
+		WithInvoiceLines([]efactura.InvoiceLine{line}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return invoice
+}
+
+func TestFromInvoice(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := buildTestInvoice(t)
+	invoice.Note = []efactura.InvoiceNote{
+		{SubjectCode: "AAI", Note: "Livrare intracomunitară"},
+		{SubjectCode: "AAI", Note: "Scutit de TVA"},
+	}
+
+	_, err := FromInvoice(invoice)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "BT-49")
+		assert.NotContains(err.Error(), "BT-34")
+	}
+
+	doc, err := FromInvoice(invoice, ConvertCustomerEndpoint(EndpointSchemeGLN, "4000001000005"))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(CustomizationID, doc.CustomizationID)
+	assert.Equal(ProfileIDBilling, doc.ProfileID)
+	if assert.Len(doc.Note, 1) {
+		assert.Equal(efactura.InvoiceNoteSubjectCodeType("AAI"), doc.Note[0].SubjectCode)
+		assert.Equal("Livrare intracomunitară\nScutit de TVA", doc.Note[0].Note)
+	}
+	// The source invoice is not modified.
+	assert.Len(invoice.Note, 2)
+	assert.Nil(invoice.Supplier.Party.EndpointID)
+
+	xmlData, err := doc.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	xmlStr := string(xmlData)
+	assert.Contains(xmlStr, `<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"`)
+	assert.Contains(xmlStr, `<cbc:CustomizationID>`+CustomizationID+`</cbc:CustomizationID><cbc:ProfileID>`+ProfileIDBilling+`</cbc:ProfileID><cbc:ID>EXP-1</cbc:ID>`)
+	assert.Contains(xmlStr, `<cac:AccountingSupplierParty><cac:Party><cbc:EndpointID schemeID="9947">RO10000008</cbc:EndpointID>`)
+	assert.Contains(xmlStr, `<cac:AccountingCustomerParty><cac:Party><cbc:EndpointID schemeID="0088">4000001000005</cbc:EndpointID>`)
+	assert.Contains(xmlStr, `<cbc:Note>#AAI#Livrare intracomunitară&#xA;Scutit de TVA</cbc:Note>`)
+	assert.NotContains(xmlStr, "UBLVersionID")
+	assert.NotContains(xmlStr, efactura.CIUSRO_v101)
+
+	_, err = FromInvoice(invoice, ConvertProfileID("urn:fdc:peppol.eu:2017:poacc:selfbilling:01:1.0"),
+		ConvertCustomerEndpoint(EndpointSchemeEmail, "factura@example.com"))
+	assert.NoError(err)
+
+	invoice.InvoiceTypeCode = efactura.InvoiceTypeInvoiceInformationAccountingPurposes
+	_, err = FromInvoice(invoice, ConvertCustomerEndpoint(EndpointSchemeGLN, "4000001000005"))
+	assert.Error(err)
+}
+
+func TestInvoiceRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := buildTestInvoice(t)
+	invoice.Customer.Party.EndpointID = efactura.MakeValueWithScheme("4000001000005", string(EndpointSchemeGLN)).Ptr()
+	doc, err := FromInvoice(invoice)
+	if !assert.NoError(err) {
+		return
+	}
+	xmlData, err := doc.XMLIndent("", "  ")
+	if !assert.NoError(err) {
+		return
+	}
+
+	var parsed Invoice
+	if !assert.NoError(Unmarshal(xmlData, &parsed)) {
+		return
+	}
+	assert.Equal("EXP-1", parsed.ID)
+	assert.Equal(CustomizationID, parsed.CustomizationID)
+	assert.Equal(ProfileIDBilling, parsed.ProfileID)
+	if assert.NotNil(parsed.Supplier.Party.EndpointID) {
+		assert.Equal("RO10000008", parsed.Supplier.Party.EndpointID.Value)
+		assert.Equal(string(EndpointSchemeROVAT), parsed.Supplier.Party.EndpointID.GetAttrByName("schemeID").Value)
+	}
+
+	converted := parsed.Invoice()
+	assert.Equal(efactura.CIUSRO_v101, converted.CustomizationID)
+	assert.Equal(efactura.UBLVersionID, converted.UBLVersionID)
+
+	// Decimals are not comparable after a round trip, so compare the
+	// marshaled UBL XML.
+	invoice.Supplier.Party.EndpointID = doc.Supplier.Party.EndpointID
+	invoice.ProfileID = ProfileIDBilling
+	expectedXML, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	convertedXML, err := converted.XML()
+	if assert.NoError(err) {
+		assert.Equal(string(expectedXML), string(convertedXML))
+	}
+}
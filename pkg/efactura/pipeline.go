@@ -0,0 +1,420 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// PipelineItem is the unit of work flowing through a Pipeline. Each stage
+// fills the fields it's responsible for.
+type PipelineItem struct {
+	// Message is the message from the messages list (set by the source).
+	Message Message
+	// Download is the download response (set by PipelineDownload).
+	Download *DownloadInvoiceResponse
+	// Document is the parsed document (set by PipelineParse).
+	Document *DownloadedDocument
+	// Entry is the store entry (set by PipelineStore).
+	Entry *store.Entry
+	// Err is the error of the stage that failed to process the item. Items
+	// with an error skip the remaining stages and are passed to the sink.
+	Err error
+	// FailedStage is the name of the stage that failed to process the item.
+	FailedStage string
+}
+
+// PipelineSource produces the items of a pipeline by calling emit for each
+// item. emit blocks while the first stage is busy and its buffer is full
+// (back-pressure) and returns an error if the pipeline is cancelled, in
+// which case the source must return.
+type PipelineSource func(ctx context.Context, emit func(item *PipelineItem) error) error
+
+// PipelineStageFunc processes an item. If keep is false (and err is nil),
+// the item is dropped (eg. by a filter stage). If err is not nil, the item
+// is passed to the sink with the error set.
+type PipelineStageFunc func(ctx context.Context, item *PipelineItem) (keep bool, err error)
+
+// PipelineStage is a stage of a Pipeline.
+type PipelineStage struct {
+	// Name is the name of the stage, used in errors and metrics.
+	Name string
+	// Concurrency is the number of items processed concurrently by the
+	// stage. Default is 1.
+	Concurrency int
+	// Buffer is the size of the output buffer of the stage. Default is
+	// Concurrency.
+	Buffer int
+	// Func processes an item.
+	Func PipelineStageFunc
+}
+
+// PipelineStageMetrics are the metrics of a pipeline stage.
+type PipelineStageMetrics struct {
+	Name string
+	// Processed is the number of items processed by the stage.
+	Processed int64
+	// Dropped is the number of items dropped by the stage.
+	Dropped int64
+	// Failed is the number of items that failed in the stage.
+	Failed int64
+	// InFlight is the number of items being processed by the stage.
+	InFlight int64
+	// Busy is the total time spent processing items (summed over all the
+	// workers of the stage).
+	Busy time.Duration
+}
+
+// PipelineMetrics are the metrics of a Pipeline.
+type PipelineMetrics struct {
+	// Emitted is the number of items emitted by the source.
+	Emitted int64
+	// Completed is the number of items that reached the sink without an
+	// error.
+	Completed int64
+	// Failed is the number of items that reached the sink with an error.
+	Failed int64
+	Stages []PipelineStageMetrics
+}
+
+type pipelineStageCounters struct {
+	processed, dropped, failed, inFlight, busy atomic.Int64
+}
+
+// Pipeline is a streaming pipeline of stages connected by channels with
+// bounded buffers: a source (eg. the messages list) followed by stages (eg.
+// filter → download → parse → store), each with its own concurrency limit.
+// Since the buffers are bounded, a slow stage blocks the stages before it,
+// so the memory used is bounded regardless of the number of messages. The
+// order of the items is not preserved by stages with Concurrency > 1.
+type Pipeline struct {
+	source PipelineSource
+	stages []PipelineStage
+
+	emitted, completed, failed atomic.Int64
+	counters                   []pipelineStageCounters
+}
+
+// NewPipeline creates a Pipeline with the given source and stages.
+func NewPipeline(source PipelineSource, stages ...PipelineStage) *Pipeline {
+	return &Pipeline{
+		source:   source,
+		stages:   stages,
+		counters: make([]pipelineStageCounters, len(stages)),
+	}
+}
+
+// Metrics returns a snapshot of the pipeline metrics. It's safe to call
+// Metrics while the pipeline is running.
+func (p *Pipeline) Metrics() PipelineMetrics {
+	m := PipelineMetrics{
+		Emitted:   p.emitted.Load(),
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+		Stages:    make([]PipelineStageMetrics, len(p.stages)),
+	}
+	for i, stage := range p.stages {
+		c := &p.counters[i]
+		m.Stages[i] = PipelineStageMetrics{
+			Name:      stage.Name,
+			Processed: c.processed.Load(),
+			Dropped:   c.dropped.Load(),
+			Failed:    c.failed.Load(),
+			InFlight:  c.inFlight.Load(),
+			Busy:      time.Duration(c.busy.Load()),
+		}
+	}
+	return m
+}
+
+// Run runs the pipeline until the source is exhausted and all the items were
+// processed, calling sink (if not nil) for each item that went through all
+// the stages or failed. sink is called from a single goroutine. Run returns
+// the error returned by the source, or the ctx error if ctx was cancelled.
+// A Pipeline must not be run concurrently.
+func (p *Pipeline) Run(ctx context.Context, sink func(item *PipelineItem)) error {
+	if p.source == nil {
+		return errors.New("pipeline: nil source")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	src := make(chan *PipelineItem, 1)
+	srcErr := make(chan error, 1)
+	go func() {
+		defer close(src)
+		srcErr <- p.source(ctx, func(item *PipelineItem) error {
+			select {
+			case src <- item:
+				p.emitted.Add(1)
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var in <-chan *PipelineItem = src
+	for i, stage := range p.stages {
+		in = p.runStage(ctx, i, stage, in)
+	}
+	for item := range in {
+		if item.Err != nil {
+			p.failed.Add(1)
+		} else {
+			p.completed.Add(1)
+		}
+		if sink != nil {
+			sink(item)
+		}
+	}
+
+	if err := <-srcErr; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (p *Pipeline) runStage(
+	ctx context.Context, index int, stage PipelineStage, in <-chan *PipelineItem,
+) <-chan *PipelineItem {
+	concurrency := max(stage.Concurrency, 1)
+	buffer := stage.Buffer
+	if buffer <= 0 {
+		buffer = concurrency
+	}
+	counters := &p.counters[index]
+	out := make(chan *PipelineItem, buffer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range in {
+				if item.Err == nil {
+					counters.inFlight.Add(1)
+					start := time.Now()
+					keep, err := stage.Func(ctx, item)
+					counters.busy.Add(int64(time.Since(start)))
+					counters.inFlight.Add(-1)
+					counters.processed.Add(1)
+					switch {
+					case err != nil:
+						counters.failed.Add(1)
+						item.Err, item.FailedStage = fmt.Errorf("%s: %w", stage.Name, err), stage.Name
+					case !keep:
+						counters.dropped.Add(1)
+						continue
+					}
+				}
+				select {
+				case out <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// MessagesListSource returns a PipelineSource that emits the messages from
+// the messages list of the given cif for the last numDays days.
+func (c *Client) MessagesListSource(cif string, numDays int, msgType MessageFilterType) PipelineSource {
+	return func(ctx context.Context, emit func(item *PipelineItem) error) error {
+		res, err := c.GetMessagesList(ctx, cif, numDays, msgType)
+		if err != nil {
+			return err
+		}
+		if !res.IsOk() {
+			return fmt.Errorf("messages list: %s", res.Error)
+		}
+		for _, m := range res.Messages {
+			if err := emit(&PipelineItem{Message: m}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// MessagesPaginationSource returns a PipelineSource that emits the messages
+// of the given cif between startTs and endTs, fetching the next page from the
+// messages list with pagination only when the messages from the previous page
+// were consumed by the pipeline. Like GetAllMessagesPagination, intervals
+// rejected by ANAF are split in two halves and messages are deduplicated by
+// ID.
+func (c *Client) MessagesPaginationSource(cif string, startTs, endTs time.Time, msgType MessageFilterType) PipelineSource {
+	return func(ctx context.Context, emit func(item *PipelineItem) error) error {
+		seen := make(map[string]struct{})
+		var fetch func(startTs, endTs time.Time) error
+		fetch = func(startTs, endTs time.Time) error {
+			for page := int64(1); ; page++ {
+				res, err := c.GetMessagesListPagination(ctx, cif, startTs, endTs, page, msgType)
+				if err != nil {
+					return err
+				}
+				if res.IsPaginationWindowError() && endTs.Sub(startTs) > minPaginationWindow {
+					mid := startTs.Add(endTs.Sub(startTs) / 2)
+					if err := fetch(startTs, mid); err != nil {
+						return err
+					}
+					return fetch(mid, endTs)
+				}
+				if !res.IsOk() {
+					return fmt.Errorf("messages list page %d: %s", page, res.Error)
+				}
+				for _, m := range res.Messages {
+					if _, ok := seen[m.ID]; ok {
+						continue
+					}
+					seen[m.ID] = struct{}{}
+					if err := emit(&PipelineItem{Message: m}); err != nil {
+						return err
+					}
+				}
+				if page >= res.TotalPages {
+					return nil
+				}
+			}
+		}
+		return fetch(startTs, endTs)
+	}
+}
+
+// PipelineFilter returns a stage that keeps only the messages for which keep
+// returns true.
+func PipelineFilter(name string, keep func(m Message) bool) PipelineStage {
+	return PipelineStage{
+		Name: name,
+		Func: func(ctx context.Context, item *PipelineItem) (bool, error) {
+			return keep(item.Message), nil
+		},
+	}
+}
+
+// PipelineSkipStored returns a stage that drops the messages already stored
+// in st (by the key used by PipelineStore), useful for incremental
+// synchronization.
+func PipelineSkipStored(st store.Store) PipelineStage {
+	return PipelineStage{
+		Name: "skip-stored",
+		Func: func(ctx context.Context, item *PipelineItem) (bool, error) {
+			_, err := st.Stat(ctx, item.Message.ID)
+			switch {
+			case err == nil:
+				return false, nil
+			case errors.Is(err, store.ErrNotFound):
+				return true, nil
+			default:
+				return false, err
+			}
+		},
+	}
+}
+
+// PipelineDownload returns a stage that downloads the zip archive of each
+// message, with at most concurrency concurrent downloads. The download
+// options are passed to DownloadInvoice.
+func (c *Client) PipelineDownload(concurrency int, opts ...DownloadOption) PipelineStage {
+	return PipelineStage{
+		Name:        "download",
+		Concurrency: concurrency,
+		Func: func(ctx context.Context, item *PipelineItem) (bool, error) {
+			res, err := c.DownloadInvoice(ctx, item.Message.GetID(), opts...)
+			if err != nil {
+				return false, err
+			}
+			if !res.IsOk() {
+				return false, fmt.Errorf("download %s: %s: %s", item.Message.ID, res.Error.Title, res.Error.Error)
+			}
+			item.Download = res
+			return true, nil
+		},
+	}
+}
+
+// PipelineParse returns a stage that parses the document from the
+// downloaded zip archive (see UnmarshalDownloadedInvoiceXML), with at most
+// concurrency concurrent parses. Must be placed after PipelineDownload.
+func PipelineParse(concurrency int, opts ...ParseOption) PipelineStage {
+	var parseOpts parseOptions
+	for _, opt := range opts {
+		opt(&parseOpts)
+	}
+	return PipelineStage{
+		Name:        "parse",
+		Concurrency: concurrency,
+		Func: func(ctx context.Context, item *PipelineItem) (bool, error) {
+			if item.Download == nil {
+				return false, errors.New("missing download")
+			}
+			documentXML, _, err := parseInvoiceZip(ctx, item.Download.Zip)
+			if err != nil {
+				return false, err
+			}
+			item.Document, err = unmarshalDownloadedXML(documentXML.data, parseOpts)
+			return err == nil, err
+		},
+	}
+}
+
+// PipelineStore returns a stage that stores the downloaded zip archive of
+// each message in st, keyed by the message ID, with at most concurrency
+// concurrent writes. The correlation ID of the download is recorded in the
+// store entry. Must be placed after PipelineDownload.
+func PipelineStore(st store.Store, concurrency int) PipelineStage {
+	return PipelineStage{
+		Name:        "store",
+		Concurrency: concurrency,
+		Func: func(ctx context.Context, item *PipelineItem) (bool, error) {
+			if item.Download == nil {
+				return false, errors.New("missing download")
+			}
+			m := item.Message
+			entry := store.Entry{
+				Key:           m.ID,
+				DownloadID:    m.GetID(),
+				UploadIndex:   m.GetUploadIndex(),
+				CIF:           m.CIF,
+				MessageType:   m.Type,
+				Name:          m.ID + ".zip",
+				CorrelationID: item.Download.CorrelationID,
+				Metadata:      MetadataFromContext(ctx),
+			}
+			if err := st.Put(ctx, entry, item.Download.Zip); err != nil {
+				return false, err
+			}
+			stored, err := st.Stat(ctx, entry.Key)
+			if err != nil {
+				return false, err
+			}
+			item.Entry = &stored
+			return true, nil
+		},
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+const testPipelineInvoiceXML = `<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"/>`
+
+func TestPipelineSync(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/listaMesajeFactura", func(w http.ResponseWriter, r *http.Request) {
+		var messages []efactura.Message
+		for id := 3001; id <= 3006; id++ {
+			messageType := efactura.MessageTypeReceivedInvoice
+			if id == 3006 {
+				messageType = efactura.MessageTypeSentInvoice
+			}
+			messages = append(messages, efactura.Message{
+				ID:   strconv.Itoa(id),
+				Type: messageType,
+				CIF:  "12345678",
+			})
+		}
+		writeJSON(w, efactura.MessagesListResponse{Messages: messages})
+	})
+	var downloads atomic.Int64
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		id, _ := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		w.Header().Set("Content-Type", "application/zip")
+		if id == 3005 {
+			w.Write([]byte("not a zip"))
+			return
+		}
+		w.Write(newTestZip(t, id, testPipelineInvoiceXML))
+	})
+
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	assert.NoError(st.Put(ctx, store.Entry{Key: "3001"}, newTestInvoiceZip(t, 3001)))
+
+	pipeline := efactura.NewPipeline(
+		client.MessagesListSource("12345678", 60, efactura.MessageFilterAll),
+		efactura.PipelineFilter("received", func(m efactura.Message) bool {
+			return m.Type == efactura.MessageTypeReceivedInvoice
+		}),
+		efactura.PipelineSkipStored(st),
+		client.PipelineDownload(2),
+		efactura.PipelineParse(2),
+		efactura.PipelineStore(st, 1),
+	)
+
+	var completed, failed []string
+	err := pipeline.Run(ctx, func(item *efactura.PipelineItem) {
+		if item.Err != nil {
+			assert.Equal("parse", item.FailedStage)
+			failed = append(failed, item.Message.ID)
+			return
+		}
+		if assert.NotNil(item.Document) && assert.NotNil(item.Document.Invoice) && assert.NotNil(item.Entry) {
+			assert.Equal(item.Message.ID, item.Entry.Key)
+		}
+		completed = append(completed, item.Message.ID)
+	})
+	assert.NoError(err)
+	sort.Strings(completed)
+	assert.Equal([]string{"3002", "3003", "3004"}, completed)
+	assert.Equal([]string{"3005"}, failed)
+	assert.Equal(int64(4), downloads.Load())
+
+	entries, err := st.List(ctx)
+	if assert.NoError(err) {
+		assert.Len(entries, 4)
+	}
+
+	metrics := pipeline.Metrics()
+	assert.Equal(int64(6), metrics.Emitted)
+	assert.Equal(int64(3), metrics.Completed)
+	assert.Equal(int64(1), metrics.Failed)
+	if assert.Len(metrics.Stages, 5) {
+		assert.Equal("received", metrics.Stages[0].Name)
+		assert.Equal(int64(6), metrics.Stages[0].Processed)
+		assert.Equal(int64(1), metrics.Stages[0].Dropped)
+		assert.Equal(int64(1), metrics.Stages[1].Dropped)
+		assert.Equal(int64(4), metrics.Stages[2].Processed)
+		assert.Equal(int64(1), metrics.Stages[3].Failed)
+		assert.Equal(int64(3), metrics.Stages[4].Processed)
+		for _, stage := range metrics.Stages {
+			assert.Zero(stage.InFlight)
+		}
+	}
+}
+
+func TestPipelineBackPressure(t *testing.T) {
+	assert := assert.New(t)
+
+	const total = 100
+	var emitted, maxAhead atomic.Int64
+	var consumed atomic.Int64
+	source := func(ctx context.Context, emit func(item *efactura.PipelineItem) error) error {
+		for i := 0; i < total; i++ {
+			if err := emit(&efactura.PipelineItem{Message: efactura.Message{ID: strconv.Itoa(i)}}); err != nil {
+				return err
+			}
+			ahead := emitted.Add(1) - consumed.Load()
+			for {
+				cur := maxAhead.Load()
+				if ahead <= cur || maxAhead.CompareAndSwap(cur, ahead) {
+					break
+				}
+			}
+		}
+		return nil
+	}
+	stage := efactura.PipelineStage{
+		Name:        "noop",
+		Concurrency: 2,
+		Buffer:      1,
+		Func: func(ctx context.Context, item *efactura.PipelineItem) (bool, error) {
+			return true, nil
+		},
+	}
+	var sunk int
+	err := efactura.NewPipeline(source, stage).Run(context.Background(), func(item *efactura.PipelineItem) {
+		time.Sleep(time.Millisecond)
+		consumed.Add(1)
+		sunk++
+	})
+	assert.NoError(err)
+	assert.Equal(total, sunk)
+	// The sink is slow, so the source can't get ahead of it by more than the
+	// source buffer (1) + workers (2) + output buffer (1) + the item being
+	// sunk, plus some slack since the counters are not updated atomically
+	// with the channel operations.
+	assert.LessOrEqual(maxAhead.Load(), int64(8))
+}
+
+func TestPipelineCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := func(ctx context.Context, emit func(item *efactura.PipelineItem) error) error {
+		for i := 0; ; i++ {
+			if err := emit(&efactura.PipelineItem{Message: efactura.Message{ID: strconv.Itoa(i)}}); err != nil {
+				return err
+			}
+		}
+	}
+	var sunk int
+	err := efactura.NewPipeline(source).Run(ctx, func(item *efactura.PipelineItem) {
+		if sunk++; sunk == 10 {
+			cancel()
+		}
+	})
+	assert.True(errors.Is(err, context.Canceled))
+}
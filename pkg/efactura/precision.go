@@ -0,0 +1,219 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/printesoi/xml-go"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// PrecisionPolicy describes the number of decimals allowed for the decimal
+// values of different field classes. CIUS-RO requires amounts to have at most
+// two decimals, but allows more decimals for unit prices. The policy is not
+// global: it's passed to InvoiceLineBuilder.WithPricePrecision when building
+// and to PrecisionPolicy.ValidateInvoice when validating.
+type PrecisionPolicy struct {
+	// AmountDecimals is the number of decimals used for amounts (totals,
+	// line amounts, allowances/charges, tax amounts).
+	AmountDecimals int32
+	// PriceDecimals is the maximum number of decimals used for unit prices
+	// (BT-146, BT-147, BT-148).
+	PriceDecimals int32
+	// MinPriceDecimals is the minimum number of decimals used for unit
+	// prices.
+	MinPriceDecimals int32
+}
+
+const (
+	amountDecimals   = 2
+	priceDecimals    = 4
+	minPriceDecimals = 2
+)
+
+// DefaultPrecisionPolicy returns the default PrecisionPolicy: amounts with
+// two decimals and prices with two to four decimals.
+func DefaultPrecisionPolicy() PrecisionPolicy {
+	return PrecisionPolicy{
+		AmountDecimals:   amountDecimals,
+		PriceDecimals:    priceDecimals,
+		MinPriceDecimals: minPriceDecimals,
+	}
+}
+
+// FormatAmount rounds the amount to AmountDecimals decimals and returns the
+// string representation with exactly AmountDecimals decimals.
+func (p PrecisionPolicy) FormatAmount(amount types.Decimal) string {
+	return amount.Round(p.AmountDecimals).StringFixed(p.AmountDecimals)
+}
+
+// FormatPrice rounds the price to PriceDecimals decimals and returns the
+// string representation with at least MinPriceDecimals decimals.
+func (p PrecisionPolicy) FormatPrice(price types.Decimal) string {
+	rounded := price.Round(p.PriceDecimals)
+	places := decimalPlaces(rounded, p.PriceDecimals)
+	if places < p.MinPriceDecimals {
+		places = p.MinPriceDecimals
+	}
+	return rounded.StringFixed(places)
+}
+
+// FormatAmount returns the string representation of the amount, as used when
+// marshaling: rounded to exactly two decimals.
+func FormatAmount(amount types.Decimal) string {
+	return amount.StringFixed(amountDecimals)
+}
+
+// FormatPrice returns the string representation of the unit price, as used
+// when marshaling. The price is not rounded (the rounding is done by the
+// InvoiceLineBuilder): it's marshaled with all its significant decimals, but
+// at least two.
+func FormatPrice(price types.Decimal) string {
+	places := decimalPlaces(price, -price.Exponent())
+	return price.StringFixed(max(places, minPriceDecimals))
+}
+
+// decimalPlaces returns the number of significant decimals of d, but at most
+// maxPlaces.
+func decimalPlaces(d types.Decimal, maxPlaces int32) int32 {
+	var places int32
+	for ; places < maxPlaces; places++ {
+		if d.Equal(d.Round(places)) {
+			break
+		}
+	}
+	return places
+}
+
+// ValidateInvoice checks that all the amounts and prices of the invoice
+// (eg. a parsed invoice) have at most the number of decimals allowed by the
+// policy. All the non-conforming fields are returned as a joined error.
+func (p PrecisionPolicy) ValidateInvoice(iv Invoice) error {
+	var errs []error
+	checkAmount := func(path string, a *AmountWithCurrency) {
+		if a == nil {
+			return
+		}
+		if decimalPlaces(a.Amount, p.AmountDecimals+1) > p.AmountDecimals {
+			errs = append(errs, fmt.Errorf("%s: amount %s has more than %d decimals",
+				path, a.Amount.String(), p.AmountDecimals))
+		}
+	}
+	checkPrice := func(path string, a *AmountWithCurrency) {
+		if decimalPlaces(a.Amount, p.PriceDecimals+1) > p.PriceDecimals {
+			errs = append(errs, fmt.Errorf("%s: price %s has more than %d decimals",
+				path, a.Amount.String(), p.PriceDecimals))
+		}
+	}
+
+	for i := range iv.AllowanceCharges {
+		ac := &iv.AllowanceCharges[i]
+		checkAmount(fmt.Sprintf("AllowanceCharges[%d].Amount", i), &ac.Amount)
+		checkAmount(fmt.Sprintf("AllowanceCharges[%d].BaseAmount", i), ac.BaseAmount)
+	}
+	for i := range iv.TaxTotal {
+		taxTotal := &iv.TaxTotal[i]
+		checkAmount(fmt.Sprintf("TaxTotal[%d].TaxAmount", i), taxTotal.TaxAmount)
+		for j := range taxTotal.TaxSubtotals {
+			subtotal := &taxTotal.TaxSubtotals[j]
+			checkAmount(fmt.Sprintf("TaxTotal[%d].TaxSubtotals[%d].TaxableAmount", i, j), &subtotal.TaxableAmount)
+			checkAmount(fmt.Sprintf("TaxTotal[%d].TaxSubtotals[%d].TaxAmount", i, j), &subtotal.TaxAmount)
+		}
+	}
+
+	total := &iv.LegalMonetaryTotal
+	checkAmount("LegalMonetaryTotal.LineExtensionAmount", &total.LineExtensionAmount)
+	checkAmount("LegalMonetaryTotal.TaxExclusiveAmount", &total.TaxExclusiveAmount)
+	checkAmount("LegalMonetaryTotal.TaxInclusiveAmount", &total.TaxInclusiveAmount)
+	checkAmount("LegalMonetaryTotal.AllowanceTotalAmount", total.AllowanceTotalAmount)
+	checkAmount("LegalMonetaryTotal.ChargeTotalAmount", total.ChargeTotalAmount)
+	checkAmount("LegalMonetaryTotal.PrepaidAmount", total.PrepaidAmount)
+	checkAmount("LegalMonetaryTotal.PayableRoundingAmount", total.PayableRoundingAmount)
+	checkAmount("LegalMonetaryTotal.PayableAmount", &total.PayableAmount)
+
+	for i := range iv.InvoiceLines {
+		line := &iv.InvoiceLines[i]
+		checkAmount(fmt.Sprintf("InvoiceLines[%d].LineExtensionAmount", i), &line.LineExtensionAmount)
+		for j := range line.AllowanceCharges {
+			ac := &line.AllowanceCharges[j]
+			checkAmount(fmt.Sprintf("InvoiceLines[%d].AllowanceCharges[%d].Amount", i, j), &ac.Amount)
+			checkAmount(fmt.Sprintf("InvoiceLines[%d].AllowanceCharges[%d].BaseAmount", i, j), ac.BaseAmount)
+		}
+		checkPrice(fmt.Sprintf("InvoiceLines[%d].Price.PriceAmount", i), &line.Price.PriceAmount)
+		if ac := line.Price.AllowanceCharge; ac != nil {
+			checkPrice(fmt.Sprintf("InvoiceLines[%d].Price.AllowanceCharge.Amount", i), &ac.Amount)
+			checkPrice(fmt.Sprintf("InvoiceLines[%d].Price.AllowanceCharge.BaseAmount", i), &ac.BaseAmount)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidatePrecision checks that the invoice conforms to the
+// DefaultPrecisionPolicy (see PrecisionPolicy.ValidateInvoice).
+func (iv Invoice) ValidatePrecision() error {
+	return DefaultPrecisionPolicy().ValidateInvoice(iv)
+}
+
+// priceAmountWithCurrency is an AmountWithCurrency that is marshaled as a
+// price (using FormatPrice).
+type priceAmountWithCurrency AmountWithCurrency
+
+// MarshalXML implements the xml.Marshaler interface.
+func (a priceAmountWithCurrency) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type amountWithCurrency struct {
+		Amount     string           `xml:",chardata"`
+		CurrencyID CurrencyCodeType `xml:"currencyID,attr,omitempty"`
+	}
+	xmlAmount := amountWithCurrency{
+		Amount:     FormatPrice(a.Amount),
+		CurrencyID: a.CurrencyID,
+	}
+	return e.EncodeElement(xmlAmount, start)
+}
+
+// MarshalXML implements the xml.Marshaler interface. We use a custom
+// marshaling function for InvoiceLinePrice to marshal the price amount as a
+// price instead of an amount (see FormatPrice).
+func (p InvoiceLinePrice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type invoiceLinePrice InvoiceLinePrice
+	xmlPrice := struct {
+		PriceAmount priceAmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 PriceAmount"`
+		invoiceLinePrice
+	}{
+		PriceAmount:      priceAmountWithCurrency(p.PriceAmount),
+		invoiceLinePrice: invoiceLinePrice(p),
+	}
+	return e.EncodeElement(xmlPrice, start)
+}
+
+// MarshalXML implements the xml.Marshaler interface. We use a custom
+// marshaling function for InvoiceLinePriceAllowanceCharge to marshal the
+// amounts as prices (see FormatPrice).
+func (ac InvoiceLinePriceAllowanceCharge) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type invoiceLinePriceAllowanceCharge InvoiceLinePriceAllowanceCharge
+	xmlAllowanceCharge := struct {
+		invoiceLinePriceAllowanceCharge
+		Amount     priceAmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 Amount"`
+		BaseAmount priceAmountWithCurrency `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 BaseAmount"`
+	}{
+		invoiceLinePriceAllowanceCharge: invoiceLinePriceAllowanceCharge(ac),
+		Amount:                          priceAmountWithCurrency(ac.Amount),
+		BaseAmount:                      priceAmountWithCurrency(ac.BaseAmount),
+	}
+	return e.EncodeElement(xmlAllowanceCharge, start)
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func mustDecimal(value string) types.Decimal {
+	d, err := types.NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestPrecisionPolicyFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := DefaultPrecisionPolicy()
+	tests := []struct {
+		value  string
+		amount string
+		price  string
+	}{
+		{value: "10", amount: "10.00", price: "10.00"},
+		{value: "1.5", amount: "1.50", price: "1.50"},
+		{value: "1.235", amount: "1.24", price: "1.235"},
+		{value: "0.123456", amount: "0.12", price: "0.1235"},
+		{value: "2.00001", amount: "2.00", price: "2.00"},
+	}
+	for _, tt := range tests {
+		d := mustDecimal(tt.value)
+		assert.Equal(tt.amount, policy.FormatAmount(d), "amount %s", tt.value)
+		assert.Equal(tt.price, policy.FormatPrice(d), "price %s", tt.value)
+	}
+
+	policy = PrecisionPolicy{AmountDecimals: 0, PriceDecimals: 6}
+	assert.Equal("-2", policy.FormatAmount(mustDecimal("-2.34567")))
+	assert.Equal("0.123456", policy.FormatPrice(mustDecimal("0.1234561")))
+}
+
+func TestFormatPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		value string
+		price string
+	}{
+		{value: "10", price: "10.00"},
+		{value: "1.5", price: "1.50"},
+		{value: "1.235", price: "1.235"},
+		{value: "0.123456", price: "0.123456"},
+	}
+	for _, tt := range tests {
+		assert.Equal(tt.price, FormatPrice(mustDecimal(tt.value)), "price %s", tt.value)
+		assert.Equal(DefaultPrecisionPolicy().FormatAmount(mustDecimal(tt.value)),
+			FormatAmount(mustDecimal(tt.value)), "amount %s", tt.value)
+	}
+}
+
+func TestInvoicePrecision(t *testing.T) {
+	assert := assert.New(t)
+
+	taxCategory := InvoiceLineTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1000)).
+		WithGrossPriceAmount(mustDecimal("0.12345")).
+		WithItemName("Surub").
+		WithItemTaxCategory(taxCategory).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	preciseLine, err := NewInvoiceLineBuilder("2", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(1000)).
+		WithGrossPriceAmount(mustDecimal("0.1234567")).
+		WithPricePrecision(6).
+		WithItemName("Piulita").
+		WithItemTaxCategory(taxCategory).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("0.123457", preciseLine.Price.PriceAmount.Amount.String())
+	assert.Equal("123.46", preciseLine.LineExtensionAmount.Amount.String())
+
+	invoice, err := NewInvoiceBuilder("INV-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithInvoiceLines([]InvoiceLine{line}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+
+	xmlData, err := invoice.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(xmlData), `<cbc:PriceAmount currencyID="RON">0.1235</cbc:PriceAmount>`)
+	assert.Contains(string(xmlData), `<cbc:LineExtensionAmount currencyID="RON">123.50</cbc:LineExtensionAmount>`)
+
+	var parsed Invoice
+	if assert.NoError(UnmarshalInvoice(xmlData, &parsed)) {
+		assert.NoError(parsed.ValidatePrecision())
+	}
+
+	parsed.InvoiceLines[0].LineExtensionAmount.Amount = mustDecimal("123.451")
+	parsed.InvoiceLines[0].Price.PriceAmount.Amount = mustDecimal("0.12345")
+	err = parsed.ValidatePrecision()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "InvoiceLines[0].LineExtensionAmount")
+		assert.Contains(err.Error(), "InvoiceLines[0].Price.PriceAmount")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitGroup is a group of e-factura endpoints that share a client-side
+// rate limiter (see ClientRateLimit).
+type RateLimitGroup string
+
+const (
+	// RateLimitGroupAll is applied to all the calls of the APIs protected
+	// with OAuth2, in addition to the limit of the call group.
+	RateLimitGroupAll RateLimitGroup = "all"
+	// RateLimitGroupUpload groups the upload calls (invoices and messages).
+	RateLimitGroupUpload RateLimitGroup = "upload"
+	// RateLimitGroupMessageState groups the get message state calls.
+	RateLimitGroupMessageState RateLimitGroup = "stareMesaj"
+	// RateLimitGroupMessagesList groups the messages list calls (with or
+	// without pagination).
+	RateLimitGroupMessagesList RateLimitGroup = "listaMesaje"
+	// RateLimitGroupDownload groups the download calls.
+	RateLimitGroupDownload RateLimitGroup = "descarcare"
+)
+
+// RateLimit is a token bucket rate limit: at most Limit calls every Per,
+// with bursts of at most Burst calls.
+type RateLimit struct {
+	// Limit is the number of calls allowed every Per.
+	Limit int
+	// Per is the time interval of the limit.
+	Per time.Duration
+	// Burst is the maximum number of calls made at once. If <= 0, Limit
+	// is used.
+	Burst int
+}
+
+// DefaultRateLimits returns the default client-side rate limits: 1000 calls
+// per minute in total, the global limit of the ANAF APIs. The other ANAF
+// limits are enforced per CIF or per message (eg. 1500 messages list calls
+// per day per CIF), so the limits of the call groups depend on the
+// integration and must be configured explicitly (see ClientRateLimit).
+func DefaultRateLimits() map[RateLimitGroup]RateLimit {
+	return map[RateLimitGroup]RateLimit{
+		RateLimitGroupAll: {Limit: 1000, Per: time.Minute, Burst: 50},
+	}
+}
+
+// tokenBucket is a token bucket rate limiter, safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Limit
+	}
+	return &tokenBucket{
+		rate:   float64(limit.Limit) / limit.Per.Seconds(),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket and returns the time to wait until
+// the token is available.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	d := b.reserve()
+	if d == 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, d); err != nil {
+		b.cancel()
+		return err
+	}
+	return nil
+}
+
+// waitRateLimit blocks until the rate limiters of the given group (and of
+// RateLimitGroupAll) allow a new call, or ctx is done. The wait is recorded
+// in the telemetry of the call.
+func (c *Client) waitRateLimit(ctx context.Context, group RateLimitGroup) error {
+	if len(c.rateLimiters) == 0 {
+		return nil
+	}
+	start := time.Now()
+	defer func() {
+		callTelemetryFromContext(ctx).rateLimitWait(time.Since(start))
+	}()
+	if b := c.rateLimiters[group]; b != nil {
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+	}
+	if b := c.rateLimiters[RateLimitGroupAll]; b != nil {
+		return b.wait(ctx)
+	}
+	return nil
+}
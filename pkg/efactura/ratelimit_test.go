@@ -0,0 +1,87 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestClientRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t,
+		efactura.ClientRateLimit(efactura.RateLimitGroupMessageState, efactura.RateLimit{
+			Limit: 1,
+			Per:   time.Hour,
+			Burst: 2,
+		}),
+	)
+	var calls atomic.Int32
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="in prelucrare"/>`)
+	})
+
+	// The burst allows the first two calls.
+	for i := 0; i < 2; i++ {
+		_, err := client.GetMessageState(context.Background(), 5001)
+		assert.NoError(err)
+	}
+
+	// The third call must wait for an hour, so it fails when the context
+	// expires, without making the request.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.GetMessageState(ctx, 5001)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Equal(int32(2), calls.Load())
+}
+
+func TestClientRateLimitWait(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t,
+		efactura.ClientRateLimit(efactura.RateLimitGroupAll, efactura.RateLimit{
+			Limit: 20,
+			Per:   time.Second,
+			Burst: 1,
+		}),
+	)
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="in prelucrare"/>`)
+	})
+
+	// With a rate of one call every 50ms and no burst, the last two calls
+	// must wait.
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.GetMessageState(context.Background(), 5001)
+		assert.NoError(err)
+	}
+	assert.GreaterOrEqual(time.Since(start), 90*time.Millisecond)
+}
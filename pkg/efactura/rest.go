@@ -37,6 +37,7 @@ import (
 	"github.com/printesoi/e-factura-go/internal/ptr"
 	iregexp "github.com/printesoi/e-factura-go/internal/regexp"
 	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/text"
 	ptime "github.com/printesoi/e-factura-go/pkg/time"
 	pxml "github.com/printesoi/e-factura-go/pkg/xml"
 )
@@ -89,6 +90,17 @@ type (
 			ErrorMessage string `xml:"errorMessage,attr"`
 		} `xml:"Errors,omitempty"`
 
+		// Metadata is the user metadata attached to the upload (see
+		// UploadOptionMetadata and ContextWithMetadata). It's never
+		// serialized in the XML.
+		Metadata Metadata `xml:"-"`
+		// SHA256 is the hex encoded SHA-256 checksum of the uploaded XML,
+		// only set by UploadPassThrough. It's never serialized in the XML.
+		SHA256 string `xml:"-"`
+		// CorrelationID is the correlation ID of the upload (see
+		// ClientCorrelationIDGenerator). It's never serialized in the XML.
+		CorrelationID string `xml:"-"`
+
 		// Hardcode the namespace here so we don't need a customer marshaling
 		// method.
 		XMLName xml.Name `xml:"mfp:anaf:dgti:spv:respUploadFisier:v1 header"`
@@ -105,6 +117,10 @@ type (
 			ErrorMessage string `xml:"errorMessage,attr"`
 		} `xml:"Errors,omitempty"`
 
+		// Metadata is the user metadata from the request context (see
+		// ContextWithMetadata). It's never serialized in the XML.
+		Metadata Metadata `xml:"-"`
+
 		// Hardcode the namespace here so we don't need a customer marshaling
 		// method.
 		XMLName xml.Name `xml:"mfp:anaf:dgti:efactura:stareMesajFactura:v1 header"`
@@ -155,6 +171,13 @@ type (
 	DownloadInvoiceResponse struct {
 		Error *DownloadInvoiceResponseError
 		Zip   []byte
+
+		// Metadata is the user metadata from the request context (see
+		// ContextWithMetadata).
+		Metadata Metadata
+		// CorrelationID is the correlation ID of the download (see
+		// ClientCorrelationIDGenerator).
+		CorrelationID string
 	}
 
 	// DownloadInvoiceParseZipResponse is the type returned by the
@@ -182,9 +205,21 @@ type (
 		// Invoice is the parsed Invoice if the InvoiceXML is storing an
 		// invoice.
 		Invoice *Invoice
+		// CreditNote is the parsed CreditNote if the InvoiceXML is storing
+		// a UBL credit note.
+		CreditNote *CreditNote
 		// InvoiceError is the parse InvoiceErrorMessage if InvoiceXML is
 		// storing an invoice error message.
 		InvoiceError *InvoiceErrorMessage
+		// Document is the parsed document if InvoiceXML is storing a
+		// registered custom document type (see RegisterDocumentType).
+		Document any
+		// DocumentType is the name of the custom document type, if Document
+		// is set.
+		DocumentType string
+		// ParseIssues are the issues fixed while parsing the InvoiceXML in
+		// ParseModeLenient.
+		ParseIssues []ParseIssue
 	}
 
 	// InvoiceErrorMessage is the type corresponding to an Invoice message
@@ -245,18 +280,20 @@ const (
 	messageTimeLayout = "200601021504"
 )
 
+// The paths of the endpoints are relative to the base of their endpoint group
+// (see ClientConfig.ApiBaseURL and ClientConfig.PublicApiBaseURL).
 const (
 	apiBase                      = "FCTEL/rest/"
-	apiPathUpload                = apiBase + "upload"
-	apiPathMessageState          = apiBase + "stareMesaj"
-	apiPathMessageList           = apiBase + "listaMesajeFactura"
-	apiPathMessagePaginationList = apiBase + "listaMesajePaginatieFactura"
-	apiPathDownload              = apiBase + "descarcare"
+	apiPathUpload                = "upload"
+	apiPathMessageState          = "stareMesaj"
+	apiPathMessageList           = "listaMesajeFactura"
+	apiPathMessagePaginationList = "listaMesajePaginatieFactura"
+	apiPathDownload              = "descarcare"
 	apiPathValidateSignature     = "/api/validate/signature"
 
 	publicApiBase         = "FCTEL/rest/"
-	publicApiPathValidate = publicApiBase + "validare/%s"
-	publicApiPathXMLToPDF = publicApiBase + "transformare/%s"
+	publicApiPathValidate = "validare/%s"
+	publicApiPathXMLToPDF = "transformare/%s"
 )
 
 var (
@@ -269,6 +306,17 @@ var (
 
 	regexZipFile          = regexp.MustCompile("^\\d+.xml$")
 	regexZipSignatureFile = regexp.MustCompile("^semnatura_\\d+.xml$")
+
+	// regexPaginationWindowError matches the errors returned by the list
+	// messages with pagination endpoint when the requested time interval is
+	// rejected (eg. it's too wide or contains too many messages).
+	regexPaginationWindowError = regexp.MustCompile("(?i)(\\binterval\\w*\\b.*\\b(mai mare|depas\\w*|prea mare)\\b|\\bprea multe (mesaje|inregistrari)\\b)")
+)
+
+const (
+	// minPaginationWindow is the smallest time interval that
+	// GetAllMessagesPagination will try when splitting a rejected interval.
+	minPaginationWindow = time.Minute
 )
 
 func (s ValidateStandard) String() string {
@@ -294,6 +342,42 @@ func (r *ValidateResponse) GetFirstMessage() string {
 	return r.Messages[0].Message
 }
 
+// GetFirstErrorMessage returns the first message from the validate response
+// if the validation failed, or empty string otherwise.
+func (r *ValidateResponse) GetFirstErrorMessage() string {
+	if r.IsOk() {
+		return ""
+	}
+	return r.GetFirstMessage()
+}
+
+// ValidationErrors returns the messages of a failed validation parsed as
+// ValidationErrors (see ParseValidationMessages). If the validation was
+// successful, nil is returned.
+func (r *ValidateResponse) ValidationErrors() ValidationErrors {
+	if r == nil || r.IsOk() {
+		return nil
+	}
+	messages := make([]string, len(r.Messages))
+	for i, m := range r.Messages {
+		messages[i] = m.Message
+	}
+	return validationErrorsOrState(ParseValidationMessages(messages...), r.State)
+}
+
+// IsValid returns true if the validate signature response reports that the
+// signature is valid for the given invoice. The endpoint only returns a
+// message, so this is based on the message text (eg. "Fișierele încărcate au
+// fost validate cu succes, iar semnătura este validă...").
+func (r *ValidateSignatureResponse) IsValid() bool {
+	if r == nil {
+		return false
+	}
+	msg := " " + strings.ToLower(text.Transliterate(r.Message)) + " "
+	return strings.Contains(msg, "validate cu succes") &&
+		!strings.Contains(msg, " nu ") && !strings.Contains(msg, "invalid")
+}
+
 // IsOk returns true if the XML-To-PDF response was successful.
 func (r *GeneratePDFResponse) IsOk() bool {
 	return r != nil && r.Error == nil
@@ -317,6 +401,36 @@ func (r *GeneratePDFResponseError) GetFirstMessage() string {
 	return r.Messages[0].Message
 }
 
+// ValidationErrors returns the messages of the error response parsed as
+// ValidationErrors (see ParseValidationMessages), so the XML-To-PDF
+// endpoint can be used as a validator.
+func (r *GeneratePDFResponseError) ValidationErrors() ValidationErrors {
+	if r == nil {
+		return nil
+	}
+	messages := make([]string, len(r.Messages))
+	for i, m := range r.Messages {
+		messages[i] = m.Message
+	}
+	return validationErrorsOrState(ParseValidationMessages(messages...), r.State)
+}
+
+// ValidationErrors returns the validation errors of a failed XML-To-PDF
+// conversion, or nil if the conversion was successful.
+func (r *GeneratePDFResponse) ValidationErrors() ValidationErrors {
+	return r.GetError().ValidationErrors()
+}
+
+// validationErrorsOrState returns errs, or a ValidationError with the
+// response state if a failed response has no messages, so the failure is
+// not lost.
+func validationErrorsOrState(errs []ValidationError, state Code) ValidationErrors {
+	if len(errs) == 0 {
+		return ValidationErrors{{Rule: ValidationRuleUnidentified, Message: string(state)}}
+	}
+	return errs
+}
+
 // IsOk returns true if the response corresponding to an upload was successful.
 func (r *UploadResponse) IsOk() bool {
 	return r != nil && r.ExecutionStatus != nil && *r.ExecutionStatus == 0
@@ -489,21 +603,40 @@ func (r *DownloadInvoiceParseZipResponse) IsOk() bool {
 }
 
 // IsOk returns true if the response corresponding to fetching messages list
-// was successful.
+// was successful. A response with the "no messages" error is successful (see
+// Empty).
 func (r *MessagesListResponse) IsOk() bool {
-	return r != nil && (r.Error == "" || strings.HasPrefix(r.Error, "Nu exista mesaje in "))
+	return r != nil && (r.Error == "" || ierrors.ErrorMessageMatchNoMessages(r.Error))
+}
+
+// Empty returns true if the response is successful and there are no
+// messages, either because ANAF returned the "no messages" error (eg. "Nu
+// exista mesaje in ultimele 1 zile") or an empty list.
+func (r *MessagesListResponse) Empty() bool {
+	return r.IsOk() && len(r.Messages) == 0
+}
+
+// GetFirstErrorMessage returns the error message of the response if the
+// response is not successful, or empty string otherwise.
+func (r *MessagesListResponse) GetFirstErrorMessage() string {
+	if r == nil || r.IsOk() {
+		return ""
+	}
+	return r.Error
 }
 
 // ValidateXML call the validate endpoint with the given standard and XML body
 // reader.
-func (c *Client) ValidateXML(ctx context.Context, xml io.Reader, st ValidateStandard) (*ValidateResponse, error) {
-	var response *ValidateResponse
+func (c *Client) ValidateXML(ctx context.Context, xml io.Reader, st ValidateStandard) (response *ValidateResponse, err error) {
+	ctx, call := c.startCall(ctx, OperationValidate, http.MethodPost)
+	defer func() { call.finish(err) }()
 
-	path := fmt.Sprintf(publicApiPathValidate, st)
-	req, err := c.publicApiClient.NewRequest(ctx, http.MethodPost, path, nil, xml)
+	path := c.publicApiBase + fmt.Sprintf(publicApiPathValidate, st)
+	req, err := c.publicApiClient.NewRequest(ctx, http.MethodPost, path, nil, xml, c.requestOptions()...)
 	if err != nil {
 		return nil, err
 	}
+	call.request(req)
 
 	// This is explicitly requested in the docs.
 	req.Header.Set("Content-Type", "text/plain")
@@ -511,6 +644,7 @@ func (c *Client) ValidateXML(ctx context.Context, xml io.Reader, st ValidateStan
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
 	}
+	call.response(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -519,13 +653,13 @@ func (c *Client) ValidateXML(ctx context.Context, xml io.Reader, st ValidateStan
 			fmt.Errorf("expected %s, got %s", api_helpers.MediaTypeApplicationJSON, api_helpers.ResponseMediaType(resp.Header)))
 	}
 
-	response = new(ValidateResponse)
-	if err := api_helpers.UnmarshalReaderJSON(resp.Body, response); err != nil {
+	res := new(ValidateResponse)
+	if err := api_helpers.UnmarshalReaderJSON(resp.Body, res); err != nil {
 		return nil, ierrors.NewErrorResponseParse(resp,
 			fmt.Errorf("failed to decode JSON body: %v", err), false)
 	}
 
-	return response, nil
+	return res, nil
 }
 
 // ValidateInvoice validate the provided Invoice
@@ -542,20 +676,25 @@ func (c *Client) ValidateInvoice(ctx context.Context, invoice Invoice) (*Validat
 // successful and no validation or other invalid request error occurred, check
 // if response.IsOk() == true.
 func (c *Client) XMLToPDF(ctx context.Context, xml io.Reader, st ValidateStandard, noValidate bool) (response *GeneratePDFResponse, err error) {
-	path := fmt.Sprintf(publicApiPathXMLToPDF, st)
+	path := c.publicApiBase + fmt.Sprintf(publicApiPathXMLToPDF, st)
 	if noValidate {
 		path, _ = url.JoinPath(path, "DA")
 	}
-	req, er := c.publicApiClient.NewRequest(ctx, http.MethodPost, path, nil, xml)
+	ctx, call := c.startCall(ctx, OperationXMLToPDF, http.MethodPost)
+	defer func() { call.finish(err) }()
+
+	req, er := c.publicApiClient.NewRequest(ctx, http.MethodPost, path, nil, xml, c.requestOptions()...)
 	if err = er; err != nil {
 		return
 	}
+	call.request(req)
 
 	req.Header.Set("Content-Type", "text/plain")
 	resp, er := c.publicApiClient.Do(req)
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
 	}
+	call.response(resp)
 	if err = er; err != nil {
 		return
 	}
@@ -578,6 +717,7 @@ func (c *Client) XMLToPDF(ctx context.Context, xml io.Reader, st ValidateStandar
 				fmt.Errorf("failed to read body: %v", err), false)
 			return
 		}
+		call.responseSize(len(response.PDF))
 	default:
 		err = ierrors.NewErrorResponse(resp,
 			fmt.Errorf("expected %s or %s, got %s", api_helpers.MediaTypeApplicationJSON,
@@ -600,12 +740,18 @@ func (c *Client) InvoiceToPDF(ctx context.Context, invoice Invoice, noValidate b
 type uploadOptions struct {
 	extern      *string
 	autofactura *string
+	executare   *string
+	metadata    Metadata
+}
+
+func (o uploadOptions) hasFlags() bool {
+	return o.extern != nil || o.autofactura != nil || o.executare != nil
 }
 
 type UploadOption func(*uploadOptions)
 
 // UploadOptionForeign is an upload option specifying that the buyer is not a
-// Romanian entity (no CUI or NIF).
+// Romanian entity (no CUI or NIF). It sets the extern=DA query parameter.
 func UploadOptionForeign() UploadOption {
 	return func(o *uploadOptions) {
 		o.extern = ptr.String("DA")
@@ -614,15 +760,54 @@ func UploadOptionForeign() UploadOption {
 
 // UploadOptionSelfBilled is an upload option specifying that it's a
 // self-billed invoice (the buyer is issuing the invoice on behalf of the
-// supplier.
+// supplier). It sets the autofactura=DA query parameter.
 func UploadOptionSelfBilled() UploadOption {
 	return func(o *uploadOptions) {
 		o.autofactura = ptr.String("DA")
 	}
 }
 
+// UploadOptionEnforcement is an upload option specifying that the invoice is
+// uploaded by an enforcement agent (executor) on behalf of the supplier.
+func UploadOptionEnforcement() UploadOption {
+	return func(o *uploadOptions) {
+		o.executare = ptr.String("DA")
+	}
+}
+
+// UploadOptionFlags is an upload option that sets the given upload flags. It
+// allows using the same flags for uploading and for checking the flags
+// reflected in the message details (see Message.CheckUploadFlags).
+func UploadOptionFlags(flags ...UploadFlag) UploadOption {
+	return func(o *uploadOptions) {
+		for _, flag := range flags {
+			switch flag {
+			case UploadFlagForeign:
+				UploadOptionForeign()(o)
+			case UploadFlagSelfBilled:
+				UploadOptionSelfBilled()(o)
+			case UploadFlagEnforcement:
+				UploadOptionEnforcement()(o)
+			}
+		}
+	}
+}
+
+// UploadOptionMetadata is an upload option that attaches the given user
+// metadata to the UploadResponse. The metadata is not sent to ANAF. The
+// metadata is merged with the metadata from the context (see
+// ContextWithMetadata), the keys from md taking precedence.
+func UploadOptionMetadata(md Metadata) UploadOption {
+	return func(o *uploadOptions) {
+		o.metadata = o.metadata.Merge(md)
+	}
+}
+
 // UploadXML uploads and invoice or message XML. Optional upload options can be
-// provided via call params.
+// provided via call params. DetectUploadStandard can be used for getting the
+// upload standard of a XML document (including the registered custom
+// document types). The correlation ID of the upload (from ctx, or generated
+// by the Client) is sent with the request and returned in the response.
 func (c *Client) UploadXML(
 	ctx context.Context, xml io.Reader, st UploadStandard, cif string, opts ...UploadOption,
 ) (response *UploadResponse, err error) {
@@ -630,69 +815,162 @@ func (c *Client) UploadXML(
 	for _, opt := range opts {
 		opt(&uploadOptions)
 	}
+	ctx, correlationID := c.withCorrelationID(ctx)
 
 	query := url.Values{
 		"standard": {st.String()},
 		"cif":      {cif},
 	}
 	if uploadOptions.autofactura != nil {
-		query.Set("autofactura", *uploadOptions.autofactura)
+		query.Set(UploadFlagSelfBilled.String(), *uploadOptions.autofactura)
 	}
 	if uploadOptions.extern != nil {
-		query.Set("extern", *uploadOptions.extern)
+		query.Set(UploadFlagForeign.String(), *uploadOptions.extern)
 	}
+	if uploadOptions.executare != nil {
+		query.Set(UploadFlagEnforcement.String(), *uploadOptions.executare)
+	}
+
+	ctx, call := c.startCall(ctx, OperationUpload, http.MethodPost)
+	defer func() { call.finish(err) }()
 
-	req, er := c.apiClient.NewRequest(ctx, http.MethodPost, apiPathUpload, query, xml)
+	if err = c.waitRateLimit(ctx, RateLimitGroupUpload); err != nil {
+		return
+	}
+	req, er := c.apiClient.NewRequest(ctx, http.MethodPost, c.apiBase+apiPathUpload, query, xml, c.requestOptions()...)
 	if err = er; err != nil {
 		return
 	}
+	call.request(req)
 
 	res := new(UploadResponse)
 	if err = c.apiClient.DoUnmarshalXML(req, res); err == nil {
+		res.Metadata = MetadataFromContext(ctx).Merge(uploadOptions.metadata)
+		res.CorrelationID = correlationID
 		response = res
 	}
 	return
 }
 
-// UploadInvoice uploads the given Invoice with the provided optional options.
-func (c *Client) UploadInvoice(
-	ctx context.Context, invoice Invoice, cif string, opts ...UploadOption,
+// Document is a document that can be uploaded to e-factura. The document
+// decides the upload standard, so the standard can never mismatch the
+// uploaded XML.
+type Document interface {
+	// UploadStandard returns the standard used for uploading the document.
+	UploadStandard() UploadStandard
+}
+
+// UploadStandard implements the Document interface.
+func (iv Invoice) UploadStandard() UploadStandard {
+	return UploadStandardUBL
+}
+
+// UploadStandard implements the Document interface.
+func (m RaspMessage) UploadStandard() UploadStandard {
+	return UploadStandardRASP
+}
+
+// Upload marshals and uploads the given document, using the upload standard
+// of the document. Upload options are only allowed for invoice documents
+// (they are rejected for messages), except for UploadOptionMetadata. If the
+// Client was created with ClientGeneratedWith, the "generated with" comment
+// is set for an Invoice or CreditNote without a Comment.
+func (c *Client) Upload(
+	ctx context.Context, doc Document, cif string, opts ...UploadOption,
 ) (response *UploadResponse, err error) {
-	xmlReader, err := pxml.MarshalXMLToReader(invoice)
+	if doc == nil {
+		return nil, fmt.Errorf("invalid document")
+	}
+	st := doc.UploadStandard()
+	if st == UploadStandardRASP {
+		o := uploadOptions{}
+		for _, opt := range opts {
+			opt(&o)
+		}
+		if o.hasFlags() {
+			return nil, fmt.Errorf("upload options are not supported for the %s standard", st)
+		}
+	}
+	switch d := doc.(type) {
+	case Invoice:
+		d.Comment = c.generatedWithComment(d.Comment)
+		doc = d
+	case CreditNote:
+		d.Comment = c.generatedWithComment(d.Comment)
+		doc = d
+	}
+
+	xmlReader, err := pxml.MarshalXMLToReader(doc)
 	if err != nil {
 		return nil, err
 	}
+	return c.UploadXML(ctx, xmlReader, st, cif, opts...)
+}
 
-	return c.UploadXML(ctx, xmlReader, UploadStandardUBL, cif, opts...)
+// UploadInvoice uploads the given Invoice with the provided optional options.
+func (c *Client) UploadInvoice(
+	ctx context.Context, invoice Invoice, cif string, opts ...UploadOption,
+) (response *UploadResponse, err error) {
+	return c.Upload(ctx, invoice, cif, opts...)
+}
+
+// UploadCII uploads the given CII document (eg. a cii.CrossIndustryInvoice)
+// with the provided optional options. An error is returned if the document
+// does not use the CII upload standard.
+func (c *Client) UploadCII(
+	ctx context.Context, doc Document, cif string, opts ...UploadOption,
+) (response *UploadResponse, err error) {
+	if doc == nil || doc.UploadStandard() != UploadStandardCII {
+		return nil, fmt.Errorf("invalid document: expected a %s document", UploadStandardCII)
+	}
+	return c.Upload(ctx, doc, cif, opts...)
 }
 
 // UploadRaspMessage uploads the given RaspMessage.
 func (c *Client) UploadRaspMessage(
 	ctx context.Context, msg RaspMessage, cif string,
 ) (response *UploadResponse, err error) {
-	xmlReader, err := pxml.MarshalXMLToReader(msg)
-	if err != nil {
-		return nil, err
-	}
-	return c.UploadXML(ctx, xmlReader, UploadStandardRASP, cif)
+	return c.Upload(ctx, msg, cif)
 }
 
 // GetMessageState fetch the state of a message. The uploadIndex must a result
-// from an upload operation.
+// from an upload operation. If the Client has a MessageStateCache (see
+// ClientMessageStateCache), terminal states are returned from the cache, and
+// terminal states fetched from ANAF are cached. Cache errors are not fatal:
+// if the cache lookup fails, the state is fetched from ANAF, and failing to
+// cache the state is ignored.
 func (c *Client) GetMessageState(
 	ctx context.Context, uploadIndex int64,
 ) (response *GetMessageStateResponse, err error) {
+	if c.stateCache != nil {
+		if state, ok, er := c.stateCache.GetMessageState(ctx, uploadIndex); er == nil && ok && state.IsTerminal() {
+			state.Metadata = MetadataFromContext(ctx).Clone()
+			return state, nil
+		}
+	}
+
 	query := url.Values{
 		"id_incarcare": {strconv.FormatInt(uploadIndex, 10)},
 	}
-	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, apiPathMessageState, query, nil)
+	ctx, call := c.startCall(ctx, OperationMessageState, http.MethodGet)
+	defer func() { call.finish(err) }()
+
+	if err = c.waitRateLimit(ctx, RateLimitGroupMessageState); err != nil {
+		return
+	}
+	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, c.apiBase+apiPathMessageState, query, nil, c.requestOptions()...)
 	if err = er; err != nil {
 		return
 	}
+	call.request(req)
 
 	res := new(GetMessageStateResponse)
 	if err = c.apiClient.DoUnmarshalXML(req, res); err == nil {
+		res.Metadata = MetadataFromContext(ctx).Clone()
 		response = res
+		if c.stateCache != nil && res.IsTerminal() {
+			_ = c.stateCache.PutMessageState(ctx, uploadIndex, res)
+		}
 	}
 	return
 }
@@ -714,13 +992,21 @@ func (c *Client) GetMessagesList(
 	if msgType != MessageFilterAll {
 		query.Set("filter", msgType.String())
 	}
-	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, apiPathMessageList, query, nil)
+	ctx, call := c.startCall(ctx, OperationMessagesList, http.MethodGet)
+	defer func() { call.finish(err) }()
+
+	if err = c.waitRateLimit(ctx, RateLimitGroupMessagesList); err != nil {
+		return
+	}
+	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, c.apiBase+apiPathMessageList, query, nil, c.requestOptions()...)
 	if err = er; err != nil {
 		return
 	}
+	call.request(req)
 
 	res := new(MessagesListResponse)
 	if err = c.apiClient.DoUnmarshalJSON(req, res, func(r *http.Response, _ any) error {
+		call.response(r)
 		if limit, ok := ierrors.ErrorMessageMatchLimitExceeded(res.Error); ok {
 			return ierrors.NewLimitExceededError(r, limit, fmt.Errorf("%s: %s", res.Title, res.Error))
 		}
@@ -752,13 +1038,21 @@ func (c *Client) GetMessagesListPagination(
 		query.Set("filter", f)
 	}
 
-	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, apiPathMessagePaginationList, query, nil)
+	ctx, call := c.startCall(ctx, OperationMessagesListPagination, http.MethodGet)
+	defer func() { call.finish(err) }()
+
+	if err = c.waitRateLimit(ctx, RateLimitGroupMessagesList); err != nil {
+		return
+	}
+	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, c.apiBase+apiPathMessagePaginationList, query, nil, c.requestOptions()...)
 	if err = er; err != nil {
 		return
 	}
+	call.request(req)
 
 	res := new(MessagesListPaginationResponse)
 	if err = c.apiClient.DoUnmarshalJSON(req, res, func(r *http.Response, _ any) error {
+		call.response(r)
 		if limit, ok := ierrors.ErrorMessageMatchLimitExceeded(res.Error); ok {
 			return ierrors.NewLimitExceededError(r, limit, fmt.Errorf("%s: %s", res.Title, res.Error))
 		}
@@ -769,64 +1063,116 @@ func (c *Client) GetMessagesListPagination(
 	return
 }
 
-// DownloadInvoice downloads an invoice zip for a given download index.
-func (c *Client) DownloadInvoice(
-	ctx context.Context, downloadID int64,
-) (response *DownloadInvoiceResponse, err error) {
-	query := url.Values{
-		"id": {strconv.FormatInt(downloadID, 10)},
+// IsPaginationWindowError returns true if the response error means that ANAF
+// rejected the time interval requested (eg. the interval is too wide), so the
+// request can be retried with a smaller interval.
+func (r *MessagesListPaginationResponse) IsPaginationWindowError() bool {
+	return r != nil && r.Error != "" && regexPaginationWindowError.MatchString(r.Error)
+}
+
+// GetAllMessagesPagination fetches all the messages for a provided cif between
+// startTs and endTs by walking all the pages returned by the list messages
+// with pagination endpoint. If ANAF rejects the requested interval (eg. the
+// interval is too wide), the interval is split in two halves that are fetched
+// separately and the results are merged, so the caller gets the full list
+// instead of the raw error. Limit exceeded errors are never retried, to avoid
+// burning the daily quota. If a page fails with any other error, the
+// (non-ok) response for that page is returned.
+func (c *Client) GetAllMessagesPagination(
+	ctx context.Context, cif string, startTs, endTs time.Time, msgType MessageFilterType,
+) (response *MessagesListResponse, err error) {
+	response = &MessagesListResponse{CUI: cif}
+	seen := make(map[string]struct{})
+	var fetch func(startTs, endTs time.Time) (*MessagesListPaginationResponse, error)
+	fetch = func(startTs, endTs time.Time) (*MessagesListPaginationResponse, error) {
+		for page := int64(1); ; page++ {
+			res, err := c.GetMessagesListPagination(ctx, cif, startTs, endTs, page, msgType)
+			if err != nil {
+				return nil, err
+			}
+			if res.IsPaginationWindowError() && endTs.Sub(startTs) > minPaginationWindow {
+				// The window was rejected, so we split it in two halves.
+				// Messages already collected from the previous pages are
+				// deduplicated by ID.
+				mid := startTs.Add(endTs.Sub(startTs) / 2)
+				if res, err := fetch(startTs, mid); err != nil || !res.IsOk() {
+					return res, err
+				}
+				return fetch(mid, endTs)
+			}
+			if !res.IsOk() {
+				return res, nil
+			}
+
+			response.Title, response.Serial = res.Title, res.Serial
+			for _, m := range res.Messages {
+				if _, ok := seen[m.ID]; ok {
+					continue
+				}
+				seen[m.ID] = struct{}{}
+				response.Messages = append(response.Messages, m)
+			}
+			if page >= res.TotalPages {
+				return res, nil
+			}
+		}
 	}
-	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, apiPathDownload, query, nil)
+
+	res, er := fetch(startTs, endTs)
 	if err = er; err != nil {
+		response = nil
 		return
 	}
-
-	resp, er := c.apiClient.Do(req)
-	if resp != nil && resp.Body != nil {
-		defer resp.Body.Close()
+	if !res.IsOk() {
+		response = &res.MessagesListResponse
 	}
-	if err = er; err != nil {
-		return
+	return
+}
+
+// DownloadInvoice downloads an invoice zip for a given download index. By
+// default a single attempt is made; use DownloadOptionRetry to retry (and
+// resume, if possible) interrupted or corrupted downloads. The correlation ID
+// of the download (from ctx, or generated by the Client) is sent with the
+// requests and returned in the response.
+func (c *Client) DownloadInvoice(
+	ctx context.Context, downloadID int64, opts ...DownloadOption,
+) (response *DownloadInvoiceResponse, err error) {
+	downloadOpts := downloadOptions{maxAttempts: 1}
+	for _, opt := range opts {
+		opt(&downloadOpts)
 	}
+	// All the attempts share the same correlation ID.
+	ctx, _ = c.withCorrelationID(ctx)
 
-	// If the response content type is application/json, then the download
-	// failed, otherwise we got the zip in response body
-	switch mediaType := api_helpers.ResponseMediaType(resp.Header); mediaType {
-	case api_helpers.MediaTypeApplicationJSON:
-		resError := new(DownloadInvoiceResponseError)
-		if err = api_helpers.UnmarshalReaderJSON(resp.Body, resError); err != nil {
-			err = ierrors.NewErrorResponseParse(resp, err, false)
+	var partial *partialDownload
+	for attempt := 1; ; attempt++ {
+		response, partial, err = c.downloadInvoiceAttempt(ctx, downloadID, partial, downloadOpts.retryEnabled())
+		if err == nil || attempt >= downloadOpts.maxAttempts || !isRetryableDownloadError(ctx, err) {
 			return
 		}
-		if limit, ok := ierrors.ErrorMessageMatchLimitExceeded(resError.Error); ok {
-			err = ierrors.NewLimitExceededError(resp, limit, fmt.Errorf("%s: %s", resError.Title, resError.Error))
+		if er := sleepContext(ctx, downloadOpts.backoffFor(attempt)); er != nil {
 			return
 		}
-		response = &DownloadInvoiceResponse{Error: resError}
-	case api_helpers.MediaTypeApplicationZIP:
-		response = &DownloadInvoiceResponse{}
-		if response.Zip, err = io.ReadAll(resp.Body); err != nil {
-			err = ierrors.NewErrorResponseParse(resp, err, false)
-			return
-		}
-	case api_helpers.MediaTypeTextPlain:
-		err = ierrors.NewErrorResponseDetectType(resp)
-	default:
-		err = ierrors.NewErrorResponse(resp,
-			fmt.Errorf("expected %s or %s, got %s", api_helpers.MediaTypeApplicationJSON,
-				api_helpers.MediaTypeApplicationPDF, mediaType))
 	}
-	return
 }
 
 // DownloadInvoiceParseZip same as DownloadInvoice but also parses the zip
 // archive. If the response is not nil, the DownloadResponse will always be
 // set. If there was an error parsing the zip archive, the response will
 // contain the download response, and an error is returned. This method is not
-// validating the signature.
+// validating the signature. The invoice is parsed using ParseModeDefault,
+// unless a different mode is selected using ParseOptionMode. Documents not
+// encoded as UTF-8 are converted using the CharsetReader of the Client (see
+// ClientCharsetReader), unless a different one is set using
+// ParseOptionCharsetReader.
 func (c *Client) DownloadInvoiceParseZip(
-	ctx context.Context, downloadID int64,
+	ctx context.Context, downloadID int64, opts ...ParseOption,
 ) (response *DownloadInvoiceParseZipResponse, err error) {
+	parseOpts := parseOptions{charsetReader: c.charsetReader}
+	for _, opt := range opts {
+		opt(&parseOpts)
+	}
+
 	dres, er := c.DownloadInvoice(ctx, downloadID)
 	if er != nil {
 		return nil, er
@@ -846,28 +1192,34 @@ func (c *Client) DownloadInvoiceParseZip(
 	response.InvoiceXML, response.InvoiceName = invoiceXML.data, invoiceXML.name
 	response.SignatureXML, response.SignatureName = signatureXML.data, signatureXML.name
 
-	var invoice *Invoice
-	var invoiceError *InvoiceErrorMessage
-	invoice, invoiceError, err = parseDownloadedInvoiceXML(ctx, response.InvoiceXML)
-	if err != nil {
+	doc, er := unmarshalDownloadedXML(response.InvoiceXML, parseOpts)
+	if err = er; err != nil {
 		return
 	}
 
-	response.Invoice, response.InvoiceError = invoice, invoiceError
+	response.Invoice, response.InvoiceError = doc.Invoice, doc.InvoiceError
+	response.CreditNote = doc.CreditNote
+	response.Document, response.DocumentType = doc.Document, doc.DocumentType
+	response.ParseIssues = doc.ParseIssues
 	return
 }
 
 func (c *Client) doValidateSignature(
 	ctx context.Context, body io.Reader, contentType string,
 ) (response *ValidateSignatureResponse, err error) {
+	ctx, call := c.startCall(ctx, OperationValidateSignature, http.MethodPost)
+	defer func() { call.finish(err) }()
+
 	req, er := c.publicApiClient.NewRequest(ctx, http.MethodPost, apiPathValidateSignature, nil, body,
-		client.RequestOptionHeader("Content-Type", contentType))
+		c.requestOptions(client.RequestOptionHeader("Content-Type", contentType))...)
 	if err = er; err != nil {
 		return
 	}
+	call.request(req)
 
 	eres := new(ValidateSignatureResponse)
 	if err = c.publicApiClient.DoUnmarshalJSON(req, eres, func(r *http.Response, _ any) error {
+		call.response(r)
 		// TODO: check rate limiting
 		return nil
 	}); err == nil {
@@ -994,38 +1346,3 @@ func parseInvoiceZip(ctx context.Context, zipBody []byte) (invoiceXml, signature
 
 	return
 }
-
-func parseDownloadedInvoiceXML(ctx context.Context, invoiceXML []byte) (invoice *Invoice, invoiceError *InvoiceErrorMessage, err error) {
-	// This is a trick for optimizing the unmarshaling: since the xml
-	// can be either an Invoice or an InvoiceErrorMessage, we create a
-	// struct with just an xml.Name, and based on the namespace we
-	// unmarshal one or the other.
-	type docName struct {
-		XMLName xml.Name
-	}
-	var doc docName
-	if err = pxml.UnmarshalXML(invoiceXML, &doc); err != nil {
-		return
-	}
-	switch doc.XMLName.Space {
-	case xmlnsUBLInvoice2:
-		iv := new(Invoice)
-		if err = pxml.UnmarshalXML(invoiceXML, iv); err != nil {
-			return
-		}
-		invoice = iv
-
-	case xmlnsMsgErrorV1:
-		ie := new(InvoiceErrorMessage)
-		if err = pxml.UnmarshalXML(invoiceXML, &ie); err != nil {
-			return
-		}
-		invoiceError = ie
-
-	default:
-		err = fmt.Errorf("invalid namespace for invoice/message: %q", doc.XMLName.Space)
-		return
-	}
-
-	return
-}
@@ -0,0 +1,154 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	efactura_errors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+func TestGetAllMessagesPagination(t *testing.T) {
+	assert := assert.New(t)
+
+	const (
+		pageSize  = 2
+		maxWindow = 10 * 24 * time.Hour
+	)
+
+	startTs := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	endTs := startTs.Add(40 * 24 * time.Hour)
+
+	// One message every 36 hours for the whole interval.
+	type testMessage struct {
+		ts  time.Time
+		msg efactura.Message
+	}
+	var messages []testMessage
+	for i, ts := 0, startTs; ts.Before(endTs); i, ts = i+1, ts.Add(36*time.Hour) {
+		messages = append(messages, testMessage{ts: ts, msg: efactura.Message{
+			ID:   strconv.Itoa(1000 + i),
+			Type: efactura.MessageTypeReceivedInvoice,
+		}})
+	}
+
+	client, mux := setupTestClient(t)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/listaMesajePaginatieFactura", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		q := r.URL.Query()
+		startMs, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+		endMs, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+		page, _ := strconv.ParseInt(q.Get("pagina"), 10, 64)
+		start, end := time.UnixMilli(startMs), time.UnixMilli(endMs)
+		if end.Sub(start) > maxWindow {
+			writeJSON(w, map[string]any{
+				"eroare": "Intervalul dintre startTime si endTime nu poate fi mai mare de 10 zile",
+				"titlu":  "Lista Mesaje",
+			})
+			return
+		}
+
+		var inWindow []efactura.Message
+		for _, m := range messages {
+			if !m.ts.Before(start) && m.ts.Before(end) {
+				inWindow = append(inWindow, m.msg)
+			}
+		}
+		if len(inWindow) == 0 {
+			writeJSON(w, map[string]any{
+				"eroare": "Nu exista mesaje in intervalul selectat",
+				"titlu":  "Lista Mesaje",
+			})
+			return
+		}
+		totalPages := (int64(len(inWindow)) + pageSize - 1) / pageSize
+		from := (page - 1) * pageSize
+		to := min(from+pageSize, int64(len(inWindow)))
+		writeJSON(w, map[string]any{
+			"mesaje":                              inWindow[from:to],
+			"numar_inregistrari_in_pagina":        to - from,
+			"numar_total_inregistrari_per_pagina": pageSize,
+			"numar_total_inregistrari":            len(inWindow),
+			"numar_total_pagini":                  totalPages,
+			"index_pagina_curenta":                page,
+			"titlu":                               "Lista Mesaje",
+		})
+	})
+
+	res, err := client.GetAllMessagesPagination(context.Background(), "123456789",
+		startTs, endTs, efactura.MessageFilterAll)
+	if !assert.NoError(err) || !assert.True(res.IsOk()) {
+		return
+	}
+	if assert.Len(res.Messages, len(messages)) {
+		for i, m := range messages {
+			assert.Equal(m.msg.ID, res.Messages[i].ID)
+		}
+	}
+	assert.Greater(calls, 1)
+}
+
+func TestGetAllMessagesPaginationLimitExceeded(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	var calls int
+	mux.HandleFunc("/FCTEL/rest/listaMesajePaginatieFactura", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, map[string]any{
+			"eroare": "S-au facut deja 100000 de interogari de tip lista mesaje de catre CUI=123456789 in cursul zilei",
+			"titlu":  "Lista Mesaje",
+		})
+	})
+
+	endTs := time.Now()
+	res, err := client.GetAllMessagesPagination(context.Background(), "123456789",
+		endTs.Add(-30*24*time.Hour), endTs, efactura.MessageFilterAll)
+	assert.Nil(res)
+	var limitErr *efactura_errors.LimitExceededError
+	if assert.True(errors.As(err, &limitErr)) {
+		assert.Equal(int64(100000), limitErr.Limit)
+	}
+	assert.Equal(1, calls, "limit exceeded errors must not be retried")
+}
+
+func TestGetAllMessagesPaginationOtherError(t *testing.T) {
+	assert := assert.New(t)
+
+	client, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/listaMesajePaginatieFactura", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"eroare": "CIF introdus= 123456789 nu este un numar",
+			"titlu":  "Lista Mesaje",
+		})
+	})
+
+	endTs := time.Now()
+	res, err := client.GetAllMessagesPagination(context.Background(), "123456789",
+		endTs.Add(-24*time.Hour), endTs, efactura.MessageFilterAll)
+	if assert.NoError(err) && assert.NotNil(res) {
+		assert.False(res.IsOk())
+		assert.Equal("CIF introdus= 123456789 nu este un numar", res.Error)
+	}
+}
@@ -15,7 +15,11 @@
 package efactura_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 	_ "time/tzdata"
 
@@ -192,3 +196,80 @@ func TestUnmarshalMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestMessagesListResponseEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		error string
+		ok    bool
+		empty bool
+	}{
+		{error: "", ok: true, empty: true},
+		{error: "Nu exista mesaje in ultimele 1 zile", ok: true, empty: true},
+		{error: "Nu exista mesaje in intervalul selectat", ok: true, empty: true},
+		{error: "Nu există mesaje în ultimele 60 de zile", ok: true, empty: true},
+		{error: "  NU EXISTA   MESAJE IN ultimele 5 zile", ok: true, empty: true},
+		{error: "Nu au fost gasite mesaje", ok: true, empty: true},
+		{error: "There are no messages in the selected interval", ok: true, empty: true},
+		{error: "CIF introdus= 123 nu este un numar", ok: false, empty: false},
+		{error: "S-au facut deja 1000 de interogari de tip lista mesaje in cursul zilei", ok: false, empty: false},
+	}
+	for _, tt := range tests {
+		res := &efactura.MessagesListResponse{Error: tt.error}
+		assert.Equal(tt.ok, res.IsOk(), tt.error)
+		assert.Equal(tt.empty, res.Empty(), tt.error)
+	}
+
+	res := &efactura.MessagesListResponse{Messages: []efactura.Message{{ID: "1"}}}
+	assert.True(res.IsOk())
+	assert.False(res.Empty())
+
+	var nilRes *efactura.MessagesListResponse
+	assert.False(nilRes.Empty())
+}
+
+func TestXMLToPDFValidationErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	c, mux := setupTestClient(t)
+	mux.HandleFunc("/FCTEL/rest/transformare/FACT1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"stare": "nok", "trace_id": "abc", "Messages": [
+			{"message": "E: validari globale  eroare:  [BR-RO-010]-Numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric; "},
+			{"message": "E: fisier xml eroare: cvc-complex-type.2.4.a: Invalid content"}
+		]}`)
+	})
+	mux.HandleFunc("/FCTEL/rest/validare/FACT1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"stare": "nok", "trace_id": "abc", "Messages": [
+			{"message": "E: validari globale  eroare:  [BR-RO-010]-Numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric; "},
+			{"message": "E: fisier xml eroare: cvc-complex-type.2.4.a: Invalid content"}
+		]}`)
+	})
+
+	ctx := context.Background()
+	pdfRes, err := c.XMLToPDF(ctx, strings.NewReader("<Invoice/>"), efactura.ValidateStandardFACT1, false)
+	if !assert.NoError(err) || !assert.False(pdfRes.IsOk()) {
+		return
+	}
+	errs := pdfRes.ValidationErrors()
+	if assert.Len(errs, 2) {
+		assert.Equal("BR-RO-010", errs[0].Rule)
+		assert.Equal("Numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric", errs[0].Message)
+		assert.Equal(efactura.ValidationRuleUnidentified, errs[1].Rule)
+	}
+
+	// The validation endpoint reports the same errors.
+	validateRes, err := c.ValidateXML(ctx, strings.NewReader("<Invoice/>"), efactura.ValidateStandardFACT1)
+	if assert.NoError(err) {
+		assert.Equal(errs, validateRes.ValidationErrors())
+	}
+
+	assert.Nil((&efactura.GeneratePDFResponse{PDF: []byte("%PDF")}).ValidationErrors())
+	assert.Nil((&efactura.ValidateResponse{State: efactura.CodeOk}).ValidationErrors())
+	errs = (&efactura.GeneratePDFResponseError{State: "nok"}).ValidationErrors()
+	if assert.Len(errs, 1) {
+		assert.Equal(efactura.ValidationError{Rule: efactura.ValidationRuleUnidentified, Message: "nok"}, errs[0])
+	}
+}
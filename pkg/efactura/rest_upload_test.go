@@ -0,0 +1,228 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/printesoi/xml-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/efactura/cii"
+)
+
+// uploadRequest is a recorded request to the upload endpoint.
+type uploadRequest struct {
+	Query   url.Values
+	RootTag xml.Name
+}
+
+// setupTestUploadClient creates a test client whose upload endpoint records
+// all the requests and responds with a successful upload response.
+func setupTestUploadClient(t *testing.T) (*efactura.Client, *[]uploadRequest) {
+	t.Helper()
+
+	client, mux := setupTestClient(t)
+	var requests []uploadRequest
+	mux.HandleFunc("/FCTEL/rest/upload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Request method: %v, want %v", r.Method, http.MethodPost)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var root struct {
+			XMLName xml.Name
+		}
+		_ = xml.Unmarshal(body, &root)
+		requests = append(requests, uploadRequest{
+			Query:   r.URL.Query(),
+			RootTag: root.XMLName,
+		})
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+			`<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="42"/>`)
+	})
+	return client, &requests
+}
+
+func TestUploadDocument(t *testing.T) {
+	assert := assert.New(t)
+
+	client, requests := setupTestUploadClient(t)
+	ctx := context.Background()
+
+	res, err := client.Upload(ctx, efactura.Invoice{ID: "1"}, "123456789")
+	if assert.NoError(err) && assert.True(res.IsOk()) {
+		assert.Equal(int64(42), res.GetUploadIndex())
+	}
+	res, err = client.Upload(ctx, efactura.RaspMessage{UploadIndex: 42, Message: "test"}, "123456789")
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+	}
+
+	if assert.Len(*requests, 2) {
+		assert.Equal("UBL", (*requests)[0].Query.Get("standard"))
+		assert.Equal("123456789", (*requests)[0].Query.Get("cif"))
+		assert.Equal("Invoice", (*requests)[0].RootTag.Local)
+		assert.Equal("RASP", (*requests)[1].Query.Get("standard"))
+		assert.Equal("header", (*requests)[1].RootTag.Local)
+	}
+
+	_, err = client.Upload(ctx, efactura.RaspMessage{UploadIndex: 42, Message: "test"}, "123456789",
+		efactura.UploadOptionForeign())
+	assert.Error(err, "upload options must be rejected for messages")
+	_, err = client.Upload(ctx, nil, "123456789")
+	assert.Error(err)
+	assert.Len(*requests, 2)
+}
+
+func TestUploadOptionsQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	client, requests := setupTestUploadClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		opts  []efactura.UploadOption
+		query url.Values
+	}{{
+		name:  "no options",
+		query: url.Values{"standard": {"UBL"}, "cif": {"123456789"}},
+	}, {
+		name:  "self-billed",
+		opts:  []efactura.UploadOption{efactura.UploadOptionSelfBilled()},
+		query: url.Values{"standard": {"UBL"}, "cif": {"123456789"}, "autofactura": {"DA"}},
+	}, {
+		name:  "foreign",
+		opts:  []efactura.UploadOption{efactura.UploadOptionForeign()},
+		query: url.Values{"standard": {"UBL"}, "cif": {"123456789"}, "extern": {"DA"}},
+	}, {
+		name:  "enforcement",
+		opts:  []efactura.UploadOption{efactura.UploadOptionEnforcement()},
+		query: url.Values{"standard": {"UBL"}, "cif": {"123456789"}, "executare": {"DA"}},
+	}, {
+		name: "all flags",
+		opts: []efactura.UploadOption{efactura.UploadOptionFlags(
+			efactura.UploadFlagSelfBilled, efactura.UploadFlagForeign, efactura.UploadFlagEnforcement)},
+		query: url.Values{"standard": {"UBL"}, "cif": {"123456789"},
+			"autofactura": {"DA"}, "extern": {"DA"}, "executare": {"DA"}},
+	}, {
+		name:  "metadata is never sent",
+		opts:  []efactura.UploadOption{efactura.UploadOptionMetadata(efactura.Metadata{"order_id": "ORD-1"})},
+		query: url.Values{"standard": {"UBL"}, "cif": {"123456789"}},
+	}}
+	for i, test := range tests {
+		_, err := client.UploadInvoice(ctx, efactura.Invoice{ID: "1"}, "123456789", test.opts...)
+		if assert.NoError(err, test.name) && assert.Len(*requests, i+1, test.name) {
+			assert.Equal(test.query, (*requests)[i].Query, test.name)
+		}
+	}
+}
+
+func TestUploadCreditNote(t *testing.T) {
+	assert := assert.New(t)
+
+	client, requests := setupTestUploadClient(t)
+	ctx := context.Background()
+
+	res, err := client.UploadCreditNote(ctx, efactura.CreditNote{ID: "CN-1"}, "123456789",
+		efactura.UploadOptionSelfBilled())
+	if assert.NoError(err) {
+		assert.Equal(int64(42), res.GetUploadIndex())
+	}
+	if assert.Len(*requests, 1) {
+		assert.Equal("CN", (*requests)[0].Query.Get("standard"))
+		assert.Equal("DA", (*requests)[0].Query.Get("autofactura"))
+		assert.Equal("CreditNote", (*requests)[0].RootTag.Local)
+		assert.Equal("urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2", (*requests)[0].RootTag.Space)
+	}
+}
+
+func TestUploadCII(t *testing.T) {
+	assert := assert.New(t)
+
+	client, requests := setupTestUploadClient(t)
+	ctx := context.Background()
+
+	doc := cii.FromInvoice(efactura.Invoice{ID: "CII-1", DocumentCurrencyCode: efactura.CurrencyRON})
+	res, err := client.UploadCII(ctx, doc, "123456789")
+	if assert.NoError(err) {
+		assert.Equal(int64(42), res.GetUploadIndex())
+	}
+	if assert.Len(*requests, 1) {
+		assert.Equal("CII", (*requests)[0].Query.Get("standard"))
+		assert.Equal("CrossIndustryInvoice", (*requests)[0].RootTag.Local)
+		assert.Equal("urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100", (*requests)[0].RootTag.Space)
+	}
+
+	_, err = client.UploadCII(ctx, efactura.Invoice{ID: "UBL-1"}, "123456789")
+	assert.Error(err, "should not upload an UBL invoice as CII")
+	assert.Len(*requests, 1)
+}
+
+func TestUploadPassThrough(t *testing.T) {
+	assert := assert.New(t)
+
+	// Re-serializing this document would change the prefixes, the
+	// whitespace, the comment and the signature element.
+	signedXML := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- produced by another system -->
+<ubl:Invoice xmlns:ubl="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+    xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+    xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+  <cbc:ID>PT-1</cbc:ID>
+  <ds:Signature><ds:SignatureValue>c2lnbmF0dXJl</ds:SignatureValue></ds:Signature>
+</ubl:Invoice>`
+
+	client, mux := setupTestClient(t)
+	var bodies []string
+	var queries []url.Values
+	mux.HandleFunc("/FCTEL/rest/upload", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		queries = append(queries, r.URL.Query())
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+			`<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="42"/>`)
+	})
+
+	ctx := context.Background()
+	xmlData := []byte(signedXML)
+	res, err := client.UploadPassThrough(ctx, xmlData, "123456789", efactura.UploadOptionSelfBilled())
+	if assert.NoError(err) && assert.True(res.IsOk()) {
+		assert.Equal(int64(42), res.GetUploadIndex())
+		assert.Len(res.SHA256, 64)
+	}
+	if assert.Len(bodies, 1) {
+		assert.Equal(signedXML, bodies[0])
+		assert.Equal(string(efactura.UploadStandardUBL), queries[0].Get("standard"))
+		assert.Equal("DA", queries[0].Get("autofactura"))
+	}
+	assert.Equal(signedXML, string(xmlData))
+
+	_, err = client.UploadPassThrough(ctx, []byte(`<ubl:Invoice xmlns:ubl="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2">`), "123456789")
+	assert.Error(err, "malformed XML")
+	_, err = client.UploadPassThrough(ctx, []byte(`<header xmlns="mfp:anaf:dgti:spv:reqMesaj:v1" index_incarcare="1" message="test"/>`),
+		"123456789", efactura.UploadOptionForeign())
+	assert.Error(err, "upload options are not supported for messages")
+	assert.Len(bodies, 1)
+}
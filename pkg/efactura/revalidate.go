@@ -0,0 +1,219 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+const (
+	// MetadataKeySignatureValidatedAt is the store entry metadata key with
+	// the time (RFC 3339) of the last signature validation.
+	MetadataKeySignatureValidatedAt = "signature_validated_at"
+	// MetadataKeySignatureValid is the store entry metadata key with the
+	// result ("true" or "false") of the last signature validation.
+	MetadataKeySignatureValid = "signature_valid"
+	// MetadataKeySignatureMessage is the store entry metadata key with the
+	// message of the last signature validation.
+	MetadataKeySignatureMessage = "signature_message"
+
+	defaultRevalidationInterval = 24 * time.Hour
+	defaultRevalidationMinAge   = 30 * 24 * time.Hour
+)
+
+// SignatureValidator validates the signature of an invoice zip archive.
+// Client implements this interface (using the ANAF validate signature
+// endpoint).
+type SignatureValidator interface {
+	ValidateSignatureZipData(ctx context.Context, zipData []byte) (*ValidateSignatureResponse, error)
+}
+
+// RevalidationResult is the result of re-validating an archived invoice.
+type RevalidationResult struct {
+	// Key is the store key of the archive.
+	Key string
+	// ValidatedAt is the time of the validation.
+	ValidatedAt time.Time
+	// Valid is true if the archive is intact and the signature is valid.
+	Valid bool
+	// Message is the message of the validate signature response, or the
+	// reason the archive is invalid (eg. truncated file).
+	Message string
+	// Err is set if the archive could not be validated (eg. a network
+	// error). In this case the validation is not recorded and will be
+	// retried on the next pass.
+	Err error
+}
+
+// RevalidationConfig is the config used by a SignatureRevalidator.
+type RevalidationConfig struct {
+	// Interval is the interval between two re-validation passes of Run.
+	// Default is 24 hours.
+	Interval time.Duration
+	// MinAge is the minimum duration since the last validation of an
+	// archive before validating it again. Default is 30 days.
+	MinAge time.Duration
+	// Filter, if set, selects the entries to be re-validated.
+	Filter func(entry store.Entry) bool
+	// OnResult, if set, is called for each validated archive.
+	OnResult func(result RevalidationResult)
+}
+
+// RevalidationConfigOption allows gradually modifying a RevalidationConfig
+type RevalidationConfigOption func(*RevalidationConfig)
+
+// RevalidationInterval sets the interval between two re-validation passes.
+func RevalidationInterval(interval time.Duration) RevalidationConfigOption {
+	return func(c *RevalidationConfig) {
+		c.Interval = interval
+	}
+}
+
+// RevalidationMinAge sets the minimum duration since the last validation of
+// an archive before validating it again.
+func RevalidationMinAge(minAge time.Duration) RevalidationConfigOption {
+	return func(c *RevalidationConfig) {
+		c.MinAge = minAge
+	}
+}
+
+// RevalidationFilter sets the function that selects the entries to be
+// re-validated.
+func RevalidationFilter(filter func(entry store.Entry) bool) RevalidationConfigOption {
+	return func(c *RevalidationConfig) {
+		c.Filter = filter
+	}
+}
+
+// RevalidationOnResult sets the function called for each validated archive.
+func RevalidationOnResult(onResult func(result RevalidationResult)) RevalidationConfigOption {
+	return func(c *RevalidationConfig) {
+		c.OnResult = onResult
+	}
+}
+
+// SignatureRevalidator periodically re-validates the signatures of the
+// invoice zip archives from a store (eg. archived with ArchiveSentInvoice),
+// detecting bit rot or truncated files. The result and the time of the last
+// validation are recorded in the entry metadata (see the MetadataKeySignature*
+// constants).
+type SignatureRevalidator struct {
+	validator SignatureValidator
+	store     store.Store
+	config    RevalidationConfig
+}
+
+// NewSignatureRevalidator creates a SignatureRevalidator for the archives in
+// st, using validator (usually a *Client) for validating the signatures.
+func NewSignatureRevalidator(validator SignatureValidator, st store.Store, opts ...RevalidationConfigOption) *SignatureRevalidator {
+	cfg := RevalidationConfig{
+		Interval: defaultRevalidationInterval,
+		MinAge:   defaultRevalidationMinAge,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &SignatureRevalidator{
+		validator: validator,
+		store:     st,
+		config:    cfg,
+	}
+}
+
+// Run runs a re-validation pass every Interval, until ctx is done. The first
+// pass is run immediately. Run returns the ctx error, or the error returned
+// by a pass if the store cannot be listed.
+func (r *SignatureRevalidator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RevalidateOnce(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RevalidateOnce runs a single re-validation pass: each archive not
+// validated in the last MinAge is checked against the recorded size and
+// checksum and its signature is validated. The results are returned sorted by
+// key. An error is returned only if the store cannot be listed or ctx is
+// done, the errors for individual archives are reported in the results.
+func (r *SignatureRevalidator) RevalidateOnce(ctx context.Context) (results []RevalidationResult, err error) {
+	entries, err := r.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if !r.isDue(entry) {
+			continue
+		}
+		result := r.revalidate(ctx, entry)
+		if r.config.OnResult != nil {
+			r.config.OnResult(result)
+		}
+		results = append(results, result)
+	}
+	return
+}
+
+func (r *SignatureRevalidator) isDue(entry store.Entry) bool {
+	if r.config.Filter != nil && !r.config.Filter(entry) {
+		return false
+	}
+	validatedAt, err := time.Parse(time.RFC3339, entry.Metadata[MetadataKeySignatureValidatedAt])
+	if err != nil {
+		return true
+	}
+	return time.Since(validatedAt) >= r.config.MinAge
+}
+
+func (r *SignatureRevalidator) revalidate(ctx context.Context, entry store.Entry) (result RevalidationResult) {
+	result = RevalidationResult{Key: entry.Key, ValidatedAt: time.Now()}
+
+	entry, data, err := r.store.Get(ctx, entry.Key)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	if err := store.Verify(entry, data); err != nil {
+		result.Message = err.Error()
+	} else if _, _, err := parseInvoiceZip(ctx, data); err != nil {
+		result.Message = "invalid archive: " + err.Error()
+	} else if res, err := r.validator.ValidateSignatureZipData(ctx, data); err != nil {
+		result.Err = err
+		return
+	} else {
+		result.Valid, result.Message = res.IsValid(), res.Message
+	}
+
+	result.Err = store.UpdateMetadata(ctx, r.store, entry.Key, map[string]string{
+		MetadataKeySignatureValidatedAt: result.ValidatedAt.UTC().Format(time.RFC3339),
+		MetadataKeySignatureValid:       strconv.FormatBool(result.Valid),
+		MetadataKeySignatureMessage:     result.Message,
+	})
+	return
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+const (
+	testSignatureValidMessage   = "Fișierele încărcate au fost validate cu succes, iar semnătura este validă pentru documentul respectiv."
+	testSignatureInvalidMessage = "Fișierele încărcate NU au putut fi validate cu succes, semnătura NU este validă pentru documentul respectiv."
+)
+
+type testSignatureValidator struct {
+	calls int
+	err   error
+}
+
+func (v *testSignatureValidator) ValidateSignatureZipData(ctx context.Context, zipData []byte) (*efactura.ValidateSignatureResponse, error) {
+	v.calls++
+	if v.err != nil {
+		return nil, v.err
+	}
+	return &efactura.ValidateSignatureResponse{Message: testSignatureValidMessage}, nil
+}
+
+// corruptStore simulates bit rot by returning truncated data for a key.
+type corruptStore struct {
+	store.Store
+	key string
+}
+
+func (s corruptStore) Get(ctx context.Context, key string) (store.Entry, []byte, error) {
+	entry, data, err := s.Store.Get(ctx, key)
+	if err == nil && key == s.key {
+		data = data[:len(data)/2]
+	}
+	return entry, data, err
+}
+
+func TestValidateSignatureResponseIsValid(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True((&efactura.ValidateSignatureResponse{Message: testSignatureValidMessage}).IsValid())
+	assert.False((&efactura.ValidateSignatureResponse{Message: testSignatureInvalidMessage}).IsValid())
+	assert.False((&efactura.ValidateSignatureResponse{}).IsValid())
+	assert.False((*efactura.ValidateSignatureResponse)(nil).IsValid())
+}
+
+func TestSignatureRevalidator(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	mem := store.NewMemoryStore()
+	assert.NoError(mem.Put(ctx, store.Entry{Key: "3001"}, newTestInvoiceZip(t, 3001)))
+	assert.NoError(mem.Put(ctx, store.Entry{Key: "3002"}, newTestInvoiceZip(t, 3002)))
+	assert.NoError(mem.Put(ctx, store.Entry{Key: "3003"}, []byte("not a zip")))
+	st := corruptStore{Store: mem, key: "3002"}
+
+	validator := &testSignatureValidator{}
+	var reported []string
+	revalidator := efactura.NewSignatureRevalidator(validator, st,
+		efactura.RevalidationOnResult(func(result efactura.RevalidationResult) {
+			reported = append(reported, result.Key)
+		}))
+
+	results, err := revalidator.RevalidateOnce(ctx)
+	if assert.NoError(err) && assert.Len(results, 3) {
+		assert.True(results[0].Valid)
+		assert.NoError(results[0].Err)
+		assert.Equal(testSignatureValidMessage, results[0].Message)
+
+		assert.False(results[1].Valid, "truncated archive")
+		assert.NoError(results[1].Err)
+		assert.Contains(results[1].Message, "corrupted")
+
+		assert.False(results[2].Valid, "invalid archive")
+		assert.NoError(results[2].Err)
+	}
+	assert.Equal([]string{"3001", "3002", "3003"}, reported)
+	assert.Equal(1, validator.calls)
+
+	entry, err := mem.Stat(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal("true", entry.Metadata[efactura.MetadataKeySignatureValid])
+		validatedAt, err := time.Parse(time.RFC3339, entry.Metadata[efactura.MetadataKeySignatureValidatedAt])
+		if assert.NoError(err) {
+			assert.WithinDuration(time.Now(), validatedAt, time.Minute)
+		}
+	}
+	entry, err = mem.Stat(ctx, "3002")
+	if assert.NoError(err) {
+		assert.Equal("false", entry.Metadata[efactura.MetadataKeySignatureValid])
+	}
+
+	// Archives validated recently are skipped.
+	results, err = revalidator.RevalidateOnce(ctx)
+	if assert.NoError(err) {
+		assert.Empty(results)
+	}
+
+	// Validation errors are not recorded, so the archive is retried.
+	validator.err = errors.New("network error")
+	revalidator = efactura.NewSignatureRevalidator(validator, mem,
+		efactura.RevalidationMinAge(0),
+		efactura.RevalidationFilter(func(entry store.Entry) bool {
+			return entry.Key == "3001"
+		}))
+	results, err = revalidator.RevalidateOnce(ctx)
+	if assert.NoError(err) && assert.Len(results, 1) {
+		assert.Error(results[0].Err)
+	}
+	entry, err = mem.Stat(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal("true", entry.Metadata[efactura.MetadataKeySignatureValid])
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.True(errors.Is(revalidator.Run(ctx), context.Canceled))
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/printesoi/xml-go"
+	"github.com/shopspring/decimal"
+)
+
+// RoundTripReport is the result of RoundTripCheck. The leaves of the
+// documents are compared as "path=value" entries (eg.
+// "Invoice/InvoiceLine/ID=1") and the attributes as "path/@name=value"
+// entries (eg. "Invoice/LegalMonetaryTotal/PayableAmount/@currencyID=RON").
+// The namespaces, the comments and the whitespace around the values are
+// ignored, and the decimal numbers are compared by value (eg. 19.00 and 19).
+type RoundTripReport struct {
+	// Reproduced is the document marshaled from the parsed document.
+	Reproduced []byte
+	// Missing are the entries of the original document that are not in the
+	// reproduced document (ie. the data lost by the round trip).
+	Missing []string
+	// Extra are the entries of the reproduced document that are not in the
+	// original document.
+	Extra []string
+}
+
+// OK returns true if the document survived the round trip losslessly.
+func (r *RoundTripReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// String returns a human readable summary of the report.
+func (r *RoundTripReport) String() string {
+	if r.OK() {
+		return "round trip: lossless"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "round trip: %d missing, %d extra", len(r.Missing), len(r.Extra))
+	for _, e := range r.Missing {
+		fmt.Fprintf(&sb, "\n- %s", e)
+	}
+	for _, e := range r.Extra {
+		fmt.Fprintf(&sb, "\n+ %s", e)
+	}
+	return sb.String()
+}
+
+// RoundTripCheck parses the given Invoice or CreditNote XML document, marshals
+// it back to XML and compares the two documents, so that users can verify
+// that their documents survive UnmarshalInvoice/UnmarshalCreditNote and XML
+// without losing data (see RoundTripReport). An error is returned only if the
+// document cannot be parsed or marshaled.
+func RoundTripCheck(xmlData []byte) (*RoundTripReport, error) {
+	reproduced, err := reproduceDocument(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	originalEntries, err := flattenXML(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	reproducedEntries, err := flattenXML(reproduced)
+	if err != nil {
+		return nil, err
+	}
+	return &RoundTripReport{
+		Reproduced: reproduced,
+		Missing:    diffEntries(originalEntries, reproducedEntries),
+		Extra:      diffEntries(reproducedEntries, originalEntries),
+	}, nil
+}
+
+// reproduceDocument unmarshals the document (Invoice or CreditNote) and
+// marshals it back to XML.
+func reproduceDocument(xmlData []byte) ([]byte, error) {
+	root, err := xmlRootName(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	switch root.Local {
+	case "Invoice":
+		var invoice Invoice
+		if err := UnmarshalInvoice(xmlData, &invoice); err != nil {
+			return nil, err
+		}
+		return invoice.XML()
+	case "CreditNote":
+		var creditNote CreditNote
+		if err := UnmarshalCreditNote(xmlData, &creditNote); err != nil {
+			return nil, err
+		}
+		return creditNote.XML()
+	}
+	return nil, fmt.Errorf("unknown root element %s", root.Local)
+}
+
+// flattenXML returns the sorted list of the leaves of the XML document as
+// "path=value" entries and of the attributes as "path/@name=value" entries.
+// The namespace declarations and the whitespace are ignored, and the
+// numbers are compared by value.
+func flattenXML(xmlData []byte) ([]string, error) {
+	type element struct {
+		path     string
+		text     strings.Builder
+		children int
+	}
+	var (
+		entries []string
+		stack   []*element
+	)
+	d := xml.NewDecoder(bytes.NewReader(xmlData))
+	for {
+		t, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := t.(type) {
+		case xml.StartElement:
+			p := tok.Name.Local
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children++
+				p = parent.path + "/" + p
+			}
+			for _, attr := range tok.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" ||
+					attr.Name.Space == "http://www.w3.org/2001/XMLSchema-instance" {
+					continue
+				}
+				entries = append(entries, p+"/@"+attr.Name.Local+"="+normalizeFlatValue(attr.Value))
+			}
+			stack = append(stack, &element{path: p})
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if text := strings.TrimSpace(e.text.String()); text != "" || e.children == 0 {
+				entries = append(entries, e.path+"="+normalizeFlatValue(text))
+			}
+		}
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// normalizeFlatValue normalizes the decimal numbers (eg. 19.00 and 19),
+// keeping the integers as they are, since they can be identifiers.
+func normalizeFlatValue(s string) string {
+	if !strings.Contains(s, ".") || strings.ContainsAny(s, "eE") {
+		return s
+	}
+	if d, err := decimal.NewFromString(s); err == nil {
+		return d.String()
+	}
+	return s
+}
+
+// diffEntries returns the entries of a not in b (as multisets).
+func diffEntries(a, b []string) (diff []string) {
+	counts := make(map[string]int, len(b))
+	for _, e := range b {
+		counts[e]++
+	}
+	for _, e := range a {
+		if counts[e] > 0 {
+			counts[e]--
+			continue
+		}
+		diff = append(diff, e)
+	}
+	return diff
+}
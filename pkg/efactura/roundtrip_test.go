@@ -0,0 +1,96 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTripCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	original, err := corpusFS.ReadFile("testdata/corpus/invoice_allowances.xml")
+	if !assert.NoError(err) {
+		return
+	}
+	report, err := RoundTripCheck(original)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.True(report.OK(), report.String())
+	assert.Equal("round trip: lossless", report.String())
+	var invoice Invoice
+	assert.NoError(UnmarshalInvoice(report.Reproduced, &invoice))
+
+	// cbc:TaxPointDate (BT-7) is not part of the model.
+	lossy := strings.Replace(string(original), "<cbc:InvoiceTypeCode>",
+		"<cbc:TaxPointDate>2024-03-31</cbc:TaxPointDate>\n  <cbc:InvoiceTypeCode>", 1)
+	report, err = RoundTripCheck([]byte(lossy))
+	if assert.NoError(err) {
+		assert.False(report.OK())
+		assert.Equal([]string{"Invoice/TaxPointDate=2024-03-31"}, report.Missing)
+		assert.Empty(report.Extra)
+		assert.Contains(report.String(), "1 missing, 0 extra\n- Invoice/TaxPointDate=2024-03-31")
+	}
+
+	_, err = RoundTripCheck([]byte(`<Order xmlns="urn:oasis:names:specification:ubl:schema:xsd:Order-2"/>`))
+	assert.ErrorContains(err, "unknown root element Order")
+	_, err = RoundTripCheck([]byte(`<Invoice`))
+	assert.Error(err)
+}
+
+// FuzzInvoiceRoundTrip checks that any document accepted by UnmarshalInvoice
+// can be marshaled, and that marshaling is stable: the marshaled document is
+// parsed and marshaled again to the same bytes.
+func FuzzInvoiceRoundTrip(f *testing.F) {
+	paths, err := fs.Glob(corpusFS, "testdata/corpus/*.xml")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := corpusFS.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var invoice Invoice
+		if err := UnmarshalInvoice(data, &invoice); err != nil {
+			return
+		}
+		xml1, err := invoice.XML()
+		if err != nil {
+			t.Fatalf("error marshaling the parsed invoice: %v", err)
+		}
+		var parsed Invoice
+		if err := UnmarshalInvoice(xml1, &parsed); err != nil {
+			t.Fatalf("error parsing the marshaled invoice: %v\n%s", err, xml1)
+		}
+		xml2, err := parsed.XML()
+		if err != nil {
+			t.Fatalf("error marshaling the re-parsed invoice: %v", err)
+		}
+		if !bytes.Equal(xml1, xml2) {
+			t.Fatalf("marshaling is not stable:\n%s\n---\n%s", xml1, xml2)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// SanitizedField is a text field changed by Invoice.Sanitize or
+// CreditNote.Sanitize.
+type SanitizedField struct {
+	// Path is the path of the field (eg. "InvoiceLines[2].Item.Name").
+	Path string
+	// Original is the value of the field before sanitizing.
+	Original string
+	// Sanitized is the value of the field after sanitizing.
+	Sanitized string
+}
+
+// String implements the fmt.Stringer interface.
+func (f SanitizedField) String() string {
+	return fmt.Sprintf("%s: %q -> %q", f.Path, f.Original, f.Sanitized)
+}
+
+type sanitizeOptions struct {
+	replacement string
+}
+
+// SanitizeOption is an option for Invoice.Sanitize, CreditNote.Sanitize and
+// SanitizeXMLText.
+type SanitizeOption func(*sanitizeOptions)
+
+// SanitizeOptionReplacement sets the string replacing each character not
+// allowed in XML 1.0 (by default the characters are removed), eg. " " so
+// that the words separated by a form feed are not joined.
+func SanitizeOptionReplacement(replacement string) SanitizeOption {
+	return func(o *sanitizeOptions) {
+		o.replacement = replacement
+	}
+}
+
+// IsXMLChar returns true if r is allowed in an XML 1.0 document:
+// #x9 | #xA | #xD | [#x20-#xD7FF] | [#xE000-#xFFFD] | [#x10000-#x10FFFF].
+func IsXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
+
+// SanitizeXMLText returns s without the characters not allowed in XML 1.0
+// (eg. the control characters pasted from ERPs) and without the invalid
+// UTF-8 sequences. encoding/xml silently replaces these characters with
+// U+FFFD, so the documents are rejected by ANAF with errors that are hard to
+// trace back to the input.
+func SanitizeXMLText(s string, opts ...SanitizeOption) string {
+	var o sanitizeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return sanitizeXMLText(s, o)
+}
+
+func sanitizeXMLText(s string, o sanitizeOptions) string {
+	clean := true
+	for _, r := range s {
+		if r == utf8.RuneError || !IsXMLChar(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		// An U+FFFD encoded in the input is kept, only the invalid
+		// sequences are removed.
+		if (r == utf8.RuneError && size == 1) || !IsXMLChar(r) {
+			sb.WriteString(o.replacement)
+		} else {
+			sb.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+// Sanitize removes the characters not allowed in XML 1.0 from all the text
+// fields of the invoice (see SanitizeXMLText), and returns the list of the
+// modified fields. It should be called before marshaling invoices built from
+// user input.
+func (iv *Invoice) Sanitize(opts ...SanitizeOption) []SanitizedField {
+	return sanitizeDocument(iv, opts)
+}
+
+// Sanitize removes the characters not allowed in XML 1.0 from all the text
+// fields of the credit note (see Invoice.Sanitize).
+func (cn *CreditNote) Sanitize(opts ...SanitizeOption) []SanitizedField {
+	return sanitizeDocument(cn, opts)
+}
+
+func sanitizeDocument(doc any, opts []SanitizeOption) []SanitizedField {
+	s := sanitizer{}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	s.sanitize(reflect.ValueOf(doc).Elem(), "")
+	return s.fields
+}
+
+// sanitizer walks the exported fields of a document and sanitizes the
+// strings.
+type sanitizer struct {
+	opts   sanitizeOptions
+	fields []SanitizedField
+}
+
+func (s *sanitizer) sanitize(v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.String:
+		original := v.String()
+		if sanitized := sanitizeXMLText(original, s.opts); sanitized != original && v.CanSet() {
+			v.SetString(sanitized)
+			s.fields = append(s.fields, SanitizedField{
+				Path:      path,
+				Original:  original,
+				Sanitized: sanitized,
+			})
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			s.sanitize(v.Elem(), path)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Binary data (eg. the embedded attachments).
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			s.sanitize(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := f.Name
+			if path != "" {
+				fieldPath = path + "." + f.Name
+			}
+			s.sanitize(v.Field(i), fieldPath)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestSanitizeXMLText(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Servicii IT\tlunare\r\n", efactura.SanitizeXMLText("Servicii IT\tlunare\r\n"))
+	assert.Equal("Serviciiconsultanta", efactura.SanitizeXMLText("Servicii\x00\x0bconsultanta"))
+	assert.Equal("Servicii  consultanta", efactura.SanitizeXMLText("Servicii\x0c\x1fconsultanta",
+		efactura.SanitizeOptionReplacement(" ")))
+	// Invalid UTF-8 and non-characters are removed, valid U+FFFD is kept.
+	assert.Equal("ab�c", efactura.SanitizeXMLText("a\xffb�c￾"))
+	assert.Equal("Ştefan 🙂", efactura.SanitizeXMLText("Ştefan 🙂"))
+}
+
+func TestInvoiceSanitize(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := efactura.Invoice{
+		ID:   "INV-1",
+		Note: []efactura.InvoiceNote{{Note: "Plata\x07 in 30 de zile"}},
+		InvoiceLines: []efactura.InvoiceLine{{
+			Item: efactura.InvoiceLineItem{Name: "Produs 1"},
+		}, {
+			Item: efactura.InvoiceLineItem{
+				Name:        "Produs\x1b[0m 2",
+				Description: "ok",
+			},
+		}},
+	}
+	invoice.Supplier.Party.PostalAddress.CityName = "Cluj\x00"
+
+	fields := invoice.Sanitize()
+	assert.Equal([]efactura.SanitizedField{{
+		Path:      "Note[0].Note",
+		Original:  "Plata\x07 in 30 de zile",
+		Sanitized: "Plata in 30 de zile",
+	}, {
+		Path:      "Supplier.Party.PostalAddress.PostalAddress.CityName",
+		Original:  "Cluj\x00",
+		Sanitized: "Cluj",
+	}, {
+		Path:      "InvoiceLines[1].Item.Name",
+		Original:  "Produs\x1b[0m 2",
+		Sanitized: "Produs[0m 2",
+	}}, fields)
+	assert.Equal("Plata in 30 de zile", invoice.Note[0].Note)
+	assert.Equal("Produs[0m 2", invoice.InvoiceLines[1].Item.Name)
+	assert.Empty(invoice.Sanitize())
+
+	creditNote := efactura.CreditNote{ID: "CN\x011"}
+	if fields := creditNote.Sanitize(); assert.Len(fields, 1) {
+		assert.Equal("ID", fields[0].Path)
+		assert.Equal("CN1", creditNote.ID)
+	}
+}
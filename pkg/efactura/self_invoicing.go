@@ -0,0 +1,288 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// SelfInvoicingKind is the kind of a self-invoicing (autofactură) scenario.
+type SelfInvoicingKind string
+
+const (
+	// SelfInvoicingProtocol is the self-invoice for goods given free of
+	// charge (protocol expenses, gifts) above the limits allowed by art. 270
+	// alin. (4) lit. b) from Codul fiscal.
+	SelfInvoicingProtocol SelfInvoicingKind = "protocol"
+	// SelfInvoicingOwnConsumption is the self-invoice for goods taken over
+	// for the own or personal needs of the company, its employees or
+	// associates (art. 270 alin. (4) lit. a) from Codul fiscal).
+	SelfInvoicingOwnConsumption SelfInvoicingKind = "own-consumption"
+	// SelfInvoicingOwnUseServices is the self-invoice for services provided
+	// free of charge for the personal needs of the company employees or
+	// associates (art. 271 alin. (4) from Codul fiscal).
+	SelfInvoicingOwnUseServices SelfInvoicingKind = "own-use-services"
+)
+
+// SelfInvoicingNote is the mention required by art. 319 alin. (8) from Codul
+// fiscal on every self-invoice.
+const SelfInvoicingNote = "Autofactură"
+
+// SelfInvoicingPreset is a preset for a self-invoicing scenario: the invoice
+// type code, the notes and the VAT category used for the invoice lines. The
+// supplier and the customer of a self-invoice are the same company. Presets
+// can be exported to and imported from JSON (see WriteSelfInvoicingPresets
+// and ReadSelfInvoicingPresets) to be customized.
+type SelfInvoicingPreset struct {
+	Kind SelfInvoicingKind `json:"kind"`
+	// Description is a human readable description of the scenario.
+	Description string `json:"description,omitempty"`
+	// InvoiceTypeCode is the type code of the invoice (BT-3).
+	InvoiceTypeCode InvoiceTypeCodeType `json:"invoiceTypeCode"`
+	// Notes are the invoice notes (BT-22). The first note should be
+	// SelfInvoicingNote, since it's used to detect self-invoices.
+	Notes []string `json:"notes"`
+	// TaxCategory is the VAT category of the invoice lines (BT-151).
+	TaxCategory TaxCategoryCodeType `json:"taxCategory"`
+	// TaxExemptionReason is the VAT exemption reason text (BT-120), used
+	// only if TaxCategory requires an exemption reason.
+	TaxExemptionReason string `json:"taxExemptionReason,omitempty"`
+	// TaxExemptionReasonCode is the VAT exemption reason code (BT-121),
+	// used only if TaxCategory requires an exemption reason.
+	TaxExemptionReasonCode TaxExemptionReasonCodeType `json:"taxExemptionReasonCode,omitempty"`
+}
+
+var defaultSelfInvoicingPresets = []SelfInvoicingPreset{
+	{
+		Kind:            SelfInvoicingProtocol,
+		Description:     "Bunuri acordate gratuit (protocol, cadouri) peste limitele legale",
+		InvoiceTypeCode: InvoiceTypeCommercialInvoice,
+		Notes: []string{
+			SelfInvoicingNote,
+			"Livrare de bunuri cu titlu gratuit, conform art. 270 alin. (4) lit. b) din Codul fiscal",
+		},
+		TaxCategory: TaxCategoryVATStandardRate,
+	},
+	{
+		Kind:            SelfInvoicingOwnConsumption,
+		Description:     "Bunuri preluate pentru nevoile proprii ale persoanei impozabile",
+		InvoiceTypeCode: InvoiceTypeCommercialInvoice,
+		Notes: []string{
+			SelfInvoicingNote,
+			"Preluare de bunuri pentru nevoile proprii, conform art. 270 alin. (4) lit. a) din Codul fiscal",
+		},
+		TaxCategory: TaxCategoryVATStandardRate,
+	},
+	{
+		Kind:            SelfInvoicingOwnUseServices,
+		Description:     "Servicii prestate gratuit pentru uzul propriu al angajaților sau asociaților",
+		InvoiceTypeCode: InvoiceTypeCommercialInvoice,
+		Notes: []string{
+			SelfInvoicingNote,
+			"Prestare de servicii cu titlu gratuit, conform art. 271 alin. (4) din Codul fiscal",
+		},
+		TaxCategory: TaxCategoryVATStandardRate,
+	},
+}
+
+// SelfInvoicingPresets returns the default presets for the self-invoicing
+// scenarios.
+func SelfInvoicingPresets() []SelfInvoicingPreset {
+	presets := make([]SelfInvoicingPreset, 0, len(defaultSelfInvoicingPresets))
+	for _, p := range defaultSelfInvoicingPresets {
+		p.Notes = append([]string(nil), p.Notes...)
+		presets = append(presets, p)
+	}
+	return presets
+}
+
+// GetSelfInvoicingPreset returns the default preset for the given kind.
+func GetSelfInvoicingPreset(kind SelfInvoicingKind) (preset SelfInvoicingPreset, ok bool) {
+	for _, p := range SelfInvoicingPresets() {
+		if p.Kind == kind {
+			return p, true
+		}
+	}
+	return
+}
+
+// Validate checks that the preset is usable for building self-invoices.
+func (p SelfInvoicingPreset) Validate() error {
+	if p.Kind == "" {
+		return fmt.Errorf("self-invoicing preset: missing kind")
+	}
+	if p.InvoiceTypeCode == "" {
+		return fmt.Errorf("self-invoicing preset %s: missing invoice type code", p.Kind)
+	}
+	if p.TaxCategory == "" {
+		return fmt.Errorf("self-invoicing preset %s: missing tax category", p.Kind)
+	}
+	if p.TaxCategory.ExemptionReasonRequired() && p.TaxExemptionReason == "" && p.TaxExemptionReasonCode == "" {
+		return fmt.Errorf("self-invoicing preset %s: tax category %s requires an exemption reason",
+			p.Kind, p.TaxCategory)
+	}
+	return nil
+}
+
+// LineTaxCategory returns the VAT category for the invoice lines of a
+// self-invoice. percent is the VAT rate applied to the taxable base (the
+// purchase price or the cost of the goods or services), and is ignored if
+// the tax category of the preset is exempted.
+func (p SelfInvoicingPreset) LineTaxCategory(percent types.Decimal) InvoiceLineTaxCategory {
+	taxCategory := InvoiceLineTaxCategory{
+		ID:        p.TaxCategory,
+		TaxScheme: TaxSchemeVAT,
+	}
+	if p.TaxCategory.TaxRateExempted() {
+		taxCategory.Percent = types.Zero
+	} else {
+		taxCategory.Percent = percent
+	}
+	return taxCategory
+}
+
+// Apply sets on the builder the invoice type code, the notes, the tax
+// exemption reason (if any) and the parties of the self-invoice: company is
+// both the supplier and the customer.
+func (p SelfInvoicingPreset) Apply(b *InvoiceBuilder, company InvoiceSupplierParty) *InvoiceBuilder {
+	b.WithInvoiceTypeCode(p.InvoiceTypeCode).
+		WithSupplier(company).
+		WithCustomer(SelfInvoicingCustomer(company))
+	for _, note := range p.Notes {
+		b.AppendNotes(InvoiceNote{Note: note})
+	}
+	if p.TaxCategory.ExemptionReasonRequired() {
+		b.AddTaxExemptionReason(p.TaxCategory, p.TaxExemptionReason, p.TaxExemptionReasonCode)
+	}
+	return b
+}
+
+// NewSelfInvoiceBuilder creates an InvoiceBuilder for a self-invoice with the
+// given ID, using the default preset for the given kind. The lines should use
+// the tax category returned by the preset's LineTaxCategory.
+func NewSelfInvoiceBuilder(id string, kind SelfInvoicingKind, company InvoiceSupplierParty) (*InvoiceBuilder, error) {
+	preset, ok := GetSelfInvoicingPreset(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown self-invoicing kind %q", kind)
+	}
+	return preset.Apply(NewInvoiceBuilder(id), company), nil
+}
+
+// SelfInvoicingCustomer returns the customer party of a self-invoice issued
+// by the given company (the supplier).
+func SelfInvoicingCustomer(company InvoiceSupplierParty) InvoiceCustomerParty {
+	customer := InvoiceCustomerParty{
+		Identifications: company.Identifications,
+		CommercialName:  company.CommercialName,
+		PostalAddress:   MakeInvoiceCustomerPostalAddress(company.PostalAddress.PostalAddress),
+		TaxScheme:       company.TaxScheme,
+		LegalEntity: InvoiceCustomerLegalEntity{
+			Name:      company.LegalEntity.Name,
+			CompanyID: company.LegalEntity.CompanyID,
+		},
+	}
+	if company.Contact != nil {
+		customer.Contact = &InvoiceCustomerContact{
+			Name:  company.Contact.Name,
+			Phone: company.Contact.Phone,
+			Email: company.Contact.Email,
+		}
+	}
+	return customer
+}
+
+// IsSelfInvoice returns true if the invoice is a self-invoice: the supplier
+// and the customer have the same VAT identifier (or legal registration ID)
+// and the invoice has the SelfInvoicingNote note.
+func (iv Invoice) IsSelfInvoice() bool {
+	supplierID := partyCompanyID(iv.Supplier.Party.TaxScheme, iv.Supplier.Party.LegalEntity.CompanyID)
+	customerID := partyCompanyID(iv.Customer.Party.TaxScheme, iv.Customer.Party.LegalEntity.CompanyID)
+	if supplierID == "" || !strings.EqualFold(supplierID, customerID) {
+		return false
+	}
+	for _, note := range iv.Note {
+		if strings.EqualFold(strings.TrimSpace(note.Note), SelfInvoicingNote) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchSelfInvoicingPreset returns the preset from presets matching the
+// self-invoice iv: all the notes of the preset must be notes of the invoice
+// and the invoice type code must match. If iv is not a self-invoice, or no
+// preset matches, ok is false.
+func MatchSelfInvoicingPreset(iv Invoice, presets []SelfInvoicingPreset) (preset SelfInvoicingPreset, ok bool) {
+	if !iv.IsSelfInvoice() {
+		return
+	}
+	notes := make(map[string]bool, len(iv.Note))
+	for _, note := range iv.Note {
+		notes[strings.TrimSpace(note.Note)] = true
+	}
+	for _, p := range presets {
+		if p.InvoiceTypeCode != iv.InvoiceTypeCode {
+			continue
+		}
+		matches := true
+		for _, note := range p.Notes {
+			if !notes[note] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return p, true
+		}
+	}
+	return
+}
+
+// ReadSelfInvoicingPresets reads a JSON array of presets from r (eg. exported
+// with WriteSelfInvoicingPresets and customized). Each preset is validated.
+func ReadSelfInvoicingPresets(r io.Reader) ([]SelfInvoicingPreset, error) {
+	var presets []SelfInvoicingPreset
+	if err := json.NewDecoder(r).Decode(&presets); err != nil {
+		return nil, fmt.Errorf("self-invoicing presets: %w", err)
+	}
+	for _, p := range presets {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return presets, nil
+}
+
+// WriteSelfInvoicingPresets writes the presets to w as an indented JSON array.
+func WriteSelfInvoicingPresets(w io.Writer, presets []SelfInvoicingPreset) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(presets)
+}
+
+func partyCompanyID(taxScheme *InvoicePartyTaxScheme, companyID *ValueWithAttrs) string {
+	if taxScheme != nil && taxScheme.CompanyID != "" {
+		return taxScheme.CompanyID
+	}
+	if companyID != nil {
+		return companyID.Value
+	}
+	return ""
+}
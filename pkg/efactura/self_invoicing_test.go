@@ -0,0 +1,115 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestSelfInvoicingPresets(t *testing.T) {
+	assert := assert.New(t)
+
+	company := getInvoiceSupplierParty()
+	for _, preset := range SelfInvoicingPresets() {
+		if !assert.NoError(preset.Validate(), "preset %s", preset.Kind) {
+			continue
+		}
+
+		b, err := NewSelfInvoiceBuilder("AF-1", preset.Kind, company)
+		if !assert.NoError(err) {
+			continue
+		}
+		line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(2)).
+			WithGrossPriceAmount(types.D(50)).
+			WithItemName("Cadou").
+			WithItemTaxCategory(preset.LineTaxCategory(types.D(19))).
+			Build()
+		if !assert.NoError(err) {
+			continue
+		}
+		invoice, err := b.WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyRON).
+			AppendInvoiceLines(line).
+			Build()
+		if !assert.NoError(err, "preset %s", preset.Kind) {
+			continue
+		}
+
+		assert.Equal(preset.InvoiceTypeCode, invoice.InvoiceTypeCode)
+		assert.Equal(company.LegalEntity.Name, invoice.Customer.Party.LegalEntity.Name)
+		assert.Equal(company.TaxScheme.CompanyID, invoice.Customer.Party.TaxScheme.CompanyID)
+		assert.Equal(company.PostalAddress.PostalAddress, invoice.Customer.Party.PostalAddress.PostalAddress)
+		if assert.Len(invoice.Note, len(preset.Notes)) {
+			assert.Equal(SelfInvoicingNote, invoice.Note[0].Note)
+		}
+		// VAT is charged on the taxable base.
+		assert.True(invoice.LegalMonetaryTotal.TaxExclusiveAmount.Amount.Equal(types.D(100)))
+		assert.True(invoice.LegalMonetaryTotal.TaxInclusiveAmount.Amount.Equal(types.D(119)))
+
+		assert.True(invoice.IsSelfInvoice())
+		matched, ok := MatchSelfInvoicingPreset(invoice, SelfInvoicingPresets())
+		if assert.True(ok) {
+			assert.Equal(preset.Kind, matched.Kind)
+		}
+	}
+
+	_, err := NewSelfInvoiceBuilder("AF-1", "unknown", company)
+	assert.Error(err)
+}
+
+func TestIsSelfInvoice(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := Invoice{
+		Supplier: MakeInvoiceSupplier(getInvoiceSupplierParty()),
+		Customer: MakeInvoiceCustomer(getInvoiceCustomerParty()),
+		Note:     []InvoiceNote{{Note: SelfInvoicingNote}},
+	}
+	assert.False(invoice.IsSelfInvoice(), "different supplier and customer")
+
+	invoice.Customer.Party = SelfInvoicingCustomer(invoice.Supplier.Party)
+	assert.True(invoice.IsSelfInvoice())
+
+	invoice.Note = nil
+	assert.False(invoice.IsSelfInvoice(), "missing self-invoice note")
+	_, ok := MatchSelfInvoicingPreset(invoice, SelfInvoicingPresets())
+	assert.False(ok)
+}
+
+func TestSelfInvoicingPresetsJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	presets := SelfInvoicingPresets()
+	if assert.NoError(WriteSelfInvoicingPresets(&buf, presets)) {
+		readPresets, err := ReadSelfInvoicingPresets(&buf)
+		if assert.NoError(err) {
+			assert.Equal(presets, readPresets)
+		}
+	}
+
+	_, err := ReadSelfInvoicingPresets(strings.NewReader(`[{"kind":"x","invoiceTypeCode":"380","taxCategory":"E"}]`))
+	assert.Error(err, "exempt category without an exemption reason")
+	_, err = ReadSelfInvoicingPresets(strings.NewReader(`{`))
+	assert.Error(err)
+}
@@ -0,0 +1,236 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/xmlsig"
+)
+
+// SignatureStatus is the status of an archive checked by
+// ValidateSignaturesDir.
+type SignatureStatus string
+
+const (
+	// SignatureStatusValid means the signature is valid and the signing
+	// certificate is trusted.
+	SignatureStatusValid SignatureStatus = "valid"
+	// SignatureStatusInvalid means the invoice was modified or the
+	// signature value is invalid.
+	SignatureStatusInvalid SignatureStatus = "invalid"
+	// SignatureStatusUnknownSigner means the signature is intact, but the
+	// signing certificate is missing or cannot be verified against the
+	// trusted roots (eg. a new MFP certificate not yet in the trust store).
+	SignatureStatusUnknownSigner SignatureStatus = "unknown_signer"
+	// SignatureStatusError means the archive could not be checked (eg. it
+	// is truncated or incomplete).
+	SignatureStatusError SignatureStatus = "error"
+)
+
+// SignatureReportEntry is the result of checking a single archive.
+type SignatureReportEntry struct {
+	// Path is the path of the archive.
+	Path string
+	// Status is the status of the signature.
+	Status SignatureStatus
+	// Signer is the subject of the signing certificate, if known.
+	Signer string
+	// Message is the reason for the status, or the message of the remote
+	// validation.
+	Message string
+	// Remote is true if the status is the result of the remote validation.
+	Remote bool
+	// Err is the error returned by the verification, if any.
+	Err error
+}
+
+// SignatureReport is the summary report of ValidateSignaturesDir.
+type SignatureReport struct {
+	// Entries are the results for each archive, sorted by path.
+	Entries []SignatureReportEntry
+	// Valid, Invalid, UnknownSigner and Errors are the number of archives
+	// with each status.
+	Valid         int
+	Invalid       int
+	UnknownSigner int
+	Errors        int
+}
+
+// Total returns the number of archives checked.
+func (r *SignatureReport) Total() int {
+	return len(r.Entries)
+}
+
+// OK returns true if all the archives have a valid signature.
+func (r *SignatureReport) OK() bool {
+	return r.Valid == r.Total()
+}
+
+// String returns a one line summary of the report.
+func (r *SignatureReport) String() string {
+	return fmt.Sprintf("%d archives: %d valid, %d invalid, %d unknown signer, %d errors",
+		r.Total(), r.Valid, r.Invalid, r.UnknownSigner, r.Errors)
+}
+
+func (r *SignatureReport) add(entry SignatureReportEntry) {
+	switch entry.Status {
+	case SignatureStatusValid:
+		r.Valid++
+	case SignatureStatusInvalid:
+		r.Invalid++
+	case SignatureStatusUnknownSigner:
+		r.UnknownSigner++
+	default:
+		r.Errors++
+	}
+	r.Entries = append(r.Entries, entry)
+}
+
+// SignatureCheckConfig is the config used by ValidateSignaturesDir.
+type SignatureCheckConfig struct {
+	// Verifier is the verifier used to check the signatures locally. If
+	// nil, a verifier using the default trust store is created.
+	Verifier *xmlsig.Verifier
+	// RemoteValidator, if set, is used to validate the archives that cannot
+	// be validated locally (unknown signer or unsupported archive). The
+	// archives with an invalid digest or signature value are never sent.
+	RemoteValidator SignatureValidator
+	// OnResult, if set, is called for each checked archive.
+	OnResult func(entry SignatureReportEntry)
+}
+
+// SignatureCheckConfigOption allows gradually modifying a
+// SignatureCheckConfig.
+type SignatureCheckConfigOption func(*SignatureCheckConfig)
+
+// SignatureCheckVerifier sets the verifier used to check the signatures
+// locally.
+func SignatureCheckVerifier(verifier *xmlsig.Verifier) SignatureCheckConfigOption {
+	return func(c *SignatureCheckConfig) {
+		c.Verifier = verifier
+	}
+}
+
+// SignatureCheckRemoteFallback sets the validator (usually a *Client) used
+// for the archives that cannot be validated locally.
+func SignatureCheckRemoteFallback(validator SignatureValidator) SignatureCheckConfigOption {
+	return func(c *SignatureCheckConfig) {
+		c.RemoteValidator = validator
+	}
+}
+
+// SignatureCheckOnResult sets the function called for each checked archive.
+func SignatureCheckOnResult(onResult func(entry SignatureReportEntry)) SignatureCheckConfigOption {
+	return func(c *SignatureCheckConfig) {
+		c.OnResult = onResult
+	}
+}
+
+// ValidateSignaturesDir walks dir recursively and verifies the signature of
+// each invoice zip archive (as downloaded from ANAF) locally, optionally
+// falling back to the remote validation (see SignatureCheckRemoteFallback).
+// It's meant for periodic assurance of an invoice archive. An error is
+// returned only if dir cannot be walked or ctx is done, the errors for
+// individual archives are reported in the report entries.
+func ValidateSignaturesDir(ctx context.Context, dir string, opts ...SignatureCheckConfigOption) (*SignatureReport, error) {
+	var cfg SignatureCheckConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Verifier == nil {
+		verifier, err := xmlsig.NewVerifier()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Verifier = verifier
+	}
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".zip") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	report := new(SignatureReport)
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry := checkSignatureFile(ctx, path, cfg)
+		if cfg.OnResult != nil {
+			cfg.OnResult(entry)
+		}
+		report.add(entry)
+	}
+	return report, nil
+}
+
+func checkSignatureFile(ctx context.Context, path string, cfg SignatureCheckConfig) (entry SignatureReportEntry) {
+	entry = SignatureReportEntry{Path: path}
+
+	zipData, err := os.ReadFile(path)
+	if err != nil {
+		entry.Status, entry.Message, entry.Err = SignatureStatusError, err.Error(), err
+		return
+	}
+	result, err := cfg.Verifier.VerifyZipData(zipData)
+	switch {
+	case err == nil:
+		entry.Status = SignatureStatusValid
+		entry.Signer = result.Certificate.Subject.String()
+		return
+	case errors.Is(err, xmlsig.ErrDigestMismatch), errors.Is(err, xmlsig.ErrInvalidSignature):
+		entry.Status = SignatureStatusInvalid
+	case errors.Is(err, xmlsig.ErrNoCertificate), errors.Is(err, xmlsig.ErrUntrustedCertificate):
+		entry.Status = SignatureStatusUnknownSigner
+	default:
+		entry.Status = SignatureStatusError
+	}
+	entry.Message, entry.Err = err.Error(), err
+	if cfg.RemoteValidator == nil || entry.Status == SignatureStatusInvalid {
+		return
+	}
+
+	res, err := cfg.RemoteValidator.ValidateSignatureZipData(ctx, zipData)
+	if err != nil {
+		// Keep the local status, the remote validation failed.
+		entry.Message = fmt.Sprintf("%s (remote validation failed: %v)", entry.Message, err)
+		return
+	}
+	entry.Remote, entry.Err, entry.Message = true, nil, res.Message
+	if res.IsValid() {
+		entry.Status = SignatureStatusValid
+	} else {
+		entry.Status = SignatureStatusInvalid
+	}
+	return
+}
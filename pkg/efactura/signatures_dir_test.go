@@ -0,0 +1,249 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/trust"
+	"github.com/printesoi/e-factura-go/pkg/xmlsig"
+)
+
+const testSignedInvoice = `<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"><ID>FCT-1</ID></Invoice>`
+
+// newTestCertificate creates a certificate for a new key, signed by parent
+// (self-signed if parent is nil).
+func newTestCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// newTestSignedZip returns an archive with the invoice data and a detached
+// signature of testSignedInvoice.
+func newTestSignedZip(t *testing.T, data string, cert *x509.Certificate, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+
+	digest := sha256.Sum256([]byte(testSignedInvoice))
+	signedInfo := fmt.Sprintf(`<ds:SignedInfo xmlns:ds="%s">`+
+		`<ds:CanonicalizationMethod Algorithm="%s"></ds:CanonicalizationMethod>`+
+		`<ds:SignatureMethod Algorithm="%s"></ds:SignatureMethod>`+
+		`<ds:Reference URI="">`+
+		`<ds:Transforms><ds:Transform Algorithm="%s"></ds:Transform></ds:Transforms>`+
+		`<ds:DigestMethod Algorithm="%s"></ds:DigestMethod>`+
+		`<ds:DigestValue>%s</ds:DigestValue>`+
+		`</ds:Reference></ds:SignedInfo>`,
+		xmlsig.NamespaceDSig, xmlsig.AlgorithmC14N, xmlsig.AlgorithmECDSASHA256,
+		xmlsig.AlgorithmEnvelopedSignature, xmlsig.AlgorithmSHA256,
+		base64.StdEncoding.EncodeToString(digest[:]))
+	hashed := sha256.Sum256([]byte(signedInfo))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureValue := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	signature := fmt.Sprintf(`<ds:Signature xmlns:ds="%s">%s<ds:SignatureValue>%s</ds:SignatureValue>`+
+		`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo></ds:Signature>`,
+		xmlsig.NamespaceDSig, signedInfo, base64.StdEncoding.EncodeToString(signatureValue),
+		base64.StdEncoding.EncodeToString(cert.Raw))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"3001234567.xml":           data,
+		"semnatura_3001234567.xml": signature,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidateSignaturesDir(t *testing.T) {
+	assert := assert.New(t)
+
+	rootCert, rootKey := newTestCertificate(t, "Test Root CA", nil, nil)
+	cert, key := newTestCertificate(t, "Ministerul Finantelor Publice", rootCert, rootKey)
+	unknownCert, unknownKey := newTestCertificate(t, "Unknown Signer", nil, nil)
+
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("2024/valid.zip", newTestSignedZip(t, testSignedInvoice, cert, key))
+	writeFile("2024/tampered.zip", newTestSignedZip(t, strings.Replace(testSignedInvoice, "FCT-1", "FCT-2", 1), cert, key))
+	writeFile("unknown.zip", newTestSignedZip(t, testSignedInvoice, unknownCert, unknownKey))
+	writeFile("truncated.zip", []byte("PK\x03\x04"))
+	writeFile("notes.txt", []byte("not an archive"))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(rootCert)
+	verifier, err := xmlsig.NewVerifier(xmlsig.VerifierRoots(roots))
+	if !assert.NoError(err) {
+		return
+	}
+
+	var results int
+	report, err := efactura.ValidateSignaturesDir(context.Background(), dir,
+		efactura.SignatureCheckVerifier(verifier),
+		efactura.SignatureCheckOnResult(func(entry efactura.SignatureReportEntry) {
+			results++
+		}))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(4, results)
+	assert.Equal(4, report.Total())
+	assert.False(report.OK())
+	assert.Equal("4 archives: 1 valid, 1 invalid, 1 unknown signer, 1 errors", report.String())
+
+	statuses := make(map[string]efactura.SignatureStatus)
+	for _, entry := range report.Entries {
+		rel, _ := filepath.Rel(dir, entry.Path)
+		statuses[filepath.ToSlash(rel)] = entry.Status
+		assert.False(entry.Remote)
+		if entry.Status == efactura.SignatureStatusValid {
+			assert.Contains(entry.Signer, "Ministerul Finantelor Publice")
+		}
+	}
+	assert.Equal(map[string]efactura.SignatureStatus{
+		"2024/tampered.zip": efactura.SignatureStatusInvalid,
+		"2024/valid.zip":    efactura.SignatureStatusValid,
+		"truncated.zip":     efactura.SignatureStatusError,
+		"unknown.zip":       efactura.SignatureStatusUnknownSigner,
+	}, statuses)
+
+	// The archives that cannot be validated locally are validated remotely,
+	// the tampered archive is not sent.
+	validator := &testSignatureValidator{}
+	report, err = efactura.ValidateSignaturesDir(context.Background(), dir,
+		efactura.SignatureCheckVerifier(verifier),
+		efactura.SignatureCheckRemoteFallback(validator))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(2, validator.calls)
+	assert.Equal(3, report.Valid)
+	assert.Equal(1, report.Invalid)
+	for _, entry := range report.Entries {
+		if filepath.Base(entry.Path) == "unknown.zip" {
+			assert.True(entry.Remote)
+			assert.NoError(entry.Err)
+			assert.Equal(testSignatureValidMessage, entry.Message)
+		}
+	}
+
+	// If the remote validation fails, the local status is kept.
+	validator = &testSignatureValidator{err: errors.New("network error")}
+	report, err = efactura.ValidateSignaturesDir(context.Background(), dir,
+		efactura.SignatureCheckVerifier(verifier),
+		efactura.SignatureCheckRemoteFallback(validator))
+	if assert.NoError(err) {
+		assert.Equal(1, report.UnknownSigner)
+		assert.Equal(1, report.Errors)
+	}
+
+	// Without a verifier, the certificates from the default trust directory
+	// (where the CLI update-trust command writes) are used.
+	configDir := t.TempDir()
+	t.Setenv(trust.EnvTrustDir, "")
+	t.Setenv("HOME", configDir)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("AppData", configDir)
+	trustDir, err := trust.DefaultDir()
+	if !assert.NoError(err) {
+		return
+	}
+	if err := os.MkdirAll(trustDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trustDir, "root.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootCert.Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = efactura.ValidateSignaturesDir(context.Background(), dir)
+	if assert.NoError(err) {
+		assert.Equal(1, report.Valid)
+		assert.Equal(1, report.UnknownSigner)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = efactura.ValidateSignaturesDir(ctx, dir, efactura.SignatureCheckVerifier(verifier))
+	assert.ErrorIs(err, context.Canceled)
+
+	_, err = efactura.ValidateSignaturesDir(context.Background(), filepath.Join(dir, "missing"),
+		efactura.SignatureCheckVerifier(verifier))
+	assert.Error(err)
+}
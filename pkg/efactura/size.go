@@ -0,0 +1,285 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"encoding"
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/printesoi/xml-go"
+)
+
+// MaxUploadSize is the maximum size in bytes of a XML document accepted by
+// the upload API (10 MB).
+const MaxUploadSize = 10 * 1024 * 1024
+
+// EstimateXMLSize returns an estimate of the size in bytes of the XML
+// encoding of the invoice (see Invoice.XML), computed by walking the invoice
+// without marshaling it. The estimate is usually within a few bytes of the
+// real size, so it can be compared with MaxUploadSize before doing the
+// expensive marshal and validation work, eg. to decide to split the invoice
+// or to drop the attachments.
+func (iv Invoice) EstimateXMLSize() int {
+	iv.Prefill()
+	return len(xml.Header) + estimateElementSize("Invoice", reflect.ValueOf(iv))
+}
+
+// EstimateXMLSize returns an estimate of the size in bytes of the XML
+// encoding of the credit note (see Invoice.EstimateXMLSize).
+func (cn CreditNote) EstimateXMLSize() int {
+	cn.Prefill()
+	return len(xml.Header) + estimateElementSize("CreditNote", reflect.ValueOf(cn))
+}
+
+var (
+	amountWithCurrencyType = reflect.TypeOf(AmountWithCurrency{})
+	invoiceNoteType        = reflect.TypeOf(InvoiceNote{})
+	textMarshalerType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	xmlMarshalerAttrType   = reflect.TypeOf((*xml.MarshalerAttr)(nil)).Elem()
+)
+
+// xmlnsPrefixes are the prefixes bound to the UBL namespaces by
+// setupUBLXMLEncoder.
+var xmlnsPrefixes = map[string]string{
+	xmlnsUBLcac: "cac",
+	xmlnsUBLcbc: "cbc",
+}
+
+// estimateElementSize returns the estimated size of the element with the
+// given name and value.
+func estimateElementSize(name string, v reflect.Value) int {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return 0
+	}
+	// <name> and </name>
+	size := 2*len(name) + 5
+	switch v.Type() {
+	case amountWithCurrencyType:
+		a := v.Interface().(AmountWithCurrency)
+		size += len(FormatAmount(a.Amount))
+		if a.CurrencyID != "" {
+			size += estimateAttrSize("currencyID", escapedTextSize(string(a.CurrencyID)))
+		}
+		return size
+	case invoiceNoteType:
+		n := v.Interface().(InvoiceNote)
+		if n.SubjectCode != "" {
+			size += len(n.SubjectCode) + 2
+		}
+		return size + escapedTextSize(n.Note)
+	}
+	if textSize, ok := xmlValueTextSize(v); ok {
+		return size + textSize
+	}
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	return size + estimateStructContentSize(v)
+}
+
+// estimateStructContentSize returns the estimated size of the attributes
+// and the content of the element with the given struct value.
+func estimateStructContentSize(v reflect.Value) (size int) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		tag := f.Tag.Get("xml")
+		if !f.IsExported() || tag == "-" || f.Name == "XMLName" {
+			continue
+		}
+		if f.Anonymous && tag == "" {
+			if fv = indirectValue(fv); fv.IsValid() && fv.Kind() == reflect.Struct {
+				size += estimateStructContentSize(fv)
+			}
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+		omitEmpty := hasXMLTagFlag(flags, "omitempty")
+		switch {
+		case hasXMLTagFlag(flags, "attr"):
+			if omitEmpty && isEmptyXMLValue(fv) {
+				continue
+			}
+			if textSize, ok := xmlValueTextSize(indirectValue(fv)); ok {
+				_, local := splitXMLName(name)
+				if local == "" {
+					local = f.Name
+				}
+				size += estimateAttrSize(local, textSize)
+			}
+		case hasXMLTagFlag(flags, "chardata"):
+			if textSize, ok := xmlValueTextSize(indirectValue(fv)); ok {
+				size += textSize
+			}
+		case hasXMLTagFlag(flags, "comment"):
+			if s := indirectValue(fv); s.IsValid() && s.Kind() == reflect.String && s.Len() > 0 {
+				// <!--comment-->
+				size += s.Len() + 7
+			}
+		case hasXMLTagFlag(flags, "innerxml"):
+			if s := indirectValue(fv); s.IsValid() && (s.Kind() == reflect.String || s.Kind() == reflect.Slice) {
+				size += s.Len()
+			}
+		default:
+			if omitEmpty && isEmptyXMLValue(fv) {
+				continue
+			}
+			space, local := splitXMLName(name)
+			if local == "" {
+				local = f.Name
+			}
+			// The parents of a a>b>c path.
+			parents := strings.Split(local, ">")
+			local = parents[len(parents)-1]
+			for _, parent := range parents[:len(parents)-1] {
+				size += 2*len(parent) + 5
+			}
+			if prefix := xmlnsPrefixes[space]; prefix != "" {
+				local = prefix + ":" + local
+			}
+			if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+				for j := 0; j < fv.Len(); j++ {
+					size += estimateElementSize(local, fv.Index(j))
+				}
+			} else {
+				size += estimateElementSize(local, fv)
+			}
+		}
+	}
+	return size
+}
+
+// xmlValueTextSize returns the size of the escaped text of a value
+// marshaled as chardata or as an attribute, if the value is a leaf.
+func xmlValueTextSize(v reflect.Value) (int, bool) {
+	if !v.IsValid() {
+		return 0, false
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		// The binary objects are marshaled base64 encoded.
+		return base64.StdEncoding.EncodedLen(v.Len()), true
+	}
+	if text, ok := xmlValueText(v); ok {
+		return escapedTextSize(text), true
+	}
+	return 0, false
+}
+
+// xmlValueText returns the text of a leaf value.
+func xmlValueText(v reflect.Value) (string, bool) {
+	// The types.Decimal, types.Date and types.DateTime implement
+	// xml.MarshalerAttr, some of them with a pointer receiver.
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if ptr.Type().Implements(xmlMarshalerAttrType) {
+		if attr, err := ptr.Interface().(xml.MarshalerAttr).MarshalXMLAttr(xml.Name{}); err == nil {
+			return attr.Value, true
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	}
+	if ptr.Type().Implements(textMarshalerType) {
+		if text, err := ptr.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text), true
+		}
+	}
+	return "", false
+}
+
+// estimateAttrSize returns the size of the attribute ` name="value"`, given
+// the size of the escaped value.
+func estimateAttrSize(name string, valueSize int) int {
+	return len(name) + valueSize + 4
+}
+
+// escapedTextSize returns the size of the text escaped like xml.EscapeText.
+func escapedTextSize(s string) int {
+	size := len(s)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<', '>':
+			size += 3
+		case '&', '"', '\'', '\t', '\n', '\r':
+			size += 4
+		}
+	}
+	return size
+}
+
+// splitXMLName splits the name of a xml tag "namespace local" in the
+// namespace and the local name.
+func splitXMLName(name string) (space, local string) {
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func hasXMLTagFlag(flags, flag string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyXMLValue returns true if the value is omitted by a omitempty flag,
+// like encoding/xml does.
+func isEmptyXMLValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// indirectValue dereferences the pointers and the interfaces, returning the
+// zero Value for nil.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
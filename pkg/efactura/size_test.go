@@ -0,0 +1,80 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestInvoiceEstimateXMLSize(t *testing.T) {
+	assert := assert.New(t)
+
+	var lines []InvoiceLine
+	for i := 1; i <= 50; i++ {
+		line, err := NewInvoiceLineBuilder(fmt.Sprint(i), CurrencyEUR).
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(float64(i))).
+			WithGrossPriceAmount(types.D(12.5)).
+			WithItemName(fmt.Sprintf("Produs <%d> & \"accesorii\"", i)).
+			WithItemTaxCategory(InvoiceLineTaxCategory{
+				TaxScheme: TaxSchemeVAT,
+				ID:        TaxCategoryVATStandardRate,
+				Percent:   types.D(19),
+			}).
+			Build()
+		if assert.NoError(err) {
+			lines = append(lines, line)
+		}
+	}
+	invoice, err := NewInvoiceBuilder("test.size.01").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 4, 1)).
+		WithDocumentCurrencyCode(CurrencyEUR).
+		WithTaxCurrencyCode(CurrencyRON).
+		WithDocumentToTaxCurrencyExchangeRate(types.D(4.9691)).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithInvoiceLines(lines).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	invoice.Note = []InvoiceNote{
+		{Note: "Nota 1\nrandul 2"},
+		{SubjectCode: "AAI", Note: "Nota 2"},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		estimated int
+		xml       func() ([]byte, error)
+	}{
+		{"invoice", invoice.EstimateXMLSize(), invoice.XML},
+		{"credit note", invoice.CreditNote().EstimateXMLSize(), invoice.CreditNote().XML},
+	} {
+		data, err := tc.xml()
+		if !assert.NoError(err) {
+			continue
+		}
+		// The estimate must be within 1% of the real size.
+		assert.InDelta(len(data), tc.estimated, float64(len(data))/100, tc.name)
+	}
+	assert.Less(invoice.EstimateXMLSize(), MaxUploadSize)
+}
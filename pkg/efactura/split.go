@@ -0,0 +1,343 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// SplitConfig is the config used for splitting an invoice.
+type SplitConfig struct {
+	// IDFunc generates the ID of a part. part is 1-based and key is the
+	// group key of the part. The default generates "<originalID>-<part>".
+	IDFunc func(originalID string, part int, key string) string
+	// NoteFunc, if set, generates a note added to each part (eg. to link the
+	// part to the original invoice).
+	NoteFunc func(originalID string, part, numParts int, key string) string
+	// Customize, if set, is called for each part after it was built. It can
+	// be used to set fields specific to a group (eg. the Delivery for a
+	// delivery location). The totals must not be changed.
+	Customize func(key string, invoice *Invoice)
+	// TaxCurrencyExchangeRate is the exchange rate from the document currency
+	// to the tax currency. Required if the invoice TaxCurrencyCode is
+	// different from the DocumentCurrencyCode.
+	TaxCurrencyExchangeRate types.Decimal
+}
+
+// SplitConfigOption allows gradually modifying a SplitConfig
+type SplitConfigOption func(*SplitConfig)
+
+// SplitIDFunc sets the function used for generating the IDs of the parts.
+func SplitIDFunc(f func(originalID string, part int, key string) string) SplitConfigOption {
+	return func(c *SplitConfig) {
+		c.IDFunc = f
+	}
+}
+
+// SplitNoteFunc sets the function used for generating a note for each part.
+func SplitNoteFunc(f func(originalID string, part, numParts int, key string) string) SplitConfigOption {
+	return func(c *SplitConfig) {
+		c.NoteFunc = f
+	}
+}
+
+// SplitCustomize sets the function called for customizing each part.
+func SplitCustomize(f func(key string, invoice *Invoice)) SplitConfigOption {
+	return func(c *SplitConfig) {
+		c.Customize = f
+	}
+}
+
+// SplitTaxCurrencyExchangeRate sets the exchange rate from the document
+// currency to the tax currency.
+func SplitTaxCurrencyExchangeRate(rate types.Decimal) SplitConfigOption {
+	return func(c *SplitConfig) {
+		c.TaxCurrencyExchangeRate = rate
+	}
+}
+
+// SplitInvoice splits the invoice in several invoices, one for each distinct
+// key returned by keyFunc for the invoice lines (eg. per delivery location or
+// per contract line group), in the order of the first appearance of each key.
+// The lines keep their IDs, and the totals of each part are recomputed. Each
+// document level allowance (charge) is distributed to the parts with lines of
+// its VAT category, proportionally to the net amount of these lines in each
+// part (see splitAllowancesCharges), so the sum of the net amounts of the
+// parts always equals the net amount of the original invoice (the VAT
+// amounts may differ by rounding, since VAT is rounded for each part). The
+// prepaid amount is apportioned to the parts proportionally to their tax
+// inclusive amounts (see splitPrepaidAmount). The payable rounding amount of
+// the original invoice is not carried to the parts.
+func SplitInvoice(invoice Invoice, keyFunc func(line InvoiceLine) string, opts ...SplitConfigOption) (parts []Invoice, err error) {
+	cfg := SplitConfig{
+		IDFunc: func(originalID string, part int, key string) string {
+			return fmt.Sprintf("%s-%d", originalID, part)
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if keyFunc == nil {
+		return nil, errors.New("split: invalid key func")
+	}
+	if len(invoice.InvoiceLines) == 0 {
+		return nil, errors.New("split: invoice has no lines")
+	}
+
+	var keys []string
+	groups := make(map[string][]InvoiceLine)
+	for _, line := range invoice.InvoiceLines {
+		key := keyFunc(line)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], line)
+	}
+
+	partsLines := make([][]InvoiceLine, len(keys))
+	for i, key := range keys {
+		partsLines[i] = groups[key]
+	}
+	partsAllowancesCharges, err := splitAllowancesCharges(invoice.AllowanceCharges, partsLines)
+	if err != nil {
+		return nil, err
+	}
+
+	builders := make([]*InvoiceBuilder, len(keys))
+	for i, key := range keys {
+		b := newInvoiceBuilderFromInvoice(invoice, cfg.TaxCurrencyExchangeRate)
+		b.WithID(cfg.IDFunc(invoice.ID, i+1, key))
+		b.WithInvoiceLines(partsLines[i])
+		b.WithAllowancesCharges(partsAllowancesCharges[i])
+		if cfg.NoteFunc != nil {
+			b.WithNotes(append(append([]InvoiceNote(nil), b.notes...), InvoiceNote{
+				Note: cfg.NoteFunc(invoice.ID, i+1, len(keys), key),
+			}))
+		}
+		builders[i] = b
+	}
+	if prepaid := invoice.LegalMonetaryTotal.PrepaidAmount; prepaid != nil && !prepaid.Amount.IsZero() {
+		partsPrepaidAmounts, er := splitPrepaidAmount(prepaid.Amount, builders)
+		if err = er; err != nil {
+			return nil, err
+		}
+		for i, b := range builders {
+			b.WithPrepaidAmount(partsPrepaidAmounts[i])
+		}
+	}
+
+	lineExtensionAmount := types.Zero
+	for i, key := range keys {
+		part, er := builders[i].Build()
+		if err = er; err != nil {
+			return nil, fmt.Errorf("split: part %d (%s): %w", i+1, key, err)
+		}
+		copyInvoiceNonBuilderFields(&part, invoice)
+		if cfg.Customize != nil {
+			cfg.Customize(key, &part)
+		}
+		lineExtensionAmount = lineExtensionAmount.Add(part.LegalMonetaryTotal.LineExtensionAmount.Amount)
+		parts = append(parts, part)
+	}
+
+	if expected := invoice.LegalMonetaryTotal.LineExtensionAmount.Amount; expected.IsInitialized() &&
+		!expected.Equal(lineExtensionAmount) {
+		return nil, fmt.Errorf("split: sum of line extension amounts %s differs from the invoice amount %s",
+			lineExtensionAmount.String(), expected.String())
+	}
+	return parts, nil
+}
+
+// splitAllowancesCharges distributes the document level allowances and
+// charges to the parts with the given lines. An allowance (charge) is split
+// between the parts with lines of its VAT category, proportionally to the
+// net amount of these lines in each part, and the last of these parts gets
+// the rounding remainder. The base amount, if set, is split the same way. An
+// allowance (charge) with a VAT category without lines (or with a zero net
+// amount) is kept on the first part.
+func splitAllowancesCharges(allowancesCharges []InvoiceDocumentAllowanceCharge, partsLines [][]InvoiceLine) ([][]InvoiceDocumentAllowanceCharge, error) {
+	partsAllowancesCharges := make([][]InvoiceDocumentAllowanceCharge, len(partsLines))
+	for i, allowanceCharge := range allowancesCharges {
+		key := makeTaxCategoryKey(allowanceCharge.TaxCategory)
+		total, last := types.Zero, -1
+		partsAmounts := make([]types.Decimal, len(partsLines))
+		for j, lines := range partsLines {
+			partsAmounts[j] = types.Zero
+			for _, line := range lines {
+				if makeTaxCategoryKeyLine(line.Item.TaxCategory) == key {
+					partsAmounts[j] = partsAmounts[j].Add(line.LineExtensionAmount.Amount)
+				}
+			}
+			if partsAmounts[j].IsNegative() {
+				return nil, fmt.Errorf("split: allowance/charge %d: negative net amount for tax category %s/%s in part %d",
+					i, allowanceCharge.TaxCategory.ID, allowanceCharge.TaxCategory.Percent.String(), j+1)
+			}
+			if !partsAmounts[j].IsZero() {
+				last = j
+			}
+			total = total.Add(partsAmounts[j])
+		}
+		if last < 0 {
+			partsAllowancesCharges[0] = append(partsAllowancesCharges[0], allowanceCharge)
+			continue
+		}
+
+		remainingAmount := allowanceCharge.Amount.Amount
+		var remainingBaseAmount types.Decimal
+		if allowanceCharge.BaseAmount != nil {
+			remainingBaseAmount = allowanceCharge.BaseAmount.Amount
+		}
+		for j, partAmount := range partsAmounts {
+			if partAmount.IsZero() {
+				continue
+			}
+			partAllowanceCharge := allowanceCharge
+			if j == last {
+				partAllowanceCharge.Amount.Amount = remainingAmount
+			} else {
+				partAllowanceCharge.Amount.Amount = allowanceCharge.Amount.Amount.Mul(partAmount).DivRound(total, 2)
+				remainingAmount = remainingAmount.Sub(partAllowanceCharge.Amount.Amount)
+			}
+			if allowanceCharge.BaseAmount != nil {
+				baseAmount := *allowanceCharge.BaseAmount
+				if j == last {
+					baseAmount.Amount = remainingBaseAmount
+				} else {
+					baseAmount.Amount = allowanceCharge.BaseAmount.Amount.Mul(partAmount).DivRound(total, 2)
+					remainingBaseAmount = remainingBaseAmount.Sub(baseAmount.Amount)
+				}
+				partAllowanceCharge.BaseAmount = &baseAmount
+			}
+			partsAllowancesCharges[j] = append(partsAllowancesCharges[j], partAllowanceCharge)
+		}
+	}
+	return partsAllowancesCharges, nil
+}
+
+// splitPrepaidAmount apportions the prepaid amount to the parts built by the
+// given builders, proportionally to the tax inclusive amount of each part.
+// The last part gets the rounding remainder. If the sum of the tax inclusive
+// amounts is not positive, the prepaid amount is kept on the first part.
+func splitPrepaidAmount(prepaidAmount types.Decimal, builders []*InvoiceBuilder) ([]types.Decimal, error) {
+	total := types.Zero
+	partsAmounts := make([]types.Decimal, len(builders))
+	for i, b := range builders {
+		// The tax inclusive amount doesn't depend on the prepaid amount.
+		part, err := b.WithPrepaidAmount(types.Zero).Build()
+		if err != nil {
+			return nil, fmt.Errorf("split: part %d: %w", i+1, err)
+		}
+		partsAmounts[i] = part.LegalMonetaryTotal.TaxInclusiveAmount.Amount
+		total = total.Add(partsAmounts[i])
+	}
+
+	partsPrepaidAmounts := make([]types.Decimal, len(builders))
+	for i := range partsPrepaidAmounts {
+		partsPrepaidAmounts[i] = types.Zero
+	}
+	if total.Sign() <= 0 {
+		partsPrepaidAmounts[0] = prepaidAmount
+		return partsPrepaidAmounts, nil
+	}
+	remaining := prepaidAmount
+	for i, partAmount := range partsAmounts {
+		if i == len(partsAmounts)-1 {
+			partsPrepaidAmounts[i] = remaining
+			break
+		}
+		partsPrepaidAmounts[i] = prepaidAmount.Mul(partAmount).DivRound(total, 2)
+		remaining = remaining.Sub(partsPrepaidAmounts[i])
+	}
+	return partsPrepaidAmounts, nil
+}
+
+// newInvoiceBuilderFromInvoice creates an InvoiceBuilder with all the fields
+// supported by the builder copied from the given invoice. The tax exemption
+// reasons are taken from the invoice tax subtotals. The prepaid amount note
+// is removed from the notes, since Build adds it again with the prepaid
+// amount of the new invoice (the advance invoices stay referenced as
+// preceding invoices). The exchange rate is used only if the invoice tax
+// currency is different from the document currency.
+func newInvoiceBuilderFromInvoice(invoice Invoice, taxCurrencyExchangeRate types.Decimal) *InvoiceBuilder {
+	b := NewInvoiceBuilder(invoice.ID).
+		WithIssueDate(invoice.IssueDate).
+		WithInvoiceTypeCode(invoice.InvoiceTypeCode).
+		WithDocumentCurrencyCode(invoice.DocumentCurrencyCode).
+		WithTaxCurrencyCode(invoice.TaxCurrencyCode).
+		WithAccountingCost(invoice.AccountingCost).
+		WithBuyerReference(invoice.BuyerReference).
+		WithNotes(invoice.Note).
+		WithSupplier(invoice.Supplier.Party).
+		WithCustomer(invoice.Customer.Party).
+		WithAllowancesCharges(invoice.AllowanceCharges).
+		WithInvoiceLines(invoice.InvoiceLines)
+	if invoice.DueDate != nil {
+		b.WithDueDate(*invoice.DueDate)
+	}
+	if taxCurrencyExchangeRate.IsInitialized() {
+		b.WithDocumentToTaxCurrencyExchangeRate(taxCurrencyExchangeRate)
+	}
+	if invoice.OrderReference != nil {
+		b.WithOrderReference(*invoice.OrderReference)
+	}
+	if invoice.InvoicePeriod != nil {
+		b.WithInvoicePeriod(*invoice.InvoicePeriod)
+	}
+	if invoice.Delivery != nil {
+		b.WithDelivery(*invoice.Delivery)
+	}
+	for _, ref := range invoice.BillingReferences {
+		b.AppendBillingReferences(ref.InvoiceDocumentReference)
+	}
+	if invoice.ContractDocumentReference != nil {
+		b.WithContractDocumentReference(invoice.ContractDocumentReference.ID)
+	}
+	if len(invoice.AdditionalDocumentReferences) > 0 {
+		b.WithAdditionalDocumentReferences(invoice.AdditionalDocumentReferences)
+	}
+	if len(invoice.PaymentMeans) > 0 {
+		b.AppendPaymentMeans(invoice.PaymentMeans...)
+	}
+	if invoice.PaymentTerms != nil {
+		b.WithPaymentTerms(*invoice.PaymentTerms)
+	}
+	if prepaid := invoice.LegalMonetaryTotal.PrepaidAmount; prepaid != nil {
+		b.WithNotes(withoutPrepaidAmountNotes(invoice.Note))
+		b.WithPrepaidAmount(prepaid.Amount)
+	}
+	for _, taxTotal := range invoice.TaxTotal {
+		for _, subtotal := range taxTotal.TaxSubtotals {
+			category := subtotal.TaxCategory
+			if category.TaxExemptionReason != "" || category.TaxExemptionReasonCode != "" {
+				b.AddTaxExemptionReason(category.ID, category.TaxExemptionReason, category.TaxExemptionReasonCode)
+			}
+		}
+	}
+	return b
+}
+
+// copyInvoiceNonBuilderFields copies from src to dst the fields that are not
+// supported by the InvoiceBuilder.
+func copyInvoiceNonBuilderFields(dst *Invoice, src Invoice) {
+	dst.DespatchDocumentReference = src.DespatchDocumentReference
+	dst.ReceiptDocumentReference = src.ReceiptDocumentReference
+	dst.OriginatorDocumentReference = src.OriginatorDocumentReference
+	dst.ProjectReference = src.ProjectReference
+	dst.Payee = src.Payee
+	dst.TaxRepresentative = src.TaxRepresentative
+}
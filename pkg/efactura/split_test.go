@@ -0,0 +1,292 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func buildTestSplitInvoice(t *testing.T) Invoice {
+	t.Helper()
+
+	standardTaxCategory := InvoiceLineTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	exemptTaxCategory := InvoiceLineTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATExempt,
+		Percent:   types.D(0),
+	}
+
+	var lines []InvoiceLine
+	for i, l := range []struct {
+		location string
+		quantity float64
+		price    float64
+		category InvoiceLineTaxCategory
+	}{
+		{"depot-a", 3, 10.33, standardTaxCategory},
+		{"depot-b", 2, 7.5, standardTaxCategory},
+		{"depot-a", 1, 100, exemptTaxCategory},
+		{"depot-c", 5, 1.11, standardTaxCategory},
+	} {
+		line, err := NewInvoiceLineBuilder(fmt.Sprint(i+1), CurrencyRON).
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(l.quantity)).
+			WithGrossPriceAmount(types.D(l.price)).
+			WithItemName("Item").
+			WithNote(l.location).
+			WithItemTaxCategory(l.category).
+			Build()
+		if err != nil {
+			t.Fatalf("error building line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	allowance, err := NewInvoiceDocumentAllowanceBuilder(CurrencyRON, types.D(5), InvoiceTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}).WithAllowanceChargeReason("Discount").Build()
+	if err != nil {
+		t.Fatalf("error building allowance: %v", err)
+	}
+
+	invoice, err := NewInvoiceBuilder("INV-100").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 3, 31)).
+		WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithBuyerReference("BUYER-REF").
+		AddTaxExemptionReason(TaxCategoryVATExempt, "Scutit", "").
+		AppendAllowanceCharge(allowance).
+		WithInvoiceLines(lines).
+		Build()
+	if err != nil {
+		t.Fatalf("error building invoice: %v", err)
+	}
+	return invoice
+}
+
+func TestSplitInvoice(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := buildTestSplitInvoice(t)
+	invoice.Delivery = &InvoiceDelivery{ActualDeliveryDate: types.NewDate(2024, 3, 1)}
+
+	parts, err := SplitInvoice(invoice, func(line InvoiceLine) string {
+		return line.Note
+	},
+		SplitNoteFunc(func(originalID string, part, numParts int, key string) string {
+			return fmt.Sprintf("Partea %d din %d a facturii %s", part, numParts, originalID)
+		}),
+		SplitCustomize(func(key string, part *Invoice) {
+			part.AccountingCost = key
+		}),
+	)
+	if !assert.NoError(err) || !assert.Len(parts, 3) {
+		return
+	}
+
+	assert.Equal("INV-100-1", parts[0].ID)
+	assert.Equal("INV-100-2", parts[1].ID)
+	assert.Equal("INV-100-3", parts[2].ID)
+	assert.Equal("depot-a", parts[0].AccountingCost)
+	if assert.Len(parts[1].Note, 1) {
+		assert.Equal("Partea 2 din 3 a facturii INV-100", parts[1].Note[0].Note)
+	}
+
+	if assert.Len(parts[0].InvoiceLines, 2) {
+		assert.Equal("1", parts[0].InvoiceLines[0].ID)
+		assert.Equal("3", parts[0].InvoiceLines[1].ID)
+	}
+	// The 5 RON allowance is distributed proportionally to the net amount of
+	// the S/19 lines of each part: 30.99, 15 and 5.55.
+	for i, expected := range []string{"3.01", "1.46", "0.53"} {
+		if assert.Len(parts[i].AllowanceCharges, 1) {
+			assert.Equal(expected, parts[i].AllowanceCharges[0].Amount.Amount.StringFixed(2))
+		}
+	}
+
+	lineExtensionAmount, taxExclusiveAmount, taxInclusiveAmount := types.Zero, types.Zero, types.Zero
+	for _, part := range parts {
+		assert.Equal("BUYER-REF", part.BuyerReference)
+		assert.Equal(invoice.Delivery, part.Delivery)
+		lineExtensionAmount = lineExtensionAmount.Add(part.LegalMonetaryTotal.LineExtensionAmount.Amount)
+		taxExclusiveAmount = taxExclusiveAmount.Add(part.LegalMonetaryTotal.TaxExclusiveAmount.Amount)
+		taxInclusiveAmount = taxInclusiveAmount.Add(part.LegalMonetaryTotal.TaxInclusiveAmount.Amount)
+	}
+	assert.Equal(invoice.LegalMonetaryTotal.LineExtensionAmount.Amount.StringFixed(2), lineExtensionAmount.StringFixed(2))
+	assert.Equal(invoice.LegalMonetaryTotal.TaxExclusiveAmount.Amount.StringFixed(2), taxExclusiveAmount.StringFixed(2))
+	// VAT is rounded per part, so the difference must be at most 0.01 per part.
+	diff := invoice.LegalMonetaryTotal.TaxInclusiveAmount.Amount.Sub(taxInclusiveAmount).Abs()
+	assert.True(diff.LessThanOrEqual(types.D(0.03).Decimal), "tax inclusive amount differs by %s", diff)
+
+	// The exemption reason must be preserved for the part with the exempt line.
+	var foundExempt bool
+	for _, subtotal := range parts[0].TaxTotal[0].TaxSubtotals {
+		if subtotal.TaxCategory.ID == TaxCategoryVATExempt {
+			foundExempt = true
+			assert.Equal("Scutit", subtotal.TaxCategory.TaxExemptionReason)
+		}
+	}
+	assert.True(foundExempt)
+
+	_, err = SplitInvoice(invoice, nil)
+	assert.Error(err)
+}
+
+func TestSplitInvoiceAllowancesCharges(t *testing.T) {
+	assert := assert.New(t)
+
+	vat19 := InvoiceTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	vat9 := InvoiceTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(9),
+	}
+
+	var lines []InvoiceLine
+	for i, l := range []struct {
+		location string
+		price    float64
+		category InvoiceTaxCategory
+	}{
+		{"depot-a", 100, vat19},
+		{"depot-b", 50, vat19},
+		{"depot-b", 40, vat9},
+	} {
+		line, err := NewInvoiceLineBuilder(fmt.Sprint(i+1), CurrencyRON).
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(1)).
+			WithGrossPriceAmount(types.D(l.price)).
+			WithItemName("Item").
+			WithNote(l.location).
+			WithItemTaxCategory(InvoiceLineTaxCategory{
+				TaxScheme: l.category.TaxScheme,
+				ID:        l.category.ID,
+				Percent:   l.category.Percent,
+			}).
+			Build()
+		if err != nil {
+			t.Fatalf("error building line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	invoice, err := NewInvoiceBuilder("INV-200").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		AppendAllowanceChargeBuilders(
+			// The S/9 lines are only in the second part.
+			NewInvoiceDocumentAllowanceBuilder("", types.D(4), vat9),
+			// 10% of the S/19 lines from both parts.
+			NewInvoiceDocumentAllowanceBuilder("", types.Decimal{}, vat19).
+				WithBaseAmount(types.D(150)).
+				WithPercent(types.D(10)),
+			NewInvoiceDocumentChargeBuilder("", types.D(3), vat19),
+		).
+		WithInvoiceLines(lines).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+
+	parts, err := SplitInvoice(invoice, func(line InvoiceLine) string {
+		return line.Note
+	})
+	if !assert.NoError(err) || !assert.Len(parts, 2) {
+		return
+	}
+
+	if assert.Len(parts[0].AllowanceCharges, 2) {
+		allowance := parts[0].AllowanceCharges[0]
+		assert.Equal("10.00", allowance.Amount.Amount.StringFixed(2))
+		if assert.NotNil(allowance.BaseAmount) {
+			assert.Equal("100.00", allowance.BaseAmount.Amount.StringFixed(2))
+		}
+		assert.Equal("2.00", parts[0].AllowanceCharges[1].Amount.Amount.StringFixed(2))
+	}
+	if assert.Len(parts[1].AllowanceCharges, 3) {
+		assert.Equal("4.00", parts[1].AllowanceCharges[0].Amount.Amount.StringFixed(2))
+		allowance := parts[1].AllowanceCharges[1]
+		assert.Equal("5.00", allowance.Amount.Amount.StringFixed(2))
+		if assert.NotNil(allowance.BaseAmount) {
+			assert.Equal("50.00", allowance.BaseAmount.Amount.StringFixed(2))
+		}
+		assert.Equal("1.00", parts[1].AllowanceCharges[2].Amount.Amount.StringFixed(2))
+	}
+
+	// Each part has the VAT of its own lines, allowances and charges.
+	assert.Equal("92.00", parts[0].LegalMonetaryTotal.TaxExclusiveAmount.Amount.StringFixed(2))
+	assert.Equal("17.48", parts[0].TaxTotal[0].TaxAmount.Amount.StringFixed(2))
+	assert.Equal("82.00", parts[1].LegalMonetaryTotal.TaxExclusiveAmount.Amount.StringFixed(2))
+	assert.Equal("11.98", parts[1].TaxTotal[0].TaxAmount.Amount.StringFixed(2))
+	assert.Equal(invoice.TaxTotal[0].TaxAmount.Amount.StringFixed(2),
+		parts[0].TaxTotal[0].TaxAmount.Amount.Add(parts[1].TaxTotal[0].TaxAmount.Amount).StringFixed(2))
+}
+
+func TestSplitInvoicePrepaidAmount(t *testing.T) {
+	assert := assert.New(t)
+
+	advance := InvoiceDocumentReference{ID: "AV-1", IssueDate: types.NewDate(2024, 2, 1)}
+	invoice, err := newInvoiceBuilderFromInvoice(buildTestSplitInvoice(t), types.Decimal{}).
+		WithPrepaidAmount(types.D(50), advance).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+
+	parts, err := SplitInvoice(invoice, func(line InvoiceLine) string {
+		return line.Note
+	})
+	if !assert.NoError(err) || !assert.Len(parts, 3) {
+		return
+	}
+
+	// The prepaid amount is apportioned proportionally to the tax inclusive
+	// amounts of the parts: 133.30, 16.11 and 5.97.
+	for i, expected := range []string{"42.89", "5.18", "1.93"} {
+		total := parts[i].LegalMonetaryTotal
+		if assert.NotNil(total.PrepaidAmount) {
+			assert.Equal(expected, total.PrepaidAmount.Amount.StringFixed(2))
+			assert.True(total.PayableAmount.Amount.Equal(total.TaxInclusiveAmount.Amount.Sub(total.PrepaidAmount.Amount)))
+		}
+		assert.Equal([]InvoiceNote{{
+			SubjectCode: InvoiceNoteSubjectPaymentInformation,
+			Note:        "Avans încasat: " + expected + " RON",
+		}}, parts[i].Note)
+		if assert.Len(parts[i].BillingReferences, 1) {
+			assert.Equal(advance, parts[i].BillingReferences[0].InvoiceDocumentReference)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// MessageStateCache is a permanent cache for the terminal states of the
+// uploaded messages (see GetMessageStateCode.IsTerminal). Once a message
+// reached a terminal state, the state never changes, so GetMessageState
+// returns the cached state instead of querying ANAF again (see
+// ClientMessageStateCache).
+type MessageStateCache interface {
+	// GetMessageState returns the cached state for the given upload index.
+	// ok is false if the state is not cached.
+	GetMessageState(ctx context.Context, uploadIndex int64) (state *GetMessageStateResponse, ok bool, err error)
+	// PutMessageState caches the terminal state for the given upload index.
+	PutMessageState(ctx context.Context, uploadIndex int64, state *GetMessageStateResponse) error
+}
+
+// IsTerminal returns true if the state is final: ok, nok or invalid XML.
+func (c GetMessageStateCode) IsTerminal() bool {
+	switch c {
+	case GetMessageStateCodeOk, GetMessageStateCodeNok, GetMessageStateCodeInvalidXML:
+		return true
+	}
+	return false
+}
+
+// IsTerminal returns true if the message is in a final state (see
+// GetMessageStateCode.IsTerminal).
+func (r *GetMessageStateResponse) IsTerminal() bool {
+	return r != nil && r.State.IsTerminal()
+}
+
+// storeMessageStateCache is a MessageStateCache backed by a store.Store.
+type storeMessageStateCache struct {
+	st store.Store
+}
+
+// NewStoreMessageStateCache returns a MessageStateCache that keeps the states
+// in the given store (eg. a store.DirStore for a persistent cache or a
+// store.MemoryStore for a per-process cache), keyed by the upload index. A
+// dedicated store should be used, not the one with the archived invoices.
+func NewStoreMessageStateCache(st store.Store) MessageStateCache {
+	return storeMessageStateCache{st: st}
+}
+
+// GetMessageState implements the MessageStateCache interface.
+func (c storeMessageStateCache) GetMessageState(ctx context.Context, uploadIndex int64) (*GetMessageStateResponse, bool, error) {
+	_, data, err := c.st.Get(ctx, strconv.FormatInt(uploadIndex, 10))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	state := new(GetMessageStateResponse)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, false, err
+	}
+	return state, true, nil
+}
+
+// PutMessageState implements the MessageStateCache interface.
+func (c storeMessageStateCache) PutMessageState(ctx context.Context, uploadIndex int64, state *GetMessageStateResponse) error {
+	// The metadata belongs to the request, it's never cached.
+	cached := *state
+	cached.Metadata = nil
+	data, err := json.Marshal(&cached)
+	if err != nil {
+		return err
+	}
+	key := strconv.FormatInt(uploadIndex, 10)
+	return c.st.Put(ctx, store.Entry{
+		Key:         key,
+		UploadIndex: uploadIndex,
+		Name:        key + ".json",
+	}, data)
+}
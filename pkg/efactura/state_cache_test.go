@@ -0,0 +1,95 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+func TestGetMessageStateCache(t *testing.T) {
+	assert := assert.New(t)
+
+	st := store.NewMemoryStore()
+	client, mux := setupTestClient(t, efactura.ClientMessageStateCache(efactura.NewStoreMessageStateCache(st)))
+	calls := make(map[string]int)
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		uploadIndex := r.URL.Query().Get("id_incarcare")
+		calls[uploadIndex]++
+		w.Header().Set("Content-Type", "application/xml")
+		switch uploadIndex {
+		case "5001":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="ok" id_descarcare="3001"/>`)
+		case "5002":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="in prelucrare"/>`)
+		case "5003":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="XML cu erori nepreluat de sistem">
+  <Errors errorMessage="E: validare esuata"/>
+</header>`)
+		}
+	})
+
+	for i := 0; i < 3; i++ {
+		ctx := efactura.ContextWithMetadata(context.Background(), efactura.Metadata{"run": fmt.Sprint(i)})
+		for _, uploadIndex := range []int64{5001, 5002, 5003} {
+			res, err := client.GetMessageState(ctx, uploadIndex)
+			if !assert.NoError(err) {
+				continue
+			}
+			assert.Equal(fmt.Sprint(i), res.Metadata["run"])
+			switch uploadIndex {
+			case 5001:
+				assert.True(res.IsOk())
+				assert.Equal(int64(3001), res.GetDownloadID())
+			case 5002:
+				assert.True(res.IsProcessing())
+			case 5003:
+				assert.True(res.IsInvalidXML())
+				assert.Equal("E: validare esuata", res.GetFirstErrorMessage())
+			}
+		}
+	}
+	assert.Equal(map[string]int{"5001": 1, "5002": 3, "5003": 1}, calls)
+
+	entries, err := st.List(context.Background())
+	if assert.NoError(err) && assert.Len(entries, 2) {
+		assert.Equal(int64(5001), entries[0].UploadIndex)
+		assert.Equal(int64(5003), entries[1].UploadIndex)
+	}
+	_, data, err := st.Get(context.Background(), "5001")
+	if assert.NoError(err) {
+		assert.NotContains(string(data), "run")
+	}
+}
+
+func TestGetMessageStateCodeIsTerminal(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(efactura.GetMessageStateCodeOk.IsTerminal())
+	assert.True(efactura.GetMessageStateCodeNok.IsTerminal())
+	assert.True(efactura.GetMessageStateCodeInvalidXML.IsTerminal())
+	assert.False(efactura.GetMessageStateCodeProcessing.IsTerminal())
+	assert.False(efactura.GetMessageStateCode("").IsTerminal())
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition is returned when an event cannot be applied to a
+// Submission in its current status (eg. a download before the upload).
+var ErrInvalidTransition = errors.New("invalid submission transition")
+
+// SubmissionStatus is the status of a Submission.
+type SubmissionStatus string
+
+const (
+	// SubmissionStatusNew is the status of a Submission with no events.
+	SubmissionStatusNew SubmissionStatus = ""
+	// SubmissionStatusCreated is the status after the document was created
+	// locally.
+	SubmissionStatusCreated SubmissionStatus = "created"
+	// SubmissionStatusUploaded is the status after the document was
+	// uploaded, while ANAF is processing it.
+	SubmissionStatusUploaded SubmissionStatus = "uploaded"
+	// SubmissionStatusAccepted is the status after ANAF accepted the
+	// document (state ok).
+	SubmissionStatusAccepted SubmissionStatus = "accepted"
+	// SubmissionStatusRejected is the status after ANAF rejected the
+	// document (state nok or invalid XML).
+	SubmissionStatusRejected SubmissionStatus = "rejected"
+	// SubmissionStatusDownloaded is the status after the response (the
+	// signed invoice or the errors) was downloaded.
+	SubmissionStatusDownloaded SubmissionStatus = "downloaded"
+	// SubmissionStatusArchived is the status after the downloaded response
+	// was archived.
+	SubmissionStatusArchived SubmissionStatus = "archived"
+)
+
+// Submission is the state of a document submitted to ANAF: created →
+// uploaded → accepted or rejected → downloaded → archived. The state is only
+// changed by applying journal events (see Apply and Journal), so it can be
+// reconstructed at any time by replaying the events.
+type Submission struct {
+	// ID identifies the submission (eg. the invoice number).
+	ID string
+	// Status is the current status.
+	Status SubmissionStatus
+	// UploadIndex is the upload index (index_incarcare) returned by the
+	// upload.
+	UploadIndex int64
+	// State is the last message state returned by ANAF.
+	State GetMessageStateCode
+	// DownloadID is the download ID (id_descarcare) of the response.
+	DownloadID int64
+	// ArchiveKey is the key of the archived response (eg. in a store.Store).
+	ArchiveKey string
+	// CreatedAt is the time of the created event.
+	CreatedAt time.Time
+	// UpdatedAt is the time of the last event.
+	UpdatedAt time.Time
+	// Events are the events applied to the submission, in order.
+	Events []JournalEvent
+}
+
+// Apply applies the event to the submission. If the event is not valid in
+// the current status, the submission is not changed and an error wrapping
+// ErrInvalidTransition is returned.
+func (s *Submission) Apply(event JournalEvent) error {
+	if event.SubmissionID == "" {
+		return fmt.Errorf("%w: missing submission ID", ErrInvalidTransition)
+	}
+	if s.ID != "" && event.SubmissionID != s.ID {
+		return fmt.Errorf("%w: event for submission %q applied to %q",
+			ErrInvalidTransition, event.SubmissionID, s.ID)
+	}
+	invalid := func() error {
+		return fmt.Errorf("%w: %s event for submission %q in status %q",
+			ErrInvalidTransition, event.Type, event.SubmissionID, s.Status)
+	}
+
+	next := *s
+	switch event.Type {
+	case JournalEventCreated:
+		if s.Status != SubmissionStatusNew {
+			return invalid()
+		}
+		next.ID = event.SubmissionID
+		next.Status = SubmissionStatusCreated
+		next.CreatedAt = event.Time
+
+	case JournalEventUploaded:
+		if s.Status != SubmissionStatusCreated {
+			return invalid()
+		}
+		next.Status = SubmissionStatusUploaded
+		next.UploadIndex = event.UploadIndex
+
+	case JournalEventStateChanged:
+		if s.Status != SubmissionStatusUploaded {
+			return invalid()
+		}
+		next.State = event.State
+		switch event.State {
+		case GetMessageStateCodeOk:
+			next.Status = SubmissionStatusAccepted
+		case GetMessageStateCodeNok, GetMessageStateCodeInvalidXML:
+			next.Status = SubmissionStatusRejected
+		}
+		if event.DownloadID != 0 {
+			next.DownloadID = event.DownloadID
+		}
+
+	case JournalEventDownloaded:
+		if s.Status != SubmissionStatusAccepted && s.Status != SubmissionStatusRejected {
+			return invalid()
+		}
+		next.Status = SubmissionStatusDownloaded
+		if event.DownloadID != 0 {
+			next.DownloadID = event.DownloadID
+		}
+
+	case JournalEventArchived:
+		if s.Status != SubmissionStatusDownloaded {
+			return invalid()
+		}
+		next.Status = SubmissionStatusArchived
+		next.ArchiveKey = event.ArchiveKey
+
+	default:
+		return fmt.Errorf("%w: unknown event type %q", ErrInvalidTransition, event.Type)
+	}
+
+	next.UpdatedAt = event.Time
+	next.Events = append(append([]JournalEvent(nil), s.Events...), event)
+	*s = next
+	return nil
+}
+
+// IsTerminal returns true if nothing else is expected to happen to the
+// submission (it was archived).
+func (s *Submission) IsTerminal() bool {
+	return s != nil && s.Status == SubmissionStatusArchived
+}
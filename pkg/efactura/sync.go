@@ -0,0 +1,239 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+const (
+	// defaultSyncOverlap is the default time interval before the end of the
+	// last synced interval that is fetched again by SyncMessages.
+	defaultSyncOverlap = 15 * time.Minute
+	// defaultSyncClockSkew is the default tolerance for the difference
+	// between the local clock and the ANAF clock.
+	defaultSyncClockSkew = time.Minute
+	// maxSyncLookback is the oldest message (relative to now) that can be
+	// fetched from the messages list (ANAF keeps the messages for 60 days).
+	maxSyncLookback = 60 * 24 * time.Hour
+)
+
+// SyncCursor is the state of SyncMessages for a CIF and a message filter,
+// persisted in a SyncCursorStore between runs.
+type SyncCursor struct {
+	// CIF is the CIF of the synced messages.
+	CIF string `json:"cif"`
+	// Filter is the message filter used for the sync.
+	Filter MessageFilterType `json:"filter"`
+	// SyncedUntil is the end of the last synced interval.
+	SyncedUntil time.Time `json:"synced_until"`
+	// LastMessageID is the ID of the most recent message discovered so far.
+	LastMessageID string `json:"last_message_id,omitempty"`
+	// RecentIDs are the IDs of the messages created in the overlap interval
+	// before SyncedUntil, used for deduplicating the messages fetched again
+	// by the next run.
+	RecentIDs []string `json:"recent_ids,omitempty"`
+}
+
+// SyncCursorStore persists the SyncCursor for each CIF and message filter.
+type SyncCursorStore interface {
+	// LoadSyncCursor returns the cursor for the given CIF and filter, or nil
+	// if the messages were never synced.
+	LoadSyncCursor(ctx context.Context, cif string, filter MessageFilterType) (*SyncCursor, error)
+	// SaveSyncCursor saves the cursor, replacing the existing cursor for the
+	// same CIF and filter.
+	SaveSyncCursor(ctx context.Context, cursor SyncCursor) error
+}
+
+// storeSyncCursorStore is a SyncCursorStore backed by a store.Store.
+type storeSyncCursorStore struct {
+	st store.Store
+}
+
+// NewStoreSyncCursorStore returns a SyncCursorStore that keeps the cursors
+// in the given store (eg. a store.DirStore), keyed by the CIF and the filter.
+// A dedicated store should be used, not the one with the archived invoices.
+func NewStoreSyncCursorStore(st store.Store) SyncCursorStore {
+	return storeSyncCursorStore{st: st}
+}
+
+func syncCursorKey(cif string, filter MessageFilterType) string {
+	return "sync-" + cif + "-" + strconv.Itoa(int(filter))
+}
+
+// LoadSyncCursor implements the SyncCursorStore interface.
+func (s storeSyncCursorStore) LoadSyncCursor(ctx context.Context, cif string, filter MessageFilterType) (*SyncCursor, error) {
+	_, data, err := s.st.Get(ctx, syncCursorKey(cif, filter))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cursor := new(SyncCursor)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// SaveSyncCursor implements the SyncCursorStore interface.
+func (s storeSyncCursorStore) SaveSyncCursor(ctx context.Context, cursor SyncCursor) error {
+	data, err := json.Marshal(&cursor)
+	if err != nil {
+		return err
+	}
+	key := syncCursorKey(cursor.CIF, cursor.Filter)
+	return s.st.Put(ctx, store.Entry{
+		Key:  key,
+		CIF:  cursor.CIF,
+		Name: key + ".json",
+	}, data)
+}
+
+type syncOptions struct {
+	filter          MessageFilterType
+	overlap         time.Duration
+	clockSkew       time.Duration
+	initialLookback time.Duration
+}
+
+// SyncOption is an option for SyncMessages.
+type SyncOption func(*syncOptions)
+
+// SyncOptionFilter sets the message filter (default MessageFilterAll). Each
+// filter has its own cursor.
+func SyncOptionFilter(filter MessageFilterType) SyncOption {
+	return func(o *syncOptions) {
+		o.filter = filter
+	}
+}
+
+// SyncOptionOverlap sets the time interval before the end of the last synced
+// interval that is fetched again (default 15 minutes), so that the messages
+// indexed late by ANAF are not missed. The messages fetched again are
+// deduplicated.
+func SyncOptionOverlap(overlap time.Duration) SyncOption {
+	return func(o *syncOptions) {
+		o.overlap = overlap
+	}
+}
+
+// SyncOptionClockSkew sets the tolerance for the difference between the
+// local clock and the ANAF clock (default 1 minute): the synced interval
+// ends at now minus the clock skew, so it's never in the future for ANAF.
+func SyncOptionClockSkew(clockSkew time.Duration) SyncOption {
+	return func(o *syncOptions) {
+		o.clockSkew = clockSkew
+	}
+}
+
+// SyncOptionInitialLookback sets the interval fetched by the first sync for a
+// CIF and filter (default and maximum 60 days).
+func SyncOptionInitialLookback(lookback time.Duration) SyncOption {
+	return func(o *syncOptions) {
+		o.initialLookback = lookback
+	}
+}
+
+// SyncMessages fetches the messages for the given CIF that were created
+// since the last sync, using the cursor persisted in cursors for the CIF and
+// the message filter (see SyncOptionFilter), and returns the newly
+// discovered messages. The first sync fetches the messages from the last 60
+// days (see SyncOptionInitialLookback). Each run fetches again an overlap
+// interval before the end of the previous one (see SyncOptionOverlap), and
+// the messages already discovered by the previous run are skipped. The cursor
+// is saved only if all the messages were fetched, so a failed run is simply
+// repeated by the next one.
+func (c *Client) SyncMessages(
+	ctx context.Context, cif string, cursors SyncCursorStore, opts ...SyncOption,
+) (messages []Message, err error) {
+	syncOpts := syncOptions{
+		filter:          MessageFilterAll,
+		overlap:         defaultSyncOverlap,
+		clockSkew:       defaultSyncClockSkew,
+		initialLookback: maxSyncLookback,
+	}
+	for _, opt := range opts {
+		opt(&syncOpts)
+	}
+
+	cursor, er := cursors.LoadSyncCursor(ctx, cif, syncOpts.filter)
+	if err = er; err != nil {
+		return
+	}
+	now := time.Now()
+	endTs := now.Add(-syncOpts.clockSkew)
+	oldest := now.Add(-maxSyncLookback).Add(syncOpts.clockSkew)
+	startTs := now.Add(-syncOpts.initialLookback)
+	seen := make(map[string]struct{})
+	if cursor != nil {
+		startTs = cursor.SyncedUntil.Add(-syncOpts.overlap)
+		for _, id := range cursor.RecentIDs {
+			seen[id] = struct{}{}
+		}
+	} else {
+		cursor = &SyncCursor{CIF: cif, Filter: syncOpts.filter}
+	}
+	if startTs.Before(oldest) {
+		startTs = oldest
+	}
+	if !startTs.Before(endTs) {
+		return nil, nil
+	}
+
+	res, er := c.GetAllMessagesPagination(ctx, cif, startTs, endTs, syncOpts.filter)
+	if err = er; err != nil {
+		return
+	}
+	if !res.IsOk() {
+		err = fmt.Errorf("sync messages: %s: %s", res.Title, res.Error)
+		return
+	}
+
+	var lastCreated time.Time
+	recentFrom := endTs.Add(-syncOpts.overlap)
+	var recentIDs []string
+	for _, m := range res.Messages {
+		created, ok := m.GetCreationDate()
+		// Messages without a valid creation date are always kept for
+		// deduplication.
+		if !ok || !created.Before(recentFrom) {
+			recentIDs = append(recentIDs, m.ID)
+		}
+		if _, ok := seen[m.ID]; ok {
+			continue
+		}
+		seen[m.ID] = struct{}{}
+		messages = append(messages, m)
+		if ok && !created.Before(lastCreated) {
+			lastCreated, cursor.LastMessageID = created, m.ID
+		}
+	}
+
+	cursor.SyncedUntil = endTs
+	cursor.RecentIDs = recentIDs
+	if err = cursors.SaveSyncCursor(ctx, *cursor); err != nil {
+		messages = nil
+	}
+	return
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/store"
+	ptime "github.com/printesoi/e-factura-go/pkg/time"
+)
+
+func TestSyncMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	type indexedMessage struct {
+		message efactura.Message
+		created time.Time
+	}
+	var messages []indexedMessage
+	addMessage := func(id string, created time.Time) {
+		messages = append(messages, indexedMessage{
+			message: efactura.Message{
+				ID:           id,
+				Type:         efactura.MessageTypeReceivedInvoice,
+				CIF:          "12345678",
+				CreationDate: ptime.TimeInRomania(created).Format("200601021504"),
+			},
+			created: created.Truncate(time.Minute),
+		})
+	}
+
+	client, mux := setupTestClient(t)
+	var requests int
+	var lastStartTs time.Time
+	mux.HandleFunc("/FCTEL/rest/listaMesajePaginatieFactura", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		startMs, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+		endMs, _ := strconv.ParseInt(r.URL.Query().Get("endTime"), 10, 64)
+		startTs, endTs := time.UnixMilli(startMs), time.UnixMilli(endMs)
+		lastStartTs = startTs
+		res := efactura.MessagesListPaginationResponse{TotalPages: 1, CurrentPageIndex: 1}
+		for _, m := range messages {
+			if !m.created.Before(startTs) && !m.created.After(endTs) {
+				res.Messages = append(res.Messages, m.message)
+			}
+		}
+		res.RecordsInPage = int64(len(res.Messages))
+		res.TotalRecords = res.RecordsInPage
+		writeJSON(w, res)
+	})
+
+	ctx := context.Background()
+	cursors := efactura.NewStoreSyncCursorStore(store.NewMemoryStore())
+	now := time.Now()
+	addMessage("1", now.Add(-10*24*time.Hour))
+	addMessage("2", now.Add(-5*time.Minute))
+
+	// The first sync fetches all the messages.
+	synced, err := client.SyncMessages(ctx, "12345678", cursors, efactura.SyncOptionClockSkew(0))
+	if assert.NoError(err) && assert.Len(synced, 2) {
+		assert.Equal("1", synced[0].ID)
+		assert.Equal("2", synced[1].ID)
+	}
+	cursor, err := cursors.LoadSyncCursor(ctx, "12345678", efactura.MessageFilterAll)
+	if assert.NoError(err) && assert.NotNil(cursor) {
+		assert.Equal("2", cursor.LastMessageID)
+		assert.Equal([]string{"2"}, cursor.RecentIDs)
+		assert.WithinDuration(now, cursor.SyncedUntil, time.Minute)
+	}
+
+	// The next sync only fetches the delta (with the overlap), and the
+	// message "2" fetched again is skipped.
+	addMessage("3", time.Now())
+	synced, err = client.SyncMessages(ctx, "12345678", cursors, efactura.SyncOptionClockSkew(0))
+	if assert.NoError(err) && assert.Len(synced, 1) {
+		assert.Equal("3", synced[0].ID)
+	}
+	assert.WithinDuration(cursor.SyncedUntil.Add(-15*time.Minute), lastStartTs, time.Second)
+
+	// Each filter has its own cursor.
+	synced, err = client.SyncMessages(ctx, "12345678", cursors,
+		efactura.SyncOptionClockSkew(0),
+		efactura.SyncOptionFilter(efactura.MessageFilterReceived))
+	if assert.NoError(err) {
+		assert.Len(synced, 3)
+	}
+	assert.Equal(3, requests)
+}
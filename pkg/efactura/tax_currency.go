@@ -0,0 +1,88 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// ConvertTaxToRON sets the VAT accounting currency (BT-6) of the invoice to
+// RON and the invoice total VAT amount in RON (BT-111), converting the VAT
+// amount of each VAT breakdown (BT-117) with the given exchange rate (the
+// RON value of one unit of the document currency), like the InvoiceBuilder
+// does. An existing BT-111 is replaced. This is required by BR-RO-030 if the
+// document currency (BT-5) is not RON. An error is returned if the document
+// currency is RON or the invoice has no VAT breakdown.
+func (iv *Invoice) ConvertTaxToRON(rate types.Decimal) error {
+	taxTotal, err := convertTaxTotal(iv.TaxTotal, iv.DocumentCurrencyCode, CurrencyRON, rate)
+	if err != nil {
+		return err
+	}
+	iv.TaxCurrencyCode = CurrencyRON
+	iv.TaxTotal = taxTotal
+	return nil
+}
+
+// ConvertTaxToRON sets the VAT accounting currency (BT-6) of the credit note
+// to RON and the total VAT amount in RON (BT-111) (see
+// Invoice.ConvertTaxToRON).
+func (cn *CreditNote) ConvertTaxToRON(rate types.Decimal) error {
+	taxTotal, err := convertTaxTotal(cn.TaxTotal, cn.DocumentCurrencyCode, CurrencyRON, rate)
+	if err != nil {
+		return err
+	}
+	cn.TaxCurrencyCode = CurrencyRON
+	cn.TaxTotal = taxTotal
+	return nil
+}
+
+// convertTaxTotal returns the tax totals with the total VAT amount in the
+// document currency and the total VAT amount in the tax currency.
+func convertTaxTotal(taxTotals []InvoiceTaxTotal, documentCurrencyID, taxCurrencyID CurrencyCodeType, rate types.Decimal) ([]InvoiceTaxTotal, error) {
+	if documentCurrencyID == "" {
+		return nil, errors.New("document currency code not set")
+	}
+	if documentCurrencyID == taxCurrencyID {
+		return nil, fmt.Errorf("document currency is already %s", taxCurrencyID)
+	}
+	if !rate.IsInitialized() || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid exchange rate %s", rate)
+	}
+
+	var documentTaxTotal *InvoiceTaxTotal
+	for i := range taxTotals {
+		if taxTotals[i].TaxAmount != nil && taxTotals[i].TaxAmount.CurrencyID == documentCurrencyID {
+			documentTaxTotal = &taxTotals[i]
+			break
+		}
+	}
+	if documentTaxTotal == nil || len(documentTaxTotal.TaxSubtotals) == 0 {
+		return nil, errors.New("the invoice has no VAT breakdown")
+	}
+
+	taxAmount := types.Zero
+	for _, subtotal := range documentTaxTotal.TaxSubtotals {
+		taxAmount = taxAmount.Add(subtotal.TaxAmount.Amount.Mul(rate).AsAmount())
+	}
+	return []InvoiceTaxTotal{*documentTaxTotal, {
+		TaxAmount: &AmountWithCurrency{
+			Amount:     taxAmount,
+			CurrencyID: taxCurrencyID,
+		},
+	}}, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestInvoiceConvertTaxToRON(t *testing.T) {
+	assert := assert.New(t)
+
+	rate := types.D(4.9691)
+	newBuilder := func() *InvoiceBuilder {
+		var lines []InvoiceLine
+		for i, percent := range []float64{19, 9, 19} {
+			line, err := NewInvoiceLineBuilder(string(rune('1'+i)), CurrencyEUR).
+				WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(3)).
+				WithGrossPriceAmount(types.D(12.37)).
+				WithItemName("Produs").
+				WithItemTaxCategory(InvoiceLineTaxCategory{
+					TaxScheme: TaxSchemeVAT,
+					ID:        TaxCategoryVATStandardRate,
+					Percent:   types.D(percent),
+				}).
+				Build()
+			if assert.NoError(err) {
+				lines = append(lines, line)
+			}
+		}
+		return NewInvoiceBuilder("test.convert.01").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDocumentCurrencyCode(CurrencyEUR).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithInvoiceLines(lines)
+	}
+
+	expected, err := newBuilder().
+		WithTaxCurrencyCode(CurrencyRON).
+		WithDocumentToTaxCurrencyExchangeRate(rate).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	invoice, err := newBuilder().Build()
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.NoError(invoice.ConvertTaxToRON(rate)) {
+		assert.Equal(CurrencyRON, invoice.TaxCurrencyCode)
+		assertTaxTotalsEqual(t, expected.TaxTotal, invoice.TaxTotal)
+	}
+	// Converting again replaces BT-111.
+	if assert.NoError(invoice.ConvertTaxToRON(types.D(5))) && assert.Len(invoice.TaxTotal, 2) {
+		assert.Equal(CurrencyRON, invoice.TaxTotal[1].TaxAmount.CurrencyID)
+		assert.Equal(invoice.TaxTotal[0].TaxAmount.Amount.Mul(types.D(5)).AsAmount().String(),
+			invoice.TaxTotal[1].TaxAmount.Amount.String())
+	}
+
+	cn := expected.CreditNote()
+	assert.NoError(cn.ConvertTaxToRON(rate))
+	assertTaxTotalsEqual(t, expected.TaxTotal, cn.TaxTotal)
+
+	assert.Error(invoice.ConvertTaxToRON(types.Decimal{}))
+	invoice.DocumentCurrencyCode = CurrencyRON
+	assert.Error(invoice.ConvertTaxToRON(rate))
+	assert.Error((&Invoice{DocumentCurrencyCode: CurrencyEUR}).ConvertTaxToRON(rate))
+}
+
+// assertTaxTotalsEqual compares the total VAT amounts, since the order of the
+// VAT breakdowns is not deterministic.
+func assertTaxTotalsEqual(t *testing.T, expected, actual []InvoiceTaxTotal) {
+	t.Helper()
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+	for i := range expected {
+		assert.Equal(t, *expected[i].TaxAmount, *actual[i].TaxAmount)
+		assert.Len(t, actual[i].TaxSubtotals, len(expected[i].TaxSubtotals))
+	}
+}
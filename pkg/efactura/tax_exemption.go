@@ -0,0 +1,125 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+)
+
+type taxExemptionReasonInfo struct {
+	// reason is the standard Romanian text of the exemption reason (BT-120).
+	reason string
+	// category is the only VAT category code (BT-118) allowed for the
+	// exemption reason code.
+	category TaxCategoryCodeType
+}
+
+// taxExemptionReasons is the catalog of the VATEX exemption reason codes
+// (BT-121).
+var taxExemptionReasons = map[TaxExemptionReasonCodeType]taxExemptionReasonInfo{
+	TaxExemptionCodeVATEX_EU_79_C:    {reason: "Exceptie cf. Art. 79, lit c din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132:     {reason: "Exceptie cf. Art. 132 din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1A:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (a) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1B:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (b) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1C:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (c) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1D:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (d) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1E:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (e) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1F:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (f) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1G:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (g) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1H:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (h) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1I:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (i) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1J:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (j) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1K:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (k) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1L:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (l) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1M:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (m) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1N:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (n) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1O:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (o) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1P:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (p) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_132_1Q:  {reason: "Exceptie cf. Art. 132, alin. 1, lit (q) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143:     {reason: "Exceptie cf. Art. 143 din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1A:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (a) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1B:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (b) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1C:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (c) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1D:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (d) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1E:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (e) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1F:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (f) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1FA: {reason: "Exceptie cf. Art. 143, alin. 1, lit (fa) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1G:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (g) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1H:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (h) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1I:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (i) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1J:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (j) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1K:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (k) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_143_1L:  {reason: "Exceptie cf. Art. 143, alin. 1, lit (l) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148:     {reason: "Exceptie cf. Art. 148 din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_A:   {reason: "Exceptie cf. Art. 148, lit. (a) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_B:   {reason: "Exceptie cf. Art. 148, lit. (b) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_C:   {reason: "Exceptie cf. Art. 148, lit. (c) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_D:   {reason: "Exceptie cf. Art. 148, lit. (d) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_E:   {reason: "Exceptie cf. Art. 148, lit. (e) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_F:   {reason: "Exceptie cf. Art. 148, lit. (f) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_148_G:   {reason: "Exceptie cf. Art. 148, lit. (g) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151:     {reason: "Exceptie cf. Art. 151 din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151_1A:  {reason: "Exceptie cf. Art. 151, alin. 1, lit (a) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151_1AA: {reason: "Exceptie cf. Art. 151, alin. 1, lit (aa) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151_1B:  {reason: "Exceptie cf. Art. 151, alin. 1, lit (b) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151_1C:  {reason: "Exceptie cf. Art. 151, alin. 1, lit (c) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151_1D:  {reason: "Exceptie cf. Art. 151, alin. 1, lit (d) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_151_1E:  {reason: "Exceptie cf. Art. 151, alin. 1, lit (e) din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_309:     {reason: "Exceptie cf. Art. 309 din Directiva 2006/112/EC", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_AE:      {reason: "Taxare inversa", category: TaxCategoryVATReverseCharge},
+	TaxExemptionCodeVATEX_EU_D:       {reason: "Regim special pentru agentiile de turism", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_F:       {reason: "Regim special pentru bunuri second hand", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_G:       {reason: "Export in afara UE", category: TaxCategoryVATNotChargedFreeExportItem},
+	TaxExemptionCodeVATEX_EU_I:       {reason: "Regim special pentru obiecte de arta", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_IC:      {reason: "Livrare intra-comunitara", category: TaxCategoryVATExemptIntraCommunitySupply},
+	TaxExemptionCodeVATEX_EU_J:       {reason: "Regim special pentru obiecte de colectie si antichitati", category: TaxCategoryVATExempt},
+	TaxExemptionCodeVATEX_EU_O:       {reason: "Nu face obiectul TVA", category: TaxCategoryNotSubjectToVAT}}
+
+// IsValid returns true if the code is a known VATEX exemption reason code.
+func (c TaxExemptionReasonCodeType) IsValid() bool {
+	_, ok := taxExemptionReasons[c]
+	return ok
+}
+
+// Reason returns the standard Romanian text of the exemption reason (BT-120)
+// for the code, or false if the code is unknown.
+func (c TaxExemptionReasonCodeType) Reason() (reason string, ok bool) {
+	info, ok := taxExemptionReasons[c]
+	return info.reason, ok
+}
+
+// TaxCategory returns the VAT category code (BT-118) the exemption reason
+// code can be used with, or false if the code is unknown. VATEX-EU-AE,
+// VATEX-EU-IC, VATEX-EU-G and VATEX-EU-O are only allowed with the AE, K, G
+// and O categories respectively, all the other codes are only allowed with
+// the E category.
+func (c TaxExemptionReasonCodeType) TaxCategory() (category TaxCategoryCodeType, ok bool) {
+	info, ok := taxExemptionReasons[c]
+	return info.category, ok
+}
+
+// ValidateTaxExemptionReasonCode checks that code is a known VATEX code that
+// can be used with the given VAT category code.
+func ValidateTaxExemptionReasonCode(category TaxCategoryCodeType, code TaxExemptionReasonCodeType) error {
+	codeCategory, ok := code.TaxCategory()
+	if !ok {
+		return fmt.Errorf("unknown tax exemption reason code %q", code)
+	}
+	if codeCategory != category {
+		return fmt.Errorf("tax exemption reason code %s is only allowed with tax category %s, not %s",
+			code, codeCategory, category)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	perrors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+// Operation identifies a Client call (an ANAF endpoint) in the telemetry.
+type Operation string
+
+// The operations of the Client calls, named after the ANAF endpoints.
+const (
+	OperationUpload                 Operation = "upload"
+	OperationMessageState           Operation = "stareMesaj"
+	OperationMessagesList           Operation = "listaMesajeFactura"
+	OperationMessagesListPagination Operation = "listaMesajePaginatieFactura"
+	OperationDownload               Operation = "descarcare"
+	OperationValidate               Operation = "validare"
+	OperationXMLToPDF               Operation = "transformare"
+	OperationValidateSignature      Operation = "validareSemnatura"
+)
+
+// CallInfo describes a Client call.
+type CallInfo struct {
+	// Operation is the called endpoint.
+	Operation Operation
+	// Method is the HTTP method of the request.
+	Method string
+}
+
+// CallResult is the result of a Client call.
+type CallResult struct {
+	// Duration is the duration of the call, including the wait for the
+	// client-side rate limiters.
+	Duration time.Duration
+	// StatusCode is the HTTP status code of the response, or 0 if no
+	// response was received (eg. a network error).
+	StatusCode int
+	// RequestSize is the size in bytes of the request body, or -1 if
+	// unknown.
+	RequestSize int64
+	// ResponseSize is the size in bytes of the response body, or -1 if
+	// unknown.
+	ResponseSize int64
+	// RateLimitWait is the time the call waited for the client-side rate
+	// limiters (see ClientRateLimit).
+	RateLimitWait time.Duration
+	// LimitExceeded is true if ANAF rejected the call because a limit was
+	// exceeded (see errors.LimitExceededError).
+	LimitExceeded bool
+	// Err is the error returned by the call, if any.
+	Err error
+}
+
+// Instrumentation receives the telemetry of the Client calls, eg. for
+// creating OpenTelemetry spans and recording metrics. StartCall is called
+// before each call (each attempt for the downloads with
+// DownloadOptionRetry). The returned context is used for the call, so it
+// can carry a span that is propagated to the HTTP client. The returned
+// function is called once, when the call is done.
+type Instrumentation interface {
+	StartCall(ctx context.Context, info CallInfo) (context.Context, func(result CallResult))
+}
+
+// InstrumentationFunc is an adapter allowing a function to be used as an
+// Instrumentation.
+type InstrumentationFunc func(ctx context.Context, info CallInfo) (context.Context, func(result CallResult))
+
+// StartCall implements the Instrumentation interface.
+func (f InstrumentationFunc) StartCall(ctx context.Context, info CallInfo) (context.Context, func(result CallResult)) {
+	return f(ctx, info)
+}
+
+// callTelemetry collects the result of a call. All methods are no-ops on a
+// nil *callTelemetry, which is used when the Client has no Instrumentation.
+type callTelemetry struct {
+	end    func(CallResult)
+	start  time.Time
+	result CallResult
+}
+
+type callTelemetryKey struct{}
+
+// startCall starts the telemetry of a call. The returned context must be
+// used for the call.
+func (c *Client) startCall(ctx context.Context, op Operation, method string) (context.Context, *callTelemetry) {
+	if c.instrumentation == nil {
+		return ctx, nil
+	}
+	ctx, end := c.instrumentation.StartCall(ctx, CallInfo{Operation: op, Method: method})
+	call := &callTelemetry{
+		end:    end,
+		start:  time.Now(),
+		result: CallResult{RequestSize: -1, ResponseSize: -1},
+	}
+	return context.WithValue(ctx, callTelemetryKey{}, call), call
+}
+
+// callTelemetryFromContext returns the telemetry of the call of ctx, or nil.
+func callTelemetryFromContext(ctx context.Context) *callTelemetry {
+	call, _ := ctx.Value(callTelemetryKey{}).(*callTelemetry)
+	return call
+}
+
+func (t *callTelemetry) rateLimitWait(d time.Duration) {
+	if t != nil {
+		t.result.RateLimitWait += d
+	}
+}
+
+func (t *callTelemetry) request(req *http.Request) {
+	if t == nil || req == nil {
+		return
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		t.result.RequestSize = 0
+	} else if req.ContentLength > 0 {
+		t.result.RequestSize = req.ContentLength
+	}
+}
+
+func (t *callTelemetry) response(resp *http.Response) {
+	if t == nil || resp == nil {
+		return
+	}
+	t.result.StatusCode = resp.StatusCode
+	if resp.ContentLength >= 0 {
+		t.result.ResponseSize = resp.ContentLength
+	}
+}
+
+func (t *callTelemetry) responseSize(n int) {
+	if t != nil {
+		t.result.ResponseSize = int64(n)
+	}
+}
+
+// finish ends the telemetry of the call with the error returned by the
+// call.
+func (t *callTelemetry) finish(err error) {
+	if t == nil || t.end == nil {
+		return
+	}
+	result := t.result
+	result.Duration = time.Since(t.start)
+	result.Err = err
+
+	var limitErr *perrors.LimitExceededError
+	var errResp *perrors.ErrorResponse
+	if errors.As(err, &limitErr) {
+		result.LimitExceeded = true
+		errResp = limitErr.ErrorResponse
+	} else {
+		errors.As(err, &errResp)
+	}
+	if errResp != nil && errResp.StatusCode != 0 {
+		result.StatusCode = errResp.StatusCode
+	}
+	if err == nil && result.StatusCode == 0 {
+		result.StatusCode = http.StatusOK
+	}
+	t.end(result)
+	t.end = nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+type testInstrumentation struct {
+	mu      sync.Mutex
+	started []efactura.CallInfo
+	results []efactura.CallResult
+}
+
+func (i *testInstrumentation) StartCall(ctx context.Context, info efactura.CallInfo) (context.Context, func(efactura.CallResult)) {
+	i.mu.Lock()
+	i.started = append(i.started, info)
+	i.mu.Unlock()
+	return ctx, func(result efactura.CallResult) {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.results = append(i.results, result)
+	}
+}
+
+func TestClientInstrumentation(t *testing.T) {
+	assert := assert.New(t)
+
+	instrumentation := new(testInstrumentation)
+	client, mux := setupTestClient(t,
+		efactura.ClientInstrumentation(instrumentation),
+		efactura.ClientRateLimit(efactura.RateLimitGroupAll, efactura.RateLimit{
+			Limit: 20,
+			Per:   time.Second,
+			Burst: 1,
+		}))
+	mux.HandleFunc("/FCTEL/rest/upload", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+
+			`<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="42"/>`)
+	})
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/FCTEL/rest/listaMesajeFactura", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"eroare": "S-au facut deja 1000 descarcari de mesaje in cursul zilei",
+			"titlu":  "Lista Mesaje",
+		})
+	})
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		fmt.Fprint(w, "PK-data")
+	})
+
+	ctx := context.Background()
+	_, err := client.UploadInvoice(ctx, efactura.Invoice{ID: "1"}, "123456789")
+	assert.NoError(err)
+	_, err = client.GetMessageState(ctx, 42)
+	assert.Error(err)
+	_, err = client.GetMessagesList(ctx, "123456789", 1, efactura.MessageFilterAll)
+	assert.Error(err)
+	_, err = client.DownloadInvoice(ctx, 3001)
+	assert.NoError(err)
+
+	assert.Equal([]efactura.CallInfo{
+		{Operation: efactura.OperationUpload, Method: http.MethodPost},
+		{Operation: efactura.OperationMessageState, Method: http.MethodGet},
+		{Operation: efactura.OperationMessagesList, Method: http.MethodGet},
+		{Operation: efactura.OperationDownload, Method: http.MethodGet},
+	}, instrumentation.started)
+	if !assert.Len(instrumentation.results, 4) {
+		return
+	}
+
+	upload := instrumentation.results[0]
+	assert.Equal(http.StatusOK, upload.StatusCode)
+	assert.Greater(upload.RequestSize, int64(0))
+	assert.NoError(upload.Err)
+	assert.Greater(upload.Duration, time.Duration(0))
+
+	state := instrumentation.results[1]
+	assert.Equal(http.StatusInternalServerError, state.StatusCode)
+	assert.Equal(int64(0), state.RequestSize)
+	assert.Error(state.Err)
+	// The rate limit of one call every 50ms, without burst.
+	assert.Greater(state.RateLimitWait, 10*time.Millisecond)
+
+	list := instrumentation.results[2]
+	assert.True(list.LimitExceeded)
+	assert.Equal(http.StatusOK, list.StatusCode)
+
+	download := instrumentation.results[3]
+	assert.Equal(http.StatusOK, download.StatusCode)
+	assert.Equal(int64(len("PK-data")), download.ResponseSize)
+}
+
+func TestClientInstrumentationValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	instrumentation := new(testInstrumentation)
+	client, mux := setupTestClient(t, efactura.ClientInstrumentation(instrumentation))
+	mux.HandleFunc("/FCTEL/rest/validare/FACT1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"stare": "ok", "trace_id": "1"})
+	})
+
+	_, err := client.ValidateInvoice(context.Background(), efactura.Invoice{ID: "1"})
+	assert.NoError(err)
+	assert.Equal([]efactura.CallInfo{
+		{Operation: efactura.OperationValidate, Method: http.MethodPost},
+	}, instrumentation.started)
+	if assert.Len(instrumentation.results, 1) {
+		assert.Equal(http.StatusOK, instrumentation.results[0].StatusCode)
+		assert.Greater(instrumentation.results[0].RequestSize, int64(0))
+	}
+}
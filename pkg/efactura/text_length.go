@@ -0,0 +1,426 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextLimit is the maximum length of a text business term of an invoice, as
+// imposed by the CIUS-RO rules.
+type TextLimit struct {
+	// BT is the ID of the business term (eg. "BT-22").
+	BT string
+	// Field is the path of the field, with [] for the repeated fields (eg.
+	// "InvoiceLines[].Item.Name").
+	Field string
+	// MaxLength is the maximum number of characters.
+	MaxLength int
+}
+
+// Rule returns the ID of the CIUS-RO rule checking the length (eg.
+// "BR-RO-L100").
+func (l TextLimit) Rule() string {
+	return fmt.Sprintf("BR-RO-L%03d", l.MaxLength)
+}
+
+// textVisitor is called with the path and a pointer to each value of a text
+// field.
+type textVisitor func(path string, s *string)
+
+type textField struct {
+	TextLimit
+	visit func(iv *Invoice, fn textVisitor)
+}
+
+// addressTextFields returns the text fields of the postal address returned
+// by get, with the BTs of the address line 1, line 2, line 3, city and postal
+// code.
+func addressTextFields(prefix string, bts [5]string, get func(iv *Invoice) *PostalAddress) []textField {
+	field := func(bt, name string, maxLength int, value func(a *PostalAddress) *string) textField {
+		return textField{
+			TextLimit: TextLimit{BT: bt, Field: prefix + "." + name, MaxLength: maxLength},
+			visit: func(iv *Invoice, fn textVisitor) {
+				if a := get(iv); a != nil {
+					fn(prefix+"."+name, value(a))
+				}
+			},
+		}
+	}
+	return []textField{
+		field(bts[0], "Line1", 150, func(a *PostalAddress) *string { return &a.Line1 }),
+		field(bts[1], "Line2", 100, func(a *PostalAddress) *string { return &a.Line2 }),
+		field(bts[2], "Line3", 100, func(a *PostalAddress) *string { return &a.Line3 }),
+		field(bts[3], "CityName", 50, func(a *PostalAddress) *string { return &a.CityName }),
+		field(bts[4], "PostalZone", 20, func(a *PostalAddress) *string { return &a.PostalZone }),
+	}
+}
+
+// singleTextField returns a text field with a single value, returned by get
+// (nil if the field is missing).
+func singleTextField(bt, field string, maxLength int, get func(iv *Invoice) *string) textField {
+	return textField{
+		TextLimit: TextLimit{BT: bt, Field: field, MaxLength: maxLength},
+		visit: func(iv *Invoice, fn textVisitor) {
+			if s := get(iv); s != nil {
+				fn(field, s)
+			}
+		},
+	}
+}
+
+// allowanceChargeReasonField returns the text field of the reason of the
+// document level allowances (charge == false) or charges.
+func allowanceChargeReasonField(bt string, charge bool) textField {
+	return textField{
+		TextLimit: TextLimit{BT: bt, Field: "AllowanceCharges[].AllowanceChargeReason", MaxLength: 100},
+		visit: func(iv *Invoice, fn textVisitor) {
+			for i := range iv.AllowanceCharges {
+				if ac := &iv.AllowanceCharges[i]; ac.ChargeIndicator == charge {
+					fn(fmt.Sprintf("AllowanceCharges[%d].AllowanceChargeReason", i), &ac.AllowanceChargeReason)
+				}
+			}
+		},
+	}
+}
+
+// lineAllowanceChargeReasonField returns the text field of the reason of the
+// line allowances (charge == false) or charges.
+func lineAllowanceChargeReasonField(bt string, charge bool) textField {
+	return textField{
+		TextLimit: TextLimit{BT: bt, Field: "InvoiceLines[].AllowanceCharges[].AllowanceChargeReason", MaxLength: 100},
+		visit: func(iv *Invoice, fn textVisitor) {
+			for i := range iv.InvoiceLines {
+				for j := range iv.InvoiceLines[i].AllowanceCharges {
+					if ac := &iv.InvoiceLines[i].AllowanceCharges[j]; ac.ChargeIndicator == charge {
+						fn(fmt.Sprintf("InvoiceLines[%d].AllowanceCharges[%d].AllowanceChargeReason", i, j), &ac.AllowanceChargeReason)
+					}
+				}
+			}
+		},
+	}
+}
+
+// textFields are the text fields with a maximum length.
+var textFields = concatTextFields(
+	[]textField{
+		singleTextField("BT-1", "ID", 200, func(iv *Invoice) *string { return &iv.ID }),
+		singleTextField("BT-10", "BuyerReference", 200, func(iv *Invoice) *string { return &iv.BuyerReference }),
+		{
+			TextLimit: TextLimit{BT: "BT-22", Field: "Note[].Note", MaxLength: 300},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.Note {
+					fn(fmt.Sprintf("Note[%d].Note", i), &iv.Note[i].Note)
+				}
+			},
+		},
+		singleTextField("BT-20", "PaymentTerms.Note", 100, func(iv *Invoice) *string {
+			if iv.PaymentTerms == nil {
+				return nil
+			}
+			return &iv.PaymentTerms.Note
+		}),
+
+		singleTextField("BT-27", "Supplier.Party.LegalEntity.Name", 200, func(iv *Invoice) *string {
+			return &iv.Supplier.Party.LegalEntity.Name
+		}),
+		singleTextField("BT-28", "Supplier.Party.CommercialName.Name", 200, func(iv *Invoice) *string {
+			if iv.Supplier.Party.CommercialName == nil {
+				return nil
+			}
+			return &iv.Supplier.Party.CommercialName.Name
+		}),
+		singleTextField("BT-33", "Supplier.Party.LegalEntity.CompanyLegalForm", 1000, func(iv *Invoice) *string {
+			return &iv.Supplier.Party.LegalEntity.CompanyLegalForm
+		}),
+	},
+	addressTextFields("Supplier.Party.PostalAddress", [5]string{"BT-35", "BT-36", "BT-162", "BT-37", "BT-38"},
+		func(iv *Invoice) *PostalAddress { return &iv.Supplier.Party.PostalAddress.PostalAddress }),
+	[]textField{
+		singleTextField("BT-41", "Supplier.Party.Contact.Name", 100, func(iv *Invoice) *string {
+			if iv.Supplier.Party.Contact == nil {
+				return nil
+			}
+			return &iv.Supplier.Party.Contact.Name
+		}),
+		singleTextField("BT-42", "Supplier.Party.Contact.Phone", 100, func(iv *Invoice) *string {
+			if iv.Supplier.Party.Contact == nil {
+				return nil
+			}
+			return &iv.Supplier.Party.Contact.Phone
+		}),
+		singleTextField("BT-43", "Supplier.Party.Contact.Email", 100, func(iv *Invoice) *string {
+			if iv.Supplier.Party.Contact == nil {
+				return nil
+			}
+			return &iv.Supplier.Party.Contact.Email
+		}),
+
+		singleTextField("BT-44", "Customer.Party.LegalEntity.Name", 200, func(iv *Invoice) *string {
+			return &iv.Customer.Party.LegalEntity.Name
+		}),
+		singleTextField("BT-45", "Customer.Party.CommercialName.Name", 200, func(iv *Invoice) *string {
+			if iv.Customer.Party.CommercialName == nil {
+				return nil
+			}
+			return &iv.Customer.Party.CommercialName.Name
+		}),
+	},
+	addressTextFields("Customer.Party.PostalAddress", [5]string{"BT-50", "BT-51", "BT-163", "BT-52", "BT-53"},
+		func(iv *Invoice) *PostalAddress { return &iv.Customer.Party.PostalAddress.PostalAddress }),
+	[]textField{
+		singleTextField("BT-56", "Customer.Party.Contact.Name", 100, func(iv *Invoice) *string {
+			if iv.Customer.Party.Contact == nil {
+				return nil
+			}
+			return &iv.Customer.Party.Contact.Name
+		}),
+		singleTextField("BT-57", "Customer.Party.Contact.Phone", 100, func(iv *Invoice) *string {
+			if iv.Customer.Party.Contact == nil {
+				return nil
+			}
+			return &iv.Customer.Party.Contact.Phone
+		}),
+		singleTextField("BT-58", "Customer.Party.Contact.Email", 100, func(iv *Invoice) *string {
+			if iv.Customer.Party.Contact == nil {
+				return nil
+			}
+			return &iv.Customer.Party.Contact.Email
+		}),
+
+		singleTextField("BT-59", "Payee.Name.Name", 200, func(iv *Invoice) *string {
+			if iv.Payee == nil {
+				return nil
+			}
+			return &iv.Payee.Name.Name
+		}),
+		singleTextField("BT-62", "TaxRepresentative.Name.Name", 200, func(iv *Invoice) *string {
+			if iv.TaxRepresentative == nil {
+				return nil
+			}
+			return &iv.TaxRepresentative.Name.Name
+		}),
+	},
+	addressTextFields("TaxRepresentative.PostalAddress", [5]string{"BT-64", "BT-65", "BT-164", "BT-66", "BT-67"},
+		func(iv *Invoice) *PostalAddress {
+			if iv.TaxRepresentative == nil {
+				return nil
+			}
+			return &iv.TaxRepresentative.PostalAddress.PostalAddress
+		}),
+	[]textField{
+		singleTextField("BT-70", "Delivery.DeliveryParty.Name.Name", 200, func(iv *Invoice) *string {
+			if iv.Delivery == nil || iv.Delivery.DeliveryParty == nil || iv.Delivery.DeliveryParty.Name == nil {
+				return nil
+			}
+			return &iv.Delivery.DeliveryParty.Name.Name
+		}),
+	},
+	addressTextFields("Delivery.DeliveryLocation.DeliveryAddress", [5]string{"BT-75", "BT-76", "BT-165", "BT-77", "BT-78"},
+		func(iv *Invoice) *PostalAddress {
+			if iv.Delivery == nil || iv.Delivery.DeliveryLocation == nil || iv.Delivery.DeliveryLocation.DeliveryAddress == nil {
+				return nil
+			}
+			return &iv.Delivery.DeliveryLocation.DeliveryAddress.PostalAddress
+		}),
+	[]textField{
+		{
+			TextLimit: TextLimit{BT: "BT-83", Field: "PaymentMeans[].PaymentID", MaxLength: 140},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.PaymentMeans {
+					fn(fmt.Sprintf("PaymentMeans[%d].PaymentID", i), &iv.PaymentMeans[i].PaymentID)
+				}
+			},
+		},
+		{
+			TextLimit: TextLimit{BT: "BT-85", Field: "PaymentMeans[].PayeeFinancialAccounts[].Name", MaxLength: 200},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.PaymentMeans {
+					for j := range iv.PaymentMeans[i].PayeeFinancialAccounts {
+						fn(fmt.Sprintf("PaymentMeans[%d].PayeeFinancialAccounts[%d].Name", i, j),
+							&iv.PaymentMeans[i].PayeeFinancialAccounts[j].Name)
+					}
+				}
+			},
+		},
+		allowanceChargeReasonField("BT-97", false),
+		allowanceChargeReasonField("BT-104", true),
+		{
+			TextLimit: TextLimit{BT: "BT-120", Field: "TaxTotal[].TaxSubtotals[].TaxCategory.TaxExemptionReason", MaxLength: 100},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.TaxTotal {
+					for j := range iv.TaxTotal[i].TaxSubtotals {
+						fn(fmt.Sprintf("TaxTotal[%d].TaxSubtotals[%d].TaxCategory.TaxExemptionReason", i, j),
+							&iv.TaxTotal[i].TaxSubtotals[j].TaxCategory.TaxExemptionReason)
+					}
+				}
+			},
+		},
+		{
+			TextLimit: TextLimit{BT: "BT-127", Field: "InvoiceLines[].Note", MaxLength: 300},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.InvoiceLines {
+					fn(fmt.Sprintf("InvoiceLines[%d].Note", i), &iv.InvoiceLines[i].Note)
+				}
+			},
+		},
+		lineAllowanceChargeReasonField("BT-139", false),
+		lineAllowanceChargeReasonField("BT-144", true),
+		{
+			TextLimit: TextLimit{BT: "BT-153", Field: "InvoiceLines[].Item.Name", MaxLength: 100},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.InvoiceLines {
+					fn(fmt.Sprintf("InvoiceLines[%d].Item.Name", i), &iv.InvoiceLines[i].Item.Name)
+				}
+			},
+		},
+		{
+			TextLimit: TextLimit{BT: "BT-154", Field: "InvoiceLines[].Item.Description", MaxLength: 200},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.InvoiceLines {
+					fn(fmt.Sprintf("InvoiceLines[%d].Item.Description", i), &iv.InvoiceLines[i].Item.Description)
+				}
+			},
+		},
+		{
+			TextLimit: TextLimit{BT: "BT-160", Field: "InvoiceLines[].Item.AdditionalProperties[].Name", MaxLength: 50},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.InvoiceLines {
+					for j := range iv.InvoiceLines[i].Item.AdditionalProperties {
+						fn(fmt.Sprintf("InvoiceLines[%d].Item.AdditionalProperties[%d].Name", i, j),
+							&iv.InvoiceLines[i].Item.AdditionalProperties[j].Name)
+					}
+				}
+			},
+		},
+		{
+			TextLimit: TextLimit{BT: "BT-161", Field: "InvoiceLines[].Item.AdditionalProperties[].Value", MaxLength: 100},
+			visit: func(iv *Invoice, fn textVisitor) {
+				for i := range iv.InvoiceLines {
+					for j := range iv.InvoiceLines[i].Item.AdditionalProperties {
+						fn(fmt.Sprintf("InvoiceLines[%d].Item.AdditionalProperties[%d].Value", i, j),
+							&iv.InvoiceLines[i].Item.AdditionalProperties[j].Value)
+					}
+				}
+			},
+		},
+	},
+)
+
+func concatTextFields(fields ...[]textField) (result []textField) {
+	for _, f := range fields {
+		result = append(result, f...)
+	}
+	return
+}
+
+// TextLimits returns the maximum lengths of the text business terms of an
+// invoice, checked by ValidateInvoiceOffline and applied by
+// Invoice.TruncateTextFields. The returned slice is a copy and can be
+// modified by the caller.
+func TextLimits() []TextLimit {
+	limits := make([]TextLimit, len(textFields))
+	for i, f := range textFields {
+		limits[i] = f.TextLimit
+	}
+	return limits
+}
+
+// TruncatedField is a text field truncated by Invoice.TruncateTextFields.
+type TruncatedField struct {
+	// Limit is the exceeded limit.
+	Limit TextLimit
+	// Path is the path of the truncated field (eg.
+	// "InvoiceLines[2].Item.Name").
+	Path string
+	// Original is the value of the field before truncating.
+	Original string
+	// Truncated is the value of the field after truncating.
+	Truncated string
+}
+
+// String implements the fmt.Stringer interface.
+func (f TruncatedField) String() string {
+	return fmt.Sprintf("%s (%s) truncated to %d characters: %q -> %q",
+		f.Path, f.Limit.BT, f.Limit.MaxLength, f.Original, f.Truncated)
+}
+
+// TruncationPolicy is the policy for truncating the text fields longer than
+// the maximum length (see Invoice.TruncateTextFields and
+// InvoiceBuilder.WithTruncation).
+type TruncationPolicy struct {
+	// Ellipsis is appended to the truncated texts, within the maximum
+	// length (eg. "..." or "…"). If empty, the texts are only cut.
+	Ellipsis string
+	// OnTruncate is called for each truncated field (optional), eg. to log
+	// a warning.
+	OnTruncate func(TruncatedField)
+}
+
+// TruncateTextFields truncates the text fields of the invoice that are
+// longer than the maximum length (see TextLimits) according to the policy,
+// and returns the list of truncated fields. The lengths are counted in
+// characters (runes), not bytes.
+func (iv *Invoice) TruncateTextFields(policy TruncationPolicy) (truncated []TruncatedField) {
+	for _, f := range textFields {
+		limit := f.TextLimit
+		f.visit(iv, func(path string, s *string) {
+			if utf8.RuneCountInString(*s) <= limit.MaxLength {
+				return
+			}
+			field := TruncatedField{
+				Limit:     limit,
+				Path:      path,
+				Original:  *s,
+				Truncated: truncateText(*s, limit.MaxLength, policy.Ellipsis),
+			}
+			*s = field.Truncated
+			truncated = append(truncated, field)
+			if policy.OnTruncate != nil {
+				policy.OnTruncate(field)
+			}
+		})
+	}
+	return
+}
+
+// truncateText returns the first maxLength characters of s, ending with
+// ellipsis. The trailing spaces before the ellipsis are removed.
+func truncateText(s string, maxLength int, ellipsis string) string {
+	ellipsisLength := utf8.RuneCountInString(ellipsis)
+	if ellipsisLength >= maxLength {
+		ellipsis, ellipsisLength = "", 0
+	}
+	runes := []rune(s)[:maxLength-ellipsisLength]
+	if ellipsis == "" {
+		return string(runes)
+	}
+	return strings.TrimRightFunc(string(runes), unicode.IsSpace) + ellipsis
+}
+
+func (v *invoiceValidator) validateTextLengths(iv Invoice) {
+	for _, f := range textFields {
+		limit := f.TextLimit
+		f.visit(&iv, func(path string, s *string) {
+			if n := utf8.RuneCountInString(*s); n > limit.MaxLength {
+				v.add(limit.Rule(), path, "%s must have at most %d characters, got %d",
+					limit.BT, limit.MaxLength, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestTextLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	limits := TextLimits()
+	assert.Len(limits, len(textFields))
+	bts := make(map[string]TextLimit)
+	for _, l := range limits {
+		_, dup := bts[l.BT]
+		assert.False(dup, "duplicate %s", l.BT)
+		bts[l.BT] = l
+	}
+	assert.Equal(TextLimit{BT: "BT-153", Field: "InvoiceLines[].Item.Name", MaxLength: 100}, bts["BT-153"])
+	assert.Equal("BR-RO-L100", bts["BT-153"].Rule())
+	assert.Equal("BR-RO-L050", bts["BT-37"].Rule())
+	assert.Equal("BR-RO-L1000", bts["BT-33"].Rule())
+
+	// The returned slice is a copy.
+	limits[0].MaxLength = 1
+	assert.NotEqual(1, TextLimits()[0].MaxLength)
+}
+
+func TestTruncateText(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("abcde", truncateText("abcdefgh", 5, ""))
+	assert.Equal("ab...", truncateText("abcdefgh", 5, "..."))
+	assert.Equal("ab…", truncateText("ab cdefgh", 4, "…"))
+	assert.Equal("ăîșțâ", truncateText("ăîșțâăîșțâ", 5, ""))
+	// The ellipsis is dropped if it doesn't fit.
+	assert.Equal("ab", truncateText("abcdefgh", 2, "..."))
+}
+
+func TestInvoiceTextLengths(t *testing.T) {
+	assert := assert.New(t)
+
+	longName := strings.Repeat("Produs ", 20)
+	vat19 := InvoiceLineTaxCategory{
+		TaxScheme: TaxSchemeVAT,
+		ID:        TaxCategoryVATStandardRate,
+		Percent:   types.D(19),
+	}
+	builder := NewInvoiceBuilder("FCT-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 3, 31)).
+		WithDocumentCurrencyCode(CurrencyRON).
+		WithSupplier(getInvoiceSupplierParty()).
+		WithCustomer(getInvoiceCustomerParty()).
+		WithPaymentMeans(InvoicePaymentMeans{
+			PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansCreditTransfer},
+		}).
+		AppendLineBuilders(
+			NewInvoiceLineBuilder("", "").WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(1)).WithGrossPriceAmount(types.D(100)).
+				WithItemName("Produs 1").WithItemTaxCategory(vat19),
+			NewInvoiceLineBuilder("", "").WithUnitCode("H87").
+				WithInvoicedQuantity(types.D(1)).WithGrossPriceAmount(types.D(100)).
+				WithItemName(longName).WithItemTaxCategory(vat19),
+		).
+		WithValidation(true)
+
+	// The long item name fails the validation.
+	_, err := builder.Build()
+	var verrs ValidationErrors
+	if assert.ErrorAs(err, &verrs) && assert.Len(verrs, 1) {
+		assert.Equal("BR-RO-L100", verrs[0].Rule)
+		assert.Equal("InvoiceLines[1].Item.Name", verrs[0].Path)
+		assert.Contains(verrs[0].Message, "BT-153")
+	}
+
+	// With a truncation policy the item name is truncated and reported.
+	var warnings []TruncatedField
+	invoice, err := builder.WithTruncation(&TruncationPolicy{
+		Ellipsis: "...",
+		OnTruncate: func(f TruncatedField) {
+			warnings = append(warnings, f)
+		},
+	}).Build()
+	if assert.NoError(err) {
+		name := invoice.InvoiceLines[1].Item.Name
+		assert.LessOrEqual(len([]rune(name)), 100)
+		assert.True(strings.HasSuffix(name, "Produs..."), name)
+		assert.Equal("Produs 1", invoice.InvoiceLines[0].Item.Name)
+		if assert.Len(warnings, 1) {
+			assert.Equal("BT-153", warnings[0].Limit.BT)
+			assert.Equal("InvoiceLines[1].Item.Name", warnings[0].Path)
+			assert.Equal(longName, warnings[0].Original)
+			assert.Equal(name, warnings[0].Truncated)
+			assert.Contains(warnings[0].String(), "InvoiceLines[1].Item.Name (BT-153) truncated to 100 characters")
+		}
+	}
+
+	// The optional fields and the repeated fields are visited.
+	invoice.Note = []InvoiceNote{{Note: "ok"}, {Note: strings.Repeat("n", 301)}}
+	invoice.Customer.Party.Contact = &InvoiceCustomerContact{Email: strings.Repeat("e", 101)}
+	invoice.AllowanceCharges = []InvoiceDocumentAllowanceCharge{
+		{ChargeIndicator: true, AllowanceChargeReason: strings.Repeat("c", 101)},
+	}
+	verrs = ValidateInvoiceOffline(invoice)
+	var paths []string
+	for _, verr := range verrs {
+		if strings.HasPrefix(verr.Rule, "BR-RO-L") {
+			paths = append(paths, verr.Path)
+		}
+	}
+	assert.Equal([]string{
+		"Note[1].Note",
+		"Customer.Party.Contact.Email",
+		"AllowanceCharges[0].AllowanceChargeReason",
+	}, paths)
+
+	truncated := invoice.TruncateTextFields(TruncationPolicy{})
+	if assert.Len(truncated, 3) {
+		assert.Equal("BT-22", truncated[0].Limit.BT)
+		assert.Equal("BT-58", truncated[1].Limit.BT)
+		assert.Equal("BT-104", truncated[2].Limit.BT)
+	}
+	assert.Equal(strings.Repeat("n", 300), invoice.Note[1].Note)
+	assert.Empty(invoice.TruncateTextFields(TruncationPolicy{}))
+}
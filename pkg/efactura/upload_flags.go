@@ -0,0 +1,132 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UploadFlag is a flag set when uploading an invoice (see UploadOptionFlags).
+// The value is the name of the upload query parameter.
+type UploadFlag string
+
+const (
+	// UploadFlagForeign is set for invoices with a non-Romanian buyer (see
+	// UploadOptionForeign).
+	UploadFlagForeign UploadFlag = "extern"
+	// UploadFlagSelfBilled is set for self-billed invoices (see
+	// UploadOptionSelfBilled).
+	UploadFlagSelfBilled UploadFlag = "autofactura"
+	// UploadFlagEnforcement is set for invoices uploaded by an enforcement
+	// agent (see UploadOptionEnforcement).
+	UploadFlagEnforcement UploadFlag = "executare"
+)
+
+// uploadFlags are all the upload flags, in the order returned by
+// ParseUploadFlags.
+var uploadFlags = []UploadFlag{UploadFlagForeign, UploadFlagSelfBilled, UploadFlagEnforcement}
+
+// regexUploadFlags match the upload flags in the message details, both in the
+// invoice messages (eg. "ca autofactutra in numele cif=") and in the error
+// messages (eg. "tip declarat=AUTOFACTURA").
+var regexUploadFlags = map[UploadFlag]*regexp.Regexp{
+	UploadFlagForeign:     regexp.MustCompile(`(?i)\bextern\b`),
+	UploadFlagSelfBilled:  regexp.MustCompile(`(?i)\bautofact\w*\b`),
+	UploadFlagEnforcement: regexp.MustCompile(`(?i)\bexecutare\b`),
+}
+
+// String implements the fmt.Stringer interface.
+func (f UploadFlag) String() string {
+	return string(f)
+}
+
+// ParseUploadFlags parses the upload flags reflected in the details of a
+// message from the messages list.
+func ParseUploadFlags(details string) (flags []UploadFlag) {
+	for _, flag := range uploadFlags {
+		if regexUploadFlags[flag].MatchString(details) {
+			flags = append(flags, flag)
+		}
+	}
+	return
+}
+
+// GetUploadFlags returns the upload flags reflected in the message details.
+func (m Message) GetUploadFlags() []UploadFlag {
+	return ParseUploadFlags(m.Details)
+}
+
+// HasUploadFlag returns true if the given upload flag is reflected in the
+// message details.
+func (m Message) HasUploadFlag(flag UploadFlag) bool {
+	re, ok := regexUploadFlags[flag]
+	return ok && re.MatchString(m.Details)
+}
+
+// UploadFlagsMismatchError is returned by Message.CheckUploadFlags if the
+// upload flags reflected in the message details are not the expected ones.
+type UploadFlagsMismatchError struct {
+	MessageID string
+	// Missing are the expected flags not found in the message details.
+	Missing []UploadFlag
+	// Unexpected are the flags found in the message details, but not
+	// expected.
+	Unexpected []UploadFlag
+}
+
+func (e *UploadFlagsMismatchError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("missing %s", joinUploadFlags(e.Missing)))
+	}
+	if len(e.Unexpected) > 0 {
+		parts = append(parts, fmt.Sprintf("unexpected %s", joinUploadFlags(e.Unexpected)))
+	}
+	return fmt.Sprintf("message %s: upload flags mismatch: %s", e.MessageID, strings.Join(parts, ", "))
+}
+
+func joinUploadFlags(flags []UploadFlag) string {
+	names := make([]string, len(flags))
+	for i, flag := range flags {
+		names[i] = flag.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// CheckUploadFlags checks that the message was uploaded with exactly the
+// given flags (eg. to detect a self-billed invoice uploaded without the
+// UploadFlagSelfBilled flag). An *UploadFlagsMismatchError is returned if the
+// flags differ.
+func (m Message) CheckUploadFlags(want ...UploadFlag) error {
+	wanted := make(map[UploadFlag]bool, len(want))
+	for _, flag := range want {
+		wanted[flag] = true
+	}
+	mismatch := &UploadFlagsMismatchError{MessageID: m.ID}
+	for _, flag := range uploadFlags {
+		switch has := m.HasUploadFlag(flag); {
+		case wanted[flag] && !has:
+			mismatch.Missing = append(mismatch.Missing, flag)
+		case !wanted[flag] && has:
+			mismatch.Unexpected = append(mismatch.Unexpected, flag)
+		}
+	}
+	if len(mismatch.Missing) > 0 || len(mismatch.Unexpected) > 0 {
+		return mismatch
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+func TestParseUploadFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		details string
+		flags   []efactura.UploadFlag
+	}{
+		{
+			details: "Factura cu id_incarcare=42 emisa de cif_emitent=123456789 pentru cif_beneficiar=987654321",
+		},
+		{
+			details: "Factura cu id_incarcare=42 transmisa de cif=123456789  ca autofactutra in numele cif=987654321",
+			flags:   []efactura.UploadFlag{efactura.UploadFlagSelfBilled},
+		},
+		{
+			details: "Erori de validare identificate la factura de tip declarat=AUTOFACTURA, transmisa cu id_incarcare=42",
+			flags:   []efactura.UploadFlag{efactura.UploadFlagSelfBilled},
+		},
+		{
+			details: "Erori de validare identificate la factura de tip declarat=EXTERN, transmisa cu id_incarcare=42",
+			flags:   []efactura.UploadFlag{efactura.UploadFlagForeign},
+		},
+		{
+			details: "Factura cu id_incarcare=42 emisa de cif_emitent=123456789 transmisa ca executare de cif=987654321",
+			flags:   []efactura.UploadFlag{efactura.UploadFlagEnforcement},
+		},
+		{
+			details: "Erori de validare identificate la factura de tip declarat=EXTERN, tip declarat=AUTOFACTURA, transmisa cu id_incarcare=42",
+			flags:   []efactura.UploadFlag{efactura.UploadFlagForeign, efactura.UploadFlagSelfBilled},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(test.flags, efactura.ParseUploadFlags(test.details), test.details)
+		m := efactura.Message{Details: test.details}
+		assert.Equal(test.flags, m.GetUploadFlags(), test.details)
+		for _, flag := range test.flags {
+			assert.True(m.HasUploadFlag(flag), test.details)
+		}
+	}
+	assert.False(efactura.Message{Details: "externalizat"}.HasUploadFlag(efactura.UploadFlagForeign))
+	assert.False(efactura.Message{Details: "autofactura"}.HasUploadFlag(efactura.UploadFlag("unknown")))
+}
+
+func TestMessageCheckUploadFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	selfBilled := efactura.Message{
+		ID:      "3001",
+		Details: "Factura cu id_incarcare=42 transmisa de cif=123456789  ca autofactutra in numele cif=987654321",
+	}
+	assert.NoError(selfBilled.CheckUploadFlags(efactura.UploadFlagSelfBilled))
+
+	err := selfBilled.CheckUploadFlags()
+	var mismatch *efactura.UploadFlagsMismatchError
+	if assert.True(errors.As(err, &mismatch)) {
+		assert.Equal("3001", mismatch.MessageID)
+		assert.Empty(mismatch.Missing)
+		assert.Equal([]efactura.UploadFlag{efactura.UploadFlagSelfBilled}, mismatch.Unexpected)
+		assert.Equal("message 3001: upload flags mismatch: unexpected autofactura", err.Error())
+	}
+
+	err = selfBilled.CheckUploadFlags(efactura.UploadFlagForeign, efactura.UploadFlagSelfBilled)
+	if assert.True(errors.As(err, &mismatch)) {
+		assert.Equal([]efactura.UploadFlag{efactura.UploadFlagForeign}, mismatch.Missing)
+		assert.Empty(mismatch.Unexpected)
+	}
+}
+
+func TestUploadOptionFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	client, requests := setupTestUploadClient(t)
+	ctx := context.Background()
+
+	_, err := client.UploadInvoice(ctx, efactura.Invoice{ID: "1"}, "123456789",
+		efactura.UploadOptionFlags(efactura.UploadFlagForeign, efactura.UploadFlagEnforcement))
+	assert.NoError(err)
+	_, err = client.UploadInvoice(ctx, efactura.Invoice{ID: "2"}, "123456789",
+		efactura.UploadOptionEnforcement())
+	assert.NoError(err)
+	_, err = client.Upload(ctx, efactura.RaspMessage{UploadIndex: 42, Message: "test"}, "123456789",
+		efactura.UploadOptionEnforcement())
+	assert.Error(err, "upload options must be rejected for messages")
+
+	if assert.Len(*requests, 2) {
+		query := (*requests)[0].Query
+		assert.Equal("DA", query.Get("extern"))
+		assert.Equal("DA", query.Get("executare"))
+		assert.False(query.Has("autofactura"))
+		assert.Equal("DA", (*requests)[1].Query.Get("executare"))
+	}
+}
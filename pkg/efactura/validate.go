@@ -0,0 +1,467 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// ValidationError is a failed validation rule reported by
+// ValidateInvoiceOffline.
+type ValidationError struct {
+	// Rule is the ID of the failed rule, as used by the EN 16931 and CIUS-RO
+	// validation artefacts (eg. "BR-CO-15", "BR-RO-010").
+	Rule string
+	// Path is the path of the offending field (eg.
+	// "InvoiceLines[2].InvoicedQuantity"), or empty if the rule applies to
+	// the whole invoice.
+	Path string
+	// Message is a human readable description of the error.
+	Message string
+	// MessageRO is the description of the error in Romanian. It's empty for
+	// the errors parsed from the ANAF messages (see ParseValidationMessages),
+	// since the Message is already in Romanian.
+	MessageRO string
+}
+
+// Error implements the error interface. The format is similar to the one of
+// the ANAF validation messages, so the rule ID can be extracted the same way.
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("[%s] %s", e.Rule, e.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", e.Rule, e.Path, e.Message)
+}
+
+// ErrorRO is like Error, but uses the Romanian message if available.
+func (e ValidationError) ErrorRO() string {
+	if e.MessageRO == "" {
+		return e.Error()
+	}
+	e.Message = e.MessageRO
+	return e.Error()
+}
+
+// ValidationErrors is the error returned by InvoiceBuilder.Build if the
+// offline validation is enabled (see InvoiceBuilder.WithValidation) and the
+// invoice is not valid.
+type ValidationErrors []ValidationError
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, verr := range e {
+		msgs[i] = verr.Error()
+	}
+	return fmt.Sprintf("invoice validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// ValidationRuleUnidentified is the rule ID of the ValidationErrors parsed
+// from messages without a rule ID (eg. XML schema errors).
+const ValidationRuleUnidentified = "UNIDENTIFIED"
+
+// regexValidationRuleID matches the rule IDs in the ANAF validation
+// messages, eg. "[BR-RO-010]" or "[BR-CO-15]".
+var regexValidationRuleID = regexp.MustCompile(`\[([A-Z][A-Z0-9]*(?:-[A-Z0-9]+)+)\]`)
+
+// ParseValidationMessages parses the messages returned by the ANAF
+// validation and XML-To-PDF APIs (eg. "E: validari globale eroare:
+// [BR-RO-010]-Numărul facturii...") into ValidationErrors, so the remote
+// and the offline validation report the same error objects. For a message
+// with a single rule ID, the Message is the text after the rule ID. A
+// message referencing multiple rules results in a ValidationError with the
+// whole message for each rule, and a message without a rule ID in a
+// ValidationError with the ValidationRuleUnidentified rule. The Path is not
+// reported by ANAF, so it's always empty.
+func ParseValidationMessages(messages ...string) (errs []ValidationError) {
+	for _, message := range messages {
+		message = strings.TrimSpace(message)
+		if message == "" {
+			continue
+		}
+		locs := regexValidationRuleID.FindAllStringSubmatchIndex(message, -1)
+		switch len(locs) {
+		case 0:
+			errs = append(errs, ValidationError{Rule: ValidationRuleUnidentified, Message: message})
+		case 1:
+			loc := locs[0]
+			text := strings.Trim(message[loc[1]:], " -:;")
+			if text == "" {
+				text = message
+			}
+			errs = append(errs, ValidationError{Rule: message[loc[2]:loc[3]], Message: text})
+		default:
+			for _, loc := range locs {
+				errs = append(errs, ValidationError{Rule: message[loc[2]:loc[3]], Message: message})
+			}
+		}
+	}
+	return
+}
+
+// ValidateInvoiceOffline validates the invoice without calling the ANAF
+// validation API, so it can be used in CI pipelines and air-gapped systems
+// and it's not subject to the API rate limits. An empty list means no errors
+// were found.
+//
+// The XSD constraints relevant for the Invoice model (mandatory fields, code
+// lists, decimals) and the core EN 16931 and CIUS-RO Schematron rules are
+// implemented in Go, with the rule IDs of the official validation
+// artefacts. The maximum lengths of the text fields (see TextLimits) are
+// also checked. This is not a full Schematron engine, so an invoice passing the
+// offline validation can still be rejected by ANAF; use Client.ValidateInvoice
+// (or the validationdiff package) for an authoritative answer.
+func ValidateInvoiceOffline(iv Invoice) []ValidationError {
+	v := &invoiceValidator{}
+	v.validateHeader(iv)
+	v.validateParties(iv)
+	v.validatePayment(iv)
+	v.validateAdditionalDocuments(iv)
+	v.validateTaxes(iv)
+	v.validateTotals(iv)
+	v.validateVATBreakdown(iv)
+	v.validateLines(iv)
+	v.validateTextLengths(iv)
+
+	if err := iv.ValidatePrecision(); err != nil {
+		var errs []error
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		} else {
+			errs = []error{err}
+		}
+		for _, er := range errs {
+			v.add("BR-DEC", "", "%v", er)
+		}
+	}
+	return v.errs
+}
+
+// invoiceValidator collects the validation errors for an invoice.
+type invoiceValidator struct {
+	errs []ValidationError
+}
+
+// add adds a validation error. The Romanian message is formatted from the
+// translation of the format in validationMessagesRO, translating the
+// validationTerm arguments as well.
+func (v *invoiceValidator) add(rule, path, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{
+		Rule:      rule,
+		Path:      path,
+		Message:   fmt.Sprintf(format, args...),
+		MessageRO: formatValidationMessageRO(format, args...),
+	})
+}
+
+func (v *invoiceValidator) validateHeader(iv Invoice) {
+	if strings.TrimSpace(iv.ID) == "" {
+		v.add("BR-02", "ID", "the invoice number (BT-1) is missing")
+	} else if !strings.ContainsAny(iv.ID, "0123456789") {
+		v.add("BR-RO-010", "ID", "the invoice number (BT-1) must contain at least one digit")
+	}
+	if !iv.IssueDate.IsInitialized() {
+		v.add("BR-03", "IssueDate", "the invoice issue date (BT-2) is missing")
+	}
+	if iv.InvoiceTypeCode == "" {
+		v.add("BR-04", "InvoiceTypeCode", "the invoice type code (BT-3) is missing")
+	} else if !iv.InvoiceTypeCode.IsValid() {
+		v.add("BR-RO-020", "InvoiceTypeCode", "invalid invoice type code %q", iv.InvoiceTypeCode)
+	}
+	if iv.DocumentCurrencyCode == "" {
+		v.add("BR-05", "DocumentCurrencyCode", "the invoice currency code (BT-5) is missing")
+	} else if !iv.DocumentCurrencyCode.IsValid() {
+		v.add("BR-CL-04", "DocumentCurrencyCode", "invalid currency code %q", iv.DocumentCurrencyCode)
+	}
+	if iv.TaxCurrencyCode != "" && !iv.TaxCurrencyCode.IsValid() {
+		v.add("BR-CL-05", "TaxCurrencyCode", "invalid currency code %q", iv.TaxCurrencyCode)
+	}
+	for i, note := range iv.Note {
+		if note.SubjectCode != "" && !note.SubjectCode.IsValid() {
+			v.add("BR-CL-08", fmt.Sprintf("Note[%d].SubjectCode", i),
+				"invalid invoice note subject code %q", note.SubjectCode)
+		}
+	}
+	if iv.DocumentCurrencyCode != "" && iv.DocumentCurrencyCode != CurrencyRON && iv.TaxCurrencyCode != CurrencyRON {
+		v.add("BR-RO-030", "TaxCurrencyCode",
+			"the VAT accounting currency code (BT-6) must be RON if the invoice currency is %s", iv.DocumentCurrencyCode)
+	}
+}
+
+func (v *invoiceValidator) validateAddress(path string, address PostalAddress, countryRule, subentityRule, sectorRule string) {
+	if address.Country.Code == "" {
+		v.add(countryRule, path+".Country", "the country code is missing")
+		return
+	}
+	if !address.Country.Code.IsValid() {
+		v.add("BR-CL-14", path+".Country", "invalid country code %q", address.Country.Code)
+		return
+	}
+	if address.Country.Code != CountryCodeRO {
+		return
+	}
+	if !address.CountrySubentity.IsValid() {
+		v.add(subentityRule, path+".CountrySubentity",
+			"the country subdivision must be a ISO 3166-2:RO code for a RO address, got %q", address.CountrySubentity)
+		return
+	}
+	if address.CountrySubentity == CountrySubentityRO_B {
+		switch address.CityName {
+		case CityNameROBSector1, CityNameROBSector2, CityNameROBSector3,
+			CityNameROBSector4, CityNameROBSector5, CityNameROBSector6:
+		default:
+			v.add(sectorRule, path+".CityName",
+				"the city name must be SECTOR1 - SECTOR6 for an address in RO-B, got %q", address.CityName)
+		}
+	}
+}
+
+func (v *invoiceValidator) validateParties(iv Invoice) {
+	supplier := iv.Supplier.Party
+	if strings.TrimSpace(supplier.LegalEntity.Name) == "" {
+		v.add("BR-06", "Supplier.Party.LegalEntity.Name", "the seller name (BT-27) is missing")
+	}
+	v.validateAddress("Supplier.Party.PostalAddress", supplier.PostalAddress.PostalAddress,
+		"BR-09", "BR-RO-100", "BR-RO-110")
+
+	customer := iv.Customer.Party
+	if strings.TrimSpace(customer.LegalEntity.Name) == "" {
+		v.add("BR-07", "Customer.Party.LegalEntity.Name", "the buyer name (BT-44) is missing")
+	}
+	v.validateAddress("Customer.Party.PostalAddress", customer.PostalAddress.PostalAddress,
+		"BR-11", "BR-RO-101", "BR-RO-111")
+}
+
+func (v *invoiceValidator) validatePayment(iv Invoice) {
+	for i, pm := range iv.PaymentMeans {
+		if pm.PaymentMeansCode.Code == "" {
+			v.add("BR-49", fmt.Sprintf("PaymentMeans[%d].PaymentMeansCode", i),
+				"the payment means type code (BT-81) is missing")
+		} else if !pm.PaymentMeansCode.Code.IsValid() {
+			v.add("BR-CL-16", fmt.Sprintf("PaymentMeans[%d].PaymentMeansCode", i),
+				"invalid payment means code %q", pm.PaymentMeansCode.Code)
+		}
+		for j, account := range pm.PayeeFinancialAccounts {
+			if strings.TrimSpace(account.ID) == "" {
+				v.add("BR-50", fmt.Sprintf("PaymentMeans[%d].PayeeFinancialAccounts[%d].ID", i, j),
+					"the payment account identifier (BT-84) is missing")
+			}
+		}
+	}
+	payable := iv.LegalMonetaryTotal.PayableAmount.Amount
+	if payable.IsPositive() && iv.DueDate == nil && iv.PaymentTerms == nil {
+		v.add("BR-CO-25", "DueDate",
+			"the due date (BT-9) or the payment terms (BT-20) must be present if the amount due is positive")
+	}
+}
+
+func (v *invoiceValidator) validateAdditionalDocuments(iv Invoice) {
+	for i, ref := range iv.AdditionalDocumentReferences {
+		path := fmt.Sprintf("AdditionalDocumentReferences[%d]", i)
+		if strings.TrimSpace(ref.ID.Value) == "" {
+			v.add("BR-52", path+".ID", "the supporting document reference (BT-122) is missing")
+		}
+		if ref.Attachment == nil || ref.Attachment.EmbeddedDocument == nil {
+			continue
+		}
+		if doc := ref.Attachment.EmbeddedDocument; !doc.MimeCode.IsValid() {
+			v.add("BR-CL-24", path+".Attachment.EmbeddedDocument.MimeCode",
+				"invalid attached document mime code %q", doc.MimeCode)
+		}
+	}
+}
+
+func (v *invoiceValidator) validateTaxCategory(path, rule string, id TaxCategoryCodeType) {
+	if id == "" {
+		v.add(rule, path, "the VAT category code is missing")
+	} else if !id.IsValid() {
+		v.add(rule, path, "invalid VAT category code %q", id)
+	}
+}
+
+func (v *invoiceValidator) validateTaxes(iv Invoice) {
+	for i, ac := range iv.AllowanceCharges {
+		v.validateTaxCategory(fmt.Sprintf("AllowanceCharges[%d].TaxCategory.ID", i), "BR-CL-17", ac.TaxCategory.ID)
+		v.validateAllowanceChargeReasonCode(fmt.Sprintf("AllowanceCharges[%d].AllowanceChargeReasonCode", i),
+			ac.ChargeIndicator, ac.AllowanceChargeReasonCode)
+	}
+	for i, taxTotal := range iv.TaxTotal {
+		for j, subtotal := range taxTotal.TaxSubtotals {
+			path := fmt.Sprintf("TaxTotal[%d].TaxSubtotals[%d].TaxCategory", i, j)
+			category := subtotal.TaxCategory
+			v.validateTaxCategory(path+".ID", "BR-CL-17", category.ID)
+			if category.TaxExemptionReasonCode != "" && !category.TaxExemptionReasonCode.IsValid() {
+				v.add("BR-CL-22", path+".TaxExemptionReasonCode",
+					"invalid tax exemption reason code %q", category.TaxExemptionReasonCode)
+			}
+			if category.ID.ExemptionReasonRequired() &&
+				category.TaxExemptionReason == "" && category.TaxExemptionReasonCode == "" {
+				v.add(exemptionReasonRule(category.ID), path,
+					"the VAT breakdown for category %s must have an exemption reason (BT-120) or code (BT-121)", category.ID)
+			}
+		}
+	}
+}
+
+// validateAllowanceChargeReasonCode checks the reason code of an allowance
+// (BR-CL-19) or of a charge (BR-CL-20).
+func (v *invoiceValidator) validateAllowanceChargeReasonCode(path string, chargeIndicator bool, code string) {
+	if code == "" {
+		return
+	}
+	if chargeIndicator {
+		if !ChargeReasonCodeType(code).IsValid() {
+			v.add("BR-CL-20", path, "invalid charge reason code %q", code)
+		}
+	} else if !AllowanceReasonCodeType(code).IsValid() {
+		v.add("BR-CL-19", path, "invalid allowance reason code %q", code)
+	}
+}
+
+// exemptionReasonRule returns the ID of the rule that requires an exemption
+// reason for the given VAT category.
+func exemptionReasonRule(id TaxCategoryCodeType) string {
+	switch id {
+	case TaxCategoryVATExempt:
+		return "BR-E-10"
+	case TaxCategoryVATReverseCharge:
+		return "BR-AE-10"
+	case TaxCategoryVATExemptIntraCommunitySupply:
+		return "BR-IC-10"
+	case TaxCategoryVATNotChargedFreeExportItem:
+		return "BR-G-10"
+	case TaxCategoryNotSubjectToVAT:
+		return "BR-O-10"
+	}
+	return "BR-CL-22"
+}
+
+func (v *invoiceValidator) validateTotals(iv Invoice) {
+	total := iv.LegalMonetaryTotal
+	amount := func(a *AmountWithCurrency) types.Decimal {
+		if a == nil {
+			return types.Zero
+		}
+		return a.Amount.AsAmount()
+	}
+	checkSum := func(rule, path string, expected, actual types.Decimal, what validationTerm) {
+		if !expected.AsAmount().Equal(actual.AsAmount()) {
+			v.add(rule, path, "%s is %s, expected %s", what, actual.AsAmount().StringFixed(2), expected.AsAmount().StringFixed(2))
+		}
+	}
+
+	linesTotal := types.Zero
+	for _, line := range iv.InvoiceLines {
+		linesTotal = linesTotal.Add(amount(&line.LineExtensionAmount))
+	}
+	allowanceTotal, chargeTotal := types.Zero, types.Zero
+	for _, ac := range iv.AllowanceCharges {
+		if ac.ChargeIndicator {
+			chargeTotal = chargeTotal.Add(amount(&ac.Amount))
+		} else {
+			allowanceTotal = allowanceTotal.Add(amount(&ac.Amount))
+		}
+	}
+
+	checkSum("BR-CO-10", "LegalMonetaryTotal.LineExtensionAmount", linesTotal,
+		amount(&total.LineExtensionAmount), "the sum of invoice line net amounts (BT-106)")
+	checkSum("BR-CO-11", "LegalMonetaryTotal.AllowanceTotalAmount", allowanceTotal,
+		amount(total.AllowanceTotalAmount), "the sum of allowances on document level (BT-107)")
+	checkSum("BR-CO-12", "LegalMonetaryTotal.ChargeTotalAmount", chargeTotal,
+		amount(total.ChargeTotalAmount), "the sum of charges on document level (BT-108)")
+	checkSum("BR-CO-13", "LegalMonetaryTotal.TaxExclusiveAmount",
+		amount(&total.LineExtensionAmount).Sub(amount(total.AllowanceTotalAmount)).Add(amount(total.ChargeTotalAmount)),
+		amount(&total.TaxExclusiveAmount), "the invoice total amount without VAT (BT-109)")
+
+	// The VAT total in the invoice currency (BT-110).
+	vatTotal := documentVATTotal(iv)
+	vatAmount := types.Zero
+	if vatTotal != nil {
+		vatAmount = amount(vatTotal.TaxAmount)
+		subtotalsSum := types.Zero
+		for _, subtotal := range vatTotal.TaxSubtotals {
+			subtotalsSum = subtotalsSum.Add(amount(&subtotal.TaxAmount))
+		}
+		checkSum("BR-CO-14", "TaxTotal.TaxAmount", subtotalsSum, vatAmount, "the invoice total VAT amount (BT-110)")
+	} else if len(iv.InvoiceLines) > 0 {
+		v.add("BR-CO-18", "TaxTotal", "the invoice must have at least one VAT breakdown (BG-23)")
+	}
+	checkSum("BR-CO-15", "LegalMonetaryTotal.TaxInclusiveAmount",
+		amount(&total.TaxExclusiveAmount).Add(vatAmount),
+		amount(&total.TaxInclusiveAmount), "the invoice total amount with VAT (BT-112)")
+	// The terms of the amount due for payment are reported, since the most
+	// common cause of BR-CO-16 is a prepaid amount (BT-113) not subtracted.
+	taxInclusive, prepaid, rounding := amount(&total.TaxInclusiveAmount), amount(total.PrepaidAmount), amount(total.PayableRoundingAmount)
+	if expected, actual := taxInclusive.Sub(prepaid).Add(rounding), amount(&total.PayableAmount); !expected.Equal(actual) {
+		v.add("BR-CO-16", "LegalMonetaryTotal.PayableAmount",
+			"the amount due for payment (BT-115) is %s, expected %s = %s (BT-112) - %s (BT-113) + %s (BT-114)",
+			actual.StringFixed(2), expected.StringFixed(2), taxInclusive.StringFixed(2),
+			prepaid.StringFixed(2), rounding.StringFixed(2))
+	}
+}
+
+func (v *invoiceValidator) validateLines(iv Invoice) {
+	if len(iv.InvoiceLines) == 0 {
+		v.add("BR-16", "InvoiceLines", "the invoice must have at least one invoice line (BG-25)")
+	}
+	ids := make(map[string]bool)
+	for i, line := range iv.InvoiceLines {
+		path := fmt.Sprintf("InvoiceLines[%d]", i)
+		if strings.TrimSpace(line.ID) == "" {
+			v.add("BR-21", path+".ID", "the invoice line identifier (BT-126) is missing")
+		} else if ids[line.ID] {
+			v.add("BR-21", path+".ID", "duplicate invoice line identifier %q", line.ID)
+		}
+		ids[line.ID] = true
+		if !line.InvoicedQuantity.Quantity.IsInitialized() {
+			v.add("BR-22", path+".InvoicedQuantity", "the invoiced quantity (BT-129) is missing")
+		}
+		if line.InvoicedQuantity.UnitCode == "" {
+			v.add("BR-23", path+".InvoicedQuantity", "the invoiced quantity unit of measure (BT-130) is missing")
+		}
+		if !line.LineExtensionAmount.Amount.IsInitialized() {
+			v.add("BR-24", path+".LineExtensionAmount", "the invoice line net amount (BT-131) is missing")
+		}
+		if strings.TrimSpace(line.Item.Name) == "" {
+			v.add("BR-25", path+".Item.Name", "the item name (BT-153) is missing")
+		}
+		if !line.Price.PriceAmount.Amount.IsInitialized() {
+			v.add("BR-26", path+".Price.PriceAmount", "the item net price (BT-146) is missing")
+		} else if line.Price.PriceAmount.Amount.IsNegative() {
+			v.add("BR-27", path+".Price.PriceAmount", "the item net price (BT-146) must not be negative")
+		}
+		if ac := line.Price.AllowanceCharge; ac != nil && ac.BaseAmount.Amount.IsNegative() {
+			v.add("BR-28", path+".Price.AllowanceCharge.BaseAmount", "the item gross price (BT-148) must not be negative")
+		}
+		if origin := line.Item.OriginCountry; origin != nil && !origin.Code.IsValid() {
+			v.add("BR-CL-15", path+".Item.OriginCountry", "invalid country code %q", origin.Code)
+		}
+		for j, property := range line.Item.AdditionalProperties {
+			if strings.TrimSpace(property.Name) == "" || strings.TrimSpace(property.Value) == "" {
+				v.add("BR-54", fmt.Sprintf("%s.Item.AdditionalProperties[%d]", path, j),
+					"the item attribute name (BT-160) and value (BT-161) must be present")
+			}
+		}
+		v.validateTaxCategory(path+".Item.TaxCategory.ID", "BR-CL-18", line.Item.TaxCategory.ID)
+		for j, ac := range line.AllowanceCharges {
+			v.validateAllowanceChargeReasonCode(fmt.Sprintf("%s.AllowanceCharges[%d].AllowanceChargeReasonCode", path, j),
+				ac.ChargeIndicator, ac.AllowanceChargeReasonCode)
+		}
+	}
+}
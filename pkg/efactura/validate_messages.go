@@ -0,0 +1,117 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+)
+
+// validationTerm is a business term used as an argument of a validation
+// message (eg. "the invoice total amount with VAT (BT-112)"), translated
+// in the Romanian message.
+type validationTerm string
+
+// validationMessagesRO are the Romanian translations of the formats and the
+// terms of the offline validation messages, using the terms of the CIUS-RO
+// specification. The formats without a translation are used as they are.
+var validationMessagesRO = map[string]string{
+	// Header
+	"the invoice number (BT-1) is missing":                                              "numărul facturii (BT-1) lipsește",
+	"the invoice number (BT-1) must contain at least one digit":                         "numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric",
+	"the invoice issue date (BT-2) is missing":                                          "data emiterii facturii (BT-2) lipsește",
+	"the invoice type code (BT-3) is missing":                                           "codul tipului facturii (BT-3) lipsește",
+	"invalid invoice type code %q":                                                      "cod invalid al tipului facturii %q",
+	"the invoice currency code (BT-5) is missing":                                       "codul monedei facturii (BT-5) lipsește",
+	"invalid invoice note subject code %q":                                              "cod invalid al subiectului comentariului %q",
+	"invalid currency code %q":                                                          "cod invalid de monedă %q",
+	"the VAT accounting currency code (BT-6) must be RON if the invoice currency is %s": "codul monedei de contabilizare a TVA (BT-6) trebuie să fie RON dacă moneda facturii este %s",
+
+	// Parties
+	"the seller name (BT-27) is missing": "numele vânzătorului (BT-27) lipsește",
+	"the buyer name (BT-44) is missing":  "numele cumpărătorului (BT-44) lipsește",
+	"the country code is missing":        "codul țării lipsește",
+	"invalid country code %q":            "cod invalid de țară %q",
+	"the country subdivision must be a ISO 3166-2:RO code for a RO address, got %q": "subdiviziunea țării trebuie să fie un cod ISO 3166-2:RO pentru o adresă din RO, nu %q",
+	"the city name must be SECTOR1 - SECTOR6 for an address in RO-B, got %q":        "numele localității trebuie să fie SECTOR1 - SECTOR6 pentru o adresă din RO-B, nu %q",
+
+	// Payment
+	"the payment means type code (BT-81) is missing":                                                 "codul tipului instrumentului de plată (BT-81) lipsește",
+	"invalid payment means code %q":                                                                  "cod invalid al instrumentului de plată %q",
+	"the payment account identifier (BT-84) is missing":                                              "identificatorul contului de plată (BT-84) lipsește",
+	"the due date (BT-9) or the payment terms (BT-20) must be present if the amount due is positive": "data scadentă (BT-9) sau termenii de plată (BT-20) trebuie să fie prezenți dacă suma de plată este pozitivă",
+
+	// Additional documents
+	"the supporting document reference (BT-122) is missing": "referința documentului justificativ (BT-122) lipsește",
+	"invalid attached document mime code %q":                "cod MIME invalid al documentului atașat %q",
+
+	// Taxes
+	"the VAT category code is missing":     "codul categoriei de TVA lipsește",
+	"invalid VAT category code %q":         "cod invalid al categoriei de TVA %q",
+	"invalid allowance reason code %q":     "cod invalid al motivului deducerii %q",
+	"invalid charge reason code %q":        "cod invalid al motivului taxei suplimentare %q",
+	"invalid tax exemption reason code %q": "cod invalid al motivului scutirii de TVA %q",
+	"the VAT breakdown for category %s must have an exemption reason (BT-120) or code (BT-121)":     "detalierea TVA pentru categoria %s trebuie să aibă motivul scutirii (BT-120) sau codul motivului scutirii (BT-121)",
+	"the VAT rate for category %s must be greater than zero":                                        "cota TVA pentru categoria %s trebuie să fie mai mare decât zero",
+	"the VAT rate for category %s must be 0, got %s":                                                "cota TVA pentru categoria %s trebuie să fie 0, nu %s",
+	"the VAT category taxable amount (BT-116) for category %s and rate %s%% is %s, expected %s":     "baza de calcul pentru categoria de TVA (BT-116) %s cu cota %s%% este %s, în loc de %s",
+	"the VAT category tax amount (BT-117) for category %s and rate %s%% is %s, expected %s":         "valoarea TVA pentru categoria de TVA (BT-117) %s cu cota %s%% este %s, în loc de %s",
+	"the VAT category tax amount (BT-117) for category %s must be 0, got %s":                        "valoarea TVA pentru categoria de TVA (BT-117) %s trebuie să fie 0, nu %s",
+	"missing VAT breakdown for category %s and rate %s%%, expected a taxable amount (BT-116) of %s": "lipsește detalierea TVA pentru categoria %s cu cota %s%%, cu baza de calcul (BT-116) %s",
+	"the invoice must have at least one VAT breakdown (BG-23)":                                      "factura trebuie să aibă cel puțin o detaliere a TVA (BG-23)",
+
+	// Totals
+	"%s is %s, expected %s":                            "%s este %s, în loc de %s",
+	"the sum of invoice line net amounts (BT-106)":     "suma valorilor nete ale liniilor facturii (BT-106)",
+	"the sum of allowances on document level (BT-107)": "suma deducerilor la nivelul documentului (BT-107)",
+	"the sum of charges on document level (BT-108)":    "suma taxelor suplimentare la nivelul documentului (BT-108)",
+	"the invoice total amount without VAT (BT-109)":    "valoarea totală a facturii fără TVA (BT-109)",
+	"the invoice total VAT amount (BT-110)":            "valoarea totală a TVA a facturii (BT-110)",
+	"the invoice total amount with VAT (BT-112)":       "valoarea totală a facturii cu TVA (BT-112)",
+
+	"the amount due for payment (BT-115) is %s, expected %s = %s (BT-112) - %s (BT-113) + %s (BT-114)": "suma de plată (BT-115) este %s, în loc de %s = %s (BT-112) - %s (BT-113) + %s (BT-114)",
+
+	// Lines
+	"the invoice must have at least one invoice line (BG-25)":   "factura trebuie să aibă cel puțin o linie (BG-25)",
+	"the invoice line identifier (BT-126) is missing":           "identificatorul liniei facturii (BT-126) lipsește",
+	"duplicate invoice line identifier %q":                      "identificator duplicat al liniei facturii %q",
+	"the invoiced quantity (BT-129) is missing":                 "cantitatea facturată (BT-129) lipsește",
+	"the invoiced quantity unit of measure (BT-130) is missing": "unitatea de măsură a cantității facturate (BT-130) lipsește",
+	"the invoice line net amount (BT-131) is missing":           "valoarea netă a liniei facturii (BT-131) lipsește",
+	"the item name (BT-153) is missing":                         "numele articolului (BT-153) lipsește",
+	"the item net price (BT-146) is missing":                    "prețul net al articolului (BT-146) lipsește",
+	"the item net price (BT-146) must not be negative":          "prețul net al articolului (BT-146) nu trebuie să fie negativ",
+	"the item gross price (BT-148) must not be negative":        "prețul brut al articolului (BT-148) nu trebuie să fie negativ",
+
+	// Text lengths
+	"%s must have at most %d characters, got %d": "%s trebuie să aibă cel mult %d caractere, nu %d",
+}
+
+// formatValidationMessageRO formats the Romanian translation of the given
+// validation message format.
+func formatValidationMessageRO(format string, args ...any) string {
+	if ro, ok := validationMessagesRO[format]; ok {
+		format = ro
+	}
+	roArgs := make([]any, len(args))
+	for i, arg := range args {
+		if term, ok := arg.(validationTerm); ok {
+			if ro, ok := validationMessagesRO[string(term)]; ok {
+				arg = ro
+			}
+		}
+		roArgs[i] = arg
+	}
+	return fmt.Sprintf(format, roArgs...)
+}
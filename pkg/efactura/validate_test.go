@@ -0,0 +1,196 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func validationRules(errs []ValidationError) (rules []string) {
+	for _, err := range errs {
+		rules = append(rules, err.Rule)
+	}
+	return
+}
+
+func TestValidateInvoiceOffline(t *testing.T) {
+	assert := assert.New(t)
+
+	line, err := NewInvoiceLineBuilder("1", CurrencyRON).
+		WithUnitCode("H87").
+		WithInvoicedQuantity(types.D(2)).
+		WithGrossPriceAmount(types.D(50)).
+		WithItemName("Produs").
+		WithItemTaxCategory(InvoiceLineTaxCategory{
+			TaxScheme: TaxSchemeVAT,
+			ID:        TaxCategoryVATStandardRate,
+			Percent:   types.D(19),
+		}).
+		Build()
+	if !assert.NoError(err) {
+		return
+	}
+	build := func() Invoice {
+		invoice, err := NewInvoiceBuilder("FCT-1").
+			WithIssueDate(types.MakeDate(2024, 3, 1)).
+			WithDueDate(types.MakeDate(2024, 3, 31)).
+			WithInvoiceTypeCode(InvoiceTypeCommercialInvoice).
+			WithDocumentCurrencyCode(CurrencyRON).
+			WithSupplier(getInvoiceSupplierParty()).
+			WithCustomer(getInvoiceCustomerParty()).
+			WithPaymentMeans(InvoicePaymentMeans{
+				PaymentMeansCode: PaymentMeansCode{Code: PaymentMeansCreditTransfer},
+			}).
+			WithInvoiceLines([]InvoiceLine{line}).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return invoice
+	}
+
+	assert.Empty(ValidateInvoiceOffline(build()))
+
+	tests := []struct {
+		name   string
+		modify func(iv *Invoice)
+		rules  []string
+	}{
+		{"missing id", func(iv *Invoice) { iv.ID = "" }, []string{"BR-02"}},
+		{"id without digits", func(iv *Invoice) { iv.ID = "FCT" }, []string{"BR-RO-010"}},
+		{"invalid type code", func(iv *Invoice) { iv.InvoiceTypeCode = "325" }, []string{"BR-RO-020"}},
+		{"foreign currency without RON", func(iv *Invoice) {
+			iv.DocumentCurrencyCode, iv.TaxTotal[0].TaxAmount.CurrencyID = CurrencyEUR, CurrencyEUR
+		}, []string{"BR-RO-030"}},
+		{"invalid currency", func(iv *Invoice) {
+			iv.DocumentCurrencyCode, iv.TaxCurrencyCode = "XYZ", CurrencyRON
+			iv.TaxTotal[0].TaxAmount.CurrencyID = "XYZ"
+		}, []string{"BR-CL-04"}},
+		{"invalid note subject code", func(iv *Invoice) {
+			iv.Note = []InvoiceNote{{SubjectCode: "aai", Note: "Livrare partiala"}}
+		}, []string{"BR-CL-08"}},
+		{"missing seller name", func(iv *Invoice) { iv.Supplier.Party.LegalEntity.Name = "" }, []string{"BR-06"}},
+		{"invalid county", func(iv *Invoice) { iv.Supplier.Party.PostalAddress.CountrySubentity = "B" }, []string{"BR-RO-100"}},
+		{"invalid sector", func(iv *Invoice) { iv.Customer.Party.PostalAddress.CityName = "Bucuresti" }, []string{"BR-RO-111"}},
+		{"invalid country", func(iv *Invoice) { iv.Customer.Party.PostalAddress.Country.Code = "XX" }, []string{"BR-CL-14"}},
+		{"invalid payment means", func(iv *Invoice) { iv.PaymentMeans[0].PaymentMeansCode.Code = "999" }, []string{"BR-CL-16"}},
+		{"invalid attachment", func(iv *Invoice) {
+			iv.AdditionalDocumentReferences = []InvoiceAdditionalDocumentReference{
+				MakeSupportingDocument("", "Pontaj", []byte("ore"), "text/plain", "pontaj.txt"),
+			}
+		}, []string{"BR-52", "BR-CL-24"}},
+		{"no due date", func(iv *Invoice) { iv.DueDate = nil }, []string{"BR-CO-25"}},
+		{"wrong line total", func(iv *Invoice) {
+			iv.LegalMonetaryTotal.LineExtensionAmount.Amount = types.D(99)
+		}, []string{"BR-CO-10", "BR-CO-13"}},
+		{"wrong payable amount", func(iv *Invoice) {
+			iv.LegalMonetaryTotal.PayableAmount.Amount = types.D(100)
+		}, []string{"BR-CO-16"}},
+		{"wrong vat total", func(iv *Invoice) {
+			iv.TaxTotal[0].TaxAmount.Amount = types.D(20)
+		}, []string{"BR-CO-14", "BR-CO-15"}},
+		{"no lines", func(iv *Invoice) { iv.InvoiceLines = nil }, []string{"BR-CO-10", "BR-S-08", "BR-16"}},
+		{"invalid allowance reason code", func(iv *Invoice) {
+			iv.InvoiceLines[0].AllowanceCharges = []InvoiceLineAllowanceCharge{{
+				Amount:                    AmountWithCurrency{Amount: types.Zero, CurrencyID: CurrencyRON},
+				AllowanceChargeReasonCode: string(ChargeReasonCodeFreightService),
+			}}
+		}, []string{"BR-CL-19"}},
+		{"missing item name", func(iv *Invoice) { iv.InvoiceLines[0].Item.Name = "" }, []string{"BR-25"}},
+		{"invalid line tax category", func(iv *Invoice) { iv.InvoiceLines[0].Item.TaxCategory.ID = "X" }, []string{"BR-CL-18"}},
+		{"invalid item attributes", func(iv *Invoice) {
+			iv.InvoiceLines[0].Item.OriginCountry = &Country{Code: "XX"}
+			iv.InvoiceLines[0].Item.AdditionalProperties = []InvoiceItemProperty{
+				MakeInvoiceItemProperty("Culoare", "Roșu"),
+				MakeInvoiceItemProperty("Mărime", ""),
+			}
+		}, []string{"BR-CL-15", "BR-54"}},
+		{"too many decimals", func(iv *Invoice) {
+			iv.LegalMonetaryTotal.PayableAmount.Amount = types.D(119.001)
+		}, []string{"BR-DEC"}},
+		{"missing exemption reason", func(iv *Invoice) {
+			iv.TaxTotal[0].TaxSubtotals[0].TaxCategory.ID = TaxCategoryVATExempt
+		}, []string{"BR-E-10", "BR-E-08", "BR-E-09", "BR-S-08"}},
+		{"wrong vat breakdown taxable amount", func(iv *Invoice) {
+			iv.TaxTotal[0].TaxSubtotals[0].TaxableAmount.Amount = types.D(90)
+		}, []string{"BR-S-08", "BR-S-09"}},
+		{"wrong vat breakdown tax amount", func(iv *Invoice) {
+			iv.TaxTotal[0].TaxSubtotals[0].TaxAmount.Amount = types.D(18)
+		}, []string{"BR-CO-14", "BR-S-09"}},
+		{"zero standard rate", func(iv *Invoice) {
+			iv.InvoiceLines[0].Item.TaxCategory.Percent = types.Zero
+		}, []string{"BR-S-05", "BR-S-08", "BR-S-08"}},
+		{"zero rate with percent", func(iv *Invoice) {
+			iv.AllowanceCharges = append(iv.AllowanceCharges, InvoiceDocumentAllowanceCharge{
+				ChargeIndicator: true,
+				Amount:          AmountWithCurrency{Amount: types.D(10), CurrencyID: CurrencyRON},
+				TaxCategory: InvoiceTaxCategory{
+					ID:      TaxCategoryVATZeroRate,
+					Percent: types.D(5),
+				},
+			})
+		}, []string{"BR-CO-12", "BR-Z-07", "BR-Z-08"}},
+	}
+	for _, test := range tests {
+		invoice := build()
+		test.modify(&invoice)
+		errs := ValidateInvoiceOffline(invoice)
+		assert.Equal(test.rules, validationRules(errs), test.name)
+	}
+
+	errs := ValidateInvoiceOffline(Invoice{})
+	assert.Subset(validationRules(errs), []string{"BR-02", "BR-03", "BR-04", "BR-05", "BR-06", "BR-07", "BR-09", "BR-11", "BR-16"})
+	assert.Equal("[BR-02] ID: the invoice number (BT-1) is missing", errs[0].Error())
+	assert.Equal("[BR-02] ID: numărul facturii (BT-1) lipsește", errs[0].ErrorRO())
+
+	invoice := build()
+	invoice.TaxTotal[0].TaxSubtotals[0].TaxAmount.Amount = types.D(18)
+	errs = ValidateInvoiceOffline(invoice)
+	if assert.Len(errs, 2) {
+		assert.Equal("the invoice total VAT amount (BT-110) is 19.00, expected 18.00", errs[0].Message)
+		assert.Equal("valoarea totală a TVA a facturii (BT-110) este 19.00, în loc de 18.00", errs[0].MessageRO)
+		assert.Equal("the VAT category tax amount (BT-117) for category S and rate 19% is 18.00, expected 19.00", errs[1].Message)
+		assert.Equal("valoarea TVA pentru categoria de TVA (BT-117) S cu cota 19% este 18.00, în loc de 19.00", errs[1].MessageRO)
+	}
+}
+
+func TestParseValidationMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	errs := ParseValidationMessages(
+		"E: validari globale  eroare:  [BR-RO-010]-Numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric; ",
+		"E: fisier xml eroare: cvc-complex-type.2.4.a: Invalid content was found starting with element 'cbc:Note'.",
+		"  ",
+		"[BR-CO-10] [BR-CO-13] Totalurile nu corespund",
+	)
+	if !assert.Len(errs, 4) {
+		return
+	}
+	assert.Equal(ValidationError{
+		Rule:    "BR-RO-010",
+		Message: "Numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric",
+	}, errs[0])
+	assert.Equal(ValidationRuleUnidentified, errs[1].Rule)
+	assert.Contains(errs[1].Message, "cvc-complex-type.2.4.a")
+	assert.Equal([]string{"BR-CO-10", "BR-CO-13"}, validationRules(errs[2:]))
+	assert.Equal("[BR-CO-10] [BR-CO-13] Totalurile nu corespund", errs[3].Message)
+	assert.Equal("[BR-RO-010] Numărul facturii (BT-1) trebuie să conțină cel puțin un caracter numeric", errs[0].Error())
+
+	assert.Nil(ParseValidationMessages())
+}
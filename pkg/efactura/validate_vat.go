@@ -0,0 +1,188 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"fmt"
+
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// vatRulePrefix returns the prefix of the EN 16931 rules specific to the
+// given VAT category (eg. "BR-S" for the rules BR-S-01 - BR-S-10), or an
+// empty string if the category has no specific rules checked offline.
+func vatRulePrefix(id TaxCategoryCodeType) string {
+	switch id {
+	case TaxCategoryVATStandardRate:
+		return "BR-S"
+	case TaxCategoryVATZeroRate:
+		return "BR-Z"
+	case TaxCategoryVATExempt:
+		return "BR-E"
+	case TaxCategoryVATReverseCharge:
+		return "BR-AE"
+	case TaxCategoryVATExemptIntraCommunitySupply:
+		return "BR-IC"
+	case TaxCategoryVATNotChargedFreeExportItem:
+		return "BR-G"
+	case TaxCategoryNotSubjectToVAT:
+		return "BR-O"
+	}
+	return ""
+}
+
+// vatBreakdownKey identifies a VAT breakdown (BG-23) by the VAT category code
+// and the VAT rate.
+type vatBreakdownKey struct {
+	id      TaxCategoryCodeType
+	percent string
+}
+
+func makeVATBreakdownKey(id TaxCategoryCodeType, percent types.Decimal) vatBreakdownKey {
+	if id == TaxCategoryNotSubjectToVAT {
+		// Not subject to VAT (O) has no rate.
+		percent = types.Zero
+	}
+	return vatBreakdownKey{id: id, percent: percent.String()}
+}
+
+// documentVATTotal returns the TaxTotal with the VAT total in the invoice
+// currency (BT-110), or nil if missing.
+func documentVATTotal(iv Invoice) *InvoiceTaxTotal {
+	for i := range iv.TaxTotal {
+		taxTotal := &iv.TaxTotal[i]
+		if taxTotal.TaxAmount != nil && (taxTotal.TaxAmount.CurrencyID == "" ||
+			taxTotal.TaxAmount.CurrencyID == iv.DocumentCurrencyCode) {
+			return taxTotal
+		}
+	}
+	return nil
+}
+
+// validateVATRate checks the VAT rate of an invoice line, document level
+// allowance or document level charge against its VAT category (BR-*-05,
+// BR-*-06, BR-*-07).
+func (v *invoiceValidator) validateVATRate(rule, path string, id TaxCategoryCodeType, percent types.Decimal) {
+	switch id {
+	case TaxCategoryVATStandardRate:
+		if !percent.IsPositive() {
+			v.add(rule, path, "the VAT rate for category %s must be greater than zero", id)
+		}
+	case TaxCategoryVATZeroRate, TaxCategoryVATExempt, TaxCategoryVATReverseCharge,
+		TaxCategoryVATExemptIntraCommunitySupply, TaxCategoryVATNotChargedFreeExportItem:
+		if !percent.IsZero() {
+			v.add(rule, path, "the VAT rate for category %s must be 0, got %s", id, percent.String())
+		}
+	}
+}
+
+// validateVATBreakdown checks the consistency of the VAT breakdown (BG-23)
+// with the invoice lines and the document level allowances and charges: the
+// VAT rates of each category (BR-*-05, BR-*-06, BR-*-07), the taxable amount
+// of each category and rate (BR-*-08) and the VAT amount of each category
+// (BR-*-09).
+func (v *invoiceValidator) validateVATBreakdown(iv Invoice) {
+	var keys []vatBreakdownKey
+	taxable := make(map[vatBreakdownKey]types.Decimal)
+	// The taxable amounts are only checked if all the VAT categories are
+	// known, to avoid reporting again the invalid categories.
+	known := true
+	addTaxable := func(id TaxCategoryCodeType, percent, amount types.Decimal) {
+		if vatRulePrefix(id) == "" {
+			known = false
+			return
+		}
+		key := makeVATBreakdownKey(id, percent)
+		sum, ok := taxable[key]
+		if !ok {
+			keys = append(keys, key)
+			sum = types.Zero
+		}
+		taxable[key] = sum.Add(amount.AsAmount())
+	}
+
+	for i, line := range iv.InvoiceLines {
+		category := line.Item.TaxCategory
+		if prefix := vatRulePrefix(category.ID); prefix != "" {
+			v.validateVATRate(prefix+"-05", fmt.Sprintf("InvoiceLines[%d].Item.TaxCategory.Percent", i),
+				category.ID, category.Percent)
+		}
+		addTaxable(category.ID, category.Percent, line.LineExtensionAmount.Amount)
+	}
+	for i, ac := range iv.AllowanceCharges {
+		category := ac.TaxCategory
+		amount := ac.Amount.Amount
+		if prefix := vatRulePrefix(category.ID); prefix != "" {
+			rule := prefix + "-06"
+			if ac.ChargeIndicator {
+				rule = prefix + "-07"
+			}
+			v.validateVATRate(rule, fmt.Sprintf("AllowanceCharges[%d].TaxCategory.Percent", i),
+				category.ID, category.Percent)
+		}
+		if !ac.ChargeIndicator {
+			amount = amount.Neg()
+		}
+		addTaxable(category.ID, category.Percent, amount)
+	}
+
+	vatTotal := documentVATTotal(iv)
+	if vatTotal == nil || !known {
+		return
+	}
+	breakdowns := make(map[vatBreakdownKey]bool)
+	for j, subtotal := range vatTotal.TaxSubtotals {
+		category := subtotal.TaxCategory
+		prefix := vatRulePrefix(category.ID)
+		if prefix == "" {
+			continue
+		}
+		path := fmt.Sprintf("TaxTotal.TaxSubtotals[%d]", j)
+		key := makeVATBreakdownKey(category.ID, category.Percent)
+		breakdowns[key] = true
+
+		expected, ok := taxable[key]
+		if !ok {
+			expected = types.Zero
+		}
+		actual := subtotal.TaxableAmount.Amount.AsAmount()
+		if !actual.Equal(expected) {
+			v.add(prefix+"-08", path+".TaxableAmount",
+				"the VAT category taxable amount (BT-116) for category %s and rate %s%% is %s, expected %s",
+				category.ID, key.percent, actual.StringFixed(2), expected.StringFixed(2))
+		}
+
+		taxAmount := subtotal.TaxAmount.Amount.AsAmount()
+		if category.ID == TaxCategoryVATStandardRate {
+			expectedTax := actual.Mul(category.Percent).Div(types.D(100)).AsAmount()
+			if !taxAmount.Equal(expectedTax) {
+				v.add(prefix+"-09", path+".TaxAmount",
+					"the VAT category tax amount (BT-117) for category %s and rate %s%% is %s, expected %s",
+					category.ID, key.percent, taxAmount.StringFixed(2), expectedTax.StringFixed(2))
+			}
+		} else if !taxAmount.IsZero() {
+			v.add(prefix+"-09", path+".TaxAmount",
+				"the VAT category tax amount (BT-117) for category %s must be 0, got %s",
+				category.ID, taxAmount.StringFixed(2))
+		}
+	}
+	for _, key := range keys {
+		if !breakdowns[key] {
+			v.add(vatRulePrefix(key.id)+"-08", "TaxTotal.TaxSubtotals",
+				"missing VAT breakdown for category %s and rate %s%%, expected a taxable amount (BT-116) of %s",
+				key.id, key.percent, taxable[key].StringFixed(2))
+		}
+	}
+}
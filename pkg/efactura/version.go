@@ -0,0 +1,34 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"github.com/printesoi/e-factura-go/pkg/constants"
+)
+
+// Version returns the version of the e-factura-go library.
+func Version() string {
+	return constants.Version
+}
+
+// GeneratedWithComment returns the XML comment set by a Client created with
+// ClientGeneratedWith in the generated documents, eg.
+// "Generated with MyERP 2.1 (e-factura-go v0.0.1-alpha)".
+func GeneratedWithComment(application string) string {
+	if application == "" {
+		return "Generated with e-factura-go " + Version()
+	}
+	return "Generated with " + application + " (e-factura-go " + Version() + ")"
+}
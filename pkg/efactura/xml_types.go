@@ -15,6 +15,9 @@
 package efactura
 
 import (
+	"encoding/json"
+	"sort"
+
 	"github.com/printesoi/e-factura-go/pkg/types"
 	"github.com/printesoi/xml-go"
 )
@@ -23,25 +26,39 @@ import (
 // chardata and the currency ID as the currencyID attribute. The name of the
 // node must be controlled by the parent type.
 type AmountWithCurrency struct {
-	Amount     types.Decimal    `xml:",chardata"`
-	CurrencyID CurrencyCodeType `xml:"currencyID,attr,omitempty"`
+	Amount     types.Decimal    `xml:",chardata" json:"amount,omitempty"`
+	CurrencyID CurrencyCodeType `xml:"currencyID,attr,omitempty" json:"currencyID,omitempty"`
 }
 
 // MarshalXML implements the xml.Marshaler interface. We use a custom
-// marshaling function for AmountWithCurrency to ensure two digits after the
-// decimal point.
+// marshaling function for AmountWithCurrency to ensure the number of digits
+// after the decimal point (two, see FormatAmount).
 func (a AmountWithCurrency) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	type amountWithCurrency struct {
 		Amount     string           `xml:",chardata"`
 		CurrencyID CurrencyCodeType `xml:"currencyID,attr,omitempty"`
 	}
 	xmlAmount := amountWithCurrency{
-		Amount:     a.Amount.StringFixed(2),
+		Amount:     FormatAmount(a.Amount),
 		CurrencyID: a.CurrencyID,
 	}
 	return e.EncodeElement(xmlAmount, start)
 }
 
+// MarshalJSON implements the json.Marshaler interface. The amount is
+// marshaled as a JSON string with the number of digits after the decimal
+// point, like in XML.
+func (a AmountWithCurrency) MarshalJSON() ([]byte, error) {
+	type amountWithCurrency struct {
+		Amount     string           `json:"amount"`
+		CurrencyID CurrencyCodeType `json:"currencyID,omitempty"`
+	}
+	return json.Marshal(amountWithCurrency{
+		Amount:     FormatAmount(a.Amount),
+		CurrencyID: a.CurrencyID,
+	})
+}
+
 // ValueWithAttrs represents and embeddable type that stores a string as
 // chardata and a list of attributes. The name of the XML node must be
 // controlled by the parent type.
@@ -50,6 +67,44 @@ type ValueWithAttrs struct {
 	Attributes []xml.Attr `xml:",any,attr,omitempty"`
 }
 
+// valueWithAttrsJSON is the JSON representation of a ValueWithAttrs. The
+// attributes are keyed by the local name (the namespaces are not kept).
+type valueWithAttrsJSON struct {
+	Value      string            `json:"value"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (v ValueWithAttrs) MarshalJSON() ([]byte, error) {
+	jv := valueWithAttrsJSON{Value: v.Value}
+	for _, attr := range v.Attributes {
+		if jv.Attributes == nil {
+			jv.Attributes = make(map[string]string, len(v.Attributes))
+		}
+		jv.Attributes[attr.Name.Local] = attr.Value
+	}
+	return json.Marshal(jv)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The attributes are
+// sorted by name.
+func (v *ValueWithAttrs) UnmarshalJSON(data []byte) error {
+	var jv valueWithAttrsJSON
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return err
+	}
+	*v = ValueWithAttrs{Value: jv.Value}
+	names := make([]string, 0, len(jv.Attributes))
+	for name := range jv.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.Attributes = append(v.Attributes, xml.Attr{Name: xml.Name{Local: name}, Value: jv.Attributes[name]})
+	}
+	return nil
+}
+
 // Ptr is a helper method to return a *ValueWithAttrs from the receiver in
 // contexts where a pointer is needed.
 func (v ValueWithAttrs) Ptr() *ValueWithAttrs {
@@ -95,7 +150,7 @@ func (v *ValueWithAttrs) GetAttrByName(name string) (attr xml.Attr) {
 
 // IDNode is a struct that encodes a node that only has a cbc:ID property.
 type IDNode struct {
-	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID"`
+	ID string `xml:"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2 ID" json:"id,omitempty"`
 }
 
 // MakeIDNode creates a IDNode with the given id.
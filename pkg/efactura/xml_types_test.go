@@ -0,0 +1,130 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package efactura
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/printesoi/xml-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAmount struct {
+	XMLName xml.Name           `xml:"Amount"`
+	Amount  AmountWithCurrency `xml:"Value"`
+}
+
+func TestAmountWithCurrencyXML(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"0", "0.00"},
+		{"-0", "0.00"},
+		{"-0.004", "0.00"},
+		{"-0.005", "-0.01"},
+		{"1.005", "1.01"},
+		{"1.5e3", "1500.00"},
+		{"1E-2", "0.01"},
+		{"-1234567.891", "-1234567.89"},
+		{"123456789012345678901234567890.125", "123456789012345678901234567890.13"},
+	}
+	for _, tt := range tests {
+		v := testAmount{Amount: AmountWithCurrency{Amount: mustDecimal(tt.value), CurrencyID: CurrencyRON}}
+		data, err := xml.Marshal(v)
+		if !assert.NoError(err, tt.value) {
+			continue
+		}
+		expected := `<Amount><Value currencyID="RON">` + tt.expected + `</Value></Amount>`
+		assert.Equal(expected, string(data), tt.value)
+
+		// The marshaled amount is stable across a round trip.
+		var parsed testAmount
+		if assert.NoError(xml.Unmarshal(data, &parsed), tt.value) {
+			assert.Equal(tt.expected, parsed.Amount.Amount.StringFixed(2), tt.value)
+			assert.Equal(CurrencyRON, parsed.Amount.CurrencyID, tt.value)
+			data2, err := xml.Marshal(parsed)
+			if assert.NoError(err, tt.value) {
+				assert.Equal(expected, string(data2), tt.value)
+			}
+		}
+	}
+
+	var v testAmount
+	data, err := xml.Marshal(v)
+	if assert.NoError(err) {
+		assert.Equal(`<Amount><Value>0.00</Value></Amount>`, string(data))
+	}
+
+	for _, input := range []string{
+		`<Amount><Value currencyID="RON">1,50</Value></Amount>`,
+		`<Amount><Value currencyID="RON"></Value></Amount>`,
+		`<Amount><Value currencyID="RON">NaN</Value></Amount>`,
+		`<Amount><Value currencyID="RON">1.5.0</Value></Amount>`,
+	} {
+		assert.Error(xml.Unmarshal([]byte(input), &v), input)
+	}
+}
+
+func TestAmountWithCurrencyPrecision(t *testing.T) {
+	assert := assert.New(t)
+
+	amount := testAmount{Amount: AmountWithCurrency{Amount: mustDecimal("-2.34567"), CurrencyID: CurrencyEUR}}
+	data, err := xml.Marshal(amount)
+	if assert.NoError(err) {
+		assert.Equal(`<Amount><Value currencyID="EUR">-2.35</Value></Amount>`, string(data))
+	}
+}
+
+func TestAmountWithCurrencyJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		amount   AmountWithCurrency
+		expected string
+	}{
+		{AmountWithCurrency{Amount: mustDecimal("1.5"), CurrencyID: CurrencyRON}, `{"amount":"1.50","currencyID":"RON"}`},
+		{AmountWithCurrency{Amount: mustDecimal("-0.001")}, `{"amount":"0.00"}`},
+		{AmountWithCurrency{Amount: mustDecimal("1e20"), CurrencyID: CurrencyEUR}, `{"amount":"100000000000000000000.00","currencyID":"EUR"}`},
+		{AmountWithCurrency{}, `{"amount":"0.00"}`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.amount)
+		if !assert.NoError(err) {
+			continue
+		}
+		assert.Equal(tt.expected, string(data))
+
+		var parsed AmountWithCurrency
+		if assert.NoError(json.Unmarshal(data, &parsed), tt.expected) {
+			assert.True(parsed.Amount.Equal(tt.amount.Amount.AsAmount()), tt.expected)
+			assert.Equal(tt.amount.CurrencyID, parsed.CurrencyID, tt.expected)
+			data2, err := json.Marshal(parsed)
+			if assert.NoError(err) {
+				assert.Equal(tt.expected, string(data2))
+			}
+		}
+	}
+
+	var parsed AmountWithCurrency
+	if assert.NoError(json.Unmarshal([]byte(`{"amount":1.5e2,"currencyID":"RON"}`), &parsed)) {
+		assert.Equal("150", parsed.Amount.String())
+	}
+	assert.Error(json.Unmarshal([]byte(`{"amount":"1,50"}`), &parsed))
+	assert.Error(json.Unmarshal([]byte(`{"amount":"1e"}`), &parsed))
+}
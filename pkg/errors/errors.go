@@ -72,6 +72,11 @@ func (e *BuilderError) Error() string {
 	return errMsg
 }
 
+// Unwrap returns the underlying error.
+func (e *BuilderError) Unwrap() error {
+	return e.Err
+}
+
 // ValidateSignatureError is an error returned if the signature cannot be
 // successfully validated.
 type ValidateSignatureError struct {
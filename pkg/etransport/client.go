@@ -16,16 +16,59 @@ package etransport
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	xoauth2 "golang.org/x/oauth2"
 
 	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/constants"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
 )
 
 // ClientConfig is the config used to create a Client
 type ClientConfig struct {
 	// the client to use for making requests to the ANAF APIs protected with OAuth2.
 	ApiClient *client.ApiClient
+	// the generator of the correlation IDs attached to the uploads
+	// (optional). If not set, correlation.NewUUIDv7 is used.
+	CorrelationIDGenerator correlation.IDGenerator
+	// the base URL of the e-Transport endpoints (optional), eg. for a proxy
+	// that rewrites the paths. If not set, the endpoints are under
+	// ETRANSPORT/ws/v1/ relative to the base URL of the ApiClient.
+	BaseURL string
+	// the ANAF environment of the e-Transport endpoints (optional). If set
+	// and BaseURL is not set, the endpoints are under ETRANSPORT/ws/v1/
+	// relative to the base URL of the environment, regardless of the base
+	// URL of the ApiClient.
+	Environment client.Environment
+}
+
+// Validate checks that the config is complete and consistent. The ApiClient
+// is already validated when created (see client.ApiClientConfig.Validate).
+// All the problems found are returned as a single joined error.
+func (c *ClientConfig) Validate() error {
+	var errs []error
+	if c.ApiClient == nil {
+		errs = append(errs, errors.New("missing ApiClient"))
+	}
+	if c.Environment != "" {
+		if err := c.Environment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.BaseURL != "" {
+		if err := client.ValidateBaseURL(c.BaseURL); err != nil {
+			errs = append(errs, fmt.Errorf("BaseURL: %w", err))
+		}
+		if client.SameHost(c.BaseURL, constants.PublicApiBaseURL) {
+			errs = append(errs, fmt.Errorf("BaseURL %q points to the public APIs, not to the protected APIs", c.BaseURL))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid client config: %w", errors.Join(errs...))
+	}
+	return nil
 }
 
 // ClientConfigOption allows gradually modifying a ClientConfig
@@ -38,9 +81,39 @@ func ClientApiClient(apiClient *client.ApiClient) ClientConfigOption {
 	}
 }
 
+// ClientCorrelationIDGenerator sets the generator of the correlation IDs
+// attached to the uploads that are not called with a context already
+// carrying a correlation ID (see correlation.NewContext).
+func ClientCorrelationIDGenerator(gen correlation.IDGenerator) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.CorrelationIDGenerator = gen
+	}
+}
+
+// ClientBaseURL sets the base URL of the e-Transport endpoints, eg.
+// "https://proxy.example.com/anaf/etransport/" for a proxy that forwards to
+// https://api.anaf.ro/prod/ETRANSPORT/ws/v1/. The requests are still made
+// with the ApiClient (and its OAuth2 token).
+func ClientBaseURL(baseURL string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.BaseURL = baseURL
+	}
+}
+
+// ClientEnvironment sets the ANAF environment (client.EnvironmentProduction
+// or client.EnvironmentSandbox) of the e-Transport endpoints. The base URL set
+// with ClientBaseURL takes precedence.
+func ClientEnvironment(env client.Environment) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.Environment = env
+	}
+}
+
 // Client is a client that talks to ANAF e-transport APIs.
 type Client struct {
-	apiClient *client.ApiClient
+	apiClient      *client.ApiClient
+	correlationIDs correlation.IDGenerator
+	apiBase        string
 }
 
 // NewProductionClient creates a new basic Client for the ANAF e-transport production APIs.
@@ -56,6 +129,7 @@ func NewProductionClient(ctx context.Context, tokenSource xoauth2.TokenSource) (
 
 	return &Client{
 		apiClient: apiClient,
+		apiBase:   apiBase,
 	}, nil
 }
 
@@ -72,6 +146,7 @@ func NewSandboxClient(ctx context.Context, tokenSource xoauth2.TokenSource) (*Cl
 
 	return &Client{
 		apiClient: apiClient,
+		apiBase:   apiBase,
 	}, nil
 }
 
@@ -82,8 +157,20 @@ func NewClient(opts ...ClientConfigOption) (*Client, error) {
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
+	groupBase := apiBase
+	if cfg.Environment != "" {
+		groupBase = cfg.Environment.ApiBaseURL() + apiBase
+	}
+	if cfg.BaseURL != "" {
+		groupBase = cfg.BaseURL
+	}
 	return &Client{
-		apiClient: cfg.ApiClient,
+		apiClient:      cfg.ApiClient,
+		correlationIDs: cfg.CorrelationIDGenerator,
+		apiBase:        groupBase,
 	}, nil
 }
@@ -1080,3 +1080,13 @@ const (
 	ConfirmationTypePartiallyConfirmed ConfirmationType = "20"
 	ConfirmationTypeUnconfirmed        ConfirmationType = "30"
 )
+
+// IsValid returns true if the confirmation type is one of the known
+// confirmation types.
+func (ct ConfirmationType) IsValid() bool {
+	switch ct {
+	case ConfirmationTypeConfirmed, ConfirmationTypePartiallyConfirmed, ConfirmationTypeUnconfirmed:
+		return true
+	}
+	return false
+}
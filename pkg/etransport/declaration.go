@@ -85,6 +85,14 @@ func (pd *PostingDeclarationV2) SetVehicleChange(vehicleChange PostingDeclaratio
 	return pd
 }
 
+// SetCorrection set the given PostingDeclarationNotification as the
+// PostingDeclarationV2 payload, marked as a correction of the declared
+// transport with the given UIT.
+func (pd *PostingDeclarationV2) SetCorrection(uit UITType, notification PostingDeclarationNotification) *PostingDeclarationV2 {
+	notification.Correction = &PostingDeclarationNotificationCorrection{UIT: uit}
+	return pd.SetNotification(notification)
+}
+
 func (pd PostingDeclarationV2) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	type postingDeclaration PostingDeclarationV2
 	var eTransport struct {
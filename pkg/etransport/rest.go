@@ -20,21 +20,25 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 
 	ierrors "github.com/printesoi/e-factura-go/internal/errors"
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/correlation"
 	"github.com/printesoi/e-factura-go/pkg/types"
 	ixml "github.com/printesoi/e-factura-go/pkg/xml"
 )
 
+// The paths of the endpoints are relative to the base of the e-Transport
+// endpoint group (see ClientConfig.BaseURL).
 const (
 	apiBase             = "ETRANSPORT/ws/v1/"
-	apiPathUploadV2     = apiBase + "upload/%s/%s/2"
-	apiPathMessageList  = apiBase + "lista/%d/%s"
-	apiPathMessageState = apiBase + "stareMesaj/%d"
-	apiPathInfo         = apiBase + "info"
+	apiPathUploadV2     = "upload/%s/%s/2"
+	apiPathMessageList  = "lista/%d/%s"
+	apiPathMessageState = "stareMesaj/%d"
+	apiPathInfo         = "info"
 )
 
+// MessageState is the state of a declaration from the messages list.
 type MessageState string
 
 const (
@@ -42,6 +46,7 @@ const (
 	MessageStateERR MessageState = "ERR"
 )
 
+// Message is a declaration from the messages list.
 type Message struct {
 	UIT                          UITType         `json:"uit"`
 	DeclarantCode                int64           `json:"cod_decl"`
@@ -70,6 +75,7 @@ type Message struct {
 	PostIncident                 string          `json:"post_avarie,omitempty"`
 }
 
+// MessageErrorType is the type of a MessageError.
 type MessageErrorType string
 
 const (
@@ -78,11 +84,36 @@ const (
 	MessageErrorTypeInfo MessageErrorType = "INFO"
 )
 
+// MessageError is an error, a warning or an info message of a declaration.
 type MessageError struct {
 	Type    MessageErrorType `json:"tip"`
 	Message string           `json:"mesaj"`
 }
 
+// IsOk returns true if the declaration was processed successfully and the
+// UIT can be used.
+func (m Message) IsOk() bool {
+	return m.State == MessageStateOK
+}
+
+// GetUIT returns the UIT of the declaration (should only be called when
+// IsOk() == true).
+func (m Message) GetUIT() UITType {
+	return m.UIT
+}
+
+// GetFirstErrorMessage returns the first error message (of type
+// MessageErrorTypeErr) of the declaration. If the declaration has no error
+// messages, empty string is returned.
+func (m Message) GetFirstErrorMessage() string {
+	for _, me := range m.Messages {
+		if me.IsErr() {
+			return me.Message
+		}
+	}
+	return ""
+}
+
 func (me MessageError) IsErr() bool {
 	return me.Type == MessageErrorTypeErr
 }
@@ -110,9 +141,17 @@ type MessagesListResponse struct {
 }
 
 // IsOk returns true if the response corresponding to fetching messages list
-// was successful.
+// was successful. A response with the "no messages" error is successful (see
+// Empty).
 func (r *MessagesListResponse) IsOk() bool {
-	return r != nil && (len(r.Errors) == 0 || len(r.Errors) == 1 && strings.HasPrefix(r.Errors[0].ErrorMessage, "Nu exista mesaje in "))
+	return r != nil && (len(r.Errors) == 0 || len(r.Errors) == 1 && ierrors.ErrorMessageMatchNoMessages(r.Errors[0].ErrorMessage))
+}
+
+// Empty returns true if the response is successful and there are no
+// messages, either because ANAF returned the "no messages" error (eg. "Nu
+// exista mesaje in ultimele 1 zile") or an empty list.
+func (r *MessagesListResponse) Empty() bool {
+	return r.IsOk() && len(r.Messages) == 0
 }
 
 // GetFirstErrorMessage returns the first error message. If no error messages
@@ -133,7 +172,7 @@ func (r *MessagesListResponse) GetFirstErrorMessage() string {
 func (c *Client) GetMessagesList(
 	ctx context.Context, cif string, numDays int,
 ) (response *MessagesListResponse, err error) {
-	path := fmt.Sprintf(apiPathMessageList, numDays, cif)
+	path := c.apiBase + fmt.Sprintf(apiPathMessageList, numDays, cif)
 	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, path, nil, nil)
 	if err = er; err != nil {
 		return
@@ -153,6 +192,8 @@ func (c *Client) GetMessagesList(
 	return
 }
 
+// GetMessageStateResponse is the parsed response from the get message state
+// endpoint.
 type GetMessageStateResponse struct {
 	Errors []struct {
 		ErrorMessage string `json:"errorMessage"`
@@ -163,6 +204,7 @@ type GetMessageStateResponse struct {
 	TraceID         string              `json:"trace_id"`
 }
 
+// GetMessageStateCode is the state of an uploaded message.
 type GetMessageStateCode string
 
 const (
@@ -172,6 +214,15 @@ const (
 	GetMessageStateCodeInvalidXML GetMessageStateCode = "XML cu erori nepreluat de sistem"
 )
 
+// IsTerminal returns true if the state is final: ok, nok or invalid XML.
+func (c GetMessageStateCode) IsTerminal() bool {
+	switch c {
+	case GetMessageStateCodeOk, GetMessageStateCodeNok, GetMessageStateCodeInvalidXML:
+		return true
+	}
+	return false
+}
+
 // IsOk returns true if the message state if ok (processed, and can be
 // downloaded).
 func (r *GetMessageStateResponse) IsOk() bool {
@@ -194,6 +245,12 @@ func (r *GetMessageStateResponse) IsInvalidXML() bool {
 	return r != nil && r.State == GetMessageStateCodeInvalidXML
 }
 
+// IsTerminal returns true if the message is in a final state (see
+// GetMessageStateCode.IsTerminal).
+func (r *GetMessageStateResponse) IsTerminal() bool {
+	return r != nil && r.State.IsTerminal()
+}
+
 // GetFirstErrorMessage returns the first error message. If no error messages
 // are set for the response, empty string is returned.
 func (r *GetMessageStateResponse) GetFirstErrorMessage() string {
@@ -208,7 +265,7 @@ func (r *GetMessageStateResponse) GetFirstErrorMessage() string {
 func (c *Client) GetMessageState(
 	ctx context.Context, uploadIndex int64,
 ) (response *GetMessageStateResponse, err error) {
-	path := fmt.Sprintf(apiPathMessageState, uploadIndex)
+	path := c.apiBase + fmt.Sprintf(apiPathMessageState, uploadIndex)
 	req, er := c.apiClient.NewRequest(ctx, http.MethodGet, path, nil, nil)
 	if err = er; err != nil {
 		return
@@ -228,6 +285,7 @@ func (c *Client) GetMessageState(
 	return
 }
 
+// UploadV2Response is the parsed response from the upload (v2) endpoint.
 type UploadV2Response struct {
 	DateResponse    string  `json:"dateResponse"`
 	ExecutionStatus int32   `json:"ExecutionStatus"`
@@ -239,10 +297,13 @@ type UploadV2Response struct {
 	Errors          []struct {
 		ErrorMessage string `json:"errorMessage"`
 	} `json:"errors,omitempty"`
+
+	// CorrelationID is the correlation ID of the upload (see
+	// ClientCorrelationIDGenerator). It's never set by ANAF.
+	CorrelationID string `json:"-"`
 }
 
-// IsOk returns true if the response corresponding to fetching messages list
-// was successful.
+// IsOk returns true if the upload was successful.
 func (r *UploadV2Response) IsOk() bool {
 	return r != nil && r.ExecutionStatus == 0
 }
@@ -273,6 +334,13 @@ func (r *UploadV2Response) GetFirstErrorMessage() string {
 	return r.Errors[0].ErrorMessage
 }
 
+var (
+	_ client.Response = (*MessagesListResponse)(nil)
+	_ client.Response = (*GetMessageStateResponse)(nil)
+	_ client.Response = (*UploadV2Response)(nil)
+	_ client.Response = Message{}
+)
+
 type uploadStandard string
 
 const (
@@ -282,7 +350,8 @@ const (
 func (c *Client) UploadV2XML(
 	ctx context.Context, xml io.Reader, cif string,
 ) (response *UploadV2Response, err error) {
-	path := fmt.Sprintf(apiPathUploadV2, uploadStandardETransp, cif)
+	ctx, correlationID := correlation.Ensure(ctx, c.correlationIDs)
+	path := c.apiBase + fmt.Sprintf(apiPathUploadV2, uploadStandardETransp, cif)
 	req, er := c.apiClient.NewRequest(ctx, http.MethodPost, path, nil, xml)
 	if err = er; err != nil {
 		return
@@ -298,11 +367,15 @@ func (c *Client) UploadV2XML(
 		}
 		return nil
 	}); err == nil {
+		res.CorrelationID = correlationID
 		response = res
 	}
 	return
 }
 
+// UploadPostingDeclarationV2 marshals and uploads the given posting
+// declaration. If the upload is successful, the UIT is returned in the
+// response (see UploadV2Response.GetUIT).
 func (c *Client) UploadPostingDeclarationV2(
 	ctx context.Context, decl PostingDeclarationV2, cif string,
 ) (response *UploadV2Response, err error) {
@@ -313,3 +386,102 @@ func (c *Client) UploadPostingDeclarationV2(
 
 	return c.UploadV2XML(ctx, xmlReader, cif)
 }
+
+// UploadPostingDeclaration marshals and uploads the given posting
+// declaration. Since v2 is the only declaration version accepted by ANAF,
+// this is the same as UploadPostingDeclarationV2.
+func (c *Client) UploadPostingDeclaration(
+	ctx context.Context, decl PostingDeclarationV2, cif string,
+) (response *UploadV2Response, err error) {
+	return c.UploadPostingDeclarationV2(ctx, decl, cif)
+}
+
+// ConfirmTransport uploads a confirmation (confirmare) for the declared
+// transport with the given UIT, eg. for the intra-community acquisitions once
+// the goods are received. The confirmation type must be one of
+// ConfirmationTypeConfirmed, ConfirmationTypePartiallyConfirmed or
+// ConfirmationTypeUnconfirmed.
+func (c *Client) ConfirmTransport(
+	ctx context.Context, cif string, confirmation PostingDeclarationConfirmation,
+) (response *UploadV2Response, err error) {
+	if confirmation.UIT == "" {
+		return nil, errors.New("etransport: confirmation: missing UIT")
+	}
+	if !confirmation.ConfirmationType.IsValid() {
+		return nil, fmt.Errorf("etransport: confirmation: invalid confirmation type %q", confirmation.ConfirmationType)
+	}
+	decl := PostingDeclarationV2{DeclarantCode: cif}
+	decl.SetConfirmation(confirmation)
+	return c.UploadPostingDeclarationV2(ctx, decl, cif)
+}
+
+// CorrectTransport uploads a correction (corectie) of the declared transport
+// with the given UIT: the notification replaces the declared one, while
+// keeping the same UIT.
+func (c *Client) CorrectTransport(
+	ctx context.Context, cif string, uit UITType, notification PostingDeclarationNotification,
+) (response *UploadV2Response, err error) {
+	if uit == "" {
+		return nil, errors.New("etransport: correction: missing UIT")
+	}
+	decl := PostingDeclarationV2{DeclarantCode: cif}
+	decl.SetCorrection(uit, notification)
+	return c.UploadPostingDeclarationV2(ctx, decl, cif)
+}
+
+// ChangeVehicle uploads a vehicle change (modifVehicul) for the declared
+// transport with the given UIT, eg. if the goods are transferred to another
+// vehicle during the transport.
+func (c *Client) ChangeVehicle(
+	ctx context.Context, cif string, vehicleChange PostingDeclarationVehicleChange,
+) (response *UploadV2Response, err error) {
+	if vehicleChange.UIT == "" {
+		return nil, errors.New("etransport: vehicle change: missing UIT")
+	}
+	decl := PostingDeclarationV2{DeclarantCode: cif}
+	decl.SetVehicleChange(vehicleChange)
+	return c.UploadPostingDeclarationV2(ctx, decl, cif)
+}
+
+// DeleteTransport uploads a deletion (stergere) of the declared transport
+// with the given UIT.
+func (c *Client) DeleteTransport(
+	ctx context.Context, cif string, uit UITType,
+) (response *UploadV2Response, err error) {
+	if uit == "" {
+		return nil, errors.New("etransport: deletion: missing UIT")
+	}
+	decl := PostingDeclarationV2{DeclarantCode: cif}
+	decl.SetDeletion(PostingDeclarationDeletion{UIT: uit})
+	return c.UploadPostingDeclarationV2(ctx, decl, cif)
+}
+
+// ErrDeclarationNotFound is returned by DownloadDeclaration if there is no
+// message for the given upload index.
+var ErrDeclarationNotFound = errors.New("etransport: declaration not found")
+
+// DownloadDeclaration fetches the processed declaration (the UIT, the state
+// and the validation messages) for the given upload index. The e-Transport API
+// has no endpoint for downloading a single declaration, so the declaration is
+// looked up in the messages list for the last numDays days (between 1 and
+// 60). If no message matches the upload index, ErrDeclarationNotFound is
+// returned.
+func (c *Client) DownloadDeclaration(
+	ctx context.Context, cif string, uploadIndex int64, numDays int,
+) (message *Message, err error) {
+	res, er := c.GetMessagesList(ctx, cif, numDays)
+	if err = er; err != nil {
+		return
+	}
+	if !res.IsOk() {
+		err = fmt.Errorf("etransport: messages list: %s", res.GetFirstErrorMessage())
+		return
+	}
+	for i := range res.Messages {
+		if res.Messages[i].UploadID == uploadIndex {
+			return &res.Messages[i], nil
+		}
+	}
+	err = fmt.Errorf("%w: upload index %d", ErrDeclarationNotFound, uploadIndex)
+	return
+}
@@ -0,0 +1,356 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package etransport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	ferrors "github.com/printesoi/e-factura-go/pkg/errors"
+	"github.com/printesoi/e-factura-go/pkg/etransport"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// setupTestClient sets up a test HTTP server along with an etransport.Client
+// that is configured to talk to that test server.
+func setupTestClient(t *testing.T) (*etransport.Client, *http.ServeMux) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	apiClient, err := client.NewApiClient(
+		client.ApiClientContext(context.Background()),
+		client.ApiClientBaseURL(server.URL+"/"),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if err != nil {
+		t.Fatalf("error creating api client: %v", err)
+	}
+	c, err := etransport.NewClient(etransport.ClientApiClient(apiClient))
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return c, mux
+}
+
+func TestMessagesListResponseEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	var res etransport.MessagesListResponse
+	err := json.Unmarshal([]byte(`{"errors":[{"errorMessage":"Nu există mesaje în ultimele 5 zile"}],"titlu":"Lista Mesaje"}`), &res)
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+		assert.True(res.Empty())
+	}
+
+	res = etransport.MessagesListResponse{}
+	err = json.Unmarshal([]byte(`{"errors":[{"errorMessage":"CUI= 123 nu exista in baza de date"}]}`), &res)
+	if assert.NoError(err) {
+		assert.False(res.IsOk())
+		assert.False(res.Empty())
+	}
+}
+
+func TestUploadPostingDeclaration(t *testing.T) {
+	assert := assert.New(t)
+
+	c, mux := setupTestClient(t)
+	mux.HandleFunc("/ETRANSPORT/ws/v1/upload/ETRANSP/123456789/2", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("application/xml", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Contains(string(body), `<stergere uit="4X0Y1Z2A3B4C5D6E"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"dateResponse":"202401021504","ExecutionStatus":0,"index_incarcare":5001,"UIT":"4X0Y1Z2A3B4C5D6E","trace_id":"t-1","ref_declarant":"ref-1"}`)
+	})
+
+	decl := etransport.PostingDeclarationV2{DeclarantCode: "123456789"}
+	decl.SetDeletion(etransport.PostingDeclarationDeletion{UIT: "4X0Y1Z2A3B4C5D6E"})
+	res, err := c.UploadPostingDeclaration(context.Background(), decl, "123456789")
+	if assert.NoError(err) && assert.True(res.IsOk()) {
+		assert.Equal(int64(5001), res.GetUploadIndex())
+		assert.Equal(etransport.UITType("4X0Y1Z2A3B4C5D6E"), res.GetUIT())
+	}
+}
+
+func TestGetMessageState(t *testing.T) {
+	assert := assert.New(t)
+
+	c, mux := setupTestClient(t)
+	mux.HandleFunc("/ETRANSPORT/ws/v1/stareMesaj/5001", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"stare":"ok","dateResponse":"202401021504","ExecutionStatus":0}`)
+	})
+
+	res, err := c.GetMessageState(context.Background(), 5001)
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+	}
+}
+
+func TestGetMessageStateLimitExceeded(t *testing.T) {
+	assert := assert.New(t)
+
+	c, mux := setupTestClient(t)
+	mux.HandleFunc("/ETRANSPORT/ws/v1/stareMesaj/5001", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"errors":[{"errorMessage":"S-au facut deja 100000 interogari de stare in cursul zilei"}]}`)
+	})
+
+	_, err := c.GetMessageState(context.Background(), 5001)
+	var limitErr *ferrors.LimitExceededError
+	if assert.ErrorAs(err, &limitErr) {
+		assert.Equal(int64(100000), limitErr.Limit)
+	}
+}
+
+func TestMessageStateIsTerminal(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(etransport.GetMessageStateCodeOk.IsTerminal())
+	assert.True(etransport.GetMessageStateCodeNok.IsTerminal())
+	assert.True(etransport.GetMessageStateCodeInvalidXML.IsTerminal())
+	assert.False(etransport.GetMessageStateCodeProcessing.IsTerminal())
+
+	var res *etransport.GetMessageStateResponse
+	assert.False(res.IsTerminal())
+	res = &etransport.GetMessageStateResponse{State: etransport.GetMessageStateCodeNok}
+	assert.True(res.IsTerminal())
+}
+
+// firstError is error handling code shared by efactura and etransport.
+func firstError(res client.Response) string {
+	if res.IsOk() {
+		return ""
+	}
+	return res.GetFirstErrorMessage()
+}
+
+func TestResponseHelpers(t *testing.T) {
+	assert := assert.New(t)
+
+	var msg etransport.Message
+	err := json.Unmarshal([]byte(`{"uit":"4X0Y1Z2A3B4C5D6E","stare":"ERR","mesaje":[{"tip":"WARN","mesaj":"avertisment"},{"tip":"ERR","mesaj":"eroare"}]}`), &msg)
+	if assert.NoError(err) {
+		assert.False(msg.IsOk())
+		assert.Equal("eroare", firstError(msg))
+	}
+
+	etRes := &etransport.GetMessageStateResponse{State: etransport.GetMessageStateCodeNok}
+	etRes.Errors = append(etRes.Errors, struct {
+		ErrorMessage string `json:"errorMessage"`
+	}{ErrorMessage: "eroare validare"})
+	assert.Equal("eroare validare", firstError(etRes))
+
+	efRes := &efactura.MessagesListResponse{Error: "CIF introdus= 123 nu este un numar"}
+	assert.Equal("CIF introdus= 123 nu este un numar", firstError(efRes))
+	efRes = &efactura.MessagesListResponse{Error: "Nu exista mesaje in ultimele 1 zile"}
+	assert.Equal("", firstError(efRes))
+}
+
+func TestDownloadDeclaration(t *testing.T) {
+	assert := assert.New(t)
+
+	c, mux := setupTestClient(t)
+	mux.HandleFunc("/ETRANSPORT/ws/v1/lista/7/123456789", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"mesaje":[`+
+			`{"uit":"AAAAAAAAAAAAAAAA","id_incarcare":5000,"stare":"ERR","mesaje":[{"tip":"ERR","mesaj":"eroare"}]},`+
+			`{"uit":"4X0Y1Z2A3B4C5D6E","id_incarcare":5001,"stare":"OK"}],`+
+			`"ExecutionStatus":0}`)
+	})
+
+	ctx := context.Background()
+	message, err := c.DownloadDeclaration(ctx, "123456789", 5001, 7)
+	if assert.NoError(err) {
+		assert.True(message.IsOk())
+		assert.Equal(etransport.UITType("4X0Y1Z2A3B4C5D6E"), message.GetUIT())
+	}
+	message, err = c.DownloadDeclaration(ctx, "123456789", 5000, 7)
+	if assert.NoError(err) {
+		assert.False(message.IsOk())
+		if assert.Len(message.Messages, 1) {
+			assert.True(message.Messages[0].IsErr())
+		}
+	}
+	_, err = c.DownloadDeclaration(ctx, "123456789", 5002, 7)
+	assert.True(errors.Is(err, etransport.ErrDeclarationNotFound))
+}
+
+func TestTransportOperations(t *testing.T) {
+	assert := assert.New(t)
+
+	c, mux := setupTestClient(t)
+	var bodies []string
+	mux.HandleFunc("/ETRANSPORT/ws/v1/upload/ETRANSP/123456789/2", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"ExecutionStatus":0,"index_incarcare":5002}`)
+	})
+
+	const uit = etransport.UITType("4X0Y1Z2A3B4C5D6E")
+	ctx := context.Background()
+	_, err := c.ConfirmTransport(ctx, "123456789", etransport.PostingDeclarationConfirmation{
+		UIT:              uit,
+		ConfirmationType: etransport.ConfirmationTypePartiallyConfirmed,
+		Remarks:          "lipsa 2 colete",
+	})
+	assert.NoError(err)
+	_, err = c.CorrectTransport(ctx, "123456789", uit, etransport.PostingDeclarationNotification{
+		OpType: etransport.OpTypeTTN,
+	})
+	assert.NoError(err)
+	_, err = c.ChangeVehicle(ctx, "123456789", etransport.PostingDeclarationVehicleChange{
+		UIT:          uit,
+		LicensePlate: "B200XYZ",
+		ChangeDate:   types.MakeDateTime(2024, 3, 1, 10, 30, 0, 0),
+	})
+	assert.NoError(err)
+	_, err = c.DeleteTransport(ctx, "123456789", uit)
+	assert.NoError(err)
+
+	if assert.Len(bodies, 4) {
+		for _, body := range bodies {
+			assert.Contains(body, `codDeclarant="123456789"`)
+		}
+		assert.Contains(bodies[0], `<confirmare uit="4X0Y1Z2A3B4C5D6E" tipConfirmare="20" observatii="lipsa 2 colete">`)
+		assert.Contains(bodies[1], `<notificare codTipOperatiune="30">`)
+		assert.Contains(bodies[1], `<corectie uit="4X0Y1Z2A3B4C5D6E">`)
+		assert.Contains(bodies[2], `<modifVehicul uit="4X0Y1Z2A3B4C5D6E" nrVehicul="B200XYZ"`)
+		assert.Contains(bodies[3], `<stergere uit="4X0Y1Z2A3B4C5D6E">`)
+	}
+
+	_, err = c.ConfirmTransport(ctx, "123456789", etransport.PostingDeclarationConfirmation{
+		UIT:              uit,
+		ConfirmationType: "40",
+	})
+	assert.Error(err)
+	_, err = c.DeleteTransport(ctx, "123456789", "")
+	assert.Error(err)
+	assert.Len(bodies, 4)
+}
+
+func TestClientBaseURL(t *testing.T) {
+	assert := assert.New(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	apiClient, err := client.NewApiClient(
+		client.ApiClientBaseURL(server.URL+"/"),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	c, err := etransport.NewClient(
+		etransport.ClientApiClient(apiClient),
+		etransport.ClientBaseURL(server.URL+"/proxy/etransport/"),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	mux.HandleFunc("/proxy/etransport/stareMesaj/5001", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"stare":"ok","dateResponse":"202401021504","ExecutionStatus":0}`)
+	})
+	res, err := c.GetMessageState(context.Background(), 5001)
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+	}
+
+	_, err = etransport.NewClient(
+		etransport.ClientApiClient(apiClient),
+		etransport.ClientBaseURL("https://webservicesp.anaf.ro/prod/ETRANSPORT/ws/v1"),
+	)
+	if assert.Error(err) {
+		assert.ErrorContains(err, "trailing slash")
+		assert.ErrorContains(err, "points to the public APIs")
+	}
+}
+
+func TestClientEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	var urls []string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/test/ETRANSPORT/ws/v1/stareMesaj/5001", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"stare":"ok","dateResponse":"202401021504","ExecutionStatus":0}`)
+	})
+
+	// Send the requests for the ANAF APIs to the test server.
+	target, _ := url.Parse(server.URL)
+	httpClient := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		urls = append(urls, r.URL.String())
+		r = r.Clone(r.Context())
+		r.URL.Scheme, r.URL.Host, r.Host = target.Scheme, target.Host, ""
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	apiClient, err := client.NewApiClient(
+		client.ApiClientContext(context.WithValue(context.Background(), xoauth2.HTTPClient, httpClient)),
+		client.ApiClientProductionEnvironment(true),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	c, err := etransport.NewClient(
+		etransport.ClientApiClient(apiClient),
+		etransport.ClientEnvironment(client.EnvironmentSandbox),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	res, err := c.GetMessageState(context.Background(), 5001)
+	if assert.NoError(err) {
+		assert.True(res.IsOk())
+	}
+	assert.Equal([]string{"https://api.anaf.ro/test/ETRANSPORT/ws/v1/stareMesaj/5001"}, urls)
+
+	_, err = etransport.NewClient(
+		etransport.ClientApiClient(apiClient),
+		etransport.ClientEnvironment("prod"),
+	)
+	assert.ErrorContains(err, `invalid environment "prod"`)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
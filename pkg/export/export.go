@@ -0,0 +1,170 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package export exports parsed invoices to formats used in accounting
+// review workflows (eg. XLSX workbooks).
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+const (
+	// SheetSent is the name of the sheet with the sent invoices.
+	SheetSent = "Sent"
+	// SheetReceived is the name of the sheet with the received invoices.
+	SheetReceived = "Received"
+)
+
+// invoiceColumns are the header columns of the invoice sheets. The first
+// columns are the invoice (header) metadata, repeated for each line.
+var invoiceColumns = []string{
+	"Invoice ID",
+	"Issue date",
+	"Due date",
+	"Type code",
+	"Currency",
+	"Supplier ID",
+	"Supplier name",
+	"Customer ID",
+	"Customer name",
+	"Invoice net amount",
+	"Invoice VAT amount",
+	"Invoice total amount",
+	"Invoice payable amount",
+	"Line ID",
+	"Item name",
+	"Seller item ID",
+	"Quantity",
+	"Unit code",
+	"Unit price",
+	"Line net amount",
+	"VAT category",
+	"VAT percent",
+	"Line note",
+}
+
+// WriteInvoicesXLSX writes to w an XLSX workbook with a sheet for the sent
+// invoices and one for the received invoices. Each sheet has one row per
+// invoice line, with the invoice header metadata (IDs, dates, parties,
+// totals) repeated on each row. Amounts and quantities are written as
+// numbers, dates as YYYY-MM-DD text.
+func WriteInvoicesXLSX(w io.Writer, sent, received []efactura.Invoice) error {
+	return writeXLSX(w, []xlsxSheet{
+		invoicesSheet(SheetSent, sent),
+		invoicesSheet(SheetReceived, received),
+	})
+}
+
+func invoicesSheet(name string, invoices []efactura.Invoice) xlsxSheet {
+	sheet := xlsxSheet{name: name}
+	header := make([]xlsxCell, 0, len(invoiceColumns))
+	for _, column := range invoiceColumns {
+		header = append(header, xlsxCell{value: column, bold: true})
+	}
+	sheet.rows = append(sheet.rows, header)
+
+	for _, iv := range invoices {
+		supplierID, supplierName := supplierParty(iv.Supplier.Party)
+		customerID, customerName := customerParty(iv.Customer.Party)
+		var dueDate string
+		if iv.DueDate != nil {
+			dueDate = formatDate(*iv.DueDate)
+		}
+		var vatAmount types.Decimal
+		for _, taxTotal := range iv.TaxTotal {
+			if taxTotal.TaxAmount != nil && taxTotal.TaxAmount.CurrencyID == iv.DocumentCurrencyCode {
+				vatAmount = taxTotal.TaxAmount.Amount
+			}
+		}
+		total := iv.LegalMonetaryTotal
+		invoiceCells := []xlsxCell{
+			textCell(iv.ID),
+			textCell(formatDate(iv.IssueDate)),
+			textCell(dueDate),
+			textCell(string(iv.InvoiceTypeCode)),
+			textCell(string(iv.DocumentCurrencyCode)),
+			textCell(supplierID),
+			textCell(supplierName),
+			textCell(customerID),
+			textCell(customerName),
+			numberCell(total.TaxExclusiveAmount.Amount),
+			numberCell(vatAmount),
+			numberCell(total.TaxInclusiveAmount.Amount),
+			numberCell(total.PayableAmount.Amount),
+		}
+
+		for _, line := range iv.InvoiceLines {
+			var sellerItemID string
+			if line.Item.SellerItemID != nil {
+				sellerItemID = line.Item.SellerItemID.ID
+			}
+			row := append(append([]xlsxCell(nil), invoiceCells...),
+				textCell(line.ID),
+				textCell(line.Item.Name),
+				textCell(sellerItemID),
+				numberCell(line.InvoicedQuantity.Quantity),
+				textCell(string(line.InvoicedQuantity.UnitCode)),
+				numberCell(line.Price.PriceAmount.Amount),
+				numberCell(line.LineExtensionAmount.Amount),
+				textCell(string(line.Item.TaxCategory.ID)),
+				numberCell(line.Item.TaxCategory.Percent),
+				textCell(line.Note),
+			)
+			sheet.rows = append(sheet.rows, row)
+		}
+	}
+	return sheet
+}
+
+func textCell(s string) xlsxCell {
+	return xlsxCell{value: s}
+}
+
+// numberCell returns a numeric cell, or an empty text cell if d is not set.
+func numberCell(d types.Decimal) xlsxCell {
+	if !d.IsInitialized() {
+		return xlsxCell{}
+	}
+	return xlsxCell{value: d.String(), numeric: true}
+}
+
+func formatDate(d types.Date) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(time.DateOnly)
+}
+
+func partyID(taxScheme *efactura.InvoicePartyTaxScheme, companyID *efactura.ValueWithAttrs) string {
+	if taxScheme != nil && taxScheme.CompanyID != "" {
+		return taxScheme.CompanyID
+	}
+	if companyID != nil {
+		return companyID.Value
+	}
+	return ""
+}
+
+func supplierParty(party efactura.InvoiceSupplierParty) (id, name string) {
+	return partyID(party.TaxScheme, party.LegalEntity.CompanyID), party.LegalEntity.Name
+}
+
+func customerParty(party efactura.InvoiceCustomerParty) (id, name string) {
+	return partyID(party.TaxScheme, party.LegalEntity.CompanyID), party.LegalEntity.Name
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func TestXLSXColumnName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("A", xlsxColumnName(0))
+	assert.Equal("Z", xlsxColumnName(25))
+	assert.Equal("AA", xlsxColumnName(26))
+	assert.Equal("AZ", xlsxColumnName(51))
+	assert.Equal("BA", xlsxColumnName(52))
+}
+
+func TestWriteInvoicesXLSX(t *testing.T) {
+	assert := assert.New(t)
+
+	invoice := efactura.Invoice{
+		ID:                   "INV & 1",
+		IssueDate:            types.MakeDate(2024, 3, 1),
+		DueDate:              types.NewDate(2024, 3, 31),
+		InvoiceTypeCode:      efactura.InvoiceTypeCommercialInvoice,
+		DocumentCurrencyCode: efactura.CurrencyRON,
+		LegalMonetaryTotal: efactura.InvoiceLegalMonetaryTotal{
+			TaxExclusiveAmount: efactura.AmountWithCurrency{Amount: types.D(30)},
+			TaxInclusiveAmount: efactura.AmountWithCurrency{Amount: types.D(35.7)},
+			PayableAmount:      efactura.AmountWithCurrency{Amount: types.D(35.7)},
+		},
+		InvoiceLines: []efactura.InvoiceLine{{
+			ID:                  "1",
+			InvoicedQuantity:    efactura.InvoicedQuantity{Quantity: types.D(3), UnitCode: "H87"},
+			LineExtensionAmount: efactura.AmountWithCurrency{Amount: types.D(30)},
+			Item:                efactura.InvoiceLineItem{Name: "Item <1>"},
+			Price:               efactura.InvoiceLinePrice{PriceAmount: efactura.AmountWithCurrency{Amount: types.D(10)}},
+		}, {
+			ID:   "2",
+			Item: efactura.InvoiceLineItem{Name: "Item 2"},
+		}},
+	}
+	invoice.Supplier.Party.LegalEntity.Name = "Supplier SRL"
+	invoice.Customer.Party.TaxScheme = &efactura.InvoicePartyTaxScheme{CompanyID: "RO123"}
+
+	var buf bytes.Buffer
+	if !assert.NoError(WriteInvoicesXLSX(&buf, []efactura.Invoice{invoice}, nil)) {
+		return
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(err) {
+		return
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if !assert.NoError(err) {
+			return
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		assert.NoError(err)
+		files[f.Name] = string(data)
+
+		// All the parts must be well-formed XML.
+		d := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := d.Token(); err != nil {
+				assert.ErrorIs(err, io.EOF, f.Name)
+				break
+			}
+		}
+	}
+	for _, name := range []string{
+		"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml",
+	} {
+		assert.Contains(files, name)
+	}
+	assert.Contains(files["xl/workbook.xml"], `<sheet name="Sent" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(files["xl/workbook.xml"], `<sheet name="Received" sheetId="2" r:id="rId2"/>`)
+
+	sent := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(sent, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">Invoice ID</t></is></c>`)
+	assert.Contains(sent, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">INV &amp; 1</t></is></c>`)
+	assert.Contains(sent, `<c r="C2" t="inlineStr"><is><t xml:space="preserve">2024-03-31</t></is></c>`)
+	assert.Contains(sent, `<c r="H2" t="inlineStr"><is><t xml:space="preserve">RO123</t></is></c>`)
+	assert.Contains(sent, `<c r="L2"><v>35.7</v></c>`)
+	assert.Contains(sent, `<c r="O2" t="inlineStr"><is><t xml:space="preserve">Item &lt;1&gt;</t></is></c>`)
+	assert.Contains(sent, `<c r="Q2"><v>3</v></c>`)
+	assert.Contains(sent, `<row r="3">`)
+	assert.NotContains(sent, `<row r="4">`)
+
+	received := files["xl/worksheets/sheet2.xml"]
+	assert.Contains(received, `<row r="1">`)
+	assert.NotContains(received, `<row r="2">`)
+}
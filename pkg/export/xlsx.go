@@ -0,0 +1,164 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/printesoi/xml-go"
+)
+
+// xlsxCell is a cell of a worksheet. If numeric is true, value must be a
+// valid number and the cell is written as a number, otherwise the cell is
+// written as an inline string.
+type xlsxCell struct {
+	value   string
+	numeric bool
+	bold    bool
+}
+
+type xlsxSheet struct {
+	name string
+	rows [][]xlsxCell
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`%s</Types>`
+	xlsxContentTypeSheet = `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets>%s</sheets></workbook>`
+	xlsxWorkbookSheet = `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s` +
+		`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookRelsSheet = `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`
+
+	// xlsxStyles defines two cell formats: 0 is the default, 1 is bold
+	// (used for the header row).
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`
+
+	xlsxWorksheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxWorksheetFooter = `</sheetData></worksheet>`
+)
+
+// xlsxColumnName returns the name of the column with the 0-based index col
+// (A, B, ..., Z, AA, AB, ...).
+func xlsxColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+func xlsxEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeXLSX writes a workbook with the given sheets to w.
+func writeXLSX(w io.Writer, sheets []xlsxSheet) error {
+	var contentTypes, workbookSheets, workbookRels bytes.Buffer
+	for i, sheet := range sheets {
+		fmt.Fprintf(&contentTypes, xlsxContentTypeSheet, i+1)
+		fmt.Fprintf(&workbookSheets, xlsxWorkbookSheet, xlsxEscape(sheet.name), i+1, i+1)
+		fmt.Fprintf(&workbookRels, xlsxWorkbookRelsSheet, i+1, i+1)
+	}
+
+	zw := zip.NewWriter(w)
+	writeFile := func(name, content string) error {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(fw, content)
+		return err
+	}
+	files := []struct {
+		name, content string
+	}{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, contentTypes.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, workbookSheets.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxWorkbookRels, workbookRels.String(), len(sheets)+1)},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, file := range files {
+		if err := writeFile(file.name, file.content); err != nil {
+			return err
+		}
+	}
+	for i, sheet := range sheets {
+		if err := writeFile(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xml returns the worksheet XML of the sheet.
+func (s xlsxSheet) xml() string {
+	var b bytes.Buffer
+	b.WriteString(xlsxWorksheetHeader)
+	for r, row := range s.rows {
+		rowNum := strconv.Itoa(r + 1)
+		b.WriteString(`<row r="` + rowNum + `">`)
+		for c, cell := range row {
+			ref := xlsxColumnName(c) + rowNum
+			style := ""
+			if cell.bold {
+				style = ` s="1"`
+			}
+			if cell.numeric {
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, cell.value)
+			} else {
+				fmt.Fprintf(&b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+					ref, style, xlsxEscape(cell.value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(xlsxWorksheetFooter)
+	return b.String()
+}
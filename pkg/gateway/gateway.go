@@ -0,0 +1,373 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package gateway exposes the e-factura client as REST endpoints, so that
+// applications not written in Go can integrate with e-factura over HTTP
+// instead of linking the library. The gateway is meant to listen on
+// localhost (see cmd/efactura-gateway).
+//
+// The endpoints are:
+//
+//	POST /v1/invoices?cif=CIF         upload an invoice (JSON or XML body)
+//	GET  /v1/uploads/{index}/state    get the state of an uploaded message
+//	GET  /v1/downloads/{id}           download the zip with the invoice
+//	POST /v1/validate                 validate an invoice (JSON or XML body)
+//	GET  /healthz                     health check
+//
+// The errors are returned as a JSON object with the "error" field.
+package gateway
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	ferrors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+const (
+	// DefaultMaxBodySize is the default maximum size of the request bodies.
+	DefaultMaxBodySize = 10 << 20
+
+	contentTypeJSON = "application/json"
+	contentTypeXML  = "application/xml"
+	contentTypeZip  = "application/zip"
+)
+
+// Config is the config used to create a Gateway.
+type Config struct {
+	// AuthToken is the bearer token required by all the endpoints except
+	// the health check (optional). If empty, the requests are not
+	// authenticated.
+	AuthToken string
+	// MaxBodySize is the maximum size of the request bodies (default
+	// DefaultMaxBodySize).
+	MaxBodySize int64
+}
+
+// ConfigOption allows gradually modifying a Config.
+type ConfigOption func(*Config)
+
+// ConfigAuthToken sets the bearer token required by the endpoints (sent in
+// the Authorization header as "Bearer <token>").
+func ConfigAuthToken(token string) ConfigOption {
+	return func(c *Config) {
+		c.AuthToken = token
+	}
+}
+
+// ConfigMaxBodySize sets the maximum size of the request bodies.
+func ConfigMaxBodySize(size int64) ConfigOption {
+	return func(c *Config) {
+		c.MaxBodySize = size
+	}
+}
+
+// Gateway is an http.Handler exposing the e-factura APIs as REST endpoints.
+type Gateway struct {
+	api efactura.API
+	cfg Config
+	mux *http.ServeMux
+}
+
+// New creates a new Gateway backed by the given e-factura API (usually an
+// *efactura.Client).
+func New(api efactura.API, opts ...ConfigOption) *Gateway {
+	cfg := Config{
+		MaxBodySize: DefaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	g := &Gateway{
+		api: api,
+		cfg: cfg,
+		mux: http.NewServeMux(),
+	}
+	g.mux.HandleFunc("POST /v1/invoices", g.authenticated(g.handleUpload))
+	g.mux.HandleFunc("GET /v1/uploads/{index}/state", g.authenticated(g.handleMessageState))
+	g.mux.HandleFunc("GET /v1/downloads/{id}", g.authenticated(g.handleDownload))
+	g.mux.HandleFunc("POST /v1/validate", g.authenticated(g.handleValidate))
+	g.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return g
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// UploadResponse is the response of the upload endpoint.
+type UploadResponse struct {
+	UploadIndex   int64  `json:"upload_index"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// MessageStateResponse is the response of the message state endpoint.
+type MessageStateResponse struct {
+	State      efactura.GetMessageStateCode `json:"state"`
+	DownloadID int64                        `json:"download_id,omitempty"`
+	Error      string                       `json:"error,omitempty"`
+}
+
+// ValidateResponse is the response of the validate endpoint.
+type ValidateResponse struct {
+	Valid    bool     `json:"valid"`
+	Messages []string `json:"messages,omitempty"`
+}
+
+// ErrorResponse is the response of all the endpoints in case of errors.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Limit is the ANAF limit that was exceeded for the day, only set for
+	// the responses with status 429 Too Many Requests.
+	Limit int64 `json:"limit,omitempty"`
+	// TraceID is the trace ID of the failed ANAF response, if any.
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+func (g *Gateway) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	if g.cfg.AuthToken == "" {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(g.cfg.AuthToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid or missing bearer token"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (g *Gateway) handleUpload(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cif := query.Get("cif")
+	if cif == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "missing cif"})
+		return
+	}
+	var opts []efactura.UploadOption
+	for _, o := range []struct {
+		param string
+		opt   efactura.UploadOption
+	}{
+		{"foreign", efactura.UploadOptionForeign()},
+		{"self_billed", efactura.UploadOptionSelfBilled()},
+		{"enforcement", efactura.UploadOptionEnforcement()},
+	} {
+		set, err := queryBool(query.Get(o.param))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid %s: %v", o.param, err)})
+			return
+		}
+		if set {
+			opts = append(opts, o.opt)
+		}
+	}
+
+	body, isJSON, ok := g.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	var res *efactura.UploadResponse
+	var err error
+	if isJSON {
+		var invoice efactura.Invoice
+		if !decodeInvoice(w, body, &invoice) {
+			return
+		}
+		res, err = g.api.UploadInvoice(r.Context(), invoice, cif, opts...)
+	} else {
+		st := efactura.UploadStandardUBL
+		if s := query.Get("standard"); s != "" {
+			st = efactura.UploadStandard(strings.ToUpper(s))
+		}
+		res, err = g.api.UploadXML(r.Context(), bytes.NewReader(body), st, cif, opts...)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !res.IsOk() {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: res.GetFirstErrorMessage()})
+		return
+	}
+	writeJSON(w, http.StatusOK, UploadResponse{
+		UploadIndex:   res.GetUploadIndex(),
+		CorrelationID: res.CorrelationID,
+	})
+}
+
+func (g *Gateway) handleMessageState(w http.ResponseWriter, r *http.Request) {
+	uploadIndex, err := strconv.ParseInt(r.PathValue("index"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid upload index"})
+		return
+	}
+	res, err := g.api.GetMessageState(r.Context(), uploadIndex)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, MessageStateResponse{
+		State:      res.State,
+		DownloadID: res.GetDownloadID(),
+		Error:      res.GetFirstErrorMessage(),
+	})
+}
+
+func (g *Gateway) handleDownload(w http.ResponseWriter, r *http.Request) {
+	downloadID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid download id"})
+		return
+	}
+	res, err := g.api.DownloadInvoice(r.Context(), downloadID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !res.IsOk() {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: res.Error.Error})
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeZip)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%d.zip"`, downloadID))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(res.Zip)
+}
+
+func (g *Gateway) handleValidate(w http.ResponseWriter, r *http.Request) {
+	body, isJSON, ok := g.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	var res *efactura.ValidateResponse
+	var err error
+	if isJSON {
+		var invoice efactura.Invoice
+		if !decodeInvoice(w, body, &invoice) {
+			return
+		}
+		res, err = g.api.ValidateInvoice(r.Context(), invoice)
+	} else {
+		st := efactura.ValidateStandardFACT1
+		if s := r.URL.Query().Get("standard"); s != "" {
+			st = efactura.ValidateStandard(strings.ToUpper(s))
+		}
+		res, err = g.api.ValidateXML(r.Context(), bytes.NewReader(body), st)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := ValidateResponse{Valid: res.IsOk()}
+	for _, m := range res.Messages {
+		response.Messages = append(response.Messages, m.Message)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// readBody reads the request body, limited to MaxBodySize. It returns whether
+// the body is JSON (or XML otherwise) and false if an error response was
+// written.
+func (g *Gateway) readBody(w http.ResponseWriter, r *http.Request) (body []byte, isJSON bool, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case err != nil:
+		writeJSON(w, http.StatusUnsupportedMediaType, ErrorResponse{Error: "missing or invalid Content-Type"})
+		return
+	case mediaType == contentTypeJSON:
+		isJSON = true
+	case mediaType == contentTypeXML || mediaType == "text/xml":
+	default:
+		writeJSON(w, http.StatusUnsupportedMediaType, ErrorResponse{Error: "unsupported Content-Type " + mediaType})
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(http.MaxBytesReader(w, r.Body, g.cfg.MaxBodySize)); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+		} else {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		return
+	}
+	return buf.Bytes(), isJSON, true
+}
+
+// decodeInvoice decodes the JSON encoded invoice from body. It returns false
+// if an error response was written.
+func decodeInvoice(w http.ResponseWriter, body []byte, invoice *efactura.Invoice) bool {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.DisallowUnknownFields()
+	if err := d.Decode(invoice); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid invoice: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// writeError writes the response for an error returned by the e-factura
+// client: 429 for exceeded limits, 502 for failed ANAF responses and 500
+// otherwise.
+func writeError(w http.ResponseWriter, err error) {
+	var limitErr *ferrors.LimitExceededError
+	var responseErr *ferrors.ErrorResponse
+	switch {
+	case errors.As(err, &limitErr):
+		res := ErrorResponse{Error: err.Error(), Limit: limitErr.Limit}
+		if limitErr.ErrorResponse != nil && limitErr.TraceID != nil {
+			res.TraceID = *limitErr.TraceID
+		}
+		writeJSON(w, http.StatusTooManyRequests, res)
+	case errors.As(err, &responseErr):
+		res := ErrorResponse{Error: err.Error()}
+		if responseErr.TraceID != nil {
+			res.TraceID = *responseErr.TraceID
+		}
+		writeJSON(w, http.StatusBadGateway, res)
+	default:
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func queryBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	xoauth2 "golang.org/x/oauth2"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/gateway"
+)
+
+// setupTestGateway sets up a test HTTP server simulating the ANAF APIs along
+// with a Gateway backed by an efactura.Client talking to that test server.
+func setupTestGateway(t *testing.T, opts ...gateway.ConfigOption) (*gateway.Gateway, *http.ServeMux) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	apiClient, err := client.NewApiClient(
+		client.ApiClientContext(context.Background()),
+		client.ApiClientBaseURL(server.URL+"/"),
+		client.ApiClientOAuth2TokenSource(xoauth2.StaticTokenSource(&xoauth2.Token{
+			AccessToken: "test-access-token",
+		})),
+	)
+	if err != nil {
+		t.Fatalf("error creating api client: %v", err)
+	}
+	publicApiClient, err := client.NewPublicApiClient(
+		client.PublicApiClientBaseURL(server.URL + "/"),
+	)
+	if err != nil {
+		t.Fatalf("error creating public api client: %v", err)
+	}
+	c, err := efactura.NewClient(
+		efactura.ClientApiClient(apiClient),
+		efactura.ClientPublicApiClient(publicApiClient),
+	)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return gateway.New(c, opts...), mux
+}
+
+func doRequest(g *gateway.Gateway, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("error decoding response %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestGatewayUpload(t *testing.T) {
+	assert := assert.New(t)
+
+	g, mux := setupTestGateway(t)
+	var uploads []string
+	mux.HandleFunc("/FCTEL/rest/upload", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads = append(uploads, r.URL.RawQuery+" "+string(body))
+		w.Header().Set("Content-Type", "application/xml")
+		if strings.Contains(string(body), "INVALID") {
+			_, _ = io.WriteString(w, `<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="1"><Errors errorMessage="Fisierul transmis nu este valid."/></header>`)
+			return
+		}
+		_, _ = io.WriteString(w, `<header xmlns="mfp:anaf:dgti:spv:respUploadFisier:v1" dateResponse="202401021504" ExecutionStatus="0" index_incarcare="5001"/>`)
+	})
+
+	rec := doRequest(g, http.MethodPost, "/v1/invoices?cif=123456789", "application/json", `{"id":"INV-1","issueDate":"2024-03-01"}`)
+	if assert.Equal(http.StatusOK, rec.Code) {
+		var res gateway.UploadResponse
+		decodeBody(t, rec, &res)
+		assert.Equal(int64(5001), res.UploadIndex)
+		assert.NotEmpty(res.CorrelationID)
+	}
+	if assert.Len(uploads, 1) {
+		assert.Contains(uploads[0], "standard=UBL")
+		assert.Contains(uploads[0], "<cbc:ID>INV-1</cbc:ID>")
+	}
+
+	rec = doRequest(g, http.MethodPost, "/v1/invoices?cif=123456789&standard=cn&foreign=true", "application/xml; charset=utf-8", `<CreditNote/>`)
+	assert.Equal(http.StatusOK, rec.Code)
+	if assert.Len(uploads, 2) {
+		assert.Contains(uploads[1], "standard=CN")
+		assert.Contains(uploads[1], "extern=DA")
+		assert.Contains(uploads[1], "<CreditNote/>")
+	}
+
+	rec = doRequest(g, http.MethodPost, "/v1/invoices?cif=123456789", "application/xml", `<Invoice>INVALID</Invoice>`)
+	if assert.Equal(http.StatusUnprocessableEntity, rec.Code) {
+		var res gateway.ErrorResponse
+		decodeBody(t, rec, &res)
+		assert.Equal("Fisierul transmis nu este valid.", res.Error)
+	}
+
+	// Invalid requests are not sent to ANAF.
+	assert.Equal(http.StatusBadRequest, doRequest(g, http.MethodPost, "/v1/invoices", "application/xml", `<Invoice/>`).Code)
+	assert.Equal(http.StatusBadRequest, doRequest(g, http.MethodPost, "/v1/invoices?cif=123456789&foreign=maybe", "application/xml", `<Invoice/>`).Code)
+	assert.Equal(http.StatusBadRequest, doRequest(g, http.MethodPost, "/v1/invoices?cif=123456789", "application/json", `{"unknown":1}`).Code)
+	assert.Equal(http.StatusUnsupportedMediaType, doRequest(g, http.MethodPost, "/v1/invoices?cif=123456789", "text/plain", `x`).Code)
+	assert.Len(uploads, 3)
+}
+
+func TestGatewayMessageStateAndDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	g, mux := setupTestGateway(t)
+	mux.HandleFunc("/FCTEL/rest/stareMesaj", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("id_incarcare") {
+		case "5001":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = io.WriteString(w, `<header xmlns="mfp:anaf:dgti:efactura:stareMesajFactura:v1" stare="ok" id_descarcare="3001"/>`)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, `{"eroare":"S-au facut deja 100 interogari de stare pentru mesajul cu id_incarcare=5002 in cursul zilei","titlu":"Stare mesaj"}`)
+		}
+	})
+	mux.HandleFunc("/FCTEL/rest/descarcare", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") == "3001" {
+			w.Header().Set("Content-Type", "application/zip")
+			_, _ = io.WriteString(w, "PK-zip-data")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"eroare":"Pentru id=3002 nu exista inregistrata nici o factura","titlu":"Descarcare mesaj"}`)
+	})
+
+	rec := doRequest(g, http.MethodGet, "/v1/uploads/5001/state", "", "")
+	if assert.Equal(http.StatusOK, rec.Code) {
+		var res gateway.MessageStateResponse
+		decodeBody(t, rec, &res)
+		assert.Equal(efactura.GetMessageStateCodeOk, res.State)
+		assert.Equal(int64(3001), res.DownloadID)
+	}
+
+	rec = doRequest(g, http.MethodGet, "/v1/uploads/5002/state", "", "")
+	if assert.Equal(http.StatusTooManyRequests, rec.Code) {
+		var res gateway.ErrorResponse
+		decodeBody(t, rec, &res)
+		assert.Equal(int64(100), res.Limit)
+	}
+	assert.Equal(http.StatusBadRequest, doRequest(g, http.MethodGet, "/v1/uploads/abc/state", "", "").Code)
+
+	rec = doRequest(g, http.MethodGet, "/v1/downloads/3001", "", "")
+	if assert.Equal(http.StatusOK, rec.Code) {
+		assert.Equal("application/zip", rec.Header().Get("Content-Type"))
+		assert.Equal("PK-zip-data", rec.Body.String())
+	}
+	rec = doRequest(g, http.MethodGet, "/v1/downloads/3002", "", "")
+	if assert.Equal(http.StatusUnprocessableEntity, rec.Code) {
+		var res gateway.ErrorResponse
+		decodeBody(t, rec, &res)
+		assert.Contains(res.Error, "nu exista inregistrata")
+	}
+}
+
+func TestGatewayValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	g, mux := setupTestGateway(t)
+	mux.HandleFunc("/FCTEL/rest/validare/FCN", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"stare":"nok","Messages":[{"message":"E: eroare validare"}],"trace_id":"t-1"}`)
+	})
+
+	rec := doRequest(g, http.MethodPost, "/v1/validate?standard=fcn", "text/xml", `<CreditNote/>`)
+	if assert.Equal(http.StatusOK, rec.Code) {
+		var res gateway.ValidateResponse
+		decodeBody(t, rec, &res)
+		assert.False(res.Valid)
+		assert.Equal([]string{"E: eroare validare"}, res.Messages)
+	}
+}
+
+func TestGatewayAuthAndLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	g, _ := setupTestGateway(t, gateway.ConfigAuthToken("secret"), gateway.ConfigMaxBodySize(16))
+
+	assert.Equal(http.StatusNoContent, doRequest(g, http.MethodGet, "/healthz", "", "").Code)
+	assert.Equal(http.StatusUnauthorized, doRequest(g, http.MethodGet, "/v1/uploads/5001/state", "", "").Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/invoices?cif=123456789", strings.NewReader(strings.Repeat("x", 32)))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/xml")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	assert.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+}
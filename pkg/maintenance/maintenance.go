@@ -0,0 +1,323 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package maintenance provides support for parsing the maintenance windows
+// announced by ANAF, so that callers can plan around the announced downtime
+// of the APIs instead of discovering it by failed requests.
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+)
+
+// Service is an ANAF service that can be affected by a maintenance window.
+type Service string
+
+const (
+	// ServiceAll is used for maintenance windows that affect all services.
+	ServiceAll Service = ""
+	// ServiceEFactura is the RO e-Factura service.
+	ServiceEFactura Service = "efactura"
+	// ServiceETransport is the RO e-Transport service.
+	ServiceETransport Service = "etransport"
+	// ServiceOAuth2 is the ANAF OAuth2 authorization service.
+	ServiceOAuth2 Service = "oauth2"
+)
+
+// dateTimeLayout is the layout accepted for times in JSON schedules, besides
+// RFC 3339. Times in this layout are in the Romanian timezone.
+const dateTimeLayout = "2006-01-02 15:04"
+
+// Window is a maintenance window during which the services are unavailable.
+type Window struct {
+	// Start of the maintenance window.
+	Start time.Time `json:"start"`
+	// End of the maintenance window (exclusive).
+	End time.Time `json:"end"`
+	// Services affected by the maintenance. If empty, the maintenance
+	// affects all services.
+	Services []Service `json:"services,omitempty"`
+	// Description of the maintenance, as announced.
+	Description string `json:"description,omitempty"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Besides RFC 3339,
+// the start and end times can be in the "YYYY-MM-DD HH:MM" format, in which
+// case they are assumed to be in the Romanian timezone.
+func (w *Window) UnmarshalJSON(data []byte) error {
+	var tw struct {
+		Start       string    `json:"start"`
+		End         string    `json:"end"`
+		Services    []Service `json:"services"`
+		Description string    `json:"description"`
+	}
+	if err := json.Unmarshal(data, &tw); err != nil {
+		return err
+	}
+
+	start, err := parseTime(tw.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	end, err := parseTime(tw.End)
+	if err != nil {
+		return fmt.Errorf("invalid end: %w", err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end %s is not after start %s", tw.End, tw.Start)
+	}
+
+	*w = Window{
+		Start:       start,
+		End:         end,
+		Services:    tw.Services,
+		Description: tw.Description,
+	}
+	return nil
+}
+
+// Affects returns true if the maintenance window affects the given service.
+// ServiceAll is affected by any maintenance window.
+func (w Window) Affects(service Service) bool {
+	if service == ServiceAll || len(w.Services) == 0 {
+		return true
+	}
+	for _, s := range w.Services {
+		if s == service || s == ServiceAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns true if t is inside the maintenance window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Schedule is a list of maintenance windows.
+type Schedule struct {
+	Windows []Window `json:"windows"`
+}
+
+// ParseJSON parses a maintenance schedule from JSON. The expected format is:
+//
+//	{
+//	  "windows": [
+//	    {
+//	      "start": "2024-05-12 18:00",
+//	      "end": "2024-05-12T23:00:00+03:00",
+//	      "services": ["efactura", "etransport"],
+//	      "description": "Lucrari de mentenanta"
+//	    }
+//	  ]
+//	}
+func ParseJSON(data []byte) (*Schedule, error) {
+	s := new(Schedule)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	s.sort()
+	return s, nil
+}
+
+// LoadJSON is like ParseJSON, but reads the schedule from the given reader.
+func LoadJSON(r io.Reader) (*Schedule, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSON(data)
+}
+
+// Add adds the given windows to the schedule.
+func (s *Schedule) Add(windows ...Window) {
+	s.Windows = append(s.Windows, windows...)
+	s.sort()
+}
+
+func (s *Schedule) sort() {
+	sort.SliceStable(s.Windows, func(i, j int) bool {
+		return s.Windows[i].Start.Before(s.Windows[j].Start)
+	})
+}
+
+// Active returns the maintenance window affecting service that contains t.
+// If no such window exists, ok is false.
+func (s *Schedule) Active(service Service, t time.Time) (window Window, ok bool) {
+	if s == nil {
+		return
+	}
+	for _, w := range s.Windows {
+		if w.Affects(service) && w.Contains(t) {
+			return w, true
+		}
+	}
+	return
+}
+
+// Next returns the first maintenance window affecting service that starts
+// after t. If no such window exists, ok is false.
+func (s *Schedule) Next(service Service, t time.Time) (window Window, ok bool) {
+	if s == nil {
+		return
+	}
+	for _, w := range s.Windows {
+		if w.Affects(service) && w.Start.After(t) {
+			return w, true
+		}
+	}
+	return
+}
+
+// AvailableAt returns the first time, not before t, when service is not
+// affected by any maintenance window. Consecutive or overlapping windows are
+// skipped.
+func (s *Schedule) AvailableAt(service Service, t time.Time) time.Time {
+	for {
+		w, ok := s.Active(service, t)
+		if !ok {
+			return t
+		}
+		t = w.End
+	}
+}
+
+var (
+	// "12.05.2024, intre orele 18:00 - 23:00" or
+	// "12.05.2024 in intervalul orar 18:00-23:00"
+	regexAnnouncementInterval = regexp.MustCompile(
+		`(?i)(\d{1,2})[./](\d{1,2})[./](\d{4})[^\d]{0,40}?(\d{1,2})[:.](\d{2})\s*(?:-|–|pana la|până la)\s*(\d{1,2})[:.](\d{2})`)
+	// "de la 12.05.2024 ora 18:00 pana la 13.05.2024 ora 06:00"
+	regexAnnouncementRange = regexp.MustCompile(
+		`(?i)(\d{1,2})[./](\d{1,2})[./](\d{4})[^\d]{0,20}?(\d{1,2})[:.](\d{2})\D{0,30}?(?:pana la|până la|-|–)\s*(\d{1,2})[./](\d{1,2})[./](\d{4})[^\d]{0,20}?(\d{1,2})[:.](\d{2})`)
+)
+
+// ParseAnnouncement extracts the maintenance windows from the text of an ANAF
+// announcement (eg. scraped from the ANAF website). The text is expected to
+// contain intervals like "12.05.2024, intre orele 18:00 - 23:00" or
+// "de la 12.05.2024 ora 18:00 pana la 13.05.2024 ora 06:00". Times are in the
+// Romanian timezone. The affected services are detected from the text; if no
+// known service is mentioned, the windows affect all services. If no interval
+// is found, an error is returned.
+func ParseAnnouncement(text string) ([]Window, error) {
+	services := detectServices(text)
+	description := strings.TrimSpace(text)
+
+	var windows []Window
+	var consumed [][]int
+	for _, m := range regexAnnouncementRange.FindAllStringSubmatchIndex(text, -1) {
+		g := submatches(text, m)
+		start, err := makeTime(g[1], g[2], g[3], g[4], g[5])
+		if err != nil {
+			return nil, err
+		}
+		end, err := makeTime(g[6], g[7], g[8], g[9], g[10])
+		if err != nil {
+			return nil, err
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("invalid maintenance interval %q", g[0])
+		}
+		windows = append(windows, Window{Start: start, End: end, Services: services, Description: description})
+		consumed = append(consumed, m[:2])
+	}
+	for _, m := range regexAnnouncementInterval.FindAllStringSubmatchIndex(text, -1) {
+		if overlaps(consumed, m[0], m[1]) {
+			continue
+		}
+		g := submatches(text, m)
+		start, err := makeTime(g[1], g[2], g[3], g[4], g[5])
+		if err != nil {
+			return nil, err
+		}
+		end, err := makeTime(g[1], g[2], g[3], g[6], g[7])
+		if err != nil {
+			return nil, err
+		}
+		if !end.After(start) {
+			// The interval ends in the next day (eg. 22:00 - 02:00).
+			end = end.AddDate(0, 0, 1)
+		}
+		windows = append(windows, Window{Start: start, End: end, Services: services, Description: description})
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no maintenance interval found")
+	}
+	sort.SliceStable(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows, nil
+}
+
+func detectServices(text string) (services []Service) {
+	lower := strings.ToLower(text)
+	if strings.Contains(lower, "e-factura") || strings.Contains(lower, "efactura") {
+		services = append(services, ServiceEFactura)
+	}
+	if strings.Contains(lower, "e-transport") || strings.Contains(lower, "etransport") {
+		services = append(services, ServiceETransport)
+	}
+	return
+}
+
+func submatches(text string, m []int) []string {
+	g := make([]string, len(m)/2)
+	for i := range g {
+		if m[2*i] >= 0 {
+			g[i] = text[m[2*i]:m[2*i+1]]
+		}
+	}
+	return g
+}
+
+func overlaps(ranges [][]int, start, end int) bool {
+	for _, r := range ranges {
+		if start < r[1] && r[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+func makeTime(day, month, year, hour, min string) (time.Time, error) {
+	var v [5]int
+	for i, s := range []string{day, month, year, hour, min} {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		v[i] = n
+	}
+	if v[1] < 1 || v[1] > 12 || v[0] < 1 || v[0] > 31 || v[3] > 24 || v[4] > 59 {
+		return time.Time{}, fmt.Errorf("invalid date/time %s.%s.%s %s:%s", day, month, year, hour, min)
+	}
+	return itime.Date(v[2], time.Month(v[1]), v[0], v[3], v[4], 0, 0), nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return itime.ParseInRomania(dateTimeLayout, s)
+}
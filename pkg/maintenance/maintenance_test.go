@@ -0,0 +1,88 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+)
+
+func TestParseJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule, err := ParseJSON([]byte(`{
+		"windows": [
+			{"start": "2024-05-20 22:00", "end": "2024-05-21 02:00", "services": ["etransport"]},
+			{"start": "2024-05-12 18:00", "end": "2024-05-12T23:00:00+03:00", "services": ["efactura"], "description": "test"},
+			{"start": "2024-05-12 23:00", "end": "2024-05-13 01:00"}
+		]
+	}`))
+	if !assert.NoError(err) || !assert.Len(schedule.Windows, 3) {
+		return
+	}
+	assert.True(schedule.Windows[0].Start.Equal(itime.Date(2024, time.May, 12, 18, 0, 0, 0)))
+	assert.Equal("test", schedule.Windows[0].Description)
+
+	at := itime.Date(2024, time.May, 12, 19, 0, 0, 0)
+	w, ok := schedule.Active(ServiceEFactura, at)
+	if assert.True(ok) {
+		assert.Equal([]Service{ServiceEFactura}, w.Services)
+	}
+	_, ok = schedule.Active(ServiceETransport, at)
+	assert.False(ok)
+
+	// The e-factura window is followed by a window for all services.
+	assert.True(schedule.AvailableAt(ServiceEFactura, at).Equal(itime.Date(2024, time.May, 13, 1, 0, 0, 0)))
+	assert.True(schedule.AvailableAt(ServiceETransport, at).Equal(at))
+
+	next, ok := schedule.Next(ServiceETransport, at)
+	if assert.True(ok) {
+		assert.True(next.Start.Equal(itime.Date(2024, time.May, 12, 23, 0, 0, 0)))
+	}
+
+	_, err = ParseJSON([]byte(`{"windows": [{"start": "2024-05-12 18:00", "end": "2024-05-12 17:00"}]}`))
+	assert.Error(err)
+	_, err = ParseJSON([]byte(`{"windows": [{"start": "12.05.2024", "end": "2024-05-12 17:00"}]}`))
+	assert.Error(err)
+}
+
+func TestParseAnnouncement(t *testing.T) {
+	assert := assert.New(t)
+
+	windows, err := ParseAnnouncement("Va informam ca in data de 12.05.2024, intre orele 18:00 - 23:00, " +
+		"sistemul RO e-Factura va fi indisponibil. De asemenea, in 14.05.2024 in intervalul orar 22:00-02:00.")
+	if assert.NoError(err) && assert.Len(windows, 2) {
+		assert.True(windows[0].Start.Equal(itime.Date(2024, time.May, 12, 18, 0, 0, 0)))
+		assert.True(windows[0].End.Equal(itime.Date(2024, time.May, 12, 23, 0, 0, 0)))
+		assert.Equal([]Service{ServiceEFactura}, windows[0].Services)
+		assert.True(windows[1].Start.Equal(itime.Date(2024, time.May, 14, 22, 0, 0, 0)))
+		assert.True(windows[1].End.Equal(itime.Date(2024, time.May, 15, 2, 0, 0, 0)))
+	}
+
+	windows, err = ParseAnnouncement("Aplicatiile vor fi indisponibile de la 18.05.2024 ora 20:00 pana la 19.05.2024 ora 08:00.")
+	if assert.NoError(err) && assert.Len(windows, 1) {
+		assert.True(windows[0].Start.Equal(itime.Date(2024, time.May, 18, 20, 0, 0, 0)))
+		assert.True(windows[0].End.Equal(itime.Date(2024, time.May, 19, 8, 0, 0, 0)))
+		assert.Empty(windows[0].Services)
+		assert.True(windows[0].Affects(ServiceETransport))
+	}
+
+	_, err = ParseAnnouncement("Nicio lucrare programata.")
+	assert.Error(err)
+}
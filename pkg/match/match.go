@@ -0,0 +1,368 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package match compares a received invoice against the purchase order and
+// the goods receipt (a 3-way match), reporting the quantity, price and total
+// mismatches per line. The purchase order and the goods receipt are plain
+// structs filled by the user from the ERP, so this is a scaffold for
+// accounts payable automation built on parsed invoices.
+package match
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// PurchaseOrder is a purchase order.
+type PurchaseOrder struct {
+	// ID is the purchase order ID, matched against the order reference of
+	// the invoice (BT-13), if both are set.
+	ID string
+	// Currency is the currency of the prices. If set, it must be the
+	// invoice currency (BT-5).
+	Currency efactura.CurrencyCodeType
+	Lines    []OrderLine
+}
+
+// OrderLine is a line of a purchase order.
+type OrderLine struct {
+	// ID is the ID of the order line, only used in the reported mismatches.
+	ID string
+	// ItemID is the item ID, matched against the key of the invoice lines
+	// (see Config.LineKey).
+	ItemID string
+	// Name is the item name, used as the key if the ItemID is empty.
+	Name string
+	// Quantity is the ordered quantity.
+	Quantity types.Decimal
+	// UnitCode is the unit of measure of the quantity. If set, it must be
+	// the unit of measure of the invoiced quantity (BT-130).
+	UnitCode efactura.UnitCodeType
+	// UnitPrice is the net price per unit (without VAT).
+	UnitPrice types.Decimal
+}
+
+// GoodsReceipt is a goods receipt (NIR).
+type GoodsReceipt struct {
+	ID    string
+	Lines []ReceiptLine
+}
+
+// ReceiptLine is a line of a goods receipt.
+type ReceiptLine struct {
+	// ItemID is the item ID, matched like OrderLine.ItemID.
+	ItemID string
+	// Name is the item name, used as the key if the ItemID is empty.
+	Name string
+	// Quantity is the received quantity.
+	Quantity types.Decimal
+}
+
+// MismatchKind is the kind of a Mismatch.
+type MismatchKind string
+
+const (
+	// MismatchOrderReference means that the order reference of the invoice
+	// (BT-13) is not the purchase order ID.
+	MismatchOrderReference MismatchKind = "order_reference"
+	// MismatchCurrency means that the invoice currency is not the purchase
+	// order currency.
+	MismatchCurrency MismatchKind = "currency"
+	// MismatchUnorderedItem means that an invoice line has no matching
+	// order line.
+	MismatchUnorderedItem MismatchKind = "unordered_item"
+	// MismatchUnitCode means that the unit of measure of an invoice line is
+	// not the unit of measure of the order line.
+	MismatchUnitCode MismatchKind = "unit_code"
+	// MismatchPrice means that the net price of an invoice line is not the
+	// price of the order line.
+	MismatchPrice MismatchKind = "price"
+	// MismatchLineTotal means that the net amount of an invoice line
+	// (BT-131) is not the invoiced quantity multiplied by the order price.
+	MismatchLineTotal MismatchKind = "line_total"
+	// MismatchOrderedQuantity means that the quantity invoiced for an item
+	// exceeds the ordered quantity.
+	MismatchOrderedQuantity MismatchKind = "ordered_quantity"
+	// MismatchReceivedQuantity means that the quantity invoiced for an item
+	// exceeds the received quantity.
+	MismatchReceivedQuantity MismatchKind = "received_quantity"
+	// MismatchTotal means that the sum of the invoice line net amounts
+	// (BT-106) is not the sum of the expected line totals.
+	MismatchTotal MismatchKind = "total"
+)
+
+// Mismatch is a difference between the invoice and the purchase order or
+// the goods receipt.
+type Mismatch struct {
+	Kind MismatchKind
+	// Key is the item key, empty for the document level mismatches.
+	Key string
+	// InvoiceLineID is the ID of the invoice line (BT-126), empty for the
+	// document and item level mismatches.
+	InvoiceLineID string
+	// OrderLineID is the ID of the order line, if any.
+	OrderLineID string
+	// Expected is the value from the purchase order or the goods receipt.
+	Expected string
+	// Actual is the value from the invoice.
+	Actual string
+}
+
+// String returns a human readable description of the mismatch.
+func (m Mismatch) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(m.Kind))
+	if m.InvoiceLineID != "" {
+		fmt.Fprintf(&sb, " (invoice line %s)", m.InvoiceLineID)
+	} else if m.Key != "" {
+		fmt.Fprintf(&sb, " (item %s)", m.Key)
+	}
+	fmt.Fprintf(&sb, ": expected %q, got %q", m.Expected, m.Actual)
+	return sb.String()
+}
+
+// Result is the result of a Match.
+type Result struct {
+	Mismatches []Mismatch
+	// UninvoicedOrderLines are the IDs (or the keys, if the ID is empty) of
+	// the order lines not invoiced. This is not a mismatch, since an order
+	// can be invoiced in multiple invoices.
+	UninvoicedOrderLines []string
+}
+
+// Matched returns true if no mismatches were found.
+func (r Result) Matched() bool {
+	return len(r.Mismatches) == 0
+}
+
+// Config is the config used by Match.
+type Config struct {
+	// LineKey returns the key of an invoice line, matched against the keys
+	// of the order and receipt lines. Default is DefaultLineKey.
+	LineKey func(efactura.InvoiceLine) string
+	// QuantityTolerance is the allowed absolute difference between the
+	// quantities. Default is zero.
+	QuantityTolerance types.Decimal
+	// PriceTolerance is the allowed absolute difference between the unit
+	// prices. Default is zero.
+	PriceTolerance types.Decimal
+	// AmountTolerance is the allowed absolute difference between the
+	// amounts (line totals and total). Default is 0.01, for the rounding
+	// differences.
+	AmountTolerance types.Decimal
+}
+
+// ConfigOption allows gradually modifying a Config.
+type ConfigOption func(*Config)
+
+// ConfigLineKey sets the function returning the key of an invoice line.
+func ConfigLineKey(key func(efactura.InvoiceLine) string) ConfigOption {
+	return func(c *Config) {
+		c.LineKey = key
+	}
+}
+
+// ConfigQuantityTolerance sets the allowed difference between quantities.
+func ConfigQuantityTolerance(tolerance types.Decimal) ConfigOption {
+	return func(c *Config) {
+		c.QuantityTolerance = tolerance
+	}
+}
+
+// ConfigPriceTolerance sets the allowed difference between unit prices.
+func ConfigPriceTolerance(tolerance types.Decimal) ConfigOption {
+	return func(c *Config) {
+		c.PriceTolerance = tolerance
+	}
+}
+
+// ConfigAmountTolerance sets the allowed difference between amounts.
+func ConfigAmountTolerance(tolerance types.Decimal) ConfigOption {
+	return func(c *Config) {
+		c.AmountTolerance = tolerance
+	}
+}
+
+// DefaultLineKey returns the Seller's item identifier (BT-155), the item
+// standard identifier (BT-157) or the item name (BT-153), the first that is
+// not empty.
+func DefaultLineKey(line efactura.InvoiceLine) string {
+	if id := line.SellerItemID(); id != "" {
+		return id
+	}
+	if id := line.StandardItemID(); id != "" {
+		return id
+	}
+	return line.Item.Name
+}
+
+// normalizeKey normalizes a key, so the keys are matched ignoring the case
+// and the surrounding spaces.
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+func lineKey(itemID, name string) string {
+	if strings.TrimSpace(itemID) != "" {
+		return normalizeKey(itemID)
+	}
+	return normalizeKey(name)
+}
+
+// NetUnitPrice returns the net price of an invoice line for one unit of the
+// invoiced quantity: the item net price (BT-146) divided by the item price
+// base quantity (BT-149), if set.
+func NetUnitPrice(line efactura.InvoiceLine) types.Decimal {
+	price := line.Price.PriceAmount.Amount
+	if bq := line.Price.BaseQuantity; bq != nil && !bq.Quantity.IsZero() {
+		price = price.Div(bq.Quantity)
+	}
+	return price
+}
+
+// orderItem is the aggregated data of the order lines with the same key.
+type orderItem struct {
+	line     OrderLine
+	quantity types.Decimal
+	invoiced types.Decimal
+	seen     bool
+}
+
+// Match compares the invoice against the purchase order and the goods
+// receipt (optional, nil to only compare against the purchase order).
+//
+// The invoice lines are matched with the order and receipt lines by key
+// (see Config.LineKey, OrderLine.ItemID). If multiple order (or receipt)
+// lines have the same key, their quantities are summed and the price of
+// the first one is used. For each invoice line, the unit of measure, the
+// net unit price and the net amount are compared with the order line; for
+// each item, the total invoiced quantity is compared with the ordered and
+// the received quantities. Invoicing less than ordered or received is not
+// a mismatch, since an order can be invoiced in multiple invoices.
+func Match(iv efactura.Invoice, po PurchaseOrder, receipt *GoodsReceipt, opts ...ConfigOption) Result {
+	cfg := Config{
+		LineKey:           DefaultLineKey,
+		QuantityTolerance: types.Zero,
+		PriceTolerance:    types.Zero,
+		AmountTolerance:   types.D(0.01),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var res Result
+	if po.ID != "" {
+		if orderID := iv.OrderID(); orderID != "" && orderID != po.ID {
+			res.add(Mismatch{Kind: MismatchOrderReference, Expected: po.ID, Actual: orderID})
+		}
+	}
+	if po.Currency != "" && po.Currency != iv.DocumentCurrencyCode {
+		res.add(Mismatch{Kind: MismatchCurrency, Expected: string(po.Currency), Actual: string(iv.DocumentCurrencyCode)})
+	}
+
+	var keys []string
+	items := make(map[string]*orderItem)
+	for _, line := range po.Lines {
+		key := lineKey(line.ItemID, line.Name)
+		if item, ok := items[key]; ok {
+			item.quantity = item.quantity.Add(line.Quantity)
+			continue
+		}
+		keys = append(keys, key)
+		items[key] = &orderItem{line: line, quantity: line.Quantity, invoiced: types.Zero}
+	}
+
+	expectedTotal, actualTotal := types.Zero, types.Zero
+	for _, line := range iv.InvoiceLines {
+		key := normalizeKey(cfg.LineKey(line))
+		actualTotal = actualTotal.Add(line.LineExtensionAmount.Amount)
+		item, ok := items[key]
+		if !ok {
+			res.add(Mismatch{Kind: MismatchUnorderedItem, Key: key, InvoiceLineID: line.ID, Actual: line.Item.Name})
+			continue
+		}
+		item.seen = true
+		quantity := line.InvoicedQuantity.Quantity
+		item.invoiced = item.invoiced.Add(quantity)
+
+		mismatch := Mismatch{Key: key, InvoiceLineID: line.ID, OrderLineID: item.line.ID}
+		if unitCode := item.line.UnitCode; unitCode != "" && unitCode != line.InvoicedQuantity.UnitCode {
+			mismatch.Kind, mismatch.Expected, mismatch.Actual = MismatchUnitCode, string(unitCode), string(line.InvoicedQuantity.UnitCode)
+			res.add(mismatch)
+		}
+		if price := NetUnitPrice(line); !within(price, item.line.UnitPrice, cfg.PriceTolerance) {
+			mismatch.Kind, mismatch.Expected, mismatch.Actual = MismatchPrice, item.line.UnitPrice.String(), price.String()
+			res.add(mismatch)
+		}
+		expected := quantity.Mul(item.line.UnitPrice).AsAmount()
+		expectedTotal = expectedTotal.Add(expected)
+		if amount := line.LineExtensionAmount.Amount; !within(amount, expected, cfg.AmountTolerance) {
+			mismatch.Kind, mismatch.Expected, mismatch.Actual = MismatchLineTotal, expected.String(), amount.String()
+			res.add(mismatch)
+		}
+	}
+
+	received := make(map[string]types.Decimal)
+	if receipt != nil {
+		for _, line := range receipt.Lines {
+			key := lineKey(line.ItemID, line.Name)
+			if q, ok := received[key]; ok {
+				received[key] = q.Add(line.Quantity)
+			} else {
+				received[key] = line.Quantity
+			}
+		}
+	}
+	for _, key := range keys {
+		item := items[key]
+		if !item.seen {
+			id := item.line.ID
+			if id == "" {
+				id = key
+			}
+			res.UninvoicedOrderLines = append(res.UninvoicedOrderLines, id)
+			continue
+		}
+		if item.invoiced.Cmp(item.quantity.Add(cfg.QuantityTolerance)) > 0 {
+			res.add(Mismatch{Kind: MismatchOrderedQuantity, Key: key, OrderLineID: item.line.ID,
+				Expected: item.quantity.String(), Actual: item.invoiced.String()})
+		}
+		if receipt != nil {
+			q, ok := received[key]
+			if !ok {
+				q = types.Zero
+			}
+			if item.invoiced.Cmp(q.Add(cfg.QuantityTolerance)) > 0 {
+				res.add(Mismatch{Kind: MismatchReceivedQuantity, Key: key, OrderLineID: item.line.ID,
+					Expected: q.String(), Actual: item.invoiced.String()})
+			}
+		}
+	}
+	if !within(actualTotal, expectedTotal, cfg.AmountTolerance) {
+		res.add(Mismatch{Kind: MismatchTotal, Expected: expectedTotal.String(), Actual: actualTotal.String()})
+	}
+	return res
+}
+
+func (r *Result) add(m Mismatch) {
+	r.Mismatches = append(r.Mismatches, m)
+}
+
+// within returns true if |a - b| <= tolerance.
+func within(a, b, tolerance types.Decimal) bool {
+	return types.DD(a.Sub(b).Decimal.Abs()).Cmp(tolerance) <= 0
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package match_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/match"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func newTestLine(id, itemID, name string, quantity, price, amount float64) efactura.InvoiceLine {
+	line := efactura.InvoiceLine{
+		ID:                  id,
+		InvoicedQuantity:    efactura.InvoicedQuantity{Quantity: types.D(quantity), UnitCode: "C62"},
+		LineExtensionAmount: efactura.AmountWithCurrency{Amount: types.D(amount), CurrencyID: efactura.CurrencyRON},
+	}
+	line.Item.Name = name
+	if itemID != "" {
+		line.Item.SellerItemID = efactura.NewIDNode(itemID)
+	}
+	line.Price.PriceAmount = efactura.AmountWithCurrency{Amount: types.D(price), CurrencyID: efactura.CurrencyRON}
+	return line
+}
+
+func newTestOrder() match.PurchaseOrder {
+	return match.PurchaseOrder{
+		ID:       "PO-1",
+		Currency: efactura.CurrencyRON,
+		Lines: []match.OrderLine{
+			{ID: "1", ItemID: "A1", Quantity: types.D(10), UnitCode: "C62", UnitPrice: types.D(5)},
+			{ID: "2", Name: "Service", Quantity: types.D(1), UnitPrice: types.D(100)},
+			{ID: "3", ItemID: "C3", Quantity: types.D(2), UnitPrice: types.D(1)},
+		},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	iv := efactura.Invoice{
+		DocumentCurrencyCode: efactura.CurrencyRON,
+		OrderReference:       &efactura.InvoiceOrderReference{OrderID: "PO-1"},
+		InvoiceLines: []efactura.InvoiceLine{
+			newTestLine("1", "a1", "Item A", 4, 5, 20),
+			newTestLine("2", "A1", "Item A", 6, 5, 30),
+			newTestLine("3", "", " service ", 1, 100, 100),
+		},
+	}
+	receipt := &match.GoodsReceipt{Lines: []match.ReceiptLine{
+		{ItemID: "A1", Quantity: types.D(10)},
+	}}
+	res := match.Match(iv, newTestOrder(), receipt)
+	// The service was not received.
+	if assert.Len(res.Mismatches, 1) {
+		m := res.Mismatches[0]
+		assert.Equal(match.MismatchReceivedQuantity, m.Kind)
+		assert.Equal("service", m.Key)
+		assert.Equal("2", m.OrderLineID)
+		assert.Equal("0", m.Expected)
+		assert.Equal("1", m.Actual)
+	}
+	assert.Equal([]string{"3"}, res.UninvoicedOrderLines)
+	assert.True(match.Match(iv, newTestOrder(), nil).Matched())
+}
+
+func TestMatchMismatches(t *testing.T) {
+	assert := assert.New(t)
+
+	priceLine := newTestLine("2", "A1", "Item A", 2, 5.5, 11)
+	priceLine.Price.BaseQuantity = &efactura.InvoicedQuantity{Quantity: types.D(1), UnitCode: "C62"}
+	iv := efactura.Invoice{
+		DocumentCurrencyCode: efactura.CurrencyEUR,
+		OrderReference:       &efactura.InvoiceOrderReference{OrderID: "PO-2"},
+		InvoiceLines: []efactura.InvoiceLine{
+			newTestLine("1", "A1", "Item A", 10, 5, 50),
+			priceLine,
+			newTestLine("3", "X9", "Unknown", 1, 1, 1),
+			newTestLine("4", "C3", "Item C", 2, 1, 3),
+		},
+	}
+	res := match.Match(iv, newTestOrder(), nil)
+	assert.False(res.Matched())
+
+	var kinds []match.MismatchKind
+	for _, m := range res.Mismatches {
+		kinds = append(kinds, m.Kind)
+	}
+	assert.Equal([]match.MismatchKind{
+		match.MismatchOrderReference,
+		match.MismatchCurrency,
+		match.MismatchPrice,
+		match.MismatchLineTotal,
+		match.MismatchUnorderedItem,
+		match.MismatchLineTotal,
+		match.MismatchOrderedQuantity,
+		match.MismatchTotal,
+	}, kinds)
+	assert.Equal("price (invoice line 2): expected \"5\", got \"5.5\"", res.Mismatches[2].String())
+	assert.Equal("ordered_quantity (item a1): expected \"10\", got \"12\"", res.Mismatches[6].String())
+	assert.Equal("62", res.Mismatches[7].Expected)
+	assert.Equal("65", res.Mismatches[7].Actual)
+	assert.Equal([]string{"2"}, res.UninvoicedOrderLines)
+
+	// With tolerances only the document level mismatches remain.
+	res = match.Match(iv, newTestOrder(), nil,
+		match.ConfigPriceTolerance(types.D(0.5)),
+		match.ConfigQuantityTolerance(types.D(2)),
+		match.ConfigAmountTolerance(types.D(5)))
+	kinds = nil
+	for _, m := range res.Mismatches {
+		kinds = append(kinds, m.Kind)
+	}
+	assert.Equal([]match.MismatchKind{
+		match.MismatchOrderReference,
+		match.MismatchCurrency,
+		match.MismatchUnorderedItem,
+	}, kinds)
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// Checkpoint is the state of a Poller for a CIF.
+type Checkpoint struct {
+	// CIF is the CIF of the polled messages.
+	CIF string `json:"cif"`
+	// LastMessageID is the ID of the last dispatched message.
+	LastMessageID int64 `json:"last_message_id"`
+	// PolledAt is the time of the last poll that dispatched all the new
+	// messages.
+	PolledAt time.Time `json:"polled_at"`
+}
+
+// CheckpointStore persists the Checkpoint for each CIF.
+type CheckpointStore interface {
+	// LoadCheckpoint returns the checkpoint for the given CIF, or nil if
+	// the CIF was never polled.
+	LoadCheckpoint(ctx context.Context, cif string) (*Checkpoint, error)
+	// SaveCheckpoint saves the checkpoint, replacing the existing
+	// checkpoint for the same CIF.
+	SaveCheckpoint(ctx context.Context, checkpoint Checkpoint) error
+}
+
+// storeCheckpointStore is a CheckpointStore backed by a store.Store.
+type storeCheckpointStore struct {
+	st store.Store
+}
+
+// NewStoreCheckpointStore returns a CheckpointStore that keeps the
+// checkpoints in the given store (eg. a store.DirStore), keyed by the CIF. A
+// dedicated store should be used, not the one with the archived invoices.
+func NewStoreCheckpointStore(st store.Store) CheckpointStore {
+	return storeCheckpointStore{st: st}
+}
+
+func checkpointKey(cif string) string {
+	return "notify-" + cif
+}
+
+// LoadCheckpoint implements the CheckpointStore interface.
+func (s storeCheckpointStore) LoadCheckpoint(ctx context.Context, cif string) (*Checkpoint, error) {
+	_, data, err := s.st.Get(ctx, checkpointKey(cif))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(Checkpoint)
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// SaveCheckpoint implements the CheckpointStore interface.
+func (s storeCheckpointStore) SaveCheckpoint(ctx context.Context, checkpoint Checkpoint) error {
+	data, err := json.Marshal(&checkpoint)
+	if err != nil {
+		return err
+	}
+	key := checkpointKey(checkpoint.CIF)
+	return s.st.Put(ctx, store.Entry{
+		Key:  key,
+		CIF:  checkpoint.CIF,
+		Name: key + ".json",
+	}, data)
+}
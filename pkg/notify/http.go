@@ -0,0 +1,70 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+)
+
+// httpCallbackHandler is a Handler that posts the events to a callback URL.
+type httpCallbackHandler struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPCallbackHandler returns a Handler that posts each event as JSON to
+// the given callback URL, using the given HTTP client (http.DefaultClient if
+// nil). The correlation ID of the event is sent in the X-Correlation-ID
+// header. A response with a non-2xx status code is an error, so the event is
+// dispatched again by the next poll.
+func NewHTTPCallbackHandler(url string, client *http.Client) Handler {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpCallbackHandler{url: url, client: client}
+}
+
+// HandleEvent implements the Handler interface.
+func (h *httpCallbackHandler) HandleEvent(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if event.CorrelationID != "" {
+		req.Header.Set(correlation.HeaderName, event.CorrelationID)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notify: callback %s: %s", h.url, resp.Status)
+	}
+	return nil
+}
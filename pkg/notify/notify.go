@@ -0,0 +1,353 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package notify polls the e-factura messages list on a schedule and
+// dispatches the new messages as typed events to registered handlers (Go
+// functions or HTTP callbacks). The ID of the last dispatched message is
+// checkpointed for each CIF, so restarts don't duplicate the events.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+const (
+	// DefaultInterval is the default interval between two polls.
+	DefaultInterval = 5 * time.Minute
+	// DefaultInitialDays is the default number of days fetched for a CIF
+	// without a checkpoint.
+	DefaultInitialDays = 1
+	// maxDays is the maximum number of days accepted by the messages list
+	// endpoint.
+	maxDays = 60
+)
+
+// EventType is the type of an Event.
+type EventType string
+
+const (
+	// EventInvoiceReceived is dispatched for a received invoice (FACTURA
+	// PRIMITA).
+	EventInvoiceReceived EventType = "invoice_received"
+	// EventInvoiceSent is dispatched for a sent invoice that was accepted
+	// by ANAF (FACTURA TRIMISA).
+	EventInvoiceSent EventType = "invoice_sent"
+	// EventInvoiceError is dispatched for a sent invoice that was rejected
+	// by ANAF (ERORI FACTURA).
+	EventInvoiceError EventType = "invoice_error"
+	// EventBuyerMessage is dispatched for a buyer message (MESAJ CUMPARATOR
+	// PRIMIT / MESAJ CUMPARATOR TRANSMIS).
+	EventBuyerMessage EventType = "buyer_message"
+	// EventUnknown is dispatched for the messages with an unknown type.
+	EventUnknown EventType = "unknown"
+)
+
+// EventTypeForMessage returns the EventType for the given message.
+func EventTypeForMessage(m efactura.Message) EventType {
+	switch {
+	case m.IsReceivedInvoice():
+		return EventInvoiceReceived
+	case m.IsSentInvoice():
+		return EventInvoiceSent
+	case m.IsError():
+		return EventInvoiceError
+	case m.IsBuyerMessage():
+		return EventBuyerMessage
+	}
+	return EventUnknown
+}
+
+// Event is a new message found in the messages list of a CIF.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType `json:"type"`
+	// CIF is the CIF whose messages list contains the message.
+	CIF string `json:"cif"`
+	// Message is the new message.
+	Message efactura.Message `json:"message"`
+	// DetectedAt is the time the message was found.
+	DetectedAt time.Time `json:"detected_at"`
+	// CorrelationID is the correlation ID of the event: the one carried by
+	// the poll context, or a new one for each event. The context passed to
+	// the handlers carries it, so the operations done by the handlers (eg.
+	// downloading the message) share it.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Metadata is the user metadata from the poll context (see
+	// efactura.ContextWithMetadata).
+	Metadata efactura.Metadata `json:"metadata,omitempty"`
+}
+
+// Handler handles the events dispatched by a Poller. If HandleEvent returns
+// an error, the event is dispatched again by the next poll.
+type Handler interface {
+	HandleEvent(ctx context.Context, event Event) error
+}
+
+// HandlerFunc is an adapter to allow the use of an ordinary function as a
+// Handler.
+type HandlerFunc func(ctx context.Context, event Event) error
+
+// HandleEvent calls f(ctx, event).
+func (f HandlerFunc) HandleEvent(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// PollerConfig is the config used to create a Poller.
+type PollerConfig struct {
+	// CIFs are the CIFs whose messages are polled.
+	CIFs []string
+	// Interval is the interval between two polls (default DefaultInterval).
+	Interval time.Duration
+	// InitialDays is the number of days fetched for a CIF without a
+	// checkpoint (default DefaultInitialDays).
+	InitialDays int
+	// Filter is the message filter (default efactura.MessageFilterAll).
+	Filter efactura.MessageFilterType
+	// ErrorHandler is called by Run with the errors of the polls
+	// (optional).
+	ErrorHandler func(cif string, err error)
+	// CorrelationIDGenerator is the generator of the correlation IDs of the
+	// events (optional). If not set, correlation.NewUUIDv7 is used.
+	CorrelationIDGenerator correlation.IDGenerator
+}
+
+// PollerConfigOption allows gradually modifying a PollerConfig.
+type PollerConfigOption func(*PollerConfig)
+
+// PollerCIFs adds the CIFs whose messages are polled.
+func PollerCIFs(cifs ...string) PollerConfigOption {
+	return func(c *PollerConfig) {
+		c.CIFs = append(c.CIFs, cifs...)
+	}
+}
+
+// PollerInterval sets the interval between two polls.
+func PollerInterval(interval time.Duration) PollerConfigOption {
+	return func(c *PollerConfig) {
+		c.Interval = interval
+	}
+}
+
+// PollerInitialDays sets the number of days fetched for a CIF without a
+// checkpoint (at most 60).
+func PollerInitialDays(days int) PollerConfigOption {
+	return func(c *PollerConfig) {
+		c.InitialDays = days
+	}
+}
+
+// PollerFilter sets the message filter.
+func PollerFilter(filter efactura.MessageFilterType) PollerConfigOption {
+	return func(c *PollerConfig) {
+		c.Filter = filter
+	}
+}
+
+// PollerErrorHandler sets the function called by Run with the errors of the
+// polls.
+func PollerErrorHandler(f func(cif string, err error)) PollerConfigOption {
+	return func(c *PollerConfig) {
+		c.ErrorHandler = f
+	}
+}
+
+// PollerCorrelationIDGenerator sets the generator of the correlation IDs of
+// the events.
+func PollerCorrelationIDGenerator(gen correlation.IDGenerator) PollerConfigOption {
+	return func(c *PollerConfig) {
+		c.CorrelationIDGenerator = gen
+	}
+}
+
+// Poller polls the messages list of a set of CIFs and dispatches the new
+// messages to the registered handlers. The messages of a CIF are dispatched
+// in the ascending order of their IDs, and the checkpoint is saved after each
+// message was handled by all the handlers. If a handler fails, the following
+// messages of the CIF are dispatched by the next poll, so the events are
+// delivered at least once.
+type Poller struct {
+	lister      efactura.MessageLister
+	checkpoints CheckpointStore
+	cfg         PollerConfig
+
+	mu       sync.RWMutex
+	handlers map[EventType][]Handler
+	all      []Handler
+}
+
+// NewPoller creates a new Poller that lists the messages using lister (eg.
+// an *efactura.Client) and keeps the checkpoints in the given store.
+func NewPoller(lister efactura.MessageLister, checkpoints CheckpointStore, opts ...PollerConfigOption) (*Poller, error) {
+	cfg := PollerConfig{
+		Interval:    DefaultInterval,
+		InitialDays: DefaultInitialDays,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if lister == nil {
+		return nil, errors.New("notify: nil message lister")
+	}
+	if checkpoints == nil {
+		return nil, errors.New("notify: nil checkpoint store")
+	}
+	if len(cfg.CIFs) == 0 {
+		return nil, errors.New("notify: no CIFs")
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("notify: invalid interval %v", cfg.Interval)
+	}
+	if cfg.InitialDays < 1 || cfg.InitialDays > maxDays {
+		return nil, fmt.Errorf("notify: invalid initial days %d", cfg.InitialDays)
+	}
+	return &Poller{
+		lister:      lister,
+		checkpoints: checkpoints,
+		cfg:         cfg,
+		handlers:    make(map[EventType][]Handler),
+	}, nil
+}
+
+// Handle registers a handler for the events of the given type.
+func (p *Poller) Handle(eventType EventType, h Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[eventType] = append(p.handlers[eventType], h)
+}
+
+// HandleFunc registers a handler function for the events of the given type.
+func (p *Poller) HandleFunc(eventType EventType, f func(ctx context.Context, event Event) error) {
+	p.Handle(eventType, HandlerFunc(f))
+}
+
+// HandleAll registers a handler for all the events.
+func (p *Poller) HandleAll(h Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.all = append(p.all, h)
+}
+
+// Run polls the messages every Interval until ctx is done, and returns the
+// context error. The errors of the polls are reported to the ErrorHandler (see
+// PollerErrorHandler) and don't stop the Poller.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		for _, cif := range p.cfg.CIFs {
+			if err := p.PollCIF(ctx, cif); err != nil && ctx.Err() == nil && p.cfg.ErrorHandler != nil {
+				p.cfg.ErrorHandler(cif, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll polls the messages of all the CIFs once. The CIFs are polled even if
+// the poll of a previous CIF failed, and the errors are joined.
+func (p *Poller) Poll(ctx context.Context) error {
+	var errs []error
+	for _, cif := range p.cfg.CIFs {
+		if err := p.PollCIF(ctx, cif); err != nil {
+			errs = append(errs, fmt.Errorf("cif %s: %w", cif, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PollCIF polls the messages of the given CIF once and dispatches the
+// messages newer than the checkpoint.
+func (p *Poller) PollCIF(ctx context.Context, cif string) error {
+	checkpoint, err := p.checkpoints.LoadCheckpoint(ctx, cif)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	numDays := p.cfg.InitialDays
+	if checkpoint != nil {
+		// Fetch all the days since the last poll, so no message is missed
+		// if the Poller was stopped for a while.
+		numDays = int(now.Sub(checkpoint.PolledAt)/(24*time.Hour)) + 1
+		numDays = max(1, min(numDays, maxDays))
+	} else {
+		checkpoint = &Checkpoint{CIF: cif}
+	}
+
+	res, err := p.lister.GetMessagesList(ctx, cif, numDays, p.cfg.Filter)
+	if err != nil {
+		return err
+	}
+	if !res.IsOk() {
+		return fmt.Errorf("%s: %s", res.Title, res.Error)
+	}
+
+	messages := make([]efactura.Message, 0, len(res.Messages))
+	for _, m := range res.Messages {
+		if m.GetID() > checkpoint.LastMessageID {
+			messages = append(messages, m)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].GetID() < messages[j].GetID()
+	})
+
+	for _, m := range messages {
+		eventCtx, correlationID := correlation.Ensure(ctx, p.cfg.CorrelationIDGenerator)
+		event := Event{
+			Type:          EventTypeForMessage(m),
+			CIF:           cif,
+			Message:       m,
+			DetectedAt:    now,
+			CorrelationID: correlationID,
+			Metadata:      efactura.MetadataFromContext(ctx).Clone(),
+		}
+		if err := p.dispatch(eventCtx, event); err != nil {
+			return fmt.Errorf("message %s: %w", m.ID, err)
+		}
+		checkpoint.LastMessageID = m.GetID()
+		if err := p.checkpoints.SaveCheckpoint(ctx, *checkpoint); err != nil {
+			return err
+		}
+	}
+
+	// The poll time is only advanced if all the messages were handled.
+	checkpoint.PolledAt = now
+	return p.checkpoints.SaveCheckpoint(ctx, *checkpoint)
+}
+
+func (p *Poller) dispatch(ctx context.Context, event Event) error {
+	p.mu.RLock()
+	handlers := append(append([]Handler(nil), p.handlers[event.Type]...), p.all...)
+	p.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := h.HandleEvent(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/correlation"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+// fakeLister is an efactura.MessageLister returning a fixed messages list.
+type fakeLister struct {
+	messages []efactura.Message
+	numDays  []int
+	err      error
+}
+
+func (l *fakeLister) GetMessagesList(ctx context.Context, cif string, numDays int, msgType efactura.MessageFilterType) (*efactura.MessagesListResponse, error) {
+	l.numDays = append(l.numDays, numDays)
+	if l.err != nil {
+		return nil, l.err
+	}
+	return &efactura.MessagesListResponse{CUI: cif, Messages: l.messages}, nil
+}
+
+func (l *fakeLister) GetMessagesListPagination(ctx context.Context, cif string, startTs, endTs time.Time, page int64, msgType efactura.MessageFilterType) (*efactura.MessagesListPaginationResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (l *fakeLister) GetAllMessagesPagination(ctx context.Context, cif string, startTs, endTs time.Time, msgType efactura.MessageFilterType) (*efactura.MessagesListResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newMessage(id int, messageType string) efactura.Message {
+	return efactura.Message{ID: strconv.Itoa(id), Type: messageType}
+}
+
+func TestPoller(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	lister := &fakeLister{messages: []efactura.Message{
+		newMessage(12, efactura.MessageTypeError),
+		newMessage(10, efactura.MessageTypeReceivedInvoice),
+		newMessage(11, efactura.MessageTypeBuyerMessage),
+	}}
+	checkpoints := NewStoreCheckpointStore(store.NewMemoryStore())
+	newPoller := func() *Poller {
+		p, err := NewPoller(lister, checkpoints, PollerCIFs("123456789"), PollerInitialDays(7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	var received, all []string
+	p := newPoller()
+	p.HandleFunc(EventInvoiceReceived, func(ctx context.Context, event Event) error {
+		received = append(received, event.Message.ID)
+		return nil
+	})
+	p.HandleAll(HandlerFunc(func(ctx context.Context, event Event) error {
+		assert.Equal("123456789", event.CIF)
+		all = append(all, string(event.Type)+":"+event.Message.ID)
+		return nil
+	}))
+
+	if assert.NoError(p.Poll(ctx)) {
+		assert.Equal([]string{"10"}, received)
+		assert.Equal([]string{"invoice_received:10", "buyer_message:11", "invoice_error:12"}, all)
+	}
+	assert.Equal([]int{7}, lister.numDays)
+
+	// A restarted poller doesn't dispatch the messages again.
+	lister.messages = append(lister.messages, newMessage(13, efactura.MessageTypeSentInvoice))
+	all = nil
+	p = newPoller()
+	p.HandleAll(HandlerFunc(func(ctx context.Context, event Event) error {
+		all = append(all, string(event.Type)+":"+event.Message.ID)
+		return nil
+	}))
+	if assert.NoError(p.Poll(ctx)) {
+		assert.Equal([]string{"invoice_sent:13"}, all)
+	}
+	// The next polls fetch only the days since the last poll.
+	assert.Equal([]int{7, 1}, lister.numDays)
+
+	// The events carry the metadata of the poll context, and a correlation
+	// ID per event, also carried by the handler context.
+	lister.messages = append(lister.messages,
+		newMessage(14, efactura.MessageTypeReceivedInvoice),
+		newMessage(15, efactura.MessageTypeReceivedInvoice))
+	var events []Event
+	p = newPoller()
+	p.HandleAll(HandlerFunc(func(ctx context.Context, event Event) error {
+		assert.Equal(event.CorrelationID, correlation.FromContext(ctx))
+		events = append(events, event)
+		return nil
+	}))
+	mdCtx := efactura.ContextWithMetadata(ctx, efactura.Metadata{"tenant": "acme"})
+	if assert.NoError(p.Poll(mdCtx)) && assert.Len(events, 2) {
+		assert.NotEmpty(events[0].CorrelationID)
+		assert.NotEqual(events[0].CorrelationID, events[1].CorrelationID)
+		assert.Equal(efactura.Metadata{"tenant": "acme"}, events[0].Metadata)
+	}
+
+	// A correlation ID carried by the poll context is used for all the
+	// events.
+	lister.messages = append(lister.messages, newMessage(16, efactura.MessageTypeReceivedInvoice))
+	events = nil
+	if assert.NoError(p.Poll(correlation.NewContext(ctx, "poll-1"))) && assert.Len(events, 1) {
+		assert.Equal("poll-1", events[0].CorrelationID)
+		assert.Empty(events[0].Metadata)
+	}
+
+	checkpoint, err := checkpoints.LoadCheckpoint(ctx, "123456789")
+	if assert.NoError(err) && assert.NotNil(checkpoint) {
+		assert.Equal(int64(16), checkpoint.LastMessageID)
+	}
+}
+
+func TestPollerHandlerError(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	lister := &fakeLister{messages: []efactura.Message{
+		newMessage(1, efactura.MessageTypeReceivedInvoice),
+		newMessage(2, efactura.MessageTypeReceivedInvoice),
+		newMessage(3, efactura.MessageTypeReceivedInvoice),
+	}}
+	p, err := NewPoller(lister, NewStoreCheckpointStore(store.NewMemoryStore()), PollerCIFs("123456789"))
+	if !assert.NoError(err) {
+		return
+	}
+	var handled []string
+	fail := true
+	p.HandleFunc(EventInvoiceReceived, func(ctx context.Context, event Event) error {
+		if event.Message.ID == "2" && fail {
+			return errors.New("handler failed")
+		}
+		handled = append(handled, event.Message.ID)
+		return nil
+	})
+
+	assert.ErrorContains(p.Poll(ctx), "handler failed")
+	assert.Equal([]string{"1"}, handled)
+
+	// The failed message and the following ones are dispatched again.
+	fail = false
+	assert.NoError(p.Poll(ctx))
+	assert.Equal([]string{"1", "2", "3"}, handled)
+
+	lister.err = errors.New("list failed")
+	assert.ErrorContains(p.Poll(ctx), "list failed")
+}
+
+func TestHTTPCallbackHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	var events []Event
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("application/json", r.Header.Get("Content-Type"))
+		assert.Equal("corr-1", r.Header.Get(correlation.HeaderName))
+		var event Event
+		if assert.NoError(json.NewDecoder(r.Body).Decode(&event)) {
+			events = append(events, event)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	h := NewHTTPCallbackHandler(server.URL, nil)
+	event := Event{
+		Type:          EventInvoiceReceived,
+		CIF:           "123456789",
+		Message:       newMessage(1, efactura.MessageTypeReceivedInvoice),
+		CorrelationID: "corr-1",
+		Metadata:      efactura.Metadata{"order": "PO-1"},
+	}
+	if assert.NoError(h.HandleEvent(context.Background(), event)) && assert.Len(events, 1) {
+		assert.Equal(EventInvoiceReceived, events[0].Type)
+		assert.Equal("1", events[0].Message.ID)
+		assert.Equal("corr-1", events[0].CorrelationID)
+		assert.Equal(efactura.Metadata{"order": "PO-1"}, events[0].Metadata)
+	}
+
+	status = http.StatusInternalServerError
+	assert.Error(h.HandleEvent(context.Background(), event))
+}
+
+func TestNewPollerInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	checkpoints := NewStoreCheckpointStore(store.NewMemoryStore())
+	_, err := NewPoller(&fakeLister{}, checkpoints)
+	assert.Error(err, "no CIFs")
+	_, err = NewPoller(nil, checkpoints, PollerCIFs("1"))
+	assert.Error(err)
+	_, err = NewPoller(&fakeLister{}, checkpoints, PollerCIFs("1"), PollerInitialDays(61))
+	assert.Error(err)
+	_, err = NewPoller(&fakeLister{}, checkpoints, PollerCIFs("1"), PollerInterval(0))
+	assert.Error(err)
+}
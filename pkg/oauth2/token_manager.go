@@ -0,0 +1,301 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	xoauth2 "golang.org/x/oauth2"
+)
+
+const (
+	// DefaultTokenRefreshBefore is the default duration before the token
+	// expiry when the TokenManager refreshes the token.
+	DefaultTokenRefreshBefore = 24 * time.Hour
+	// DefaultTokenRefreshRetryInterval is the default duration the
+	// TokenManager.Run waits before retrying a failed refresh.
+	DefaultTokenRefreshRetryInterval = time.Minute
+)
+
+// ErrTokenNotFound is returned by a TokenStore.Load if there is no token
+// stored.
+var ErrTokenNotFound = errors.New("efactura.oauth2: token not found")
+
+// TokenStore is the interface used by the TokenManager to persist the
+// token, so that the refreshed tokens survive application restarts.
+// Implementations can store the token on disk, in a database, in Redis, etc.
+type TokenStore interface {
+	// Load returns the stored token, or ErrTokenNotFound if no token is
+	// stored.
+	Load(ctx context.Context) (*xoauth2.Token, error)
+	// Save stores the token, replacing the previously stored token.
+	Save(ctx context.Context, t *xoauth2.Token) error
+}
+
+// FileTokenStore is a TokenStore that stores the token as JSON in a file.
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore creates a new FileTokenStore that stores the token in
+// the file at the given path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load implements the TokenStore interface.
+func (s *FileTokenStore) Load(ctx context.Context) (*xoauth2.Token, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return TokenFromJSON(data)
+}
+
+// Save implements the TokenStore interface. The token is written to a
+// temporary file which is then renamed, so a crash never leaves a partially
+// written token.
+func (s *FileTokenStore) Save(ctx context.Context, t *xoauth2.Token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// TokenManager is a TokenSource that refreshes the token before it expires
+// and persists every refreshed token in a TokenStore. It can be passed to
+// efactura.NewProductionClient, etransport.NewProductionClient, etc. The
+// TokenManager is safe for concurrent access.
+type TokenManager struct {
+	ctx           context.Context // used for HTTP requests and store calls
+	conf          *xoauth2.Config
+	store         TokenStore
+	refreshBefore time.Duration
+	retryInterval time.Duration
+	initialToken  *xoauth2.Token
+
+	mu sync.Mutex // guards t
+	t  *xoauth2.Token
+}
+
+// TokenManagerOption allows gradually modifying a TokenManager.
+type TokenManagerOption func(*TokenManager)
+
+// TokenManagerRefreshBefore sets the duration before the token expiry when
+// the token is refreshed (default DefaultTokenRefreshBefore).
+func TokenManagerRefreshBefore(d time.Duration) TokenManagerOption {
+	return func(m *TokenManager) {
+		m.refreshBefore = d
+	}
+}
+
+// TokenManagerRetryInterval sets the duration Run waits before retrying a
+// failed refresh (default DefaultTokenRefreshRetryInterval).
+func TokenManagerRetryInterval(d time.Duration) TokenManagerOption {
+	return func(m *TokenManager) {
+		m.retryInterval = d
+	}
+}
+
+// TokenManagerInitialToken sets the token to use if the store does not have
+// a token yet (eg. the token obtained with Config.Exchange). The token is
+// saved in the store.
+func TokenManagerInitialToken(t *xoauth2.Token) TokenManagerOption {
+	return func(m *TokenManager) {
+		m.initialToken = t
+	}
+}
+
+// NewTokenManager creates a new TokenManager that loads the token from the
+// given store. If the store has no token, the token set with
+// TokenManagerInitialToken is used and saved in the store, otherwise
+// ErrTokenNotFound is returned.
+func (c *Config) NewTokenManager(ctx context.Context, store TokenStore, opts ...TokenManagerOption) (*TokenManager, error) {
+	if store == nil {
+		return nil, errors.New("efactura.oauth2: token store must be set")
+	}
+	m := &TokenManager{
+		ctx:           ctx,
+		conf:          &c.Config,
+		store:         store,
+		refreshBefore: DefaultTokenRefreshBefore,
+		retryInterval: DefaultTokenRefreshRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	t, err := store.Load(ctx)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrTokenNotFound) && m.initialToken != nil:
+		t = m.initialToken
+		if err := store.Save(ctx, t); err != nil {
+			return nil, fmt.Errorf("efactura.oauth2: cannot save token: %w", err)
+		}
+	default:
+		return nil, err
+	}
+	m.t = t
+	return m, nil
+}
+
+// needsRefresh returns true if the token must be refreshed. A token without
+// an expiry never needs a refresh.
+func (m *TokenManager) needsRefresh() bool {
+	if m.t == nil || m.t.AccessToken == "" {
+		return true
+	}
+	if m.t.Expiry.IsZero() {
+		return false
+	}
+	return !timeNow().Add(m.refreshBefore).Before(m.t.Expiry)
+}
+
+// refresh refreshes the token and saves it in the store. The token is
+// reloaded from the store first: if another TokenManager sharing the store
+// (eg. another instance of the application) already refreshed it, the stored
+// token is used, since the cached refresh token may have been invalidated by
+// the refresh token rotation. This method must be called with m.mu locked.
+func (m *TokenManager) refresh(ctx context.Context) (*xoauth2.Token, error) {
+	stored, err := m.store.Load(ctx)
+	switch {
+	case err == nil:
+		if m.t == nil || stored.AccessToken != m.t.AccessToken || stored.RefreshToken != m.t.RefreshToken {
+			m.t = stored
+			if !m.needsRefresh() {
+				return m.t, nil
+			}
+		}
+	case errors.Is(err, ErrTokenNotFound):
+	default:
+		return nil, fmt.Errorf("efactura.oauth2: cannot load token: %w", err)
+	}
+
+	if m.t == nil || m.t.RefreshToken == "" {
+		return nil, errors.New("oauth2: token expired and refresh token is not set")
+	}
+
+	tk, err := retrieveToken(ctx, m.conf, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {m.t.RefreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tk.RefreshToken == "" {
+		tk.RefreshToken = m.t.RefreshToken
+	}
+	m.t = tk
+	if err := m.store.Save(ctx, tk); err != nil {
+		return tk, fmt.Errorf("efactura.oauth2: cannot save token: %w", err)
+	}
+	return tk, nil
+}
+
+// Token implements the xoauth2.TokenSource interface. It returns the current
+// token, refreshing it first if it expires in less than the refresh before
+// duration. If the refreshed token cannot be saved, the token is still
+// returned along with the error.
+func (m *TokenManager) Token() (*xoauth2.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.needsRefresh() {
+		return m.t, nil
+	}
+	return m.refresh(m.ctx)
+}
+
+// Refresh forces a refresh of the token, regardless of its expiry. If the
+// token was already refreshed by another TokenManager sharing the store, the
+// stored token is returned instead.
+func (m *TokenManager) Refresh(ctx context.Context) (*xoauth2.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refresh(ctx)
+}
+
+// nextRefresh returns the duration until the token must be refreshed.
+func (m *TokenManager) nextRefresh() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.needsRefresh() {
+		return 0
+	}
+	if m.t.Expiry.IsZero() {
+		return -1
+	}
+	return m.t.Expiry.Add(-m.refreshBefore).Sub(timeNow())
+}
+
+// Run refreshes the token in the background before it expires, until the
+// given context is done. Failed refreshes are retried after the retry
+// interval. Run returns the context error. Using Run is optional, since
+// Token refreshes the token if needed, but it keeps the stored token fresh
+// even if the client is not used for a long time.
+func (m *TokenManager) Run(ctx context.Context) error {
+	for {
+		wait := m.nextRefresh()
+		if wait < 0 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if _, err := m.Refresh(ctx); err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(m.retryInterval):
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	xoauth2 "golang.org/x/oauth2"
+)
+
+func setupTestTokenServer(t *testing.T) (Config, *int32) {
+	var refreshes int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := atomic.AddInt32(&refreshes, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"access-%d","token_type":"Bearer","refresh_token":"refresh-%d","expires_in":7776000}`, n, n)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg, err := MakeConfig(
+		ConfigCredentials("client-id", "client-secret"),
+		ConfigRedirectURL("https://localhost/callback"),
+		ConfigEndpoint(xoauth2.Endpoint{
+			AuthURL:   srv.URL + "/authorize",
+			TokenURL:  srv.URL + "/token",
+			AuthStyle: xoauth2.AuthStyleInHeader,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, &refreshes
+}
+
+func TestTokenManager(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cfg, refreshes := setupTestTokenServer(t)
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+
+	_, err := cfg.NewTokenManager(ctx, store)
+	assert.ErrorIs(err, ErrTokenNotFound)
+
+	// A token valid for a long time is not refreshed.
+	initial := &xoauth2.Token{
+		AccessToken:  "access-0",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-0",
+		Expiry:       time.Now().Add(30 * 24 * time.Hour),
+	}
+	tm, err := cfg.NewTokenManager(ctx, store, TokenManagerInitialToken(initial))
+	if !assert.NoError(err) {
+		return
+	}
+	tk, err := tm.Token()
+	if assert.NoError(err) {
+		assert.Equal("access-0", tk.AccessToken)
+	}
+	assert.Equal(int32(0), atomic.LoadInt32(refreshes))
+	stored, err := store.Load(ctx)
+	if assert.NoError(err) {
+		assert.Equal("refresh-0", stored.RefreshToken)
+	}
+
+	// A token expiring before the refresh before duration is refreshed and
+	// the refreshed token is saved.
+	tm, err = cfg.NewTokenManager(ctx, store, TokenManagerRefreshBefore(60*24*time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+	tk, err = tm.Token()
+	if assert.NoError(err) {
+		assert.Equal("access-1", tk.AccessToken)
+		assert.Equal("refresh-1", tk.RefreshToken)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(refreshes))
+	stored, err = store.Load(ctx)
+	if assert.NoError(err) {
+		assert.Equal("access-1", stored.AccessToken)
+		assert.Equal("refresh-1", stored.RefreshToken)
+	}
+
+	// The refreshed token is reused.
+	tm, err = cfg.NewTokenManager(ctx, store)
+	if !assert.NoError(err) {
+		return
+	}
+	tk, err = tm.Token()
+	if assert.NoError(err) {
+		assert.Equal("access-1", tk.AccessToken)
+	}
+	tk, err = tm.Refresh(ctx)
+	if assert.NoError(err) {
+		assert.Equal("access-2", tk.AccessToken)
+	}
+	assert.Equal(int32(2), atomic.LoadInt32(refreshes))
+}
+
+func TestTokenManagerSharedStore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cfg, refreshes := setupTestTokenServer(t)
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+	initial := &xoauth2.Token{
+		AccessToken:  "access-0",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-0",
+		Expiry:       time.Now().Add(30 * 24 * time.Hour),
+	}
+	tm1, err := cfg.NewTokenManager(ctx, store, TokenManagerInitialToken(initial),
+		TokenManagerRefreshBefore(60*24*time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+	tm2, err := cfg.NewTokenManager(ctx, store, TokenManagerRefreshBefore(60*24*time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+
+	tk, err := tm1.Token()
+	if assert.NoError(err) {
+		assert.Equal("access-1", tk.AccessToken)
+		assert.Equal("refresh-1", tk.RefreshToken)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(refreshes))
+
+	// The second manager still has the rotated refresh token cached, so it
+	// must use the token refreshed by the first manager.
+	tk, err = tm2.Token()
+	if assert.NoError(err) {
+		assert.Equal("access-1", tk.AccessToken)
+		assert.Equal("refresh-1", tk.RefreshToken)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(refreshes))
+
+	tk, err = tm2.Refresh(ctx)
+	if assert.NoError(err) {
+		assert.Equal("access-2", tk.AccessToken)
+	}
+	tk, err = tm1.Refresh(ctx)
+	if assert.NoError(err) {
+		assert.Equal("access-2", tk.AccessToken)
+		assert.Equal("refresh-2", tk.RefreshToken)
+	}
+	assert.Equal(int32(2), atomic.LoadInt32(refreshes))
+}
+
+func TestTokenManagerRun(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg, refreshes := setupTestTokenServer(t)
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+	tm, err := cfg.NewTokenManager(context.Background(), store,
+		TokenManagerInitialToken(&xoauth2.Token{
+			AccessToken:  "access-0",
+			RefreshToken: "refresh-0",
+			Expiry:       time.Now().Add(time.Hour),
+		}),
+		TokenManagerRefreshBefore(2*time.Hour))
+	if !assert.NoError(err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- tm.Run(ctx)
+	}()
+	assert.Eventually(func() bool {
+		return atomic.LoadInt32(refreshes) >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.ErrorIs(<-done, context.Canceled)
+
+	stored, err := store.Load(context.Background())
+	if assert.NoError(err) {
+		assert.Equal("access-1", stored.AccessToken)
+	}
+	// The refreshed token is valid for 90 days, so no other refresh happens.
+	assert.Equal(int32(1), atomic.LoadInt32(refreshes))
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"github.com/printesoi/e-factura-go/pkg/text"
+)
+
+// font is one of the standard PDF Type1 fonts used by the renderer. The
+// standard fonts don't need to be embedded, but they only support the
+// WinAnsi encoding (Latin-1), so the other characters (eg. ș, ț, ă) are
+// transliterated.
+type font int
+
+const (
+	fontRegular font = iota
+	fontBold
+)
+
+// resourceName returns the name of the font in the page resources.
+func (f font) resourceName() string {
+	if f == fontBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// The widths (in 1/1000 of the font size) of the ASCII printable characters
+// (32-126), from the Adobe font metrics of Helvetica and Helvetica-Bold.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// encodeWinAnsi encodes s in the WinAnsi encoding, transliterating the
+// characters not supported by the encoding.
+func encodeWinAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 32 && r < 127:
+			out = append(out, byte(r))
+		case r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		case r == '€':
+			out = append(out, 0x80)
+		default:
+			for _, c := range []byte(text.Transliterate(string(r))) {
+				if c >= 32 && c < 127 {
+					out = append(out, c)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// charWidth returns the width of the WinAnsi encoded character c.
+func (f font) charWidth(c byte) int {
+	widths := &helveticaWidths
+	if f == fontBold {
+		widths = &helveticaBoldWidths
+	}
+	switch {
+	case c >= 32 && c < 127:
+		return widths[c-32]
+	case c >= 0xc0:
+		// Latin-1 letters are as wide as their base letter.
+		if base := text.Transliterate(string(rune(c))); len(base) > 0 && base[0] >= 32 && base[0] < 127 {
+			return widths[base[0]-32]
+		}
+	}
+	return 556
+}
+
+// width returns the width of s in points for the given font size.
+func (f font) width(s string, size float64) float64 {
+	var w int
+	for _, c := range encodeWinAnsi(s) {
+		w += f.charWidth(c)
+	}
+	return float64(w) * size / 1000
+}
+
+// wrap splits s in lines not wider than maxWidth, breaking at spaces (or
+// inside the words longer than a line).
+func (f font) wrap(s string, size, maxWidth float64) (lines []string) {
+	var line []rune
+	lineWidth := func(rs []rune) float64 { return f.width(string(rs), size) }
+	for _, word := range splitWords(s) {
+		candidate := append(append([]rune(nil), line...), word...)
+		if len(line) > 0 {
+			candidate = append(append(append([]rune(nil), line...), ' '), word...)
+		}
+		if lineWidth(candidate) <= maxWidth {
+			line = candidate
+			continue
+		}
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		// Break the words longer than a line.
+		for lineWidth(word) > maxWidth && len(word) > 1 {
+			n := len(word) - 1
+			for n > 1 && lineWidth(word[:n]) > maxWidth {
+				n--
+			}
+			lines = append(lines, string(word[:n]))
+			word = word[n:]
+		}
+		line = word
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, string(line))
+	}
+	return
+}
+
+func splitWords(s string) (words [][]rune) {
+	var word []rune
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			if len(word) > 0 {
+				words = append(words, word)
+				word = nil
+			}
+			continue
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+	return
+}
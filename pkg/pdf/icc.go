@@ -0,0 +1,91 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// srgbProfile returns a minimal ICC v2 display profile for sRGB (D50 adapted
+// primaries and a 2.2 gamma), used for the output intent of the PDF/A
+// documents.
+func srgbProfile() []byte {
+	s15Fixed16 := func(f float64) uint32 {
+		return uint32(int32(math.Round(f * 65536)))
+	}
+	xyz := func(x, y, z float64) []byte {
+		var buf bytes.Buffer
+		buf.WriteString("XYZ \x00\x00\x00\x00")
+		for _, v := range []float64{x, y, z} {
+			_ = binary.Write(&buf, binary.BigEndian, s15Fixed16(v))
+		}
+		return buf.Bytes()
+	}
+	description := "sRGB IEC61966-2.1"
+	var desc bytes.Buffer
+	desc.WriteString("desc\x00\x00\x00\x00")
+	_ = binary.Write(&desc, binary.BigEndian, uint32(len(description)+1))
+	desc.WriteString(description)
+	desc.WriteByte(0)
+	// No Unicode and ScriptCode descriptions.
+	desc.Write(make([]byte, 4+4+2+1+67))
+
+	type tag struct {
+		signature string
+		data      []byte
+	}
+	trc := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01\x02\x33")
+	tags := []tag{
+		{"desc", desc.Bytes()},
+		{"cprt", []byte("text\x00\x00\x00\x00No copyright, use freely\x00")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	var data bytes.Buffer
+	offset := 128 + 4 + 12*len(tags)
+	var table bytes.Buffer
+	_ = binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	for _, t := range tags {
+		for offset%4 != 0 {
+			offset++
+			data.WriteByte(0)
+		}
+		table.WriteString(t.signature)
+		_ = binary.Write(&table, binary.BigEndian, []uint32{uint32(offset), uint32(len(t.data))})
+		data.Write(t.data)
+		offset += len(t.data)
+	}
+
+	var header bytes.Buffer
+	_ = binary.Write(&header, binary.BigEndian, uint32(offset))
+	header.Write(make([]byte, 4))       // Preferred CMM
+	header.Write([]byte{2, 0x10, 0, 0}) // Version 2.1
+	header.WriteString("mntrRGB XYZ ")  // Class, color space, PCS
+	_ = binary.Write(&header, binary.BigEndian, []uint16{2024, 1, 1, 0, 0, 0})
+	header.WriteString("acsp")
+	header.Write(make([]byte, 4+4+4+4+8+4)) // Platform, flags, device, attributes, intent
+	header.Write(xyz(0.9642, 1.0, 0.8249)[8:])
+	header.Write(make([]byte, 4+16+28)) // Creator, ID, reserved
+
+	return append(append(header.Bytes(), table.Bytes()...), data.Bytes()...)
+}
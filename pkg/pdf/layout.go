@@ -0,0 +1,362 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// The A4 page size and the margins, in points.
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	margin       = 40.0
+	footerHeight = 30.0
+	contentWidth = pageWidth - 2*margin
+
+	logoMaxWidth  = 150.0
+	logoMaxHeight = 60.0
+)
+
+// column is a column of a table.
+type column struct {
+	x, width float64
+	right    bool
+}
+
+// layout lays out the content on pages, top to bottom.
+type layout struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	// y is the position of the cursor (the top of the next content).
+	y float64
+	// onNewPage is called after a new page is started (eg. for repeating
+	// the header of a table).
+	onNewPage func()
+}
+
+func (l *layout) newPage() {
+	l.page = &bytes.Buffer{}
+	l.pages = append(l.pages, l.page)
+	l.y = pageHeight - margin
+	if l.onNewPage != nil {
+		l.onNewPage()
+	}
+}
+
+// ensure starts a new page if the content of height h doesn't fit on the
+// current page.
+func (l *layout) ensure(h float64) {
+	if l.page == nil || l.y-h < margin+footerHeight {
+		l.newPage()
+	}
+}
+
+// text draws s with its baseline at y.
+func (l *layout) text(x, y float64, f font, size float64, s string) {
+	fmt.Fprintf(l.page, "BT /%s %s Tf %s %s Td %s Tj ET\n",
+		f.resourceName(), pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfString(s))
+}
+
+// textRight draws s right-aligned to xRight.
+func (l *layout) textRight(xRight, y float64, f font, size float64, s string) {
+	l.text(xRight-f.width(s, size), y, f, size, s)
+}
+
+// line draws a horizontal line at y.
+func (l *layout) line(x1, x2, y float64) {
+	fmt.Fprintf(l.page, "0.5 w 0.6 G %s %s m %s %s l S 0 G\n",
+		pdfNumber(x1), pdfNumber(y), pdfNumber(x2), pdfNumber(y))
+}
+
+// fill fills a gray rectangle.
+func (l *layout) fill(x, y, w, h float64) {
+	fmt.Fprintf(l.page, "0.93 g %s %s %s %s re f 0 g\n",
+		pdfNumber(x), pdfNumber(y), pdfNumber(w), pdfNumber(h))
+}
+
+// paragraph draws the wrapped text at x and moves the cursor down.
+func (l *layout) paragraph(x, width float64, f font, size float64, s string) {
+	for _, line := range f.wrap(s, size, width) {
+		l.ensure(size * 1.4)
+		l.y -= size * 1.2
+		l.text(x, l.y, f, size, line)
+		l.y -= size * 0.2
+	}
+}
+
+// block draws the lines of a column without page breaks (used for the
+// header) and returns the position of the cursor after the block.
+func (l *layout) block(x, y, width float64, lines []styledLine) float64 {
+	for _, sl := range lines {
+		for _, line := range sl.font.wrap(sl.text, sl.size, width) {
+			y -= sl.size * 1.2
+			l.text(x, y, sl.font, sl.size, line)
+			y -= sl.size * 0.25
+		}
+	}
+	return y
+}
+
+type styledLine struct {
+	text string
+	font font
+	size float64
+}
+
+// row draws a table row with the cells wrapped to the column widths and
+// moves the cursor below the row.
+func (l *layout) row(columns []column, cells []string, f font, size float64) {
+	wrapped := make([][]string, len(cells))
+	lines := 1
+	for i, cell := range cells {
+		wrapped[i] = f.wrap(cell, size, columns[i].width-4)
+		if len(wrapped[i]) > lines {
+			lines = len(wrapped[i])
+		}
+	}
+	height := float64(lines)*size*1.3 + 4
+	l.ensure(height)
+	for i, cellLines := range wrapped {
+		y := l.y - 2
+		for _, line := range cellLines {
+			y -= size * 1.3
+			if line == "" {
+				continue
+			}
+			if columns[i].right {
+				l.textRight(columns[i].x+columns[i].width-2, y+size*0.3, f, size, line)
+			} else {
+				l.text(columns[i].x+2, y+size*0.3, f, size, line)
+			}
+		}
+	}
+	l.y -= height
+}
+
+// tableColumns returns the columns with the given widths (0 for the column
+// filling the remaining width) and alignments.
+func tableColumns(widths []float64, right []bool) []column {
+	var fixed float64
+	for _, w := range widths {
+		fixed += w
+	}
+	columns := make([]column, len(widths))
+	x := margin
+	for i, w := range widths {
+		if w == 0 {
+			w = contentWidth - fixed
+		}
+		columns[i] = column{x: x, width: w, right: right[i]}
+		x += w
+	}
+	return columns
+}
+
+// document is a rendered PDF document.
+type document struct {
+	writer  writer
+	pages   int
+	catalog int
+	info    int
+}
+
+func (r *Renderer) renderDocument(iv efactura.Invoice) (*document, error) {
+	v := NewView(iv, r.lang)
+	labels := v.Labels
+	l := &layout{}
+	l.newPage()
+
+	// Header: title, document details and logo.
+	var logoHeight float64
+	var logoWidth float64
+	if r.logoImage != nil {
+		b := r.logoImage.Bounds()
+		scale := min(logoMaxWidth/float64(b.Dx()), logoMaxHeight/float64(b.Dy()))
+		logoWidth, logoHeight = float64(b.Dx())*scale, float64(b.Dy())*scale
+		fmt.Fprintf(l.page, "q %s 0 0 %s %s %s cm /Im1 Do Q\n",
+			pdfNumber(logoWidth), pdfNumber(logoHeight),
+			pdfNumber(pageWidth-margin-logoWidth), pdfNumber(l.y-logoHeight))
+	}
+	details := []styledLine{{text: v.Title, font: fontBold, size: 18}}
+	details = append(details, styledLine{text: labels.Number + " " + v.ID, font: fontBold, size: 11})
+	details = append(details, styledLine{text: labels.IssueDate + ": " + v.IssueDate, size: 10})
+	if v.DueDate != "" {
+		details = append(details, styledLine{text: labels.DueDate + ": " + v.DueDate, size: 10})
+	}
+	details = append(details, styledLine{text: labels.Currency + ": " + v.Currency, size: 10})
+	y := l.block(margin, l.y, contentWidth-logoWidth-10, details)
+	l.y = min(y, l.y-logoHeight) - 16
+
+	// Parties.
+	partyLines := func(title string, p PartyView) []styledLine {
+		lines := []styledLine{
+			{text: strings.ToUpper(title), font: fontBold, size: 8},
+			{text: p.Name, font: fontBold, size: 10},
+		}
+		add := func(label, value string) {
+			if value != "" {
+				if label != "" {
+					value = label + ": " + value
+				}
+				lines = append(lines, styledLine{text: value, size: 9})
+			}
+		}
+		add(labels.VATID, p.VATID)
+		add(labels.RegistrationID, p.RegistrationID)
+		for _, line := range p.Address {
+			add("", line)
+		}
+		add(labels.Email, p.Email)
+		add(labels.Phone, p.Phone)
+		return lines
+	}
+	partyWidth := contentWidth/2 - 10
+	ySupplier := l.block(margin, l.y, partyWidth, partyLines(labels.Supplier, v.Supplier))
+	yCustomer := l.block(margin+contentWidth/2+10, l.y, partyWidth, partyLines(labels.Customer, v.Customer))
+	l.y = min(ySupplier, yCustomer) - 20
+
+	// Invoice lines.
+	lineColumns := tableColumns(
+		[]float64{25, 0, 50, 35, 65, 40, 75},
+		[]bool{false, false, true, false, true, true, true},
+	)
+	lineHeader := []string{labels.LineNo, labels.Item, labels.Quantity, labels.Unit,
+		labels.Price, labels.VATRate, labels.Amount}
+	tableHeader := func(columns []column, header []string) func() {
+		return func() {
+			l.fill(margin, l.y-14, contentWidth, 14)
+			l.row(columns, header, fontBold, 8)
+		}
+	}
+	tableHeader(lineColumns, lineHeader)()
+	l.onNewPage = tableHeader(lineColumns, lineHeader)
+	for _, line := range v.Lines {
+		name := line.Name
+		if line.Description != "" {
+			name += " - " + line.Description
+		}
+		l.row(lineColumns, []string{line.ID, name, line.Quantity, line.Unit,
+			line.Price, line.VATRate, line.Amount}, fontRegular, 9)
+		l.line(margin, pageWidth-margin, l.y)
+	}
+	l.onNewPage = nil
+	l.y -= 16
+
+	// VAT breakdown.
+	if len(v.VAT) > 0 {
+		l.ensure(60)
+		l.paragraph(margin, contentWidth, fontBold, 10, labels.VATBreakdown)
+		l.y -= 4
+		vatColumns := tableColumns([]float64{70, 50, 0, 100, 100}, []bool{false, true, false, true, true})
+		vatHeader := []string{labels.VATCategory, labels.VATRate, "", labels.TaxableAmount, labels.TaxAmount}
+		tableHeader(vatColumns, vatHeader)()
+		l.onNewPage = tableHeader(vatColumns, vatHeader)
+		for _, vat := range v.VAT {
+			l.row(vatColumns, []string{vat.Category, vat.Rate, vat.ExemptionReason,
+				vat.TaxableAmount, vat.TaxAmount}, fontRegular, 9)
+		}
+		l.onNewPage = nil
+		l.y -= 16
+	}
+
+	// Totals.
+	for _, total := range v.Totals {
+		f, size := fontRegular, 9.0
+		if total.Bold {
+			f, size = fontBold, 11
+		}
+		l.ensure(size * 1.6)
+		l.y -= size * 1.4
+		l.text(pageWidth-margin-250, l.y, f, size, total.Label)
+		l.textRight(pageWidth-margin, l.y, f, size, total.Amount+" "+v.Currency)
+	}
+	l.y -= 16
+
+	// Notes and payment details.
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{{labels.Payment, v.Payment}, {labels.Notes, v.Notes}} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		l.ensure(40)
+		l.paragraph(margin, contentWidth, fontBold, 10, section.title)
+		for _, line := range section.lines {
+			l.paragraph(margin, contentWidth, fontRegular, 9, line)
+		}
+		l.y -= 10
+	}
+
+	// Footer.
+	for i, page := range l.pages {
+		footer := fmt.Sprintf("%s %d / %d", labels.Page, i+1, len(l.pages))
+		l.page = page
+		l.line(margin, pageWidth-margin, margin+footerHeight-12)
+		l.text(margin, margin, fontRegular, 8, v.Title+" "+v.ID)
+		l.textRight(pageWidth-margin, margin, fontRegular, 8, footer)
+	}
+
+	// Objects.
+	doc := &document{pages: len(l.pages)}
+	w := &doc.writer
+	fontRegularID := w.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	fontBoldID := w.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	resources := fmt.Sprintf("/Font << /F1 %d 0 R /F2 %d 0 R >>", fontRegularID, fontBoldID)
+	if r.logoImage != nil {
+		imageID, err := addImage(w, r.logoImage)
+		if err != nil {
+			return nil, err
+		}
+		resources += fmt.Sprintf(" /XObject << /Im1 %d 0 R >>", imageID)
+	}
+	pagesID := w.reserve()
+	var kids []string
+	for _, page := range l.pages {
+		contentID, err := w.addStream("", page.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		pageID := w.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+			pagesID, pdfNumber(pageWidth), pdfNumber(pageHeight), resources, contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	w.set(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	doc.info = w.add(fmt.Sprintf("<< /Title %s /Producer %s >>", pdfTextString(v.Title+" "+v.ID), pdfString(producer)))
+	doc.catalog = w.add(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return doc, nil
+}
+
+// addImage adds the image as a RGB image XObject. The transparent pixels
+// are composed over a white background.
+func addImage(w *writer, img image.Image) (int, error) {
+	b := img.Bounds()
+	data := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			data = append(data, byte((r+white)>>8), byte((g+white)>>8), byte((bl+white)>>8))
+		}
+	}
+	return w.addStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8",
+		b.Dx(), b.Dy()), data)
+}
@@ -0,0 +1,455 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+// ErrUnsupportedPDF is returned when an existing PDF file cannot be updated,
+// eg. it's encrypted or it uses cross-reference streams.
+var ErrUnsupportedPDF = errors.New("pdf: unsupported PDF file")
+
+// objRef is a reference to an indirect object.
+type objRef struct {
+	id, gen int
+}
+
+func (r objRef) String() string {
+	return fmt.Sprintf("%d %d R", r.id, r.gen)
+}
+
+// dictEntry is an entry of a dictionary, with the raw value.
+type dictEntry struct {
+	key   string
+	value []byte
+}
+
+// dict is a dictionary with the entries in the original order.
+type dict []dictEntry
+
+// get returns the raw value of the entry with the given key (without the
+// leading slash), or nil if the entry doesn't exist.
+func (d dict) get(key string) []byte {
+	for _, e := range d {
+		if e.key == key {
+			return e.value
+		}
+	}
+	return nil
+}
+
+// without returns the dictionary without the entries with the given keys.
+func (d dict) without(keys ...string) dict {
+	var res dict
+	for _, e := range d {
+		keep := true
+		for _, key := range keys {
+			if e.key == key {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (d dict) String() string {
+	var buf bytes.Buffer
+	for i, e := range d {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte('/')
+		buf.WriteString(e.key)
+		buf.WriteByte(' ')
+		buf.Write(e.value)
+	}
+	return buf.String()
+}
+
+// pdfReader is a minimal reader of an existing PDF file, enough for adding
+// an incremental update. Only the files with cross-reference tables are
+// supported.
+type pdfReader struct {
+	data      []byte
+	startxref int
+	trailer   dict
+	size      int
+}
+
+func newPDFReader(data []byte) (*pdfReader, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("%w: missing header", ErrUnsupportedPDF)
+	}
+	idx := bytes.LastIndex(data, []byte("startxref"))
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: missing startxref", ErrUnsupportedPDF)
+	}
+	i := skipSpace(data, idx+len("startxref"))
+	end := scanToken(data, i)
+	startxref, err := strconv.Atoi(string(data[i:end]))
+	if err != nil || startxref < 0 || startxref >= len(data) {
+		return nil, fmt.Errorf("%w: invalid startxref", ErrUnsupportedPDF)
+	}
+	r := &pdfReader{data: data, startxref: startxref}
+	if _, r.trailer, err = r.xrefSection(startxref); err != nil {
+		return nil, err
+	}
+	if r.trailer.get("Encrypt") != nil {
+		return nil, fmt.Errorf("%w: encrypted file", ErrUnsupportedPDF)
+	}
+	if r.size, err = strconv.Atoi(string(r.trailer.get("Size"))); err != nil {
+		return nil, fmt.Errorf("%w: invalid trailer Size", ErrUnsupportedPDF)
+	}
+	return r, nil
+}
+
+// xrefSection parses the cross-reference section at offset and returns the
+// offsets of the objects in use and the trailer.
+func (r *pdfReader) xrefSection(offset int) (map[int]int, dict, error) {
+	data := r.data
+	if !bytes.HasPrefix(data[offset:], []byte("xref")) {
+		return nil, nil, fmt.Errorf("%w: cross-reference streams are not supported", ErrUnsupportedPDF)
+	}
+	offsets := make(map[int]int)
+	i := offset + len("xref")
+	for {
+		i = skipSpace(data, i)
+		if bytes.HasPrefix(data[i:], []byte("trailer")) {
+			break
+		}
+		var first, count int
+		if _, err := fmt.Sscanf(string(data[i:min(i+64, len(data))]), "%d %d", &first, &count); err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid cross-reference section", ErrUnsupportedPDF)
+		}
+		i = bytes.IndexByte(data[i:], '\n') + i + 1
+		for n := 0; n < count; n++ {
+			// Each entry has exactly 20 bytes, but some writers use a single
+			// byte end of line.
+			i = skipSpace(data, i)
+			if i+18 > len(data) {
+				return nil, nil, fmt.Errorf("%w: truncated cross-reference section", ErrUnsupportedPDF)
+			}
+			objOffset, err := strconv.Atoi(string(data[i : i+10]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: invalid cross-reference entry", ErrUnsupportedPDF)
+			}
+			if data[i+17] == 'n' {
+				offsets[first+n] = objOffset
+			}
+			i += 18
+		}
+	}
+	trailer, _, err := parseDict(data, skipSpace(data, i+len("trailer")))
+	if err != nil {
+		return nil, nil, err
+	}
+	return offsets, trailer, nil
+}
+
+// lookup returns the offset of the object, following the previous
+// cross-reference sections.
+func (r *pdfReader) lookup(ref objRef) (int, error) {
+	offset := r.startxref
+	for seen := 0; seen < 1024; seen++ {
+		offsets, trailer, err := r.xrefSection(offset)
+		if err != nil {
+			return 0, err
+		}
+		if objOffset, ok := offsets[ref.id]; ok {
+			return objOffset, nil
+		}
+		prev := trailer.get("Prev")
+		if prev == nil {
+			break
+		}
+		if offset, err = strconv.Atoi(string(prev)); err != nil || offset < 0 || offset >= len(r.data) {
+			return 0, fmt.Errorf("%w: invalid Prev", ErrUnsupportedPDF)
+		}
+	}
+	return 0, fmt.Errorf("%w: object %s not found", ErrUnsupportedPDF, ref)
+}
+
+// dict returns the dictionary of the object.
+func (r *pdfReader) dict(ref objRef) (dict, error) {
+	offset, err := r.lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("%d %d obj", ref.id, ref.gen)
+	if offset >= len(r.data) || !bytes.HasPrefix(r.data[offset:], []byte(header)) {
+		return nil, fmt.Errorf("%w: object %s not found at offset %d", ErrUnsupportedPDF, ref, offset)
+	}
+	d, _, err := parseDict(r.data, skipSpace(r.data, offset+len(header)))
+	return d, err
+}
+
+// resolveDict returns the dictionary value, which is either a direct
+// dictionary or a reference to a dictionary object.
+func (r *pdfReader) resolveDict(value []byte) (dict, error) {
+	if ref, ok := parseRef(value); ok {
+		return r.dict(ref)
+	}
+	d, _, err := parseDict(value, 0)
+	return d, err
+}
+
+// parseRef parses an indirect reference.
+func parseRef(value []byte) (ref objRef, ok bool) {
+	_, err := fmt.Sscanf(string(value), "%d %d R", &ref.id, &ref.gen)
+	return ref, err == nil && bytes.HasSuffix(value, []byte("R"))
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips the white space and the comments.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch {
+		case isSpace(data[i]):
+			i++
+		case data[i] == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanToken returns the end of the regular token (number, keyword) at i.
+func scanToken(data []byte, i int) int {
+	for i < len(data) && !isSpace(data[i]) && !isDelimiter(data[i]) {
+		i++
+	}
+	return i
+}
+
+// scanObject returns the end of the object at i. The references are
+// scanned as a single object.
+func scanObject(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, fmt.Errorf("%w: unexpected end of file", ErrUnsupportedPDF)
+	}
+	switch {
+	case bytes.HasPrefix(data[i:], []byte("<<")):
+		_, end, err := parseDict(data, i)
+		return end, err
+	case data[i] == '<':
+		end := bytes.IndexByte(data[i:], '>')
+		if end < 0 {
+			return 0, fmt.Errorf("%w: unterminated hex string", ErrUnsupportedPDF)
+		}
+		return i + end + 1, nil
+	case data[i] == '(':
+		depth := 0
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case '\\':
+				j++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("%w: unterminated string", ErrUnsupportedPDF)
+	case data[i] == '[':
+		j := skipSpace(data, i+1)
+		for j < len(data) && data[j] != ']' {
+			end, err := scanObject(data, j)
+			if err != nil {
+				return 0, err
+			}
+			j = skipSpace(data, end)
+		}
+		if j >= len(data) {
+			return 0, fmt.Errorf("%w: unterminated array", ErrUnsupportedPDF)
+		}
+		return j + 1, nil
+	case data[i] == '/':
+		return scanToken(data, i+1), nil
+	case isDelimiter(data[i]):
+		return 0, fmt.Errorf("%w: unexpected %q", ErrUnsupportedPDF, data[i])
+	}
+	end := scanToken(data, i)
+	// A reference is two integers followed by R.
+	if j := skipSpace(data, end); j < len(data) && data[j] >= '0' && data[j] <= '9' {
+		genEnd := scanToken(data, j)
+		if k := skipSpace(data, genEnd); k < len(data) && data[k] == 'R' && scanToken(data, k) == k+1 {
+			return k + 1, nil
+		}
+	}
+	return end, nil
+}
+
+// parseDict parses the dictionary at i and returns its entries and the end
+// of the dictionary.
+func parseDict(data []byte, i int) (dict, int, error) {
+	if !bytes.HasPrefix(data[i:], []byte("<<")) {
+		return nil, 0, fmt.Errorf("%w: expected dictionary", ErrUnsupportedPDF)
+	}
+	var d dict
+	i = skipSpace(data, i+2)
+	for !bytes.HasPrefix(data[i:], []byte(">>")) {
+		if i >= len(data) || data[i] != '/' {
+			return nil, 0, fmt.Errorf("%w: invalid dictionary key", ErrUnsupportedPDF)
+		}
+		keyEnd := scanToken(data, i+1)
+		valueStart := skipSpace(data, keyEnd)
+		valueEnd, err := scanObject(data, valueStart)
+		if err != nil {
+			return nil, 0, err
+		}
+		d = append(d, dictEntry{key: string(data[i+1 : keyEnd]), value: data[valueStart:valueEnd]})
+		i = skipSpace(data, valueEnd)
+	}
+	return d, i + 2, nil
+}
+
+// decodeTextString decodes a PDF text string (literal or hexadecimal,
+// PDFDocEncoding or UTF-16BE with BOM). PDFDocEncoding is decoded as
+// Latin-1, which is the same for the common characters.
+func decodeTextString(value []byte) string {
+	var raw []byte
+	switch {
+	case len(value) >= 2 && value[0] == '(':
+		s := value[1 : len(value)-1]
+		for i := 0; i < len(s); i++ {
+			if s[i] != '\\' || i+1 == len(s) {
+				raw = append(raw, s[i])
+				continue
+			}
+			i++
+			switch c := s[i]; c {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b':
+				raw = append(raw, '\b')
+			case 'f':
+				raw = append(raw, '\f')
+			case '\r', '\n':
+				// Line continuation.
+			default:
+				if c >= '0' && c <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; j++ {
+						n = n*8 + int(s[i]-'0')
+						i++
+					}
+					i--
+					raw = append(raw, byte(n))
+				} else {
+					raw = append(raw, c)
+				}
+			}
+		}
+	case len(value) >= 2 && value[0] == '<':
+		h := bytes.Map(func(r rune) rune {
+			if isSpace(byte(r)) {
+				return -1
+			}
+			return r
+		}, value[1:len(value)-1])
+		if len(h)%2 == 1 {
+			h = append(h, '0')
+		}
+		raw = make([]byte, hex.DecodedLen(len(h)))
+		if _, err := hex.Decode(raw, h); err != nil {
+			return ""
+		}
+	default:
+		return ""
+	}
+	if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+		u := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			u = append(u, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// pdfTextString encodes s as a PDF text string: a literal string for ASCII
+// and a UTF-16BE hexadecimal string otherwise.
+func pdfTextString(s string) string {
+	ascii := true
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return pdfString(s)
+	}
+	buf := []byte{0xfe, 0xff}
+	for _, u := range utf16.Encode([]rune(s)) {
+		buf = append(buf, byte(u>>8), byte(u))
+	}
+	return "<" + hex.EncodeToString(buf) + ">"
+}
+
+// pdfName encodes s as a PDF name.
+func pdfName(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('/')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(&buf, "#%02X", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDict(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte(`<< /Type /Catalog /Pages 3 0 R /Names << /Dests 5 0 R >> % comment
+/Lang (ro\)\(RO) /Kids [1 0 R 2 0 R [3 4]] /ID <AB01> /Count 2 >>`)
+	d, end, err := parseDict(data, 0)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(len(data), end)
+	assert.Equal("/Catalog", string(d.get("Type")))
+	assert.Equal("3 0 R", string(d.get("Pages")))
+	assert.Equal("<< /Dests 5 0 R >>", string(d.get("Names")))
+	assert.Equal(`(ro\)\(RO)`, string(d.get("Lang")))
+	assert.Equal("[1 0 R 2 0 R [3 4]]", string(d.get("Kids")))
+	assert.Equal("<AB01>", string(d.get("ID")))
+	assert.Equal("2", string(d.get("Count")))
+	assert.Nil(d.get("Missing"))
+	assert.Equal("/Type /Catalog /Count 2", d.without("Pages", "Names", "Lang", "Kids", "ID").String())
+
+	ref, ok := parseRef(d.get("Pages"))
+	assert.True(ok)
+	assert.Equal(objRef{id: 3}, ref)
+	_, ok = parseRef(d.get("Count"))
+	assert.False(ok)
+
+	_, _, err = parseDict([]byte("<< /Type /Catalog"), 0)
+	assert.ErrorIs(err, ErrUnsupportedPDF)
+}
+
+func TestTextStrings(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Factura (1)", decodeTextString([]byte(`(Factura \(1\))`)))
+	assert.Equal("a\nbé", decodeTextString([]byte(`(a\nb\351)`)))
+	assert.Equal("AB", decodeTextString([]byte(`<4142>`)))
+	assert.Equal("Factură", decodeTextString([]byte(pdfTextString("Factură"))))
+	assert.Equal("(Factura)", pdfTextString("Factura"))
+	assert.Equal("/text#2Fxml", pdfName("text/xml"))
+}
+
+func TestSRGBProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	profile := srgbProfile()
+	assert.Equal(uint32(len(profile)), binary.BigEndian.Uint32(profile))
+	assert.Equal("mntrRGB XYZ ", string(profile[12:24]))
+	assert.Equal("acsp", string(profile[36:40]))
+	tags := binary.BigEndian.Uint32(profile[128:])
+	assert.Equal(uint32(9), tags)
+	for i := uint32(0); i < tags; i++ {
+		entry := profile[132+12*i:]
+		offset, size := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		assert.Zero(offset % 4)
+		assert.LessOrEqual(int(offset+size), len(profile))
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package pdf renders invoices as human-readable PDF or HTML documents
+// locally, without the rate-limited ANAF XML-To-PDF API. The PDF is
+// generated with the standard PDF fonts (no font embedding), so the
+// characters outside Latin-1 (eg. ș, ț, ă) are transliterated; the HTML is
+// rendered with a html/template that can be replaced. EmbedAttachments
+// turns a PDF into a PDF/A-3 archive with the invoice XML and the signature
+// embedded.
+package pdf
+
+import (
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// DefaultHTMLTemplate is the default template used by RenderHTML. It's
+// executed with a View.
+var DefaultHTMLTemplate = template.Must(template.ParseFS(templatesFS, "templates/invoice.html"))
+
+// Config is the config used to create a Renderer.
+type Config struct {
+	// Language is the language of the labels. Default is LanguageRO.
+	Language Language
+	// Logo is a PNG or JPEG image shown in the header (optional).
+	Logo []byte
+	// HTMLTemplate is the template used by RenderHTML, executed with a
+	// View. Default is DefaultHTMLTemplate.
+	HTMLTemplate *template.Template
+}
+
+// ConfigOption allows gradually modifying a Config.
+type ConfigOption func(*Config)
+
+// ConfigLanguage sets the language of the labels.
+func ConfigLanguage(lang Language) ConfigOption {
+	return func(c *Config) {
+		c.Language = lang
+	}
+}
+
+// ConfigLogo sets the logo (a PNG or JPEG image).
+func ConfigLogo(logo []byte) ConfigOption {
+	return func(c *Config) {
+		c.Logo = logo
+	}
+}
+
+// ConfigHTMLTemplate sets the template used by RenderHTML.
+func ConfigHTMLTemplate(t *template.Template) ConfigOption {
+	return func(c *Config) {
+		c.HTMLTemplate = t
+	}
+}
+
+// Renderer renders invoices as PDF or HTML documents.
+type Renderer struct {
+	lang         Language
+	logo         []byte
+	logoImage    image.Image
+	htmlTemplate *template.Template
+}
+
+// NewRenderer creates a new Renderer. An error is returned if the logo is
+// not a valid PNG or JPEG image.
+func NewRenderer(opts ...ConfigOption) (*Renderer, error) {
+	cfg := Config{
+		Language:     LanguageRO,
+		HTMLTemplate: DefaultHTMLTemplate,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	r := &Renderer{
+		lang:         cfg.Language,
+		logo:         cfg.Logo,
+		htmlTemplate: cfg.HTMLTemplate,
+	}
+	if len(cfg.Logo) > 0 {
+		img, _, err := image.Decode(bytes.NewReader(cfg.Logo))
+		if err != nil {
+			return nil, fmt.Errorf("pdf: invalid logo: %w", err)
+		}
+		r.logoImage = img
+	}
+	return r, nil
+}
+
+// View returns the View of the invoice passed to the HTML template.
+func (r *Renderer) View(iv efactura.Invoice) View {
+	v := NewView(iv, r.lang)
+	if len(r.logo) > 0 {
+		v.Logo = template.URL("data:" + http.DetectContentType(r.logo) + ";base64," +
+			base64.StdEncoding.EncodeToString(r.logo))
+	}
+	return v
+}
+
+// RenderHTML writes the invoice as a HTML document to w.
+func (r *Renderer) RenderHTML(w io.Writer, iv efactura.Invoice) error {
+	return r.htmlTemplate.Execute(w, r.View(iv))
+}
+
+// RenderPDF writes the invoice as an A4 PDF document to w.
+func (r *Renderer) RenderPDF(w io.Writer, iv efactura.Invoice) error {
+	doc, err := r.renderDocument(iv)
+	if err != nil {
+		return err
+	}
+	return doc.writer.writeTo(w, doc.catalog, doc.info)
+}
+
+// RenderCreditNotePDF writes the credit note as an A4 PDF document to w.
+func (r *Renderer) RenderCreditNotePDF(w io.Writer, cn efactura.CreditNote) error {
+	return r.RenderPDF(w, cn.Invoice())
+}
+
+// RenderCreditNoteHTML writes the credit note as a HTML document to w.
+func (r *Renderer) RenderCreditNoteHTML(w io.Writer, cn efactura.CreditNote) error {
+	return r.RenderHTML(w, cn.Invoice())
+}
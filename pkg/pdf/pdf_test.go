@@ -0,0 +1,302 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/pdf"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+func buildTestInvoice(t *testing.T, lines int) efactura.Invoice {
+	t.Helper()
+
+	var invoiceLines []efactura.InvoiceLine
+	for i := 1; i <= lines; i++ {
+		line, err := efactura.NewInvoiceLineBuilder(strconv.Itoa(i), efactura.CurrencyRON).
+			WithUnitCode("H87").
+			WithInvoicedQuantity(types.D(2)).
+			WithGrossPriceAmount(types.D(50)).
+			WithItemName(fmt.Sprintf("Produs %d", i)).
+			WithItemTaxCategory(efactura.InvoiceLineTaxCategory{
+				TaxScheme: efactura.TaxSchemeVAT,
+				ID:        efactura.TaxCategoryVATStandardRate,
+				Percent:   types.D(19),
+			}).
+			Build()
+		if err != nil {
+			t.Fatalf("error building line: %v", err)
+		}
+		invoiceLines = append(invoiceLines, line)
+	}
+	invoice, err := efactura.NewInvoiceBuilder("FCT-1").
+		WithIssueDate(types.MakeDate(2024, 3, 1)).
+		WithDueDate(types.MakeDate(2024, 3, 31)).
+		WithInvoiceTypeCode(efactura.InvoiceTypeCommercialInvoice).
+		WithDocumentCurrencyCode(efactura.CurrencyRON).
+		WithSupplier(efactura.InvoiceSupplierParty{
+			PostalAddress: efactura.MakeInvoiceSupplierPostalAddress(efactura.PostalAddress{
+				Country:          efactura.CountryRO,
+				CountrySubentity: efactura.CountrySubentityRO_B,
+				CityName:         efactura.CityNameROBSector1,
+				Line1:            "Strada Șoseaua 1",
+			}),
+			TaxScheme: &efactura.InvoicePartyTaxScheme{
+				TaxScheme: efactura.TaxSchemeVAT,
+				CompanyID: "RO10000008",
+			},
+			LegalEntity: efactura.InvoiceSupplierLegalEntity{
+				Name:      "Furnizor SRL",
+				CompanyID: efactura.NewValueWithAttrs("J40/1/2020"),
+			},
+		}).
+		WithCustomer(efactura.InvoiceCustomerParty{
+			PostalAddress: efactura.MakeInvoiceCustomerPostalAddress(efactura.PostalAddress{
+				Country:          efactura.CountryRO,
+				CountrySubentity: efactura.CountrySubentityRO_CJ,
+				CityName:         "Cluj-Napoca",
+				Line1:            "Strada Client 2",
+			}),
+			LegalEntity: efactura.InvoiceCustomerLegalEntity{
+				Name:      "Client SRL",
+				CompanyID: efactura.NewValueWithAttrs("RO20000001"),
+			},
+		}).
+		WithInvoiceLines(invoiceLines).
+		Build()
+	if err != nil {
+		t.Fatalf("error building invoice: %v", err)
+	}
+	invoice.Note = []efactura.InvoiceNote{{Note: "Mulțumim pentru comandă"}}
+	return invoice
+}
+
+func testLogo() []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+		img.Set(x, 1, color.NRGBA{B: 255, A: 0})
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+var (
+	pdfObjectRegexp = regexp.MustCompile(`(?s)(\d+) 0 obj\n(.*?)\nendobj`)
+	pdfStreamRegexp = regexp.MustCompile(`(?s)stream\n(.*)\nendstream`)
+)
+
+// checkPDF checks the structure of the PDF document and returns the
+// content of the inflated streams.
+func checkPDF(t *testing.T, data []byte) string {
+	t.Helper()
+	assert := assert.New(t)
+
+	assert.True(bytes.HasPrefix(data, []byte("%PDF-1.7\n")))
+	assert.True(bytes.HasSuffix(data, []byte("%%EOF\n")))
+
+	// The startxref offset points to the cross-reference table and the
+	// offsets of the table point to the objects. The previous sections of
+	// the incremental updates are checked too.
+	idx := bytes.LastIndex(data, []byte("startxref\n"))
+	if !assert.True(idx > 0) {
+		return ""
+	}
+	var xrefOffset int
+	_, err := fmt.Sscanf(string(data[idx:]), "startxref\n%d", &xrefOffset)
+	if !assert.NoError(err) {
+		return ""
+	}
+	for xrefOffset > 0 {
+		if !assert.True(bytes.HasPrefix(data[xrefOffset:], []byte("xref\n"))) {
+			return ""
+		}
+		lines := strings.Split(string(data[xrefOffset:]), "\n")[1:]
+		for len(lines) > 0 && lines[0] != "trailer" {
+			var first, count int
+			_, err = fmt.Sscanf(lines[0], "%d %d", &first, &count)
+			if !assert.NoError(err) {
+				return ""
+			}
+			for i, entry := range lines[1 : count+1] {
+				var offset int
+				if _, err := fmt.Sscanf(entry, "%010d", &offset); assert.NoError(err) && strings.HasSuffix(entry, " n ") {
+					id := first + i
+					assert.True(bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj\n", id))), "object %d", id)
+				}
+			}
+			lines = lines[count+1:]
+		}
+		xrefOffset = 0
+		if len(lines) > 1 {
+			fmt.Sscanf(lines[1][strings.Index(lines[1], "/Prev")+1:], "Prev %d", &xrefOffset)
+		}
+	}
+
+	var content strings.Builder
+	for _, m := range pdfObjectRegexp.FindAllSubmatch(data, -1) {
+		sm := pdfStreamRegexp.FindSubmatch(m[2])
+		if sm == nil {
+			continue
+		}
+		if !bytes.Contains(m[2], []byte("/FlateDecode")) {
+			content.Write(sm[1])
+			continue
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(sm[1]))
+		if !assert.NoError(err) {
+			continue
+		}
+		inflated, err := io.ReadAll(zr)
+		assert.NoError(err)
+		content.Write(inflated)
+	}
+	return content.String()
+}
+
+func TestRenderPDF(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer()
+	if !assert.NoError(err) {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(r.RenderPDF(&buf, buildTestInvoice(t, 2))) {
+		return
+	}
+	content := checkPDF(t, buf.Bytes())
+	assert.Contains(content, "(Nr. FCT-1)")
+	assert.Contains(content, "(Furnizor SRL)")
+	assert.Contains(content, "(Client SRL)")
+	assert.Contains(content, "(Produs 2)")
+	assert.Contains(content, "(Pagina 1 / 1)")
+	// Ș is not in the WinAnsi encoding of the standard fonts.
+	assert.Contains(content, "(Strada Soseaua 1)")
+	assert.Contains(string(buf.Bytes()), "/BaseFont /Helvetica-Bold")
+	assert.NotContains(string(buf.Bytes()), "/XObject")
+
+	// The output is deterministic.
+	var buf2 bytes.Buffer
+	if assert.NoError(r.RenderPDF(&buf2, buildTestInvoice(t, 2))) {
+		assert.Equal(buf.Bytes(), buf2.Bytes())
+	}
+}
+
+func TestRenderPDFPages(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer(pdf.ConfigLanguage(pdf.LanguageEN))
+	if !assert.NoError(err) {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(r.RenderPDF(&buf, buildTestInvoice(t, 80))) {
+		return
+	}
+	content := checkPDF(t, buf.Bytes())
+	assert.Contains(content, "(Produs 80)")
+	assert.Regexp(`\(Page 1 / [2-9]\)`, content)
+	assert.Contains(string(buf.Bytes()), "/Type /Pages")
+	// The header of the lines table is repeated on each page.
+	pages := strings.Count(content, "(Page ")
+	assert.Equal(pages, strings.Count(content, "(Unit price)"))
+}
+
+func TestRenderPDFLogo(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer(pdf.ConfigLogo(testLogo()))
+	if !assert.NoError(err) {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(r.RenderPDF(&buf, buildTestInvoice(t, 1))) {
+		return
+	}
+	content := checkPDF(t, buf.Bytes())
+	assert.Contains(string(buf.Bytes()), "/Subtype /Image /Width 4 /Height 2")
+	assert.Contains(content, "/Im1 Do")
+	// The transparent pixels are white.
+	assert.Contains(content, "\xff\x00\x00\xff\x00\x00\xff\x00\x00\xff\x00\x00\xff\xff\xff")
+
+	_, err = pdf.NewRenderer(pdf.ConfigLogo([]byte("not an image")))
+	assert.Error(err)
+}
+
+func TestRenderHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer(pdf.ConfigLogo(testLogo()))
+	if !assert.NoError(err) {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(r.RenderHTML(&buf, buildTestInvoice(t, 1))) {
+		return
+	}
+	html := buf.String()
+	assert.Contains(html, `<html lang="ro">`)
+	assert.Contains(html, "Factură")
+	assert.Contains(html, "FCT-1")
+	assert.Contains(html, "Strada Șoseaua 1")
+	assert.Contains(html, "Mulțumim pentru comandă")
+	assert.Contains(html, `src="data:image/png;base64,`)
+
+	r, err = pdf.NewRenderer(pdf.ConfigLanguage(pdf.LanguageEN))
+	if !assert.NoError(err) {
+		return
+	}
+	buf.Reset()
+	if assert.NoError(r.RenderHTML(&buf, buildTestInvoice(t, 1))) {
+		assert.Contains(buf.String(), `<html lang="en">`)
+		assert.Contains(buf.String(), "Amount due")
+		assert.NotContains(buf.String(), "<img")
+	}
+}
+
+func TestRenderCreditNote(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer(pdf.ConfigLanguage(pdf.LanguageEN))
+	if !assert.NoError(err) {
+		return
+	}
+	cn := buildTestInvoice(t, 1).CreditNote()
+	cn.CreditNoteTypeCode = efactura.InvoiceTypeCreditNote
+	var buf bytes.Buffer
+	if assert.NoError(r.RenderCreditNoteHTML(&buf, cn)) {
+		assert.Contains(buf.String(), "Credit note")
+	}
+	buf.Reset()
+	if assert.NoError(r.RenderCreditNotePDF(&buf, cn)) {
+		assert.Contains(checkPDF(t, buf.Bytes()), "(Credit note)")
+	}
+}
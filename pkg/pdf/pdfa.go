@@ -0,0 +1,347 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// AFRelationship is the relationship between an embedded file and the PDF
+// document (the AFRelationship key of the file specification).
+type AFRelationship string
+
+const (
+	// AFRelationshipSource is used for the original source of the PDF
+	// document, eg. the invoice XML.
+	AFRelationshipSource AFRelationship = "Source"
+	// AFRelationshipData is used for the data used to render the document.
+	AFRelationshipData AFRelationship = "Data"
+	// AFRelationshipAlternative is used for an alternative representation
+	// of the document.
+	AFRelationshipAlternative AFRelationship = "Alternative"
+	// AFRelationshipSupplement is used for a supplemental representation
+	// of the document, eg. the signature of the invoice XML.
+	AFRelationshipSupplement AFRelationship = "Supplement"
+	// AFRelationshipUnspecified is used when the relationship is not known.
+	AFRelationshipUnspecified AFRelationship = "Unspecified"
+)
+
+// Attachment is a file embedded in a PDF/A-3 document.
+type Attachment struct {
+	// Name is the file name.
+	Name string
+	// Data is the content of the file.
+	Data []byte
+	// MimeType is the MIME type of the file (application/octet-stream if
+	// not set).
+	MimeType string
+	// Description is an optional description of the file.
+	Description string
+	// Relationship is the relationship with the document
+	// (AFRelationshipUnspecified if not set).
+	Relationship AFRelationship
+	// ModTime is the modification time of the file. The time of the
+	// archive is used if not set.
+	ModTime time.Time
+}
+
+// ErrAttachmentsExist is returned by EmbedAttachments if the PDF file
+// already has embedded files.
+var ErrAttachmentsExist = errors.New("pdf: the PDF file already has embedded files")
+
+// ArchiveConfig is the configuration of EmbedAttachments.
+type ArchiveConfig struct {
+	// Title is the title of the document. The title of the PDF file is
+	// kept if not set.
+	Title string
+	// CurrentTime is the creation and modification time of the archive.
+	// time.Now() is used if not set.
+	CurrentTime time.Time
+}
+
+// ArchiveOption allows gradually modifying an ArchiveConfig.
+type ArchiveOption func(*ArchiveConfig)
+
+// ArchiveTitle sets the title of the document.
+func ArchiveTitle(title string) ArchiveOption {
+	return func(c *ArchiveConfig) {
+		c.Title = title
+	}
+}
+
+// ArchiveCurrentTime sets the creation and modification time of the archive,
+// eg. for reproducible output.
+func ArchiveCurrentTime(t time.Time) ArchiveOption {
+	return func(c *ArchiveConfig) {
+		c.CurrentTime = t
+	}
+}
+
+// DownloadAttachments returns the attachments for the invoice XML and the
+// signature XML from a downloaded ZIP archive: the invoice is the source of
+// the document and the signature is a supplement.
+func DownloadAttachments(res *efactura.DownloadInvoiceParseZipResponse) []Attachment {
+	var attachments []Attachment
+	if len(res.InvoiceXML) > 0 {
+		attachments = append(attachments, Attachment{
+			Name:         firstNonEmpty(res.InvoiceName, "factura.xml"),
+			Data:         res.InvoiceXML,
+			MimeType:     "text/xml",
+			Description:  "e-Factura UBL XML",
+			Relationship: AFRelationshipSource,
+		})
+	}
+	if len(res.SignatureXML) > 0 {
+		attachments = append(attachments, Attachment{
+			Name:         firstNonEmpty(res.SignatureName, "semnatura.xml"),
+			Data:         res.SignatureXML,
+			MimeType:     "text/xml",
+			Description:  "e-Factura XML signature",
+			Relationship: AFRelationshipSupplement,
+		})
+	}
+	return attachments
+}
+
+// EmbedAttachments writes to w the PDF file pdfData as a PDF/A-3b document
+// with the attachments embedded as associated files (the same mechanism used
+// by Factur-X/ZUGFeRD). The original file is kept unchanged and an
+// incremental update is appended with the embedded files, the XMP metadata
+// and the sRGB output intent.
+//
+// PDF/A-3 also requires all the fonts to be embedded. The documents created
+// by Renderer use the standard PDF fonts, which are not embedded, so a strict
+// PDF/A validator reports them even if the rest of the document conforms.
+//
+// ErrUnsupportedPDF is returned for encrypted files and for files using
+// cross-reference streams, ErrAttachmentsExist for files with embedded
+// files.
+func EmbedAttachments(w io.Writer, pdfData []byte, attachments []Attachment, opts ...ArchiveOption) error {
+	cfg := ArchiveConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.CurrentTime.IsZero() {
+		cfg.CurrentTime = time.Now()
+	}
+
+	r, err := newPDFReader(pdfData)
+	if err != nil {
+		return err
+	}
+	root, ok := parseRef(r.trailer.get("Root"))
+	if !ok || root.gen != 0 {
+		return fmt.Errorf("%w: invalid trailer Root", ErrUnsupportedPDF)
+	}
+	catalog, err := r.dict(root)
+	if err != nil {
+		return err
+	}
+	var names dict
+	if value := catalog.get("Names"); value != nil {
+		if names, err = r.resolveDict(value); err != nil {
+			return err
+		}
+		if names.get("EmbeddedFiles") != nil {
+			return ErrAttachmentsExist
+		}
+	}
+	title := cfg.Title
+	var author string
+	if value := r.trailer.get("Info"); value != nil {
+		if info, err := r.resolveDict(value); err == nil {
+			if title == "" {
+				title = decodeTextString(info.get("Title"))
+			}
+			author = decodeTextString(info.get("Author"))
+		}
+	}
+
+	w2 := &writer{base: r.size - 1}
+	sorted := make([]Attachment, len(attachments))
+	copy(sorted, attachments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	var afs, nameTree []string
+	for _, a := range sorted {
+		fileSpecID, err := addAttachment(w2, a, cfg.CurrentTime)
+		if err != nil {
+			return err
+		}
+		afs = append(afs, fmt.Sprintf("%d 0 R", fileSpecID))
+		nameTree = append(nameTree, fmt.Sprintf("%s %d 0 R", pdfTextString(a.Name), fileSpecID))
+	}
+
+	metadata := xmpMetadata(title, author, cfg.CurrentTime)
+	metadataID := w2.add(fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream",
+		len(metadata), metadata))
+	iccID, err := w2.addStream("/N 3", srgbProfile())
+	if err != nil {
+		return err
+	}
+	infoDict := dict{}
+	if title != "" {
+		infoDict = append(infoDict, dictEntry{"Title", []byte(pdfTextString(title))})
+	}
+	if author != "" {
+		infoDict = append(infoDict, dictEntry{"Author", []byte(pdfTextString(author))})
+	}
+	infoDict = append(infoDict,
+		dictEntry{"Producer", []byte(pdfString(producer))},
+		dictEntry{"CreationDate", []byte(pdfString(pdfDate(cfg.CurrentTime)))},
+		dictEntry{"ModDate", []byte(pdfString(pdfDate(cfg.CurrentTime)))},
+	)
+	infoID := w2.add("<< " + infoDict.String() + " >>")
+
+	names = append(names, dictEntry{"EmbeddedFiles", []byte("<< /Names [" + strings.Join(nameTree, " ") + "] >>")})
+	catalog = append(catalog.without("Names", "AF", "Metadata", "OutputIntents"),
+		dictEntry{"Names", []byte("<< " + names.String() + " >>")},
+		dictEntry{"AF", []byte("[" + strings.Join(afs, " ") + "]")},
+		dictEntry{"Metadata", []byte(fmt.Sprintf("%d 0 R", metadataID))},
+		dictEntry{"OutputIntents", []byte(fmt.Sprintf(
+			"[<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>]",
+			iccID))},
+	)
+	if catalog.get("MarkInfo") == nil {
+		catalog = append(catalog, dictEntry{"MarkInfo", []byte("<< /Marked false >>")})
+	}
+
+	// The first identifier is kept from the original file, the second one
+	// identifies this version.
+	digest := md5.New()
+	digest.Write(pdfData)
+	for _, a := range sorted {
+		digest.Write(a.Data)
+	}
+	versionID := "<" + hex.EncodeToString(digest.Sum(nil)) + ">"
+	firstID := versionID
+	if ids := r.trailer.get("ID"); len(ids) > 2 {
+		i := skipSpace(ids, 1)
+		if end, err := scanObject(ids, i); err == nil {
+			firstID = string(ids[i:end])
+		}
+	}
+	trailer := fmt.Sprintf("/Root %s /Info %d 0 R /ID [%s %s] /Prev %d",
+		root, infoID, firstID, versionID, r.startxref)
+	replaced := map[int][]byte{root.id: []byte("<< " + catalog.String() + " >>")}
+	return w2.writeUpdateTo(w, pdfData, replaced, trailer)
+}
+
+// producer is the Producer of the documents.
+const producer = "e-factura-go"
+
+// addAttachment adds the embedded file stream and the file specification
+// and returns the number of the file specification.
+func addAttachment(w *writer, a Attachment, now time.Time) (int, error) {
+	mimeType := firstNonEmpty(a.MimeType, "application/octet-stream")
+	relationship := a.Relationship
+	if relationship == "" {
+		relationship = AFRelationshipUnspecified
+	}
+	modTime := a.ModTime
+	if modTime.IsZero() {
+		modTime = now
+	}
+	checksum := md5.Sum(a.Data)
+	fileID, err := w.addStream(fmt.Sprintf("/Type /EmbeddedFile /Subtype %s /Params << /ModDate %s /Size %d /CheckSum <%s> >>",
+		pdfName(mimeType), pdfString(pdfDate(modTime)), len(a.Data), hex.EncodeToString(checksum[:])), a.Data)
+	if err != nil {
+		return 0, err
+	}
+	spec := fmt.Sprintf("<< /Type /Filespec /F %s /UF %s /EF << /F %d 0 R /UF %d 0 R >> /AFRelationship %s",
+		pdfString(a.Name), pdfTextString(a.Name), fileID, fileID, pdfName(string(relationship)))
+	if a.Description != "" {
+		spec += " /Desc " + pdfTextString(a.Description)
+	}
+	return w.add(spec + " >>"), nil
+}
+
+// pdfDate formats t as a PDF date string.
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("D:%s%c%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// xmpMetadata returns the XMP metadata packet of a PDF/A-3b document.
+func xmpMetadata(title, author string, t time.Time) string {
+	escape := func(s string) string {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+	date := t.Format(time.RFC3339)
+	var buf strings.Builder
+	buf.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	buf.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	buf.WriteString("<rdf:Description rdf:about=\"\" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\">\n")
+	buf.WriteString("<pdfaid:part>3</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>\n")
+	buf.WriteString("</rdf:Description>\n")
+	buf.WriteString("<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	buf.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if title != "" {
+		fmt.Fprintf(&buf, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", escape(title))
+	}
+	if author != "" {
+		fmt.Fprintf(&buf, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", escape(author))
+	}
+	buf.WriteString("</rdf:Description>\n")
+	buf.WriteString("<rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	fmt.Fprintf(&buf, "<pdf:Producer>%s</pdf:Producer>\n", producer)
+	buf.WriteString("</rdf:Description>\n")
+	buf.WriteString("<rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	fmt.Fprintf(&buf, "<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n<xmp:MetadataDate>%s</xmp:MetadataDate>\n",
+		date, date, date)
+	buf.WriteString("</rdf:Description>\n")
+	buf.WriteString("</rdf:RDF>\n</x:xmpmeta>\n")
+	buf.WriteString("<?xpacket end=\"w\"?>")
+	return buf.String()
+}
+
+// RenderArchivePDF renders the invoice or the credit note from a downloaded
+// ZIP archive and writes it as a PDF/A-3 document with the invoice XML and
+// the signature embedded (see EmbedAttachments).
+func (r *Renderer) RenderArchivePDF(w io.Writer, res *efactura.DownloadInvoiceParseZipResponse, opts ...ArchiveOption) error {
+	var buf bytes.Buffer
+	switch {
+	case res.Invoice != nil:
+		if err := r.RenderPDF(&buf, *res.Invoice); err != nil {
+			return err
+		}
+	case res.CreditNote != nil:
+		if err := r.RenderCreditNotePDF(&buf, *res.CreditNote); err != nil {
+			return err
+		}
+	default:
+		return errors.New("pdf: the archive doesn't contain an invoice or a credit note")
+	}
+	return EmbedAttachments(w, buf.Bytes(), DownloadAttachments(res), opts...)
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/pdf"
+)
+
+func testDownloadResponse(t *testing.T) *efactura.DownloadInvoiceParseZipResponse {
+	invoice := buildTestInvoice(t, 1)
+	invoiceXML, err := invoice.XML()
+	if err != nil {
+		t.Fatalf("error marshaling invoice: %v", err)
+	}
+	return &efactura.DownloadInvoiceParseZipResponse{
+		InvoiceXML:    invoiceXML,
+		InvoiceName:   "4000000001.xml",
+		SignatureXML:  []byte(`<Signature xmlns="http://www.w3.org/2000/09/xmldsig#"/>`),
+		SignatureName: "semnatura_4000000001.xml",
+		Invoice:       &invoice,
+	}
+}
+
+func TestEmbedAttachments(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer()
+	if !assert.NoError(err) {
+		return
+	}
+	var rendered bytes.Buffer
+	if !assert.NoError(r.RenderPDF(&rendered, buildTestInvoice(t, 1))) {
+		return
+	}
+	res := testDownloadResponse(t)
+	archiveTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("EET", 2*3600))
+
+	var buf bytes.Buffer
+	err = pdf.EmbedAttachments(&buf, rendered.Bytes(), pdf.DownloadAttachments(res),
+		pdf.ArchiveCurrentTime(archiveTime))
+	if !assert.NoError(err) {
+		return
+	}
+	data := buf.Bytes()
+	// The original file is kept and the attachments are added in an
+	// incremental update.
+	assert.True(bytes.HasPrefix(data, rendered.Bytes()))
+	content := checkPDF(t, data)
+	assert.Contains(content, string(res.InvoiceXML))
+	assert.Contains(content, string(res.SignatureXML))
+	assert.Contains(content, "mntrRGB XYZ ")
+
+	s := string(data[rendered.Len():])
+	assert.Contains(s, "/Type /EmbeddedFile /Subtype /text#2Fxml")
+	assert.Contains(s, "/F (4000000001.xml) /UF (4000000001.xml)")
+	assert.Contains(s, "/AFRelationship /Source")
+	assert.Contains(s, "/AFRelationship /Supplement")
+	assert.Regexp(`/EmbeddedFiles << /Names \[\(4000000001\.xml\) \d+ 0 R \(semnatura_4000000001\.xml\) \d+ 0 R\] >>`, s)
+	assert.Regexp(`/AF \[\d+ 0 R \d+ 0 R\]`, s)
+	assert.Contains(s, "/OutputIntents [<< /Type /OutputIntent /S /GTS_PDFA1")
+	assert.Contains(s, "/Prev ")
+	assert.Contains(s, "/CreationDate (D:20240301120000+02'00')")
+	// The XMP metadata matches the document information.
+	assert.Contains(s, "<pdfaid:part>3</pdfaid:part>")
+	assert.Contains(s, "<pdfaid:conformance>B</pdfaid:conformance>")
+	assert.Contains(s, `<rdf:li xml:lang="x-default">Factură FCT-1</rdf:li>`)
+	assert.Contains(s, "/Title <feff")
+	assert.Contains(s, "<xmp:CreateDate>2024-03-01T12:00:00+02:00</xmp:CreateDate>")
+
+	// The output is deterministic with a fixed time.
+	var buf2 bytes.Buffer
+	if assert.NoError(pdf.EmbedAttachments(&buf2, rendered.Bytes(), pdf.DownloadAttachments(res),
+		pdf.ArchiveCurrentTime(archiveTime))) {
+		assert.Equal(data, buf2.Bytes())
+	}
+
+	// The attachments cannot be embedded twice.
+	err = pdf.EmbedAttachments(&buf2, data, nil)
+	assert.True(errors.Is(err, pdf.ErrAttachmentsExist))
+}
+
+func TestEmbedAttachmentsTitle(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer(pdf.ConfigLanguage(pdf.LanguageEN))
+	if !assert.NoError(err) {
+		return
+	}
+	var rendered bytes.Buffer
+	if !assert.NoError(r.RenderPDF(&rendered, buildTestInvoice(t, 1))) {
+		return
+	}
+	var buf bytes.Buffer
+	if assert.NoError(pdf.EmbedAttachments(&buf, rendered.Bytes(), []pdf.Attachment{{
+		Name: "date.csv",
+		Data: []byte("a,b\n"),
+	}}, pdf.ArchiveTitle("Arhivă <FCT-1>"))) {
+		s := buf.String()
+		assert.Contains(s, `<rdf:li xml:lang="x-default">Arhivă &lt;FCT-1&gt;</rdf:li>`)
+		assert.Contains(s, "/Subtype /application#2Foctet-stream")
+		assert.Contains(s, "/AFRelationship /Unspecified")
+		checkPDF(t, buf.Bytes())
+	}
+}
+
+func TestEmbedAttachmentsUnsupported(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	err := pdf.EmbedAttachments(&buf, []byte("not a pdf"), nil)
+	assert.True(errors.Is(err, pdf.ErrUnsupportedPDF))
+
+	xrefStream := []byte("%PDF-1.7\n1 0 obj\n<< /Type /XRef /Size 2 /Root 2 0 R >>\nstream\n\nendstream\nendobj\nstartxref\n9\n%%EOF\n")
+	err = pdf.EmbedAttachments(&buf, xrefStream, nil)
+	assert.True(errors.Is(err, pdf.ErrUnsupportedPDF))
+
+	encrypted := []byte("%PDF-1.7\nxref\n0 1\n0000000000 65535 f \ntrailer\n<< /Size 1 /Root 1 0 R /Encrypt 2 0 R >>\nstartxref\n9\n%%EOF\n")
+	err = pdf.EmbedAttachments(&buf, encrypted, nil)
+	assert.True(errors.Is(err, pdf.ErrUnsupportedPDF))
+}
+
+func TestRenderArchivePDF(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := pdf.NewRenderer()
+	if !assert.NoError(err) {
+		return
+	}
+	res := testDownloadResponse(t)
+	var buf bytes.Buffer
+	if assert.NoError(r.RenderArchivePDF(&buf, res)) {
+		content := checkPDF(t, buf.Bytes())
+		assert.Contains(content, "(Nr. FCT-1)")
+		assert.Contains(content, string(res.InvoiceXML))
+	}
+
+	res.Invoice = nil
+	assert.Error(r.RenderArchivePDF(&buf, res))
+}
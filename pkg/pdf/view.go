@@ -0,0 +1,361 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// Language is the language of the rendered documents.
+type Language string
+
+const (
+	// LanguageRO renders the documents in Romanian.
+	LanguageRO Language = "ro"
+	// LanguageEN renders the documents in English.
+	LanguageEN Language = "en"
+)
+
+// Labels are the texts of the rendered documents.
+type Labels struct {
+	Invoice           string
+	CreditNote        string
+	Number            string
+	IssueDate         string
+	DueDate           string
+	Currency          string
+	Supplier          string
+	Customer          string
+	VATID             string
+	RegistrationID    string
+	Email             string
+	Phone             string
+	LineNo            string
+	Item              string
+	Quantity          string
+	Unit              string
+	Price             string
+	VATRate           string
+	Amount            string
+	VATBreakdown      string
+	VATCategory       string
+	TaxableAmount     string
+	TaxAmount         string
+	LinesTotal        string
+	AllowancesTotal   string
+	ChargesTotal      string
+	TaxExclusiveTotal string
+	VATTotal          string
+	TaxInclusiveTotal string
+	PrepaidAmount     string
+	RoundingAmount    string
+	PayableAmount     string
+	Notes             string
+	Payment           string
+	PaymentAccount    string
+	Page              string
+}
+
+var languageLabels = map[Language]Labels{
+	LanguageRO: {
+		Invoice:           "Factură",
+		CreditNote:        "Factură storno",
+		Number:            "Nr.",
+		IssueDate:         "Data emiterii",
+		DueDate:           "Data scadenței",
+		Currency:          "Moneda",
+		Supplier:          "Furnizor",
+		Customer:          "Client",
+		VATID:             "CIF",
+		RegistrationID:    "Nr. Reg. Com.",
+		Email:             "Email",
+		Phone:             "Telefon",
+		LineNo:            "Nr.",
+		Item:              "Denumire",
+		Quantity:          "Cant.",
+		Unit:              "U.M.",
+		Price:             "Preț unitar",
+		VATRate:           "TVA %",
+		Amount:            "Valoare",
+		VATBreakdown:      "Detalierea TVA",
+		VATCategory:       "Categorie",
+		TaxableAmount:     "Bază impozabilă",
+		TaxAmount:         "Valoare TVA",
+		LinesTotal:        "Total linii",
+		AllowancesTotal:   "Total deduceri",
+		ChargesTotal:      "Total taxe suplimentare",
+		TaxExclusiveTotal: "Total fără TVA",
+		VATTotal:          "Total TVA",
+		TaxInclusiveTotal: "Total cu TVA",
+		PrepaidAmount:     "Sumă plătită",
+		RoundingAmount:    "Rotunjire",
+		PayableAmount:     "Total de plată",
+		Notes:             "Observații",
+		Payment:           "Plată",
+		PaymentAccount:    "Cont",
+		Page:              "Pagina",
+	},
+	LanguageEN: {
+		Invoice:           "Invoice",
+		CreditNote:        "Credit note",
+		Number:            "No.",
+		IssueDate:         "Issue date",
+		DueDate:           "Due date",
+		Currency:          "Currency",
+		Supplier:          "Seller",
+		Customer:          "Buyer",
+		VATID:             "VAT ID",
+		RegistrationID:    "Registration ID",
+		Email:             "Email",
+		Phone:             "Phone",
+		LineNo:            "No.",
+		Item:              "Item",
+		Quantity:          "Qty",
+		Unit:              "Unit",
+		Price:             "Unit price",
+		VATRate:           "VAT %",
+		Amount:            "Amount",
+		VATBreakdown:      "VAT breakdown",
+		VATCategory:       "Category",
+		TaxableAmount:     "Taxable amount",
+		TaxAmount:         "VAT amount",
+		LinesTotal:        "Sum of lines",
+		AllowancesTotal:   "Allowances",
+		ChargesTotal:      "Charges",
+		TaxExclusiveTotal: "Total without VAT",
+		VATTotal:          "Total VAT",
+		TaxInclusiveTotal: "Total with VAT",
+		PrepaidAmount:     "Paid amount",
+		RoundingAmount:    "Rounding",
+		PayableAmount:     "Amount due",
+		Notes:             "Notes",
+		Payment:           "Payment",
+		PaymentAccount:    "Account",
+		Page:              "Page",
+	},
+}
+
+// LanguageLabels returns the labels for the given language (Romanian for
+// the unknown languages).
+func LanguageLabels(lang Language) Labels {
+	if labels, ok := languageLabels[lang]; ok {
+		return labels
+	}
+	return languageLabels[LanguageRO]
+}
+
+// PartyView is a party (seller or buyer) of a View.
+type PartyView struct {
+	Name           string
+	VATID          string
+	RegistrationID string
+	Address        []string
+	Email          string
+	Phone          string
+}
+
+// LineView is an invoice line of a View.
+type LineView struct {
+	ID          string
+	Name        string
+	Description string
+	Quantity    string
+	Unit        string
+	Price       string
+	VATRate     string
+	Amount      string
+}
+
+// VATView is a VAT breakdown entry of a View.
+type VATView struct {
+	Category        string
+	Rate            string
+	TaxableAmount   string
+	TaxAmount       string
+	ExemptionReason string
+}
+
+// TotalView is a document total of a View.
+type TotalView struct {
+	Label  string
+	Amount string
+	// Bold is true for the amount due.
+	Bold bool
+}
+
+// View is the data passed to the HTML template, with all the values
+// formatted for display.
+type View struct {
+	Labels    Labels
+	Language  Language
+	Title     string
+	ID        string
+	IssueDate string
+	DueDate   string
+	Currency  string
+	Supplier  PartyView
+	Customer  PartyView
+	Lines     []LineView
+	VAT       []VATView
+	Totals    []TotalView
+	Notes     []string
+	Payment   []string
+	// Logo is the logo as a data URI (empty if no logo is set).
+	Logo template.URL
+}
+
+// NewView returns the View of the invoice for the given language.
+func NewView(iv efactura.Invoice, lang Language) View {
+	labels := LanguageLabels(lang)
+	v := View{
+		Labels:    labels,
+		Language:  lang,
+		Title:     labels.Invoice,
+		ID:        iv.ID,
+		IssueDate: formatDate(iv.IssueDate, lang),
+		DueDate:   formatDate(iv.GetDueDate(), lang),
+		Currency:  string(iv.DocumentCurrencyCode),
+	}
+	if iv.InvoiceTypeCode == efactura.InvoiceTypeCreditNote {
+		v.Title = labels.CreditNote
+	}
+
+	supplier := iv.Supplier.Party
+	v.Supplier = PartyView{
+		Name:           supplier.Name(),
+		VATID:          firstNonEmpty(supplier.VATID(), supplier.TaxRegistrationID()),
+		RegistrationID: supplier.LegalRegistrationID(),
+		Address:        addressLines(supplier.PostalAddress.PostalAddress),
+		Email:          supplier.Email(),
+		Phone:          supplier.Phone(),
+	}
+	customer := iv.Customer.Party
+	v.Customer = PartyView{
+		Name:           customer.Name(),
+		VATID:          customer.VATID(),
+		RegistrationID: customer.LegalRegistrationID(),
+		Address:        addressLines(customer.PostalAddress.PostalAddress),
+		Email:          customer.Email(),
+		Phone:          customer.Phone(),
+	}
+
+	for _, line := range iv.InvoiceLines {
+		v.Lines = append(v.Lines, LineView{
+			ID:          line.ID,
+			Name:        line.Item.Name,
+			Description: line.Item.Description,
+			Quantity:    line.InvoicedQuantity.Quantity.String(),
+			Unit:        string(line.InvoicedQuantity.UnitCode),
+			Price:       formatAmount(line.Price.PriceAmount.Amount),
+			VATRate:     line.VATRate().String(),
+			Amount:      formatAmount(line.LineExtensionAmount.Amount),
+		})
+	}
+
+	var vatTotal *efactura.AmountWithCurrency
+	for _, tt := range iv.TaxTotal {
+		if tt.TaxAmount != nil && tt.TaxAmount.CurrencyID == iv.DocumentCurrencyCode {
+			vatTotal = tt.TaxAmount
+		}
+		if len(tt.TaxSubtotals) == 0 || tt.TaxSubtotals[0].TaxAmount.CurrencyID != iv.DocumentCurrencyCode {
+			continue
+		}
+		for _, st := range tt.TaxSubtotals {
+			v.VAT = append(v.VAT, VATView{
+				Category:        string(st.TaxCategory.ID),
+				Rate:            st.TaxCategory.Percent.String(),
+				TaxableAmount:   formatAmount(st.TaxableAmount.Amount),
+				TaxAmount:       formatAmount(st.TaxAmount.Amount),
+				ExemptionReason: st.TaxCategory.TaxExemptionReason,
+			})
+		}
+	}
+
+	total := func(label string, amount *efactura.AmountWithCurrency) {
+		if amount != nil {
+			v.Totals = append(v.Totals, TotalView{Label: label, Amount: formatAmount(amount.Amount)})
+		}
+	}
+	lmt := iv.LegalMonetaryTotal
+	total(labels.LinesTotal, &lmt.LineExtensionAmount)
+	total(labels.AllowancesTotal, lmt.AllowanceTotalAmount)
+	total(labels.ChargesTotal, lmt.ChargeTotalAmount)
+	total(labels.TaxExclusiveTotal, &lmt.TaxExclusiveAmount)
+	total(labels.VATTotal, vatTotal)
+	total(labels.TaxInclusiveTotal, &lmt.TaxInclusiveAmount)
+	total(labels.PrepaidAmount, lmt.PrepaidAmount)
+	total(labels.RoundingAmount, lmt.PayableRoundingAmount)
+	v.Totals = append(v.Totals, TotalView{
+		Label:  labels.PayableAmount,
+		Amount: formatAmount(lmt.PayableAmount.Amount),
+		Bold:   true,
+	})
+
+	for _, note := range iv.Note {
+		v.Notes = append(v.Notes, note.Note)
+	}
+	for _, id := range iv.PaymentAccountIDs() {
+		v.Payment = append(v.Payment, labels.PaymentAccount+": "+id)
+	}
+	if note := iv.PaymentTermsNote(); note != "" {
+		v.Payment = append(v.Payment, note)
+	}
+	return v
+}
+
+func formatAmount(d types.Decimal) string {
+	return efactura.FormatAmount(d)
+}
+
+func formatDate(d types.Date, lang Language) string {
+	if !d.IsInitialized() {
+		return ""
+	}
+	if lang == LanguageEN {
+		return d.Format("2006-01-02")
+	}
+	return d.Format("02.01.2006")
+}
+
+func addressLines(a efactura.PostalAddress) (lines []string) {
+	for _, line := range []string{a.Line1, a.Line2, a.Line3} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	var city []string
+	for _, s := range []string{a.PostalZone, a.CityName, string(a.CountrySubentity), string(a.Country.Code)} {
+		if s != "" {
+			city = append(city, s)
+		}
+	}
+	if len(city) > 0 {
+		lines = append(lines, strings.Join(city, ", "))
+	}
+	return
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
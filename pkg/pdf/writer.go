@@ -0,0 +1,172 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// writer is a minimal PDF writer: the objects are kept in memory and
+// written with the cross-reference table at the end.
+type writer struct {
+	// base is the number of objects of the original file, for incremental
+	// updates. The objects of the writer are numbered from base+1.
+	base    int
+	objects [][]byte
+}
+
+// reserve reserves an object number, for objects referenced before being
+// written (eg. the pages tree).
+func (w *writer) reserve() int {
+	w.objects = append(w.objects, nil)
+	return w.base + len(w.objects)
+}
+
+// set sets the body of the reserved object id.
+func (w *writer) set(id int, body string) {
+	w.objects[id-w.base-1] = []byte(body)
+}
+
+// add adds an object and returns its number.
+func (w *writer) add(body string) int {
+	id := w.reserve()
+	w.set(id, body)
+	return id
+}
+
+// addStream adds a stream object compressed with Flate. dict are the
+// entries of the stream dictionary, besides Filter and Length.
+func (w *writer) addStream(dict string, data []byte) (int, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	id := w.reserve()
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< %s /Filter /FlateDecode /Length %d >>\nstream\n", dict, buf.Len())
+	obj.Write(buf.Bytes())
+	obj.WriteString("\nendstream")
+	w.objects[id-w.base-1] = obj.Bytes()
+	return id, nil
+}
+
+// writeTo writes the PDF file with the given catalog and info objects.
+func (w *writer) writeTo(out io.Writer, catalog, info int) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, body := range w.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R", len(w.objects)+1, catalog)
+	if info > 0 {
+		fmt.Fprintf(&buf, " /Info %d 0 R", info)
+	}
+	fmt.Fprintf(&buf, " >>\nstartxref\n%d\n%%%%EOF\n", xref)
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// writeUpdateTo writes the original file followed by an incremental update
+// with the objects of the writer and the replaced objects of the original
+// file. trailer are the entries of the trailer dictionary, besides Size.
+func (w *writer) writeUpdateTo(out io.Writer, original []byte, replaced map[int][]byte, trailer string) error {
+	var buf bytes.Buffer
+	buf.Write(original)
+	if len(original) > 0 && original[len(original)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	offsets := make(map[int]int, len(replaced)+len(w.objects))
+	ids := make([]int, 0, len(replaced)+len(w.objects))
+	writeObject := func(id int, body []byte) {
+		offsets[id] = buf.Len()
+		ids = append(ids, id)
+		fmt.Fprintf(&buf, "%d 0 obj\n", id)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+	replacedIDs := make([]int, 0, len(replaced))
+	for id := range replaced {
+		replacedIDs = append(replacedIDs, id)
+	}
+	sort.Ints(replacedIDs)
+	for _, id := range replacedIDs {
+		writeObject(id, replaced[id])
+	}
+	for i, body := range w.objects {
+		writeObject(w.base+i+1, body)
+	}
+	sort.Ints(ids)
+
+	// The cross-reference section has a subsection for each run of
+	// consecutive object numbers.
+	xref := buf.Len()
+	buf.WriteString("xref\n")
+	for start := 0; start < len(ids); {
+		end := start + 1
+		for end < len(ids) && ids[end] == ids[end-1]+1 {
+			end++
+		}
+		fmt.Fprintf(&buf, "%d %d\n", ids[start], end-start)
+		for _, id := range ids[start:end] {
+			fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[id])
+		}
+		start = end
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n",
+		w.base+len(w.objects)+1, trailer, xref)
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// pdfString returns s as a PDF literal string, encoded with WinAnsi.
+func pdfString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('(')
+	for _, c := range encodeWinAnsi(s) {
+		switch c {
+		case '(', ')', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+// pdfNumber formats a coordinate or a size.
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,440 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package scheduler runs the registered bulk jobs (eg. sync, download,
+// reconcile) only inside the configured time-of-day windows (eg. during the
+// night), spreads their API calls over the window to respect the quotas and
+// pauses the calls while a circuit breaker is open or during the announced
+// maintenance windows.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/maintenance"
+)
+
+const (
+	// DefaultPauseInterval is the default interval between two checks of
+	// the circuit breaker while it's open.
+	DefaultPauseInterval = 30 * time.Second
+)
+
+var (
+	// ErrWindowClosed is returned by Throttle.Wait when the current window
+	// is closed. Jobs should stop and resume in the next window.
+	ErrWindowClosed = errors.New("scheduler: the time window is closed")
+	// ErrBudgetExhausted is returned by Throttle.Wait when the calls budget
+	// of the current window was used.
+	ErrBudgetExhausted = errors.New("scheduler: the calls budget of the window is exhausted")
+)
+
+// Breaker reports if the calls must be paused, eg. a circuit breaker in the
+// open state.
+type Breaker interface {
+	Open() bool
+}
+
+// BreakerFunc is an adapter to allow the use of an ordinary function as a
+// Breaker.
+type BreakerFunc func() bool
+
+// Open calls f().
+func (f BreakerFunc) Open() bool {
+	return f()
+}
+
+// JobFunc is the function of a job. The job must call th.Wait before each API
+// call and stop when Wait returns an error. Since a job may be stopped
+// before it's done, it should be resumable (eg. using the sync cursors of
+// efactura.Client.SyncMessages).
+type JobFunc func(ctx context.Context, th *Throttle) error
+
+// Job is a job registered with a Scheduler.
+type Job struct {
+	// Name is the name of the job, used in the errors.
+	Name string
+	// Run is the function of the job.
+	Run JobFunc
+	// Interval is the minimum interval between two runs of the job inside
+	// a window. If zero, the job runs once per window occurrence.
+	Interval time.Duration
+}
+
+// Config is the config used to create a Scheduler.
+type Config struct {
+	// Windows are the time-of-day windows when the jobs run.
+	Windows []Window
+	// CallsPerWindow is the budget of API calls for an occurrence of a
+	// window, shared by all the jobs. The calls are spread evenly over the
+	// window. If zero, the calls are not limited.
+	CallsPerWindow int
+	// MinCallInterval is the minimum interval between two API calls.
+	MinCallInterval time.Duration
+	// Breaker pauses the calls while it's open (optional).
+	Breaker Breaker
+	// PauseInterval is the interval between two checks of the Breaker
+	// while it's open (default DefaultPauseInterval).
+	PauseInterval time.Duration
+	// Maintenance pauses the calls during the maintenance windows
+	// affecting MaintenanceService (optional).
+	Maintenance        *maintenance.Schedule
+	MaintenanceService maintenance.Service
+	// ErrorHandler is called by Run with the errors of the jobs, except
+	// ErrWindowClosed and ErrBudgetExhausted (optional).
+	ErrorHandler func(job string, err error)
+	// Now returns the current time (default time.Now).
+	Now func() time.Time
+	// Sleep waits for the given duration or until ctx is done (default
+	// a timer).
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// ConfigOption allows gradually modifying a Config.
+type ConfigOption func(*Config)
+
+// ConfigWindows adds the time-of-day windows when the jobs run.
+func ConfigWindows(windows ...Window) ConfigOption {
+	return func(c *Config) {
+		c.Windows = append(c.Windows, windows...)
+	}
+}
+
+// ConfigCallsPerWindow sets the budget of API calls for an occurrence of a
+// window.
+func ConfigCallsPerWindow(calls int) ConfigOption {
+	return func(c *Config) {
+		c.CallsPerWindow = calls
+	}
+}
+
+// ConfigMinCallInterval sets the minimum interval between two API calls.
+func ConfigMinCallInterval(interval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MinCallInterval = interval
+	}
+}
+
+// ConfigBreaker sets the Breaker that pauses the calls while it's open.
+func ConfigBreaker(breaker Breaker) ConfigOption {
+	return func(c *Config) {
+		c.Breaker = breaker
+	}
+}
+
+// ConfigPauseInterval sets the interval between two checks of the Breaker
+// while it's open.
+func ConfigPauseInterval(interval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.PauseInterval = interval
+	}
+}
+
+// ConfigMaintenance pauses the calls during the maintenance windows of the
+// schedule affecting service.
+func ConfigMaintenance(schedule *maintenance.Schedule, service maintenance.Service) ConfigOption {
+	return func(c *Config) {
+		c.Maintenance = schedule
+		c.MaintenanceService = service
+	}
+}
+
+// ConfigErrorHandler sets the function called by Run with the errors of the
+// jobs.
+func ConfigErrorHandler(f func(job string, err error)) ConfigOption {
+	return func(c *Config) {
+		c.ErrorHandler = f
+	}
+}
+
+// ConfigClock sets the functions used for getting the current time and for
+// waiting, eg. for tests.
+func ConfigClock(now func() time.Time, sleep func(ctx context.Context, d time.Duration) error) ConfigOption {
+	return func(c *Config) {
+		c.Now = now
+		c.Sleep = sleep
+	}
+}
+
+// Scheduler runs the registered jobs inside the configured windows. The jobs
+// run sequentially, in the order they were registered.
+type Scheduler struct {
+	cfg      Config
+	throttle *Throttle
+
+	mu      sync.Mutex
+	jobs    []Job
+	lastRun map[string]time.Time
+}
+
+// NewScheduler creates a new Scheduler.
+func NewScheduler(opts ...ConfigOption) (*Scheduler, error) {
+	cfg := Config{
+		PauseInterval: DefaultPauseInterval,
+		Now:           time.Now,
+		Sleep:         sleep,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(cfg.Windows) == 0 {
+		return nil, errors.New("scheduler: no windows")
+	}
+	if cfg.CallsPerWindow < 0 {
+		return nil, fmt.Errorf("scheduler: invalid calls per window %d", cfg.CallsPerWindow)
+	}
+	if cfg.PauseInterval <= 0 {
+		return nil, fmt.Errorf("scheduler: invalid pause interval %v", cfg.PauseInterval)
+	}
+	s := &Scheduler{
+		cfg:     cfg,
+		lastRun: make(map[string]time.Time),
+	}
+	s.throttle = &Throttle{s: s}
+	return s, nil
+}
+
+// Register registers a job. The names of the jobs must be unique.
+func (s *Scheduler) Register(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.Run == nil {
+		return fmt.Errorf("scheduler: job %q has no function", job.Name)
+	}
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return fmt.Errorf("scheduler: job %q already registered", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// RegisterFunc registers a job that runs once per window occurrence.
+func (s *Scheduler) RegisterFunc(name string, f JobFunc) error {
+	return s.Register(Job{Name: name, Run: f})
+}
+
+// Throttle returns the Throttle shared by the jobs, eg. for making calls
+// outside of the jobs that count towards the same budget.
+func (s *Scheduler) Throttle() *Throttle {
+	return s.throttle
+}
+
+// window returns the bounds of the window occurrence containing t.
+func (s *Scheduler) window(t time.Time) (start, end time.Time, ok bool) {
+	for _, w := range s.cfg.Windows {
+		if wStart, wEnd, wOk := w.Bounds(t); wOk && (!ok || wEnd.After(end)) {
+			start, end, ok = wStart, wEnd, true
+		}
+	}
+	return
+}
+
+// nextStart returns the first start of a window after t.
+func (s *Scheduler) nextStart(t time.Time) (next time.Time) {
+	for _, w := range s.cfg.Windows {
+		if start := w.NextStart(t); next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return
+}
+
+// due returns true if the job is due at now, in the window occurrence
+// starting at windowStart. Called with s.mu locked.
+func (s *Scheduler) due(job Job, windowStart, now time.Time) bool {
+	last, ok := s.lastRun[job.Name]
+	if !ok || last.Before(windowStart) {
+		return true
+	}
+	return job.Interval > 0 && !now.Before(last.Add(job.Interval))
+}
+
+// RunDue runs the jobs that are due now, if the current time is inside a
+// window. The jobs run even if a previous job failed, and the errors are
+// joined. ErrWindowClosed and ErrBudgetExhausted returned by a job are not
+// reported, but stop running the remaining jobs.
+func (s *Scheduler) RunDue(ctx context.Context) error {
+	var errs []error
+	s.runDue(ctx, func(job string, err error) {
+		errs = append(errs, fmt.Errorf("job %s: %w", job, err))
+	})
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Scheduler) runDue(ctx context.Context, report func(job string, err error)) {
+	for ctx.Err() == nil {
+		now := s.cfg.Now()
+		windowStart, _, ok := s.window(now)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		var job Job
+		var found bool
+		for _, j := range s.jobs {
+			if s.due(j, windowStart, now) {
+				job, found = j, true
+				s.lastRun[j.Name] = now
+				break
+			}
+		}
+		s.mu.Unlock()
+		if !found {
+			return
+		}
+
+		err := job.Run(ctx, s.throttle)
+		if errors.Is(err, ErrWindowClosed) || errors.Is(err, ErrBudgetExhausted) {
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			report(job.Name, err)
+		}
+	}
+}
+
+// nextWakeUp returns when the jobs may be due after now.
+func (s *Scheduler) nextWakeUp(now time.Time) time.Time {
+	_, end, ok := s.window(now)
+	if !ok {
+		return s.nextStart(now)
+	}
+	next := end
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.Interval > 0 {
+			if due := s.lastRun[job.Name].Add(job.Interval); due.Before(next) {
+				next = due
+			}
+		}
+	}
+	if !next.After(now) {
+		next = now.Add(time.Second)
+	}
+	return next
+}
+
+// Run runs the due jobs inside the windows until ctx is done, and returns the
+// context error. The errors of the jobs are reported to the ErrorHandler (see
+// ConfigErrorHandler) and don't stop the Scheduler.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		s.runDue(ctx, func(job string, err error) {
+			if s.cfg.ErrorHandler != nil {
+				s.cfg.ErrorHandler(job, err)
+			}
+		})
+		now := s.cfg.Now()
+		if err := s.cfg.Sleep(ctx, s.nextWakeUp(now).Sub(now)); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Throttle spreads the API calls of the jobs over the current window. It's
+// safe for concurrent use.
+type Throttle struct {
+	s *Scheduler
+
+	mu          sync.Mutex
+	windowStart time.Time
+	calls       int
+	next        time.Time
+}
+
+// Calls returns the number of calls made in the current window occurrence.
+func (th *Throttle) Calls() int {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if start, _, ok := th.s.window(th.s.cfg.Now()); !ok || !start.Equal(th.windowStart) {
+		return 0
+	}
+	return th.calls
+}
+
+// Wait waits until the next API call is allowed: inside a window, outside the
+// maintenance windows, with the breaker closed and at least the call
+// interval after the previous call. ErrWindowClosed is returned if the
+// window is closed (or closes before the call is allowed),
+// ErrBudgetExhausted if the calls budget of the window was used, and the
+// context error if ctx is done.
+func (th *Throttle) Wait(ctx context.Context) error {
+	cfg := th.s.cfg
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		now := cfg.Now()
+		start, end, ok := th.s.window(now)
+		if !ok {
+			return ErrWindowClosed
+		}
+
+		var wait time.Duration
+		switch {
+		case cfg.Maintenance != nil && cfg.Maintenance.AvailableAt(cfg.MaintenanceService, now).After(now):
+			wait = cfg.Maintenance.AvailableAt(cfg.MaintenanceService, now).Sub(now)
+		case cfg.Breaker != nil && cfg.Breaker.Open():
+			wait = cfg.PauseInterval
+		default:
+			th.mu.Lock()
+			if !start.Equal(th.windowStart) {
+				th.windowStart, th.calls, th.next = start, 0, time.Time{}
+			}
+			if cfg.CallsPerWindow > 0 && th.calls >= cfg.CallsPerWindow {
+				th.mu.Unlock()
+				return ErrBudgetExhausted
+			}
+			if !now.Before(th.next) {
+				th.calls++
+				interval := cfg.MinCallInterval
+				if cfg.CallsPerWindow > 0 {
+					interval = max(interval, end.Sub(start)/time.Duration(cfg.CallsPerWindow))
+				}
+				th.next = now.Add(interval)
+				th.mu.Unlock()
+				return nil
+			}
+			wait = th.next.Sub(now)
+			th.mu.Unlock()
+		}
+		if !now.Add(wait).Before(end) {
+			return ErrWindowClosed
+		}
+		if err := cfg.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
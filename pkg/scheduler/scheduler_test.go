@@ -0,0 +1,274 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/maintenance"
+	"github.com/printesoi/e-factura-go/pkg/scheduler"
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+)
+
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	slept  []time.Duration
+	cancel context.CancelFunc
+	until  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.slept = append(c.slept, d)
+	stop := !c.until.IsZero() && !c.now.Before(c.until)
+	c.mu.Unlock()
+	if stop && c.cancel != nil {
+		c.cancel()
+	}
+	return ctx.Err()
+}
+
+func TestParseWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	w, err := scheduler.ParseWindow("01:00-05:30")
+	if assert.NoError(err) {
+		assert.Equal(scheduler.MakeWindow(1, 0, 5, 30), w)
+		assert.Equal("01:00-05:30", w.String())
+	}
+	w, err = scheduler.ParseWindow("22:00 - 24:00")
+	if assert.NoError(err) {
+		assert.Equal(24*time.Hour, w.End)
+	}
+	for _, s := range []string{"", "01:00", "1:00-05:00", "25:00-05:00", "01:60-05:00", "24:30-01:00", "aa:bb-cc:dd"} {
+		_, err := scheduler.ParseWindow(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	night := scheduler.MakeWindow(1, 0, 5, 0)
+	assert.True(night.Contains(itime.Date(2024, 3, 1, 1, 0, 0, 0)))
+	assert.True(night.Contains(itime.Date(2024, 3, 1, 4, 59, 0, 0)))
+	assert.False(night.Contains(itime.Date(2024, 3, 1, 5, 0, 0, 0)))
+	assert.False(night.Contains(itime.Date(2024, 3, 1, 0, 59, 0, 0)))
+	assert.Equal(itime.Date(2024, 3, 1, 1, 0, 0, 0), night.NextStart(itime.Date(2024, 3, 1, 0, 0, 0, 0)))
+	assert.Equal(itime.Date(2024, 3, 2, 1, 0, 0, 0), night.NextStart(itime.Date(2024, 3, 1, 1, 0, 0, 0)))
+
+	// The window wraps past midnight.
+	late := scheduler.MakeWindow(22, 0, 2, 0)
+	start, end, ok := late.Bounds(itime.Date(2024, 3, 2, 1, 0, 0, 0))
+	if assert.True(ok) {
+		assert.Equal(itime.Date(2024, 3, 1, 22, 0, 0, 0), start)
+		assert.Equal(itime.Date(2024, 3, 2, 2, 0, 0, 0), end)
+	}
+	assert.True(late.Contains(itime.Date(2024, 3, 1, 23, 0, 0, 0)))
+	assert.False(late.Contains(itime.Date(2024, 3, 1, 21, 0, 0, 0)))
+
+	// The times are in the Romanian timezone.
+	assert.True(night.Contains(time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)))
+	// The window has the same local times on the DST change.
+	_, end, ok = night.Bounds(itime.Date(2024, 3, 31, 4, 30, 0, 0))
+	if assert.True(ok) {
+		assert.Equal(itime.Date(2024, 3, 31, 5, 0, 0, 0), end)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &fakeClock{now: itime.Date(2024, 3, 1, 1, 0, 0, 0)}
+	s, err := scheduler.NewScheduler(
+		scheduler.ConfigWindows(scheduler.MakeWindow(1, 0, 5, 0)),
+		scheduler.ConfigCallsPerWindow(4),
+		scheduler.ConfigClock(clock.Now, clock.Sleep),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	th := s.Throttle()
+	ctx := context.Background()
+	// The calls are spread evenly over the window.
+	for hour := 1; hour <= 4; hour++ {
+		if assert.NoError(th.Wait(ctx)) {
+			assert.Equal(itime.Date(2024, 3, 1, hour, 0, 0, 0), clock.Now())
+		}
+	}
+	assert.Equal(4, th.Calls())
+	assert.ErrorIs(th.Wait(ctx), scheduler.ErrBudgetExhausted)
+
+	// The budget is reset in the next window occurrence.
+	clock.now = itime.Date(2024, 3, 1, 12, 0, 0, 0)
+	assert.ErrorIs(th.Wait(ctx), scheduler.ErrWindowClosed)
+	clock.now = itime.Date(2024, 3, 2, 1, 0, 0, 0)
+	assert.Equal(0, th.Calls())
+	assert.NoError(th.Wait(ctx))
+	assert.Equal(1, th.Calls())
+}
+
+func TestThrottleWindowCloses(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &fakeClock{now: itime.Date(2024, 3, 1, 1, 0, 0, 0)}
+	s, err := scheduler.NewScheduler(
+		scheduler.ConfigWindows(scheduler.MakeWindow(1, 0, 5, 0)),
+		scheduler.ConfigMinCallInterval(3*time.Hour),
+		scheduler.ConfigClock(clock.Now, clock.Sleep),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	th := s.Throttle()
+	ctx := context.Background()
+	assert.NoError(th.Wait(ctx))
+	assert.NoError(th.Wait(ctx))
+	assert.Equal(itime.Date(2024, 3, 1, 4, 0, 0, 0), clock.Now())
+	// The next call would be after the end of the window.
+	assert.ErrorIs(th.Wait(ctx), scheduler.ErrWindowClosed)
+	assert.Equal(itime.Date(2024, 3, 1, 4, 0, 0, 0), clock.Now())
+}
+
+func TestThrottlePauses(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &fakeClock{now: itime.Date(2024, 3, 1, 1, 0, 0, 0)}
+	schedule := &maintenance.Schedule{}
+	schedule.Add(maintenance.Window{
+		Start:    itime.Date(2024, 3, 1, 0, 30, 0, 0),
+		End:      itime.Date(2024, 3, 1, 2, 0, 0, 0),
+		Services: []maintenance.Service{maintenance.ServiceEFactura},
+	})
+	breakerChecks := 0
+	s, err := scheduler.NewScheduler(
+		scheduler.ConfigWindows(scheduler.MakeWindow(1, 0, 5, 0)),
+		scheduler.ConfigMaintenance(schedule, maintenance.ServiceEFactura),
+		scheduler.ConfigBreaker(scheduler.BreakerFunc(func() bool {
+			breakerChecks++
+			return breakerChecks <= 2
+		})),
+		scheduler.ConfigPauseInterval(time.Minute),
+		scheduler.ConfigClock(clock.Now, clock.Sleep),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.NoError(s.Throttle().Wait(context.Background())) {
+		// Paused until the end of the maintenance, then while the breaker
+		// was open.
+		assert.Equal([]time.Duration{time.Hour, time.Minute, time.Minute}, clock.slept)
+		assert.Equal(itime.Date(2024, 3, 1, 2, 2, 0, 0), clock.Now())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(s.Throttle().Wait(ctx), context.Canceled)
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &fakeClock{now: itime.Date(2024, 3, 1, 0, 0, 0, 0)}
+	var errorsReported []string
+	s, err := scheduler.NewScheduler(
+		scheduler.ConfigWindows(scheduler.MakeWindow(1, 0, 5, 0)),
+		scheduler.ConfigCallsPerWindow(2),
+		scheduler.ConfigClock(clock.Now, clock.Sleep),
+		scheduler.ConfigErrorHandler(func(job string, err error) {
+			errorsReported = append(errorsReported, job)
+		}),
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	var runs []string
+	assert.NoError(s.RegisterFunc("sync", func(ctx context.Context, th *scheduler.Throttle) error {
+		runs = append(runs, "sync")
+		return th.Wait(ctx)
+	}))
+	assert.NoError(s.Register(scheduler.Job{
+		Name: "reconcile",
+		Run: func(ctx context.Context, th *scheduler.Throttle) error {
+			runs = append(runs, "reconcile")
+			return errors.New("failed")
+		},
+		Interval: time.Hour,
+	}))
+	assert.Error(s.RegisterFunc("sync", func(ctx context.Context, th *scheduler.Throttle) error { return nil }))
+	assert.Error(s.Register(scheduler.Job{Name: "nil"}))
+
+	ctx := context.Background()
+	// Outside the window no job runs.
+	assert.NoError(s.RunDue(ctx))
+	assert.Empty(runs)
+
+	clock.now = itime.Date(2024, 3, 1, 1, 0, 0, 0)
+	err = s.RunDue(ctx)
+	assert.ErrorContains(err, "job reconcile: failed")
+	assert.Equal([]string{"sync", "reconcile"}, runs)
+
+	// The jobs are not due again in the same window, except the ones with
+	// an interval.
+	clock.now = itime.Date(2024, 3, 1, 1, 30, 0, 0)
+	assert.NoError(s.RunDue(ctx))
+	assert.Equal([]string{"sync", "reconcile"}, runs)
+	clock.now = itime.Date(2024, 3, 1, 2, 0, 0, 0)
+	assert.Error(s.RunDue(ctx))
+	assert.Equal([]string{"sync", "reconcile", "reconcile"}, runs)
+	assert.Empty(errorsReported)
+
+	// Run runs the jobs in each window until the context is done.
+	runs = nil
+	clock.now = itime.Date(2024, 3, 1, 12, 0, 0, 0)
+	ctx, cancel := context.WithCancel(ctx)
+	clock.cancel, clock.until = cancel, itime.Date(2024, 3, 3, 12, 0, 0, 0)
+	assert.ErrorIs(s.Run(ctx), context.Canceled)
+	// Each night sync runs once and reconcile runs every hour.
+	assert.Equal([]string{
+		"sync", "reconcile", "reconcile", "reconcile", "reconcile",
+		"sync", "reconcile", "reconcile", "reconcile", "reconcile",
+	}, runs)
+	assert.Len(errorsReported, 8)
+}
+
+func TestNewSchedulerInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := scheduler.NewScheduler()
+	assert.Error(err)
+	_, err = scheduler.NewScheduler(
+		scheduler.ConfigWindows(scheduler.MakeWindow(1, 0, 5, 0)),
+		scheduler.ConfigCallsPerWindow(-1))
+	assert.Error(err)
+	_, err = scheduler.NewScheduler(
+		scheduler.ConfigWindows(scheduler.MakeWindow(1, 0, 5, 0)),
+		scheduler.ConfigPauseInterval(0))
+	assert.Error(err)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+)
+
+// Window is a daily time-of-day window in the Romanian timezone. Start and
+// End are the offsets from midnight (with minute precision), and the window
+// wraps past midnight if End is not after Start (eg. 22:00-02:00, while
+// 00:00-00:00 is the whole day).
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// MakeWindow creates a Window from the start and end hours and minutes.
+func MakeWindow(startHour, startMinute, endHour, endMinute int) Window {
+	return Window{
+		Start: time.Duration(startHour)*time.Hour + time.Duration(startMinute)*time.Minute,
+		End:   time.Duration(endHour)*time.Hour + time.Duration(endMinute)*time.Minute,
+	}
+}
+
+// ParseWindow parses a window in the "HH:MM-HH:MM" format, eg. "01:00-05:00".
+func ParseWindow(s string) (w Window, err error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return w, fmt.Errorf("scheduler: invalid window %q", s)
+	}
+	if w.Start, err = parseTimeOfDay(strings.TrimSpace(start)); err != nil {
+		return w, fmt.Errorf("scheduler: invalid window %q: %w", s, err)
+	}
+	if w.End, err = parseTimeOfDay(strings.TrimSpace(end)); err != nil {
+		return w, fmt.Errorf("scheduler: invalid window %q: %w", s, err)
+	}
+	return w, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	// 24:00 is accepted as the end of the day.
+	if hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// String returns the window in the "HH:MM-HH:MM" format.
+func (w Window) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	}
+	return format(w.Start) + "-" + format(w.End)
+}
+
+// occurrence returns the occurrence of the window starting in the day of t.
+func (w Window) occurrence(t time.Time) (start, end time.Time) {
+	year, month, day := itime.TimeInRomania(t).Date()
+	startMinutes, endMinutes := int(w.Start/time.Minute), int(w.End/time.Minute)
+	if endMinutes <= startMinutes {
+		endMinutes += 24 * 60
+	}
+	return itime.Date(year, month, day, 0, startMinutes, 0, 0),
+		itime.Date(year, month, day, 0, endMinutes, 0, 0)
+}
+
+// Bounds returns the start and the end of the occurrence of the window that
+// contains t. If t is outside the window, ok is false.
+func (w Window) Bounds(t time.Time) (start, end time.Time, ok bool) {
+	for _, day := range []time.Time{t.AddDate(0, 0, -1), t} {
+		start, end = w.occurrence(day)
+		if !t.Before(start) && t.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// Contains returns true if t is inside the window.
+func (w Window) Contains(t time.Time) bool {
+	_, _, ok := w.Bounds(t)
+	return ok
+}
+
+// NextStart returns the first start of the window after t.
+func (w Window) NextStart(t time.Time) time.Time {
+	for _, day := range []time.Time{t, t.AddDate(0, 0, 1), t.AddDate(0, 0, 2)} {
+		if start, _ := w.occurrence(day); start.After(t) {
+			return start
+		}
+	}
+	// Not reached, the window starts every day.
+	return t
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package siruta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/etransport"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// The columns of the SIRUTA CSV export used by ParseCSV.
+const (
+	columnCode       = "SIRUTA"
+	columnName       = "DENLOC"
+	columnPostalCode = "CODP"
+	columnCounty     = "JUD"
+	columnParentCode = "SIRSUP"
+	columnType       = "TIP"
+	columnLevel      = "NIV"
+)
+
+// ParseCSV parses the CSV export of the SIRUTA registry published by INS.
+// The first row must be the header; the columns are matched by name (SIRUTA,
+// DENLOC, CODP, JUD, SIRSUP, TIP, NIV, case insensitive) and the other
+// columns are ignored. Both ';' and ',' separated files are accepted. The
+// data must be UTF-8 encoded (the files published in other encodings must be
+// converted first).
+func ParseCSV(r io.Reader) (*Catalog, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if bytes.HasPrefix(header, []byte("\xef\xbb\xbf")) {
+		_, _ = br.Discard(3)
+		header = header[3:]
+	}
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+
+	cr := csv.NewReader(br)
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	row, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("siruta: error reading header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range row {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{columnCode, columnName, columnCounty, columnLevel} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("siruta: missing column %s", name)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	optionalInt := func(row []string, name string) (int, error) {
+		v := field(row, name)
+		if v == "" {
+			return 0, nil
+		}
+		return strconv.Atoi(v)
+	}
+
+	var localities []Locality
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("siruta: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+
+		var l Locality
+		if l.Code, err = strconv.Atoi(field(row, columnCode)); err != nil {
+			return nil, fmt.Errorf("siruta: line %d: invalid SIRUTA code: %w", line, err)
+		}
+		if l.Name = field(row, columnName); l.Name == "" {
+			return nil, fmt.Errorf("siruta: line %d: missing name", line)
+		}
+		if !utf8.ValidString(l.Name) {
+			return nil, fmt.Errorf("siruta: line %d: the name is not UTF-8 encoded", line)
+		}
+		county, err := strconv.Atoi(field(row, columnCounty))
+		if err != nil {
+			return nil, fmt.Errorf("siruta: line %d: invalid county: %w", line, err)
+		}
+		l.CountyCode = etransport.CountyCodeType(strconv.Itoa(county))
+		level, err := strconv.Atoi(field(row, columnLevel))
+		if err != nil {
+			return nil, fmt.Errorf("siruta: line %d: invalid level: %w", line, err)
+		}
+		l.Level = Level(level)
+		if l.ParentCode, err = optionalInt(row, columnParentCode); err != nil {
+			return nil, fmt.Errorf("siruta: line %d: invalid parent code: %w", line, err)
+		}
+		if l.Type, err = optionalInt(row, columnType); err != nil {
+			return nil, fmt.Errorf("siruta: line %d: invalid type: %w", line, err)
+		}
+		if postalCode := field(row, columnPostalCode); postalCode != "0" {
+			l.PostalCode = postalCode
+		}
+		localities = append(localities, l)
+	}
+	return NewCatalog(localities...), nil
+}
+
+// LoadFile parses the SIRUTA CSV file at the given path (see ParseCSV). The
+// file is registered as the version of efactura.DataComponentSIRUTA (see
+// efactura.RegisterDataVersion), with the file name as the version and the
+// modification time of the file as the update date, so that stale registry
+// files are reported by efactura.CheckDataFreshness.
+func LoadFile(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := ParseCSV(f)
+	if err != nil {
+		return nil, err
+	}
+	efactura.RegisterDataVersion(efactura.DataVersion{
+		Component: efactura.DataComponentSIRUTA,
+		Version:   filepath.Base(path),
+		UpdatedAt: types.MakeDateFromTime(fi.ModTime()),
+	})
+	return catalog, nil
+}
+
+// LazyCatalog loads a Catalog on first use, so that the registry is parsed
+// only by the processes that need it.
+type LazyCatalog struct {
+	load    func() (*Catalog, error)
+	once    sync.Once
+	catalog *Catalog
+	err     error
+}
+
+// NewLazyCatalog creates a LazyCatalog that calls load on first use.
+func NewLazyCatalog(load func() (*Catalog, error)) *LazyCatalog {
+	return &LazyCatalog{load: load}
+}
+
+// NewLazyFileCatalog creates a LazyCatalog that loads the SIRUTA CSV file at
+// the given path on first use (see LoadFile).
+func NewLazyFileCatalog(path string) *LazyCatalog {
+	return NewLazyCatalog(func() (*Catalog, error) {
+		return LoadFile(path)
+	})
+}
+
+// Catalog returns the loaded Catalog. The catalog is loaded only once, and
+// the same error is returned by all calls if loading failed.
+func (l *LazyCatalog) Catalog() (*Catalog, error) {
+	l.once.Do(func() {
+		l.catalog, l.err = l.load()
+	})
+	return l.catalog, l.err
+}
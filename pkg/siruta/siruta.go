@@ -0,0 +1,257 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package siruta provides a catalog of the Romanian localities from the
+// SIRUTA registry (Sistemul Informatic al Registrului Unităţilor
+// Teritorial-Administrative) published by INS, with lookups by name and
+// county and by SIRUTA code. It can be used to validate the CityName of the
+// e-factura addresses and to get the official locality names required by the
+// e-Transport declarations.
+//
+// The registry has more than 16000 entries and is updated by INS a few times
+// a year, so it's not embedded in the library: the catalog is loaded from the
+// CSV export of the registry (see ParseCSV and NewLazyFileCatalog).
+package siruta
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/etransport"
+	"github.com/printesoi/e-factura-go/pkg/text"
+	"github.com/printesoi/e-factura-go/pkg/util"
+)
+
+var (
+	// ErrLocalityNotFound is returned if no locality matches a lookup.
+	ErrLocalityNotFound = errors.New("siruta: locality not found")
+	// ErrAmbiguousLocality is returned if more localities from the same
+	// county match a lookup by name.
+	ErrAmbiguousLocality = errors.New("siruta: ambiguous locality")
+	// ErrInvalidCounty is returned for an unknown county code.
+	ErrInvalidCounty = errors.New("siruta: invalid county")
+)
+
+// Level is the level of an entry in the SIRUTA registry (the NIV column).
+type Level int
+
+const (
+	// LevelCounty is the level of the counties.
+	LevelCounty Level = 1
+	// LevelUAT is the level of the administrative-territorial units
+	// (municipalities, towns, communes and the Bucharest sectors).
+	LevelUAT Level = 2
+	// LevelLocality is the level of the localities (the components of the
+	// UATs).
+	LevelLocality Level = 3
+)
+
+// Locality is an entry from the SIRUTA registry.
+type Locality struct {
+	// Code is the SIRUTA code.
+	Code int
+	// Name is the official name, as published by INS (eg. "CLUJ-NAPOCA",
+	// "MUNICIPIUL CLUJ-NAPOCA").
+	Name string
+	// CountyCode is the INS code of the county, the same code used by the
+	// e-Transport declarations.
+	CountyCode etransport.CountyCodeType
+	// ParentCode is the SIRUTA code of the parent entry (the UAT for a
+	// locality, the county for a UAT).
+	ParentCode int
+	// Type is the SIRUTA type of the entry (the TIP column, eg. 1 for a
+	// county seat municipality, 3 for a commune, 9 for the component
+	// locality of a municipality that gives its name).
+	Type int
+	// Level is the level of the entry.
+	Level Level
+	// PostalCode is the postal code of the entry, if any.
+	PostalCode string
+}
+
+// Catalog is an in-memory SIRUTA registry. A Catalog is safe for concurrent
+// use.
+type Catalog struct {
+	byCode map[int]Locality
+	// byName indexes the localities and the UATs by county and normalized
+	// name.
+	byName map[etransport.CountyCodeType]map[string][]Locality
+}
+
+// NewCatalog creates a new Catalog with the given entries. For duplicate
+// SIRUTA codes the last entry is kept.
+func NewCatalog(localities ...Locality) *Catalog {
+	c := &Catalog{
+		byCode: make(map[int]Locality, len(localities)),
+		byName: make(map[etransport.CountyCodeType]map[string][]Locality),
+	}
+	for _, l := range localities {
+		c.byCode[l.Code] = l
+	}
+	for _, l := range c.byCode {
+		if l.Level != LevelLocality && l.Level != LevelUAT {
+			continue
+		}
+		names := c.byName[l.CountyCode]
+		if names == nil {
+			names = make(map[string][]Locality)
+			c.byName[l.CountyCode] = names
+		}
+		key := normalizeName(l.Name)
+		names[key] = append(names[key], l)
+	}
+	for _, names := range c.byName {
+		for _, ls := range names {
+			sort.Slice(ls, func(i, j int) bool { return ls[i].Code < ls[j].Code })
+		}
+	}
+	return c
+}
+
+// Len returns the number of entries in the catalog.
+func (c *Catalog) Len() int {
+	return len(c.byCode)
+}
+
+// ByCode returns the entry with the given SIRUTA code.
+func (c *Catalog) ByCode(code int) (Locality, bool) {
+	l, ok := c.byCode[code]
+	return l, ok
+}
+
+// CanonicalName returns the official name of the entry with the given SIRUTA
+// code.
+func (c *Catalog) CanonicalName(code int) (string, bool) {
+	l, ok := c.byCode[code]
+	return l.Name, ok
+}
+
+// Find returns all the localities from the given county matching the name.
+// The names are compared ignoring the case, the diacritics, the hyphens and
+// the UAT prefixes (eg. "Cluj Napoca" matches "MUNICIPIUL CLUJ-NAPOCA" and
+// "CLUJ-NAPOCA"). The localities are returned before the UATs, ordered by
+// the SIRUTA code.
+func (c *Catalog) Find(county etransport.CountyCodeType, name string) []Locality {
+	matches := c.byName[county][normalizeName(name)]
+	result := make([]Locality, 0, len(matches))
+	for _, level := range []Level{LevelLocality, LevelUAT} {
+		for _, l := range matches {
+			if l.Level == level {
+				result = append(result, l)
+			}
+		}
+	}
+	return result
+}
+
+// Lookup returns the locality from the given county with the given name (see
+// Find). If the name matches localities (level 3), the UATs with the same
+// name are ignored. ErrLocalityNotFound is returned if no locality matches
+// and ErrAmbiguousLocality if more localities match (eg. villages with the
+// same name in different communes of the county).
+func (c *Catalog) Lookup(county etransport.CountyCodeType, name string) (Locality, error) {
+	if _, ok := util.EtransportRoCountyCodeToEfacturaCountrySubentity(county); !ok {
+		return Locality{}, fmt.Errorf("%w: %q", ErrInvalidCounty, county)
+	}
+	matches := c.Find(county, name)
+	if len(matches) == 0 {
+		return Locality{}, fmt.Errorf("%w: %q in county %s", ErrLocalityNotFound, name, county)
+	}
+	level := matches[0].Level
+	var codes []string
+	for _, l := range matches {
+		if l.Level == level {
+			codes = append(codes, fmt.Sprint(l.Code))
+		}
+	}
+	if len(codes) > 1 {
+		return Locality{}, fmt.Errorf("%w: %q in county %s matches SIRUTA codes %s",
+			ErrAmbiguousLocality, name, county, strings.Join(codes, ", "))
+	}
+	return matches[0], nil
+}
+
+// LookupSubentity is like Lookup, but the county is given as an e-factura
+// ISO 3166-2:RO code (eg. "RO-CJ").
+func (c *Catalog) LookupSubentity(subentity efactura.CountrySubentityType, name string) (Locality, error) {
+	county, ok := util.EfacturaRoCountrySubentityToEtransportCountyCode(subentity)
+	if !ok {
+		return Locality{}, fmt.Errorf("%w: %q", ErrInvalidCounty, subentity)
+	}
+	return c.Lookup(county, name)
+}
+
+// ValidateCityName checks that the CityName of a Romanian e-factura address
+// is the name of a locality from the given county. An ambiguous name is
+// valid. For Bucharest (RO-B) the city name must be one of SECTOR1 -
+// SECTOR6, as required by the CIUS-RO rules.
+func (c *Catalog) ValidateCityName(subentity efactura.CountrySubentityType, cityName string) error {
+	if subentity == efactura.CountrySubentityRO_B {
+		if !bucharestSectorRegexp.MatchString(cityName) {
+			return fmt.Errorf("siruta: the city name must be SECTOR1 - SECTOR6 for an address in RO-B, got %q", cityName)
+		}
+		return nil
+	}
+	if _, err := c.LookupSubentity(subentity, cityName); err != nil && !errors.Is(err, ErrAmbiguousLocality) {
+		return err
+	}
+	return nil
+}
+
+// ValidateAddress checks the CityName of the given e-factura address (see
+// ValidateCityName). Addresses from other countries are not checked.
+func (c *Catalog) ValidateAddress(address efactura.PostalAddress) error {
+	if address.Country.Code != efactura.CountryCodeRO {
+		return nil
+	}
+	return c.ValidateCityName(address.CountrySubentity, address.CityName)
+}
+
+// NormalizeLocation replaces the LocalityName of the given e-Transport
+// location with the official name of the matching locality, as required by
+// the e-Transport declarations. The location is not changed if an error is
+// returned (see Lookup).
+func (c *Catalog) NormalizeLocation(location *etransport.PostingDeclationLocation) error {
+	l, err := c.Lookup(location.CountyCode, location.LocalityName)
+	if err != nil {
+		return err
+	}
+	location.LocalityName = l.Name
+	return nil
+}
+
+var (
+	bucharestSectorRegexp = regexp.MustCompile(`^SECTOR[1-6]$`)
+	uatPrefixes           = []string{"MUNICIPIUL ", "ORAS ", "COMUNA "}
+)
+
+// normalizeName returns the name used as key for the lookups: transliterated,
+// upper case, with the hyphens and the repeated spaces replaced by a single
+// space and without the UAT prefix.
+func normalizeName(name string) string {
+	name = strings.ToUpper(text.Transliterate(name))
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '\t'
+	}), " ")
+	for _, prefix := range uatPrefixes {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest
+		}
+	}
+	return name
+}
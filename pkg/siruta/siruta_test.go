@@ -0,0 +1,199 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package siruta_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/etransport"
+	"github.com/printesoi/e-factura-go/pkg/siruta"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+// testCSV is a small excerpt in the format of the INS export (the codes are
+// only used for testing).
+const testCSV = "\xef\xbb\xbfSIRUTA;DENLOC;CODP;JUD;SIRSUP;TIP;NIV;MED;FSJ\n" +
+	"1001;JUDETUL CLUJ;0;12;1;40;1;0;1\n" +
+	"1010;MUNICIPIUL CLUJ-NAPOCA;400001;12;1001;1;2;1;1\n" +
+	"1029;CLUJ-NAPOCA;400001;12;1010;9;3;1;1\n" +
+	"1038;COMUNA FLOREŞTI;407280;12;1001;3;2;3;1\n" +
+	"1047;FLOREŞTI;407280;12;1038;22;3;3;1\n" +
+	"1056;VALEA MARE;0;12;1038;23;3;3;1\n" +
+	"1065;COMUNA APAHIDA;407035;12;1001;3;2;3;1\n" +
+	"1074;VALEA MARE;0;12;1065;23;3;3;1\n" +
+	"1083;COMUNA GILĂU;407310;12;1001;3;2;3;1\n" +
+	"\n"
+
+func TestParseCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	catalog, err := siruta.ParseCSV(strings.NewReader(testCSV))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(9, catalog.Len())
+
+	l, ok := catalog.ByCode(1029)
+	if assert.True(ok) {
+		assert.Equal(siruta.Locality{
+			Code:       1029,
+			Name:       "CLUJ-NAPOCA",
+			CountyCode: etransport.CountyCodeCJ,
+			ParentCode: 1010,
+			Type:       9,
+			Level:      siruta.LevelLocality,
+			PostalCode: "400001",
+		}, l)
+	}
+	name, ok := catalog.CanonicalName(1047)
+	assert.True(ok)
+	assert.Equal("FLOREŞTI", name)
+	_, ok = catalog.ByCode(9999)
+	assert.False(ok)
+
+	// Comma separated files without the optional columns.
+	catalog, err = siruta.ParseCSV(strings.NewReader("siruta,denloc,jud,niv\n1029,CLUJ-NAPOCA,12,3\n"))
+	if assert.NoError(err) {
+		assert.Equal(1, catalog.Len())
+	}
+
+	_, err = siruta.ParseCSV(strings.NewReader("SIRUTA;DENLOC;JUD\n1029;CLUJ-NAPOCA;12\n"))
+	assert.ErrorContains(err, "missing column NIV")
+	_, err = siruta.ParseCSV(strings.NewReader("SIRUTA;DENLOC;JUD;NIV\nx;CLUJ-NAPOCA;12;3\n"))
+	assert.ErrorContains(err, "line 2: invalid SIRUTA code")
+}
+
+func TestCatalogLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	catalog, err := siruta.ParseCSV(strings.NewReader(testCSV))
+	if !assert.NoError(err) {
+		return
+	}
+
+	// The localities are preferred over the UATs with the same name.
+	for _, name := range []string{"Cluj-Napoca", "cluj napoca", "MUNICIPIUL CLUJ-NAPOCA", " Cluj -  Napoca "} {
+		l, err := catalog.Lookup(etransport.CountyCodeCJ, name)
+		if assert.NoError(err, name) {
+			assert.Equal(1029, l.Code, name)
+		}
+	}
+	l, err := catalog.LookupSubentity(efactura.CountrySubentityRO_CJ, "Floresti")
+	if assert.NoError(err) {
+		assert.Equal(1047, l.Code)
+	}
+	// Only the UAT has this name.
+	l, err = catalog.Lookup(etransport.CountyCodeCJ, "Gilau")
+	if assert.NoError(err) {
+		assert.Equal(1083, l.Code)
+	}
+	assert.Len(catalog.Find(etransport.CountyCodeCJ, "Cluj-Napoca"), 2)
+
+	_, err = catalog.Lookup(etransport.CountyCodeCJ, "Valea Mare")
+	assert.True(errors.Is(err, siruta.ErrAmbiguousLocality))
+	assert.ErrorContains(err, "1056, 1074")
+	_, err = catalog.Lookup(etransport.CountyCodeAB, "Cluj-Napoca")
+	assert.True(errors.Is(err, siruta.ErrLocalityNotFound))
+	_, err = catalog.Lookup("99", "Cluj-Napoca")
+	assert.True(errors.Is(err, siruta.ErrInvalidCounty))
+	_, err = catalog.LookupSubentity("RO-XX", "Cluj-Napoca")
+	assert.True(errors.Is(err, siruta.ErrInvalidCounty))
+}
+
+func TestCatalogValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	catalog, err := siruta.ParseCSV(strings.NewReader(testCSV))
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.NoError(catalog.ValidateCityName(efactura.CountrySubentityRO_CJ, "Cluj-Napoca"))
+	assert.NoError(catalog.ValidateCityName(efactura.CountrySubentityRO_CJ, "Valea Mare"))
+	assert.Error(catalog.ValidateCityName(efactura.CountrySubentityRO_CJ, "Cluj"))
+	assert.NoError(catalog.ValidateCityName(efactura.CountrySubentityRO_B, efactura.CityNameROBSector3))
+	assert.Error(catalog.ValidateCityName(efactura.CountrySubentityRO_B, "Bucuresti"))
+
+	assert.NoError(catalog.ValidateAddress(efactura.PostalAddress{
+		CityName: "Paris",
+		Country:  efactura.Country{Code: efactura.CountryCodeFR},
+	}))
+	assert.Error(catalog.ValidateAddress(efactura.PostalAddress{
+		CityName:         "Clj",
+		CountrySubentity: efactura.CountrySubentityRO_CJ,
+		Country:          efactura.Country{Code: efactura.CountryCodeRO},
+	}))
+
+	location := etransport.PostingDeclationLocation{CountyCode: etransport.CountyCodeCJ, LocalityName: "Floresti"}
+	if assert.NoError(catalog.NormalizeLocation(&location)) {
+		assert.Equal("FLOREŞTI", location.LocalityName)
+	}
+	location.LocalityName = "Valea Mare"
+	assert.Error(catalog.NormalizeLocation(&location))
+	assert.Equal("Valea Mare", location.LocalityName)
+}
+
+func TestLazyCatalog(t *testing.T) {
+	assert := assert.New(t)
+
+	loads := 0
+	lazy := siruta.NewLazyCatalog(func() (*siruta.Catalog, error) {
+		loads++
+		return siruta.ParseCSV(strings.NewReader(testCSV))
+	})
+	for i := 0; i < 3; i++ {
+		catalog, err := lazy.Catalog()
+		if assert.NoError(err) {
+			assert.Equal(9, catalog.Len())
+		}
+	}
+	assert.Equal(1, loads)
+
+	_, err := siruta.NewLazyFileCatalog("/nonexistent/siruta.csv").Catalog()
+	assert.Error(err)
+}
+
+func TestLoadFileDataVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "siruta_2024.csv")
+	if err := os.WriteFile(path, []byte(testCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, time.July, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := siruta.LoadFile(path)
+	if assert.NoError(err) {
+		assert.Equal(9, catalog.Len())
+	}
+	version, ok := efactura.GetDataVersion(efactura.DataComponentSIRUTA)
+	if assert.True(ok) {
+		assert.Equal("siruta_2024.csv", version.Version)
+		assert.Equal(types.MakeDateFromTime(modTime), version.UpdatedAt)
+	}
+	assert.ErrorContains(efactura.CheckDataFreshness(types.MakeDate(2024, time.August, 1)),
+		"SIRUTA siruta_2024.csv (2024-07-01)")
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/printesoi/e-factura-go/internal/helpers"
+)
+
+const (
+	dirStoreDataExt  = ".data"
+	dirStoreEntryExt = ".json"
+)
+
+// DirStore is a Store that keeps the documents in a directory: for each key,
+// the data is stored in <key>.data and the entry in <key>.json.
+type DirStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewDirStore creates a DirStore in dir. The directory is created if
+// missing.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// Dir returns the directory of the store.
+func (s *DirStore) Dir() string {
+	return s.dir
+}
+
+func (s *DirStore) path(key, ext string) string {
+	return filepath.Join(s.dir, key+ext)
+}
+
+// Put implements the Store interface. The data is written before the entry,
+// so an entry is never visible without its data.
+func (s *DirStore) Put(ctx context.Context, entry Entry, data []byte) error {
+	if err := ValidateKey(entry.Key); err != nil {
+		return err
+	}
+	entry.Size = int64(len(data))
+	entry.SHA256 = Checksum(data)
+	if entry.StoredAt.IsZero() {
+		entry.StoredAt = time.Now()
+	}
+	entryData, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := helpers.WriteFileAtomic(s.path(entry.Key, dirStoreDataExt), data, 0o644); err != nil {
+		return err
+	}
+	return helpers.WriteFileAtomic(s.path(entry.Key, dirStoreEntryExt), entryData, 0o644)
+}
+
+// Get implements the Store interface.
+func (s *DirStore) Get(ctx context.Context, key string) (entry Entry, data []byte, err error) {
+	if err = ValidateKey(key); err != nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if entry, err = s.readEntry(key); err != nil {
+		return
+	}
+	data, err = os.ReadFile(s.path(key, dirStoreDataExt))
+	if errors.Is(err, fs.ErrNotExist) {
+		err = ErrNotFound
+	}
+	return
+}
+
+// Stat implements the Store interface.
+func (s *DirStore) Stat(ctx context.Context, key string) (Entry, error) {
+	if err := ValidateKey(key); err != nil {
+		return Entry{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.readEntry(key)
+}
+
+// Delete implements the Store interface.
+func (s *DirStore) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(key, dirStoreEntryExt)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := os.Remove(s.path(key, dirStoreDataExt)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// UpdateMetadata implements the MetadataUpdater interface. Only the entry
+// file is rewritten.
+func (s *DirStore) UpdateMetadata(ctx context.Context, key string, md map[string]string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, err := s.readEntry(key)
+	if err != nil {
+		return err
+	}
+	entry.Metadata = mergeMetadata(entry.Metadata, md)
+	entryData, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return helpers.WriteFileAtomic(s.path(key, dirStoreEntryExt), entryData, 0o644)
+}
+
+// List implements the Store interface.
+func (s *DirStore) List(ctx context.Context) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, dirStoreEntryExt) {
+			continue
+		}
+		entry, err := s.readEntry(strings.TrimSuffix(name, dirStoreEntryExt))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+func (s *DirStore) readEntry(key string) (entry Entry, err error) {
+	data, err := os.ReadFile(s.path(key, dirStoreEntryExt))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = ErrNotFound
+		}
+		return
+	}
+	err = json.Unmarshal(data, &entry)
+	return
+}
@@ -0,0 +1,330 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DataKeySize is the size in bytes of the data keys (AES-256).
+	DataKeySize = 32
+
+	// metadataKeyEncryptedDataKey is the metadata key of the encrypted data
+	// key of an entry stored by an EncryptedStore.
+	metadataKeyEncryptedDataKey = "_enc_data_key"
+	// metadataKeyPlainSize is the metadata key of the size of the plaintext
+	// document data of an entry stored by an EncryptedStore.
+	metadataKeyPlainSize = "_enc_size"
+	// encryptedFieldPrefix is the prefix of the encrypted metadata values.
+	encryptedFieldPrefix = "enc:v1:"
+	// dataLabel is the label of the document data in the additional data of
+	// the encrypted entry values (see entryAdditionalData).
+	dataLabel = "data"
+)
+
+// ErrNotEncrypted is returned by an EncryptedStore for entries that were not
+// stored by an EncryptedStore.
+var ErrNotEncrypted = errors.New("store: entry is not encrypted")
+
+// KMS is a key management service used for envelope encryption: the
+// documents are encrypted with a data key, and the data key is stored along
+// with the document, encrypted with a master key that never leaves the KMS
+// (eg. AWS KMS, Google Cloud KMS, HashiCorp Vault).
+type KMS interface {
+	// GenerateDataKey returns a new DataKeySize bytes data key, both in
+	// plaintext and encrypted with the master key.
+	GenerateDataKey(ctx context.Context) (plaintext, encrypted []byte, err error)
+	// DecryptDataKey decrypts a data key returned by GenerateDataKey.
+	DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error)
+}
+
+// LocalKMS is a KMS that encrypts the data keys with a local master key
+// using AES-256-GCM. Useful for tests, or if the master key is kept in a
+// secrets manager.
+type LocalKMS struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKMS creates a LocalKMS using the given 32 bytes master key.
+func NewLocalKMS(masterKey []byte) (*LocalKMS, error) {
+	if len(masterKey) != DataKeySize {
+		return nil, fmt.Errorf("store: invalid master key size %d", len(masterKey))
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKMS{aead: aead}, nil
+}
+
+// GenerateDataKey implements the KMS interface.
+func (k *LocalKMS) GenerateDataKey(ctx context.Context) (plaintext, encrypted []byte, err error) {
+	plaintext = make([]byte, DataKeySize)
+	if _, err = rand.Read(plaintext); err != nil {
+		return nil, nil, err
+	}
+	if encrypted, err = seal(k.aead, plaintext, nil); err != nil {
+		return nil, nil, err
+	}
+	return plaintext, encrypted, nil
+}
+
+// DecryptDataKey implements the KMS interface.
+func (k *LocalKMS) DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	return open(k.aead, encrypted, nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, returning nonce||ciphertext.
+// The additional data is authenticated, but not encrypted.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts data encrypted with seal with the same additional data.
+func open(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: encrypted data too short", ErrCorrupted)
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	return plaintext, nil
+}
+
+// entryAdditionalData returns the additional data of an encrypted value of
+// the entry with the given key: the document data (dataLabel) or a metadata
+// field ("field:" followed by the metadata key). Binding the values to the
+// entry key and the field prevents moving the ciphertexts between entries or
+// fields without being detected.
+func entryAdditionalData(key, label string) []byte {
+	return []byte("e-factura-go/store:" + strconv.Quote(key) + ":" + label)
+}
+
+// EncryptedStore is a Store that wraps another Store and encrypts the
+// document data (eg. B2C invoices containing personal data) and the selected
+// metadata fields using envelope encryption: each entry is encrypted with a
+// new data key (AES-256-GCM) generated by the KMS, and the encrypted data key
+// is stored in the entry metadata. The other entry fields (key, CIF, IDs,
+// etc.) are stored in plaintext, so they can still be used for lookups.
+//
+// The entries returned by an EncryptedStore have the plaintext Size and an
+// empty SHA256, since the integrity of the data is checked by the
+// authenticated encryption (a mismatch is reported as ErrCorrupted). The
+// encrypted values are bound to the entry key and to the field, so a
+// ciphertext copied to another entry or field is also reported as
+// ErrCorrupted.
+type EncryptedStore struct {
+	Store
+	kms    KMS
+	fields map[string]bool
+}
+
+// EncryptedStoreOption allows gradually modifying an EncryptedStore.
+type EncryptedStoreOption func(*EncryptedStore)
+
+// EncryptedStoreFields selects the metadata keys whose values are encrypted
+// (eg. "buyer_name", "buyer_cnp").
+func EncryptedStoreFields(keys ...string) EncryptedStoreOption {
+	return func(s *EncryptedStore) {
+		for _, key := range keys {
+			s.fields[key] = true
+		}
+	}
+}
+
+// NewEncryptedStore creates an EncryptedStore that stores the encrypted
+// documents in st, using kms for the data keys.
+func NewEncryptedStore(st Store, kms KMS, opts ...EncryptedStoreOption) *EncryptedStore {
+	s := &EncryptedStore{
+		Store:  st,
+		kms:    kms,
+		fields: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Put implements the Store interface. The data and the selected metadata
+// fields are encrypted with a new data key.
+func (s *EncryptedStore) Put(ctx context.Context, entry Entry, data []byte) error {
+	dataKey, encryptedKey, err := s.kms.GenerateDataKey(ctx)
+	if err != nil {
+		return fmt.Errorf("store: cannot generate data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	metadata := make(map[string]string, len(entry.Metadata)+2)
+	for k, v := range entry.Metadata {
+		if s.fields[k] && v != "" {
+			ciphertext, err := seal(aead, []byte(v), entryAdditionalData(entry.Key, "field:"+k))
+			if err != nil {
+				return err
+			}
+			v = encryptedFieldPrefix + base64.StdEncoding.EncodeToString(ciphertext)
+		}
+		metadata[k] = v
+	}
+	metadata[metadataKeyEncryptedDataKey] = base64.StdEncoding.EncodeToString(encryptedKey)
+	metadata[metadataKeyPlainSize] = strconv.Itoa(len(data))
+	entry.Metadata = metadata
+
+	ciphertext, err := seal(aead, data, entryAdditionalData(entry.Key, dataLabel))
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(ctx, entry, ciphertext)
+}
+
+// Get implements the Store interface. The data and the encrypted metadata
+// fields are decrypted.
+func (s *EncryptedStore) Get(ctx context.Context, key string) (Entry, []byte, error) {
+	entry, ciphertext, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return entry, nil, err
+	}
+	aead, err := s.entryAEAD(ctx, entry)
+	if err != nil {
+		return entry, nil, err
+	}
+	data, err := open(aead, ciphertext, entryAdditionalData(key, dataLabel))
+	if err != nil {
+		return entry, nil, fmt.Errorf("%s: %w", key, err)
+	}
+	if entry, err = s.decryptFields(aead, entry); err != nil {
+		return entry, nil, err
+	}
+	return entry, data, nil
+}
+
+// Stat implements the Store interface. The encrypted metadata fields are
+// decrypted.
+func (s *EncryptedStore) Stat(ctx context.Context, key string) (Entry, error) {
+	entry, err := s.Store.Stat(ctx, key)
+	if err != nil {
+		return entry, err
+	}
+	return s.decryptEntry(ctx, entry)
+}
+
+// List implements the Store interface. The encrypted metadata fields are
+// decrypted, which requires decrypting the data key of every entry.
+func (s *EncryptedStore) List(ctx context.Context) ([]Entry, error) {
+	entries, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i], err = s.decryptEntry(ctx, entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// entryAEAD returns the AEAD for the data key of the entry.
+func (s *EncryptedStore) entryAEAD(ctx context.Context, entry Entry) (cipher.AEAD, error) {
+	encoded, ok := entry.Metadata[metadataKeyEncryptedDataKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotEncrypted, entry.Key)
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: invalid data key: %v", ErrCorrupted, entry.Key, err)
+	}
+	dataKey, err := s.kms.DecryptDataKey(ctx, encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("store: %s: cannot decrypt data key: %w", entry.Key, err)
+	}
+	return newAEAD(dataKey)
+}
+
+func (s *EncryptedStore) decryptEntry(ctx context.Context, entry Entry) (Entry, error) {
+	aead, err := s.entryAEAD(ctx, entry)
+	if err != nil {
+		return entry, err
+	}
+	return s.decryptFields(aead, entry)
+}
+
+// decryptFields decrypts the selected metadata fields of the entry and
+// removes the encryption metadata. The values of the other fields are
+// returned as is, even if they look encrypted. A non-empty value of a
+// selected field that is not encrypted is reported as ErrCorrupted.
+func (s *EncryptedStore) decryptFields(aead cipher.AEAD, entry Entry) (Entry, error) {
+	metadata := make(map[string]string, len(entry.Metadata))
+	for k, v := range entry.Metadata {
+		switch k {
+		case metadataKeyEncryptedDataKey:
+			continue
+		case metadataKeyPlainSize:
+			size, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return entry, fmt.Errorf("%w: %s: invalid size %q", ErrCorrupted, entry.Key, v)
+			}
+			entry.Size = size
+			continue
+		}
+		if s.fields[k] && v != "" {
+			encoded, ok := strings.CutPrefix(v, encryptedFieldPrefix)
+			if !ok {
+				return entry, fmt.Errorf("%w: %s: field %s is not encrypted", ErrCorrupted, entry.Key, k)
+			}
+			ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return entry, fmt.Errorf("%w: %s: invalid field %s", ErrCorrupted, entry.Key, k)
+			}
+			plaintext, err := open(aead, ciphertext, entryAdditionalData(entry.Key, "field:"+k))
+			if err != nil {
+				return entry, fmt.Errorf("%s: field %s: %w", entry.Key, k, err)
+			}
+			v = string(plaintext)
+		}
+		metadata[k] = v
+	}
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	entry.Metadata = metadata
+	entry.SHA256 = ""
+	return entry, nil
+}
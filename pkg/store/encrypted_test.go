@@ -0,0 +1,163 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	kms, err := store.NewLocalKMS(bytes.Repeat([]byte{0x42}, store.DataKeySize))
+	if !assert.NoError(err) {
+		return
+	}
+	_, err = store.NewLocalKMS([]byte("short"))
+	assert.Error(err)
+
+	inner := store.NewMemoryStore()
+	s := store.NewEncryptedStore(inner, kms, store.EncryptedStoreFields("buyer_cnp"))
+
+	invoiceXML := []byte("<Invoice><BuyerCNP>1800101221144</BuyerCNP></Invoice>")
+	err = s.Put(ctx, store.Entry{
+		Key:        "3001",
+		DownloadID: 3001,
+		CIF:        "123456789",
+		Metadata:   map[string]string{"buyer_cnp": "1800101221144", "tenant": "acme"},
+	}, invoiceXML)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// The wrapped store only has the ciphertext.
+	rawEntry, rawData, err := inner.Get(ctx, "3001")
+	if assert.NoError(err) {
+		assert.NotContains(string(rawData), "1800101221144")
+		assert.True(strings.HasPrefix(rawEntry.Metadata["buyer_cnp"], "enc:v1:"))
+		assert.Equal("acme", rawEntry.Metadata["tenant"])
+		assert.Equal("123456789", rawEntry.CIF)
+	}
+
+	entry, data, err := s.Get(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal(invoiceXML, data)
+		assert.Equal(map[string]string{"buyer_cnp": "1800101221144", "tenant": "acme"}, entry.Metadata)
+		assert.Equal(int64(len(invoiceXML)), entry.Size)
+		assert.NoError(store.Verify(entry, data))
+	}
+	entry, err = s.Stat(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal("1800101221144", entry.Metadata["buyer_cnp"])
+	}
+	entries, err := s.List(ctx)
+	if assert.NoError(err) && assert.Len(entries, 1) {
+		assert.Equal("1800101221144", entries[0].Metadata["buyer_cnp"])
+	}
+
+	assert.NoError(store.UpdateMetadata(ctx, s, "3001", map[string]string{"tenant": ""}))
+	entry, data, err = s.Get(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal(map[string]string{"buyer_cnp": "1800101221144"}, entry.Metadata)
+		assert.Equal(invoiceXML, data)
+	}
+
+	// Tampering with the ciphertext is detected.
+	rawEntry, rawData, _ = inner.Get(ctx, "3001")
+	rawData[len(rawData)-1] ^= 0xff
+	assert.NoError(inner.Put(ctx, rawEntry, rawData))
+	_, _, err = s.Get(ctx, "3001")
+	assert.ErrorIs(err, store.ErrCorrupted)
+
+	// Entries not stored by an EncryptedStore are rejected.
+	assert.NoError(inner.Put(ctx, store.Entry{Key: "3002"}, []byte("plain")))
+	_, _, err = s.Get(ctx, "3002")
+	assert.ErrorIs(err, store.ErrNotEncrypted)
+
+	// A different master key cannot decrypt the data keys.
+	otherKMS, _ := store.NewLocalKMS(bytes.Repeat([]byte{0x24}, store.DataKeySize))
+	_, err = store.NewEncryptedStore(inner, otherKMS).Stat(ctx, "3001")
+	assert.Error(err)
+}
+
+func TestEncryptedStoreAdditionalData(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	kms, err := store.NewLocalKMS(bytes.Repeat([]byte{0x42}, store.DataKeySize))
+	if !assert.NoError(err) {
+		return
+	}
+	inner := store.NewMemoryStore()
+	s := store.NewEncryptedStore(inner, kms, store.EncryptedStoreFields("buyer_name", "buyer_cnp"))
+
+	for _, key := range []string{"3001", "3002", "3003", "3004"} {
+		assert.NoError(s.Put(ctx, store.Entry{
+			Key:      key,
+			Metadata: map[string]string{"buyer_name": "Ion Popescu", "buyer_cnp": "1800101221144"},
+		}, []byte("<Invoice>"+key+"</Invoice>")))
+	}
+	// rawEntry returns a copy of the entry from the wrapped store.
+	rawEntry := func(key string) (store.Entry, []byte) {
+		entry, data, err := inner.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata := make(map[string]string, len(entry.Metadata))
+		for k, v := range entry.Metadata {
+			metadata[k] = v
+		}
+		entry.Metadata = metadata
+		return entry, data
+	}
+
+	// The whole encrypted entry (data key included) copied to another key.
+	entry, data := rawEntry("3001")
+	entry.Key = "3002"
+	assert.NoError(inner.Put(ctx, entry, data))
+	_, _, err = s.Get(ctx, "3002")
+	assert.ErrorIs(err, store.ErrCorrupted)
+
+	// A field copied to another field of the same entry.
+	entry, data = rawEntry("3003")
+	entry.Metadata["buyer_name"] = entry.Metadata["buyer_cnp"]
+	assert.NoError(inner.Put(ctx, entry, data))
+	_, err = s.Stat(ctx, "3003")
+	assert.ErrorIs(err, store.ErrCorrupted)
+
+	// A selected field replaced with a plaintext value.
+	entry.Metadata["buyer_name"] = "Vasile Ionescu"
+	assert.NoError(inner.Put(ctx, entry, data))
+	_, err = s.Stat(ctx, "3003")
+	assert.ErrorIs(err, store.ErrCorrupted)
+
+	// The other fields are not decrypted, even if they look encrypted.
+	raw, _ := rawEntry("3004")
+	s = store.NewEncryptedStore(inner, kms, store.EncryptedStoreFields("buyer_name"))
+	entry, err = s.Stat(ctx, "3004")
+	if assert.NoError(err) {
+		assert.Equal("Ion Popescu", entry.Metadata["buyer_name"])
+		assert.Equal(raw.Metadata["buyer_cnp"], entry.Metadata["buyer_cnp"])
+		assert.True(strings.HasPrefix(entry.Metadata["buyer_cnp"], "enc:v1:"))
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	entry Entry
+	data  []byte
+}
+
+// MemoryStore is a Store that keeps the documents in memory. Useful for
+// tests.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+// Put implements the Store interface.
+func (s *MemoryStore) Put(ctx context.Context, entry Entry, data []byte) error {
+	if err := ValidateKey(entry.Key); err != nil {
+		return err
+	}
+	entry.Size = int64(len(data))
+	entry.SHA256 = Checksum(data)
+	if entry.StoredAt.IsZero() {
+		entry.StoredAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[entry.Key] = memoryItem{entry: entry, data: append([]byte(nil), data...)}
+	return nil
+}
+
+// Get implements the Store interface.
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[key]
+	if !ok {
+		return Entry{}, nil, ErrNotFound
+	}
+	return item.entry, append([]byte(nil), item.data...), nil
+}
+
+// Stat implements the Store interface.
+func (s *MemoryStore) Stat(ctx context.Context, key string) (Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[key]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return item.entry, nil
+}
+
+// Delete implements the Store interface.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.items, key)
+	return nil
+}
+
+// UpdateMetadata implements the MetadataUpdater interface.
+func (s *MemoryStore) UpdateMetadata(ctx context.Context, key string, md map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[key]
+	if !ok {
+		return ErrNotFound
+	}
+	item.entry.Metadata = mergeMetadata(item.entry.Metadata, md)
+	s.items[key] = item
+	return nil
+}
+
+// List implements the Store interface.
+func (s *MemoryStore) List(ctx context.Context) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := make([]Entry, 0, len(s.items))
+	for _, item := range s.items {
+		entries = append(entries, item.entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRetention is returned (wrapped in a RetentionError) if an operation
+// would delete or replace a document protected by the retention policy.
+var ErrRetention = errors.New("store: document protected by retention policy")
+
+// RetentionError is the error returned by a RetentionStore when refusing to
+// delete or replace a protected document.
+type RetentionError struct {
+	// Key is the key of the protected entry.
+	Key string
+	// LegalHold is true if the entry is under legal hold.
+	LegalHold bool
+	// RetainUntil is the end of the minimum retention period of the entry.
+	RetainUntil time.Time
+}
+
+func (e *RetentionError) Error() string {
+	if e.LegalHold {
+		return fmt.Sprintf("%s: %s is under legal hold", ErrRetention, e.Key)
+	}
+	return fmt.Sprintf("%s: %s must be kept until %s", ErrRetention, e.Key,
+		e.RetainUntil.Format(time.DateOnly))
+}
+
+// Unwrap returns ErrRetention, so errors.Is(err, ErrRetention) can be used to
+// check for retention errors.
+func (e *RetentionError) Unwrap() error {
+	return ErrRetention
+}
+
+// RetentionPolicy is the minimum keep duration of the stored documents. The
+// retention period of an entry starts at the time returned by Start (or
+// StoredAt if Start is nil) and lasts MinYears years plus MinDuration.
+type RetentionPolicy struct {
+	// MinYears is the number of years the documents must be kept.
+	MinYears int
+	// MinDuration is added to MinYears.
+	MinDuration time.Duration
+	// Start returns the start of the retention period for an entry (eg.
+	// the end of the financial year from the entry metadata). If nil, or
+	// if it returns the zero time, StoredAt is used.
+	Start func(entry Entry) time.Time
+}
+
+// DefaultRetentionPolicy keeps the documents for 10 years, the retention
+// period of the financial-accounting documents from art. 25 of Legea
+// contabilității nr. 82/1991.
+var DefaultRetentionPolicy = RetentionPolicy{MinYears: 10}
+
+// RetainUntil returns the end of the minimum retention period for entry.
+func (p RetentionPolicy) RetainUntil(entry Entry) time.Time {
+	var start time.Time
+	if p.Start != nil {
+		start = p.Start(entry)
+	}
+	if start.IsZero() {
+		start = entry.StoredAt
+	}
+	return start.AddDate(p.MinYears, 0, 0).Add(p.MinDuration)
+}
+
+// Check returns a *RetentionError if entry cannot be deleted or replaced at
+// time now: the entry is under legal hold or its minimum retention period
+// did not end yet.
+func (p RetentionPolicy) Check(entry Entry, now time.Time) error {
+	retainUntil := p.RetainUntil(entry)
+	if entry.LegalHold || now.Before(retainUntil) {
+		return &RetentionError{
+			Key:         entry.Key,
+			LegalHold:   entry.LegalHold,
+			RetainUntil: retainUntil,
+		}
+	}
+	return nil
+}
+
+// RetentionStore is a Store that wraps another Store and enforces a
+// RetentionPolicy: deleting or replacing a document which is under legal
+// hold or younger than the retention period fails with a RetentionError.
+// Note that the documents are only protected from the deletes done through
+// the RetentionStore.
+type RetentionStore struct {
+	Store
+	policy RetentionPolicy
+	mu     sync.Mutex
+}
+
+// NewRetentionStore creates a RetentionStore that enforces policy for the
+// documents from st.
+func NewRetentionStore(st Store, policy RetentionPolicy) *RetentionStore {
+	return &RetentionStore{
+		Store:  st,
+		policy: policy,
+	}
+}
+
+// Policy returns the retention policy of the store.
+func (s *RetentionStore) Policy() RetentionPolicy {
+	return s.policy
+}
+
+// Put implements the Store interface. Replacing a protected entry fails with
+// a RetentionError.
+func (s *RetentionStore) Put(ctx context.Context, entry Entry, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.Store.Stat(ctx, entry.Key)
+	switch {
+	case err == nil:
+		if err := s.policy.Check(existing, time.Now()); err != nil {
+			return err
+		}
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	return s.Store.Put(ctx, entry, data)
+}
+
+// Delete implements the Store interface. Deleting a protected entry fails
+// with a RetentionError.
+func (s *RetentionStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.Store.Stat(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Check(entry, time.Now()); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+// SetLegalHold sets or clears the legal hold flag for the entry with the
+// given key.
+func (s *RetentionStore) SetLegalHold(ctx context.Context, key string, hold bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, data, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if entry.LegalHold == hold {
+		return nil
+	}
+	entry.LegalHold = hold
+	return s.Store.Put(ctx, entry, data)
+}
+
+// UpdateMetadata implements the MetadataUpdater interface. Updating the
+// metadata is allowed for protected entries, since the document data is
+// not changed.
+func (s *RetentionStore) UpdateMetadata(ctx context.Context, key string, md map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return UpdateMetadata(ctx, s.Store, key, md)
+}
+
+// Expired returns the entries that can be deleted: the entries not under
+// legal hold for which the retention period ended.
+func (s *RetentionStore) Expired(ctx context.Context) ([]Entry, error) {
+	entries, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var expired []Entry
+	for _, entry := range entries {
+		if s.policy.Check(entry, now) == nil {
+			expired = append(expired, entry)
+		}
+	}
+	return expired, nil
+}
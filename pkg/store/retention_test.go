@@ -0,0 +1,94 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+func TestRetentionStore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	s := store.NewRetentionStore(store.NewMemoryStore(), store.DefaultRetentionPolicy)
+	now := time.Now()
+	assert.NoError(s.Put(ctx, store.Entry{Key: "old", StoredAt: now.AddDate(-11, 0, 0)}, []byte("old")))
+	assert.NoError(s.Put(ctx, store.Entry{Key: "new", StoredAt: now.AddDate(-1, 0, 0)}, []byte("new")))
+
+	// Entries younger than the retention period cannot be deleted or
+	// replaced.
+	err := s.Delete(ctx, "new")
+	var retentionErr *store.RetentionError
+	if assert.True(errors.As(err, &retentionErr)) {
+		assert.True(errors.Is(err, store.ErrRetention))
+		assert.Equal("new", retentionErr.Key)
+		assert.False(retentionErr.LegalHold)
+		assert.True(retentionErr.RetainUntil.After(now))
+	}
+	assert.True(errors.Is(s.Put(ctx, store.Entry{Key: "new"}, []byte("new2")), store.ErrRetention))
+	_, data, err := s.Get(ctx, "new")
+	if assert.NoError(err) {
+		assert.Equal("new", string(data))
+	}
+
+	expired, err := s.Expired(ctx)
+	if assert.NoError(err) && assert.Len(expired, 1) {
+		assert.Equal("old", expired[0].Key)
+	}
+
+	// Legal hold protects entries regardless of their age.
+	assert.NoError(s.SetLegalHold(ctx, "old", true))
+	entry, err := s.Stat(ctx, "old")
+	if assert.NoError(err) {
+		assert.True(entry.LegalHold)
+		assert.True(entry.StoredAt.Before(now.AddDate(-10, 0, 0)))
+	}
+	err = s.Delete(ctx, "old")
+	if assert.True(errors.As(err, &retentionErr)) {
+		assert.True(retentionErr.LegalHold)
+	}
+	expired, err = s.Expired(ctx)
+	if assert.NoError(err) {
+		assert.Empty(expired)
+	}
+
+	assert.NoError(s.SetLegalHold(ctx, "old", false))
+	assert.NoError(s.Delete(ctx, "old"))
+	assert.True(errors.Is(s.Delete(ctx, "old"), store.ErrNotFound))
+	assert.True(errors.Is(s.SetLegalHold(ctx, "old", true), store.ErrNotFound))
+}
+
+func TestRetentionPolicyStart(t *testing.T) {
+	assert := assert.New(t)
+
+	// Retention starting at the end of the financial year.
+	policy := store.RetentionPolicy{
+		MinYears: 10,
+		Start: func(entry store.Entry) time.Time {
+			return time.Date(entry.StoredAt.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+		},
+	}
+	entry := store.Entry{Key: "1", StoredAt: time.Date(2014, time.March, 1, 0, 0, 0, 0, time.UTC)}
+	assert.Equal(time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC), policy.RetainUntil(entry))
+	assert.Error(policy.Check(entry, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)))
+	assert.NoError(policy.Check(entry, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package store provides storage for the archived documents (eg. the zip
+// archives with the ANAF signed invoices).
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned if no entry exists for a given key.
+	ErrNotFound = errors.New("store: not found")
+	// ErrCorrupted is returned by Verify if the document data doesn't match
+	// the size or the checksum recorded in the entry.
+	ErrCorrupted = errors.New("store: corrupted document")
+)
+
+// Entry describes an archived document.
+type Entry struct {
+	// Key is the unique key of the entry in the store.
+	Key string `json:"key"`
+	// DownloadID is the download ID (id_descarcare) of the document.
+	DownloadID int64 `json:"download_id,omitempty"`
+	// UploadIndex is the upload index (index_incarcare) of the document.
+	UploadIndex int64 `json:"upload_index,omitempty"`
+	// CIF is the CIF of the message owner.
+	CIF string `json:"cif,omitempty"`
+	// MessageType is the message type (eg. FACTURA TRIMISA).
+	MessageType string `json:"message_type,omitempty"`
+	// Name is the name of the document (eg. the zip file name).
+	Name string `json:"name,omitempty"`
+	// Size is the size of the document data in bytes. Set by the store.
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded SHA-256 checksum of the document data. Set
+	// by the store.
+	SHA256 string `json:"sha256,omitempty"`
+	// StoredAt is the time the document was stored. Set by the store if
+	// zero.
+	StoredAt time.Time `json:"stored_at"`
+	// CorrelationID is the correlation ID of the operation that produced the
+	// document (eg. the download), see the correlation package.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Metadata is the user metadata of the document.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// LegalHold marks the document as being under legal hold. A
+	// RetentionStore refuses to delete or replace documents under legal
+	// hold, regardless of their age.
+	LegalHold bool `json:"legal_hold,omitempty"`
+}
+
+// Store is a storage for archived documents. Implementations must be safe
+// for concurrent use.
+type Store interface {
+	// Put stores the entry and the document data, replacing any existing
+	// entry with the same key.
+	Put(ctx context.Context, entry Entry, data []byte) error
+	// Get returns the entry and the document data for key, or ErrNotFound.
+	Get(ctx context.Context, key string) (Entry, []byte, error)
+	// Stat returns the entry for key without the data, or ErrNotFound.
+	Stat(ctx context.Context, key string) (Entry, error)
+	// Delete deletes the entry for key, or returns ErrNotFound.
+	Delete(ctx context.Context, key string) error
+	// List returns all the entries, sorted by key.
+	List(ctx context.Context) ([]Entry, error)
+}
+
+// MetadataUpdater is implemented by the stores that can update the metadata
+// of an entry without rewriting the document data.
+type MetadataUpdater interface {
+	// UpdateMetadata merges md into the metadata of the entry for key, or
+	// returns ErrNotFound. Keys with an empty value are removed.
+	UpdateMetadata(ctx context.Context, key string, md map[string]string) error
+}
+
+// UpdateMetadata merges md into the metadata of the entry for key. Keys with
+// an empty value are removed. If st implements MetadataUpdater, it's used,
+// otherwise the entry is replaced using Get and Put.
+func UpdateMetadata(ctx context.Context, st Store, key string, md map[string]string) error {
+	if u, ok := st.(MetadataUpdater); ok {
+		return u.UpdateMetadata(ctx, key, md)
+	}
+	entry, data, err := st.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	entry.Metadata = mergeMetadata(entry.Metadata, md)
+	return st.Put(ctx, entry, data)
+}
+
+func mergeMetadata(metadata, md map[string]string) map[string]string {
+	merged := make(map[string]string, len(metadata)+len(md))
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	for k, v := range md {
+		if v == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// Checksum returns the hex encoded SHA-256 checksum of data, as recorded in
+// Entry.SHA256.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that data matches the size and the checksum (if recorded)
+// of entry. A mismatch (eg. a truncated file or bit rot) is reported as an
+// error wrapping ErrCorrupted.
+func Verify(entry Entry, data []byte) error {
+	if int64(len(data)) != entry.Size {
+		return fmt.Errorf("%w: %s: size %d, expected %d", ErrCorrupted, entry.Key, len(data), entry.Size)
+	}
+	if entry.SHA256 != "" && Checksum(data) != entry.SHA256 {
+		return fmt.Errorf("%w: %s: checksum mismatch", ErrCorrupted, entry.Key)
+	}
+	return nil
+}
+
+// ValidateKey checks that key can be used as a store key: it must not be
+// empty, must not start with a dot and must not contain path separators.
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) {
+		return fmt.Errorf("store: invalid key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/store"
+)
+
+func testStore(t *testing.T, s store.Store) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	assert.Error(s.Put(ctx, store.Entry{Key: "../escape"}, nil))
+	assert.Error(s.Put(ctx, store.Entry{Key: ""}, nil))
+
+	err := s.Put(ctx, store.Entry{
+		Key:        "3002",
+		DownloadID: 3002,
+		Metadata:   map[string]string{"tenant": "acme"},
+	}, []byte("zip-2"))
+	assert.NoError(err)
+	assert.NoError(s.Put(ctx, store.Entry{Key: "3001", DownloadID: 3001}, []byte("zip-1")))
+
+	entry, data, err := s.Get(ctx, "3002")
+	if assert.NoError(err) {
+		assert.Equal(int64(3002), entry.DownloadID)
+		assert.Equal(int64(5), entry.Size)
+		assert.False(entry.StoredAt.IsZero())
+		assert.Equal("acme", entry.Metadata["tenant"])
+		assert.Equal("zip-2", string(data))
+		assert.Equal(store.Checksum([]byte("zip-2")), entry.SHA256)
+		assert.NoError(store.Verify(entry, data))
+		assert.True(errors.Is(store.Verify(entry, data[:3]), store.ErrCorrupted))
+		assert.True(errors.Is(store.Verify(entry, []byte("zip-9")), store.ErrCorrupted))
+	}
+
+	assert.NoError(store.UpdateMetadata(ctx, s, "3002", map[string]string{"checked": "yes", "tenant": ""}))
+	entry, data, err = s.Get(ctx, "3002")
+	if assert.NoError(err) {
+		assert.Equal(map[string]string{"checked": "yes"}, entry.Metadata)
+		assert.Equal("zip-2", string(data))
+	}
+	assert.True(errors.Is(store.UpdateMetadata(ctx, s, "missing", nil), store.ErrNotFound))
+
+	entry, err = s.Stat(ctx, "3001")
+	if assert.NoError(err) {
+		assert.Equal(int64(3001), entry.DownloadID)
+	}
+
+	entries, err := s.List(ctx)
+	if assert.NoError(err) && assert.Len(entries, 2) {
+		assert.Equal("3001", entries[0].Key)
+		assert.Equal("3002", entries[1].Key)
+	}
+
+	assert.NoError(s.Delete(ctx, "3001"))
+	assert.True(errors.Is(s.Delete(ctx, "3001"), store.ErrNotFound))
+	_, _, err = s.Get(ctx, "3001")
+	assert.True(errors.Is(err, store.ErrNotFound))
+	_, err = s.Stat(ctx, "3001")
+	assert.True(errors.Is(err, store.ErrNotFound))
+}
+
+func TestDirStore(t *testing.T) {
+	s, err := store.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, store.NewMemoryStore())
+}
+
+func TestRetentionStoreInterface(t *testing.T) {
+	testStore(t, store.NewRetentionStore(store.NewMemoryStore(), store.RetentionPolicy{}))
+}
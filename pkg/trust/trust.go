@@ -0,0 +1,398 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package trust provides the ANAF certificates needed for offline validation
+// of the signatures of the downloaded invoices. The certificates are embedded
+// in the package, and can be refreshed without a library release by
+// downloading them to an override directory (see Update and Load).
+package trust
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"embed"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/internal/helpers"
+)
+
+const (
+	// EnvTrustDir is the environment variable used for setting the override
+	// directory if no directory is given to Load.
+	EnvTrustDir = "EFACTURA_TRUST_DIR"
+
+	// SourceEmbedded is the Source of the embedded certificates.
+	SourceEmbedded = "embedded"
+
+	// maxCertificateSize is the maximum size of a downloaded certificate
+	// file.
+	maxCertificateSize = 1 << 20
+)
+
+//go:embed certs
+var embeddedCerts embed.FS
+
+// Certificate is a trusted certificate along with its origin.
+type Certificate struct {
+	// Name is the name of the file storing the certificate.
+	Name string
+	// Source is SourceEmbedded for the embedded certificates, or the path of
+	// the override directory.
+	Source string
+	// Certificate is the parsed certificate.
+	Certificate *x509.Certificate
+}
+
+// Store is a set of trusted certificates.
+type Store struct {
+	certs []Certificate
+}
+
+// Certificates returns the certificates from the store, sorted by name.
+func (s *Store) Certificates() []Certificate {
+	return append([]Certificate(nil), s.certs...)
+}
+
+// Len returns the number of certificates from the store.
+func (s *Store) Len() int {
+	return len(s.certs)
+}
+
+// CertPool returns a x509.CertPool with all the certificates from the store.
+func (s *Store) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range s.certs {
+		pool.AddCert(c.Certificate)
+	}
+	return pool
+}
+
+// Embedded returns a Store with only the certificates embedded in the
+// library.
+func Embedded() (*Store, error) {
+	files, err := loadFS(embeddedCerts, "certs", SourceEmbedded)
+	if err != nil {
+		return nil, err
+	}
+	return newStore(files), nil
+}
+
+// Load returns a Store with the embedded certificates, overridden by the
+// certificates from dir. A certificate file from dir replaces the embedded
+// certificate file with the same name. If dir is empty, the directory from
+// the EFACTURA_TRUST_DIR environment variable is used, if set. A missing
+// override directory is not an error.
+func Load(dir string) (*Store, error) {
+	files, err := loadFS(embeddedCerts, "certs", SourceEmbedded)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = os.Getenv(EnvTrustDir)
+	}
+	if dir != "" {
+		override, err := loadFS(os.DirFS(dir), ".", dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for name, certs := range override {
+			files[name] = certs
+		}
+	}
+	return newStore(files), nil
+}
+
+// LoadDefault returns a Store with the embedded certificates, overridden by
+// the certificates from the directory from the EFACTURA_TRUST_DIR environment
+// variable, or else from DefaultDir (where the CLI update-trust command
+// writes the certificates). If there is no user config directory, only the
+// embedded certificates are used.
+func LoadDefault() (*Store, error) {
+	dir := os.Getenv(EnvTrustDir)
+	if dir == "" {
+		defaultDir, err := DefaultDir()
+		if err != nil {
+			return Embedded()
+		}
+		dir = defaultDir
+	}
+	return Load(dir)
+}
+
+// DefaultDir returns the default override directory used by the CLI
+// (<user config dir>/e-factura/trust).
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "e-factura", "trust"), nil
+}
+
+func newStore(files map[string][]Certificate) *Store {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := new(Store)
+	for _, name := range names {
+		s.certs = append(s.certs, files[name]...)
+	}
+	return s
+}
+
+// isCertificateFile returns true if the name has one of the extensions used
+// for certificate files.
+func isCertificateFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pem", ".crt", ".cer":
+		return true
+	}
+	return false
+}
+
+// loadFS loads the certificates from all the certificate files from the dir
+// of fsys. The certificates are grouped by file name.
+func loadFS(fsys fs.FS, dir, source string) (map[string][]Certificate, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]Certificate)
+	for _, entry := range entries {
+		if entry.IsDir() || !isCertificateFile(entry.Name()) {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		certs, err := ParseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("trust: %s: %s: %w", source, entry.Name(), err)
+		}
+		for _, cert := range certs {
+			files[entry.Name()] = append(files[entry.Name()], Certificate{
+				Name:        entry.Name(),
+				Source:      source,
+				Certificate: cert,
+			})
+		}
+	}
+	return files, nil
+}
+
+// ParseCertificates parses all the PEM encoded certificates from data. If
+// data is not PEM encoded, a single DER encoded certificate is parsed.
+func ParseCertificates(data []byte) (certs []*x509.Certificate, err error) {
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) > 0 {
+		return certs, nil
+	}
+
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("no valid certificate found: %w", err)
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
+// Fingerprint returns the hex encoded SHA-256 fingerprint of the
+// certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Source is a location from where a certificate file is downloaded.
+type Source struct {
+	// Name is the name of the certificate file written in the override
+	// directory. If the name has no certificate file extension, the ".pem"
+	// extension is added.
+	Name string
+	// URL is the https URL of the certificate (PEM or DER encoded).
+	URL string
+	// SHA256Fingerprints are the expected SHA-256 fingerprints (see
+	// Fingerprint) of the certificates from URL. If set, every downloaded
+	// certificate must have one of these fingerprints.
+	SHA256Fingerprints []string
+}
+
+// DefaultSources are the ANAF root and intermediate certificates used by
+// the CLI update-trust command when no source is given. Each source has the
+// same name as the embedded certificate file it refreshes, and is pinned to
+// the fingerprints of the embedded certificates.
+var DefaultSources = []Source{}
+
+// checkFingerprints checks that all the certificates have one of the
+// expected fingerprints. No fingerprints means no check.
+func checkFingerprints(certs []*x509.Certificate, fingerprints []string) error {
+	if len(fingerprints) == 0 {
+		return nil
+	}
+	for _, cert := range certs {
+		fingerprint := Fingerprint(cert)
+		var found bool
+		for _, expected := range fingerprints {
+			if strings.EqualFold(strings.ReplaceAll(expected, ":", ""), fingerprint) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("certificate %s has an unexpected SHA-256 fingerprint %s",
+				cert.Subject, fingerprint)
+		}
+	}
+	return nil
+}
+
+// UpdateConfig is the config used by Update.
+type UpdateConfig struct {
+	// HTTPClient is the client used for downloading the certificates. The
+	// default is http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// UpdateConfigOption allows gradually modifying a UpdateConfig
+type UpdateConfigOption func(*UpdateConfig)
+
+// UpdateHTTPClient sets the http.Client used for downloading the
+// certificates.
+func UpdateHTTPClient(client *http.Client) UpdateConfigOption {
+	return func(c *UpdateConfig) {
+		c.HTTPClient = client
+	}
+}
+
+// Update downloads the certificates from the given sources and writes them
+// PEM encoded in the override directory dir (created if missing). Only https
+// sources are allowed, and the certificates must match the pinned
+// fingerprints of the source, if any. All the certificates are downloaded and
+// checked before writing any file, so a failed update leaves the directory
+// unchanged. It returns the paths of the written files.
+func Update(ctx context.Context, dir string, sources []Source, opts ...UpdateConfigOption) (paths []string, err error) {
+	cfg := UpdateConfig{
+		HTTPClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if dir == "" {
+		return nil, errors.New("trust: update: missing directory")
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("trust: update: no sources")
+	}
+
+	type downloadedFile struct {
+		name string
+		data []byte
+	}
+	var files []downloadedFile
+	for _, source := range sources {
+		name := source.Name
+		if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			return nil, fmt.Errorf("trust: update: invalid name %q", source.Name)
+		}
+		if !isCertificateFile(name) {
+			name += ".pem"
+		}
+		certs, err := download(ctx, cfg.HTTPClient, source.URL)
+		if err != nil {
+			return nil, fmt.Errorf("trust: update: %s: %w", source.Name, err)
+		}
+		if err := checkFingerprints(certs, source.SHA256Fingerprints); err != nil {
+			return nil, fmt.Errorf("trust: update: %s: %w", source.Name, err)
+		}
+		var data []byte
+		for _, cert := range certs {
+			data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+		files = append(files, downloadedFile{name: name, data: data})
+	}
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		filePath := filepath.Join(dir, file.name)
+		if err = helpers.WriteFileAtomic(filePath, file.data, 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, filePath)
+	}
+	return paths, nil
+}
+
+func download(ctx context.Context, client *http.Client, sourceURL string) ([]*x509.Certificate, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("insecure url %s, only https is allowed", sourceURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.Request != nil && resp.Request.URL.Scheme != "https" {
+		return nil, fmt.Errorf("insecure redirect to %s, only https is allowed", resp.Request.URL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCertificateSize))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificates(data)
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package trust_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/trust"
+)
+
+func newTestCertificate(t *testing.T, commonName string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestParseCertificates(t *testing.T) {
+	assert := assert.New(t)
+
+	der := newTestCertificate(t, "test")
+	certs, err := trust.ParseCertificates(der)
+	if assert.NoError(err) && assert.Len(certs, 1) {
+		assert.Equal("test", certs[0].Subject.CommonName)
+	}
+
+	pemData := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newTestCertificate(t, "test2")})...)
+	certs, err = trust.ParseCertificates(pemData)
+	assert.NoError(err)
+	assert.Len(certs, 2)
+
+	_, err = trust.ParseCertificates([]byte("not a certificate"))
+	assert.Error(err)
+}
+
+func TestUpdateAndLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	der := newTestCertificate(t, "anaf-test")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cert.cer", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(der)
+	})
+	mux.HandleFunc("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	client := trust.UpdateHTTPClient(server.Client())
+
+	embedded, err := trust.Embedded()
+	if !assert.NoError(err) {
+		return
+	}
+
+	dir := filepath.Join(t.TempDir(), "trust")
+	_, err = trust.Update(context.Background(), dir, []trust.Source{
+		{Name: "anaf", URL: server.URL + "/cert.cer"},
+		{Name: "bad", URL: server.URL + "/invalid"},
+	}, client)
+	assert.Error(err)
+	_, err = os.Stat(dir)
+	assert.True(os.IsNotExist(err), "a failed update must not write any file")
+
+	_, err = trust.Update(context.Background(), dir, []trust.Source{
+		{Name: "../anaf", URL: server.URL + "/cert.cer"},
+	}, client)
+	assert.Error(err)
+
+	// Only https sources are allowed.
+	_, err = trust.Update(context.Background(), dir, []trust.Source{
+		{Name: "anaf", URL: "http" + strings.TrimPrefix(server.URL, "https") + "/cert.cer"},
+	}, client)
+	assert.ErrorContains(err, "only https is allowed")
+
+	// The certificates must match the pinned fingerprints.
+	_, err = trust.Update(context.Background(), dir, []trust.Source{
+		{Name: "anaf", URL: server.URL + "/cert.cer", SHA256Fingerprints: []string{strings.Repeat("00", 32)}},
+	}, client)
+	assert.ErrorContains(err, "unexpected SHA-256 fingerprint")
+	_, err = os.Stat(dir)
+	assert.True(os.IsNotExist(err), "a failed update must not write any file")
+
+	cert, err := x509.ParseCertificate(der)
+	if !assert.NoError(err) {
+		return
+	}
+	paths, err := trust.Update(context.Background(), dir, []trust.Source{
+		{Name: "anaf", URL: server.URL + "/cert.cer", SHA256Fingerprints: []string{trust.Fingerprint(cert)}},
+	}, client)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]string{filepath.Join(dir, "anaf.pem")}, paths)
+
+	store, err := trust.Load(dir)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(embedded.Len()+1, store.Len())
+	var found bool
+	for _, c := range store.Certificates() {
+		if c.Name == "anaf.pem" {
+			found = true
+			assert.Equal(dir, c.Source)
+			assert.Equal("anaf-test", c.Certificate.Subject.CommonName)
+		}
+	}
+	assert.True(found)
+	assert.NotNil(store.CertPool())
+
+	t.Setenv(trust.EnvTrustDir, dir)
+	store, err = trust.Load("")
+	if assert.NoError(err) {
+		assert.Equal(embedded.Len()+1, store.Len())
+	}
+
+	store, err = trust.Load(filepath.Join(dir, "missing"))
+	if assert.NoError(err) {
+		assert.Equal(embedded.Len(), store.Len())
+	}
+}
+
+func TestEmbeddedCertificatesPinned(t *testing.T) {
+	assert := assert.New(t)
+
+	embedded, err := trust.Embedded()
+	if !assert.NoError(err) {
+		return
+	}
+	// Every embedded certificate must be refreshed by a default source
+	// pinned to its fingerprint.
+	for _, c := range embedded.Certificates() {
+		var pinned bool
+		for _, source := range trust.DefaultSources {
+			if source.Name != c.Name && source.Name+".pem" != c.Name {
+				continue
+			}
+			for _, fingerprint := range source.SHA256Fingerprints {
+				pinned = pinned || strings.EqualFold(fingerprint, trust.Fingerprint(c.Certificate))
+			}
+		}
+		assert.True(pinned, "embedded certificate %s (%s) is not pinned by a default source", c.Name, c.Certificate.Subject)
+	}
+	for _, source := range trust.DefaultSources {
+		assert.True(strings.HasPrefix(source.URL, "https://"), "default source %s is not https", source.Name)
+		assert.NotEmpty(source.SHA256Fingerprints, "default source %s is not pinned", source.Name)
+	}
+}
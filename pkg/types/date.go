@@ -15,6 +15,8 @@
 package types
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/printesoi/xml-go"
@@ -100,6 +102,54 @@ func (dt *Date) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (dt *Date) UnmarshalXMLAttr(attr xml.Attr) error {
+	t, err := itime.ParseInRomania(time.DateOnly, attr.Value)
+	if err != nil {
+		return err
+	}
+
+	*dt = Date{Time: t}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface. The date is marshaled
+// as a JSON string in the YYYY-MM-DD format, or as null if the date is not
+// initialized.
+func (d Date) MarshalJSON() ([]byte, error) {
+	if !d.IsInitialized() {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.Quote(d.Format(time.DateOnly))), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Both the
+// YYYY-MM-DD format and the RFC 3339 format of time.Time are accepted. A
+// JSON null leaves d unchanged.
+func (dt *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	sd, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("types: invalid date %s", data)
+	}
+	if len(sd) == len(time.DateOnly) {
+		t, err := itime.ParseInRomania(time.DateOnly, sd)
+		if err != nil {
+			return err
+		}
+		*dt = Date{Time: t}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, sd)
+	if err != nil {
+		return err
+	}
+	*dt = MakeDateFromTime(t)
+	return nil
+}
+
 // Ptr is a helper to return a *Date in contexts where a pointer is needed.
 func (d Date) Ptr() *Date {
 	return &d
@@ -142,3 +192,30 @@ func (d DateTime) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
 		Value: v,
 	}, nil
 }
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (dt *DateTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var sd string
+	if err := d.DecodeElement(&sd, &start); err != nil {
+		return err
+	}
+
+	t, err := itime.ParseInRomania(xsDateTimeFmt, sd)
+	if err != nil {
+		return err
+	}
+
+	*dt = DateTime{Time: t}
+	return nil
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (dt *DateTime) UnmarshalXMLAttr(attr xml.Attr) error {
+	t, err := itime.ParseInRomania(xsDateTimeFmt, attr.Value)
+	if err != nil {
+		return err
+	}
+
+	*dt = DateTime{Time: t}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/printesoi/xml-go"
+	"github.com/stretchr/testify/assert"
+
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+)
+
+func TestDateJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	type dates struct {
+		Date     Date  `json:"date"`
+		Optional *Date `json:"optional,omitempty"`
+	}
+
+	data, err := json.Marshal(dates{Date: MakeDate(2024, time.March, 1)})
+	if assert.NoError(err) {
+		assert.Equal(`{"date":"2024-03-01"}`, string(data))
+	}
+	data, err = json.Marshal(dates{})
+	if assert.NoError(err) {
+		assert.Equal(`{"date":null}`, string(data))
+	}
+
+	var d dates
+	if assert.NoError(json.Unmarshal([]byte(`{"date":"2024-03-01","optional":"2024-02-29T23:30:00Z"}`), &d)) {
+		assert.Equal(MakeDate(2024, time.March, 1), d.Date)
+		// 2024-03-01 01:30 in Romania.
+		assert.Equal(NewDate(2024, time.March, 1), d.Optional)
+	}
+	d = dates{}
+	if assert.NoError(json.Unmarshal([]byte(`{"date":null}`), &d)) {
+		assert.False(d.Date.IsInitialized())
+	}
+	assert.Error(json.Unmarshal([]byte(`{"date":"01.03.2024"}`), &d))
+	assert.Error(json.Unmarshal([]byte(`{"date":20240301}`), &d))
+}
+
+func TestMakeDateFromStringLeapDays(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, input := range []string{"2024-02-29", "2000-02-29", "2400-02-29"} {
+		d, err := MakeDateFromString(input)
+		if assert.NoError(err, input) {
+			assert.Equal(input, d.Format(time.DateOnly))
+			assert.Equal(time.February, d.Month(), input)
+			assert.Equal(29, d.Day(), input)
+		}
+	}
+	for _, input := range []string{
+		"2023-02-29", "1900-02-29", "2100-02-29", "2024-02-30", "2024-04-31",
+		"2024-13-01", "2024-00-10", "2024-1-1", "24-01-01", "2024/01/01",
+		"2024-01-01T00:00:00", "", " 2024-01-01",
+	} {
+		_, err := MakeDateFromString(input)
+		assert.Error(err, input)
+	}
+
+	// MakeDate normalizes the out of range values, like time.Date.
+	assert.Equal("2023-03-01", MakeDate(2023, time.February, 29).Format(time.DateOnly))
+	assert.Equal("2024-02-29", MakeDate(2024, time.March, 0).Format(time.DateOnly))
+	assert.Equal("2025-01-01", MakeDate(2024, time.December, 32).Format(time.DateOnly))
+}
+
+func TestMakeDateFromTimeMidnight(t *testing.T) {
+	if itime.RoZoneLocation == time.UTC {
+		t.Skip("the Europe/Bucharest location is not available")
+	}
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		expected string
+	}{
+		// Romania is UTC+2 in winter (EET) and UTC+3 in summer (EEST).
+		{"winter before midnight", time.Date(2024, time.January, 1, 21, 59, 59, 999999999, time.UTC), "2024-01-01"},
+		{"winter midnight", time.Date(2024, time.January, 1, 22, 0, 0, 0, time.UTC), "2024-01-02"},
+		{"summer before midnight", time.Date(2024, time.July, 1, 20, 59, 59, 0, time.UTC), "2024-07-01"},
+		{"summer midnight", time.Date(2024, time.July, 1, 21, 0, 0, 0, time.UTC), "2024-07-02"},
+		{"new year", time.Date(2023, time.December, 31, 22, 0, 0, 0, time.UTC), "2024-01-01"},
+		{"leap day", time.Date(2024, time.February, 28, 22, 0, 0, 0, time.UTC), "2024-02-29"},
+		{"after leap day", time.Date(2024, time.February, 29, 22, 0, 0, 0, time.UTC), "2024-03-01"},
+		// 2024-03-31 03:00 EET became 04:00 EEST.
+		{"DST start", time.Date(2024, time.March, 31, 1, 0, 0, 0, time.UTC), "2024-03-31"},
+		{"DST start midnight", time.Date(2024, time.March, 31, 21, 0, 0, 0, time.UTC), "2024-04-01"},
+		// 2024-10-27 04:00 EEST became 03:00 EET.
+		{"DST end before midnight", time.Date(2024, time.October, 27, 21, 59, 59, 0, time.UTC), "2024-10-27"},
+		{"DST end midnight", time.Date(2024, time.October, 27, 22, 0, 0, 0, time.UTC), "2024-10-28"},
+		{"other time zone", time.Date(2024, time.March, 1, 0, 30, 0, 0, time.FixedZone("CET", 3600)), "2024-03-01"},
+		{"other time zone previous day", time.Date(2024, time.March, 1, 0, 30, 0, 0, time.FixedZone("WET", 0)), "2024-03-01"},
+		{"other time zone next day", time.Date(2024, time.February, 29, 23, 30, 0, 0, time.FixedZone("WET", 0)), "2024-03-01"},
+	}
+	for _, tt := range tests {
+		d := MakeDateFromTime(tt.t)
+		assert.Equal(tt.expected, d.Format(time.DateOnly), tt.name)
+		assert.Equal(itime.RoZoneLocation, d.Location(), tt.name)
+		hour, min, sec := d.Clock()
+		assert.Equal(0, hour+min+sec+d.Nanosecond(), tt.name)
+		assert.Equal(d, *NewDateFromTime(tt.t), tt.name)
+	}
+
+	// A date is the same day regardless of the time zone of the input.
+	assert.Equal(MakeDate(2024, time.March, 31), MakeDateFromTime(itime.Date(2024, time.March, 31, 23, 59, 59, 0)))
+}
+
+func TestDateXML(t *testing.T) {
+	assert := assert.New(t)
+
+	type dates struct {
+		XMLName  xml.Name `xml:"Dates"`
+		Date     Date     `xml:"Date"`
+		Optional *Date    `xml:"Optional,omitempty"`
+		Attr     Date     `xml:"date,attr"`
+	}
+
+	v := dates{
+		Date:     MakeDate(2024, time.February, 29),
+		Optional: NewDate(2024, time.December, 31),
+		Attr:     MakeDate(2000, time.January, 1),
+	}
+	data, err := xml.Marshal(v)
+	if !assert.NoError(err) {
+		return
+	}
+	const expected = `<Dates date="2000-01-01"><Date>2024-02-29</Date><Optional>2024-12-31</Optional></Dates>`
+	assert.Equal(expected, string(data))
+
+	var parsed dates
+	if assert.NoError(xml.Unmarshal(data, &parsed)) {
+		assert.Equal(v.Date, parsed.Date)
+		assert.Equal(v.Optional, parsed.Optional)
+		assert.Equal(v.Attr, parsed.Attr)
+		data2, err := xml.Marshal(parsed)
+		if assert.NoError(err) {
+			assert.Equal(expected, string(data2))
+		}
+	}
+
+	for _, input := range []string{
+		`<Dates><Date>2023-02-29</Date></Dates>`,
+		`<Dates><Date>29.02.2024</Date></Dates>`,
+		`<Dates><Date></Date></Dates>`,
+		`<Dates><Date>2024-02-29T00:00:00</Date></Dates>`,
+		`<Dates date="2024-02-30"><Date>2024-02-29</Date></Dates>`,
+	} {
+		parsed = dates{}
+		assert.Error(xml.Unmarshal([]byte(input), &parsed), input)
+	}
+}
+
+func TestDateJSONTimeZones(t *testing.T) {
+	if itime.RoZoneLocation == time.UTC {
+		t.Skip("the Europe/Bucharest location is not available")
+	}
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected Date
+	}{
+		{`"2024-02-29"`, MakeDate(2024, time.February, 29)},
+		{`"2024-02-29T23:30:00+02:00"`, MakeDate(2024, time.February, 29)},
+		{`"2024-02-29T21:59:59Z"`, MakeDate(2024, time.February, 29)},
+		{`"2024-02-29T22:00:00Z"`, MakeDate(2024, time.March, 1)},
+		{`"2024-07-01T00:00:00+03:00"`, MakeDate(2024, time.July, 1)},
+		{`"2024-06-30T23:59:59+02:00"`, MakeDate(2024, time.July, 1)},
+	}
+	for _, tt := range tests {
+		var d Date
+		if assert.NoError(json.Unmarshal([]byte(tt.input), &d), tt.input) {
+			assert.Equal(tt.expected, d, tt.input)
+			data, err := json.Marshal(d)
+			if assert.NoError(err, tt.input) {
+				assert.Equal(`"`+tt.expected.Format(time.DateOnly)+`"`, string(data), tt.input)
+			}
+		}
+	}
+
+	var d Date
+	assert.Error(json.Unmarshal([]byte(`"2023-02-29"`), &d))
+	assert.Error(json.Unmarshal([]byte(`"2024-02-29T24:00:00Z"`), &d))
+	assert.Error(json.Unmarshal([]byte(`"2024-02-29 10:00:00"`), &d))
+	assert.Error(json.Unmarshal([]byte(`""`), &d))
+}
+
+func TestDateTimeXML(t *testing.T) {
+	assert := assert.New(t)
+
+	type times struct {
+		XMLName xml.Name `xml:"Times"`
+		Time    DateTime `xml:"Time"`
+		Attr    DateTime `xml:"time,attr"`
+	}
+	data, err := xml.Marshal(times{
+		Time: MakeDateTime(2024, time.February, 29, 23, 59, 59, 999999999),
+		Attr: MakeDateTime(2024, time.March, 1, 0, 0, 0, 0),
+	})
+	if !assert.NoError(err) {
+		return
+	}
+	// Fractional seconds are not marshaled.
+	const expected = `<Times time="2024-03-01T00:00:00"><Time>2024-02-29T23:59:59</Time></Times>`
+	assert.Equal(expected, string(data))
+
+	var parsed times
+	if assert.NoError(xml.Unmarshal(data, &parsed)) {
+		assert.Equal(MakeDateTime(2024, time.February, 29, 23, 59, 59, 0), parsed.Time)
+		assert.Equal(MakeDateTime(2024, time.March, 1, 0, 0, 0, 0), parsed.Attr)
+		data2, err := xml.Marshal(parsed)
+		if assert.NoError(err) {
+			assert.Equal(expected, string(data2))
+		}
+	}
+	assert.Error(xml.Unmarshal([]byte(`<Times time="2024-03-01T00:00:00"><Time>2024-02-29</Time></Times>`), &parsed))
+	assert.Error(xml.Unmarshal([]byte(`<Times time="2024-03-01T24:00:00"><Time>2024-02-29T23:59:59</Time></Times>`), &parsed))
+}
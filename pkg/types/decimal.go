@@ -15,6 +15,11 @@
 package types
 
 import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
 	"github.com/printesoi/xml-go"
 	"github.com/shopspring/decimal"
 )
@@ -111,6 +116,85 @@ func (d *Decimal) UnmarshalText(text []byte) error {
 	return d.Decimal.UnmarshalText(text)
 }
 
+// DecimalJSONFormat controls how Decimal values are marshaled to JSON.
+// Decimals are always marshaled as JSON strings to avoid the precision loss
+// of floating point numbers in JSON decoders.
+type DecimalJSONFormat struct {
+	// Precision is the number of decimals the value is rounded to (halves
+	// are rounded away from zero). A negative value disables rounding.
+	Precision int32
+	// TrailingZeros, if true, pads the value with zeros to exactly Precision
+	// decimals (eg. "1.50" for Precision 2). Otherwise trailing zeros are
+	// trimmed (eg. "1.5"). Ignored if Precision is negative.
+	TrailingZeros bool
+}
+
+// DefaultDecimalJSONFormat is the default DecimalJSONFormat: no rounding and
+// trailing zeros trimmed, ie. the value is marshaled without any loss.
+var DefaultDecimalJSONFormat = DecimalJSONFormat{
+	Precision: -1,
+}
+
+var decimalJSONFormat atomic.Pointer[DecimalJSONFormat]
+
+func init() {
+	SetDecimalJSONFormat(DefaultDecimalJSONFormat)
+}
+
+// SetDecimalJSONFormat sets the DecimalJSONFormat used when marshaling
+// Decimal values to JSON. This function is safe for concurrent use, but
+// should usually be called only once, at init time.
+func SetDecimalJSONFormat(format DecimalJSONFormat) {
+	decimalJSONFormat.Store(&format)
+}
+
+// GetDecimalJSONFormat returns the DecimalJSONFormat used when marshaling
+// Decimal values to JSON.
+func GetDecimalJSONFormat() DecimalJSONFormat {
+	return *decimalJSONFormat.Load()
+}
+
+// Format returns the string representation of d using the format.
+func (f DecimalJSONFormat) Format(d Decimal) string {
+	if f.Precision < 0 {
+		return d.String()
+	}
+	if f.TrailingZeros {
+		return d.StringFixed(f.Precision)
+	}
+	return d.Round(f.Precision).String()
+}
+
+// MarshalJSON implements the json.Marshaler interface. The decimal is
+// marshaled as a JSON string, formatted with the DecimalJSONFormat (see
+// SetDecimalJSONFormat).
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(GetDecimalJSONFormat().Format(d))), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Both JSON strings
+// ("1.50") and JSON numbers (1.50) are accepted. A JSON null leaves d
+// unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	str := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if str, err = strconv.Unquote(str); err != nil {
+			return fmt.Errorf("types: invalid decimal %s: %w", data, err)
+		}
+	}
+	v, err := NewFromString(str)
+	if err != nil {
+		return fmt.Errorf("types: invalid decimal %s: %w", data, err)
+	}
+	*d = v
+	return nil
+}
+
 // Add returns d + d2.
 func (d Decimal) Add(d2 Decimal) Decimal {
 	return DD(d.Decimal.Add(d2.Decimal))
@@ -185,6 +269,116 @@ func (d Decimal) AsAmount() Decimal {
 	return DD(d.Decimal.Round(2))
 }
 
+// RoundingMode is the mode used for rounding a Decimal to a given scale (see
+// Decimal.RoundWithMode).
+type RoundingMode int
+
+const (
+	// RoundingModeHalfUp rounds to the nearest value, with halves rounded
+	// away from zero (eg. 2.5 -> 3, -2.5 -> -3). This is the mode used by
+	// Round and AsAmount.
+	RoundingModeHalfUp RoundingMode = iota
+	// RoundingModeHalfEven rounds to the nearest value, with halves rounded
+	// to the nearest even digit (eg. 2.5 -> 2, 3.5 -> 4), also known as
+	// banker's rounding.
+	RoundingModeHalfEven
+	// RoundingModeTruncate discards the extra digits, ie. rounds towards
+	// zero (eg. 2.59 -> 2.5, -2.59 -> -2.5 for scale 1).
+	RoundingModeTruncate
+	// RoundingModeCeil rounds towards positive infinity.
+	RoundingModeCeil
+	// RoundingModeFloor rounds towards negative infinity.
+	RoundingModeFloor
+)
+
+// String returns the name of the rounding mode.
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundingModeHalfUp:
+		return "half-up"
+	case RoundingModeHalfEven:
+		return "half-even"
+	case RoundingModeTruncate:
+		return "truncate"
+	case RoundingModeCeil:
+		return "ceil"
+	case RoundingModeFloor:
+		return "floor"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// RoundWithMode rounds the decimal to scale decimal places using the given
+// rounding mode. If scale < 0, the integer part is rounded to a multiple of
+// 10^(-scale).
+//
+// Example:
+//
+//	D(2.345).RoundWithMode(2, RoundingModeHalfUp).String()   // output: "2.35"
+//	D(2.345).RoundWithMode(2, RoundingModeHalfEven).String() // output: "2.34"
+//	D(2.349).RoundWithMode(2, RoundingModeTruncate).String() // output: "2.34"
+func (d Decimal) RoundWithMode(scale int32, mode RoundingMode) Decimal {
+	switch mode {
+	case RoundingModeHalfEven:
+		return DD(d.Decimal.RoundBank(scale))
+	case RoundingModeTruncate:
+		return DD(d.Decimal.RoundDown(scale))
+	case RoundingModeCeil:
+		return DD(d.Decimal.RoundCeil(scale))
+	case RoundingModeFloor:
+		return DD(d.Decimal.RoundFloor(scale))
+	}
+	return DD(d.Decimal.Round(scale))
+}
+
+// RoundHalfUp rounds the decimal to scale decimal places, with halves
+// rounded away from zero. It's the same as Round.
+func (d Decimal) RoundHalfUp(scale int32) Decimal {
+	return d.RoundWithMode(scale, RoundingModeHalfUp)
+}
+
+// RoundHalfEven rounds the decimal to scale decimal places, with halves
+// rounded to the nearest even digit (banker's rounding).
+func (d Decimal) RoundHalfEven(scale int32) Decimal {
+	return d.RoundWithMode(scale, RoundingModeHalfEven)
+}
+
+// HasScale returns true if the decimal can be represented with at most
+// scale decimal places without rounding (eg. 1.50 has scale 1).
+func (d Decimal) HasScale(scale int32) bool {
+	return d.Decimal.Equal(d.Decimal.RoundDown(scale))
+}
+
+// MulRound returns d * d2 rounded to scale decimal places using the given
+// rounding mode. The product is computed exactly, so the result is rounded
+// only once (eg. quantity * price for a line amount).
+func (d Decimal) MulRound(d2 Decimal, scale int32, mode RoundingMode) Decimal {
+	return d.Mul(d2).RoundWithMode(scale, mode)
+}
+
+// divExactPrecision is the maximum number of decimal places of a quotient
+// computed by DivExact.
+const divExactPrecision = 32
+
+// DivExact returns d / d2 and true if the quotient can be represented
+// exactly (with at most 32 decimal places), otherwise the quotient rounded
+// to 32 decimal places and false (eg. 1 / 3). Unlike Div, the result never
+// silently loses precision. It panics if d2 is zero.
+func (d Decimal) DivExact(d2 Decimal) (Decimal, bool) {
+	q := d.Decimal.DivRound(d2.Decimal, divExactPrecision)
+	return DD(q), q.Mul(d2.Decimal).Equal(d.Decimal)
+}
+
+// Sum returns the exact sum of the given decimals, or Zero if none is
+// given.
+func Sum(values ...Decimal) Decimal {
+	sum := Zero
+	for _, v := range values {
+		sum = sum.Add(v)
+	}
+	return sum
+}
+
 // Cmp compares the numbers represented by d and d2 and returns:
 //
 //	-1 if d <  d2
@@ -0,0 +1,361 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/printesoi/xml-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimalMarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+	defer SetDecimalJSONFormat(GetDecimalJSONFormat())
+
+	type amounts struct {
+		Amount   Decimal  `json:"amount"`
+		Optional *Decimal `json:"optional,omitempty"`
+	}
+	v := amounts{Amount: D(1.505)}
+
+	tests := []struct {
+		format   DecimalJSONFormat
+		expected string
+	}{
+		{DefaultDecimalJSONFormat, `{"amount":"1.505"}`},
+		{DecimalJSONFormat{Precision: 2}, `{"amount":"1.51"}`},
+		{DecimalJSONFormat{Precision: 4}, `{"amount":"1.505"}`},
+		{DecimalJSONFormat{Precision: 4, TrailingZeros: true}, `{"amount":"1.5050"}`},
+		{DecimalJSONFormat{Precision: 0, TrailingZeros: true}, `{"amount":"2"}`},
+	}
+	for _, test := range tests {
+		SetDecimalJSONFormat(test.format)
+		data, err := json.Marshal(v)
+		if assert.NoError(err) {
+			assert.Equal(test.expected, string(data))
+		}
+	}
+
+	SetDecimalJSONFormat(DefaultDecimalJSONFormat)
+	data, err := json.Marshal(amounts{Amount: D(10), Optional: D(0.1).Ptr()})
+	if assert.NoError(err) {
+		assert.Equal(`{"amount":"10","optional":"0.1"}`, string(data))
+	}
+}
+
+func TestDecimalUnmarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var v struct {
+		Amount   Decimal  `json:"amount"`
+		Optional *Decimal `json:"optional"`
+	}
+	for _, input := range []string{
+		`{"amount":"123.4500"}`,
+		`{"amount":123.4500}`,
+		`{"amount":1.2345e2}`,
+	} {
+		v.Amount = Zero
+		if assert.NoError(json.Unmarshal([]byte(input), &v), input) {
+			assert.True(v.Amount.Equal(D(123.45)), input)
+		}
+	}
+
+	v.Amount = D(1)
+	if assert.NoError(json.Unmarshal([]byte(`{"amount":null,"optional":null}`), &v)) {
+		assert.True(v.Amount.Equal(D(1)))
+		assert.Nil(v.Optional)
+	}
+
+	assert.Error(json.Unmarshal([]byte(`{"amount":"1,5"}`), &v))
+	assert.Error(json.Unmarshal([]byte(`{"amount":""}`), &v))
+	assert.Error(json.Unmarshal([]byte(`{"amount":true}`), &v))
+}
+
+func TestNewFromString(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5e3", "1500"},
+		{"1E-2", "0.01"},
+		{"-1.2e+2", "-120"},
+		{"1.2345e2", "123.45"},
+		{"+1.5", "1.5"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{"0012.3400", "12.34"},
+	}
+	for _, tt := range tests {
+		d, err := NewFromString(tt.input)
+		if assert.NoError(err, tt.input) {
+			assert.Equal(tt.expected, d.String(), tt.input)
+			assert.True(d.IsInitialized(), tt.input)
+		}
+	}
+
+	for _, input := range []string{
+		"", " 1", "1 ", "1,5", "1.2.3", "1e", "e5", "1e2.5", "0x10", "1_000",
+		"NaN", "Inf", "-Inf", "--1", "1-",
+	} {
+		d, err := NewFromString(input)
+		assert.Error(err, input)
+		assert.False(d.IsInitialized(), input)
+	}
+}
+
+func TestDecimalHugePrecision(t *testing.T) {
+	assert := assert.New(t)
+
+	const huge = "123456789012345678901234567890.12345678901234567890123456789"
+	d, err := NewFromString(huge)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(huge, d.String())
+	assert.Equal("123456789012345678901234567890.12", d.AsAmount().String())
+	assert.Equal("123456789012345678901234567890", d.Round(0).String())
+	assert.Equal("123456789012345678901234567891", d.Add(D(0.5)).Round(0).String())
+	assert.Equal("246913578024691357802469135780.24691357802469135780246913578",
+		d.Add(d).String())
+	assert.True(d.Sub(d).Equal(Zero))
+
+	tiny, err := NewFromString("1e-400")
+	if assert.NoError(err) {
+		assert.Equal("0."+strings.Repeat("0", 399)+"1", tiny.String())
+		assert.Equal(1, tiny.Cmp(Zero))
+		assert.True(tiny.AsAmount().Equal(Zero))
+	}
+	big, err := NewFromString("1e400")
+	if assert.NoError(err) {
+		assert.Equal("1"+strings.Repeat("0", 400), big.String())
+	}
+
+	// Floats are converted with the shortest representation, not the exact
+	// binary value.
+	assert.Equal("0.3", D(0.1).Add(D(0.2)).String())
+	assert.Equal("0.1", D(0.1).String())
+	assert.Equal("0.3333333333333333", D(1).Div(D(3)).String())
+}
+
+func TestDecimalNegativeZero(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, input := range []string{"-0", "-0.00", "-0e5", "+0"} {
+		d, err := NewFromString(input)
+		if !assert.NoError(err, input) {
+			continue
+		}
+		assert.Equal("0", d.String(), input)
+		assert.True(d.Equal(Zero), input)
+		assert.Equal(0, d.Sign(), input)
+		// An explicitly parsed zero is initialized, unlike the zero value.
+		assert.True(d.IsInitialized(), input)
+	}
+
+	small := D(-0.004)
+	assert.Equal("0", small.AsAmount().String())
+	assert.Equal("0.00", small.Round(2).StringFixed(2))
+	assert.Equal("0", Zero.Neg().String())
+
+	var uninitialized Decimal
+	assert.False(uninitialized.IsInitialized())
+	assert.Equal("0", uninitialized.String())
+	assert.True(uninitialized.Equal(Zero))
+	var nilDecimal *Decimal
+	assert.False(nilDecimal.IsInitialized())
+	assert.True(nilDecimal.Value().Equal(Zero))
+}
+
+func TestDecimalRound(t *testing.T) {
+	assert := assert.New(t)
+
+	// Halves are rounded away from zero.
+	tests := []struct {
+		input    string
+		places   int32
+		expected string
+	}{
+		{"2.5", 0, "3"},
+		{"-2.5", 0, "-3"},
+		{"1.005", 2, "1.01"},
+		{"-1.005", 2, "-1.01"},
+		{"1.0049999", 2, "1"},
+		{"545", -1, "550"},
+		{"-545", -1, "-550"},
+		{"0.5", 0, "1"},
+	}
+	for _, tt := range tests {
+		d, err := NewFromString(tt.input)
+		if assert.NoError(err, tt.input) {
+			assert.Equal(tt.expected, d.Round(tt.places).String(), "%s.Round(%d)", tt.input, tt.places)
+		}
+	}
+	assert.Equal("1.23", D(1.239).Truncate(2).String())
+	assert.Equal("-1.23", D(-1.239).Truncate(2).String())
+}
+
+func TestDecimalRoundWithMode(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		scale    int32
+		mode     RoundingMode
+		expected string
+	}{
+		{"2.345", 2, RoundingModeHalfUp, "2.35"},
+		{"-2.345", 2, RoundingModeHalfUp, "-2.35"},
+		{"2.345", 2, RoundingModeHalfEven, "2.34"},
+		{"2.355", 2, RoundingModeHalfEven, "2.36"},
+		{"-2.345", 2, RoundingModeHalfEven, "-2.34"},
+		{"2.3451", 2, RoundingModeHalfEven, "2.35"},
+		{"2.349", 2, RoundingModeTruncate, "2.34"},
+		{"-2.349", 2, RoundingModeTruncate, "-2.34"},
+		{"2.341", 2, RoundingModeCeil, "2.35"},
+		{"-2.349", 2, RoundingModeCeil, "-2.34"},
+		{"2.349", 2, RoundingModeFloor, "2.34"},
+		{"-2.341", 2, RoundingModeFloor, "-2.35"},
+		{"125", -1, RoundingModeHalfEven, "120"},
+		{"2.5", 0, RoundingMode(42), "3"},
+	}
+	for _, tt := range tests {
+		d, err := NewFromString(tt.input)
+		if assert.NoError(err, tt.input) {
+			assert.Equal(tt.expected, d.RoundWithMode(tt.scale, tt.mode).String(),
+				"%s.RoundWithMode(%d, %s)", tt.input, tt.scale, tt.mode)
+		}
+	}
+	assert.Equal("1.01", D(1.005).RoundHalfUp(2).String())
+	assert.Equal("1", D(1.005).RoundHalfEven(2).String())
+	assert.Equal("RoundingMode(42)", RoundingMode(42).String())
+}
+
+func TestDecimalExactArithmetic(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(D(1.5).HasScale(1))
+	assert.True(D(1.5).HasScale(2))
+	assert.False(D(1.505).HasScale(2))
+	assert.True(D(1200).HasScale(-2))
+
+	// 3 * 33.335 = 100.005, rounded once.
+	assert.Equal("100.01", D(3).MulRound(D(33.335), 2, RoundingModeHalfUp).String())
+	assert.Equal("100", D(3).MulRound(D(33.335), 2, RoundingModeHalfEven).String())
+
+	q, exact := D(1).DivExact(D(8))
+	assert.True(exact)
+	assert.Equal("0.125", q.String())
+	q, exact = D(100).DivExact(D(3))
+	assert.False(exact)
+	assert.True(q.Mul(D(3)).Sub(D(100)).Abs().LessThan(D(1e-30).Decimal))
+
+	assert.Equal("0.3", Sum(D(0.1), D(0.2)).String())
+	assert.True(Sum().IsZero())
+}
+
+func TestDecimalMarshalXML(t *testing.T) {
+	assert := assert.New(t)
+
+	type amounts struct {
+		XMLName  xml.Name `xml:"Amounts"`
+		Amount   Decimal  `xml:"Amount"`
+		Optional *Decimal `xml:"Optional,omitempty"`
+		Attr     *Decimal `xml:"attr,attr,omitempty"`
+	}
+
+	v := amounts{Amount: D(1.50), Optional: D(-0.25).Ptr(), Attr: D(1e-3).Ptr()}
+	data, err := xml.Marshal(v)
+	if !assert.NoError(err) {
+		return
+	}
+	const expected = `<Amounts attr="0.001"><Amount>1.5</Amount><Optional>-0.25</Optional></Amounts>`
+	assert.Equal(expected, string(data))
+
+	// Marshaling must be stable across an unmarshal/marshal round trip.
+	var parsed amounts
+	if assert.NoError(xml.Unmarshal(data, &parsed)) {
+		assert.True(parsed.Amount.Equal(v.Amount))
+		data2, err := xml.Marshal(parsed)
+		if assert.NoError(err) {
+			assert.Equal(expected, string(data2))
+		}
+	}
+
+	data, err = xml.Marshal(amounts{})
+	if assert.NoError(err) {
+		assert.Equal(`<Amounts><Amount>0</Amount></Amounts>`, string(data))
+	}
+
+	if assert.NoError(xml.Unmarshal([]byte(`<Amounts><Amount>1.5E2</Amount></Amounts>`), &parsed)) {
+		assert.Equal("150", parsed.Amount.String())
+	}
+	assert.Error(xml.Unmarshal([]byte(`<Amounts><Amount>1,5</Amount></Amounts>`), &parsed))
+	// The chardata is not trimmed.
+	assert.Error(xml.Unmarshal([]byte(`<Amounts><Amount> 1.5 </Amount></Amounts>`), &parsed))
+}
+
+func TestDecimalJSONStability(t *testing.T) {
+	assert := assert.New(t)
+	defer SetDecimalJSONFormat(GetDecimalJSONFormat())
+	SetDecimalJSONFormat(DefaultDecimalJSONFormat)
+
+	for _, input := range []string{
+		"0", "-0.00", "1.50", "-123.456", "1e-20",
+		"123456789012345678901234567890.12345678901234567890123456789",
+	} {
+		d, err := NewFromString(input)
+		if !assert.NoError(err, input) {
+			continue
+		}
+		data, err := json.Marshal(d)
+		if !assert.NoError(err, input) {
+			continue
+		}
+		var parsed Decimal
+		if assert.NoError(json.Unmarshal(data, &parsed), input) {
+			assert.True(parsed.Equal(d), input)
+			data2, err := json.Marshal(parsed)
+			if assert.NoError(err, input) {
+				assert.Equal(string(data), string(data2), input)
+			}
+		}
+	}
+
+	var v Decimal
+	if assert.NoError(json.Unmarshal([]byte(`-0`), &v)) {
+		assert.Equal(`"0"`, mustMarshalJSON(t, v))
+	}
+	if assert.NoError(json.Unmarshal([]byte(`"1e3"`), &v)) {
+		assert.Equal(`"1000"`, mustMarshalJSON(t, v))
+	}
+	assert.Error(json.Unmarshal([]byte(`"1e"`), &v))
+	assert.Error(json.Unmarshal([]byte(`"NaN"`), &v))
+	assert.Error(json.Unmarshal([]byte(`{}`), &v))
+}
+
+func mustMarshalJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("error marshaling %v: %v", v, err)
+	}
+	return string(data)
+}
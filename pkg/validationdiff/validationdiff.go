@@ -0,0 +1,289 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package validationdiff runs a corpus of XML documents through a local
+// validator (eg. a schematron engine) and through the remote ANAF validator
+// (Client.ValidateXML) and diffs the failed rules, so that divergences are
+// detected when ANAF updates the validation rules. It's intended for tests
+// and dry runs; the remote validator is a public API, so nothing is uploaded.
+package validationdiff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// UnidentifiedRule is the rule ID used for the findings without a rule ID (eg.
+// XML schema errors).
+const UnidentifiedRule = "UNIDENTIFIED"
+
+// regexRuleID matches the rule IDs in the validation messages, eg.
+// "[BR-RO-010]" or "[BR-CO-15]".
+var regexRuleID = regexp.MustCompile(`\[([A-Z][A-Z0-9]*(?:-[A-Z0-9]+)+)\]`)
+
+// Finding is a failed validation rule.
+type Finding struct {
+	// RuleID is the ID of the failed rule (eg. "BR-RO-010"), or
+	// UnidentifiedRule.
+	RuleID string
+	// Message is the validation message.
+	Message string
+}
+
+// Validator validates a XML document, returning the failed rules. An empty
+// list means the document is valid. An error means the validation could not
+// be performed.
+type Validator interface {
+	Validate(ctx context.Context, xmlData []byte) ([]Finding, error)
+}
+
+// ValidatorFunc is an adapter to allow using ordinary functions as
+// Validators.
+type ValidatorFunc func(ctx context.Context, xmlData []byte) ([]Finding, error)
+
+// Validate implements the Validator interface.
+func (f ValidatorFunc) Validate(ctx context.Context, xmlData []byte) ([]Finding, error) {
+	return f(ctx, xmlData)
+}
+
+// ParseFindings parses the findings from validation messages, extracting the
+// rule IDs. A message referencing multiple rules results in a finding for
+// each rule.
+func ParseFindings(messages ...string) (findings []Finding) {
+	for _, message := range messages {
+		matches := regexRuleID.FindAllStringSubmatch(message, -1)
+		if len(matches) == 0 {
+			findings = append(findings, Finding{RuleID: UnidentifiedRule, Message: message})
+			continue
+		}
+		for _, match := range matches {
+			findings = append(findings, Finding{RuleID: match[1], Message: message})
+		}
+	}
+	return
+}
+
+// RemoteValidator returns a Validator that uses the ANAF validation API
+// (Client.ValidateXML) with the given standard.
+func RemoteValidator(client *efactura.Client, st efactura.ValidateStandard) Validator {
+	return ValidatorFunc(func(ctx context.Context, xmlData []byte) ([]Finding, error) {
+		res, err := client.ValidateXML(ctx, bytes.NewReader(xmlData), st)
+		if err != nil {
+			return nil, err
+		}
+		if res.IsOk() {
+			return nil, nil
+		}
+		messages := make([]string, len(res.Messages))
+		for i, m := range res.Messages {
+			messages[i] = m.Message
+		}
+		findings := ParseFindings(messages...)
+		if len(findings) == 0 {
+			// Invalid without any message, so we don't lose the outcome.
+			findings = []Finding{{RuleID: UnidentifiedRule, Message: string(res.State)}}
+		}
+		return findings, nil
+	})
+}
+
+// OfflineValidator returns a Validator that parses the documents as
+// efactura.Invoice and validates them with efactura.ValidateInvoiceOffline.
+// Diffing it against the RemoteValidator shows the rules not (yet)
+// implemented by the offline validator.
+func OfflineValidator() Validator {
+	return ValidatorFunc(func(ctx context.Context, xmlData []byte) (findings []Finding, err error) {
+		var invoice efactura.Invoice
+		if err = efactura.UnmarshalInvoice(xmlData, &invoice); err != nil {
+			return
+		}
+		for _, e := range efactura.ValidateInvoiceOffline(invoice) {
+			findings = append(findings, Finding{RuleID: e.Rule, Message: e.Error()})
+		}
+		return
+	})
+}
+
+// Diff is the difference between the local and the remote validation of a
+// document.
+type Diff struct {
+	// Name is the name of the document in the corpus.
+	Name string
+	// LocalOnly are the rules failed only by the local validator.
+	LocalOnly []string
+	// RemoteOnly are the rules failed only by the remote validator.
+	RemoteOnly []string
+	// Both are the rules failed by both validators.
+	Both []string
+	// LocalErr is the error returned by the local validator.
+	LocalErr error
+	// RemoteErr is the error returned by the remote validator.
+	RemoteErr error
+}
+
+// Diverged returns true if the validators disagree on the failed rules. A
+// document that could not be validated by one of the validators is not
+// considered diverged (see Failed).
+func (d Diff) Diverged() bool {
+	return !d.Failed() && (len(d.LocalOnly) > 0 || len(d.RemoteOnly) > 0)
+}
+
+// Failed returns true if any of the validators returned an error.
+func (d Diff) Failed() bool {
+	return d.LocalErr != nil || d.RemoteErr != nil
+}
+
+// String implements the fmt.Stringer interface.
+func (d Diff) String() string {
+	var parts []string
+	if d.LocalErr != nil {
+		parts = append(parts, fmt.Sprintf("local error: %v", d.LocalErr))
+	}
+	if d.RemoteErr != nil {
+		parts = append(parts, fmt.Sprintf("remote error: %v", d.RemoteErr))
+	}
+	if len(d.LocalOnly) > 0 {
+		parts = append(parts, "local only: "+strings.Join(d.LocalOnly, ", "))
+	}
+	if len(d.RemoteOnly) > 0 {
+		parts = append(parts, "remote only: "+strings.Join(d.RemoteOnly, ", "))
+	}
+	if len(parts) == 0 {
+		return d.Name + ": ok"
+	}
+	return d.Name + ": " + strings.Join(parts, "; ")
+}
+
+// Compare validates a document with both validators and diffs the failed
+// rules.
+func Compare(ctx context.Context, name string, xmlData []byte, local, remote Validator) Diff {
+	d := Diff{Name: name}
+	localFindings, localErr := local.Validate(ctx, xmlData)
+	remoteFindings, remoteErr := remote.Validate(ctx, xmlData)
+	d.LocalErr, d.RemoteErr = localErr, remoteErr
+	if d.Failed() {
+		return d
+	}
+
+	localRules, remoteRules := ruleSet(localFindings), ruleSet(remoteFindings)
+	for rule := range localRules {
+		if remoteRules[rule] {
+			d.Both = append(d.Both, rule)
+		} else {
+			d.LocalOnly = append(d.LocalOnly, rule)
+		}
+	}
+	for rule := range remoteRules {
+		if !localRules[rule] {
+			d.RemoteOnly = append(d.RemoteOnly, rule)
+		}
+	}
+	sort.Strings(d.LocalOnly)
+	sort.Strings(d.RemoteOnly)
+	sort.Strings(d.Both)
+	return d
+}
+
+func ruleSet(findings []Finding) map[string]bool {
+	rules := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		rules[f.RuleID] = true
+	}
+	return rules
+}
+
+// Report is the result of running a corpus through both validators.
+type Report struct {
+	Diffs []Diff
+}
+
+// Diverged returns the diffs of the documents for which the validators
+// disagree.
+func (r Report) Diverged() (diffs []Diff) {
+	for _, d := range r.Diffs {
+		if d.Diverged() {
+			diffs = append(diffs, d)
+		}
+	}
+	return
+}
+
+// Failed returns the diffs of the documents that could not be validated.
+func (r Report) Failed() (diffs []Diff) {
+	for _, d := range r.Diffs {
+		if d.Failed() {
+			diffs = append(diffs, d)
+		}
+	}
+	return
+}
+
+// Err returns an error listing the diverged and failed documents, or nil if
+// the validators agree on all the documents. Useful in tests.
+func (r Report) Err() error {
+	var lines []string
+	for _, d := range r.Diffs {
+		if d.Diverged() || d.Failed() {
+			lines = append(lines, d.String())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return fmt.Errorf("validation diff: %d of %d documents:\n%s", len(lines), len(r.Diffs), strings.Join(lines, "\n"))
+}
+
+// WriteText writes a human readable report to w, one line per document.
+func (r Report) WriteText(w io.Writer) error {
+	for _, d := range r.Diffs {
+		if _, err := fmt.Fprintln(w, d.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run validates all the XML documents (*.xml files) from corpus with both
+// validators. The documents are validated sequentially in lexical order. An
+// error is returned only if the corpus cannot be read or ctx is cancelled;
+// validation errors are reported in the diffs.
+func Run(ctx context.Context, corpus fs.FS, local, remote Validator) (report Report, err error) {
+	err = fs.WalkDir(corpus, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".xml" {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		xmlData, err := fs.ReadFile(corpus, name)
+		if err != nil {
+			return err
+		}
+		report.Diffs = append(report.Diffs, Compare(ctx, name, xmlData, local, remote))
+		return nil
+	})
+	return
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package validationdiff_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/validationdiff"
+)
+
+func TestParseFindings(t *testing.T) {
+	assert := assert.New(t)
+
+	findings := validationdiff.ParseFindings(
+		"E: validari globale  eroare: [BR-RO-010] Numarul facturii trebuie sa contina cel putin un caracter numeric",
+		"E: [BR-CO-15] [BR-CO-16] totaluri invalide",
+		"E: cvc-complex-type.2.4.a: Invalid content",
+	)
+	if assert.Len(findings, 4) {
+		assert.Equal("BR-RO-010", findings[0].RuleID)
+		assert.Equal("BR-CO-15", findings[1].RuleID)
+		assert.Equal("BR-CO-16", findings[2].RuleID)
+		assert.Equal(validationdiff.UnidentifiedRule, findings[3].RuleID)
+		assert.Equal("E: cvc-complex-type.2.4.a: Invalid content", findings[3].Message)
+	}
+}
+
+// newRemoteValidator returns a remote validator whose ANAF validation API
+// returns the messages from the given map (keyed by the request body).
+func newRemoteValidator(t *testing.T, messages map[string][]string) validationdiff.Validator {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/FCTEL/rest/validare/FACT1", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		res := map[string]any{"stare": "ok", "trace_id": "trace"}
+		if msgs := messages[string(body)]; len(msgs) > 0 {
+			res["stare"] = "nok"
+			var list []map[string]string
+			for _, m := range msgs {
+				list = append(list, map[string]string{"message": m})
+			}
+			res["Messages"] = list
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	publicApiClient, err := client.NewPublicApiClient(client.PublicApiClientBaseURL(server.URL + "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := efactura.NewClient(efactura.ClientPublicApiClient(publicApiClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validationdiff.RemoteValidator(c, efactura.ValidateStandardFACT1)
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	corpus := fstest.MapFS{
+		"valid.xml":         {Data: []byte("<valid/>")},
+		"same.xml":          {Data: []byte("<same/>")},
+		"diverged.xml":      {Data: []byte("<diverged/>")},
+		"sub/local-err.xml": {Data: []byte("<local-err/>")},
+		"README.md":         {Data: []byte("not a document")},
+	}
+	remote := newRemoteValidator(t, map[string][]string{
+		"<same/>":     {"E: [BR-RO-010] numar factura"},
+		"<diverged/>": {"E: [BR-RO-010] numar factura", "E: [BR-RO-A999] regula noua"},
+	})
+	local := validationdiff.ValidatorFunc(func(ctx context.Context, xmlData []byte) ([]validationdiff.Finding, error) {
+		switch string(xmlData) {
+		case "<same/>":
+			return []validationdiff.Finding{{RuleID: "BR-RO-010"}}, nil
+		case "<diverged/>":
+			return []validationdiff.Finding{{RuleID: "BR-RO-010"}, {RuleID: "BR-RO-020"}}, nil
+		case "<local-err/>":
+			return nil, errors.New("unsupported document")
+		}
+		return nil, nil
+	})
+
+	report, err := validationdiff.Run(context.Background(), corpus, local, remote)
+	if !assert.NoError(err) || !assert.Len(report.Diffs, 4) {
+		return
+	}
+	names := make([]string, len(report.Diffs))
+	for i, d := range report.Diffs {
+		names[i] = d.Name
+	}
+	assert.Equal([]string{"diverged.xml", "same.xml", "sub/local-err.xml", "valid.xml"}, names)
+
+	diverged := report.Diverged()
+	if assert.Len(diverged, 1) {
+		d := diverged[0]
+		assert.Equal([]string{"BR-RO-010"}, d.Both)
+		assert.Equal([]string{"BR-RO-020"}, d.LocalOnly)
+		assert.Equal([]string{"BR-RO-A999"}, d.RemoteOnly)
+		assert.Equal("diverged.xml: local only: BR-RO-020; remote only: BR-RO-A999", d.String())
+	}
+	failed := report.Failed()
+	if assert.Len(failed, 1) {
+		assert.Equal("sub/local-err.xml", failed[0].Name)
+	}
+	assert.Equal("same.xml: ok", report.Diffs[1].String())
+
+	err = report.Err()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "2 of 4 documents")
+	}
+	var buf strings.Builder
+	assert.NoError(report.WriteText(&buf))
+	assert.Equal(4, strings.Count(buf.String(), "\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = validationdiff.Run(ctx, corpus, local, remote)
+	assert.True(errors.Is(err, context.Canceled))
+}
+
+func TestOfflineValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	xmlData, err := efactura.Invoice{ID: "FCT"}.XML()
+	if !assert.NoError(err) {
+		return
+	}
+	findings, err := validationdiff.OfflineValidator().Validate(context.Background(), xmlData)
+	if assert.NoError(err) {
+		rules := make([]string, len(findings))
+		for i, f := range findings {
+			rules[i] = f.RuleID
+		}
+		assert.Contains(rules, "BR-RO-010")
+		assert.Contains(rules, "BR-16")
+	}
+
+	_, err = validationdiff.OfflineValidator().Validate(context.Background(), []byte("not xml"))
+	assert.Error(err)
+}
@@ -0,0 +1,254 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package vatinfo
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+)
+
+// Company is the registration data of a company returned by the web
+// service. The dates are strings in the YYYY-MM-DD format, empty if not
+// set.
+type Company struct {
+	General         GeneralData     `json:"date_generale"`
+	VAT             VATRegistration `json:"inregistrare_scop_Tva"`
+	VATOnCollection VATOnCollection `json:"inregistrare_RTVAI"`
+	Inactive        InactiveStatus  `json:"stare_inactiv"`
+	SplitVAT        SplitVAT        `json:"inregistrare_SplitTVA"`
+	// HeadOffice is the address of the registered office.
+	HeadOffice Address `json:"adresa_sediu_social"`
+	// FiscalAddress is the fiscal domicile.
+	FiscalAddress Address `json:"adresa_domiciliu_fiscal"`
+}
+
+// GeneralData is the general registration data of a company.
+type GeneralData struct {
+	CUI                int64  `json:"cui"`
+	Date               string `json:"data"`
+	Name               string `json:"denumire"`
+	Address            string `json:"adresa"`
+	RegistrationNumber string `json:"nrRegCom"`
+	Phone              string `json:"telefon"`
+	Fax                string `json:"fax"`
+	PostalCode         string `json:"codPostal"`
+	Act                string `json:"act"`
+	RegistrationStatus string `json:"stare_inregistrare"`
+	RegistrationDate   string `json:"data_inregistrare"`
+	CAEN               string `json:"cod_CAEN"`
+	IBAN               string `json:"iban"`
+	// EFactura is true if the company is registered in the RO e-Factura
+	// registry.
+	EFactura bool `json:"statusRO_e_Factura"`
+	// EFacturaRegistrationDate is the date of the registration in the RO
+	// e-Factura registry.
+	EFacturaRegistrationDate string `json:"data_inreg_Reg_RO_e_Factura"`
+	TaxOffice                string `json:"organFiscalCompetent"`
+	OwnershipForm            string `json:"forma_de_proprietate"`
+	OrganizationForm         string `json:"forma_organizare"`
+	LegalForm                string `json:"forma_juridica"`
+}
+
+// VATRegistration is the registration for VAT purposes.
+type VATRegistration struct {
+	// Registered is true if the company is registered for VAT purposes at
+	// the requested date.
+	Registered bool        `json:"scpTVA"`
+	Periods    []VATPeriod `json:"perioade_TVA"`
+}
+
+// VATPeriod is a period of registration for VAT purposes.
+type VATPeriod struct {
+	StartDate        string `json:"data_inceput_ScpTVA"`
+	EndDate          string `json:"data_sfarsit_ScpTVA"`
+	CancellationDate string `json:"data_anul_imp_ScpTVA"`
+	Message          string `json:"mesaj_ScpTVA"`
+}
+
+// VATOnCollection is the registration in the VAT on collection (TVA la
+// încasare) registry.
+type VATOnCollection struct {
+	StartDate   string `json:"dataInceputTvaInc"`
+	EndDate     string `json:"dataSfarsitTvaInc"`
+	UpdateDate  string `json:"dataActualizareTvaInc"`
+	PublishDate string `json:"dataPublicareTvaInc"`
+	ActType     string `json:"tipActTvaInc"`
+	Active      bool   `json:"statusTvaIncasare"`
+}
+
+// InactiveStatus is the inactive (or deregistered) status of a company.
+type InactiveStatus struct {
+	InactivationDate   string `json:"dataInactivare"`
+	ReactivationDate   string `json:"dataReactivare"`
+	PublishDate        string `json:"dataPublicare"`
+	DeregistrationDate string `json:"dataRadiere"`
+	Inactive           bool   `json:"statusInactivi"`
+}
+
+// SplitVAT is the registration in the split VAT registry.
+type SplitVAT struct {
+	StartDate        string `json:"dataInceputSplitTVA"`
+	CancellationDate string `json:"dataAnulareSplitTVA"`
+	Active           bool   `json:"statusSplitTVA"`
+}
+
+// Address is an address of a company.
+type Address struct {
+	Street         string
+	Number         string
+	Locality       string
+	LocalityCode   string
+	County         string
+	CountyCode     string
+	CountyAutoCode string
+	Country        string
+	Details        string
+	PostalCode     string
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The web service
+// uses the same keys for both addresses, prefixed with "s" for the head
+// office and with "d" for the fiscal domicile (eg. sdenumire_Strada and
+// ddenumire_Strada). The codes can be either strings or numbers.
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	fields := map[string]*string{
+		"denumire_Strada":     &a.Street,
+		"numar_Strada":        &a.Number,
+		"denumire_Localitate": &a.Locality,
+		"cod_Localitate":      &a.LocalityCode,
+		"denumire_Judet":      &a.County,
+		"cod_Judet":           &a.CountyCode,
+		"cod_JudetAuto":       &a.CountyAutoCode,
+		"tara":                &a.Country,
+		"detalii_Adresa":      &a.Details,
+		"cod_Postal":          &a.PostalCode,
+	}
+	*a = Address{}
+	for key, raw := range m {
+		if len(key) < 2 {
+			continue
+		}
+		field, ok := fields[key[1:]]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			// Not a string, keep the literal (eg. a number), unless null.
+			if s = string(raw); s == "null" {
+				s = ""
+			}
+		}
+		*field = strings.TrimSpace(s)
+	}
+	return nil
+}
+
+// IsZero returns true if the address has no street and no locality.
+func (a Address) IsZero() bool {
+	return a.Street == "" && a.Locality == ""
+}
+
+var sectorRegexp = regexp.MustCompile(`(?i)sector\s*([1-6])`)
+
+// PostalAddress converts the address to an efactura.PostalAddress with the
+// CIUS-RO codes: the country subdivision is the ISO 3166-2:RO code of the
+// county, and the city name of an address in Bucharest is the sector
+// (SECTOR1 - SECTOR6).
+func (a Address) PostalAddress() efactura.PostalAddress {
+	address := efactura.PostalAddress{
+		Line1:      a.Street,
+		Line2:      a.Details,
+		CityName:   a.Locality,
+		PostalZone: a.PostalCode,
+		Country:    efactura.CountryRO,
+	}
+	if a.Number != "" {
+		address.Line1 = strings.TrimSpace(address.Line1 + " Nr. " + a.Number)
+	}
+	if sub := efactura.CountrySubentityType("RO-" + strings.ToUpper(a.CountyAutoCode)); a.CountyAutoCode != "" && sub.IsValid() {
+		address.CountrySubentity = sub
+	} else if sub, ok := efactura.RoCountyNameToCountrySubentity(a.County); ok {
+		address.CountrySubentity = sub
+	}
+	if address.CountrySubentity == efactura.CountrySubentityRO_B {
+		if m := sectorRegexp.FindStringSubmatch(a.Locality); m != nil {
+			address.CityName = "SECTOR" + m[1]
+		}
+	}
+	return address
+}
+
+// CUI returns the CUI of the company.
+func (c Company) CUI() string {
+	return strconv.FormatInt(c.General.CUI, 10)
+}
+
+// VATID returns the VAT identifier of the company (the CUI with the RO
+// prefix), or an empty string if the company is not registered for VAT
+// purposes.
+func (c Company) VATID() string {
+	if !c.VAT.Registered {
+		return ""
+	}
+	return "RO" + c.CUI()
+}
+
+// Address returns the head office address, or the fiscal domicile if the
+// head office address is not set.
+func (c Company) Address() Address {
+	if c.HeadOffice.IsZero() {
+		return c.FiscalAddress
+	}
+	return c.HeadOffice
+}
+
+// CustomerParty returns the company as the Buyer of an invoice: the name,
+// the address (see Address), the VAT identifier (BT-48) if registered for
+// VAT purposes and the legal registration identifier (BT-47). The legal
+// registration identifier is the trade register number for a company
+// registered for VAT purposes, and the CUI otherwise, as required by
+// CIUS-RO when the Buyer has no VAT identifier.
+func (c Company) CustomerParty() efactura.InvoiceCustomerParty {
+	party := efactura.InvoiceCustomerParty{
+		PostalAddress: efactura.MakeInvoiceCustomerPostalAddress(c.Address().PostalAddress()),
+		LegalEntity: efactura.InvoiceCustomerLegalEntity{
+			Name: strings.TrimSpace(c.General.Name),
+		},
+	}
+	if vatID := c.VATID(); vatID != "" {
+		party.TaxScheme = &efactura.InvoicePartyTaxScheme{
+			TaxScheme: efactura.TaxSchemeVAT,
+			CompanyID: vatID,
+		}
+		if c.General.RegistrationNumber != "" {
+			party.LegalEntity.CompanyID = efactura.NewValueWithAttrs(c.General.RegistrationNumber)
+		}
+	} else {
+		party.LegalEntity.CompanyID = efactura.NewValueWithAttrs(c.CUI())
+	}
+	if c.General.Phone != "" {
+		party.Contact = &efactura.InvoiceCustomerContact{Phone: c.General.Phone}
+	}
+	return party
+}
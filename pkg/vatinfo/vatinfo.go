@@ -0,0 +1,261 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package vatinfo implements a client for the public ANAF web service that
+// returns the registration data of Romanian companies (PlatitorTvaRest):
+// name, address, VAT status, VAT on collection, split VAT, inactive status
+// and the RO e-Factura registration. The data can be used to prefill the
+// Buyer of an invoice (see Company.CustomerParty).
+package vatinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	itime "github.com/printesoi/e-factura-go/pkg/time"
+	"github.com/printesoi/e-factura-go/pkg/types"
+)
+
+const (
+	// DefaultEndpoint is the path of the PlatitorTvaRest endpoint, relative
+	// to the base URL of the public APIs (constants.PublicApiBaseURL).
+	DefaultEndpoint = "PlatitorTvaRest/api/v8/ws/tva"
+	// MaxCUIsPerRequest is the maximum number of CUIs accepted by the web
+	// service in a single request. Lookup splits larger lists in batches.
+	MaxCUIsPerRequest = 100
+	// DefaultRequestInterval is the minimum interval between two requests,
+	// since the web service allows at most one request per second.
+	DefaultRequestInterval = time.Second
+)
+
+var (
+	// ErrNotFound is returned by GetCompany if the CUI is not found.
+	ErrNotFound = errors.New("vatinfo: company not found")
+)
+
+// ClientConfig is the config used to create a Client.
+type ClientConfig struct {
+	// PublicApiClient is the client used to make the requests (optional).
+	// If nil, a client for the production public APIs is created.
+	PublicApiClient *client.PublicApiClient
+	// Endpoint is the URL of the web service, absolute or relative to the
+	// base URL of the PublicApiClient. Default is DefaultEndpoint.
+	Endpoint string
+	// RequestInterval is the minimum interval between two requests.
+	// Default is DefaultRequestInterval.
+	RequestInterval time.Duration
+}
+
+// ClientConfigOption allows gradually modifying a ClientConfig.
+type ClientConfigOption func(*ClientConfig)
+
+// ClientPublicApiClient sets the PublicApiClient used to make the requests.
+func ClientPublicApiClient(publicApiClient *client.PublicApiClient) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.PublicApiClient = publicApiClient
+	}
+}
+
+// ClientEndpoint sets the URL of the web service, eg. for a newer version
+// of the API.
+func ClientEndpoint(endpoint string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.Endpoint = endpoint
+	}
+}
+
+// ClientRequestInterval sets the minimum interval between two requests.
+func ClientRequestInterval(interval time.Duration) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.RequestInterval = interval
+	}
+}
+
+// Client is a client for the ANAF PlatitorTvaRest web service. It's safe
+// for concurrent use, the requests are serialized to respect the request
+// interval.
+type Client struct {
+	publicApiClient *client.PublicApiClient
+	endpoint        string
+	interval        time.Duration
+
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+// NewClient creates a new Client.
+func NewClient(opts ...ClientConfigOption) (*Client, error) {
+	cfg := ClientConfig{
+		Endpoint:        DefaultEndpoint,
+		RequestInterval: DefaultRequestInterval,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.RequestInterval < 0 {
+		return nil, fmt.Errorf("vatinfo: invalid request interval %s", cfg.RequestInterval)
+	}
+	if cfg.PublicApiClient == nil {
+		publicApiClient, err := client.NewPublicApiClient()
+		if err != nil {
+			return nil, err
+		}
+		cfg.PublicApiClient = publicApiClient
+	}
+	return &Client{
+		publicApiClient: cfg.PublicApiClient,
+		endpoint:        cfg.Endpoint,
+		interval:        cfg.RequestInterval,
+	}, nil
+}
+
+// LookupResponse is the result of a Lookup.
+type LookupResponse struct {
+	// Found are the companies found, in the order returned by the web
+	// service.
+	Found []Company
+	// NotFound are the CUIs not found.
+	NotFound []string
+}
+
+// Lookup returns the registration data at the given date (today if not
+// initialized) of the companies with the given CUIs. A CUI can have the RO
+// prefix, which is ignored. The CUIs are sent in batches of at most
+// MaxCUIsPerRequest.
+func (c *Client) Lookup(ctx context.Context, date types.Date, cuis ...string) (*LookupResponse, error) {
+	if !date.IsInitialized() {
+		date = types.MakeDateFromTime(itime.Now())
+	}
+	requests := make([]request, 0, len(cuis))
+	for _, cui := range cuis {
+		n, err := ParseCUI(cui)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request{CUI: n, Date: date.Format(time.DateOnly)})
+	}
+
+	res := new(LookupResponse)
+	for start := 0; start < len(requests); start += MaxCUIsPerRequest {
+		end := min(start+MaxCUIsPerRequest, len(requests))
+		batch, err := c.lookup(ctx, requests[start:end])
+		if err != nil {
+			return nil, err
+		}
+		res.Found = append(res.Found, batch.Found...)
+		for _, raw := range batch.NotFound {
+			res.NotFound = append(res.NotFound, strings.Trim(string(raw), `"`))
+		}
+	}
+	return res, nil
+}
+
+// GetCompany returns the registration data at the given date (today if not
+// initialized) of the company with the given CUI. ErrNotFound is returned
+// if the CUI is not found.
+func (c *Client) GetCompany(ctx context.Context, cui string, date types.Date) (*Company, error) {
+	res, err := c.Lookup(ctx, date, cui)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Found) == 0 {
+		return nil, ErrNotFound
+	}
+	return &res.Found[0], nil
+}
+
+// ResolveCompanyName returns the registered name of the company with the
+// given CIF (with or without the RO prefix) at the current date, so the
+// Client can be used as an efactura.CompanyNameResolver. Wrap it with
+// efactura.NewCachedCompanyNameResolver to query each CIF only once.
+// ErrNotFound is returned if the CIF is not found.
+func (c *Client) ResolveCompanyName(ctx context.Context, cif string) (string, error) {
+	company, err := c.GetCompany(ctx, cif, types.Date{})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(company.General.Name), nil
+}
+
+type request struct {
+	CUI  int64  `json:"cui"`
+	Date string `json:"data"`
+}
+
+type response struct {
+	Code     int               `json:"cod"`
+	Message  string            `json:"message"`
+	Found    []Company         `json:"found"`
+	NotFound []json.RawMessage `json:"notFound"`
+}
+
+func (c *Client) lookup(ctx context.Context, requests []request) (*response, error) {
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := c.publicApiClient.NewRequest(ctx, http.MethodPost, c.endpoint, nil, bytes.NewReader(body),
+		client.RequestOptionHeader("Content-Type", "application/json"))
+	if err != nil {
+		return nil, err
+	}
+	res := new(response)
+	if err := c.publicApiClient.DoUnmarshalJSON(req, res, nil); err != nil {
+		return nil, err
+	}
+	if res.Code != http.StatusOK {
+		return nil, fmt.Errorf("vatinfo: request failed: %d %s", res.Code, res.Message)
+	}
+	return res, nil
+}
+
+// wait waits until the request interval since the last request elapsed.
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d := c.interval - time.Since(c.lastRequest); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
+}
+
+// ParseCUI parses a CUI (CIF), with or without the RO prefix.
+func ParseCUI(cui string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(cui))
+	s = strings.TrimSpace(strings.TrimPrefix(s, "RO"))
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 || len(s) > 10 {
+		return 0, fmt.Errorf("vatinfo: invalid CUI %q", cui)
+	}
+	return n, nil
+}
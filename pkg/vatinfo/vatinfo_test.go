@@ -0,0 +1,314 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package vatinfo_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/types"
+	"github.com/printesoi/e-factura-go/pkg/vatinfo"
+)
+
+const testCompanyJSON = `{
+  "date_generale": {
+    "cui": 10000008,
+    "data": "2024-04-01",
+    "denumire": "FURNIZOR SRL",
+    "adresa": "MUNICIPIUL BUCUREŞTI, SECTOR 1, STR. EXEMPLU, NR.1",
+    "nrRegCom": "J40/1/2020",
+    "telefon": "0211234567",
+    "fax": "",
+    "codPostal": "010101",
+    "act": "",
+    "stare_inregistrare": "INREGISTRAT din data 01.01.2020",
+    "data_inregistrare": "2020-01-01",
+    "cod_CAEN": "6201",
+    "iban": "",
+    "statusRO_e_Factura": true,
+    "data_inreg_Reg_RO_e_Factura": "2022-01-01",
+    "organFiscalCompetent": "Administraţia Sector 1 a Finanţelor Publice",
+    "forma_de_proprietate": "PROPR.PRIVATA-CAPITAL PRIVAT AUTOHTON",
+    "forma_organizare": "PERSOANA JURIDICA",
+    "forma_juridica": "SOCIETATE COMERCIALĂ CU RĂSPUNDERE LIMITATĂ"
+  },
+  "inregistrare_scop_Tva": {
+    "scpTVA": true,
+    "perioade_TVA": [{"data_inceput_ScpTVA": "2020-01-01", "data_sfarsit_ScpTVA": "", "data_anul_imp_ScpTVA": "", "mesaj_ScpTVA": ""}]
+  },
+  "inregistrare_RTVAI": {"dataInceputTvaInc": "", "dataSfarsitTvaInc": "", "dataActualizareTvaInc": "", "dataPublicareTvaInc": "", "tipActTvaInc": "", "statusTvaIncasare": false},
+  "stare_inactiv": {"dataInactivare": "", "dataReactivare": "", "dataPublicare": "", "dataRadiere": "", "statusInactivi": false},
+  "inregistrare_SplitTVA": {"dataInceputSplitTVA": "", "dataAnulareSplitTVA": "", "statusSplitTVA": true},
+  "adresa_sediu_social": {
+    "sdenumire_Strada": "Str. Exemplu",
+    "snumar_Strada": "1",
+    "sdenumire_Localitate": "Sector 1 Mun. Bucureşti",
+    "scod_Localitate": 179132,
+    "sdenumire_Judet": "MUNICIPIUL BUCUREŞTI",
+    "scod_Judet": "40",
+    "scod_JudetAuto": "B",
+    "stara": "",
+    "sdetalii_Adresa": "Bl. 3, Ap. 4",
+    "scod_Postal": "010101"
+  },
+  "adresa_domiciliu_fiscal": {
+    "ddenumire_Strada": "Str. Exemplu",
+    "dnumar_Strada": "1",
+    "ddenumire_Localitate": "Sector 1 Mun. Bucureşti",
+    "dcod_Localitate": "179132",
+    "ddenumire_Judet": "MUNICIPIUL BUCUREŞTI",
+    "dcod_Judet": "40",
+    "dcod_JudetAuto": "B",
+    "dtara": "",
+    "ddetalii_Adresa": "",
+    "dcod_Postal": "010101"
+  }
+}`
+
+func setupTestClient(t *testing.T, handler http.HandlerFunc, opts ...vatinfo.ClientConfigOption) *vatinfo.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/PlatitorTvaRest/api/v8/ws/tva", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	publicApiClient, err := client.NewPublicApiClient(
+		client.PublicApiClientBaseURL(server.URL + "/"),
+	)
+	if err != nil {
+		t.Fatalf("error creating public api client: %v", err)
+	}
+	c, err := vatinfo.NewClient(append([]vatinfo.ClientConfigOption{
+		vatinfo.ClientPublicApiClient(publicApiClient),
+		vatinfo.ClientRequestInterval(0),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return c
+}
+
+type testRequest struct {
+	CUI  int64  `json:"cui"`
+	Date string `json:"data"`
+}
+
+func TestGetCompany(t *testing.T) {
+	assert := assert.New(t)
+
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("application/json", r.Header.Get("Content-Type"))
+		var reqs []testRequest
+		if !assert.NoError(json.NewDecoder(r.Body).Decode(&reqs)) {
+			return
+		}
+		assert.Equal([]testRequest{{CUI: 10000008, Date: "2024-04-01"}}, reqs)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"cod": 200, "message": "SUCCESS", "found": [%s], "notFound": []}`, testCompanyJSON)
+	})
+
+	company, err := c.GetCompany(context.Background(), " RO10000008", types.MakeDate(2024, 4, 1))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("10000008", company.CUI())
+	assert.Equal("RO10000008", company.VATID())
+	assert.Equal("FURNIZOR SRL", company.General.Name)
+	assert.True(company.General.EFactura)
+	assert.True(company.SplitVAT.Active)
+	assert.False(company.VATOnCollection.Active)
+	assert.False(company.Inactive.Inactive)
+	assert.Equal("179132", company.HeadOffice.LocalityCode)
+	assert.Equal("B", company.FiscalAddress.CountyAutoCode)
+
+	party := company.CustomerParty()
+	assert.Equal("FURNIZOR SRL", party.LegalEntity.Name)
+	if assert.NotNil(party.LegalEntity.CompanyID) {
+		assert.Equal("J40/1/2020", party.LegalEntity.CompanyID.Value)
+	}
+	if assert.NotNil(party.TaxScheme) {
+		assert.Equal("RO10000008", party.TaxScheme.CompanyID)
+	}
+	address := party.PostalAddress.PostalAddress
+	assert.Equal("Str. Exemplu Nr. 1", address.Line1)
+	assert.Equal("Bl. 3, Ap. 4", address.Line2)
+	assert.Equal(efactura.CountrySubentityRO_B, address.CountrySubentity)
+	assert.Equal(efactura.CityNameROBSector1, address.CityName)
+	assert.Equal("010101", address.PostalZone)
+	assert.Equal(efactura.CountryRO, address.Country)
+}
+
+func TestGetCompanyNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"cod": 200, "message": "SUCCESS", "found": [], "notFound": [123]}`)
+	})
+	_, err := c.GetCompany(context.Background(), "123", types.MakeDate(2024, 4, 1))
+	assert.ErrorIs(err, vatinfo.ErrNotFound)
+
+	_, err = c.GetCompany(context.Background(), "RO12A", types.MakeDate(2024, 4, 1))
+	assert.Error(err)
+}
+
+func TestLookupBatches(t *testing.T) {
+	assert := assert.New(t)
+
+	var batches []int
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var reqs []testRequest
+		if !assert.NoError(json.NewDecoder(r.Body).Decode(&reqs)) {
+			return
+		}
+		batches = append(batches, len(reqs))
+		notFound := make([]string, 0, len(reqs))
+		for _, req := range reqs {
+			notFound = append(notFound, fmt.Sprint(req.CUI))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"cod": 200, "message": "SUCCESS", "found": [], "notFound": [%s]}`, strings.Join(notFound, ","))
+	})
+
+	cuis := make([]string, 0, 150)
+	for i := 1; i <= 150; i++ {
+		cuis = append(cuis, fmt.Sprint(i))
+	}
+	res, err := c.Lookup(context.Background(), types.MakeDate(2024, 4, 1), cuis...)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]int{100, 50}, batches)
+	assert.Equal(cuis, res.NotFound)
+}
+
+func TestLookupError(t *testing.T) {
+	assert := assert.New(t)
+
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"cod": 404, "message": "NOT_FOUND"}`)
+	})
+	_, err := c.Lookup(context.Background(), types.Date{}, "123")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "NOT_FOUND")
+	}
+}
+
+func TestRequestInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	var times []time.Time
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"cod": 200, "message": "SUCCESS", "found": [], "notFound": []}`)
+	}, vatinfo.ClientRequestInterval(50*time.Millisecond))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := c.Lookup(ctx, types.MakeDate(2024, 4, 1), "123")
+		assert.NoError(err)
+	}
+	if assert.Len(times, 2) {
+		assert.GreaterOrEqual(times[1].Sub(times[0]), 50*time.Millisecond)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := c.Lookup(cancelled, types.MakeDate(2024, 4, 1), "123")
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestCustomerPartyNonVATPayer(t *testing.T) {
+	assert := assert.New(t)
+
+	var company vatinfo.Company
+	if !assert.NoError(json.Unmarshal([]byte(testCompanyJSON), &company)) {
+		return
+	}
+	company.VAT.Registered = false
+	company.HeadOffice = vatinfo.Address{}
+	company.FiscalAddress = vatinfo.Address{
+		Street:   "Str. Memorandumului",
+		Number:   "28",
+		Locality: "Mun. Cluj-Napoca",
+		County:   "CLUJ",
+	}
+
+	assert.Equal("", company.VATID())
+	party := company.CustomerParty()
+	assert.Nil(party.TaxScheme)
+	if assert.NotNil(party.LegalEntity.CompanyID) {
+		assert.Equal("10000008", party.LegalEntity.CompanyID.Value)
+	}
+	address := party.PostalAddress.PostalAddress
+	assert.Equal(efactura.CountrySubentityRO_CJ, address.CountrySubentity)
+	assert.Equal("Mun. Cluj-Napoca", address.CityName)
+}
+
+func TestResolveCompanyName(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var reqs []testRequest
+		if !assert.NoError(json.NewDecoder(r.Body).Decode(&reqs)) || !assert.Len(reqs, 1) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if reqs[0].CUI != 10000008 {
+			fmt.Fprintf(w, `{"cod": 200, "message": "SUCCESS", "found": [], "notFound": [%d]}`, reqs[0].CUI)
+			return
+		}
+		fmt.Fprintf(w, `{"cod": 200, "message": "SUCCESS", "found": [%s], "notFound": []}`, testCompanyJSON)
+	})
+
+	ctx := context.Background()
+	resolver := efactura.NewCachedCompanyNameResolver(c)
+	for i := 0; i < 2; i++ {
+		name, err := resolver.ResolveCompanyName(ctx, "RO10000008")
+		if assert.NoError(err) {
+			assert.Equal("FURNIZOR SRL", name)
+		}
+	}
+	assert.Equal(1, requests)
+
+	_, err := resolver.ResolveCompanyName(ctx, "123")
+	assert.ErrorIs(err, vatinfo.ErrNotFound)
+
+	views, err := efactura.EnrichMessages(ctx, []efactura.Message{{
+		Type:    efactura.MessageTypeReceivedInvoice,
+		Details: "Factura cu id_incarcare=5001 emisa de cif_emitent=10000008 pentru cif_beneficiar=123",
+	}}, resolver)
+	assert.Error(err)
+	if assert.Len(views, 1) {
+		assert.Equal("FURNIZOR SRL", views[0].SellerName)
+		assert.Equal("", views[0].BuyerName)
+	}
+}
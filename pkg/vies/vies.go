@@ -0,0 +1,339 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package vies implements a client for the REST API of VIES (the VAT
+// Information Exchange System of the European Commission), used to check
+// the VAT number of an EU customer, eg. before uploading an intra-community
+// supply invoice (VAT category K).
+package vies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	eferrors "github.com/printesoi/e-factura-go/pkg/errors"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the VIES REST API.
+	DefaultBaseURL = "https://ec.europa.eu/taxation_customs/vies/rest-api/"
+
+	checkVATNumberEndpoint = "check-vat-number"
+)
+
+// Error codes returned by VIES.
+const (
+	ErrorCodeInvalidInput              = "INVALID_INPUT"
+	ErrorCodeInvalidRequesterInfo      = "INVALID_REQUESTER_INFO"
+	ErrorCodeServiceUnavailable        = "SERVICE_UNAVAILABLE"
+	ErrorCodeMSUnavailable             = "MS_UNAVAILABLE"
+	ErrorCodeTimeout                   = "TIMEOUT"
+	ErrorCodeVATBlocked                = "VAT_BLOCKED"
+	ErrorCodeIPBlocked                 = "IP_BLOCKED"
+	ErrorCodeGlobalMaxConcurrentReq    = "GLOBAL_MAX_CONCURRENT_REQ"
+	ErrorCodeGlobalMaxConcurrentReqTTL = "GLOBAL_MAX_CONCURRENT_REQ_TIME"
+	ErrorCodeMSMaxConcurrentReq        = "MS_MAX_CONCURRENT_REQ"
+	ErrorCodeMSMaxConcurrentReqTTL     = "MS_MAX_CONCURRENT_REQ_TIME"
+)
+
+// Error is an error returned by VIES.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("vies: %s: %s", e.Code, e.Message)
+	}
+	return "vies: " + e.Code
+}
+
+// Temporary returns true if the error is transient (eg. the member state
+// service is unavailable) and the check can be retried later.
+func (e *Error) Temporary() bool {
+	switch e.Code {
+	case ErrorCodeServiceUnavailable, ErrorCodeMSUnavailable, ErrorCodeTimeout,
+		ErrorCodeGlobalMaxConcurrentReq, ErrorCodeGlobalMaxConcurrentReqTTL,
+		ErrorCodeMSMaxConcurrentReq, ErrorCodeMSMaxConcurrentReqTTL:
+		return true
+	}
+	return false
+}
+
+// ClientConfig is the config used to create a Client.
+type ClientConfig struct {
+	// PublicApiClient is the client used to make the requests (optional).
+	// If nil, a client with the base URL DefaultBaseURL is created.
+	PublicApiClient *client.PublicApiClient
+	// RequesterCountryCode and RequesterVATNumber are the VAT number of
+	// the requester (optional). If set, VIES returns a request identifier
+	// that can be kept as a proof of the check.
+	RequesterCountryCode string
+	RequesterVATNumber   string
+}
+
+// ClientConfigOption allows gradually modifying a ClientConfig.
+type ClientConfigOption func(*ClientConfig)
+
+// ClientPublicApiClient sets the PublicApiClient used to make the requests.
+// The base URL of the client must point to the VIES REST API.
+func ClientPublicApiClient(publicApiClient *client.PublicApiClient) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.PublicApiClient = publicApiClient
+	}
+}
+
+// ClientRequester sets the VAT number of the requester.
+func ClientRequester(countryCode, vatNumber string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.RequesterCountryCode = countryCode
+		c.RequesterVATNumber = vatNumber
+	}
+}
+
+// Client is a client for the VIES REST API.
+type Client struct {
+	publicApiClient      *client.PublicApiClient
+	requesterCountryCode string
+	requesterVATNumber   string
+}
+
+// NewClient creates a new Client.
+func NewClient(opts ...ClientConfigOption) (*Client, error) {
+	var cfg ClientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.PublicApiClient == nil {
+		publicApiClient, err := client.NewPublicApiClient(client.PublicApiClientBaseURL(DefaultBaseURL))
+		if err != nil {
+			return nil, err
+		}
+		cfg.PublicApiClient = publicApiClient
+	}
+	c := &Client{publicApiClient: cfg.PublicApiClient}
+	if cfg.RequesterVATNumber != "" {
+		c.requesterCountryCode, c.requesterVATNumber = normalize(cfg.RequesterCountryCode, cfg.RequesterVATNumber)
+		if c.requesterCountryCode == "" {
+			return nil, errors.New("vies: missing requester country code")
+		}
+	}
+	return c, nil
+}
+
+// CheckResult is the result of a VAT number check.
+type CheckResult struct {
+	// CountryCode is the VIES country code (EL for Greece).
+	CountryCode string
+	VATNumber   string
+	// Valid is true if the VAT number is valid for intra-community
+	// transactions.
+	Valid       bool
+	RequestDate time.Time
+	// RequestIdentifier is the identifier of the request, only returned
+	// if the requester is set.
+	RequestIdentifier string
+	// Name and Address are the registered name and address, if returned
+	// by the member state.
+	Name    string
+	Address string
+}
+
+// VATID returns the VAT identifier (the VAT number with the country code
+// prefix).
+func (r *CheckResult) VATID() string {
+	return r.CountryCode + r.VATNumber
+}
+
+type checkRequest struct {
+	CountryCode              string `json:"countryCode"`
+	VATNumber                string `json:"vatNumber"`
+	RequesterMemberStateCode string `json:"requesterMemberStateCode,omitempty"`
+	RequesterNumber          string `json:"requesterNumber,omitempty"`
+}
+
+type checkResponse struct {
+	CountryCode       string         `json:"countryCode"`
+	VATNumber         string         `json:"vatNumber"`
+	RequestDate       time.Time      `json:"requestDate"`
+	Valid             bool           `json:"valid"`
+	RequestIdentifier string         `json:"requestIdentifier"`
+	Name              string         `json:"name"`
+	Address           string         `json:"address"`
+	ActionSucceed     *bool          `json:"actionSucceed"`
+	ErrorWrappers     []errorWrapper `json:"errorWrappers"`
+}
+
+type errorWrapper struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (r *checkResponse) err() error {
+	if len(r.ErrorWrappers) > 0 {
+		return &Error{Code: r.ErrorWrappers[0].Error, Message: r.ErrorWrappers[0].Message}
+	}
+	if r.ActionSucceed != nil && !*r.ActionSucceed {
+		return &Error{Code: ErrorCodeServiceUnavailable}
+	}
+	return nil
+}
+
+// CheckVATNumber checks the VAT number with the given country code. The VAT
+// number can have the country code prefix, spaces, dots and dashes, which
+// are ignored. A VIES error is returned as an *Error.
+func (c *Client) CheckVATNumber(ctx context.Context, countryCode, vatNumber string) (*CheckResult, error) {
+	countryCode, vatNumber = normalize(countryCode, vatNumber)
+	if countryCode == "" || vatNumber == "" {
+		return nil, fmt.Errorf("vies: invalid VAT number %q", countryCode+vatNumber)
+	}
+	body, err := json.Marshal(checkRequest{
+		CountryCode:              countryCode,
+		VATNumber:                vatNumber,
+		RequesterMemberStateCode: c.requesterCountryCode,
+		RequesterNumber:          c.requesterVATNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.publicApiClient.NewRequest(ctx, http.MethodPost, checkVATNumberEndpoint, nil, bytes.NewReader(body),
+		client.RequestOptionHeader("Content-Type", "application/json"))
+	if err != nil {
+		return nil, err
+	}
+	res := new(checkResponse)
+	if err := c.publicApiClient.DoUnmarshalJSON(req, res, nil); err != nil {
+		// The errors are also returned with a non-success status.
+		var errResp *eferrors.ErrorResponse
+		if errors.As(err, &errResp) && json.Unmarshal(errResp.ResponseBody, res) == nil && res.err() != nil {
+			return nil, res.err()
+		}
+		return nil, err
+	}
+	if err := res.err(); err != nil {
+		return nil, err
+	}
+	return &CheckResult{
+		CountryCode:       res.CountryCode,
+		VATNumber:         res.VATNumber,
+		Valid:             res.Valid,
+		RequestDate:       res.RequestDate,
+		RequestIdentifier: res.RequestIdentifier,
+		Name:              unknownToEmpty(res.Name),
+		Address:           unknownToEmpty(res.Address),
+	}, nil
+}
+
+// CheckVATID checks a VAT identifier with the country code prefix (eg.
+// DE123456789).
+func (c *Client) CheckVATID(ctx context.Context, vatID string) (*CheckResult, error) {
+	vatID = strings.TrimSpace(vatID)
+	if len(vatID) < 3 {
+		return nil, fmt.Errorf("vies: invalid VAT ID %q", vatID)
+	}
+	return c.CheckVATNumber(ctx, vatID[:2], vatID[2:])
+}
+
+// CheckInvoiceCustomer checks the VAT identifier of the Buyer (BT-48) of
+// the invoice.
+func (c *Client) CheckInvoiceCustomer(ctx context.Context, iv efactura.Invoice) (*CheckResult, error) {
+	vatID := iv.CustomerVATID()
+	if vatID == "" {
+		return nil, errors.New("vies: missing Buyer VAT identifier (BT-48)")
+	}
+	return c.CheckVATID(ctx, vatID)
+}
+
+// RequiresCheck returns true if the invoice has lines with the VAT category
+// K (intra-community supply), for which the VAT identifier of the Buyer
+// should be checked.
+func RequiresCheck(iv efactura.Invoice) bool {
+	for _, line := range iv.InvoiceLines {
+		if line.VATCategory() == efactura.TaxCategoryVATExemptIntraCommunitySupply {
+			return true
+		}
+	}
+	for _, tt := range iv.TaxTotal {
+		for _, st := range tt.TaxSubtotals {
+			if st.TaxCategory.ID == efactura.TaxCategoryVATExemptIntraCommunitySupply {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	defaultClientOnce sync.Once
+	defaultClient     *Client
+	defaultClientErr  error
+)
+
+// ValidateEUVatNumber checks the VAT number with the given country code
+// using a default Client, returning true if the VAT number is valid.
+func ValidateEUVatNumber(ctx context.Context, countryCode, vatNumber string) (bool, error) {
+	defaultClientOnce.Do(func() {
+		defaultClient, defaultClientErr = NewClient()
+	})
+	if defaultClientErr != nil {
+		return false, defaultClientErr
+	}
+	res, err := defaultClient.CheckVATNumber(ctx, countryCode, vatNumber)
+	if err != nil {
+		return false, err
+	}
+	return res.Valid, nil
+}
+
+// normalize returns the VIES country code (EL for Greece) and the VAT
+// number without the country code prefix and the separators.
+func normalize(countryCode, vatNumber string) (string, string) {
+	countryCode = strings.ToUpper(strings.TrimSpace(countryCode))
+	if countryCode == "GR" {
+		countryCode = "EL"
+	}
+	vatNumber = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(vatNumber))
+	if countryCode != "" {
+		vatNumber = strings.TrimPrefix(vatNumber, countryCode)
+		if countryCode == "EL" {
+			vatNumber = strings.TrimPrefix(vatNumber, "GR")
+		}
+	}
+	return countryCode, vatNumber
+}
+
+// unknownToEmpty returns an empty string for the placeholder returned by
+// VIES when the data is not available.
+func unknownToEmpty(s string) string {
+	if strings.Trim(s, "- ") == "" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package vies_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/client"
+	"github.com/printesoi/e-factura-go/pkg/efactura"
+	"github.com/printesoi/e-factura-go/pkg/vies"
+)
+
+func setupTestClient(t *testing.T, handler http.HandlerFunc, opts ...vies.ClientConfigOption) *vies.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/check-vat-number", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	publicApiClient, err := client.NewPublicApiClient(
+		client.PublicApiClientBaseURL(server.URL + "/"),
+	)
+	if err != nil {
+		t.Fatalf("error creating public api client: %v", err)
+	}
+	c, err := vies.NewClient(append([]vies.ClientConfigOption{
+		vies.ClientPublicApiClient(publicApiClient),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return c
+}
+
+func TestCheckVATNumber(t *testing.T) {
+	assert := assert.New(t)
+
+	var got map[string]string
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		got = nil
+		assert.NoError(json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		if got["vatNumber"] == "000000000" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"actionSucceed":false,"errorWrappers":[{"error":"MS_UNAVAILABLE"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+  "countryCode": "` + got["countryCode"] + `",
+  "vatNumber": "` + got["vatNumber"] + `",
+  "requestDate": "2024-04-01T10:00:00.000Z",
+  "valid": true,
+  "requestIdentifier": "WAPIAAAAXYZ",
+  "name": "Kunde GmbH",
+  "address": "---"
+}`))
+	}, vies.ClientRequester("ro", "RO10000008"))
+
+	res, err := c.CheckVATNumber(context.Background(), "de", "DE 123.456-789")
+	if assert.NoError(err) {
+		assert.Equal(map[string]string{
+			"countryCode":              "DE",
+			"vatNumber":                "123456789",
+			"requesterMemberStateCode": "RO",
+			"requesterNumber":          "10000008",
+		}, got)
+		assert.True(res.Valid)
+		assert.Equal("DE123456789", res.VATID())
+		assert.Equal("WAPIAAAAXYZ", res.RequestIdentifier)
+		assert.Equal("Kunde GmbH", res.Name)
+		assert.Equal("", res.Address)
+		assert.Equal(2024, res.RequestDate.Year())
+	}
+
+	// Greece uses EL in VIES.
+	if res, err := c.CheckVATID(context.Background(), "GR123456789"); assert.NoError(err) {
+		assert.Equal("EL", got["countryCode"])
+		assert.Equal("EL123456789", res.VATID())
+	}
+
+	_, err = c.CheckVATNumber(context.Background(), "FR", "000000000")
+	var viesErr *vies.Error
+	if assert.True(errors.As(err, &viesErr)) {
+		assert.Equal(vies.ErrorCodeMSUnavailable, viesErr.Code)
+		assert.True(viesErr.Temporary())
+	}
+
+	_, err = c.CheckVATNumber(context.Background(), "", "123")
+	assert.Error(err)
+}
+
+func TestCheckInvoiceCustomer(t *testing.T) {
+	assert := assert.New(t)
+
+	c := setupTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"countryCode":"DE","vatNumber":"123456789","requestDate":"2024-04-01T10:00:00.000Z","valid":false}`))
+	})
+
+	var iv efactura.Invoice
+	_, err := c.CheckInvoiceCustomer(context.Background(), iv)
+	assert.Error(err)
+	assert.False(vies.RequiresCheck(iv))
+
+	iv.Customer.Party.TaxScheme = &efactura.InvoicePartyTaxScheme{
+		TaxScheme: efactura.TaxSchemeVAT,
+		CompanyID: "DE123456789",
+	}
+	iv.InvoiceLines = []efactura.InvoiceLine{{}}
+	iv.InvoiceLines[0].Item.TaxCategory.ID = efactura.TaxCategoryVATExemptIntraCommunitySupply
+	assert.True(vies.RequiresCheck(iv))
+	if res, err := c.CheckInvoiceCustomer(context.Background(), iv); assert.NoError(err) {
+		assert.False(res.Valid)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/printesoi/xml-go"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// CharsetReader is a function that returns a reader that converts the input
+// from the given charset (as declared in the XML declaration) to UTF-8. It
+// has the signature of xml.Decoder.CharsetReader.
+type CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+// DefaultCharsetReader is the default CharsetReader, that supports the
+// charsets from golang.org/x/text (eg. Windows-1252, ISO-8859-2) by their
+// IANA or WHATWG names.
+func DefaultCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := lookupEncoding(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+func lookupEncoding(charset string) (encoding.Encoding, error) {
+	if enc, err := ianaindex.IANA.Encoding(charset); err == nil && enc != nil {
+		return enc, nil
+	}
+	if enc, err := htmlindex.Get(charset); err == nil && enc != nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("xml: unsupported charset %q", charset)
+}
+
+var charsetReader atomic.Pointer[CharsetReader]
+
+func init() {
+	SetCharsetReader(DefaultCharsetReader)
+}
+
+// SetCharsetReader sets the CharsetReader used when unmarshaling XML
+// documents that are not encoded as UTF-8. A nil CharsetReader restores
+// DefaultCharsetReader. This function is safe for concurrent use, but should
+// usually be called only once, at init time.
+func SetCharsetReader(cr CharsetReader) {
+	if cr == nil {
+		cr = DefaultCharsetReader
+	}
+	charsetReader.Store(&cr)
+}
+
+// GetCharsetReader returns the CharsetReader used when unmarshaling.
+func GetCharsetReader() CharsetReader {
+	return *charsetReader.Load()
+}
+
+// NewDecoder creates a new xml.Decoder reading from r, using the
+// CharsetReader set with SetCharsetReader.
+func NewDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = GetCharsetReader()
+	return d
+}
+
+var xmlDeclEncodingRegex = regexp.MustCompile(`^(\s*<\?xml\s[^>]*?encoding\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+
+// isUTF8Charset returns true if the given charset is UTF-8 (or a subset of
+// it).
+func isUTF8Charset(charset string) bool {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return true
+	}
+	return false
+}
+
+// ConvertToUTF8 converts the XML document to UTF-8 using the given
+// CharsetReader (or the CharsetReader set with SetCharsetReader if nil), if
+// the XML declaration declares a different encoding. The encoding from the
+// XML declaration is replaced with UTF-8. Documents without an encoding
+// declaration, or declared as UTF-8, are returned unchanged.
+func ConvertToUTF8(data []byte, cr CharsetReader) ([]byte, error) {
+	m := xmlDeclEncodingRegex.FindSubmatchIndex(data)
+	if m == nil {
+		return data, nil
+	}
+	var charset string
+	if m[4] >= 0 {
+		charset = string(data[m[4]:m[5]])
+	} else {
+		charset = string(data[m[6]:m[7]])
+	}
+	if isUTF8Charset(charset) {
+		return data, nil
+	}
+	if cr == nil {
+		cr = GetCharsetReader()
+	}
+	r, err := cr(charset, bytes.NewReader(data[m[1]:]))
+	if err != nil {
+		return nil, err
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]byte, 0, m[3]+len(`"UTF-8"`)+len(rest))
+	converted = append(converted, data[:m[3]]...)
+	converted = append(converted, `"UTF-8"`...)
+	return append(converted, rest...), nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package xml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToUTF8(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{{
+		name:     "windows-1252",
+		data:     "<?xml version=\"1.0\" encoding=\"Windows-1252\"?><a>Caf\xe9 \x80</a>",
+		expected: "<?xml version=\"1.0\" encoding=\"UTF-8\"?><a>Café €</a>",
+	}, {
+		name:     "iso-8859-2 single quotes",
+		data:     "<?xml version='1.0' encoding='ISO-8859-2' standalone='yes'?><a>\xaai \xfe</a>",
+		expected: "<?xml version='1.0' encoding=\"UTF-8\" standalone='yes'?><a>Şi ţ</a>",
+	}, {
+		name:     "utf-8",
+		data:     `<?xml version="1.0" encoding="utf-8"?><a>Şi</a>`,
+		expected: `<?xml version="1.0" encoding="utf-8"?><a>Şi</a>`,
+	}, {
+		name:     "no declaration",
+		data:     `<a>Şi</a>`,
+		expected: `<a>Şi</a>`,
+	}}
+	for _, tt := range tests {
+		converted, err := ConvertToUTF8([]byte(tt.data), nil)
+		if assert.NoError(err, tt.name) {
+			assert.Equal(tt.expected, string(converted), tt.name)
+		}
+	}
+
+	_, err := ConvertToUTF8([]byte(`<?xml version="1.0" encoding="x-unknown"?><a/>`), nil)
+	assert.Error(err)
+}
+
+func TestUnmarshalXMLCharset(t *testing.T) {
+	assert := assert.New(t)
+
+	var v struct {
+		Value string `xml:"v"`
+	}
+	err := UnmarshalXML([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-2\"?><a><v>Bra\xbaov</v></a>"), &v)
+	if assert.NoError(err) {
+		assert.Equal("Braşov", v.Value)
+	}
+}
@@ -62,9 +62,10 @@ func MarshalIndentXMLWithHeader(v any, prefix, indent string) ([]byte, error) {
 // the value pointed to by v, which must be an arbitrary struct,
 // slice, or string. Well-formed data that does not fit into v is
 // discarded. This method must be used for unmarshaling objects from this
-// library, instead of encoding/xml.
+// library, instead of encoding/xml. Documents not encoded as UTF-8 are
+// decoded using the CharsetReader set with SetCharsetReader.
 func UnmarshalXML(data []byte, v any) error {
-	return xml.Unmarshal(data, v)
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
 }
 
 // UnmarshalReaderXML reads all the content from the given reader r and
@@ -0,0 +1,496 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package xmlsig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	pxml "github.com/printesoi/e-factura-go/pkg/xml"
+)
+
+const (
+	// AlgorithmC14N is the Canonical XML 1.0 algorithm (omits comments).
+	AlgorithmC14N = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	// AlgorithmC14NWithComments is the Canonical XML 1.0 algorithm with
+	// comments.
+	AlgorithmC14NWithComments = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	// AlgorithmC14N11 is the Canonical XML 1.1 algorithm (omits comments).
+	AlgorithmC14N11 = "http://www.w3.org/2006/12/xml-c14n11"
+	// AlgorithmC14N11WithComments is the Canonical XML 1.1 algorithm with
+	// comments.
+	AlgorithmC14N11WithComments = "http://www.w3.org/2006/12/xml-c14n11#WithComments"
+	// AlgorithmExcC14N is the Exclusive XML Canonicalization 1.0 algorithm
+	// (omits comments).
+	AlgorithmExcC14N = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	// AlgorithmExcC14NWithComments is the Exclusive XML Canonicalization 1.0
+	// algorithm with comments.
+	AlgorithmExcC14NWithComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+
+	nsXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// canonicalizer describes a canonicalization algorithm.
+type canonicalizer struct {
+	exclusive    bool
+	withComments bool
+	// inclusivePrefixes are the prefixes treated as in the inclusive
+	// canonicalization by the exclusive canonicalization (the
+	// InclusiveNamespaces PrefixList, "#default" for the default namespace).
+	inclusivePrefixes map[string]bool
+}
+
+// newCanonicalizer returns the canonicalizer for the given algorithm.
+func newCanonicalizer(algorithm string, inclusivePrefixes []string) (c canonicalizer, err error) {
+	switch algorithm {
+	case AlgorithmC14N, AlgorithmC14N11:
+	case AlgorithmC14NWithComments, AlgorithmC14N11WithComments:
+		c.withComments = true
+	case AlgorithmExcC14N:
+		c.exclusive = true
+	case AlgorithmExcC14NWithComments:
+		c.exclusive, c.withComments = true, true
+	default:
+		err = fmt.Errorf("%w: canonicalization %s", ErrUnsupportedAlgorithm, algorithm)
+		return
+	}
+	if c.exclusive && len(inclusivePrefixes) > 0 {
+		c.inclusivePrefixes = make(map[string]bool, len(inclusivePrefixes))
+		for _, prefix := range inclusivePrefixes {
+			if prefix == "#default" {
+				prefix = ""
+			}
+			c.inclusivePrefixes[prefix] = true
+		}
+	}
+	return
+}
+
+// nodeKind is the kind of a node of a parsed document.
+type nodeKind int
+
+const (
+	nodeElement nodeKind = iota
+	nodeText
+	nodeComment
+	nodeProcInst
+)
+
+// node is a node of a parsed document. The names keep their prefixes, the
+// namespaces are resolved during the canonicalization.
+type node struct {
+	kind     nodeKind
+	name     xml.Name // element name, Space is the prefix
+	attrs    []xml.Attr
+	children []*node
+	parent   *node
+	// data is the text, the comment or the processing instruction target and
+	// data (separated by a space).
+	data string
+}
+
+// document is a parsed XML document.
+type document struct {
+	// nodes are the top level nodes (the root element and the comments and
+	// processing instructions outside it).
+	nodes []*node
+	root  *node
+}
+
+// parseDocument parses an XML document, using the CharsetReader set with
+// pxml.SetCharsetReader for the documents not encoded as UTF-8. The XML
+// declaration and the DTD are dropped, as required by the canonicalization.
+func parseDocument(data []byte) (*document, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = pxml.GetCharsetReader()
+
+	doc := &document{}
+	var current *node
+	appendNode := func(n *node) {
+		if current == nil {
+			doc.nodes = append(doc.nodes, n)
+			return
+		}
+		n.parent = current
+		current.children = append(current.children, n)
+	}
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if current == nil && doc.root != nil {
+				return nil, errors.New("xml: multiple root elements")
+			}
+			n := &node{kind: nodeElement, name: t.Name, attrs: append([]xml.Attr(nil), t.Attr...)}
+			appendNode(n)
+			if doc.root == nil {
+				doc.root = n
+			}
+			current = n
+		case xml.EndElement:
+			if current == nil {
+				return nil, errors.New("xml: unexpected end element")
+			}
+			current = current.parent
+		case xml.CharData:
+			if current == nil {
+				// Whitespace outside the root element is not part of
+				// the canonical form.
+				continue
+			}
+			appendNode(&node{kind: nodeText, data: string(t)})
+		case xml.Comment:
+			appendNode(&node{kind: nodeComment, data: string(t)})
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			data := t.Target
+			if len(t.Inst) > 0 {
+				data += " " + string(t.Inst)
+			}
+			appendNode(&node{kind: nodeProcInst, data: data})
+		}
+	}
+	if doc.root == nil {
+		return nil, errors.New("xml: missing root element")
+	}
+	if current != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return doc, nil
+}
+
+// isNamespaceDecl returns the prefix if the attribute is a namespace
+// declaration ("" for the default namespace).
+func isNamespaceDecl(a xml.Attr) (prefix string, ok bool) {
+	if a.Name.Space == "" && a.Name.Local == "xmlns" {
+		return "", true
+	}
+	if a.Name.Space == "xmlns" {
+		return a.Name.Local, true
+	}
+	return "", false
+}
+
+// namespaces returns the namespaces in scope for the element (prefix to
+// URI, "" for the default namespace).
+func (n *node) namespaces() map[string]string {
+	var chain []*node
+	for e := n; e != nil; e = e.parent {
+		chain = append(chain, e)
+	}
+	scope := map[string]string{"xml": nsXML}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, a := range chain[i].attrs {
+			if prefix, ok := isNamespaceDecl(a); ok {
+				scope[prefix] = a.Value
+			}
+		}
+	}
+	return scope
+}
+
+// namespace returns the namespace URI of the element.
+func (n *node) namespace() string {
+	return n.namespaces()[n.name.Space]
+}
+
+// attr returns the value of the unqualified attribute with the given name.
+func (n *node) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// text returns the concatenated text of the element.
+func (n *node) text() string {
+	var sb strings.Builder
+	for _, c := range n.children {
+		switch c.kind {
+		case nodeText:
+			sb.WriteString(c.data)
+		case nodeElement:
+			sb.WriteString(c.text())
+		}
+	}
+	return sb.String()
+}
+
+// childElements returns the child elements with the given namespace and
+// local name.
+func (n *node) childElements(space, local string) (elements []*node) {
+	for _, c := range n.children {
+		if c.kind == nodeElement && c.name.Local == local && c.namespace() == space {
+			elements = append(elements, c)
+		}
+	}
+	return
+}
+
+// childElement returns the first child element with the given namespace
+// and local name, or nil.
+func (n *node) childElement(space, local string) *node {
+	if elements := n.childElements(space, local); len(elements) > 0 {
+		return elements[0]
+	}
+	return nil
+}
+
+// findByID returns the elements of the subtree of n with an Id (or ID or
+// id) attribute equal to id.
+func (n *node) findByID(id string) (found []*node) {
+	if n.kind != nodeElement {
+		return nil
+	}
+	for _, name := range []string{"Id", "ID", "id"} {
+		if v, ok := n.attr(name); ok && v == id {
+			found = append(found, n)
+			break
+		}
+	}
+	for _, c := range n.children {
+		found = append(found, c.findByID(id)...)
+	}
+	return
+}
+
+// canonicalizeDocument returns the canonical form of the whole document.
+func (c canonicalizer) canonicalizeDocument(doc *document) []byte {
+	var buf bytes.Buffer
+	afterRoot := false
+	for _, n := range doc.nodes {
+		switch n.kind {
+		case nodeElement:
+			c.writeElement(&buf, n, map[string]string{"": ""}, nil)
+			afterRoot = true
+		case nodeComment, nodeProcInst:
+			if n.kind == nodeComment && !c.withComments {
+				continue
+			}
+			if afterRoot {
+				buf.WriteByte('\n')
+			}
+			c.writeNode(&buf, n)
+			if !afterRoot {
+				buf.WriteByte('\n')
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// canonicalizeElement returns the canonical form of the subtree of the
+// element (the document subset with the element as apex). The namespaces
+// declared by the ancestors are rendered on the element as needed, and for
+// the inclusive canonicalization the xml:* attributes are inherited.
+func (c canonicalizer) canonicalizeElement(n *node) []byte {
+	var inherited []xml.Attr
+	if !c.exclusive {
+		seen := make(map[string]bool)
+		for _, a := range n.attrs {
+			if a.Name.Space == "xml" {
+				seen[a.Name.Local] = true
+			}
+		}
+		for e := n.parent; e != nil; e = e.parent {
+			for _, a := range e.attrs {
+				if a.Name.Space == "xml" && !seen[a.Name.Local] {
+					seen[a.Name.Local] = true
+					inherited = append(inherited, a)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	c.writeElement(&buf, n, map[string]string{"": ""}, inherited)
+	return buf.Bytes()
+}
+
+func (c canonicalizer) writeNode(buf *bytes.Buffer, n *node) {
+	switch n.kind {
+	case nodeText:
+		escapeText(buf, n.data)
+	case nodeComment:
+		buf.WriteString("<!--")
+		buf.WriteString(n.data)
+		buf.WriteString("-->")
+	case nodeProcInst:
+		buf.WriteString("<?")
+		buf.WriteString(n.data)
+		buf.WriteString("?>")
+	}
+}
+
+type canonicalAttr struct {
+	space, local, qname, value string
+}
+
+// writeElement writes the canonical form of the element. rendered are the
+// namespace declarations rendered by the output ancestors and extra are
+// attributes added to the element.
+func (c canonicalizer) writeElement(buf *bytes.Buffer, n *node, rendered map[string]string, extra []xml.Attr) {
+	scope := n.namespaces()
+
+	// The namespace declarations to render.
+	var prefixes []string
+	if c.exclusive {
+		utilized := map[string]bool{n.name.Space: true}
+		for _, a := range n.attrs {
+			if _, ok := isNamespaceDecl(a); !ok && a.Name.Space != "" {
+				utilized[a.Name.Space] = true
+			}
+		}
+		for prefix := range c.inclusivePrefixes {
+			if _, ok := scope[prefix]; ok {
+				utilized[prefix] = true
+			}
+		}
+		for prefix := range utilized {
+			prefixes = append(prefixes, prefix)
+		}
+	} else {
+		for prefix := range scope {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	var decls []canonicalAttr
+	childRendered := rendered
+	for _, prefix := range prefixes {
+		if prefix == "xml" {
+			continue
+		}
+		uri := scope[prefix]
+		if prev, ok := rendered[prefix]; ok && prev == uri {
+			continue
+		} else if !ok && uri == "" {
+			continue
+		}
+		if len(decls) == 0 {
+			childRendered = make(map[string]string, len(rendered)+1)
+			for k, v := range rendered {
+				childRendered[k] = v
+			}
+		}
+		childRendered[prefix] = uri
+		qname := "xmlns"
+		if prefix != "" {
+			qname += ":" + prefix
+		}
+		decls = append(decls, canonicalAttr{local: prefix, qname: qname, value: uri})
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].local < decls[j].local })
+
+	var attrs []canonicalAttr
+	for _, a := range append(append([]xml.Attr(nil), n.attrs...), extra...) {
+		if _, ok := isNamespaceDecl(a); ok {
+			continue
+		}
+		ca := canonicalAttr{local: a.Name.Local, qname: a.Name.Local, value: a.Value}
+		if a.Name.Space != "" {
+			ca.space = scope[a.Name.Space]
+			ca.qname = a.Name.Space + ":" + a.Name.Local
+		}
+		attrs = append(attrs, ca)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].space != attrs[j].space {
+			return attrs[i].space < attrs[j].space
+		}
+		return attrs[i].local < attrs[j].local
+	})
+
+	qname := n.name.Local
+	if n.name.Space != "" {
+		qname = n.name.Space + ":" + n.name.Local
+	}
+	buf.WriteByte('<')
+	buf.WriteString(qname)
+	for _, a := range append(decls, attrs...) {
+		buf.WriteByte(' ')
+		buf.WriteString(a.qname)
+		buf.WriteString(`="`)
+		escapeAttr(buf, a.value)
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+	for _, child := range n.children {
+		switch child.kind {
+		case nodeElement:
+			c.writeElement(buf, child, childRendered, nil)
+		case nodeComment:
+			if c.withComments {
+				c.writeNode(buf, child)
+			}
+		default:
+			c.writeNode(buf, child)
+		}
+	}
+	buf.WriteString("</")
+	buf.WriteString(qname)
+	buf.WriteByte('>')
+}
+
+func escapeText(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+func escapeAttr(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\t':
+			buf.WriteString("&#x9;")
+		case '\n':
+			buf.WriteString("&#xA;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
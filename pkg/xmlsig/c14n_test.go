@@ -0,0 +1,115 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package xmlsig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalizeDocument(t *testing.T) {
+	assert := assert.New(t)
+
+	// Example 3.3 from the Canonical XML 1.0 specification (without the
+	// DTD default attribute).
+	input := `<?xml version="1.0"?>
+<!-- Comment 1 -->
+<doc>
+   <e1   />
+   <e2   ></e2>
+   <e3   name = "elem3"   id="elem3"   />
+   <e4   name="elem4"   id="elem4"   ></e4>
+   <e5 a:attr="out" b:attr="sorted" attr2="all" attr="I'm"
+      xmlns:b="http://www.ietf.org"
+      xmlns:a="http://www.w3.org"
+      xmlns="http://example.org"/>
+   <e6 xmlns="" xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="" xmlns:a="http://www.w3.org">
+            <e9 xmlns="" xmlns:a="http://www.ietf.org"/>
+         </e8>
+      </e7>
+   </e6>
+   <t a="&lt;&quot;&#x9;" b='x'>text &amp; &gt; <![CDATA[<cdata>]]><!-- c --></t>
+</doc>
+<?pi data?>
+`
+	expected := `<doc>
+   <e1></e1>
+   <e2></e2>
+   <e3 id="elem3" name="elem3"></e3>
+   <e4 id="elem4" name="elem4"></e4>
+   <e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>
+   <e6 xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="">
+            <e9 xmlns:a="http://www.ietf.org"></e9>
+         </e8>
+      </e7>
+   </e6>
+   <t a="&lt;&quot;&#x9;" b="x">text &amp; &gt; &lt;cdata&gt;</t>
+</doc>
+<?pi data?>`
+
+	doc, err := parseDocument([]byte(input))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(expected, string(canonicalizer{}.canonicalizeDocument(doc)))
+
+	withComments, err := newCanonicalizer(AlgorithmC14NWithComments, nil)
+	if assert.NoError(err) {
+		out := string(withComments.canonicalizeDocument(doc))
+		assert.Contains(out, "<!-- Comment 1 -->\n<doc>")
+		assert.Contains(out, "&lt;cdata&gt;<!-- c --></t>")
+	}
+
+	_, err = newCanonicalizer("urn:unknown", nil)
+	assert.ErrorIs(err, ErrUnsupportedAlgorithm)
+}
+
+func TestCanonicalizeElement(t *testing.T) {
+	assert := assert.New(t)
+
+	// The example from the Exclusive XML Canonicalization specification.
+	input := `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org">` +
+		`<n1:elem2 xmlns:n1="http://example.net" xml:lang="en">` +
+		`<n3:stuff xmlns:n3="ftp://example.org"/>` +
+		`</n1:elem2></n0:local>`
+	doc, err := parseDocument([]byte(input))
+	if !assert.NoError(err) {
+		return
+	}
+	elem2 := doc.root.children[0]
+
+	assert.Equal(`<n1:elem2 xmlns:n0="foo:bar" xmlns:n1="http://example.net" xmlns:n3="ftp://example.org" xml:lang="en">`+
+		`<n3:stuff></n3:stuff></n1:elem2>`,
+		string(canonicalizer{}.canonicalizeElement(elem2)))
+
+	exclusive, err := newCanonicalizer(AlgorithmExcC14N, nil)
+	if assert.NoError(err) {
+		assert.Equal(`<n1:elem2 xmlns:n1="http://example.net" xml:lang="en">`+
+			`<n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`,
+			string(exclusive.canonicalizeElement(elem2)))
+	}
+
+	withPrefixList, err := newCanonicalizer(AlgorithmExcC14N, []string{"n0"})
+	if assert.NoError(err) {
+		assert.Equal(`<n1:elem2 xmlns:n0="foo:bar" xmlns:n1="http://example.net" xml:lang="en">`+
+			`<n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`,
+			string(withPrefixList.canonicalizeElement(elem2)))
+	}
+}
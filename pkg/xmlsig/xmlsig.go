@@ -0,0 +1,635 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+// Package xmlsig verifies the detached XMLDSig signatures of the documents
+// downloaded from ANAF (the semnatura_*.xml file from the downloaded archive)
+// without calling the ANAF signature validation API. The digests and the
+// signature are checked against the document XML, and the signing
+// certificate is verified against the trusted certificates from the trust
+// package (or custom roots).
+package xmlsig
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/printesoi/e-factura-go/pkg/trust"
+)
+
+const (
+	// NamespaceDSig is the XMLDSig namespace.
+	NamespaceDSig = "http://www.w3.org/2000/09/xmldsig#"
+
+	// AlgorithmEnvelopedSignature is the enveloped signature transform.
+	AlgorithmEnvelopedSignature = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+
+	// The supported digest algorithms.
+	AlgorithmSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	AlgorithmSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	AlgorithmSHA384 = "http://www.w3.org/2001/04/xmldsig-more#sha384"
+	AlgorithmSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+
+	// The supported signature algorithms.
+	AlgorithmRSASHA1     = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	AlgorithmRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	AlgorithmRSASHA384   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha384"
+	AlgorithmRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	AlgorithmECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	AlgorithmECDSASHA384 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384"
+	AlgorithmECDSASHA512 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512"
+)
+
+var (
+	// ErrNoSignature is returned when the document is not a XMLDSig
+	// signature.
+	ErrNoSignature = errors.New("xmlsig: not a XMLDSig signature")
+	// ErrUnsupportedAlgorithm is returned for the algorithms not supported
+	// by the verifier.
+	ErrUnsupportedAlgorithm = errors.New("xmlsig: unsupported algorithm")
+	// ErrDigestMismatch is returned when the digest of a reference doesn't
+	// match the referenced data (eg. the document was modified).
+	ErrDigestMismatch = errors.New("xmlsig: digest mismatch")
+	// ErrNoDocumentReference is returned when the signature has no
+	// reference to the detached document (eg. it only references the XAdES
+	// signed properties), so it doesn't sign the document.
+	ErrNoDocumentReference = errors.New("xmlsig: no reference to the signed document")
+	// ErrInvalidSignature is returned when the signature value doesn't
+	// match the signed info.
+	ErrInvalidSignature = errors.New("xmlsig: invalid signature")
+	// ErrNoCertificate is returned when the signature doesn't contain the
+	// signing certificate.
+	ErrNoCertificate = errors.New("xmlsig: signing certificate not found")
+	// ErrUntrustedCertificate is returned when the signing certificate
+	// cannot be verified against the trusted roots.
+	ErrUntrustedCertificate = errors.New("xmlsig: untrusted certificate")
+	// ErrNoTrustedRoots is returned by NewVerifier if no roots are set and
+	// the default trust store has no certificates.
+	ErrNoTrustedRoots = errors.New("xmlsig: no trusted root certificates")
+)
+
+var (
+	regexZipFile          = regexp.MustCompile(`^\d+\.xml$`)
+	regexZipSignatureFile = regexp.MustCompile(`^semnatura_\d+\.xml$`)
+)
+
+// Transform is a transform applied to the referenced data before computing
+// the digest.
+type Transform struct {
+	// Algorithm is the URI of the transform algorithm.
+	Algorithm string
+	// InclusivePrefixes is the InclusiveNamespaces PrefixList of the
+	// exclusive canonicalization transforms.
+	InclusivePrefixes []string
+}
+
+// Reference is a reference from the signed info of a signature.
+type Reference struct {
+	// URI is the URI of the referenced data. An empty URI (or the name of
+	// a file) references the detached document, while "#id" references an
+	// element of the signature document (eg. the XAdES signed properties).
+	URI string
+	// Transforms are the transforms applied to the referenced data.
+	Transforms []Transform
+	// DigestMethod is the URI of the digest algorithm.
+	DigestMethod string
+	// DigestValue is the expected digest.
+	DigestValue []byte
+}
+
+// isDetached returns true if the reference points to the detached document.
+func (r Reference) isDetached() bool {
+	return !strings.HasPrefix(r.URI, "#")
+}
+
+// Signature is a parsed XMLDSig signature.
+type Signature struct {
+	// CanonicalizationMethod is the URI of the canonicalization algorithm
+	// of the signed info.
+	CanonicalizationMethod string
+	// InclusivePrefixes is the InclusiveNamespaces PrefixList of the
+	// canonicalization method.
+	InclusivePrefixes []string
+	// SignatureMethod is the URI of the signature algorithm.
+	SignatureMethod string
+	// References are the references from the signed info.
+	References []Reference
+	// SignatureValue is the signature of the canonical signed info.
+	SignatureValue []byte
+	// Certificates are the certificates from the KeyInfo, in the order
+	// from the signature.
+	Certificates []*x509.Certificate
+
+	doc        *document
+	element    *node
+	signedInfo *node
+}
+
+// ParseSignature parses a XMLDSig signature document (eg. the
+// semnatura_*.xml file from the archive downloaded from ANAF). The Signature
+// element must be the root of the document or a child of the root element,
+// and it must contain exactly one SignedInfo element, so that the signed
+// info cannot be wrapped in another element of the document.
+func ParseSignature(data []byte) (sig *Signature, err error) {
+	doc, er := parseDocument(data)
+	if err = er; err != nil {
+		return
+	}
+	el := findSignatureElement(doc.root)
+	if el == nil {
+		err = ErrNoSignature
+		return
+	}
+	signedInfos := el.childElements(NamespaceDSig, "SignedInfo")
+	if len(signedInfos) != 1 {
+		err = fmt.Errorf("%w: expected one SignedInfo, found %d", ErrNoSignature, len(signedInfos))
+		return
+	}
+	signedInfo := signedInfos[0]
+
+	sig = &Signature{doc: doc, element: el, signedInfo: signedInfo}
+	if cm := signedInfo.childElement(NamespaceDSig, "CanonicalizationMethod"); cm != nil {
+		sig.CanonicalizationMethod, _ = cm.attr("Algorithm")
+		sig.InclusivePrefixes = inclusivePrefixes(cm)
+	}
+	if sm := signedInfo.childElement(NamespaceDSig, "SignatureMethod"); sm != nil {
+		sig.SignatureMethod, _ = sm.attr("Algorithm")
+	}
+	for _, ref := range signedInfo.childElements(NamespaceDSig, "Reference") {
+		var reference Reference
+		reference.URI, _ = ref.attr("URI")
+		if transforms := ref.childElement(NamespaceDSig, "Transforms"); transforms != nil {
+			for _, t := range transforms.childElements(NamespaceDSig, "Transform") {
+				algorithm, _ := t.attr("Algorithm")
+				reference.Transforms = append(reference.Transforms, Transform{
+					Algorithm:         algorithm,
+					InclusivePrefixes: inclusivePrefixes(t),
+				})
+			}
+		}
+		if dm := ref.childElement(NamespaceDSig, "DigestMethod"); dm != nil {
+			reference.DigestMethod, _ = dm.attr("Algorithm")
+		}
+		if dv := ref.childElement(NamespaceDSig, "DigestValue"); dv != nil {
+			if reference.DigestValue, err = decodeBase64(dv.text()); err != nil {
+				err = fmt.Errorf("xmlsig: invalid DigestValue: %w", err)
+				return
+			}
+		}
+		sig.References = append(sig.References, reference)
+	}
+	if len(sig.References) == 0 {
+		err = fmt.Errorf("%w: missing Reference", ErrNoSignature)
+		return
+	}
+	if sv := el.childElement(NamespaceDSig, "SignatureValue"); sv != nil {
+		if sig.SignatureValue, err = decodeBase64(sv.text()); err != nil {
+			err = fmt.Errorf("xmlsig: invalid SignatureValue: %w", err)
+			return
+		}
+	}
+	if ki := el.childElement(NamespaceDSig, "KeyInfo"); ki != nil {
+		for _, data := range ki.childElements(NamespaceDSig, "X509Data") {
+			for _, c := range data.childElements(NamespaceDSig, "X509Certificate") {
+				der, er := decodeBase64(c.text())
+				if err = er; err != nil {
+					err = fmt.Errorf("xmlsig: invalid X509Certificate: %w", err)
+					return
+				}
+				cert, er := x509.ParseCertificate(der)
+				if err = er; err != nil {
+					err = fmt.Errorf("xmlsig: invalid X509Certificate: %w", err)
+					return
+				}
+				sig.Certificates = append(sig.Certificates, cert)
+			}
+		}
+	}
+	return
+}
+
+// findSignatureElement returns the Signature element if it's the root or
+// the only Signature child of the root, or nil.
+func findSignatureElement(root *node) *node {
+	if root.kind != nodeElement {
+		return nil
+	}
+	if root.name.Local == "Signature" && root.namespace() == NamespaceDSig {
+		return root
+	}
+	if signatures := root.childElements(NamespaceDSig, "Signature"); len(signatures) == 1 {
+		return signatures[0]
+	}
+	return nil
+}
+
+// inclusivePrefixes returns the PrefixList of the InclusiveNamespaces child
+// of a canonicalization method or transform.
+func inclusivePrefixes(n *node) []string {
+	for _, c := range n.children {
+		if c.kind == nodeElement && c.name.Local == "InclusiveNamespaces" && c.namespace() == AlgorithmExcC14N {
+			prefixList, _ := c.attr("PrefixList")
+			return strings.Fields(prefixList)
+		}
+	}
+	return nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// VerifyDigests checks the digests of the references against the detached
+// document data. The Reference with the first mismatching digest is
+// returned along with an ErrDigestMismatch error. At least one reference
+// must point to the detached document, otherwise ErrNoDocumentReference is
+// returned. The "#id" references are resolved only in the Signature element,
+// and the id must be unique in it.
+func (s *Signature) VerifyDigests(data []byte) error {
+	var (
+		doc      *document
+		detached bool
+	)
+	for _, ref := range s.References {
+		var (
+			input  []byte
+			subset *node
+		)
+		if ref.isDetached() {
+			detached = true
+			input = data
+			if ref.URI == "" {
+				// A same-document reference is a node-set without
+				// comments, even with no transforms.
+				if doc == nil {
+					var err error
+					if doc, err = parseDocument(data); err != nil {
+						return err
+					}
+				}
+				subset = doc.root
+			}
+		} else {
+			found := s.element.findByID(strings.TrimPrefix(ref.URI, "#"))
+			switch len(found) {
+			case 0:
+				return fmt.Errorf("xmlsig: referenced element %s not found", ref.URI)
+			case 1:
+				subset = found[0]
+			default:
+				return fmt.Errorf("xmlsig: referenced element %s is not unique", ref.URI)
+			}
+		}
+		digest, err := s.referenceDigest(ref, input, subset, doc)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare(digest, ref.DigestValue) != 1 {
+			return fmt.Errorf("%w: reference %q", ErrDigestMismatch, ref.URI)
+		}
+	}
+	if !detached {
+		return ErrNoDocumentReference
+	}
+	return nil
+}
+
+// referenceDigest applies the transforms of the reference and returns the
+// digest. The referenced data is either the octets from input or the
+// subtree of subset (the root of doc for the whole document).
+func (s *Signature) referenceDigest(ref Reference, input []byte, subset *node, doc *document) ([]byte, error) {
+	hash, err := digestHash(ref.DigestMethod)
+	if err != nil {
+		return nil, err
+	}
+	canonicalize := func(c canonicalizer) []byte {
+		if doc != nil && subset == doc.root {
+			return c.canonicalizeDocument(doc)
+		}
+		return c.canonicalizeElement(subset)
+	}
+
+	for _, t := range ref.Transforms {
+		switch t.Algorithm {
+		case AlgorithmEnvelopedSignature:
+			// The signature is detached (or it's an ancestor of the
+			// referenced subtree), so it's not part of the data.
+		default:
+			c, err := newCanonicalizer(t.Algorithm, t.InclusivePrefixes)
+			if err != nil {
+				return nil, err
+			}
+			if subset == nil {
+				parsed, err := parseDocument(input)
+				if err != nil {
+					return nil, err
+				}
+				doc, subset = parsed, parsed.root
+			}
+			input, subset = canonicalize(c), nil
+		}
+	}
+	if subset != nil {
+		input = canonicalize(canonicalizer{})
+	}
+
+	h := hash.New()
+	h.Write(input)
+	return h.Sum(nil), nil
+}
+
+// VerifySignatureValue checks the signature value of the canonical signed
+// info with the public key of the certificate.
+func (s *Signature) VerifySignatureValue(cert *x509.Certificate) error {
+	c, err := newCanonicalizer(s.CanonicalizationMethod, s.InclusivePrefixes)
+	if err != nil {
+		return err
+	}
+	hash, err := signatureHash(s.SignatureMethod)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(c.canonicalizeElement(s.signedInfo))
+	hashed := h.Sum(nil)
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if !strings.Contains(s.SignatureMethod, "rsa-") {
+			return fmt.Errorf("%w: %s with a RSA key", ErrUnsupportedAlgorithm, s.SignatureMethod)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, hashed, s.SignatureValue); err != nil {
+			return ErrInvalidSignature
+		}
+	case *ecdsa.PublicKey:
+		if !strings.Contains(s.SignatureMethod, "ecdsa-") {
+			return fmt.Errorf("%w: %s with an ECDSA key", ErrUnsupportedAlgorithm, s.SignatureMethod)
+		}
+		// The XMLDSig ECDSA signature is the concatenation of r and s.
+		n := len(s.SignatureValue) / 2
+		if n == 0 || len(s.SignatureValue)%2 != 0 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(s.SignatureValue[:n])
+		ss := new(big.Int).SetBytes(s.SignatureValue[n:])
+		if !ecdsa.Verify(pub, hashed, r, ss) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("%w: public key %T", ErrUnsupportedAlgorithm, cert.PublicKey)
+	}
+	return nil
+}
+
+func digestHash(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case AlgorithmSHA1:
+		return crypto.SHA1, nil
+	case AlgorithmSHA256:
+		return crypto.SHA256, nil
+	case AlgorithmSHA384:
+		return crypto.SHA384, nil
+	case AlgorithmSHA512:
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("%w: digest %s", ErrUnsupportedAlgorithm, algorithm)
+}
+
+func signatureHash(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case AlgorithmRSASHA1:
+		return crypto.SHA1, nil
+	case AlgorithmRSASHA256, AlgorithmECDSASHA256:
+		return crypto.SHA256, nil
+	case AlgorithmRSASHA384, AlgorithmECDSASHA384:
+		return crypto.SHA384, nil
+	case AlgorithmRSASHA512, AlgorithmECDSASHA512:
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("%w: signature %s", ErrUnsupportedAlgorithm, algorithm)
+}
+
+// VerifierConfig is the config used to create a Verifier.
+type VerifierConfig struct {
+	// Roots are the trusted root certificates. If nil, the certificates
+	// from trust.Load (embedded and override directory) are used.
+	Roots *x509.CertPool
+	// Intermediates are additional intermediate certificates, besides the
+	// certificates from the signature (optional).
+	Intermediates *x509.CertPool
+	// CurrentTime is the time at which the certificate chain is verified
+	// (optional). If zero, the current time is used.
+	CurrentTime time.Time
+}
+
+// VerifierConfigOption allows gradually modifying a VerifierConfig.
+type VerifierConfigOption func(*VerifierConfig)
+
+// VerifierRoots sets the trusted root certificates.
+func VerifierRoots(roots *x509.CertPool) VerifierConfigOption {
+	return func(c *VerifierConfig) {
+		c.Roots = roots
+	}
+}
+
+// VerifierTrustStore sets the trusted root certificates to the certificates
+// from the trust store.
+func VerifierTrustStore(store *trust.Store) VerifierConfigOption {
+	return func(c *VerifierConfig) {
+		c.Roots = store.CertPool()
+	}
+}
+
+// VerifierIntermediates sets additional intermediate certificates.
+func VerifierIntermediates(intermediates *x509.CertPool) VerifierConfigOption {
+	return func(c *VerifierConfig) {
+		c.Intermediates = intermediates
+	}
+}
+
+// VerifierCurrentTime sets the time at which the certificate chain is
+// verified, eg. the upload time for old archives signed with expired
+// certificates.
+func VerifierCurrentTime(t time.Time) VerifierConfigOption {
+	return func(c *VerifierConfig) {
+		c.CurrentTime = t
+	}
+}
+
+// Verifier verifies detached XMLDSig signatures offline.
+type Verifier struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	currentTime   time.Time
+}
+
+// NewVerifier creates a new Verifier. If no roots are set, the certificates
+// from trust.LoadDefault are used (including the ones written by the CLI
+// update-trust command), and ErrNoTrustedRoots is returned if there are none.
+func NewVerifier(opts ...VerifierConfigOption) (*Verifier, error) {
+	cfg := &VerifierConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.Roots == nil {
+		store, err := trust.LoadDefault()
+		if err != nil {
+			return nil, err
+		}
+		if store.Len() == 0 {
+			return nil, ErrNoTrustedRoots
+		}
+		cfg.Roots = store.CertPool()
+	}
+	return &Verifier{
+		roots:         cfg.Roots,
+		intermediates: cfg.Intermediates,
+		currentTime:   cfg.CurrentTime,
+	}, nil
+}
+
+// Result is the result of a successful verification.
+type Result struct {
+	// Signature is the parsed signature.
+	Signature *Signature
+	// Certificate is the signing certificate.
+	Certificate *x509.Certificate
+	// Chains are the verified certificate chains, from the signing
+	// certificate to a trusted root.
+	Chains [][]*x509.Certificate
+}
+
+// Verify verifies the detached signature (eg. semnatura_*.xml) of the
+// document data (eg. the invoice XML): the digests of the references, the
+// signature value and the certificate chain of the signing certificate. The
+// returned error wraps ErrDigestMismatch, ErrNoDocumentReference,
+// ErrInvalidSignature, ErrNoCertificate or ErrUntrustedCertificate if the
+// signature is not valid.
+func (v *Verifier) Verify(data, signatureData []byte) (result *Result, err error) {
+	sig, er := ParseSignature(signatureData)
+	if err = er; err != nil {
+		return
+	}
+	if err = sig.VerifyDigests(data); err != nil {
+		return
+	}
+	if len(sig.Certificates) == 0 {
+		err = ErrNoCertificate
+		return
+	}
+
+	// The signing certificate is the one verifying the signature value,
+	// usually the first one. The other certificates are intermediates.
+	var cert *x509.Certificate
+	for _, c := range sig.Certificates {
+		if er := sig.VerifySignatureValue(c); er == nil {
+			cert = c
+			break
+		} else if !errors.Is(er, ErrInvalidSignature) {
+			err = er
+			return
+		}
+	}
+	if cert == nil {
+		err = ErrInvalidSignature
+		return
+	}
+
+	intermediates := x509.NewCertPool()
+	if v.intermediates != nil {
+		intermediates = v.intermediates.Clone()
+	}
+	for _, c := range sig.Certificates {
+		if c != cert {
+			intermediates.AddCert(c)
+		}
+	}
+	chains, er := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.currentTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if er != nil {
+		err = fmt.Errorf("%w: %v", ErrUntrustedCertificate, er)
+		return
+	}
+
+	result = &Result{
+		Signature:   sig,
+		Certificate: cert,
+		Chains:      chains,
+	}
+	return
+}
+
+// VerifyZipData verifies the signature of the invoice from the archive
+// downloaded from ANAF (the <index>.xml and semnatura_<index>.xml files).
+func (v *Verifier) VerifyZipData(zipData []byte) (*Result, error) {
+	zr, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, err
+	}
+	readAllZipFile := func(f *zip.File) ([]byte, error) {
+		zof, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer zof.Close()
+		return io.ReadAll(zof)
+	}
+
+	var data, signatureData []byte
+	for _, f := range zr.File {
+		if regexZipFile.MatchString(f.Name) {
+			if data, err = readAllZipFile(f); err != nil {
+				return nil, err
+			}
+		} else if regexZipSignatureFile.MatchString(f.Name) {
+			if signatureData, err = readAllZipFile(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if data == nil || signatureData == nil {
+		return nil, errors.New("xmlsig: invoice archive is not complete")
+	}
+	return v.Verify(data, signatureData)
+}
+
+// VerifyZipFile is the same as VerifyZipData, but reads the archive from
+// the given path.
+func (v *Verifier) VerifyZipFile(path string) (*Result, error) {
+	zipData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return v.VerifyZipData(zipData)
+}
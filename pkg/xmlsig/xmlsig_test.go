@@ -0,0 +1,402 @@
+// Copyright 2024 Victor Dodon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License
+
+package xmlsig_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/printesoi/e-factura-go/pkg/trust"
+	"github.com/printesoi/e-factura-go/pkg/xmlsig"
+)
+
+const (
+	testInvoice = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+		"<Invoice xmlns=\"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2\" xmlns:cbc=\"urn:cbc\">\r\n" +
+		"  <!-- generated -->\r\n" +
+		"  <cbc:ID>FCT-1</cbc:ID>\n" +
+		"  <cbc:Note lang='ro'/>\n" +
+		"</Invoice>\n"
+	// testInvoiceCanonical is the canonical form of testInvoice.
+	testInvoiceCanonical = "<Invoice xmlns=\"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2\" xmlns:cbc=\"urn:cbc\">\n" +
+		"  \n" +
+		"  <cbc:ID>FCT-1</cbc:ID>\n" +
+		"  <cbc:Note lang=\"ro\"></cbc:Note>\n" +
+		"</Invoice>"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{cert: cert, key: key}
+}
+
+func (ca testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue returns a certificate for the key, signed by the CA.
+func (ca testCA) issue(t *testing.T, key crypto.Signer) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Ministerul Finantelor Publice"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// documentReference returns the Reference to testInvoice.
+func documentReference() string {
+	digest := sha256.Sum256([]byte(testInvoiceCanonical))
+	return fmt.Sprintf(`<ds:Reference URI="">`+
+		`<ds:Transforms><ds:Transform Algorithm="%s"></ds:Transform></ds:Transforms>`+
+		`<ds:DigestMethod Algorithm="%s"></ds:DigestMethod>`+
+		`<ds:DigestValue>%s</ds:DigestValue>`+
+		`</ds:Reference>`,
+		xmlsig.AlgorithmEnvelopedSignature, xmlsig.AlgorithmSHA256,
+		base64.StdEncoding.EncodeToString(digest[:]))
+}
+
+// objectReference returns the Reference to the element with the given id,
+// whose canonical form is canonical.
+func objectReference(id, canonical string) string {
+	digest := sha256.Sum256([]byte(canonical))
+	return fmt.Sprintf(`<ds:Reference URI="#%s">`+
+		`<ds:DigestMethod Algorithm="%s"></ds:DigestMethod>`+
+		`<ds:DigestValue>%s</ds:DigestValue>`+
+		`</ds:Reference>`,
+		id, xmlsig.AlgorithmSHA256, base64.StdEncoding.EncodeToString(digest[:]))
+}
+
+// signDetached returns a detached signature of testInvoice. The signed info
+// is built in canonical form, so it's signed as is.
+func signDetached(t *testing.T, key crypto.Signer, cert *x509.Certificate) []byte {
+	t.Helper()
+
+	return []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+		signReferences(t, key, cert, documentReference(), "") + "\n")
+}
+
+// signReferences returns a Signature element with the given references and
+// object (appended after the KeyInfo).
+func signReferences(t *testing.T, key crypto.Signer, cert *x509.Certificate, references, object string) string {
+	t.Helper()
+
+	signatureMethod := xmlsig.AlgorithmRSASHA256
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		signatureMethod = xmlsig.AlgorithmECDSASHA256
+	}
+	signedInfo := fmt.Sprintf(`<ds:SignedInfo xmlns:ds="%s">`+
+		`<ds:CanonicalizationMethod Algorithm="%s"></ds:CanonicalizationMethod>`+
+		`<ds:SignatureMethod Algorithm="%s"></ds:SignatureMethod>`+
+		`%s</ds:SignedInfo>`,
+		xmlsig.NamespaceDSig, xmlsig.AlgorithmC14N, signatureMethod, references)
+
+	hashed := sha256.Sum256([]byte(signedInfo))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashed[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hashed[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	var keyInfo string
+	if cert != nil {
+		keyInfo = fmt.Sprintf("<ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo>",
+			base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+	return fmt.Sprintf("<ds:Signature xmlns:ds=\"%s\">%s\n<ds:SignatureValue>%s</ds:SignatureValue>%s%s</ds:Signature>",
+		xmlsig.NamespaceDSig, signedInfo, base64.StdEncoding.EncodeToString(signature), keyInfo, object)
+}
+
+func TestVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	ca := newTestCA(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := ca.issue(t, rsaKey)
+	signature := signDetached(t, rsaKey, cert)
+
+	verifier, err := xmlsig.NewVerifier(xmlsig.VerifierRoots(ca.pool()))
+	if !assert.NoError(err) {
+		return
+	}
+	result, err := verifier.Verify([]byte(testInvoice), signature)
+	if assert.NoError(err) {
+		assert.Equal(cert, result.Certificate)
+		if assert.Len(result.Chains, 1) {
+			assert.Equal(ca.cert, result.Chains[0][1])
+		}
+		assert.Equal(xmlsig.AlgorithmRSASHA256, result.Signature.SignatureMethod)
+	}
+
+	// ECDSA signatures.
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.Verify([]byte(testInvoice), signDetached(t, ecKey, ca.issue(t, ecKey)))
+	assert.NoError(err)
+
+	// A modified invoice.
+	_, err = verifier.Verify(bytes.Replace([]byte(testInvoice), []byte("FCT-1"), []byte("FCT-2"), 1), signature)
+	assert.ErrorIs(err, xmlsig.ErrDigestMismatch)
+
+	// A signature made with another key.
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.Verify([]byte(testInvoice), signDetached(t, otherKey, cert))
+	assert.ErrorIs(err, xmlsig.ErrInvalidSignature)
+
+	// A signature without the certificate.
+	_, err = verifier.Verify([]byte(testInvoice), signDetached(t, rsaKey, nil))
+	assert.ErrorIs(err, xmlsig.ErrNoCertificate)
+
+	// A certificate not issued by a trusted root.
+	untrusted, err := xmlsig.NewVerifier(xmlsig.VerifierRoots(newTestCA(t).pool()))
+	if assert.NoError(err) {
+		_, err = untrusted.Verify([]byte(testInvoice), signature)
+		assert.ErrorIs(err, xmlsig.ErrUntrustedCertificate)
+	}
+
+	// The chain is verified at the given time.
+	expired, err := xmlsig.NewVerifier(xmlsig.VerifierRoots(ca.pool()),
+		xmlsig.VerifierCurrentTime(time.Now().Add(24*time.Hour)))
+	if assert.NoError(err) {
+		_, err = expired.Verify([]byte(testInvoice), signature)
+		assert.ErrorIs(err, xmlsig.ErrUntrustedCertificate)
+	}
+
+	// Not a signature.
+	_, err = verifier.Verify([]byte(testInvoice), []byte(testInvoice))
+	assert.ErrorIs(err, xmlsig.ErrNoSignature)
+}
+
+func TestNewVerifierDefaultTrust(t *testing.T) {
+	assert := assert.New(t)
+
+	embedded, err := trust.Embedded()
+	if !assert.NoError(err) {
+		return
+	}
+	configDir := t.TempDir()
+	t.Setenv(trust.EnvTrustDir, "")
+	t.Setenv("HOME", configDir)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("AppData", configDir)
+
+	if embedded.Len() == 0 {
+		_, err = xmlsig.NewVerifier()
+		assert.ErrorIs(err, xmlsig.ErrNoTrustedRoots)
+	}
+
+	// The certificates written to the default trust directory (eg. by the
+	// CLI update-trust command) are used.
+	ca := newTestCA(t)
+	trustDir, err := trust.DefaultDir()
+	if !assert.NoError(err) {
+		return
+	}
+	if err := os.MkdirAll(trustDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trustDir, "root.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := ca.issue(t, rsaKey)
+	verifier, err := xmlsig.NewVerifier()
+	if !assert.NoError(err) {
+		return
+	}
+	result, err := verifier.Verify([]byte(testInvoice), signDetached(t, rsaKey, cert))
+	if assert.NoError(err) {
+		assert.Equal(cert, result.Certificate)
+	}
+}
+
+func TestVerifyReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	ca := newTestCA(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := ca.issue(t, key)
+	verifier, err := xmlsig.NewVerifier(xmlsig.VerifierRoots(ca.pool()))
+	if !assert.NoError(err) {
+		return
+	}
+
+	// The object is in canonical form, so its digest is computed as is.
+	const props = `<ds:Object xmlns:ds="` + xmlsig.NamespaceDSig + `" Id="props">signed properties</ds:Object>`
+
+	// The document and the signed properties are signed.
+	signature := signReferences(t, key, cert,
+		documentReference()+objectReference("props", props), props)
+	_, err = verifier.Verify([]byte(testInvoice), []byte(signature))
+	assert.NoError(err)
+
+	// A signature of the signed properties only doesn't sign the document.
+	signature = signReferences(t, key, cert, objectReference("props", props), props)
+	_, err = verifier.Verify([]byte(testInvoice), []byte(signature))
+	assert.ErrorIs(err, xmlsig.ErrNoDocumentReference)
+
+	// The referenced elements are looked up only in the Signature.
+	signature = "<Envelope>" + props +
+		signReferences(t, key, cert, documentReference()+objectReference("props", props), "") +
+		"</Envelope>"
+	_, err = verifier.Verify([]byte(testInvoice), []byte(signature))
+	assert.ErrorContains(err, "#props not found")
+
+	// The ids must be unique in the Signature.
+	signature = signReferences(t, key, cert,
+		documentReference()+objectReference("props", props), props+props)
+	_, err = verifier.Verify([]byte(testInvoice), []byte(signature))
+	assert.ErrorContains(err, "#props is not unique")
+
+	// The Signature must be the root or a child of the root.
+	signature = "<Envelope><Wrapper>" +
+		signReferences(t, key, cert, documentReference(), "") +
+		"</Wrapper></Envelope>"
+	_, err = verifier.Verify([]byte(testInvoice), []byte(signature))
+	assert.ErrorIs(err, xmlsig.ErrNoSignature)
+	signature = "<Envelope>" +
+		signReferences(t, key, cert, documentReference(), "") +
+		"</Envelope>"
+	_, err = verifier.Verify([]byte(testInvoice), []byte(signature))
+	assert.NoError(err)
+}
+
+func TestVerifyZipData(t *testing.T) {
+	assert := assert.New(t)
+
+	ca := newTestCA(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := signDetached(t, key, ca.issue(t, key))
+
+	newZip := func(files map[string][]byte) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, data := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	verifier, err := xmlsig.NewVerifier(xmlsig.VerifierRoots(ca.pool()))
+	if !assert.NoError(err) {
+		return
+	}
+	_, err = verifier.VerifyZipData(newZip(map[string][]byte{
+		"3001234567.xml":           []byte(testInvoice),
+		"semnatura_3001234567.xml": signature,
+	}))
+	assert.NoError(err)
+
+	_, err = verifier.VerifyZipData(newZip(map[string][]byte{
+		"3001234567.xml": []byte(testInvoice),
+	}))
+	assert.ErrorContains(err, "not complete")
+}